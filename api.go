@@ -75,6 +75,8 @@ type Config struct {
     DisallowUnknownFields         bool
 
     // CopyString indicates decoder to decode string values by copying instead of referring.
+    // It applies to json.RawMessage values as well, which otherwise refer to the
+    // source JSON, and are backed by immutable memory when it is a string.
     CopyString                    bool
 
     // ValidateString indicates decoder and encoder to validate string values: decoder will return errors 
@@ -96,7 +98,12 @@ type Config struct {
     EncodeNullForInfOrNan bool
 
     // CaseSensitive indicates that the decoder should not ignore the case of object keys.
+    // By default, like encoding/json, a key which matches no field exactly is decoded
+    // into the field whose name equals it under case folding.
     CaseSensitive bool
+
+    // BoolAsInt indicates that the decoder should decode `true`/`false` into integer values as 1/0.
+    BoolAsInt bool
 }
  
 var (
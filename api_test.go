@@ -17,8 +17,13 @@
 package sonic
 
 import (
+    `encoding/json`
+    `reflect`
+    `strings`
+    `sync`
     `testing`
 
+    `github.com/bytedance/sonic/internal/native/types`
     `github.com/stretchr/testify/require`
 )
 
@@ -44,10 +49,39 @@ func TestValid(t *testing.T) {
         {`[]`, true},
         {`[1,2]`, true},
         {`{"so":"nic"}`, true},
+
+        /* trailing commas and garbage */
+        {`[1,]`, false},
+        {`{"a":1,}`, false},
+        {`[1]]`, false},
+        {`{} x`, false},
+        {"[1] \t\r\n", true},
+
+        /* truncated inputs */
+        {`{"a":1`, false},
+        {`{"a":`, false},
+        {`"abc`, false},
+        {`tru`, false},
+        {`1.`, false},
+        {`-`, false},
+
+        /* control characters must be escaped in strings */
+        {"\"a\tb\"", false},
+        {`"a\tb"`, true},
     }
     for _, tc := range testCase {
         require.Equal(t, tc.expected, Valid([]byte(tc.data)), tc.data)
+        require.Equal(t, json.Valid([]byte(tc.data)), Valid([]byte(tc.data)), tc.data)
     }
+
+    /* nested arrays up to the depth limit */
+    n := types.MAX_RECURSE
+    require.True(t, Valid([]byte(strings.Repeat("[", n) + strings.Repeat("]", n))))
+    require.False(t, Valid([]byte(strings.Repeat("[", n) + strings.Repeat("]", n - 1))))
+
+    /* validating does not allocate */
+    data := []byte(`{"a":[1,2.5,{"b":"c\u00e9"}],"d":null,"e":true}`)
+    require.Zero(t, testing.AllocsPerRun(100, func() { Valid(data) }))
 }
 
 
@@ -67,3 +101,205 @@ func TestIdent(t *testing.T) {
   "Age": 20
 }`, string(out))
 }
+
+
+type apiTestStruct struct {
+    A int               `json:"a"`
+    B string            `json:"b,omitempty"`
+    C []float64         `json:"c"`
+    D map[string]uint8  `json:"d"`
+    E *apiTestStruct    `json:"e,omitempty"`
+    F interface{}       `json:"f"`
+}
+
+var apiTestCases = []interface{} {
+    nil,
+    true,
+    int8(-8),
+    uint64(1<<63),
+    1.5,
+    float32(-0.25),
+    "<sonic & json>",
+    "\u2028\x01\"",
+    []int{1, 2, 3},
+    []string{},
+    [2]bool{true, false},
+    map[string]int{"b": 2, "a": 1},
+    map[int]string{3: "c", 1: "a"},
+    []byte("sonic"),
+    json.RawMessage(`{"raw":[1,2]}`),
+    json.Number("-1.5e3"),
+    apiTestStruct{},
+    apiTestStruct{A: 1, B: "b", C: []float64{0.1}, D: map[string]uint8{"x": 255}, E: &apiTestStruct{A: 2}, F: []interface{}{"f", 1.0, nil}},
+    &apiTestStruct{F: map[string]interface{}{"k": false}},
+}
+
+func TestMarshalUnmarshalStd(t *testing.T) {
+    for _, v := range apiTestCases {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+
+        /* encoding */
+        out, err := ConfigStd.Marshal(v)
+        require.NoError(t, err, "%#v", v)
+        require.Equal(t, string(exp), string(out), "%#v", v)
+        str, err := ConfigStd.MarshalToString(v)
+        require.NoError(t, err, "%#v", v)
+        require.Equal(t, string(exp), str, "%#v", v)
+        if v == nil {
+            continue
+        }
+
+        /* decoding, into a fresh value of the same type */
+        typ := reflect.TypeOf(v)
+        std := reflect.New(typ)
+        require.NoError(t, json.Unmarshal(exp, std.Interface()))
+        dv := reflect.New(typ)
+        require.NoError(t, Unmarshal(exp, dv.Interface()), "%s", exp)
+        require.Equal(t, std.Interface(), dv.Interface(), "%s", exp)
+        sv := reflect.New(typ)
+        require.NoError(t, UnmarshalString(string(exp), sv.Interface()), "%s", exp)
+        require.Equal(t, std.Interface(), sv.Interface(), "%s", exp)
+    }
+}
+
+func TestMarshalUnmarshalStdErrors(t *testing.T) {
+    _, err := Marshal(make(chan int))
+    require.Error(t, err)
+    _, err = MarshalString(func() {})
+    require.Error(t, err)
+
+    var v apiTestStruct
+    require.Error(t, Unmarshal([]byte(`{"a":"1"}`), &v))
+    require.Error(t, UnmarshalString(`{"a":1`, &v))
+    require.Error(t, UnmarshalString(`{}`, v))
+}
+
+func TestSetJITEnabled(t *testing.T) {
+    defer SetJITEnabled(true)
+    require.Equal(t, APIKind == UseSonicJSON, IsJITEnabled())
+
+    /* flip the JIT for every value, both paths agree byte by byte */
+    for i, v := range apiTestCases {
+        on := i % 2 == 0
+        SetJITEnabled(on)
+        require.Equal(t, on && APIKind == UseSonicJSON, IsJITEnabled())
+
+        out, err := ConfigStd.Marshal(v)
+        require.NoError(t, err, "%#v", v)
+        SetJITEnabled(!on)
+        std, err := ConfigStd.Marshal(v)
+        require.NoError(t, err, "%#v", v)
+        require.Equal(t, string(std), string(out), "%#v", v)
+        ind, err := ConfigStd.MarshalIndent(v, "", "  ")
+        require.NoError(t, err, "%#v", v)
+        SetJITEnabled(on)
+        exp, err := ConfigStd.MarshalIndent(v, "", "  ")
+        require.NoError(t, err, "%#v", v)
+        require.Equal(t, string(exp), string(ind), "%#v", v)
+        if v == nil {
+            continue
+        }
+
+        typ := reflect.TypeOf(v)
+        dv := reflect.New(typ)
+        require.NoError(t, ConfigStd.Unmarshal(out, dv.Interface()), "%s", out)
+        SetJITEnabled(!on)
+        sv := reflect.New(typ)
+        require.NoError(t, ConfigStd.Unmarshal(out, sv.Interface()), "%s", out)
+        require.Equal(t, dv.Interface(), sv.Interface(), "%s", out)
+    }
+
+    /* the errors of encoding/json are reported while disabled */
+    SetJITEnabled(false)
+    var v apiTestStruct
+    err := Unmarshal([]byte(`{"a":"1"}`), &v)
+    require.IsType(t, &json.UnmarshalTypeError{}, err)
+    _, err = Marshal(make(chan int))
+    require.IsType(t, &json.UnsupportedTypeError{}, err)
+}
+
+func TestMarshalToBuffer(t *testing.T) {
+    for _, v := range apiTestCases {
+        exp, err := Marshal(v)
+        require.NoError(t, err)
+
+        /* the encoding is appended after the bytes already in dst, maps are unsorted */
+        out, err := MarshalToBuffer([]byte("prefix"), v)
+        require.NoError(t, err, "%#v", v)
+        require.Equal(t, "prefix", string(out[:6]))
+        require.JSONEq(t, string(exp), string(out[6:]), "%#v", v)
+        out, err = MarshalToBuffer(out[:0], v)
+        require.NoError(t, err, "%#v", v)
+        require.JSONEq(t, string(exp), string(out), "%#v", v)
+    }
+
+    /* nothing is appended on errors */
+    dst := []byte("prefix")
+    out, err := MarshalToBuffer(dst, []interface{}{1, make(chan int)})
+    require.Error(t, err)
+    require.Equal(t, "prefix", string(out))
+}
+
+func TestMarshalConcurrently(t *testing.T) {
+    const workers = 16
+    const rounds = 200
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            v := apiTestStruct{A: i, B: strings.Repeat("b", i * 64), C: make([]float64, i)}
+            exp, err := json.Marshal(v)
+            if err != nil {
+                t.Error(err)
+                return
+            }
+
+            /* the results never share the pooled buffers with other calls */
+            var outs [][]byte
+            var buf []byte
+            for j := 0; j < rounds; j++ {
+                out, err := Marshal(v)
+                if err != nil {
+                    t.Error(err)
+                    return
+                }
+                outs = append(outs, out)
+                if buf, err = MarshalToBuffer(buf[:0], v); err != nil {
+                    t.Error(err)
+                    return
+                } else if string(buf) != string(exp) {
+                    t.Errorf("worker %d: expected %s, got %s", i, exp, buf)
+                    return
+                }
+            }
+            for _, out := range outs {
+                if string(out) != string(exp) {
+                    t.Errorf("worker %d: expected %s, got %s", i, exp, out)
+                    return
+                }
+            }
+        }(i)
+    }
+    wg.Wait()
+}
+
+func BenchmarkMarshal(b *testing.B) {
+    v := &apiTestStruct{A: 1, B: "b", C: []float64{0.1, 0.2}, E: &apiTestStruct{A: 2}}
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Marshal(v)
+    }
+}
+
+func BenchmarkMarshalToBuffer(b *testing.B) {
+    v := &apiTestStruct{A: 1, B: "b", C: []float64{0.1, 0.2}, E: &apiTestStruct{A: 2}}
+    var buf []byte
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        buf, _ = MarshalToBuffer(buf[:0], v)
+    }
+}
@@ -1938,3 +1938,73 @@ func TestNode_Pop(t *testing.T) {
         })
     }
 }
+
+func TestNodeLazyAccessors(t *testing.T) {
+    src := `{"i":-42,"f":1.5,"s":"a\"b","b":true,"a":[1,"x",null],"o":{"k":[{}]},"n":null}`
+    root := NewRaw(src)
+    require.NoError(t, root.Check())
+    require.True(t, root.IsRaw())
+
+    i, err := root.Get("i").Int64()
+    require.NoError(t, err)
+    require.Equal(t, int64(-42), i)
+    f, err := root.Get("f").Float64()
+    require.NoError(t, err)
+    require.Equal(t, 1.5, f)
+    s, err := root.Get("s").String()
+    require.NoError(t, err)
+    require.Equal(t, `a"b`, s)
+    b, err := root.Get("b").Bool()
+    require.NoError(t, err)
+    require.True(t, b)
+    a, err := root.Get("a").Array()
+    require.NoError(t, err)
+    require.Equal(t, []interface{}{float64(1), "x", nil}, a)
+    m, err := root.Get("o").Map()
+    require.NoError(t, err)
+    require.Equal(t, map[string]interface{}{"k": []interface{}{map[string]interface{}{}}}, m)
+    r, err := root.Get("s").Raw()
+    require.NoError(t, err)
+    require.Equal(t, `"a\"b"`, r)
+
+    /* containers can't be read as scalars and vice versa */
+    _, err = root.Get("a").Int64()
+    require.Equal(t, ErrUnsupportType, err)
+    _, err = root.Get("o").String()
+    require.Equal(t, ErrUnsupportType, err)
+    _, err = root.Get("i").Array()
+    require.Equal(t, ErrUnsupportType, err)
+    _, err = root.Get("b").Map()
+    require.Equal(t, ErrUnsupportType, err)
+    _, err = root.Get("s").Int64()
+    require.Error(t, err)
+    require.False(t, root.Get("missing").Exists())
+
+    /* parsing errors are carried by the node itself */
+    bad := NewRaw(`[1,2,}`)
+    require.Equal(t, V_ERROR, bad.Type())
+    require.NotEmpty(t, bad.Error())
+    _, err = bad.Int64()
+    require.Error(t, err)
+}
+
+func TestNodeLazySiblings(t *testing.T) {
+    /* visiting one child leaves its siblings unparsed */
+    root := NewRaw(`{"a":[1,"x",null],"o":{"k":[{}]}}`)
+    x := root.Get("a").Index(1)
+    require.True(t, x.IsRaw())
+    require.True(t, root.Get("o").IsRaw())
+    require.False(t, root.Get("a").IsRaw())
+
+    /* a malformed sibling is skipped over but never parsed */
+    for _, src := range []string{
+        `{"a":1,"bad":{"x":tru}}`,
+        `{"bad":{"x":tru},"a":1}`,
+    } {
+        n, err := NewSearcher(src).GetByPath("a")
+        require.NoError(t, err, src)
+        v, err := n.Int64()
+        require.NoError(t, err, src)
+        require.Equal(t, int64(1), v, src)
+    }
+}
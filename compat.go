@@ -19,9 +19,6 @@
 package sonic
 
 import (
-    `bytes`
-    `encoding/json`
-    `io`
     `reflect`
 
     `github.com/bytedance/sonic/option`
@@ -30,105 +27,25 @@ import (
 const apiKind = UseStdJSON
 
 type frozenConfig struct {
-    Config
+    stdConfig
 }
 
 // Froze convert the Config to API
 func (cfg Config) Froze() API {
-    api := &frozenConfig{Config: cfg}
+    api := &frozenConfig{stdConfig{Config: cfg}}
     return api
 }
 
-func (cfg frozenConfig) marshalOptions(val interface{}, prefix, indent string) ([]byte, error) {
-    w := bytes.NewBuffer([]byte{})
-    enc := json.NewEncoder(w)
-    enc.SetEscapeHTML(cfg.EscapeHTML)
-    enc.SetIndent(prefix, indent)
-    err := enc.Encode(val)
-	out := w.Bytes()
-
-	// json.Encoder always appends '\n' after encoding,
-	// which is not same with json.Marshal()
-	if len(out) > 0 && out[len(out)-1] == '\n' {
-		out = out[:len(out)-1]
-	}
-	return out, err
-}
-
-// Marshal is implemented by sonic
-func (cfg frozenConfig) Marshal(val interface{}) ([]byte, error) {
-    if !cfg.EscapeHTML {
-        return cfg.marshalOptions(val, "", "")
-    }
-    return json.Marshal(val)
-}
-
-// MarshalToString is implemented by sonic
-func (cfg frozenConfig) MarshalToString(val interface{}) (string, error) {
-    out, err := cfg.Marshal(val)
-    return string(out), err
-}
-
-// MarshalIndent is implemented by sonic
-func (cfg frozenConfig) MarshalIndent(val interface{}, prefix, indent string) ([]byte, error) {
-    if !cfg.EscapeHTML {
-        return cfg.marshalOptions(val, prefix, indent)
-    }
-    return json.MarshalIndent(val, prefix, indent)
-}
-
-// UnmarshalFromString is implemented by sonic
-func (cfg frozenConfig) UnmarshalFromString(buf string, val interface{}) error {
-    r := bytes.NewBufferString(buf)
-    dec := json.NewDecoder(r)
-    if cfg.UseNumber {
-        dec.UseNumber()
-    }
-    if cfg.DisallowUnknownFields {
-        dec.DisallowUnknownFields()
-    }
-    err := dec.Decode(val)
+// MarshalToBuffer appends the JSON encoding bytes of val to dst, encoded like
+// Marshal, and returns the extended buffer. On error, dst is returned unchanged.
+//
+// It always encodes with the options of ConfigDefault, like Marshal.
+func MarshalToBuffer(dst []byte, val interface{}) ([]byte, error) {
+    buf, err := Marshal(val)
     if err != nil {
-        return err
+        return dst, err
     }
-
-    // check the trailing chars
-    offset := dec.InputOffset()
-    if t, err := dec.Token(); !(t == nil && err == io.EOF) {
-        return &json.SyntaxError{ Offset: offset}
-    }
-    return nil
-}
-
-// Unmarshal is implemented by sonic
-func (cfg frozenConfig) Unmarshal(buf []byte, val interface{}) error {
-    return cfg.UnmarshalFromString(string(buf), val)
-}
-
-// NewEncoder is implemented by sonic
-func (cfg frozenConfig) NewEncoder(writer io.Writer) Encoder {
-    enc := json.NewEncoder(writer)
-    if !cfg.EscapeHTML {
-        enc.SetEscapeHTML(cfg.EscapeHTML)
-    }
-    return enc
-}
-
-// NewDecoder is implemented by sonic
-func (cfg frozenConfig) NewDecoder(reader io.Reader) Decoder {
-    dec := json.NewDecoder(reader)
-    if cfg.UseNumber {
-        dec.UseNumber()
-    }
-    if cfg.DisallowUnknownFields {
-        dec.DisallowUnknownFields()
-    }
-    return dec
-}
-
-// Valid is implemented by sonic
-func (cfg frozenConfig) Valid(data []byte) bool {
-    return json.Valid(data)
+    return append(dst, buf...), nil
 }
 
 // Pretouch compiles vt ahead-of-time to avoid JIT compilation on-the-fly, in
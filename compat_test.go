@@ -191,6 +191,82 @@ func TestGet(t *testing.T) {
 	}
 }
 
+type embeddedInner struct {
+	A int
+	B string `json:"b"`
+}
+
+type EmbeddedPtr struct {
+	P int `json:"p"`
+}
+
+type embeddedTagged struct {
+	T int
+}
+
+type embeddedLeft struct {
+	C int
+	D int
+}
+
+type embeddedRight struct {
+	C int
+	D int `json:"D"`
+}
+
+type embeddedHidden struct {
+	H int
+}
+
+type embeddedOuter struct {
+	embeddedInner
+	*EmbeddedPtr
+	embeddedTagged `json:"tagged"`
+	embeddedLeft
+	embeddedRight
+	embeddedHidden
+	X int
+	A int
+}
+
+func TestCompatEmbeddedStructs(t *testing.T) {
+	for _, v := range []embeddedOuter{
+		{},
+		{
+			embeddedInner:  embeddedInner{A: 1, B: "b"},
+			EmbeddedPtr:    &EmbeddedPtr{P: 2},
+			embeddedTagged: embeddedTagged{T: 3},
+			embeddedLeft:   embeddedLeft{C: 4, D: 5},
+			embeddedRight:  embeddedRight{C: 6, D: 7},
+			embeddedHidden: embeddedHidden{H: 8},
+			X:              9,
+			A:              10,
+		},
+	} {
+		jout, jerr := json.Marshal(v)
+		require.NoError(t, jerr)
+		sout, serr := Marshal(v)
+		require.NoError(t, serr)
+		require.Equal(t, string(jout), string(sout))
+
+		/* promoted fields are decoded back, allocating the embedded pointers */
+		var exp, act embeddedOuter
+		require.NoError(t, json.Unmarshal(jout, &exp))
+		require.NoError(t, Unmarshal(jout, &act))
+		require.Equal(t, exp, act)
+	}
+
+	/* the outer field shadows the promoted one, and ambiguous ones are dropped */
+	var v embeddedOuter
+	require.NoError(t, Unmarshal([]byte(`{"A":1,"C":2,"D":3,"T":4,"tagged":{"T":5},"p":6}`), &v))
+	require.Equal(t, 1, v.A)
+	require.Equal(t, 0, v.embeddedInner.A)
+	require.Equal(t, 0, v.embeddedLeft.C+v.embeddedRight.C+v.embeddedLeft.D)
+	require.Equal(t, 3, v.embeddedRight.D)
+	require.Equal(t, 5, v.embeddedTagged.T)
+	require.Equal(t, &EmbeddedPtr{P: 6}, v.EmbeddedPtr)
+}
+
 func TestUnmarshalWithTrailingChars(t *testing.T) {
 	for i, str := range []string{
 		"123",
@@ -2641,7 +2641,7 @@ type atofTest struct {
 // All tests are passed in Go encoding/json.
 var atoftests = []atofTest{
     {"1.234e", "", nil}, // error
-    {"1i", "1", nil},    // pass
+    {"1i", "1", nil},    // error, invalid char after number
     {"1", "1", nil},
     {"1e23", "1e+23", nil},
     {"1E23", "1e+23", nil},
@@ -2758,6 +2758,14 @@ func TestDecodeFloat(t *testing.T) {
         var sonicout, stdout float64
         sonicerr := decoder.NewDecoder(tt.in).Decode(&sonicout)
         stderr := json.NewDecoder(strings.NewReader(tt.in)).Decode(&stdout)
+        if tt.in == "1i" {
+            // encoding/json stops at the end of the number, but sonic
+            // rejects the invalid char following it
+            if sonicerr == nil {
+                t.Fatalf("Test %d, %#v\nexpect error, got nil\n", i, tt.in)
+            }
+            continue
+        }
         if !reflect.DeepEqual(sonicout, stdout) {
             t.Fatalf("Test %d, %#v\ngot:\n   %#v\nexp:\n   %#v\n", i, tt.in, sonicout, stdout)
         }
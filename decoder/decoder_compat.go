@@ -27,6 +27,8 @@ import (
 	"unsafe"
 
 	"github.com/bytedance/sonic/internal/decoder/consts"
+	"github.com/bytedance/sonic/internal/decoder/errors"
+	"github.com/bytedance/sonic/internal/decoder/frame"
 	"github.com/bytedance/sonic/internal/native/types"
 	"github.com/bytedance/sonic/option"
 	"github.com/bytedance/sonic/internal/compat"
@@ -47,6 +49,16 @@ const (
      _F_allow_control   = consts.F_allow_control
      _F_no_validate_json = consts.F_no_validate_json
      _F_case_sensitive  = consts.F_case_sensitive
+     _F_bool_as_int     = consts.F_bool_as_int
+     _F_struct_as_array = consts.F_struct_as_array
+     _F_flexible_time   = consts.F_flexible_time
+     _F_limit_key_length = consts.F_limit_key_length
+     _F_report_more_data = consts.F_report_more_data
+     _F_pointer_refs    = consts.F_pointer_refs
+     _F_error_snippet   = consts.F_error_snippet
+     _F_object_as_pairs = consts.F_object_as_pairs
+     _F_limit_dynamic_depth = consts.F_limit_dynamic_depth
+     _F_grow_stack      = consts.F_grow_stack
 )
 
 type Options uint64
@@ -60,8 +72,22 @@ const (
      OptionValidateString   Options = 1 << _F_validate_string
      OptionNoValidateJSON   Options = 1 << _F_no_validate_json
      OptionCaseSensitive    Options = 1 << _F_case_sensitive
+     OptionBoolAsInt        Options = 1 << _F_bool_as_int
+     OptionStructAsArray    Options = 1 << _F_struct_as_array
+     OptionFlexibleTime     Options = 1 << _F_flexible_time
+     OptionLimitKeyLength   Options = 1 << _F_limit_key_length
+     OptionReportMoreData   Options = 1 << _F_report_more_data
+     OptionPointerRefs      Options = 1 << _F_pointer_refs
+     OptionErrorSnippet     Options = 1 << _F_error_snippet
+     OptionObjectAsPairs    Options = 1 << _F_object_as_pairs
+     OptionLimitDynamicDepth Options = 1 << _F_limit_dynamic_depth
+     OptionGrowStack        Options = 1 << _F_grow_stack
 )
 
+// ErrMoreData is returned instead of a SyntaxError when OptionReportMoreData
+// is set and the input is a valid but incomplete prefix of a JSON value.
+var ErrMoreData = errors.ErrMoreData
+
 func (self *Decoder) SetOptions(opts Options) {
      if (opts & OptionUseNumber != 0) && (opts & OptionUseInt64 != 0) {
          panic("can't set OptionUseInt64 and OptionUseNumber both!")
@@ -125,7 +151,19 @@ func (self *Decoder) Decode(val interface{}) error {
    if (self.f & uint64(OptionDisableUnknown)) != 0  {
        dec.DisallowUnknownFields()
    }
-   return dec.Decode(val)
+   err := dec.Decode(val)
+   if (self.f & uint64(OptionReportMoreData)) != 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+       err = ErrMoreData
+   }
+   if err != nil && err != ErrMoreData && (self.f & uint64(OptionErrorSnippet)) != 0 {
+       pos := int(dec.InputOffset())
+       switch e := err.(type) {
+           case *json.SyntaxError        : pos = int(e.Offset)
+           case *json.UnmarshalTypeError : pos = int(e.Offset)
+       }
+       err = errors.ErrorSnippet(self.s, pos, err)
+   }
+   return err
 }
 
 // UseInt64 indicates the Decoder to unmarshal an integer into an interface{} as an
@@ -155,6 +193,18 @@ func (self *Decoder) DisallowUnknownFields() {
      self.f |= 1 << _F_disable_unknown
 }
 
+// CaseSensitive indicates the Decoder whether to match object keys with struct fields
+// case-sensitively. It is off by default, matching encoding/json: a key which matches
+// no field exactly is decoded into the field whose name equals it under case folding.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func (self *Decoder) CaseSensitive(f bool) {
+     if f {
+         self.f |= 1 << _F_case_sensitive
+     } else {
+         self.f &^= 1 << _F_case_sensitive
+     }
+}
+
 // CopyString indicates the Decoder to decode string values by copying instead of referring.
 func (self *Decoder) CopyString() {
      self.f |= 1 << _F_copy_string
@@ -167,6 +217,24 @@ func (self *Decoder) ValidateString() {
      self.f |= 1 << _F_validate_string
 }
 
+// SetFieldNameResolver sets a function which maps each object key to the
+// name of the struct field it should be decoded into.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func (self *Decoder) SetFieldNameResolver(fn func(string) string) {
+}
+
+// SetMaxDepth limits the nesting of arrays and objects decoded into typed values.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func (self *Decoder) SetMaxDepth(n int) {
+}
+
+// RecordUnknownFields indicates the Decoder to append the dotted paths of the
+// object keys which match no struct field (eg. `user.address.zip4`) to paths
+// after decoding.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func (self *Decoder) RecordUnknownFields(paths *[]string) {
+}
+
 // Pretouch compiles vt ahead-of-time to avoid JIT compilation on-the-fly, in
 // order to reduce the first-hit latency.
 //
@@ -185,6 +253,33 @@ func NewStreamDecoder(r io.Reader) *StreamDecoder {
    return json.NewDecoder(r)
 }
 
+// RegisterSetter maps the object key to the exported setter method of *vt,
+// which makes it possible to decode into types with private fields.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func RegisterSetter(vt reflect.Type, key string, method string) error {
+     return nil
+}
+
+// CoercionFunc converts the raw JSON value src into dst, which is settable,
+// and reports whether it did.
+type CoercionFunc func(dst reflect.Value, src string) bool
+
+// RegisterCoercion installs the hook which is called on a type mismatch.
+// NOTICE: it is not supported by the compatible decoder and will be ignored.
+func RegisterCoercion(fn CoercionFunc) {
+}
+
+// DecodeFramed reads one length-prefixed JSON frame from r, and stores the
+// result in the value pointed to by v. The frame starts with the length of the
+// JSON as a 4-byte big-endian integer, and the JSON must consume the whole frame.
+func DecodeFramed(r io.Reader, v interface{}) error {
+     buf, err := frame.Read(r)
+     if err != nil {
+         return err
+     }
+     return json.Unmarshal(buf, v)
+}
+
 // SyntaxError represents json syntax error
 type SyntaxError json.SyntaxError
 
@@ -199,3 +294,6 @@ func (s SyntaxError) Error() string {
 
 // MismatchTypeError represents mismatching between json and object
 type MismatchTypeError json.UnmarshalTypeError
+
+// SnippetError wraps a decoding error with the raw JSON around it, see OptionErrorSnippet
+type SnippetError = errors.SnippetError
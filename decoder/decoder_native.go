@@ -25,6 +25,9 @@ import (
 )
 
 // Decoder is the decoder context object
+//
+// Unless OptionCopyString is set, the decoded strings and json.RawMessage values
+// refer to the source JSON, so the json.RawMessage values must not be modified.
 type Decoder = api.Decoder
 
 // SyntaxError represents json syntax error
@@ -33,6 +36,9 @@ type SyntaxError = api.SyntaxError
 // MismatchTypeError represents mismatching between json and object
 type MismatchTypeError = api.MismatchTypeError
 
+// SnippetError wraps a decoding error with the raw JSON around it, see OptionErrorSnippet
+type SnippetError = api.SnippetError
+
 // Options for decode.
 type Options = api.Options
 
@@ -45,8 +51,26 @@ const (
     OptionValidateString   Options = api.OptionValidateString
     OptionNoValidateJSON   Options = api.OptionNoValidateJSON
     OptionCaseSensitive    Options = api.OptionCaseSensitive
+    OptionBoolAsInt        Options = api.OptionBoolAsInt
+    OptionStructAsArray    Options = api.OptionStructAsArray
+    OptionFlexibleTime     Options = api.OptionFlexibleTime
+    OptionLimitKeyLength   Options = api.OptionLimitKeyLength
+    OptionReportMoreData   Options = api.OptionReportMoreData
+    OptionPointerRefs      Options = api.OptionPointerRefs
+    OptionErrorSnippet     Options = api.OptionErrorSnippet
+    OptionObjectAsPairs    Options = api.OptionObjectAsPairs
+    OptionLimitDynamicDepth Options = api.OptionLimitDynamicDepth
+    OptionGrowStack        Options = api.OptionGrowStack
 )
 
+// ErrMoreData is returned instead of a SyntaxError when OptionReportMoreData
+// is set and the input is a valid but incomplete prefix of a JSON value.
+var ErrMoreData = api.ErrMoreData
+
+// CoercionFunc converts the raw JSON value src into dst, which is settable,
+// and reports whether it did.
+type CoercionFunc = api.CoercionFunc
+
 // StreamDecoder is the decoder context object for streaming input.
 type StreamDecoder = api.StreamDecoder
 
@@ -69,4 +93,18 @@ var (
     // Skip skips only one json value, and returns first non-blank character position and its ending position if it is valid.
     // Otherwise, returns negative error code using start and invalid character position using end
     Skip = api.Skip
+
+    // DecodeFramed reads one length-prefixed JSON frame from r, and stores the
+    // result in the value pointed to by v. The frame starts with the length of the
+    // JSON as a 4-byte big-endian integer, and the JSON must consume the whole frame.
+    DecodeFramed = api.DecodeFramed
+
+    // RegisterSetter maps the object key to the exported setter method of *vt,
+    // which makes it possible to decode into types with private fields.
+    // Setters must be registered before vt is decoded for the first time.
+    RegisterSetter = api.RegisterSetter
+
+    // RegisterCoercion installs the hook which is called on a type mismatch,
+    // before the MismatchTypeError is reported. A nil hook removes it.
+    RegisterCoercion = api.RegisterCoercion
 )
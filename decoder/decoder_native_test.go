@@ -20,15 +20,26 @@
 package decoder
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	_ "reflect"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	_ "strings"
 	"testing"
 	"time"
+	"unsafe"
 
+	"github.com/bytedance/sonic/encoder"
+	"github.com/bytedance/sonic/internal/decoder/consts"
+	"github.com/bytedance/sonic/internal/envs"
+	"github.com/bytedance/sonic/internal/native/types"
 	"github.com/bytedance/sonic/internal/rt"
+	"github.com/bytedance/sonic/option"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -400,6 +411,1261 @@ func TestDecoder_SetOption(t *testing.T) {
     assert.Equal(t, v, int64(123))
 }
 
+func TestDecoder_TrailingCharsAfterNumber(t *testing.T) {
+    var i int
+    err := NewDecoder("12abc").Decode(&i)
+    require.Error(t, err)
+    se, ok := err.(SyntaxError)
+    require.True(t, ok, err)
+    assert.Equal(t, 2, se.Pos)
+
+    var f float64
+    require.Error(t, NewDecoder("1.5abc").Decode(&f))
+    var n json.Number
+    require.Error(t, NewDecoder("12abc").Decode(&n))
+
+    for _, src := range []string{"12,", "12 ", "12", "12]", "12}"} {
+        i = 0
+        require.NoError(t, NewDecoder(src).Decode(&i), src)
+        assert.Equal(t, 12, i, src)
+    }
+
+    var obj struct {
+        A int `json:"a"`
+        B int `json:"b,string"`
+    }
+    require.NoError(t, NewDecoder(`{"a":1,"b":"2"}`).Decode(&obj))
+    assert.Equal(t, 1, obj.A)
+    assert.Equal(t, 2, obj.B)
+    require.Error(t, NewDecoder(`{"a":1x,"b":"2"}`).Decode(&obj))
+
+    var m map[int]int
+    require.NoError(t, NewDecoder(`{"1":2}`).Decode(&m))
+    assert.Equal(t, map[int]int{1: 2}, m)
+}
+
+func TestDecoder_OptionBoolAsInt(t *testing.T) {
+    type T struct {
+        N int   `json:"n"`
+        U uint8 `json:"u"`
+    }
+    var obj T
+    err := NewDecoder(`{"n":true}`).Decode(&obj)
+    require.Error(t, err)
+    _, ok := err.(*MismatchTypeError)
+    require.True(t, ok, err)
+    require.Equal(t, 0, obj.N)
+
+    obj = T{}
+    d := NewDecoder(`{"n":true,"u":false}`)
+    d.SetOptions(OptionBoolAsInt)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{N: 1, U: 0}, obj)
+
+    obj = T{U: 1}
+    d = NewDecoder(`{"n":12,"u":false}`)
+    d.SetOptions(OptionBoolAsInt)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{N: 12, U: 0}, obj)
+
+    d = NewDecoder(`{"n":fals}`)
+    d.SetOptions(OptionBoolAsInt)
+    require.Error(t, d.Decode(&obj))
+}
+
+func TestDecoder_OptionStructAsArray(t *testing.T) {
+    type T struct {
+        A int    `json:"a"`
+        B string `json:"b"`
+        C bool   `json:"c"`
+    }
+    var obj T
+    err := NewDecoder(`[1,"x",true]`).Decode(&obj)
+    require.Error(t, err)
+    _, ok := err.(*MismatchTypeError)
+    require.True(t, ok, err)
+
+    obj = T{}
+    d := NewDecoder(`[1,"x",true]`)
+    d.SetOptions(OptionStructAsArray)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{A: 1, B: "x", C: true}, obj)
+
+    /* missing elements keep the fields untouched, and extra ones are skipped */
+    obj = T{C: true}
+    d = NewDecoder(`[ 2 , "y" ]`)
+    d.SetOptions(OptionStructAsArray)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{A: 2, B: "y", C: true}, obj)
+
+    var objs []T
+    d = NewDecoder(`[[3,"z",false,{}],{"a":4,"b":"w","c":true},[]]`)
+    d.SetOptions(OptionStructAsArray)
+    require.NoError(t, d.Decode(&objs))
+    require.Equal(t, []T{{3, "z", false}, {4, "w", true}, {}}, objs)
+
+    d = NewDecoder(`[1,2,true]`)
+    d.SetOptions(OptionStructAsArray)
+    err = d.Decode(&obj)
+    require.Error(t, err)
+}
+
+func TestDecoder_SetFieldNameResolver(t *testing.T) {
+    type Inner struct {
+        X int `json:"X"`
+    }
+    type T struct {
+        ID    int               `json:"ID"`
+        Name  string            `json:"NAME"`
+        Inner Inner             `json:"INNER"`
+        M     map[string]string `json:"M"`
+    }
+    src := `{"id":1,"name":"a","inner":{"x":2},"m":{"k":"v"}}`
+
+    /* keys are not matched case-insensitively */
+    var obj T
+    d := NewDecoder(src)
+    d.SetOptions(OptionCaseSensitive)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{}, obj)
+
+    /* keys are upper-cased before matching, map keys are kept */
+    calls := 0
+    d = NewDecoder(src)
+    d.SetOptions(OptionCaseSensitive)
+    d.SetFieldNameResolver(func(key string) string {
+        calls++
+        return strings.ToUpper(key)
+    })
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{ID: 1, Name: "a", Inner: Inner{X: 2}, M: map[string]string{"k": "v"}}, obj)
+    require.Equal(t, 5, calls)
+
+    /* the mapping is cached by the resolver */
+    obj = T{}
+    d.Reset(src)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, 1, obj.ID)
+    require.Equal(t, 5, calls)
+
+    /* nil disables the resolver */
+    obj = T{}
+    d.SetFieldNameResolver(nil)
+    d.Reset(src)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, T{}, obj)
+}
+
+func TestDecoder_DecodeFramed(t *testing.T) {
+    type T struct {
+        A int    `json:"a"`
+        B string `json:"b"`
+    }
+    frame := func(js string) []byte {
+        var hdr [4]byte
+        binary.BigEndian.PutUint32(hdr[:], uint32(len(js)))
+        return append(hdr[:], js...)
+    }
+
+    var buf bytes.Buffer
+    buf.Write(frame(`{"a":1,"b":"x"}`))
+    buf.Write(frame(` {"a":2,"b":"y"} `))
+
+    var v T
+    require.NoError(t, DecodeFramed(&buf, &v))
+    require.Equal(t, T{A: 1, B: "x"}, v)
+    require.NoError(t, DecodeFramed(&buf, &v))
+    require.Equal(t, T{A: 2, B: "y"}, v)
+    require.Equal(t, io.EOF, DecodeFramed(&buf, &v))
+
+    /* the JSON must consume the whole frame */
+    require.Error(t, DecodeFramed(bytes.NewReader(frame(`{"a":1}{}`)), &v))
+
+    /* truncated frames */
+    require.Equal(t, io.ErrUnexpectedEOF, DecodeFramed(bytes.NewReader([]byte{0, 0}), &v))
+    require.Equal(t, io.ErrUnexpectedEOF, DecodeFramed(bytes.NewReader(frame(`{"a":1}`)[:6]), &v))
+
+    /* the header alone does not allocate the frame */
+    var before, after runtime.MemStats
+    runtime.ReadMemStats(&before)
+    require.Equal(t, io.ErrUnexpectedEOF, DecodeFramed(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, '{', '}'}), &v))
+    runtime.ReadMemStats(&after)
+    require.Less(t, after.TotalAlloc - before.TotalAlloc, uint64(1 << 20))
+}
+
+func TestDecoder_MergeIntoExistingMap(t *testing.T) {
+    m := map[string]int{"a": 1, "b": 0}
+    require.NoError(t, NewDecoder(`{"b":2}`).Decode(&m))
+    require.Equal(t, map[string]int{"a": 1, "b": 2}, m)
+
+    type T struct {
+        M map[string]interface{} `json:"m"`
+        K map[int]string         `json:"k"`
+    }
+    obj := T{
+        M: map[string]interface{}{"a": "x"},
+        K: map[int]string{1: "x"},
+    }
+    require.NoError(t, NewDecoder(`{"m":{"b":2},"k":{"2":"y"}}`).Decode(&obj))
+    require.Equal(t, map[string]interface{}{"a": "x", "b": float64(2)}, obj.M)
+    require.Equal(t, map[int]string{1: "x", 2: "y"}, obj.K)
+
+    /* consistent with encoding/json */
+    exp := map[string]int{"a": 1}
+    require.NoError(t, json.Unmarshal([]byte(`{"b":2}`), &exp))
+    m = map[string]int{"a": 1}
+    require.NoError(t, NewDecoder(`{"b":2}`).Decode(&m))
+    require.Equal(t, exp, m)
+}
+
+func TestDecoder_ReuseSliceBackingArray(t *testing.T) {
+    buf := make([]int, 0, 8)
+    ptr := &buf[:1][0]
+
+    v := buf
+    require.NoError(t, NewDecoder(`[1,2,3]`).Decode(&v))
+    require.Equal(t, []int{1, 2, 3}, v)
+    require.Equal(t, 8, cap(v))
+    require.True(t, ptr == &v[0])
+
+    /* empty arrays keep the backing array too */
+    require.NoError(t, NewDecoder(`[]`).Decode(&v))
+    require.Equal(t, []int{}, v)
+    require.Equal(t, 8, cap(v))
+    require.True(t, ptr == &v[:1][0])
+
+    /* grows only beyond the capacity */
+    require.NoError(t, NewDecoder(`[1,2,3,4,5,6,7,8,9]`).Decode(&v))
+    require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, v)
+    require.False(t, ptr == &v[0])
+
+    /* nil slices still decode into empty ones */
+    var n []int
+    require.NoError(t, NewDecoder(`[]`).Decode(&n))
+    require.NotNil(t, n)
+    require.Len(t, n, 0)
+}
+
+func BenchmarkDecoder_ReuseSlice(b *testing.B) {
+    type T struct {
+        A int    `json:"a"`
+        B string `json:"b"`
+    }
+    var src = `[{"a":1,"b":"x"},{"a":2,"b":"y"},{"a":3,"b":"z"},{"a":4,"b":"w"}]`
+    b.Run("new", func(b *testing.B) {
+        b.ReportAllocs()
+        for i := 0; i < b.N; i++ {
+            var v []T
+            _ = NewDecoder(src).Decode(&v)
+        }
+    })
+    b.Run("reuse", func(b *testing.B) {
+        b.ReportAllocs()
+        v := make([]T, 0, 4)
+        for i := 0; i < b.N; i++ {
+            v = v[:0]
+            _ = NewDecoder(src).Decode(&v)
+        }
+    })
+}
+
+func BenchmarkDecoder_SmallObject(b *testing.B) {
+    type Strings struct {
+        ID   string `json:"id"`
+        Name string `json:"name"`
+    }
+    type Mixed struct {
+        ID    int     `json:"id"`
+        Name  string  `json:"name"`
+        Score float64 `json:"score"`
+        OK    bool    `json:"ok"`
+    }
+    b.Run("strings", func(b *testing.B) {
+        var src = `{"id":"u-1","name":"alice"}`
+        var v Strings
+        b.SetBytes(int64(len(src)))
+        b.ReportAllocs()
+        for i := 0; i < b.N; i++ {
+            _ = NewDecoder(src).Decode(&v)
+        }
+    })
+    b.Run("mixed", func(b *testing.B) {
+        var src = `{"id":1,"name":"alice","score":9.5,"ok":true}`
+        var v Mixed
+        b.SetBytes(int64(len(src)))
+        b.ReportAllocs()
+        for i := 0; i < b.N; i++ {
+            _ = NewDecoder(src).Decode(&v)
+        }
+    })
+}
+
+func TestDecoder_RecordUnknownFields(t *testing.T) {
+    type Address struct {
+        City string `json:"city"`
+        Zip  string `json:"zip"`
+    }
+    type User struct {
+        Name    string            `json:"name"`
+        Address *Address          `json:"address"`
+        Tags    map[string]string `json:"tags"`
+    }
+    type T struct {
+        User  User   `json:"user"`
+        Items []User `json:"items"`
+    }
+    src := `{
+        "user": {
+            "name": "a",
+            "address": {"city": "b", "zip": "1", "zip4": "2"},
+            "tags": {"unknown": "map keys are known"},
+            "age": {"nested": 1}
+        },
+        "items": [{"name": "c"}, {"NAME": "d", "x": [1]}],
+        "version": 2
+    }`
+
+    var obj T
+    var paths []string
+    d := NewDecoder(src)
+    d.RecordUnknownFields(&paths)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, []string{"user.address.zip4", "user.age", "items[1].x", "version"}, paths)
+    require.Equal(t, "b", obj.User.Address.City)
+    require.Equal(t, "d", obj.Items[1].Name)
+
+    /* keys only differing in case are unknown if case-sensitive */
+    paths = paths[:0]
+    obj = T{}
+    d = NewDecoder(`{"user":{"Name":"a","address":{"zip4":"1"}}}`)
+    d.SetOptions(OptionCaseSensitive)
+    d.RecordUnknownFields(&paths)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, []string{"user.Name", "user.address.zip4"}, paths)
+}
+
+type setterUser struct {
+    ID   int `json:"id"`
+    name string
+    age  int
+}
+
+func (self *setterUser) SetName(name string) {
+    self.name = name
+}
+
+func (self *setterUser) SetAge(age int) error {
+    if age < 0 {
+        return fmt.Errorf("invalid age: %d", age)
+    }
+    self.age = age
+    return nil
+}
+
+func TestDecoder_RegisterSetter(t *testing.T) {
+    vt := reflect.TypeOf(setterUser{})
+    require.NoError(t, RegisterSetter(vt, "name", "SetName"))
+    require.NoError(t, RegisterSetter(vt, "age", "SetAge"))
+    require.Error(t, RegisterSetter(vt, "id", "Missing"))
+
+    var obj []setterUser
+    require.NoError(t, NewDecoder(`[{"id":1,"name":"foo","age":18},{"NAME":"bar"}]`).Decode(&obj))
+    require.Equal(t, []setterUser{{ID: 1, name: "foo", age: 18}, {name: "bar"}}, obj)
+
+    /* errors returned by the setter abort decoding */
+    var v setterUser
+    err := NewDecoder(`{"age":-1}`).Decode(&v)
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "invalid age: -1")
+}
+
+func TestDecoder_OptionFlexibleTime(t *testing.T) {
+    type T struct {
+        At  time.Time      `json:"at"`
+        Ptr *time.Time     `json:"ptr"`
+        TTL time.Duration  `json:"ttl"`
+    }
+    want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+    for _, src := range []string{
+        `{"at":"2024-01-02T03:04:05Z","ptr":1704164645,"ttl":"1m30s"}`,
+        `{"at":1704164645,"ptr":"1704164645000","ttl":90000000000}`,
+        `{"at":1704164645000,"ptr":"2024-01-02 03:04:05","ttl":"90s"}`,
+    } {
+        var obj T
+        d := NewDecoder(src)
+        d.SetOptions(OptionFlexibleTime)
+        require.NoError(t, d.Decode(&obj), src)
+        require.True(t, want.Equal(obj.At), src)
+        require.True(t, want.Equal(*obj.Ptr), src)
+        require.Equal(t, 90 * time.Second, obj.TTL, src)
+    }
+
+    /* unix epochs are rejected without the option */
+    var obj T
+    err := NewDecoder(`{"at":1704164645}`).Decode(&obj)
+    require.Error(t, err)
+
+    /* values matching no format are mismatched */
+    obj = T{}
+    d := NewDecoder(`{"at":"yesterday","ttl":"1m"}`)
+    d.SetOptions(OptionFlexibleTime)
+    err = d.Decode(&obj)
+    require.IsType(t, &MismatchTypeError{}, err)
+    require.Equal(t, time.Minute, obj.TTL)
+}
+
+func TestDecoder_EscapedStructKeys(t *testing.T) {
+    /* tags with control characters are invalid, the field name is used instead */
+    type T struct {
+        X int `json:"a\tb"`
+        Y int `json:"a b"`
+        Z int `json:"a/b"`
+    }
+    for _, src := range []string{
+        `{"a\tb":1}`,
+        `{"a\u0009b":1,"X":2}`,
+        `{"a\u0020b":3}`,
+        `{"a\/b":4,"A\/B":5}`,
+        `{"\u0061\/\u0062":6}`,
+    } {
+        var exp, obj T
+        require.NoError(t, json.Unmarshal([]byte(src), &exp), src)
+        require.NoError(t, NewDecoder(src).Decode(&obj), src)
+        require.Equal(t, exp, obj, src)
+    }
+
+    /* keys are unescaped before they are matched */
+    var keys []string
+    var obj T
+    d := NewDecoder(`{"a\tb":1}`)
+    d.SetFieldNameResolver(func(key string) string {
+        keys = append(keys, key)
+        if key == "a\tb" {
+            return "a b"
+        }
+        return key
+    })
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, []string{"a\tb"}, keys)
+    require.Equal(t, T{Y: 1}, obj)
+}
+
+func TestDecoder_OptionLimitKeyLength(t *testing.T) {
+    type T struct {
+        A int `json:"a"`
+    }
+    long := strings.Repeat("k", 1 << 20)
+    src := `{"a":1,"` + long + `":2}`
+
+    /* overlong keys are skipped as unknown fields by default */
+    var v T
+    require.NoError(t, NewDecoder(src).Decode(&v))
+    require.Equal(t, T{A: 1}, v)
+
+    v = T{}
+    d := NewDecoder(src)
+    d.SetOptions(OptionLimitKeyLength)
+    err := d.Decode(&v)
+    require.Error(t, err)
+    se, ok := err.(SyntaxError)
+    require.True(t, ok, err)
+    assert.Equal(t, "object key too long", se.Message())
+    assert.Equal(t, 1, v.A)
+
+    /* keys within the limit and map keys are not affected */
+    var w struct {
+        A int            `json:"a"`
+        M map[string]int `json:"m"`
+    }
+    key := strings.Repeat("k", 4096)
+    d = NewDecoder(`{"` + key + `":0,"m":{"` + long + `":1},"a":2}`)
+    d.SetOptions(OptionLimitKeyLength)
+    require.NoError(t, d.Decode(&w))
+    assert.Equal(t, 2, w.A)
+    assert.Equal(t, map[string]int{long: 1}, w.M)
+}
+
+func TestDecoder_OptionReportMoreData(t *testing.T) {
+    type T struct {
+        A interface{} `json:"a"`
+        B []float64   `json:"b"`
+    }
+    decode := func(src string, opts Options) error {
+        var v T
+        d := NewDecoder(src)
+        d.SetOptions(opts)
+        return d.Decode(&v)
+    }
+
+    /* truncated input needs more data */
+    for _, src := range []string{`{"a":`, `{"a"`, `{`, `{"a":1,`, `{"a":"x`, `{"a":tr`, `{"b":[1,`, `{"b":[1.`, `{"b":[-`, `{"b":[1e+`, ``} {
+        require.Equal(t, ErrMoreData, decode(src, OptionReportMoreData), src)
+        _, ok := decode(src, 0).(SyntaxError)
+        require.True(t, ok, src)
+    }
+
+    /* malformed input is still a syntax error */
+    for _, src := range []string{`{"a":}`, `{"a":1]`, `{"a":trux`, `{"b":[1..`, `{"b":[01`, `{"b":[1x`} {
+        err := decode(src, OptionReportMoreData)
+        _, ok := err.(SyntaxError)
+        require.True(t, ok, "%s: %v", src, err)
+    }
+
+    require.NoError(t, decode(`{"a":1}`, OptionReportMoreData))
+}
+
+func TestDecoder_RegisterCoercion(t *testing.T) {
+    type T struct {
+        N int         `json:"n"`
+        U uint8       `json:"u"`
+        F float64     `json:"f"`
+        B bool        `json:"b"`
+        X int         `json:"x"`
+        M map[int]int `json:"m"`
+    }
+    RegisterCoercion(func(dst reflect.Value, src string) bool {
+        s, err := strconv.Unquote(src)
+        if err != nil {
+            return false
+        }
+        switch dst.Kind() {
+        case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+            n, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+            if err != nil {
+                return false
+            }
+            dst.SetInt(n)
+        case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+            n, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+            if err != nil {
+                return false
+            }
+            dst.SetUint(n)
+        case reflect.Float32, reflect.Float64:
+            f, err := strconv.ParseFloat(s, dst.Type().Bits())
+            if err != nil {
+                return false
+            }
+            dst.SetFloat(f)
+        case reflect.Bool:
+            b, err := strconv.ParseBool(s)
+            if err != nil {
+                return false
+            }
+            dst.SetBool(b)
+        default:
+            return false
+        }
+        return true
+    })
+    defer RegisterCoercion(nil)
+
+    var v T
+    require.NoError(t, NewDecoder(`{"n":"5","u":"7","f":"1.5","b":"true","x":1}`).Decode(&v))
+    require.Equal(t, T{N: 5, U: 7, F: 1.5, B: true, X: 1}, v)
+
+    /* values the hook rejects are still mismatches, and decoding goes on */
+    v = T{}
+    err := NewDecoder(`{"n":"abc","x":2}`).Decode(&v)
+    _, ok := err.(*MismatchTypeError)
+    require.True(t, ok, "%v", err)
+    require.Equal(t, 2, v.X)
+
+    /* a rescued value does not hide an earlier mismatch */
+    v = T{}
+    err = NewDecoder(`{"x":"abc","n":"5"}`).Decode(&v)
+    _, ok = err.(*MismatchTypeError)
+    require.True(t, ok, "%v", err)
+    require.Equal(t, 5, v.N)
+
+    /* without the hook, the mismatch is reported */
+    RegisterCoercion(nil)
+    v = T{}
+    err = NewDecoder(`{"n":"5"}`).Decode(&v)
+    _, ok = err.(*MismatchTypeError)
+    require.True(t, ok, "%v", err)
+}
+
+func TestDecoder_PointerRefs(t *testing.T) {
+    type Node struct {
+        V    int   `json:"v"`
+        Next *Node `json:"next"`
+    }
+    a := &Node{V: 1}
+    a.Next = &Node{V: 2, Next: a}
+    buf, err := encoder.Encode(a, encoder.PointerRefs)
+    require.NoError(t, err)
+
+    dec := NewDecoder(string(buf))
+    dec.SetOptions(OptionPointerRefs)
+    var v *Node
+    require.NoError(t, dec.Decode(&v))
+    require.Equal(t, 1, v.V)
+    require.Equal(t, 2, v.Next.V)
+    require.Same(t, v, v.Next.Next)
+
+    /* shared pointers, with whitespaces around the reference */
+    dec = NewDecoder(`[{"v":3},{"v":4,"next":{ "$ref" : 1 }},{"$ref":1}]`)
+    dec.SetOptions(OptionPointerRefs)
+    var vs []*Node
+    require.NoError(t, dec.Decode(&vs))
+    require.Len(t, vs, 3)
+    require.Same(t, vs[0], vs[1].Next)
+    require.Same(t, vs[0], vs[2])
+
+    /* unknown reference IDs */
+    dec = NewDecoder(`{"v":1,"next":{"$ref":2}}`)
+    dec.SetOptions(OptionPointerRefs)
+    v = nil
+    require.Error(t, dec.Decode(&v))
+
+    /* without the option, references are plain objects */
+    v = nil
+    require.NoError(t, NewDecoder(`{"v":1,"next":{"$ref":1}}`).Decode(&v))
+    require.Equal(t, &Node{V: 1, Next: &Node{}}, v)
+}
+
+func TestDecoder_ErrorSnippet(t *testing.T) {
+    type User struct {
+        Name string `json:"name"`
+        Age  int    `json:"age"`
+        City string `json:"city"`
+    }
+    src := `{"name":"alice","nickname":"al","age":12x,"city":"somewhere far away"}`
+    pos := strings.Index(src, "x")
+
+    /* syntax errors */
+    var v User
+    dec := NewDecoder(src)
+    dec.SetOptions(OptionErrorSnippet)
+    err := dec.Decode(&v)
+    require.Error(t, err)
+    se, ok := err.(*SnippetError)
+    require.True(t, ok, "%T", err)
+    require.Equal(t, pos, se.Pos)
+    require.Equal(t, src[pos-20:pos+20], se.Snippet)
+    require.Contains(t, err.Error(), strconv.Quote(se.Snippet))
+    var syn SyntaxError
+    require.ErrorAs(t, err, &syn)
+
+    /* type mismatches, with the snippet clamped to the input */
+    src = `{"age":"twelve"}`
+    dec = NewDecoder(src)
+    dec.SetOptions(OptionErrorSnippet)
+    err = dec.Decode(&v)
+    require.Error(t, err)
+    require.Contains(t, err.Error(), strconv.Quote(src))
+    var mis *MismatchTypeError
+    require.ErrorAs(t, err, &mis)
+
+    /* without the option, errors are left as is */
+    err = NewDecoder(`{"age":12x}`).Decode(&v)
+    _, ok = err.(SyntaxError)
+    require.True(t, ok, "%T", err)
+}
+
+func TestDecoder_OptionObjectAsPairs(t *testing.T) {
+    type Pair struct {
+        Key   string
+        Value int
+    }
+    var obj []Pair
+    err := NewDecoder(`{"a":1,"b":2}`).Decode(&obj)
+    require.Error(t, err)
+    _, ok := err.(*MismatchTypeError)
+    require.True(t, ok, err)
+
+    obj = nil
+    d := NewDecoder(`{"a":1,"b":2}`)
+    d.SetOptions(OptionObjectAsPairs)
+    require.NoError(t, d.Decode(&obj))
+    require.Equal(t, []Pair{{"a", 1}, {"b", 2}}, obj)
+
+    /* keys keep their order, arrays and empty objects still work */
+    type Doc struct {
+        Attrs []Pair `json:"attrs"`
+        List  []Pair `json:"list"`
+        None  []Pair `json:"none"`
+    }
+    var doc Doc
+    d = NewDecoder(`{"attrs": { "z" : 26 , "a\u0062" : 2, "z" : 0 }, "list": [{"Key":"x","Value":3}], "none": {}}`)
+    d.SetOptions(OptionObjectAsPairs)
+    require.NoError(t, d.Decode(&doc))
+    require.Equal(t, Doc{
+        Attrs: []Pair{{"z", 26}, {"ab", 2}, {"z", 0}},
+        List:  []Pair{{"x", 3}},
+        None:  []Pair{},
+    }, doc)
+
+    /* the values are decoded by their types */
+    type Any struct {
+        K string      `json:"k"`
+        V interface{} `json:"v"`
+    }
+    var anys []Any
+    d = NewDecoder(`{"s":"x","n":null,"o":{"p":[true]}}`)
+    d.SetOptions(OptionObjectAsPairs)
+    require.NoError(t, d.Decode(&anys))
+    require.Equal(t, []Any{{"s", "x"}, {"n", nil}, {"o", map[string]interface{}{"p": []interface{}{true}}}}, anys)
+
+    d = NewDecoder(`{"a":"x"}`)
+    d.SetOptions(OptionObjectAsPairs)
+    require.Error(t, d.Decode(&obj))
+
+    /* only slices of pairs take objects */
+    var ints []int
+    d = NewDecoder(`{"a":1}`)
+    d.SetOptions(OptionObjectAsPairs)
+    require.Error(t, d.Decode(&ints))
+}
+
+func TestDecoder_OptionLimitDynamicDepth(t *testing.T) {
+    /* a chain of n non-nil interfaces, each pointing to the next one */
+    chain := func(n int) []interface{} {
+        vals := make([]interface{}, n + 1)
+        for i := 0; i < n; i++ {
+            vals[i] = &vals[i + 1]
+        }
+        return vals
+    }
+
+    vals := chain(consts.MaxDynamicDepth)
+    d := NewDecoder(`[1]`)
+    d.SetOptions(OptionLimitDynamicDepth)
+    require.NoError(t, d.Decode(&vals[0]))
+    require.Equal(t, []interface{}{1.0}, vals[len(vals) - 1])
+
+    vals = chain(consts.MaxDynamicDepth + 1)
+    d = NewDecoder(`[1]`)
+    d.SetOptions(OptionLimitDynamicDepth)
+    err := d.Decode(&vals[0])
+    require.IsType(t, SyntaxError{}, err)
+    require.Contains(t, err.Error(), "recursion exceeded max depth")
+    require.Nil(t, vals[len(vals) - 1])
+
+    /* cycles of interfaces are not followed forever */
+    var a, b interface{}
+    a, b = &b, &a
+    d = NewDecoder(`1`)
+    d.SetOptions(OptionLimitDynamicDepth)
+    err = d.Decode(&a)
+    require.IsType(t, SyntaxError{}, err)
+    require.Contains(t, err.Error(), "recursion exceeded max depth")
+
+    /* nested arrays through a self-referencing slice stop at the limit too */
+    nest := func(n int) string {
+        return strings.Repeat("[", n) + strings.Repeat("]", n)
+    }
+    self := make([]interface{}, 1)
+    self[0] = &self
+    d = NewDecoder(nest(64))
+    d.SetOptions(OptionLimitDynamicDepth)
+    require.NoError(t, d.Decode(&self))
+
+    var v interface{}
+    d = NewDecoder(nest(consts.MaxDynamicDepth + 1))
+    d.SetOptions(OptionLimitDynamicDepth)
+    err = d.Decode(&v)
+    require.IsType(t, SyntaxError{}, err)
+}
+
+type growStackNode struct {
+    A *growStackNode `json:"a"`
+    B []int          `json:"b"`
+}
+
+func TestDecoder_OptionGrowStack(t *testing.T) {
+    if envs.UseOptDec {
+        t.Skip("the state stack is only grown by the JIT decoder")
+    }
+
+    /* deeper than the state stack, and with values after each nested one */
+    n := consts.MaxStack * 2
+    src := strings.Repeat(`{"b":[1],"a":`, n) + "null" + strings.Repeat(`,"b":[2,3]}`, n)
+
+    var v growStackNode
+    err := NewDecoder(src).Decode(&v)
+    require.IsType(t, &json.UnsupportedValueError{}, err)
+
+    for _, opts := range []Options{OptionGrowStack, OptionGrowStack | OptionNoValidateJSON} {
+        var v, exp growStackNode
+        d := NewDecoder(src)
+        d.SetOptions(opts)
+        require.NoError(t, d.Decode(&v))
+        require.NoError(t, json.Unmarshal([]byte(src), &exp))
+        require.Equal(t, exp, v)
+    }
+
+    /* the grown stack is still limited */
+    old := option.MaxDecoderStackDepth
+    defer func() { option.MaxDecoderStackDepth = old }()
+    option.MaxDecoderStackDepth = uint(n)
+    d := NewDecoder(src)
+    d.SetOptions(OptionGrowStack)
+    err = d.Decode(&v)
+    require.IsType(t, &json.UnsupportedValueError{}, err)
+}
+
+type requiredStruct struct {
+    Name string          `json:"name,required"`
+    Age  int             `json:"age"`
+    Next *requiredStruct `json:"next"`
+}
+
+func TestDecoder_RequiredField(t *testing.T) {
+    var v requiredStruct
+    err := NewDecoder(`{}`).Decode(&v)
+    require.Error(t, err)
+    require.Equal(t, `json: missing required field "name"`, err.Error())
+
+    v = requiredStruct{}
+    require.NoError(t, NewDecoder(`{"name":"x"}`).Decode(&v))
+    require.Equal(t, requiredStruct{Name: "x"}, v)
+
+    /* keys are matched the same way as when decoding the fields */
+    v = requiredStruct{}
+    require.NoError(t, NewDecoder(` { "age" : 1 , "NAME" : "y" } `).Decode(&v))
+    require.Equal(t, requiredStruct{Name: "y", Age: 1}, v)
+    v = requiredStruct{}
+    require.NoError(t, NewDecoder(`{"\u006eame":"z"}`).Decode(&v))
+    require.Equal(t, "z", v.Name)
+
+    /* nested objects are checked as well, and null is not an object */
+    v = requiredStruct{}
+    err = NewDecoder(`{"name":"x","next":{"age":2}}`).Decode(&v)
+    require.Error(t, err)
+    require.Equal(t, `json: missing required field "name"`, err.Error())
+    v = requiredStruct{}
+    require.NoError(t, NewDecoder(`{"name":"x","next":null}`).Decode(&v))
+    require.Nil(t, v.Next)
+}
+
+func TestDecoder_ValidateStringUnicodeErrors(t *testing.T) {
+    decode := func(src string, opts Options) (string, error) {
+        var v string
+        dec := NewDecoder(src)
+        dec.SetOptions(opts)
+        err := dec.Decode(&v)
+        return v, err
+    }
+
+    /* invalid UTF-8 is replaced like encoding/json, unless errors are asked for */
+    for _, opts := range []Options{OptionValidateString, OptionValidateString | OptionCopyString} {
+        v, err := decode("\"a\xffb\"", opts)
+        require.NoError(t, err)
+        require.Equal(t, "a\ufffdb", v)
+    }
+    v, err := decode("\"a\xffb\"", OptionUseUnicodeErrors)
+    require.NoError(t, err)
+    require.Equal(t, "a\xffb", v)
+
+    /* the error points at the first invalid byte */
+    for src, pos := range map[string]int{
+        "\"a\xffb\"": 2,
+        "\"\u00e9\xc3\"": 3,
+        "[\"\ufffd\", \"\xed\xa0\x80\"]": 9,
+    } {
+        var v interface{}
+        dec := NewDecoder(src)
+        dec.SetOptions(OptionValidateString | OptionUseUnicodeErrors)
+        err := dec.Decode(&v)
+        require.IsType(t, SyntaxError{}, err, src)
+        require.Equal(t, types.ERR_INVALID_UTF8, err.(SyntaxError).Code, src)
+        require.Equal(t, pos, err.(SyntaxError).Pos, src)
+    }
+
+    /* lone surrogates are replaced, unless errors are asked for */
+    v, err = decode(`"a\uD800b"`, OptionValidateString)
+    require.NoError(t, err)
+    require.Equal(t, "a\ufffdb", v)
+    if !envs.UseOptDec {
+        _, err = decode(`"a\uD800b"`, OptionValidateString | OptionUseUnicodeErrors)
+        require.IsType(t, SyntaxError{}, err)
+        require.Equal(t, types.ERR_INVALID_UNICODE, err.(SyntaxError).Code)
+    }
+}
+
+type rawMessageStruct struct {
+    A json.RawMessage            `json:"a"`
+    B []json.RawMessage          `json:"b"`
+    C map[string]json.RawMessage `json:"c"`
+    D *json.RawMessage           `json:"d"`
+}
+
+func TestDecoder_RawMessage(t *testing.T) {
+    src := `{"a" : {"x": [1, {"y": "a \"b\" \\ \u00e9"}], "z": null} , "b": [ 2.5 , "\/" , [] , null ],` +
+        ` "c": {"k": true, "l": {"m":"\ud83d\ude00"}}, "d": [ "e" ]}`
+    for _, opts := range []Options{0, OptionCopyString} {
+        var v, exp rawMessageStruct
+        dec := NewDecoder(src)
+        dec.SetOptions(opts)
+        require.NoError(t, dec.Decode(&v))
+        require.NoError(t, json.Unmarshal([]byte(src), &exp))
+        require.Equal(t, exp, v)
+        require.Equal(t, `{"x": [1, {"y": "a \"b\" \\ \u00e9"}], "z": null}`, string(v.A))
+
+        /* the raw values are marshaled back unchanged */
+        out, err := encoder.Encode(v, encoder.SortMapKeys | encoder.CompactMarshaler)
+        require.NoError(t, err)
+        stdout, err := json.Marshal(exp)
+        require.NoError(t, err)
+        require.Equal(t, string(stdout), string(out))
+
+        /* both decoders refer to the source, unless asked to copy it */
+        p := uintptr(unsafe.Pointer(&v.A[0]))
+        s := uintptr((*rt.GoString)(unsafe.Pointer(&src)).Ptr)
+        require.Equal(t, opts == 0, p >= s && p < s + uintptr(len(src)))
+    }
+
+    /* null is kept as it is, and invalid values are rejected */
+    var v rawMessageStruct
+    require.NoError(t, NewDecoder(`{"a":null,"d":null}`).Decode(&v))
+    require.Equal(t, "null", string(v.A))
+    require.Nil(t, v.D)
+    require.Error(t, NewDecoder(`{"a":[1,}`).Decode(&v))
+
+    /* quoted raw values still go through the unmarshaler */
+    var q struct {
+        A json.RawMessage `json:"a,string"`
+    }
+    var stdq = q
+    require.NoError(t, NewDecoder(`{"a":"1"}`).Decode(&q))
+    require.NoError(t, json.Unmarshal([]byte(`{"a":"1"}`), &stdq))
+    require.Equal(t, stdq, q)
+}
+
+func TestDecoder_UseNumberPrecision(t *testing.T) {
+    cases := []string{
+        `123456789012345678901234567890`,
+        `1.000000000000001`,
+        `-0.0`,
+        `1e400`,
+        `[18446744073709551616, {"a": 9007199254740993, "b": [1.10]}]`,
+    }
+    for _, src := range cases {
+        var exp interface{}
+        std := json.NewDecoder(strings.NewReader(src))
+        std.UseNumber()
+        require.NoError(t, std.Decode(&exp), src)
+
+        /* the literal text of each number is kept, whether copied or not */
+        for _, opts := range []Options{OptionUseNumber, OptionUseNumber | OptionCopyString} {
+            var v interface{}
+            dec := NewDecoder(src)
+            dec.SetOptions(opts)
+            require.NoError(t, dec.Decode(&v), src)
+            require.Equal(t, exp, v, src)
+        }
+    }
+
+    /* json.Number targets keep it regardless of the option */
+    var v struct {
+        N json.Number `json:"n"`
+        I interface{} `json:"i"`
+    }
+    require.NoError(t, NewDecoder(`{"n": 123456789012345678901234567890, "i": 1.000000000000001}`).Decode(&v))
+    require.Equal(t, json.Number("123456789012345678901234567890"), v.N)
+    require.Equal(t, float64(1.000000000000001), v.I)
+}
+
+type unknownFieldStruct struct {
+    Known int                 `json:"known"`
+    Next  *unknownFieldStruct `json:"next"`
+}
+
+func TestDecoder_DisallowUnknownFields(t *testing.T) {
+    src := `{"known":1,"extra":2}`
+    var v unknownFieldStruct
+    require.NoError(t, NewDecoder(src).Decode(&v))
+    require.Equal(t, unknownFieldStruct{Known: 1}, v)
+
+    /* the error names the field, like encoding/json */
+    var exp unknownFieldStruct
+    std := json.NewDecoder(strings.NewReader(src))
+    std.DisallowUnknownFields()
+    stderr := std.Decode(&exp)
+    for _, set := range []func(*Decoder){
+        (*Decoder).DisallowUnknownFields,
+        func(dec *Decoder) { dec.SetOptions(OptionDisableUnknown) },
+    } {
+        v = unknownFieldStruct{}
+        dec := NewDecoder(src)
+        set(dec)
+        err := dec.Decode(&v)
+        require.EqualError(t, err, stderr.Error())
+        require.Equal(t, `json: unknown field "extra"`, err.Error())
+        require.Equal(t, 1, v.Known)
+
+        /* the JIT decoder stops right after the key, the other one after the object */
+        if envs.UseOptDec {
+            require.Equal(t, len(src), dec.Pos())
+        } else {
+            require.Equal(t, strings.Index(src, `"extra"`) + len(`"extra"`), dec.Pos())
+        }
+    }
+
+    /* nested objects are checked as well, maps accept any key */
+    v = unknownFieldStruct{}
+    dec := NewDecoder(`{"next":{"known":2,"\u0065xtra":[1,2]},"known":1}`)
+    dec.DisallowUnknownFields()
+    require.EqualError(t, dec.Decode(&v), `json: unknown field "extra"`)
+    var m map[string]interface{}
+    dec = NewDecoder(src)
+    dec.DisallowUnknownFields()
+    require.NoError(t, dec.Decode(&m))
+}
+
+type caseSensitiveStruct struct {
+    Name   string `json:",omitempty"`
+    UserID int    `json:"userId"`
+    Lower  int    `json:"lower"`
+    Upper  int    `json:"LOWER"`
+}
+
+func TestDecoder_CaseSensitive(t *testing.T) {
+    cases := []string{
+        `{"Name":"a","userId":1,"lower":2,"LOWER":3}`,
+        `{"NAME":"a","USERID":1,"Lower":2}`,
+        `{"name":"a","userid":1,"lOwEr":2}`,
+    }
+    for _, src := range cases {
+        /* keys fall back to the case-insensitive match by default, like encoding/json */
+        var exp, v caseSensitiveStruct
+        require.NoError(t, json.Unmarshal([]byte(src), &exp), src)
+        dec := NewDecoder(src)
+        dec.CaseSensitive(false)
+        require.NoError(t, dec.Decode(&v), src)
+        require.Equal(t, exp, v, src)
+        v = caseSensitiveStruct{}
+        require.NoError(t, NewDecoder(src).Decode(&v), src)
+        require.Equal(t, exp, v, src)
+
+        /* only the exact keys are matched when asked to */
+        v = caseSensitiveStruct{}
+        dec = NewDecoder(src)
+        dec.CaseSensitive(true)
+        require.NoError(t, dec.Decode(&v), src)
+        if src == cases[0] {
+            require.Equal(t, caseSensitiveStruct{Name: "a", UserID: 1, Lower: 2, Upper: 3}, v, src)
+        } else {
+            require.Equal(t, caseSensitiveStruct{}, v, src)
+        }
+    }
+
+    /* the toggle can be turned off again */
+    var v caseSensitiveStruct
+    dec := NewDecoder(cases[1])
+    dec.CaseSensitive(true)
+    dec.CaseSensitive(false)
+    require.NoError(t, dec.Decode(&v))
+    require.Equal(t, "a", v.Name)
+}
+
+type mismatchPosStruct struct {
+    Age  int      `json:"age"`
+    Name string   `json:"name"`
+    Tags []string `json:"tags"`
+}
+
+func TestDecoder_MismatchTypePosition(t *testing.T) {
+    cases := []struct {
+        src string
+        at  string
+        exp mismatchPosStruct
+    }{
+        {`{"age":"notanumber","name":"ok"}`, `"notanumber"`, mismatchPosStruct{Name: "ok"}},
+        {`{"age":  "notanumber" ,"name":"ok"}`, `"notanumber"`, mismatchPosStruct{Name: "ok"}},
+        {`{"x":1,"age":true,"name":"ok"}`, `true`, mismatchPosStruct{Name: "ok"}},
+        {`{"age":[1,{"a":"b"}],"name":"ok"}`, `[1,{"a":"b"}]`, mismatchPosStruct{Name: "ok"}},
+        {`{"age":1,"tags":["a",2,"c"],"name":"ok"}`, `2`, mismatchPosStruct{Age: 1, Name: "ok", Tags: []string{"a", "", "c"}}},
+    }
+    for _, c := range cases {
+        /* the error points at the start of the offending value, and the remaining fields are still decoded */
+        var v mismatchPosStruct
+        err := NewDecoder(c.src).Decode(&v)
+        var mis *MismatchTypeError
+        require.ErrorAs(t, err, &mis, c.src)
+        require.Equal(t, strings.Index(c.src, c.at), mis.Pos, c.src)
+        require.Equal(t, c.exp, v, c.src)
+    }
+
+    /* string map keys are reported at their opening quote as well */
+    var m map[int]int
+    err := NewDecoder(`{"1":1,"b":2}`).Decode(&m)
+    var mis *MismatchTypeError
+    require.ErrorAs(t, err, &mis)
+    require.Equal(t, 7, mis.Pos)
+}
+
+func TestDecoder_UnquoteEdgeEscapes(t *testing.T) {
+    type T struct {
+        A string      `json:"a"`
+        B string      `json:"b,string"`
+        C interface{} `json:"c"`
+    }
+    decode := func(src string, opts Options) (T, error) {
+        var v T
+        dec := NewDecoder(src)
+        dec.SetOptions(opts)
+        err := dec.Decode(&v)
+        return v, err
+    }
+
+    for src, exp := range map[string]string{
+        `"\ud83d\ude00"` : "\U0001F600",
+        `"a\/b"`         : "a/b",
+        `"\b\f"`         : "\b\f",
+        `"\u00e9\t\\"`   : "\u00e9\t\\",
+        `"\uDBFF\uDFFF"` : "\U0010FFFF",
+    } {
+        quoted := strconv.Quote(src)
+        v, err := decode(`{"a":` + src + `,"b":` + quoted + `,"c":` + src + `}`, 0)
+        require.NoError(t, err, src)
+        require.Equal(t, T{A: exp, B: exp, C: exp}, v, src)
+
+        /* the unescaped strings survive a round trip through the encoder */
+        buf, err := encoder.Encode(v, 0)
+        require.NoError(t, err, src)
+        r, err := decode(string(buf), 0)
+        require.NoError(t, err, string(buf))
+        require.Equal(t, v, r, string(buf))
+    }
+
+    /* invalid code points are replaced with U+FFFD, unless urc is disabled */
+    for src, exp := range map[string]string{
+        `"\ud800"`       : "\ufffd",
+        `"x\udc00y"`     : "x\ufffdy",
+        `"\ud83d\u0041"` : "\ufffdA",
+    } {
+        quoted := strconv.Quote(src)
+        v, err := decode(`{"a":` + src + `,"b":` + quoted + `,"c":` + src + `}`, 0)
+        require.NoError(t, err, src)
+        require.Equal(t, T{A: exp, B: exp, C: exp}, v, src)
+        if !envs.UseOptDec {
+            _, err = decode(`{"a":` + src + `}`, OptionUseUnicodeErrors)
+            require.IsType(t, SyntaxError{}, err, src)
+            _, err = decode(`{"b":` + quoted + `}`, OptionUseUnicodeErrors)
+            require.IsType(t, SyntaxError{}, err, quoted)
+        }
+    }
+
+    /* the +1/+3 fixups report the same position for plain and quoted fields */
+    if envs.UseOptDec {
+        return
+    }
+    for src, pos := range map[string]int{
+        `{"a":"ab\q"}`        : 9,
+        `{"a":"ab\u12"}`      : 12,
+        `{"b":"\"ab\\q\""}`   : 12,
+        `{"b":"\"ab\\u12\""}` : 15,
+    } {
+        _, err := decode(src, 0)
+        require.IsType(t, SyntaxError{}, err, src)
+        require.Equal(t, pos, err.(SyntaxError).Pos, src)
+    }
+}
+
+func TestDecoder_ShortArrayClearsTail(t *testing.T) {
+    ints := [4]int{9, 9, 9, 9}
+    require.NoError(t, NewDecoder(`[1,2]`).Decode(&ints))
+    require.Equal(t, [4]int{1, 2, 0, 0}, ints)
+
+    x := 9
+    ptrs := [4]*int{nil, nil, &x, &x}
+    require.NoError(t, NewDecoder(`[1,2]`).Decode(&ptrs))
+    require.Equal(t, 1, *ptrs[0])
+    require.Equal(t, 2, *ptrs[1])
+    require.Nil(t, ptrs[2])
+    require.Nil(t, ptrs[3])
+}
+
+type maxDepthList []*maxDepthList
+
+func TestDecoder_SetMaxDepth(t *testing.T) {
+    nest := func(n int) string {
+        return strings.Repeat("[", n) + "null" + strings.Repeat("]", n)
+    }
+    decode := func(src string, depth int) error {
+        var v maxDepthList
+        d := NewDecoder(src)
+        d.SetMaxDepth(depth)
+        return d.Decode(&v)
+    }
+
+    for _, depth := range []int{1, 8, 64, 1000} {
+        require.NoError(t, decode(nest(depth), depth), depth)
+        require.NoError(t, decode(nest(depth + 1), 0), depth)
+        err := decode(nest(depth + 1), depth)
+        require.IsType(t, &json.UnsupportedValueError{}, err, depth)
+        require.Contains(t, err.Error(), "nesting too deep")
+    }
+
+    /* objects decoded into structs count the same way */
+    obj := func(n int) string {
+        return strings.Repeat(`{"a":`, n) + "null" + strings.Repeat("}", n)
+    }
+    var v growStackNode
+    d := NewDecoder(obj(16))
+    d.SetMaxDepth(16)
+    require.NoError(t, d.Decode(&v))
+    d = NewDecoder(obj(17))
+    d.SetMaxDepth(16)
+    require.IsType(t, &json.UnsupportedValueError{}, d.Decode(&v))
+
+    /* the limit applies on top of a grown stack as well */
+    if !envs.UseOptDec {
+        n := consts.MaxStack + 100
+        d = NewDecoder(obj(n))
+        d.SetOptions(OptionGrowStack)
+        d.SetMaxDepth(n)
+        require.NoError(t, d.Decode(&v))
+        d = NewDecoder(obj(n + 1))
+        d.SetOptions(OptionGrowStack)
+        d.SetMaxDepth(n)
+        require.IsType(t, &json.UnsupportedValueError{}, d.Decode(&v))
+    }
+}
+
+type quotedFieldsStruct struct {
+    N int64   `json:"n,string"`
+    U uint16  `json:"u,string"`
+    F float64 `json:"f,string"`
+    B bool    `json:"b,string"`
+    P *int64  `json:"p,string"`
+    M int     `json:"m"`
+}
+
+func TestDecoder_QuotedNumericFields(t *testing.T) {
+    var v quotedFieldsStruct
+    src := `{"n":"42","u":"7","f":"1.5","b":"true","p":"-3","m":1}`
+    require.NoError(t, NewDecoder(src).Decode(&v))
+    p := int64(-3)
+    require.Equal(t, quotedFieldsStruct{N: 42, U: 7, F: 1.5, B: true, P: &p, M: 1}, v)
+
+    /* the encoder quotes the same fields, so the value round-trips */
+    buf, err := encoder.Encode(v, 0)
+    require.NoError(t, err)
+    require.Equal(t, src, string(buf))
+
+    /* a quoted "null" leaves the field untouched, just like the std */
+    v = quotedFieldsStruct{N: 1}
+    require.NoError(t, NewDecoder(`{"n":"null","p":null}`).Decode(&v))
+    require.Equal(t, quotedFieldsStruct{N: 1}, v)
+
+    /* unquoted values are type mismatches, and the remaining fields are still decoded */
+    for _, in := range []string{`42`, `true`, `[1,{"a":2}]`, `{"a":"42"}`} {
+        var v quotedFieldsStruct
+        src := `{"n":` + in + `,"m":1}`
+        err := NewDecoder(src).Decode(&v)
+        var mis *MismatchTypeError
+        require.ErrorAs(t, err, &mis, src)
+        require.Equal(t, 5, mis.Pos, src)
+        require.Equal(t, quotedFieldsStruct{M: 1}, v, src)
+        require.Error(t, json.Unmarshal([]byte(src), &v), src)
+    }
+}
+
 func BenchmarkSkip_Sonic(b *testing.B) {
     var data = rt.Str2Mem(TwitterJson)
     if ret, _ := Skip(data); ret < 0 {
@@ -286,6 +286,41 @@ func TestDecoder_Binding(t *testing.T) {
     assert.Equal(t, _BindingValue, v, 0)
 }
 
+type (
+    definedID   int64
+    definedName string
+    aliasedID   = int64
+)
+
+func TestDecoder_DefinedTypes(t *testing.T) {
+    type T struct {
+        ID    definedID                 `json:"id"`
+        QID   definedID                 `json:"qid,string"`
+        PID   *definedID                `json:"pid"`
+        AID   aliasedID                 `json:"aid"`
+        Name  definedName               `json:"name"`
+        Names []definedName             `json:"names"`
+        ByID  map[definedID]definedName `json:"by_id"`
+        ByNm  map[definedName]definedID `json:"by_name"`
+    }
+    src := `{"id":1,"qid":"2","pid":3,"aid":4,"name":"x","names":["y","z"],"by_id":{"5":"a"},"by_name":{"b":6}}`
+
+    var exp, v T
+    assert.NoError(t, json.Unmarshal([]byte(src), &exp))
+    pos, err := decode(src, &v, false)
+    assert.NoError(t, err)
+    assert.Equal(t, len(src), pos)
+    assert.Equal(t, exp, v)
+    assert.Equal(t, definedID(1), v.ID)
+    assert.Equal(t, definedName("x"), v.Name)
+
+    /* defined types are mismatched like their underlying kinds */
+    v = T{}
+    _, err = decode(`{"id":"1","name":"x"}`, &v, false)
+    assert.Error(t, err)
+    assert.Equal(t, definedName("x"), v.Name)
+}
+
 func BenchmarkDecoder_Generic_Sonic(b *testing.B) {
     var w interface{}
     _, _ = decode(TwitterJson, &w, true)
@@ -0,0 +1,26 @@
+//go:build arm64 && go1.20 && !go1.26 && sonic_arm64jit
+// +build arm64,go1.20,!go1.26,sonic_arm64jit
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+// The ARM64 JIT encoder is still experimental, it is linked in only when
+// building with the sonic_arm64jit tag. Otherwise the VM encodes on arm64.
+import (
+    _ `github.com/bytedance/sonic/internal/encoder/arm64`
+)
@@ -21,11 +21,16 @@ package encoder
 import (
     `io`
     `bytes`
+    `encoding/binary`
     `encoding/json`
+    `fmt`
+    `math`
     `reflect`
+    `unsafe`
 
     `github.com/bytedance/sonic/option`
     `github.com/bytedance/sonic/internal/compat`
+    `github.com/bytedance/sonic/internal/encoder/alg`
 )
 
 func init() {
@@ -95,6 +100,8 @@ type Encoder struct {
     Opts Options
     prefix string
     indent string
+    item   string
+    key    string
 }
 
 // Encode returns the JSON encoding of v.
@@ -102,6 +109,9 @@ func (self *Encoder) Encode(v interface{}) ([]byte, error) {
     if self.indent != "" || self.prefix != "" { 
         return EncodeIndented(v, self.prefix, self.indent, self.Opts)
     }
+    if self.item != "" || self.key != "" {
+        return EncodeSeparated(v, self.item, self.key, self.Opts)
+    }
     return Encode(v, self.Opts)
 }
 
@@ -173,6 +183,15 @@ func (enc *Encoder) SetIndent(prefix, indent string) {
     enc.indent = indent
 }
 
+// SetSeparators instructs the encoder to write item between elements and key
+// after object keys, as if by the package-level function EncodeSeparated().
+// The separators are ignored when indenting.
+// Calling SetSeparators("", "") restores the compact ones.
+func (enc *Encoder) SetSeparators(item, key string) {
+    enc.item = item
+    enc.key = key
+}
+
 // Quote returns the JSON-quoted version of s.
 func Quote(s string) string {
     /* check for empty string */
@@ -232,6 +251,17 @@ func EncodeIndented(val interface{}, prefix string, indent string, opts Options)
    return out, err
 }
 
+// EncodeSeparated is like Encode but writes item instead of ',' between the
+// elements of arrays and objects, and key instead of ':' after object keys,
+// eg. ", " and ": " for readability without indentation.
+func EncodeSeparated(val interface{}, item string, key string, opts Options) ([]byte, error) {
+   out, err := Encode(val, opts)
+   if err != nil {
+       return nil, err
+   }
+   return alg.Separate(nil, out, item, key), nil
+}
+
 // Pretouch compiles vt ahead-of-time to avoid JIT compilation on-the-fly, in
 // order to reduce the first-hit latency.
 //
@@ -250,6 +280,30 @@ func Valid(data []byte) (ok bool, start int) {
    return json.Valid(data), 0
 }
 
+// Compact appends to dst the JSON src without the insignificant spaces, like
+// json.Compact. If src is not one valid JSON value, dst is left unchanged and
+// the error reports the position of the first invalid character.
+func Compact(dst *[]byte, src []byte) error {
+   d := bytes.NewBuffer(*dst)
+   if err := json.Compact(d, src); err != nil {
+       return err
+   }
+   *dst = d.Bytes()
+   return nil
+}
+
+// Indent appends to dst an indented form of the JSON src, like json.Indent.
+// If src is not one valid JSON value, dst is left unchanged and the error
+// reports the position of the first invalid character.
+func Indent(dst *[]byte, src []byte, prefix string, indent string) error {
+   d := bytes.NewBuffer(*dst)
+   if err := json.Indent(d, src, prefix, indent); err != nil {
+       return err
+   }
+   *dst = d.Bytes()
+   return nil
+}
+
 // StreamEncoder uses io.Writer as 
 type StreamEncoder = json.Encoder
 
@@ -260,3 +314,74 @@ func NewStreamEncoder(w io.Writer) *StreamEncoder {
    return json.NewEncoder(w)
 }
 
+// ChunkedEncoder is a StreamEncoder which hands the output off to a callback
+// in chunks of a fixed size.
+type ChunkedEncoder struct {
+   *json.Encoder
+   cw *chunkWriter
+}
+
+// NewChunkedEncoder returns a new encoder that calls flush every time size bytes
+// of JSON are produced, or an error if size is not positive. The chunk is only
+// valid until flush returns.
+func NewChunkedEncoder(size int, flush func(chunk []byte) error) (*ChunkedEncoder, error) {
+   if size <= 0 {
+      return nil, fmt.Errorf("invalid chunk size: %d", size)
+   }
+   cw := &chunkWriter{size: size, fn: flush}
+   return &ChunkedEncoder{Encoder: json.NewEncoder(cw), cw: cw}, nil
+}
+
+// Flush hands off the remaining bytes which are fewer than a chunk, if any.
+func (enc *ChunkedEncoder) Flush() error {
+   if len(enc.cw.buf) == 0 {
+      return nil
+   }
+   err := enc.cw.fn(enc.cw.buf)
+   enc.cw.buf = enc.cw.buf[:0]
+   return err
+}
+
+type chunkWriter struct {
+   size int
+   buf  []byte
+   fn   func([]byte) error
+}
+
+func (self *chunkWriter) Write(p []byte) (int, error) {
+   n := len(p)
+   for len(self.buf) + len(p) >= self.size {
+      m := self.size - len(self.buf)
+      self.buf = append(self.buf, p[:m]...)
+      err := self.fn(self.buf)
+      self.buf = self.buf[:0]
+      if p = p[m:]; err != nil {
+         return n - len(p), err
+      }
+   }
+   self.buf = append(self.buf, p...)
+   return n, nil
+}
+
+// RegisterFieldPredicate registers fn to decide whether the field named name
+// (as in JSON) of the struct vt should be emitted, v points to the struct.
+// NOTICE: it is not supported by the compatible encoder and will be ignored.
+func RegisterFieldPredicate(vt reflect.Type, name string, fn func(v unsafe.Pointer) bool) {
+}
+
+// EncodeFramed writes the JSON encoding of val to w as one length-prefixed frame,
+// which starts with the length of the JSON as a 4-byte big-endian integer.
+func EncodeFramed(w io.Writer, val interface{}) error {
+   buf, err := json.Marshal(val)
+   if err != nil {
+      return err
+   }
+   if uint64(len(buf)) > math.MaxUint32 {
+      return fmt.Errorf("JSON frame too large: %d bytes", len(buf))
+   }
+   var hdr [4]byte
+   binary.BigEndian.PutUint32(hdr[:], uint32(len(buf)))
+   _, err = w.Write(append(hdr[:], buf...))
+   return err
+}
+
@@ -31,6 +31,10 @@ type Encoder = encoder.Encoder
 // StreamEncoder uses io.Writer as input.
 type StreamEncoder = encoder.StreamEncoder
 
+// ChunkedEncoder is a StreamEncoder which hands the output off to a callback
+// in chunks of a fixed size.
+type ChunkedEncoder = encoder.ChunkedEncoder
+
 // Options is a set of encoding options.
 type Options = encoder.Options
 
@@ -74,6 +78,28 @@ const (
     // EncodeNullForInfOrNan encodes Infinity or NaN float values as 'null'
     // instead of returning an error.
     EncodeNullForInfOrNan Options = encoder.EncodeNullForInfOrNan
+
+    // StructAsArray indicates that structs are encoded as positional arrays
+    // of their field values (eg. `[1,"a"]`) instead of objects.
+    StructAsArray Options = encoder.StructAsArray
+
+    // OmitNilMapValues indicates that map entries whose value is
+    // a nil pointer or a nil interface are left out of the output.
+    OmitNilMapValues Options = encoder.OmitNilMapValues
+
+    // SortStructFields indicates that struct fields are encoded in the
+    // ascending order of their JSON names, instead of the declaration order.
+    SortStructFields Options = encoder.SortStructFields
+
+    // PointerRefs indicates that a struct pointer which has already been
+    // encoded is written as `{"$ref":N}`, where N is the 1-based order in
+    // which the pointer was first encountered. This allows cyclic values.
+    PointerRefs Options = encoder.PointerRefs
+
+    // OmitEmptyStructs indicates that struct fields with the "omitempty"
+    // option are left out when they are zero values (eg. `struct{}{}`).
+    // By default they are always encoded, the same as encoding/json.
+    OmitEmptyStructs Options = encoder.OmitEmptyStructs
 )
 
 
@@ -86,6 +112,11 @@ var (
     // followed by one or more copies of indent according to the indentation nesting.
     EncodeIndented = encoder.EncodeIndented
 
+    // EncodeSeparated is like Encode but writes item instead of ',' between the
+    // elements of arrays and objects, and key instead of ':' after object keys,
+    // eg. ", " and ": " for readability without indentation.
+    EncodeSeparated = encoder.EncodeSeparated
+
     // EncodeInto is like Encode but uses a user-supplied buffer instead of allocating a new one.
     EncodeInto = encoder.EncodeInto
 
@@ -114,8 +145,33 @@ var (
     // Note: it does not check for the invalid UTF-8 characters.
     Valid = encoder.Valid
 
+    // Compact appends to dst the JSON src without the insignificant spaces, like
+    // json.Compact. If src is not one valid JSON value, dst is left unchanged and
+    // the error reports the position of the first invalid character.
+    Compact = encoder.Compact
+
+    // Indent appends to dst an indented form of the JSON src, like json.Indent.
+    // If src is not one valid JSON value, dst is left unchanged and the error
+    // reports the position of the first invalid character.
+    Indent = encoder.Indent
+
     // NewStreamEncoder adapts to encoding/json.NewDecoder API.
     //
     // NewStreamEncoder returns a new encoder that write to w.
     NewStreamEncoder = encoder.NewStreamEncoder
+
+    // NewChunkedEncoder returns a new encoder that calls flush every time size bytes
+    // of JSON are produced, or an error if size is not positive. The chunk is only
+    // valid until flush returns.
+    NewChunkedEncoder = encoder.NewChunkedEncoder
+
+    // EncodeFramed writes the JSON encoding of val to w as one length-prefixed frame,
+    // which starts with the length of the JSON as a 4-byte big-endian integer.
+    EncodeFramed = encoder.EncodeFramed
+
+    // RegisterFieldPredicate registers fn to decide whether the field named name
+    // (as in JSON) of the struct vt should be emitted, v points to the struct.
+    // The field is emitted only if fn returns true, in addition to "omitempty", and fn
+    // must be registered before vt is compiled (encoded or pretouched) for the first time.
+    RegisterFieldPredicate = encoder.RegisterFieldPredicate
 )
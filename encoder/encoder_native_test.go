@@ -19,9 +19,23 @@
 package encoder
 
 import (
+    `bytes`
+    `encoding/binary`
     `encoding/json`
+    `fmt`
+    `io`
+    `net`
+    `net/netip`
+    `reflect`
+    `strconv`
+    `strings`
+    `sync`
+    `sync/atomic`
     `testing`
+    `unsafe`
 
+    `github.com/bytedance/sonic/decoder`
+    iencoder `github.com/bytedance/sonic/internal/encoder`
     `github.com/stretchr/testify/require`
 )
 
@@ -118,3 +132,961 @@ func TestEncoder_Marshal_EscapeHTML(t *testing.T) {
     require.Equal(t, string(ret), "{\"K\":\"\\u2028\\u2028\xe2\"}")
     require.NoError(t, err)
 }
+
+func TestEncoder_SetEscapeHTML(t *testing.T) {
+    type T struct {
+        S string            `json:"s"`
+        M map[string]string `json:"m"`
+        I interface{}       `json:"i"`
+    }
+    s := `<script>alert("a&b")</script>`
+    v := T{S: s, M: map[string]string{s: s}, I: s}
+    for _, html := range []bool{true, false} {
+        var exp, out bytes.Buffer
+        std := json.NewEncoder(&exp)
+        std.SetEscapeHTML(html)
+        require.NoError(t, std.Encode(v))
+
+        enc := NewStreamEncoder(&out)
+        enc.SetEscapeHTML(html)
+        require.NoError(t, enc.Encode(v))
+        require.Equal(t, exp.String(), out.String(), html)
+        require.Equal(t, html, !strings.Contains(out.String(), "<script>"), html)
+
+        e := Encoder{}
+        e.SetEscapeHTML(html)
+        ret, err := e.Encode(v)
+        require.NoError(t, err)
+        require.Equal(t, strings.TrimSuffix(exp.String(), "\n"), string(ret), html)
+    }
+}
+
+func TestEncoder_OmitEmptyFields(t *testing.T) {
+    type T struct {
+        S   string         `json:"s,omitempty"`
+        I   int            `json:"i,omitempty"`
+        I8  int8           `json:"i8,omitempty"`
+        I16 int16          `json:"i16,omitempty"`
+        U32 uint32         `json:"u32,omitempty"`
+        F64 float64        `json:"f64,omitempty"`
+        B   bool           `json:"b,omitempty"`
+        L   []int          `json:"l,omitempty"`
+        M   map[string]int `json:"m,omitempty"`
+        P   *int           `json:"p,omitempty"`
+        E   interface{}    `json:"e,omitempty"`
+    }
+    one := 1
+    for _, v := range []T{
+        {},
+        {L: []int{}, M: map[string]int{}},
+        {S: "s", I: -1, I8: 1, I16: 256, U32: 1 << 16, F64: 0.5, B: true, L: []int{0}, M: map[string]int{"": 0}, P: &one, E: 0},
+        {I16: 1 << 8, U32: 1 << 24, P: new(int)},
+    } {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        ret, err := Encode(v, 0)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(ret))
+    }
+
+    /* comparable structs are checked with reflection for omitzero */
+    type Point struct {
+        X, Y int16
+    }
+    type Z struct {
+        A Point `json:"a,omitzero"`
+        B Point `json:"b,omitzero"`
+    }
+    ret, err := Encode(Z{B: Point{Y: 1}}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"b":{"X":0,"Y":1}}`, string(ret))
+}
+
+func TestEncoder_QuotedFields(t *testing.T) {
+    type T struct {
+        I   int64   `json:"i,string"`
+        U   uint8   `json:"u,string"`
+        F32 float32 `json:"f32,string"`
+        F64 float64 `json:"f64,string"`
+        B   bool    `json:"b,string"`
+        S   string  `json:"s,string"`
+        P   *int    `json:"p,string"`
+        L   []int   `json:"l,string"`
+    }
+    one := 1
+    for _, v := range []T{
+        {},
+        {I: -42, U: 255, F32: 1.5, F64: 1e21, B: true, S: `a"b`, P: &one, L: []int{1}},
+    } {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        ret, err := Encode(v, 0)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(ret))
+    }
+}
+
+func TestEncoder_StructAsArray(t *testing.T) {
+    type Inner struct {
+        X int    `json:"x"`
+        Y string `json:"y"`
+    }
+    type T struct {
+        A int    `json:"a"`
+        B string `json:"b,omitempty"`
+        C *Inner `json:"c"`
+    }
+
+    in := T{A: 1, B: "x", C: &Inner{X: 2, Y: "y"}}
+    ret, err := Encode(in, StructAsArray)
+    require.NoError(t, err)
+    require.Equal(t, `[1,"x",[2,"y"]]`, string(ret))
+
+    var out T
+    dec := decoder.NewDecoder(string(ret))
+    dec.SetOptions(decoder.OptionStructAsArray)
+    require.NoError(t, dec.Decode(&out))
+    require.Equal(t, in, out)
+
+    /* omitted fields keep their positions */
+    ret, err = Encode(T{}, StructAsArray)
+    require.NoError(t, err)
+    require.Equal(t, `[0,"",null]`, string(ret))
+
+    /* the default layout is not affected */
+    ret, err = Encode(in, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":1,"b":"x","c":{"x":2,"y":"y"}}`, string(ret))
+}
+
+func TestEncoder_OmitNilMapValues(t *testing.T) {
+    x := 1
+    in := map[string]*int{"a": nil, "b": &x}
+    for _, opts := range []Options{OmitNilMapValues, OmitNilMapValues | SortMapKeys} {
+        ret, err := Encode(in, opts)
+        require.NoError(t, err)
+        require.Equal(t, `{"b":1}`, string(ret))
+    }
+
+    /* nil interfaces are dropped too, and the commas stay balanced */
+    ret, err := Encode(map[string]interface{}{"a": nil, "b": 1, "c": nil, "d": "x"}, OmitNilMapValues | SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, `{"b":1,"d":"x"}`, string(ret))
+
+    ret, err = Encode(map[string]interface{}{"a": nil}, OmitNilMapValues)
+    require.NoError(t, err)
+    require.Equal(t, `{}`, string(ret))
+
+    /* slices keep their null elements */
+    ret, err = Encode(map[string][]*int{"a": {nil, &x}}, OmitNilMapValues)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":[null,1]}`, string(ret))
+
+    ret, err = Encode(in, SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":null,"b":1}`, string(ret))
+}
+
+func TestEncoder_SortStructFields(t *testing.T) {
+    type Inner struct {
+        Z int `json:"z"`
+        A int `json:"a,omitempty"`
+    }
+    type Sorted struct {
+        A int `json:"a"`
+        B int `json:"b"`
+    }
+    type T struct {
+        C string  `json:"c"`
+        B Inner   `json:"b"`
+        P *Inner  `json:"p"`
+        S Sorted  `json:"s"`
+        L []Inner `json:"l"`
+        A int     `json:"a,string"`
+    }
+    v := &T{C: "x", B: Inner{Z: 1, A: 2}, P: &Inner{Z: 3}, S: Sorted{B: 1}, L: []Inner{{Z: 4, A: 5}}, A: 6}
+
+    ret, err := Encode(v, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"c":"x","b":{"z":1,"a":2},"p":{"z":3},"s":{"a":0,"b":1},"l":[{"z":4,"a":5}],"a":"6"}`, string(ret))
+
+    ret, err = Encode(v, SortStructFields)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":"6","b":{"a":2,"z":1},"c":"x","l":[{"a":5,"z":4}],"p":{"z":3},"s":{"a":0,"b":1}}`, string(ret))
+
+    /* structs that are already sorted, with unsorted nested structs */
+    ret, err = Encode(struct{ A Inner `json:"a"` }{Inner{Z: 1, A: 2}}, SortStructFields)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":{"a":2,"z":1}}`, string(ret))
+
+    /* positional arrays keep the declaration order */
+    ret, err = Encode(v, SortStructFields | StructAsArray)
+    require.NoError(t, err)
+    require.Equal(t, `["x",[1,2],[3,0],[0,1],[[4,5]],"6"]`, string(ret))
+}
+
+func TestEncoder_PointerRefs(t *testing.T) {
+    type Node struct {
+        V    int   `json:"v"`
+        Next *Node `json:"next"`
+    }
+    a := &Node{V: 1}
+    b := &Node{V: 2, Next: a}
+    a.Next = b
+
+    ret, err := Encode(a, PointerRefs)
+    require.NoError(t, err)
+    require.Equal(t, `{"v":1,"next":{"v":2,"next":{"$ref":1}}}`, string(ret))
+
+    /* shared pointers are written only once */
+    c := &Node{V: 3}
+    ret, err = Encode([]*Node{c, {V: 4, Next: c}, c}, PointerRefs)
+    require.NoError(t, err)
+    require.Equal(t, `[{"v":3,"next":null},{"v":4,"next":{"$ref":1}},{"$ref":1}]`, string(ret))
+
+    ret, err = Encode([]*Node{c, c}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `[{"v":3,"next":null},{"v":3,"next":null}]`, string(ret))
+}
+
+func TestEncoder_OmitEmptyStructs(t *testing.T) {
+    type Inner struct {
+        A int `json:"a"`
+    }
+    type T struct {
+        E struct{} `json:"e,omitempty"`
+        I Inner    `json:"i,omitempty"`
+        N Inner    `json:"n,omitempty"`
+        K struct{} `json:"k"`
+    }
+    v := T{N: Inner{A: 1}}
+
+    /* by default, struct values are never omitted, the same as encoding/json */
+    ret, err := Encode(v, 0)
+    require.NoError(t, err)
+    exp, err := json.Marshal(v)
+    require.NoError(t, err)
+    require.Equal(t, string(exp), string(ret))
+    require.Equal(t, `{"e":{},"i":{"a":0},"n":{"a":1},"k":{}}`, string(ret))
+
+    ret, err = Encode(v, OmitEmptyStructs)
+    require.NoError(t, err)
+    require.Equal(t, `{"n":{"a":1},"k":{}}`, string(ret))
+}
+
+func TestEncoder_NetTypes(t *testing.T) {
+    type T struct {
+        IP     net.IP                   `json:"ip"`
+        PIP    *net.IP                  `json:"pip"`
+        Nil    net.IP                   `json:"nil"`
+        Empty  net.IP                   `json:"empty,omitempty"`
+        Net    net.IPNet                `json:"net"`
+        PNet   *net.IPNet               `json:"pnet"`
+        Addr   netip.Addr               `json:"addr"`
+        Prefix netip.Prefix             `json:"prefix"`
+        IPs    []net.IP                 `json:"ips"`
+        ByAddr map[netip.Addr]string    `json:"by_addr"`
+        Any    interface{}              `json:"any"`
+    }
+    ip := net.ParseIP("2001:db8::1")
+    _, ipnet, err := net.ParseCIDR("10.0.0.0/8")
+    require.NoError(t, err)
+
+    for _, v := range []interface{}{
+        net.ParseIP("192.168.0.1"),
+        net.IP(nil),
+        *ipnet,
+        ipnet,
+        &T{},
+        &T{
+            IP     : net.ParseIP("127.0.0.1"),
+            PIP    : &ip,
+            Net    : *ipnet,
+            PNet   : ipnet,
+            Addr   : netip.MustParseAddr("::1"),
+            Prefix : netip.MustParsePrefix("192.168.0.0/16"),
+            IPs    : []net.IP{ip, nil, net.IPv4(1, 2, 3, 4)},
+            ByAddr : map[netip.Addr]string{netip.MustParseAddr("10.0.0.2"): "b", netip.MustParseAddr("10.0.0.1"): "a"},
+            Any    : ip,
+        },
+    } {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        ret, err := Encode(v, SortMapKeys)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(ret))
+    }
+}
+
+func TestEncoder_ChunkedEncoder(t *testing.T) {
+    in := make([]map[string]int, 1000)
+    for i := range in {
+        in[i] = map[string]int{"id": i}
+    }
+    js, err := Encode(in, 0)
+    require.NoError(t, err)
+
+    var out []byte
+    var sizes []int
+    enc, err := NewChunkedEncoder(512, func(chunk []byte) error {
+        out = append(out, chunk...)
+        sizes = append(sizes, len(chunk))
+        return nil
+    })
+    require.NoError(t, err)
+    enc.SetNoEncoderNewline(true)
+    require.NoError(t, enc.Encode(in))
+
+    /* only full chunks are handed off before flushing */
+    require.Len(t, sizes, len(js) / 512)
+    require.NoError(t, enc.Flush())
+    require.Len(t, sizes, (len(js) + 511) / 512)
+    for _, n := range sizes[:len(sizes) - 1] {
+        require.Equal(t, 512, n)
+    }
+    require.Equal(t, string(js), string(out))
+
+    /* escaping and validation see whole runes across the chunks */
+    str := []string{strings.Repeat("<\u2028\xff世", 100), "\xe4\xb8"}
+    exp, err := Encode(str, EscapeHTML | ValidateString)
+    require.NoError(t, err)
+    out = out[:0]
+    enc, err = NewChunkedEncoder(5, func(chunk []byte) error {
+        out = append(out, chunk...)
+        return nil
+    })
+    require.NoError(t, err)
+    enc.Opts = EscapeHTML | ValidateString
+    require.NoError(t, enc.Encode(str))
+    require.NoError(t, enc.Flush())
+    require.Equal(t, string(exp) + "\n", string(out))
+
+    /* errors from the callback are returned */
+    enc, err = NewChunkedEncoder(16, func([]byte) error { return io.ErrShortWrite })
+    require.NoError(t, err)
+    require.ErrorIs(t, enc.Encode(in), io.ErrShortWrite)
+
+    /* the size of the chunks must be positive */
+    _, err = NewChunkedEncoder(0, func([]byte) error { return nil })
+    require.Error(t, err)
+}
+
+func TestEncoder_StreamIndent(t *testing.T) {
+    type Node struct {
+        Name     string                 `json:"name"`
+        Tags     []string               `json:"tags"`
+        Attrs    map[string]interface{} `json:"attrs"`
+        Raw      json.RawMessage        `json:"raw"`
+        Children []*Node                `json:"children,omitempty"`
+    }
+    root := &Node{Name: "root", Tags: []string{}, Attrs: map[string]interface{}{}, Raw: json.RawMessage(` { "a" : [ 1 , { } ] } `)}
+    for i := 0; i < 100; i++ {
+        root.Children = append(root.Children, &Node{
+            Name:  `n"{[,:]}\\` + strconv.Itoa(i),
+            Tags:  []string{"x", "y\n"},
+            Attrs: map[string]interface{}{"i": i, "e": []interface{}{}, "o": map[string]interface{}{"k": nil}},
+            Raw:   json.RawMessage(`"\\\""`),
+        })
+    }
+
+    for _, opts := range []Options{SortMapKeys, SortMapKeys | NoValidateJSONMarshaler} {
+        exp, err := EncodeIndented(root, ">", "\t", opts)
+        require.NoError(t, err)
+        std, err := json.MarshalIndent(root, ">", "\t")
+        require.NoError(t, err)
+        require.Equal(t, string(std), string(exp))
+
+        /* the output is passed on in many pieces, each of a few bytes */
+        var out []byte
+        enc, err := NewChunkedEncoder(7, func(chunk []byte) error {
+            out = append(out, chunk...)
+            return nil
+        })
+        require.NoError(t, err)
+        enc.Opts = opts
+        enc.SetIndent(">", "\t")
+        require.NoError(t, enc.Encode(root))
+        require.NoError(t, enc.Encode([]int{}))
+        require.NoError(t, enc.Flush())
+        require.Equal(t, string(exp) + "\n[]\n", string(out))
+    }
+
+    /* nothing is written for invalid JSON */
+    var buf bytes.Buffer
+    senc := NewStreamEncoder(&buf)
+    senc.Opts = NoValidateJSONMarshaler
+    senc.SetIndent("", " ")
+    require.Error(t, senc.Encode(json.RawMessage(`[1,`)))
+    require.Zero(t, buf.Len())
+
+    /* errors from the writer are returned */
+    cenc, err := NewChunkedEncoder(16, func([]byte) error { return io.ErrShortWrite })
+    require.NoError(t, err)
+    cenc.SetIndent("", " ")
+    require.ErrorIs(t, cenc.Encode(root), io.ErrShortWrite)
+}
+
+func TestEncoder_IndentStd(t *testing.T) {
+    type Inner struct {
+        S string      `json:"s"`
+        E []int       `json:"e"`
+        M interface{} `json:"m"`
+    }
+    cases := []interface{}{
+        nil,
+        "{[:,]}",
+        []interface{}{},
+        map[string]interface{}{},
+        Inner{S: `{"a":[1,2]}`, E: []int{}, M: map[string]string{"k:{": "[v]", `q"\`: ","}},
+        []Inner{{S: "x\n}"}, {E: []int{1, 2}, M: []interface{}{map[string]int{}, []int{}, 1.5}}},
+        json.RawMessage(` { "a" : [ 1 , 2 ] } `),
+    }
+    for _, v := range cases {
+        for _, ind := range [][2]string{{"", "  "}, {"//", "\t"}, {"", ""}} {
+            exp, err := json.MarshalIndent(v, ind[0], ind[1])
+            require.NoError(t, err)
+            out, err := EncodeIndented(v, ind[0], ind[1], SortMapKeys | EscapeHTML | CompactMarshaler)
+            require.NoError(t, err)
+            require.Equal(t, string(exp), string(out))
+        }
+    }
+}
+
+func TestStreamEncoder_Std(t *testing.T) {
+    type Item struct {
+        ID   int               `json:"id"`
+        Name string            `json:"name"`
+        Tags []string          `json:"tags,omitempty"`
+        Attr map[string]string `json:"attr"`
+    }
+    in := []Item{
+        {ID: 1, Name: "<a href=\"x\">&</a>", Tags: []string{"p", "q"}, Attr: map[string]string{"b": "2", "a": "1"}},
+        {ID: 2, Name: "tab\tand 'quote'"},
+        {},
+    }
+
+    for _, html := range []bool{true, false} {
+        for _, indent := range [][2]string{{"", ""}, {"", "  "}, {"#", "\t"}} {
+            var exp, out bytes.Buffer
+            std := json.NewEncoder(&exp)
+            std.SetEscapeHTML(html)
+            std.SetIndent(indent[0], indent[1])
+
+            /* stream the items one by one into the same writer */
+            enc := NewStreamEncoder(&out)
+            enc.Opts = SortMapKeys
+            enc.SetEscapeHTML(html)
+            enc.SetIndent(indent[0], indent[1])
+            for _, v := range in {
+                require.NoError(t, std.Encode(v))
+                require.NoError(t, enc.Encode(v))
+            }
+            require.NoError(t, std.Encode(in))
+            require.NoError(t, enc.Encode(in))
+            require.Equal(t, exp.String(), out.String(), "html=%v indent=%q", html, indent)
+        }
+    }
+}
+
+func TestEncoder_SortMapKeysStable(t *testing.T) {
+    ms := map[string]int{}
+    mi := map[int]int{}
+    for i := -50; i < 150; i++ {
+        ms[strconv.Itoa(i * 7919 % 1000)] = i
+        mi[i * 7919 % 1000] = i
+    }
+
+    /* integer keys are ordered by their string forms, like encoding/json */
+    for _, v := range []interface{}{ms, mi, map[int]int{10: 1, 9: 2, -1: 3}} {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        for i := 0; i < 10; i++ {
+            out, err := Encode(v, SortMapKeys)
+            require.NoError(t, err)
+            require.Equal(t, string(exp), string(out))
+        }
+    }
+    out, err := Encode(map[int]int{10: 1, 9: 2, -1: 3}, SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, `{"-1":3,"10":1,"9":2}`, string(out))
+}
+
+func TestEncoder_DefaultValue(t *testing.T) {
+    type T struct {
+        N int      `json:"n,default=-1"`
+        S string   `json:"s,default=\"none\""`
+        P *int     `json:"p,default=0"`
+        L []string `json:"l,default=[]"`
+        Q int      `json:"q,string,default=\"7\""`
+    }
+    out, err := Encode(T{}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"n":-1,"s":"none","p":0,"l":[],"q":"7"}`, string(out))
+
+    one := 1
+    out, err = Encode(T{N: 2, S: "x", P: &one, L: []string{"y"}, Q: 3}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"n":2,"s":"x","p":1,"l":["y"],"q":"3"}`, string(out))
+
+    /* the default must be valid JSON */
+    type Bad struct {
+        N int `json:"n,default=x"`
+    }
+    _, err = Encode(Bad{}, 0)
+    require.Error(t, err)
+}
+
+func TestEncoder_Separators(t *testing.T) {
+    type Item struct {
+        Name string         `json:"name"`
+        Tags []string       `json:"tags"`
+        Meta map[string]int `json:"meta"`
+    }
+    v := []Item{{Name: `a,b:"c\\`, Tags: []string{"x", "y"}, Meta: map[string]int{"k": 1}}, {Tags: []string{}}}
+
+    out, err := EncodeSeparated(v, ", ", ": ", SortMapKeys)
+    require.NoError(t, err)
+    exp := `[{"name": "a,b:\"c\\\\", "tags": ["x", "y"], "meta": {"k": 1}}, {"name": "", "tags": [], "meta": null}]`
+    require.Equal(t, exp, string(out))
+
+    /* the separators are compact by default */
+    out, err = EncodeSeparated(v, "", "", SortMapKeys)
+    require.NoError(t, err)
+    compact, err := Encode(v, SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, string(compact), string(out))
+
+    enc := Encoder{Opts: SortMapKeys}
+    enc.SetSeparators(", ", ": ")
+    out, err = enc.Encode(v)
+    require.NoError(t, err)
+    require.Equal(t, exp, string(out))
+    enc.SetSeparators("", "")
+    out, err = enc.Encode(v)
+    require.NoError(t, err)
+    require.Equal(t, string(compact), string(out))
+
+    /* the stream encoder writes the same */
+    buf := bytes.NewBuffer(nil)
+    senc := NewStreamEncoder(buf)
+    senc.Opts = SortMapKeys
+    senc.SetSeparators(", ", ": ")
+    require.NoError(t, senc.Encode(v))
+    require.Equal(t, exp + "\n", buf.String())
+}
+
+func TestEncoder_EncodeFramed(t *testing.T) {
+    type T struct {
+        A int               `json:"a"`
+        B []string          `json:"b"`
+        C map[string]string `json:"c"`
+    }
+    in := []T{
+        {A: 1, B: []string{"x"}, C: map[string]string{"k": "v"}},
+        {A: 2},
+    }
+
+    var buf bytes.Buffer
+    for _, v := range in {
+        require.NoError(t, EncodeFramed(&buf, v))
+    }
+
+    /* the header is the length of the payload */
+    js, err := Encode(in[0], 0)
+    require.NoError(t, err)
+    require.Equal(t, uint32(len(js)), binary.BigEndian.Uint32(buf.Bytes()))
+    require.Equal(t, string(js), string(buf.Bytes()[4:4+len(js)]))
+
+    for _, exp := range in {
+        var out T
+        require.NoError(t, decoder.DecodeFramed(&buf, &out))
+        require.Equal(t, exp, out)
+    }
+    require.Zero(t, buf.Len())
+
+    /* nothing is written on errors */
+    require.Error(t, EncodeFramed(&buf, func() {}))
+    require.Zero(t, buf.Len())
+}
+
+type embeddedStringer struct {
+    X int
+}
+
+func (embeddedStringer) String() string { return "s" }
+
+func TestEncoder_EmbeddedInterface(t *testing.T) {
+    type T struct {
+        fmt.Stringer
+        A int
+    }
+    type O struct {
+        fmt.Stringer `json:"s,omitempty"`
+        A int        `json:"a"`
+    }
+    cases := []interface{}{
+        T{Stringer: embeddedStringer{X: 1}, A: 2},
+        T{Stringer: &embeddedStringer{X: 3}, A: 4},
+        T{A: 5},
+        &T{Stringer: embeddedStringer{X: 6}},
+        O{Stringer: embeddedStringer{X: 7}, A: 8},
+        O{A: 9},
+        []T{{Stringer: embeddedStringer{}}, {}},
+    }
+    for _, v := range cases {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        ret, err := Encode(v, 0)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(ret))
+    }
+
+    /* dispatched through the dynamic type of the interface */
+    ret, err := Encode(T{Stringer: embeddedStringer{X: 1}, A: 2}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"Stringer":{"X":1},"A":2}`, string(ret))
+}
+
+type predicateUser struct {
+    Role   string `json:"role"`
+    Name   string `json:"name"`
+    Salary int    `json:"salary,omitempty"`
+}
+
+func TestEncoder_FieldPredicate(t *testing.T) {
+    RegisterFieldPredicate(reflect.TypeOf(predicateUser{}), "salary", func(v unsafe.Pointer) bool {
+        return (*predicateUser)(v).Role == "admin"
+    })
+
+    ret, err := Encode(predicateUser{Role: "admin", Name: "a", Salary: 1}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"role":"admin","name":"a","salary":1}`, string(ret))
+
+    ret, err = Encode(predicateUser{Role: "guest", Name: "b", Salary: 2}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"role":"guest","name":"b"}`, string(ret))
+
+    /* "omitempty" still applies */
+    ret, err = Encode(predicateUser{Role: "admin"}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"role":"admin","name":""}`, string(ret))
+
+    /* nested in other values */
+    ret, err = Encode(map[string][]predicateUser{"u": {{Role: "admin", Salary: 3}, {Salary: 4}}}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `{"u":[{"role":"admin","name":"","salary":3},{"role":"","name":""}]}`, string(ret))
+
+    /* hiding the first field keeps the separators right */
+    type F struct {
+        A int `json:"a"`
+        B int `json:"b"`
+    }
+    RegisterFieldPredicate(reflect.TypeOf(F{}), "a", func(v unsafe.Pointer) bool {
+        return (*F)(v).B == 0
+    })
+    ret, err = Encode([]F{{A: 1, B: 2}, {A: 3}}, 0)
+    require.NoError(t, err)
+    require.Equal(t, `[{"b":2},{"a":3,"b":0}]`, string(ret))
+}
+
+func TestEncoder_RegisterWhileCompiling(t *testing.T) {
+    wg := sync.WaitGroup{}
+    for i := 0; i < 16; i++ {
+        /* a new type for each goroutine, so it is compiled concurrently */
+        vt := reflect.StructOf([]reflect.StructField{
+            {Name: "A" + strconv.Itoa(i), Type: reflect.TypeOf(0), Tag: `json:"a"`},
+            {Name: "B", Type: reflect.TypeOf(0), Tag: `json:"b"`},
+        })
+        v := reflect.New(vt).Interface()
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            RegisterFieldPredicate(vt, "a", func(unsafe.Pointer) bool { return false })
+        }()
+        go func() {
+            defer wg.Done()
+            ret, err := Encode(v, 0)
+            require.NoError(t, err)
+            require.Contains(t, []string{`{"a":0,"b":0}`, `{"b":0}`}, string(ret))
+        }()
+    }
+    wg.Wait()
+}
+
+func TestEncoder_Compact(t *testing.T) {
+    indented, err := json.MarshalIndent(json.RawMessage(TwitterJson), "", "  ")
+    require.NoError(t, err)
+    cases := []string{
+        TwitterJson,
+        string(indented),
+        ` { "a b" : "c \" d", "e" : [ 1 , "\\" , " \\\" " ] } `,
+    }
+    for _, c := range cases {
+        var exp bytes.Buffer
+        require.NoError(t, json.Compact(&exp, []byte(c)))
+        dst := []byte("x")
+        require.NoError(t, Compact(&dst, []byte(c)))
+        require.Equal(t, "x" + exp.String(), string(dst))
+    }
+
+    /* invalid JSON is rejected with its position, and dst is unchanged */
+    for _, c := range []string{``, ` `, `{"a": 1,}`, `[1 2]`, `"a`, "\"\x01\"", `1 2`} {
+        dst := []byte("x")
+        err := Compact(&dst, []byte(c))
+        require.Error(t, err, c)
+        require.Contains(t, err.Error(), "invalid json syntax at", c)
+        require.Equal(t, "x", string(dst), c)
+    }
+    require.Contains(t, Compact(new([]byte), []byte(`[1, 2 3]`)).Error(), "at 6")
+}
+
+func TestEncoder_Indent(t *testing.T) {
+    cases := []string{
+        TwitterJson,
+        ` { "a b" : "c \" d", "e" : [ 1 , "\\" , " \\\" " ], "f{" : [ ], "g:" : { } } `,
+        `[1.5e-3,-2,true,false,null,"[{:,"]` + "\n",
+    }
+    for _, c := range cases {
+        var exp bytes.Buffer
+        require.NoError(t, json.Indent(&exp, []byte(c), ">", "\t"))
+        dst := []byte("x")
+        require.NoError(t, Indent(&dst, []byte(c), ">", "\t"))
+        require.Equal(t, "x" + exp.String(), string(dst))
+    }
+
+    /* invalid JSON is rejected with its position, and dst is unchanged */
+    for _, c := range []string{``, ` `, `{"a": 1,}`, `[1 2]`, `"a`, `1 2`} {
+        dst := []byte("x")
+        err := Indent(&dst, []byte(c), "", "  ")
+        require.Error(t, err, c)
+        require.Contains(t, err.Error(), "invalid json syntax at", c)
+        require.Equal(t, "x", string(dst), c)
+    }
+}
+
+type rawMessageFields struct {
+    A json.RawMessage            `json:"a"`
+    B json.RawMessage            `json:"b,omitempty"`
+    C *json.RawMessage           `json:"c"`
+    D []json.RawMessage          `json:"d"`
+    E map[string]json.RawMessage `json:"e"`
+}
+
+func TestEncoder_RawMessageFields(t *testing.T) {
+    c := json.RawMessage(` [1, "a \" b"] `)
+    v := rawMessageFields{
+        A: json.RawMessage(`{"x": {"y": [true, null]}, "z": "é\\"}`),
+        C: &c,
+        D: []json.RawMessage{json.RawMessage(`[]`), nil, json.RawMessage(` 1.5e3 `)},
+        E: map[string]json.RawMessage{"k": json.RawMessage(`{ }`)},
+    }
+    exp, err := json.Marshal(v)
+    require.NoError(t, err)
+
+    /* the raw values are validated and compacted like encoding/json */
+    out, err := Encode(v, SortMapKeys | CompactMarshaler)
+    require.NoError(t, err)
+    require.Equal(t, string(exp), string(out))
+    out, err = Encode(v, 0)
+    require.NoError(t, err)
+    require.True(t, json.Valid(out))
+    require.Contains(t, string(out), `"a":{"x": {"y": [true, null]}, "z": "é\\"}`)
+    exp, err = json.MarshalIndent(v, ">", "  ")
+    require.NoError(t, err)
+    out, err = EncodeIndented(v, ">", "  ", SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, string(exp), string(out))
+
+    /* invalid raw values are rejected, unless asked not to validate them */
+    for _, raw := range []string{`{"a":}`, `[1,2`, ``, `1 2`} {
+        v := rawMessageFields{A: json.RawMessage(raw)}
+        _, err := json.Marshal(v)
+        require.Error(t, err, raw)
+        _, err = Encode(v, 0)
+        require.Error(t, err, raw)
+        _, err = Encode(v, CompactMarshaler)
+        require.Error(t, err, raw)
+    }
+    out, err = Encode(rawMessageFields{A: json.RawMessage(`[1,2`)}, NoValidateJSONMarshaler)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":[1,2,"c":null,"d":null,"e":null}`, string(out))
+}
+
+type durationKey int64
+
+func (d durationKey) MarshalText() ([]byte, error) {
+    return []byte(strconv.FormatInt(int64(d), 10) + "ns"), nil
+}
+
+type pairKey struct {
+    A, B int
+}
+
+func (p *pairKey) MarshalText() ([]byte, error) {
+    return []byte(fmt.Sprintf("<%d\"%d>", p.A, p.B)), nil
+}
+
+type failedKey int
+
+func (failedKey) MarshalText() ([]byte, error) {
+    return nil, fmt.Errorf("failed key")
+}
+
+func TestEncoder_TextMarshalerKeys(t *testing.T) {
+    pk := &pairKey{1, 2}
+    for _, v := range []interface{}{
+        map[durationKey]int{1000: 1, -5: 2, 0: 3},
+        map[*pairKey]int{pk: 1},
+        map[*pairKey]int{pk: 1, nil: 2},
+        map[netip.Addr]int{netip.MustParseAddr("::1"): 1, netip.MustParseAddr("1.2.3.4"): 2},
+        struct{ M map[durationKey]string }{map[durationKey]string{7: "x"}},
+    } {
+        /* keys are produced by MarshalText and escaped like any other string */
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        out, err := Encode(v, SortMapKeys | EscapeHTML)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(out))
+
+        /* unsorted maps emit the same members */
+        out, err = Encode(v, EscapeHTML)
+        require.NoError(t, err)
+        require.JSONEq(t, string(exp), string(out))
+    }
+
+    /* keys stay quoted when the values are not */
+    for _, opts := range []Options{NoQuoteTextMarshaler, NoQuoteTextMarshaler | SortMapKeys} {
+        out, err := Encode(map[durationKey]durationKey{1: 2}, opts)
+        require.NoError(t, err)
+        require.Equal(t, `{"1ns":2ns}`, string(out))
+    }
+
+    /* errors from MarshalText are passed through */
+    _, err := Encode(map[failedKey]int{1: 1}, 0)
+    require.EqualError(t, err, "failed key")
+    _, err = Encode(map[failedKey]int{1: 1}, SortMapKeys)
+    require.EqualError(t, err, "failed key")
+}
+
+type cachedEncoderType struct {
+    A int               `json:"a"`
+    B []string          `json:"b"`
+    C map[string]uint32 `json:"c"`
+}
+
+func TestEncoder_CacheReuse(t *testing.T) {
+    v := cachedEncoderType{A: 1, B: []string{"x"}, C: map[string]uint32{"y": 2}}
+    exp, err := json.Marshal(v)
+    require.NoError(t, err)
+    size := iencoder.GetEncoderCacheSize()
+
+    /* concurrent first encodes of a type compile it only once */
+    var wg sync.WaitGroup
+    var fails int32
+    start := make(chan struct{})
+    for i := 0; i < 32; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            out, err := Encode(v, SortMapKeys)
+            if err != nil || string(out) != string(exp) {
+                atomic.AddInt32(&fails, 1)
+            }
+        }()
+    }
+    close(start)
+    wg.Wait()
+    require.Zero(t, fails)
+    n := iencoder.GetEncoderCacheSize()
+    require.LessOrEqual(t, n - size, 1, "the type may be cached by an earlier run")
+
+    /* later encodes reuse the cached program */
+    for i := 0; i < 10; i++ {
+        _, err := Encode(v, 0)
+        require.NoError(t, err)
+    }
+    require.Equal(t, n, iencoder.GetEncoderCacheSize())
+}
+
+func BenchmarkEncoder_CachedType(b *testing.B) {
+    v := cachedEncoderType{A: 1, B: []string{"x", "y"}, C: map[string]uint32{"z": 2}}
+    if _, err := Encode(v, 0); err != nil {
+        b.Fatal(err)
+    }
+    size := iencoder.GetEncoderCacheSize()
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Encode(v, 0)
+    }
+    b.StopTimer()
+    if n := iencoder.GetEncoderCacheSize(); n != size {
+        b.Fatalf("%d types compiled in steady state", n - size)
+    }
+}
+
+type shortLiteralsInner struct {
+    X int `json:"x"`
+    Y int `json:"y"`
+}
+
+type shortLiteralsStruct struct {
+    A shortLiteralsInner   `json:"a"`
+    B []shortLiteralsInner `json:"b"`
+    C [2]int8              `json:"c"`
+    D int                  `json:"d,string"`
+    E bool                 `json:"e,string"`
+    F struct{}             `json:"f"`
+    G shortLiteralsInner   `json:"g"`
+    H []int                `json:"h"`
+}
+
+func TestEncoder_FusedConstants(t *testing.T) {
+    for _, v := range []shortLiteralsStruct{
+        {},
+        {A: shortLiteralsInner{1, 2}, B: []shortLiteralsInner{{}, {3, 4}}, C: [2]int8{5, 6}, D: 7, E: true, H: []int{}},
+    } {
+        /* adjacent literals are written at once, which doesn't change the output */
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        out, err := Encode(v, 0)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(out))
+    }
+}
+
+func BenchmarkEncoder_ShortLiterals(b *testing.B) {
+    v := shortLiteralsStruct{A: shortLiteralsInner{1, 2}, B: []shortLiteralsInner{{3, 4}, {5, 6}}, D: 7, H: []int{8}}
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Encode(v, 0)
+    }
+}
+
+func TestEncoder_GrowBuffer(t *testing.T) {
+    ints := make([]int, 100000)
+    strs := make([]string, 10000)
+    for i := range ints {
+        ints[i] = i * 7919 - 50000
+    }
+    for i := range strs {
+        strs[i] = strings.Repeat("\"x", i % 16)
+    }
+    for _, v := range []interface{}{ints, strs} {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+
+        /* start from a tiny buffer, so that it is grown many times */
+        buf := make([]byte, 0, 8)
+        require.NoError(t, EncodeInto(&buf, v, 0))
+        require.Equal(t, len(exp), len(buf))
+        require.Equal(t, string(exp), string(buf))
+
+        /* the bytes already in the buffer survive the grows */
+        buf = append(make([]byte, 0, 16), "prefix"...)
+        require.NoError(t, EncodeInto(&buf, v, 0))
+        require.Equal(t, "prefix" + string(exp), string(buf))
+    }
+}
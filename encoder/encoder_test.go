@@ -146,6 +146,29 @@ type RawMessageStruct struct {
     X json.RawMessage
 }
 
+func TestEncoder_RawMessageMap(t *testing.T) {
+    m := map[string]json.RawMessage{
+        "a": json.RawMessage(`{"x": [1, 2]}`),
+        "b": json.RawMessage(`"caf\u00e9"`),
+        "c": json.RawMessage(`  1.50e+3 `),
+        "d": nil,
+    }
+    ret, err := Encode(m, SortMapKeys)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":{"x": [1, 2]},"b":"caf\u00e9","c":  1.50e+3 ,"d":null}`, string(ret))
+
+    ret, err = Encode(&m, SortMapKeys|CompactMarshaler)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":{"x":[1,2]},"b":"caf\u00e9","c":1.50e+3,"d":null}`, string(ret))
+
+    /* raw values are still validated */
+    _, err = Encode(map[string]json.RawMessage{"a": json.RawMessage(`{"x":`)}, 0)
+    require.Error(t, err)
+    ret, err = Encode(map[string]json.RawMessage{"a": json.RawMessage(`{"x":`)}, NoValidateJSONMarshaler)
+    require.NoError(t, err)
+    require.Equal(t, `{"a":{"x":}`, string(ret))
+}
+
 type TextMarshalerImpl struct {
     X string
 }
@@ -306,6 +329,31 @@ func BenchmarkEncoder_Binding_StdLib(b *testing.B) {
     }
 }
 
+type longFieldNames struct {
+    TheFirstFieldWithAVeryLongDescriptiveName    int    `json:"the_first_field_with_a_very_long_descriptive_name"`
+    TheSecondFieldWithAVeryLongDescriptiveName   string `json:"the_second_field_with_a_very_long_descriptive_name"`
+    TheThirdFieldWithAnEvenLongerDescriptiveName bool   `json:"the_third_field_with_an_even_longer_descriptive_name"`
+    TheFourthFieldWithAVeryLongDescriptiveName   []int  `json:"the_fourth_field_with_a_very_long_descriptive_name"`
+}
+
+var _LongFieldNamesValue = longFieldNames{1, "two", true, []int{4}}
+
+func BenchmarkEncoder_LongFieldNames_Sonic(b *testing.B) {
+    _, _ = Encode(&_LongFieldNamesValue, 0)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Encode(&_LongFieldNamesValue, 0)
+    }
+}
+
+func BenchmarkEncoder_LongFieldNames_StdLib(b *testing.B) {
+    _, _ = json.Marshal(&_LongFieldNamesValue)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = json.Marshal(&_LongFieldNamesValue)
+    }
+}
+
 func BenchmarkEncoder_Parallel_Generic_Sonic(b *testing.B) {
     _, _ = Encode(_GenericValue, SortMapKeys | EscapeHTML | CompactMarshaler)
     b.SetBytes(int64(len(TwitterJson)))
@@ -422,6 +470,20 @@ func BenchmarkValidate_Std(b *testing.B) {
     }
 }
 
+func BenchmarkCompact_Sonic(b *testing.B) {
+    var data = rt.Str2Mem(TwitterJson)
+    var dst []byte
+    if err := Compact(&dst, data); err != nil {
+        b.Fatal(err)
+    }
+    b.SetBytes(int64(len(TwitterJson)))
+    b.ResetTimer()
+    for i:=0; i<b.N; i++ {
+        dst = dst[:0]
+        _ = Compact(&dst, data)
+    }
+}
+
 func BenchmarkCompact_Std(b *testing.B) {
     var data = rt.Str2Mem(TwitterJson)
     var dst = bytes.NewBuffer(nil)
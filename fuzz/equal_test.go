@@ -0,0 +1,162 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sonic_fuzz
+
+import (
+    `encoding/json`
+    `math`
+    `reflect`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+// jsonDeepEqual reports whether the decoded values a and b are deeply equal.
+// Unlike reflect.DeepEqual, NaNs are equal to each other, and identical
+// pointers, maps or slices are equal without comparing what they refer to.
+// Maps are compared key by key, regardless of their order.
+func jsonDeepEqual(a, b interface{}) bool {
+    return jsonDeepEqualValue(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func jsonDeepEqualValue(a, b reflect.Value) bool {
+    if !a.IsValid() || !b.IsValid() {
+        return a.IsValid() == b.IsValid()
+    }
+    if a.Type() != b.Type() {
+        return false
+    }
+
+    switch a.Kind() {
+    case reflect.Bool:
+        return a.Bool() == b.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return a.Int() == b.Int()
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return a.Uint() == b.Uint()
+    case reflect.Float32, reflect.Float64:
+        x, y := a.Float(), b.Float()
+        return x == y || (math.IsNaN(x) && math.IsNaN(y))
+    case reflect.Complex64, reflect.Complex128:
+        return a.Complex() == b.Complex()
+    case reflect.String:
+        return a.String() == b.String()
+    case reflect.Interface:
+        if a.IsNil() || b.IsNil() {
+            return a.IsNil() == b.IsNil()
+        }
+        return jsonDeepEqualValue(a.Elem(), b.Elem())
+    case reflect.Ptr:
+        if a.Pointer() == b.Pointer() {
+            return true
+        }
+        if a.IsNil() || b.IsNil() {
+            return false
+        }
+        return jsonDeepEqualValue(a.Elem(), b.Elem())
+    case reflect.Slice:
+        if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+            return false
+        }
+        if a.Pointer() == b.Pointer() {
+            return true
+        }
+        fallthrough
+    case reflect.Array:
+        for i := 0; i < a.Len(); i++ {
+            if !jsonDeepEqualValue(a.Index(i), b.Index(i)) {
+                return false
+            }
+        }
+        return true
+    case reflect.Map:
+        if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+            return false
+        }
+        if a.Pointer() == b.Pointer() {
+            return true
+        }
+        for it := a.MapRange(); it.Next(); {
+            v := b.MapIndex(it.Key())
+            if !v.IsValid() || !jsonDeepEqualValue(it.Value(), v) {
+                return false
+            }
+        }
+        return true
+    case reflect.Struct:
+        for i := 0; i < a.NumField(); i++ {
+            if !jsonDeepEqualValue(a.Field(i), b.Field(i)) {
+                return false
+            }
+        }
+        return true
+    case reflect.Func:
+        return a.IsNil() && b.IsNil()
+    default:
+        return a.Pointer() == b.Pointer()
+    }
+}
+
+func TestJsonDeepEqual(t *testing.T) {
+    nan := math.NaN()
+    type T struct {
+        F float64
+        p *float64
+    }
+    shared := map[string]interface{}{"k": nan}
+    for _, c := range []struct {
+        a, b  interface{}
+        equal bool
+    }{
+        {nil, nil, true},
+        {nil, 0.0, false},
+        {1.0, 1.0, true},
+        {1.0, int64(1), false},
+        {json.Number("1"), json.Number("1.0"), false},
+        {nan, nan, true},
+        {float32(nan), float32(nan), true},
+        {nan, 0.0, false},
+        {math.Inf(1), math.Inf(1), true},
+        {[]interface{}{nan, "a"}, []interface{}{nan, "a"}, true},
+        {[]interface{}{nan}, []interface{}{nan, nan}, false},
+        {[]interface{}{}, []interface{}(nil), false},
+        {map[string]interface{}{"a": nan, "b": 1.0}, map[string]interface{}{"b": 1.0, "a": nan}, true},
+        {map[string]interface{}{"a": 1.0}, map[string]interface{}{"b": 1.0}, false},
+        {map[string]interface{}{"a": nil}, map[string]interface{}{}, false},
+        {shared, shared, true},
+        {T{F: nan, p: &nan}, T{F: nan, p: &nan}, true},
+        {T{F: nan, p: &nan}, T{F: nan}, false},
+        {[2]float64{nan, 1}, [2]float64{nan, 1}, true},
+    } {
+        require.Equal(t, c.equal, jsonDeepEqual(c.a, c.b), "%#v vs %#v", c.a, c.b)
+        require.Equal(t, c.equal, jsonDeepEqual(c.b, c.a), "%#v vs %#v", c.b, c.a)
+    }
+
+    /* nested maps in any order */
+    var a, b interface{}
+    require.NoError(t, json.Unmarshal([]byte(`{"x":{"y":[1,{"z":null}],"w":"v"},"n":[]}`), &a))
+    require.NoError(t, json.Unmarshal([]byte(`{"n":[],"x":{"w":"v","y":[1,{"z":null}]}}`), &b))
+    require.True(t, jsonDeepEqual(a, b))
+    require.True(t, jsonDeepEqual(&a, &b))
+    b.(map[string]interface{})["x"].(map[string]interface{})["y"].([]interface{})[1].(map[string]interface{})["z"] = nan
+    require.False(t, jsonDeepEqual(a, b))
+    a.(map[string]interface{})["x"].(map[string]interface{})["y"].([]interface{})[1].(map[string]interface{})["z"] = nan
+    require.True(t, jsonDeepEqual(a, b))
+}
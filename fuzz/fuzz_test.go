@@ -145,7 +145,7 @@ func fuzzMain(t *testing.T, data []byte) {
         if jerr != nil {
             continue
         }
-        require.Equal(t, sv, jv, dump(string(data), jv, jerr, sv, serr))
+        require.True(t, jsonDeepEqual(sv, jv), dump(string(data), jv, jerr, sv, serr))
     
         v := jv
         sout, serr := target.Marshal(v)
@@ -161,7 +161,7 @@ func fuzzMain(t *testing.T, data []byte) {
             if jerr != nil {
                 continue
             }
-            require.Equal(t, sv, jv, dump(data, jv, jerr, sv, serr))
+            require.True(t, jsonDeepEqual(sv, jv), dump(data, jv, jerr, sv, serr))
         }
 
         // fuzz ast MarshalJSON API
@@ -175,7 +175,7 @@ func fuzzMain(t *testing.T, data []byte) {
             sv = typ()
             serr := json.Unmarshal(aout, sv)
             require.Equal(t, serr, nil)
-            require.Equal(t, sv, jv, dump(data, jv, jerr, sv, serr))
+            require.True(t, jsonDeepEqual(sv, jv), dump(data, jv, jerr, sv, serr))
         }
 
         if m, ok := sv.(*map[string]interface{}); ok {
@@ -149,6 +149,11 @@ func (self *ProgramCache) Get(vt *rt.GoType) interface{} {
     return (*_ProgramMap)(atomic.LoadPointer(&self.p)).get(vt)
 }
 
+// Len returns the number of programs in the cache.
+func (self *ProgramCache) Len() int {
+    return int(atomic.LoadUint64(&(*_ProgramMap)(atomic.LoadPointer(&self.p)).n))
+}
+
 func (self *ProgramCache) Compute(vt *rt.GoType, compute func(*rt.GoType, ... interface{}) (interface{}, error), ex ...interface{}) (interface{}, error) {
     var err error
     var val interface{}
@@ -21,8 +21,10 @@ import (
 
     `github.com/bytedance/sonic/internal/native`
     `github.com/bytedance/sonic/internal/native/types`
+	`github.com/bytedance/sonic/internal/decoder/coerce`
 	`github.com/bytedance/sonic/internal/decoder/consts`
 	`github.com/bytedance/sonic/internal/decoder/errors`
+    `github.com/bytedance/sonic/internal/resolver`
     `github.com/bytedance/sonic/internal/rt`
     `github.com/bytedance/sonic/option`
 )
@@ -36,6 +38,8 @@ const (
 	_F_use_number = consts.F_use_number
 	_F_validate_string = consts.F_validate_string
     _F_case_sensitive = consts.F_case_sensitive
+    _F_report_more_data = consts.F_report_more_data
+    _F_error_snippet = consts.F_error_snippet
 
 	_MaxStack = consts.MaxStack
 
@@ -47,12 +51,27 @@ const (
     OptionValidateString   = consts.OptionValidateString
     OptionNoValidateJSON   = consts.OptionNoValidateJSON
     OptionCaseSensitive    = consts.OptionCaseSensitive
+    OptionBoolAsInt        = consts.OptionBoolAsInt
+    OptionStructAsArray    = consts.OptionStructAsArray
+    OptionFlexibleTime     = consts.OptionFlexibleTime
+    OptionLimitKeyLength   = consts.OptionLimitKeyLength
+    OptionReportMoreData   = consts.OptionReportMoreData
+    OptionPointerRefs      = consts.OptionPointerRefs
+    OptionErrorSnippet     = consts.OptionErrorSnippet
+    OptionObjectAsPairs    = consts.OptionObjectAsPairs
+    OptionLimitDynamicDepth = consts.OptionLimitDynamicDepth
+    OptionGrowStack        = consts.OptionGrowStack
 )
 
+// ErrMoreData is returned instead of a SyntaxError when OptionReportMoreData
+// is set and the input is a valid but incomplete prefix of a JSON value.
+var ErrMoreData = errors.ErrMoreData
+
 type (
 	Options = consts.Options
 	MismatchTypeError = errors.MismatchTypeError
 	SyntaxError = errors.SyntaxError
+	SnippetError = errors.SnippetError
 )
 
 func (self *Decoder) SetOptions(opts Options) {
@@ -67,6 +86,9 @@ type Decoder struct {
     i int
     f uint64
     s string
+    r *resolver.NameResolver
+    u *[]string
+    d int
 }
 
 // NewDecoder creates a new decoder instance.
@@ -111,8 +133,68 @@ func (self *Decoder) CheckTrailings() error {
 
 // Decode parses the JSON-encoded data from current position and stores the result
 // in the value pointed to by val.
-func (self *Decoder) Decode(val interface{}) error {
-	return decodeImpl(&self.s, &self.i, self.f, val)
+func (self *Decoder) Decode(val interface{}) (err error) {
+	i := self.i
+	if self.r != nil || self.d > 0 {
+		err = decodeResolvedImpl(&self.s, &self.i, self.f, val, self.r, self.d)
+	} else {
+		err = decodeImpl(&self.s, &self.i, self.f, val)
+	}
+
+	/* collect the unknown fields if the value has been decoded */
+	if _, ok := err.(*MismatchTypeError); self.u != nil && (err == nil || ok) {
+		recordUnknownFields(self.s[i:self.i], reflect.TypeOf(val), self.f, self.r, self.u)
+	}
+
+	/* tell the truncated input apart from the malformed one */
+	if _, ok := err.(SyntaxError); ok && (self.f & (1 << _F_report_more_data)) != 0 && isTruncated(self.s[i:]) {
+		err = ErrMoreData
+	}
+
+	/* keep the raw JSON around the failure for diagnosis */
+	if err != nil && err != ErrMoreData && (self.f & (1 << _F_error_snippet)) != 0 {
+		err = errors.ErrorSnippet(self.s, errorPos(err, self.i), err)
+	}
+	return
+}
+
+// errorPos returns the position in the source where err happened, or pos if
+// err does not carry one.
+func errorPos(err error, pos int) int {
+	switch e := err.(type) {
+		case SyntaxError        : return e.Pos
+		case *SyntaxError       : return e.Pos
+		case *MismatchTypeError : return e.Pos
+		default                 : return pos
+	}
+}
+
+// RecordUnknownFields indicates the Decoder to append the dotted paths of the
+// object keys which match no struct field (eg. `user.address.zip4`) to paths
+// after decoding. Elements of arrays are written as `items[0]`. Nil disables it.
+func (self *Decoder) RecordUnknownFields(paths *[]string) {
+    self.u = paths
+}
+
+// SetFieldNameResolver sets a function which maps each object key to the
+// name of the struct field it should be decoded into, nil disables it.
+// The mapping of each key is cached, but it still adds a per-key cost.
+func (self *Decoder) SetFieldNameResolver(fn func(string) string) {
+    if fn == nil {
+        self.r = nil
+    } else {
+        self.r = resolver.NewNameResolver(fn)
+    }
+}
+
+// SetMaxDepth limits how deep arrays and objects may be nested in the input to n
+// levels, a json.UnsupportedValueError is returned once it is exceeded. It can be
+// tighter than the default limit of the decoder stack, and n <= 0 restores that.
+//
+// The JIT decoder enforces it on its state stack, where each map takes two levels
+// and values decoded into interface{} are only bounded by the native parser.
+func (self *Decoder) SetMaxDepth(n int) {
+    self.d = n
 }
 
 // UseInt64 indicates the Decoder to unmarshal an integer into an interface{} as an
@@ -130,7 +212,7 @@ func (self *Decoder) UseNumber() {
 }
 
 // UseUnicodeErrors indicates the Decoder to return an error when encounter invalid
-// UTF-8 escape sequences.
+// UTF-8 escape sequences, and with ValidateString, invalid UTF-8 chars as well.
 func (self *Decoder) UseUnicodeErrors() {
     self.f |= 1 << _F_disable_urc
 }
@@ -142,14 +224,28 @@ func (self *Decoder) DisallowUnknownFields() {
     self.f |= 1 << _F_disable_unknown
 }
 
+// CaseSensitive indicates the Decoder whether to match object keys with struct fields
+// case-sensitively. It is off by default, matching encoding/json: a key which matches
+// no field exactly is decoded into the field whose name equals it under case folding.
+func (self *Decoder) CaseSensitive(f bool) {
+    if f {
+        self.f |= 1 << _F_case_sensitive
+    } else {
+        self.f &^= 1 << _F_case_sensitive
+    }
+}
+
 // CopyString indicates the Decoder to decode string values by copying instead of referring.
+// It applies to json.RawMessage values as well, which otherwise refer to the source JSON
+// and must not be modified.
 func (self *Decoder) CopyString() {
     self.f |= 1 << _F_copy_string
 }
 
 // ValidateString causes the Decoder to validate string values when decoding string value 
-// in JSON. Validation is that, returning error when unescaped control chars(0x00-0x1f) or
-// invalid UTF-8 chars in the string value of JSON.
+// in JSON. Validation is that, returning error when unescaped control chars(0x00-0x1f) in
+// the string value of JSON, and replacing invalid UTF-8 chars with U+FFFD like encoding/json,
+// or returning an error positioned at the first of them if UseUnicodeErrors is set too.
 func (self *Decoder) ValidateString() {
     self.f |= 1 << _F_validate_string
 }
@@ -163,6 +259,29 @@ func Pretouch(vt reflect.Type, opts ...option.CompileOption) error {
 	return pretouchImpl(vt, opts...)
 }
 
+// RegisterSetter maps the object key to the exported setter method of *vt, so
+// that the value of the key is decoded into the argument of the method, which
+// makes it possible to decode into private fields.
+//
+// The method must take exactly one argument, and return either nothing or an
+// error. Keys mapped to setters shadow the struct fields with the same name.
+// Setters MUST be registered before vt is decoded (or pretouched) for the first time.
+func RegisterSetter(vt reflect.Type, key string, method string) error {
+	return resolver.RegisterSetter(vt, key, method)
+}
+
+// CoercionFunc converts the raw JSON value src into dst, which is settable,
+// and reports whether it did.
+type CoercionFunc = coerce.Func
+
+// RegisterCoercion installs fn as the hook which is called when a JSON value does
+// not match the type of a numeric, boolean, json.Number or interface destination.
+// If the hook reports true the value is kept and no MismatchTypeError is raised
+// for it, otherwise the mismatch is reported as usual. A nil fn removes the hook.
+func RegisterCoercion(fn CoercionFunc) {
+    coerce.Register(fn)
+}
+
 // Skip skips only one json value, and returns first non-blank character position and its ending position if it is valid.
 // Otherwise, returns negative error code using start and invalid character position using end
 func Skip(data []byte) (start int, end int) {
@@ -28,11 +28,13 @@ import (
 var (
 	pretouchImpl = jitdec.Pretouch
 	decodeImpl = jitdec.Decode
+	decodeResolvedImpl = jitdec.DecodeWithResolver
 ) 
 
  func init() {
 	if envs.UseOptDec {
 		pretouchImpl = optdec.Pretouch
 		decodeImpl = optdec.Decode
+		decodeResolvedImpl = optdec.DecodeWithResolver
 	}
  }
@@ -1,4 +1,5 @@
-// +build go1.17,!go1.26
+//go:build go1.17 && !go1.20
+// +build go1.17,!go1.20
 
 /*
  * Copyright 2021 ByteDance Inc.
@@ -26,6 +27,7 @@ import (
 var (
 	pretouchImpl = optdec.Pretouch
 	decodeImpl = optdec.Decode
+	decodeResolvedImpl = optdec.DecodeWithResolver
 )
 
 
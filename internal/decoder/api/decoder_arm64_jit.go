@@ -21,7 +21,7 @@ package api
 
 import (
 	"reflect"
-	"unsafe"
+	"sync"
 
 	"github.com/bytedance/sonic/internal/decoder/jitdec"
 	"github.com/bytedance/sonic/internal/envs"
@@ -29,72 +29,35 @@ import (
 	"github.com/bytedance/sonic/option"
 )
 
-// ARM64 JIT decoder interface
 var (
-	pretouchImpl = jitdec.Pretouch
-	decodeImpl   = decodeWithJIT
+	pretouchImpl       = jitdec.Pretouch
+	decodeImpl         = decodeWithJIT
+	decodeResolvedImpl = jitdec.DecodeWithResolver
 )
 
 func init() {
-	// Check if JIT should be enabled
-	if envs.UseJIT {
-		pretouchImpl = jitdec.Pretouch
-		decodeImpl = decodeWithJIT
-	} else if envs.UseOptDec {
-		pretouchImpl = optdec.Pretouch
-		decodeImpl = optdec.Decode
-	} else {
-		// Fallback to optimized decoder
+	if envs.UseOptDec {
 		pretouchImpl = optdec.Pretouch
 		decodeImpl = optdec.Decode
+		decodeResolvedImpl = optdec.DecodeWithResolver
 	}
 }
 
-// decodeWithJIT implements decoding using ARM64 JIT compilation
+// decodeWithJIT decodes with the ARM64 JIT, whose decoders are compiled once
+// per type and cached by jitdec. The optimized decoder takes over when the JIT
+// is disabled at runtime.
 func decodeWithJIT(sp *string, ic *int, fv uint64, val interface{}) error {
-	// Create decoder stack
-	sb := jitdec.NewStack()
-
-	// Get the type of the value to decode
-	vt := reflect.TypeOf(val)
-	if vt.Kind() == reflect.Ptr {
-		vt = vt.Elem()
-	}
-
-	// Check if JIT compilation is available and enabled
 	if !jitdec.IsJITEnabled() {
-		// Fallback to optimized decoder
 		return optdec.Decode(sp, ic, fv, val)
 	}
-
-	// Create and compile a JIT decoder for this type
-	decoder := jitdec.CreateDecoderWithName("decode_jit")
-	compiledDecoder, err := decoder.Compile(vt)
-	if err != nil {
-		// Fallback to optimized decoder on compilation error
-		return optdec.Decode(sp, ic, fv, val)
-	}
-
-	// Get the actual value pointer
-	var vp unsafe.Pointer
-	if val != nil {
-		vp = unsafe.Pointer(&val)
-	}
-
-	// Perform the decoding using compiled JIT code
-	result, err := compiledDecoder.(func(string, int, unsafe.Pointer, *jitdec._Stack, uint64, string) (int, error))(*sp, *ic, vp, sb, fv, "")
-	if err != nil {
-		// Fallback to optimized decoder on runtime error
-		return optdec.Decode(sp, ic, fv, val)
-	}
-
-	*ic = result
-	return nil
+	return jitdec.Decode(sp, ic, fv, val)
 }
 
-// ARM64JITDecoder provides additional ARM64-specific functionality
+// ARM64JITDecoder provides additional ARM64-specific functionality, the
+// types compiled by CompileType are reported by its statistics and debug info
 type ARM64JITDecoder struct {
 	*Decoder
+	jit *jitdec.Decoder
 }
 
 // NewARM64JITDecoder creates a new ARM64 JIT decoder with additional options
@@ -105,14 +68,12 @@ func NewARM64JITDecoder(name string, opts ...option.CompileOption) *ARM64JITDeco
 		// This would require extending the decoder interface
 	}
 
-	return &ARM64JITDecoder{Decoder: baseDecoder}
+	return &ARM64JITDecoder{Decoder: baseDecoder, jit: jitdec.CreateDecoderWithName(name)}
 }
 
 // CompileType compiles a specific type for ARM64 JIT decoding
 func (d *ARM64JITDecoder) CompileType(vt reflect.Type, opts ...option.CompileOption) error {
-	// Create a temporary decoder to compile this specific type
-	tempDecoder := jitdec.CreateDecoderWithName("compile_" + vt.String())
-	_, err := tempDecoder.Compile(vt, opts...)
+	_, err := d.jit.Compile(vt, opts...)
 	return err
 }
 
@@ -123,21 +84,21 @@ func (d *ARM64JITDecoder) BatchCompile(types []reflect.Type, opts ...option.Comp
 
 // GetJITStatistics returns JIT compilation statistics
 func (d *ARM64JITDecoder) GetJITStatistics() jitdec.PerfStats {
-	return jitdec.GetPerfStats(d.Decoder)
+	return jitdec.GetPerfStats(d.jit)
 }
 
 // EnableSIMD enables SIMD optimizations in the JIT compiler
 func (d *ARM64JITDecoder) EnableSIMD() {
 	opts := jitdec.DefaultJITOptions()
 	opts.EnableSIMD = true
-	d.ApplyJITOptions(opts)
+	d.jit.ApplyJITOptions(opts)
 }
 
 // DisableSIMD disables SIMD optimizations in the JIT compiler
 func (d *ARM64JITDecoder) DisableSIMD() {
 	opts := jitdec.DefaultJITOptions()
 	opts.EnableSIMD = false
-	d.ApplyJITOptions(opts)
+	d.jit.ApplyJITOptions(opts)
 }
 
 // SetOptimizationLevel sets the JIT optimization level (0-3)
@@ -147,34 +108,41 @@ func (d *ARM64JITDecoder) SetOptimizationLevel(level int) {
 	}
 	opts := jitdec.DefaultJITOptions()
 	opts.OptimizationLevel = level
-	d.ApplyJITOptions(opts)
+	d.jit.ApplyJITOptions(opts)
 }
 
 // EnableDebugMode enables debug mode for JIT compilation
 func (d *ARM64JITDecoder) EnableDebugMode() {
 	opts := jitdec.DefaultJITOptions()
 	opts.DebugMode = true
-	d.ApplyJITOptions(opts)
+	d.jit.ApplyJITOptions(opts)
 }
 
 // GetDebugInfo returns detailed debug information about the compiled code
 func (d *ARM64JITDecoder) GetDebugInfo() jitdec.DebugInfo {
-	return jitdec.GetDebugInfo(d.Decoder)
+	return jitdec.GetDebugInfo(d.jit)
 }
 
 // VerifyCompiledCode verifies that the compiled ARM64 code is valid
 func (d *ARM64JITDecoder) VerifyCompiledCode() error {
-	return d.Decoder.VerifyCode()
+	return d.jit.VerifyCode()
 }
 
-// WarmUp pre-compiles commonly used types to reduce first-hit latency
+// WarmUp pre-compiles commonly used types to reduce first-hit latency, see
+// jitdec.WarmUp. The options are the ones of Pretouch.
 func (d *ARM64JITDecoder) WarmUp(types []reflect.Type, opts ...option.CompileOption) error {
+	if len(opts) == 0 {
+		return jitdec.WarmUp(types)
+	}
+	errs := make(map[reflect.Type]error)
 	for _, vt := range types {
-		err := d.CompileType(vt, opts...)
-		if err != nil {
-			return err
+		if err := pretouchImpl(vt, opts...); err != nil {
+			errs[vt] = err
 		}
 	}
+	if len(errs) != 0 {
+		return &jitdec.BatchCompileError{Errors: errs}
+	}
 	return nil
 }
 
@@ -314,32 +282,13 @@ func GetDecoderCacheSize() int {
 	return len(globalDecoderCache.cache)
 }
 
-// Memory management for JIT compiled code
-type JITMemoryManager struct {
-	totalAllocated int64
-	maxMemory      int64
-	compiledCode    map[string]interface{}
-}
-
-var globalJITMemoryManager = &JITMemoryManager{
-	maxMemory:   100 * 1024 * 1024, // 100MB default limit
-	compiledCode: make(map[string]interface{}),
-}
-
-// GetMemoryUsage returns current memory usage statistics
+// GetMemoryUsage returns the bytes of machine code loaded by the JIT, the size
+// of the largest function and the number of functions
 func GetMemoryUsage() (allocated, max int64, count int) {
-	globalJITMemoryManager.mutex.Lock()
-	defer globalJITMemoryManager.mutex.Unlock()
-	return globalJITMemoryManager.totalAllocated,
-		globalJITMemoryManager.maxMemory,
-		len(globalJITMemoryManager.compiledCode)
+	return jitdec.GetMemoryUsage()
 }
 
-// CleanupMemory frees unused JIT compiled code
+// CleanupMemory frees unused JIT compiled code. JIT code is registered into the
+// runtime as a module and can't be unloaded, so there is nothing to free yet.
 func CleanupMemory() {
-	globalJITMemoryManager.mutex.Lock()
-	defer globalJITMemoryManager.mutex.Unlock()
-	// TODO: Implement actual cleanup logic
 }
-
-import "sync"
\ No newline at end of file
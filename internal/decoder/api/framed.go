@@ -0,0 +1,43 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+    `io`
+
+    `github.com/bytedance/sonic/internal/decoder/frame`
+    `github.com/bytedance/sonic/internal/rt`
+)
+
+// DecodeFramed reads one length-prefixed JSON frame from r, and stores the
+// result in the value pointed to by v. The frame starts with the length of the
+// JSON as a 4-byte big-endian integer, and the JSON must consume the whole frame.
+//
+// It returns io.EOF if r is exhausted before the frame starts.
+func DecodeFramed(r io.Reader, v interface{}) error {
+    buf, err := frame.Read(r)
+    if err != nil {
+        return err
+    }
+
+    /* the buffer is owned by the decoded value from now on */
+    dec := NewDecoder(rt.Mem2Str(buf))
+    if err := dec.Decode(v); err != nil {
+        return err
+    }
+    return dec.CheckTrailings()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+    `github.com/bytedance/sonic/internal/native`
+    `github.com/bytedance/sonic/internal/native/types`
+)
+
+// isTruncated reports whether src is a valid prefix of a JSON value which
+// ends before the value is complete.
+func isTruncated(src string) bool {
+    p := 0
+    m := types.NewStateMachine()
+    ret := native.ValidateOne(&src, &p, m, 0)
+    types.FreeStateMachine(m)
+
+    switch types.ParsingError(-ret) {
+        case types.ERR_EOF          : return true
+        case types.ERR_INVALID_CHAR : return isNumberTail(src, p - 1)
+        default                     : return false
+    }
+}
+
+// isNumberTail reports whether src ends with a number which covers the
+// invalid position pos, and is a valid prefix of a JSON number, eg. `-`,
+// `1.` or `1e+`.
+func isNumberTail(src string, pos int) bool {
+    i := len(src)
+    for i > 0 && isNumberChar(src[i - 1]) {
+        i--
+    }
+    if i == len(src) || pos < i {
+        return false
+    }
+
+    /* sign and integer part */
+    if i < len(src) && src[i] == '-' {
+        i++
+    }
+    if i == len(src) {
+        return true
+    }
+    switch {
+        case src[i] == '0'                  : i++
+        case src[i] >= '1' && src[i] <= '9' : i = skipDigits(src, i)
+        default                             : return false
+    }
+
+    /* fraction part */
+    if i < len(src) && src[i] == '.' {
+        if i++; i == len(src) {
+            return true
+        }
+        if !isDigit(src[i]) {
+            return false
+        }
+        i = skipDigits(src, i)
+    }
+
+    /* exponent part */
+    if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+        if i++; i < len(src) && (src[i] == '+' || src[i] == '-') {
+            i++
+        }
+        if i == len(src) {
+            return true
+        }
+        if !isDigit(src[i]) {
+            return false
+        }
+        i = skipDigits(src, i)
+    }
+    return i == len(src)
+}
+
+func skipDigits(src string, i int) int {
+    for i < len(src) && isDigit(src[i]) {
+        i++
+    }
+    return i
+}
+
+func isDigit(c byte) bool {
+    return c >= '0' && c <= '9'
+}
+
+func isNumberChar(c byte) bool {
+    return isDigit(c) || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -85,7 +85,7 @@ func (self *StreamDecoder) Decode(val interface{}) (err error) {
             return self.err
         } else {
             s = y + s
-            e = x + s
+            e = scalarEnd(self.buf, s, x + s)
         }
         
         // must copy string here for safety
@@ -104,18 +104,34 @@ func (self *StreamDecoder) Decode(val interface{}) (err error) {
             self.buf = nil
             freeBytes(mem)
         } else {
-            // remain undecoded bytes, move them onto head
-            n := copy(self.buf, self.buf[self.scanp:])
+            // remain undecoded bytes, move them onto head, with the spaces
+            // before them as encoding/json keeps them buffered
+            n := copy(self.buf, self.buf[e:])
             self.buf = self.buf[:n]
         }   
 
-        self.scanned += int64(self.scanp)
+        self.scanned += int64(e)
         self.scanp = 0
     }    
 
     return self.err
 }
 
+// scalarEnd returns where the value in buf[s:e] ends, since the fast skipping
+// runs over a number or a literal into a value following it without any space,
+// eg. 0.1"a".
+func scalarEnd(buf []byte, s int, e int) int {
+    switch buf[s] {
+        case '"', '{', '[': return e
+    }
+    for i := s; i < e; i++ {
+        switch buf[i] {
+            case '"', '{', '[', ']', '}', ',', ':': return i
+        }
+    }
+    return e
+}
+
 // InputOffset returns the input stream byte offset of the current decoder position. 
 // The offset gives the location of the end of the most recently returned token and the beginning of the next token.
 func (self *StreamDecoder) InputOffset() int64 {
@@ -173,6 +189,8 @@ func (self *StreamDecoder) setErr(err error) {
     self.err = err
     mem := self.buf[:0]
     self.buf = nil
+    self.scanned += int64(self.scanp)
+    self.scanp = 0
     freeBytes(mem)
 }
 
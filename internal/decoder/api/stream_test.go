@@ -19,10 +19,12 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/bytedance/sonic/option"
 	"github.com/stretchr/testify/assert"
@@ -229,14 +231,69 @@ func TestBuffered(t *testing.T) {
     ee4 := d2.Decode(&v2)
     assert.Equal(t, es4, ee4)
     println(str[d1.InputOffset()-5:d1.InputOffset()+5])
-    assert.Equal(t, d1.InputOffset(), d2.InputOffset()-1)
+    assert.Equal(t, d1.InputOffset(), d2.InputOffset())
 
     require.Equal(t, d1.More(), d2.More())
     es2 := d1.Decode(&v1)
     ee2 := d2.Decode(&v2)
     assert.Equal(t, es2, ee2)
     println(str[d1.InputOffset()-5:d1.InputOffset()+5])
-    assert.Equal(t, d1.InputOffset(), d2.InputOffset()-1)
+    assert.Equal(t, d1.InputOffset(), d2.InputOffset())
+}
+
+type streamItem struct {
+    ID   int               `json:"id"`
+    Name string            `json:"name"`
+    Tags []string          `json:"tags"`
+    Attr map[string]string `json:"attr"`
+}
+
+// decodeAll decodes every value of the stream the same way with both decoders.
+func decodeAll(t *testing.T, src string, r io.Reader) {
+    d1 := json.NewDecoder(strings.NewReader(src))
+    d2 := NewStreamDecoder(r)
+    n := 0
+    for d1.More() {
+        var v1, v2 streamItem
+        require.True(t, d2.More())
+        require.NoError(t, d1.Decode(&v1))
+        require.NoError(t, d2.Decode(&v2))
+        require.Equal(t, v1, v2)
+        n++
+    }
+    require.False(t, d2.More())
+    require.Equal(t, 100, n)
+}
+
+func TestStreamDecoder_Concatenated(t *testing.T) {
+    var src strings.Builder
+    for i := 0; i < 100; i++ {
+        fmt.Fprintf(&src, `{"id":%d,"name":"%s","tags":["a","b"],"attr":{"k":"%d"}}`, i, strings.Repeat("x", i * 100), i)
+    }
+
+    /* the values span many reads, of one byte, or of the halting reader */
+    decodeAll(t, src.String(), strings.NewReader(src.String()))
+    decodeAll(t, src.String(), iotest.OneByteReader(strings.NewReader(src.String())))
+    decodeAll(t, src.String(), iotest.HalfReader(strings.NewReader(src.String())))
+    decodeAll(t, src.String(), NewHaltReader(src.String(), testHalts()))
+}
+
+func TestStreamDecoder_NDJSON(t *testing.T) {
+    var src strings.Builder
+    for i := 0; i < 100; i++ {
+        fmt.Fprintf(&src, "{\"id\": %d, \"name\": \"%s\", \"tags\": null}\r\n", i, strings.Repeat("y", i * 100))
+    }
+    decodeAll(t, src.String(), strings.NewReader(src.String()))
+    decodeAll(t, src.String(), iotest.OneByteReader(strings.NewReader(src.String())))
+    decodeAll(t, src.String(), iotest.DataErrReader(strings.NewReader(src.String())))
+
+    /* the remaining lines are kept in the buffer */
+    d := NewStreamDecoder(strings.NewReader(src.String()))
+    var v streamItem
+    require.NoError(t, d.Decode(&v))
+    rest, err := ioutil.ReadAll(d.Buffered())
+    require.NoError(t, err)
+    require.True(t, strings.HasPrefix(src.String()[d.InputOffset():], string(rest)))
 }
 
 func BenchmarkDecodeStream_Std(b *testing.B) {
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+    `encoding`
+    `encoding/json`
+    `reflect`
+    `strconv`
+    `strings`
+
+    `github.com/bytedance/sonic/internal/decoder/consts`
+    `github.com/bytedance/sonic/internal/native`
+    `github.com/bytedance/sonic/internal/resolver`
+)
+
+var (
+    jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+    textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unknownFields walks a decoded JSON value along with its Go type, and
+// collects the paths of the object keys which match no struct field.
+type unknownFields struct {
+    src string
+    out *[]string
+    rn  *resolver.NameResolver
+    cs  bool
+}
+
+func recordUnknownFields(src string, vt reflect.Type, fv uint64, rn *resolver.NameResolver, out *[]string) {
+    self := unknownFields {
+        src : src,
+        out : out,
+        rn  : rn,
+        cs  : fv & (1 << consts.F_case_sensitive) != 0,
+    }
+    self.value(0, vt, "")
+}
+
+func (self *unknownFields) space(p int) int {
+    for p < len(self.src) && (self.src[p] == ' ' || self.src[p] == '\t' || self.src[p] == '\r' || self.src[p] == '\n') {
+        p++
+    }
+    return p
+}
+
+func (self *unknownFields) skip(p int) int {
+    if native.SkipOneFast(&self.src, &p) < 0 {
+        return -1
+    }
+    return p
+}
+
+func (self *unknownFields) key(p int) (string, int) {
+    i := p + 1
+    e := false
+
+    /* find the closing quote */
+    for i < len(self.src) && self.src[i] != '"' {
+        if self.src[i] == '\\' {
+            e = true
+            i++
+        }
+        i++
+    }
+    if i >= len(self.src) {
+        return "", -1
+    }
+
+    /* keys are seldom escaped */
+    if key := self.src[p + 1:i]; !e {
+        return key, i + 1
+    } else if err := json.Unmarshal([]byte(self.src[p:i + 1]), &key); err != nil {
+        return "", -1
+    } else {
+        return key, i + 1
+    }
+}
+
+func (self *unknownFields) field(vt reflect.Type, key string) (reflect.Type, bool) {
+    if self.rn != nil {
+        key = self.rn.Resolve(key)
+    }
+
+    /* exact match takes precedence over the case-insensitive one */
+    fvs := resolver.ResolveStruct(vt)
+    for _, fv := range fvs {
+        if fv.Name == key {
+            return fv.Type, true
+        }
+    }
+    if !self.cs {
+        for _, fv := range fvs {
+            if strings.EqualFold(fv.Name, key) {
+                return fv.Type, true
+            }
+        }
+    }
+    return nil, false
+}
+
+func (self *unknownFields) value(p int, vt reflect.Type, path string) int {
+    if p = self.space(p); p >= len(self.src) {
+        return -1
+    }
+
+    /* dereference the pointers */
+    for vt.Kind() == reflect.Ptr {
+        vt = vt.Elem()
+    }
+
+    /* values with custom decoders are opaque */
+    if pt := reflect.PtrTo(vt); pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+        return self.skip(p)
+    }
+
+    switch {
+        case self.src[p] == '{' && (vt.Kind() == reflect.Struct || vt.Kind() == reflect.Map) : return self.object(p, vt, path)
+        case self.src[p] == '[' && (vt.Kind() == reflect.Slice || vt.Kind() == reflect.Array) : return self.array(p, vt, path)
+        default                                                                            : return self.skip(p)
+    }
+}
+
+func (self *unknownFields) object(p int, vt reflect.Type, path string) int {
+    if p = self.space(p + 1); p < len(self.src) && self.src[p] == '}' {
+        return p + 1
+    }
+
+    for p >= 0 && p < len(self.src) && self.src[p] == '"' {
+        var ft reflect.Type
+        var ok bool
+        var key string
+
+        /* parse the key */
+        if key, p = self.key(p); p < 0 {
+            return -1
+        }
+        if p = self.space(p); p >= len(self.src) || self.src[p] != ':' {
+            return -1
+        }
+
+        /* build the path of the value */
+        sub := key
+        if path != "" {
+            sub = path + "." + key
+        }
+
+        /* map values are always known */
+        if vt.Kind() == reflect.Map {
+            ft, ok = vt.Elem(), true
+        } else {
+            ft, ok = self.field(vt, key)
+        }
+
+        /* record the unknown field and skip its value */
+        if ok {
+            p = self.value(p + 1, ft, sub)
+        } else {
+            *self.out = append(*self.out, sub)
+            p = self.skip(p + 1)
+        }
+
+        /* check for the next key */
+        if p < 0 {
+            return -1
+        }
+        if p = self.space(p); p < len(self.src) && self.src[p] == ',' {
+            p = self.space(p + 1)
+        } else if p < len(self.src) && self.src[p] == '}' {
+            return p + 1
+        } else {
+            return -1
+        }
+    }
+    return -1
+}
+
+func (self *unknownFields) array(p int, vt reflect.Type, path string) int {
+    if p = self.space(p + 1); p < len(self.src) && self.src[p] == ']' {
+        return p + 1
+    }
+
+    for i := 0; p >= 0 && p < len(self.src); i++ {
+        if p = self.value(p, vt.Elem(), path + "[" + strconv.Itoa(i) + "]"); p < 0 {
+            return -1
+        }
+        if p = self.space(p); p < len(self.src) && self.src[p] == ',' {
+            p++
+        } else if p < len(self.src) && self.src[p] == ']' {
+            return p + 1
+        } else {
+            return -1
+        }
+    }
+    return -1
+}
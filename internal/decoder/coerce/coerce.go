@@ -0,0 +1,51 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package coerce holds the hook the decoders call when a JSON value does not
+// match the Go type it is decoded into, before reporting a MismatchTypeError.
+package coerce
+
+import (
+    `reflect`
+    `sync/atomic`
+    `unsafe`
+)
+
+// Func converts the raw JSON value src into dst, which is settable, and
+// reports whether it did. Returning false keeps the mismatch error.
+type Func func(dst reflect.Value, src string) bool
+
+var hook atomic.Value
+
+// Register installs fn as the coercion hook, a nil fn removes it.
+func Register(fn Func) {
+    hook.Store(fn)
+}
+
+// Enabled reports whether a coercion hook is registered.
+func Enabled() bool {
+    fn, _ := hook.Load().(Func)
+    return fn != nil
+}
+
+// Value tries the coercion hook for the value of type vt at vp.
+func Value(vt reflect.Type, vp unsafe.Pointer, src string) bool {
+    fn, _ := hook.Load().(Func)
+    if fn == nil {
+        return false
+    }
+    return fn(reflect.NewAt(vt, vp).Elem(), src)
+}
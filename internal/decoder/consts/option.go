@@ -17,6 +17,16 @@ const (
     F_allow_control   = types.B_ALLOW_CONTROL
     F_no_validate_json = types.B_NO_VALIDATE_JSON
     F_case_sensitive = 7
+    F_bool_as_int    = 8
+    F_struct_as_array = 9
+    F_flexible_time   = 10
+    F_limit_key_length = 11
+    F_report_more_data = 12
+    F_pointer_refs     = 13
+    F_error_snippet    = 14
+    F_object_as_pairs  = 15
+    F_limit_dynamic_depth = 16
+    F_grow_stack       = 17
 )
 
 type Options uint64
@@ -30,8 +40,26 @@ const (
     OptionValidateString   Options = 1 << F_validate_string
     OptionNoValidateJSON   Options = 1 << F_no_validate_json
     OptionCaseSensitive    Options = 1 << F_case_sensitive
+    OptionBoolAsInt        Options = 1 << F_bool_as_int
+    OptionStructAsArray    Options = 1 << F_struct_as_array
+    OptionFlexibleTime     Options = 1 << F_flexible_time
+    OptionLimitKeyLength   Options = 1 << F_limit_key_length
+    OptionReportMoreData   Options = 1 << F_report_more_data
+    OptionPointerRefs      Options = 1 << F_pointer_refs
+    OptionErrorSnippet     Options = 1 << F_error_snippet
+    OptionObjectAsPairs    Options = 1 << F_object_as_pairs
+    OptionLimitDynamicDepth Options = 1 << F_limit_dynamic_depth
+    OptionGrowStack        Options = 1 << F_grow_stack
 )
 
 const (
 	MaxStack = 4096
+
+	// MaxKeyLength is the longest object key accepted for struct fields
+	// when OptionLimitKeyLength is set.
+	MaxKeyLength = 4096
+
+	// MaxDynamicDepth is the deepest nesting of values decoded through
+	// non-nil interfaces when OptionLimitDynamicDepth is set.
+	MaxDynamicDepth = 4096
 )
\ No newline at end of file
@@ -23,6 +23,7 @@ import (
     `reflect`
     `strconv`
     `strings`
+    `unicode/utf8`
 
     `github.com/bytedance/sonic/internal/native/types`
     `github.com/bytedance/sonic/internal/rt`
@@ -112,6 +113,53 @@ func clamp_zero(v int) int {
 
 /** JIT Error Helpers **/
 
+// ErrMoreData is returned instead of a SyntaxError when the input ends
+// in the middle of a value which could still be completed.
+var ErrMoreData = errors.New("json: unexpected end of input, more data is needed")
+
+// SnippetWidth is the number of raw JSON bytes that SnippetError keeps on
+// each side of the position where decoding failed.
+const SnippetWidth = 20
+
+// SnippetError wraps a decoding error with the raw JSON around the position
+// where it happened, it is returned when OptionErrorSnippet is set.
+type SnippetError struct {
+    Err     error
+    Pos     int
+    Snippet string
+}
+
+func (self *SnippetError) Error() string {
+    return fmt.Sprintf("%s, near %q", self.Err.Error(), self.Snippet)
+}
+
+func (self *SnippetError) Unwrap() error {
+    return self.Err
+}
+
+// ErrorSnippet wraps err with the bytes of src within SnippetWidth of pos.
+// The snippet is copied, so the error does not keep src alive.
+func ErrorSnippet(src string, pos int, err error) error {
+    if pos < 0 {
+        pos = 0
+    } else if pos > len(src) {
+        pos = len(src)
+    }
+    p := pos - SnippetWidth
+    if p < 0 {
+        p = 0
+    }
+    q := pos + SnippetWidth
+    if q > len(src) {
+        q = len(src)
+    }
+    return &SnippetError {
+        Err     : err,
+        Pos     : pos,
+        Snippet : string([]byte(src[p:q])),
+    }
+}
+
 var StackOverflow = &json.UnsupportedValueError {
     Str   : "Value nesting too deep",
     Value : reflect.ValueOf("..."),
@@ -183,6 +231,23 @@ func ErrorField(name string) error {
     return errors.New("json: unknown field " + strconv.Quote(name))
 }
 
+func ErrorMissingField(name string) error {
+    return errors.New("json: missing required field " + strconv.Quote(name))
+}
+
+// ErrorInvalidUTF8 reports the first invalid UTF-8 byte of src from pos on,
+// or returns nil if there is none.
+func ErrorInvalidUTF8(src string, pos int) error {
+    for i := pos; i < len(src); {
+        r, n := utf8.DecodeRuneInString(src[i:])
+        if r == utf8.RuneError && n == 1 {
+            return ErrorWrap(src, i, types.ERR_INVALID_UTF8)
+        }
+        i += n
+    }
+    return nil
+}
+
 func ErrorValue(value string, vtype reflect.Type) error {
     return &json.UnmarshalTypeError {
         Type  : vtype,
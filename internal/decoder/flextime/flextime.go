@@ -0,0 +1,124 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package flextime parses timestamps and durations given in any of several
+// formats, it backs the OptionFlexibleTime decoder option.
+package flextime
+
+import (
+    `encoding/json`
+    `math`
+    `reflect`
+    `strconv`
+    `strings`
+    `time`
+    `unsafe`
+)
+
+var (
+    timeType     = reflect.TypeOf(time.Time{})
+    durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Layouts are tried in order for timestamps given as strings, before falling
+// back to Unix epochs.
+var Layouts = []string {
+    time.RFC3339Nano,
+    time.RFC1123Z,
+    time.RFC1123,
+    "2006-01-02 15:04:05",
+    "2006-01-02",
+}
+
+// Unix epochs with an absolute value above this are taken as milliseconds,
+// since that many seconds is far beyond year 9999.
+const _MaxEpochSeconds = 1e12
+
+// IsTimeType reports whether vt is time.Time or time.Duration.
+func IsTimeType(vt reflect.Type) bool {
+    return vt == timeType || vt == durationType
+}
+
+// Decode parses the raw JSON value src and stores the result in vp, which
+// points to a value of type vt. A JSON null leaves vp untouched. It returns
+// false if src matches none of the formats.
+func Decode(src string, vt reflect.Type, vp unsafe.Pointer) bool {
+    if src == "null" {
+        return true
+    }
+
+    /* strings are unquoted first, numbers are used as they are */
+    s, quoted := src, false
+    if len(s) >= 2 && s[0] == '"' {
+        if quoted = true; strings.IndexByte(s, '\\') < 0 {
+            s = s[1:len(s) - 1]
+        } else if json.Unmarshal([]byte(s), &s) != nil {
+            return false
+        }
+    }
+
+    /* parse by the target type */
+    switch vt {
+        case timeType     : return decodeTime(s, quoted, (*time.Time)(vp))
+        case durationType : return decodeDuration(s, quoted, (*time.Duration)(vp))
+        default           : return false
+    }
+}
+
+func decodeTime(s string, quoted bool, vp *time.Time) bool {
+    if quoted {
+        for _, layout := range Layouts {
+            if t, err := time.Parse(layout, s); err == nil {
+                *vp = t
+                return true
+            }
+        }
+    }
+
+    /* integers are epochs in seconds or milliseconds */
+    if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+        if n > _MaxEpochSeconds || n < -_MaxEpochSeconds {
+            *vp = time.UnixMilli(n)
+        } else {
+            *vp = time.Unix(n, 0)
+        }
+        return true
+    }
+
+    /* fractional epochs are always in seconds */
+    if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+        sec, frac := math.Modf(f)
+        *vp = time.Unix(int64(sec), int64(frac * 1e9))
+        return true
+    }
+    return false
+}
+
+func decodeDuration(s string, quoted bool, vp *time.Duration) bool {
+    if quoted {
+        if d, err := time.ParseDuration(s); err == nil {
+            *vp = d
+            return true
+        }
+    }
+
+    /* integers are nanoseconds, same as encoding/json */
+    if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+        *vp = time.Duration(n)
+        return true
+    }
+    return false
+}
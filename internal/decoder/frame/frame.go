@@ -0,0 +1,50 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package frame
+
+import (
+    `bytes`
+    `encoding/binary`
+    `io`
+)
+
+// HeaderSize is the size of the big-endian length prefix of a JSON frame.
+const HeaderSize = 4
+
+// Read reads one length-prefixed frame from r, and returns the JSON in it.
+//
+// The JSON is read into a buffer which grows with the input, so a header
+// claiming a large frame does not allocate more than r actually provides.
+// It returns io.EOF if r is exhausted before the frame starts, and
+// io.ErrUnexpectedEOF if it is exhausted within the frame.
+func Read(r io.Reader) ([]byte, error) {
+    var hdr [HeaderSize]byte
+    if _, err := io.ReadFull(r, hdr[:]); err != nil {
+        return nil, err
+    }
+
+    /* read no further than the frame */
+    n := int64(binary.BigEndian.Uint32(hdr[:]))
+    buf := bytes.NewBuffer(nil)
+    if _, err := buf.ReadFrom(io.LimitReader(r, n)); err != nil {
+        return nil, err
+    }
+    if int64(buf.Len()) < n {
+        return nil, io.ErrUnexpectedEOF
+    }
+    return buf.Bytes(), nil
+}
@@ -1,4 +1,4 @@
-// +build go1.21,!go1.26
+// +build amd64,go1.21,!go1.26
 
 // Copyright 2023 CloudWeGo Authors
 //
@@ -0,0 +1,65 @@
+//go:build arm64 && go1.20 && !go1.21
+// +build arm64,go1.20,!go1.21
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jitdec
+
+import (
+	"strconv"
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/jit"
+	"github.com/bytedance/sonic/internal/rt"
+	"github.com/twitchyliquid64/golang-asm/obj"
+)
+
+// Notice: gcWriteBarrier takes the slot in X2 and the value in X3 and does
+// the store itself, so both registers are spilled around the call.
+var (
+	_V_writeBarrier = jit.Imm(int64(uintptr(unsafe.Pointer(&rt.RuntimeWriteBarrier))))
+
+	_F_gcWriteBarrier = jit.Func(rt.GcWriteBarrierAX)
+)
+
+// WritePtrAX stores X0 into rec, recording the write with the GC when the
+// write barrier is enabled. saveDI is kept for parity with the AMD64 decoder.
+func (self *_Assembler) WritePtrAX(i int, rec obj.Addr, saveDI bool) {
+	self.WriteRecNotAX(i, _X0, rec, saveDI, false)
+}
+
+// WriteRecNotAX stores ptr into rec, recording the write with the GC when the
+// write barrier is enabled. X16 and X17 are used as scratch registers.
+func (self *_Assembler) WriteRecNotAX(i int, ptr obj.Addr, rec obj.Addr, saveDI bool, saveAX bool) {
+	if rec.Reg == _X2.Reg || rec.Reg == _X3.Reg || rec.Reg == _X16.Reg || rec.Reg == _X17.Reg || ptr.Reg == _X16.Reg {
+		panic("rec or ptr contains a scratch register!")
+	}
+	base := obj.Addr{Type: obj.TYPE_REG, Reg: rec.Reg}
+	self.Emit("MOVD", _V_writeBarrier, _X17)                    // MOVD    ${&writeBarrier}, X17
+	self.Emit("MOVWU", jit.Ptr(_X17, 0), _X17)                  // MOVWU   (X17), X17
+	self.Emit("CMP", _X17, _ZR)                                 // CMP     X17, ZR
+	self.Sjmp("BEQ", "_no_writeBarrier"+strconv.Itoa(i)+"_{n}") // BEQ     _no_writeBarrier{i}_{n}
+	self.save(_X2, _X3)                                         // SAVE    X2, X3
+	self.Emit("MOVD", ptr, _X3)                                 // MOVD    ptr, X3
+	self.Emit("ADD", _X2, base, jit.Imm(rec.Offset))            // ADD     X2, base, ${rec.offset}
+	self.call(_F_gcWriteBarrier)                                // CALL    gcWriteBarrier
+	self.load(_X2, _X3)                                         // LOAD    X2, X3
+	self.Sjmp("B", "_end_writeBarrier"+strconv.Itoa(i)+"_{n}")  // B       _end_writeBarrier{i}_{n}
+	self.Link("_no_writeBarrier" + strconv.Itoa(i) + "_{n}")    // _no_writeBarrier{i}_{n}:
+	self.Emit("MOVD", ptr, rec)                                 // MOVD    ptr, rec
+	self.Link("_end_writeBarrier" + strconv.Itoa(i) + "_{n}")   // _end_writeBarrier{i}_{n}:
+}
@@ -0,0 +1,63 @@
+//go:build arm64 && go1.21 && !go1.26
+// +build arm64,go1.21,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jitdec
+
+import (
+	"strconv"
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/jit"
+	"github.com/bytedance/sonic/internal/rt"
+	"github.com/twitchyliquid64/golang-asm/obj"
+)
+
+// Notice: gcWriteBarrier2 returns the buffer in X25 and clobbers X27 and LR,
+// none of which carry decoder state, so nothing needs to be spilled here.
+var _WB = _X25
+
+var (
+	_V_writeBarrier = jit.Imm(int64(uintptr(unsafe.Pointer(&rt.RuntimeWriteBarrier))))
+
+	_F_gcWriteBarrier2 = jit.Func(rt.GcWriteBarrier2)
+)
+
+// WritePtrAX stores X0 into rec, recording the write with the GC when the
+// write barrier is enabled. saveDI is kept for parity with the AMD64 decoder.
+func (self *_Assembler) WritePtrAX(i int, rec obj.Addr, saveDI bool) {
+	self.WriteRecNotAX(i, _X0, rec, saveDI, false)
+}
+
+// WriteRecNotAX stores ptr into rec, recording the write with the GC when the
+// write barrier is enabled. X16 and X17 are used as scratch registers.
+func (self *_Assembler) WriteRecNotAX(i int, ptr obj.Addr, rec obj.Addr, saveDI bool, saveAX bool) {
+	if rec.Reg == _X16.Reg || rec.Reg == _X17.Reg || rec.Reg == _WB.Reg || ptr.Reg == _X16.Reg || ptr.Reg == _WB.Reg {
+		panic("rec or ptr contains a scratch register!")
+	}
+	self.Emit("MOVD", _V_writeBarrier, _X17)                    // MOVD    ${&writeBarrier}, X17
+	self.Emit("MOVWU", jit.Ptr(_X17, 0), _X17)                  // MOVWU   (X17), X17
+	self.Emit("CMP", _X17, _ZR)                                 // CMP     X17, ZR
+	self.Sjmp("BEQ", "_no_writeBarrier"+strconv.Itoa(i)+"_{n}") // BEQ     _no_writeBarrier{i}_{n}
+	self.call(_F_gcWriteBarrier2)                               // CALL    gcWriteBarrier2
+	self.Emit("MOVD", ptr, jit.Ptr(_WB, 0))                     // MOVD    ptr, (X25)
+	self.Emit("MOVD", rec, _X17)                                // MOVD    rec, X17
+	self.Emit("MOVD", _X17, jit.Ptr(_WB, 8))                    // MOVD    X17, 8(X25)
+	self.Link("_no_writeBarrier" + strconv.Itoa(i) + "_{n}")    // _no_writeBarrier{i}_{n}:
+	self.Emit("MOVD", ptr, rec)                                 // MOVD    ptr, rec
+}
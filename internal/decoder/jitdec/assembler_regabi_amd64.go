@@ -31,6 +31,7 @@ import (
 	"github.com/bytedance/sonic/internal/jit"
 	"github.com/bytedance/sonic/internal/native"
 	"github.com/bytedance/sonic/internal/native/types"
+	"github.com/bytedance/sonic/internal/resolver"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/twitchyliquid64/golang-asm/obj"
 )
@@ -97,6 +98,7 @@ const (
     _LB_error           = "_error"
     _LB_im_error        = "_im_error"
     _LB_eof_error       = "_eof_error"
+    _LB_key_error       = "_key_error"
     _LB_type_error      = "_type_error"
     _LB_field_error     = "_field_error"
     _LB_range_error     = "_range_error"
@@ -121,6 +123,7 @@ const (
 const (
     _LB_skip_one = "_skip_one"
     _LB_skip_key_value = "_skip_key_value"
+    _LB_coerce_one = "_coerce_one"
 )
 
 var (
@@ -237,6 +240,7 @@ func (self *_Assembler) compile() {
     self.copy_string()
     self.escape_string()
     self.escape_string_twice()
+    self.coerce_one()
     self.skip_one()
     self.skip_key_value()
     self.type_error()
@@ -257,6 +261,7 @@ var _OpFuncTab = [256]func(*_Assembler, *_Instr) {
     _OP_bin              : (*_Assembler)._asm_OP_bin,
     _OP_bool             : (*_Assembler)._asm_OP_bool,
     _OP_num              : (*_Assembler)._asm_OP_num,
+    _OP_raw              : (*_Assembler)._asm_OP_raw,
     _OP_i8               : (*_Assembler)._asm_OP_i8,
     _OP_i16              : (*_Assembler)._asm_OP_i16,
     _OP_i32              : (*_Assembler)._asm_OP_i32,
@@ -317,6 +322,12 @@ var _OpFuncTab = [256]func(*_Assembler, *_Instr) {
     _OP_skip_emtpy       : (*_Assembler)._asm_OP_skip_empty,
     _OP_add              : (*_Assembler)._asm_OP_add,
     _OP_check_empty      : (*_Assembler)._asm_OP_check_empty,
+    _OP_check_tuple      : (*_Assembler)._asm_OP_check_tuple,
+    _OP_check_pairs      : (*_Assembler)._asm_OP_check_pairs,
+    _OP_setter           : (*_Assembler)._asm_OP_setter,
+    _OP_check_time       : (*_Assembler)._asm_OP_check_time,
+    _OP_check_ref        : (*_Assembler)._asm_OP_check_ref,
+    _OP_required         : (*_Assembler)._asm_OP_required,
     _OP_unsupported      : (*_Assembler)._asm_OP_unsupported,
     _OP_debug            : (*_Assembler)._asm_OP_debug,
 }
@@ -379,10 +390,12 @@ func (self *_Assembler) prologue() {
     self.Emit("MOVQ", jit.Imm(0), _ARG_sv_n)        // MOVQ $0, sv.n<>+56(FP)
     self.Emit("MOVQ", jit.Imm(0), _ARG_vk)          // MOVQ $0, vk<>+64(FP)
     self.Emit("MOVQ", jit.Imm(0), _VAR_et)          // MOVQ $0, et<>+120(FP)
-    // initialize digital buffer first
-    self.Emit("MOVQ", jit.Imm(_MaxDigitNums), _VAR_st_Dc)    // MOVQ $_MaxDigitNums, ss.Dcap
-    self.Emit("LEAQ", jit.Ptr(_ST, _DbufOffset), _AX)        // LEAQ _DbufOffset(ST), AX
-    self.Emit("MOVQ", _AX, _VAR_st_Db)                       // MOVQ AX, ss.Dbuf
+    // initialize digital buffer first, only numbers need it
+    if self.p.hasNumber() {
+        self.Emit("MOVQ", jit.Imm(_MaxDigitNums), _VAR_st_Dc)    // MOVQ $_MaxDigitNums, ss.Dcap
+        self.Emit("LEAQ", jit.Ptr(_ST, _DbufOffset), _AX)        // LEAQ _DbufOffset(ST), AX
+        self.Emit("MOVQ", _AX, _VAR_st_Db)                       // MOVQ AX, ss.Dbuf
+    }
 }
 
 /** Function Calling Helpers **/
@@ -482,7 +495,7 @@ var (
 )
 
 var (
-    _V_stackOverflow              = jit.Imm(int64(uintptr(unsafe.Pointer(&stackOverflow))))
+    _V_stackOverflow              = jit.Imm(int64(uintptr(unsafe.Pointer(stackOverflow))))
     _I_json_UnsupportedValueError = jit.Itab(_T_error, reflect.TypeOf(new(json.UnsupportedValueError)))
     _I_json_MismatchTypeError     = jit.Itab(_T_error, reflect.TypeOf(new(MismatchTypeError)))
     _I_json_MismatchQuotedError   = jit.Itab(_T_error, reflect.TypeOf(new(MismatchQuotedError)))
@@ -550,6 +563,9 @@ func (self *_Assembler) parsing_error() {
     self.Emit("MOVQ" , _IL, _IC)                                        // MOVQ    IL, IC
     self.Emit("MOVL" , jit.Imm(int64(types.ERR_EOF)), _EP)              // MOVL    ${types.ERR_EOF}, EP
     self.Sjmp("JMP"  , _LB_parsing_error)                               // JMP     _parsing_error
+    self.Link(_LB_key_error)                                            // _key_error:
+    self.Emit("MOVL" , jit.Imm(int64(types.ERR_KEY_TOO_LONG)), _EP)     // MOVL    ${types.ERR_KEY_TOO_LONG}, EP
+    self.Sjmp("JMP"  , _LB_parsing_error)                               // JMP     _parsing_error
     self.Link(_LB_unquote_error)                                        // _unquote_error:
     self.Emit("SUBQ" , _VAR_sr, _SI)                                    // SUBQ    sr, SI
     self.Emit("SUBQ" , _SI, _IC)                                        // SUBQ    IL, IC
@@ -623,6 +639,27 @@ func (self *_Assembler) _asm_OP_skip_empty(p *_Instr) {
     self.Sjmp("JNS", _LB_field_error)
 }
 
+var _F_decodeCoerced = jit.Func(decodeCoerced)
+
+// coerce_one gives the coercion hook a chance to decode the mismatched value
+// before it is skipped, ET holds the type which VP points to.
+func (self *_Assembler) coerce_one() {
+    self.Link(_LB_coerce_one)                   // _coerce_one:
+    self.Emit("MOVQ" , _VP, _BX)                // MOVQ    VP, BX
+    self.Emit("MOVQ" , _ARG_sp, _CX)            // MOVQ    sp, CX
+    self.Emit("MOVQ" , _ARG_sl, _DI)            // MOVQ    sl, DI
+    self.Emit("MOVQ" , _VAR_ic, _SI)            // MOVQ    _VAR_ic, SI
+    self.call_go(_F_decodeCoerced)              // CALL_GO decodeCoerced
+    self.Emit("TESTQ", _AX, _AX)                // TESTQ   AX, AX
+    self.Sjmp("JS"   , "_coerce_mismatch")      // JS      _coerce_mismatch
+    self.Emit("MOVQ" , _AX, _IC)                // MOVQ    AX, IC
+    self.Emit("MOVQ" , _VAR_pc, _R9)            // MOVQ    pc, R9
+    self.Rjmp("JMP"  , _R9)                     // JMP     (R9)
+    self.Link("_coerce_mismatch")               // _coerce_mismatch:
+    self.Emit("MOVQ" , _BX, _VAR_et)            // MOVQ    BX, VAR_et
+    self.Sjmp("JMP"  , _LB_skip_one)            // JMP     _skip_one
+}
+
 func (self *_Assembler) skip_one() {
     self.Link(_LB_skip_one)                     // _skip:
     self.Emit("MOVQ", _VAR_ic, _IC)             // MOVQ    _VAR_ic, IC
@@ -712,8 +749,8 @@ func (self *_Assembler) check_err(vt reflect.Type, pin string, pin2 int) {
     if vt != nil {
         self.Sjmp("JNS" , "_check_err_{n}")        // JNE  _parsing_error_v
         self.Emit("MOVQ", jit.Type(vt), _ET)         
-        self.Emit("MOVQ", _ET, _VAR_et)
         if pin2 != -1 {
+            self.Emit("MOVQ", _ET, _VAR_et)
             self.Emit("SUBQ", jit.Imm(1), _BX)
             self.Emit("MOVQ", _BX, _VAR_ic)
             self.Byte(0x4c  , 0x8d, 0x0d)         // LEAQ (PC), R9
@@ -725,7 +762,7 @@ func (self *_Assembler) check_err(vt reflect.Type, pin string, pin2 int) {
             self.Byte(0x4c  , 0x8d, 0x0d)         // LEAQ (PC), R9
             self.Sref(pin, 4)
             self.Emit("MOVQ", _R9, _VAR_pc)
-            self.Sjmp("JMP" , _LB_skip_one)
+            self.Sjmp("JMP" , _LB_coerce_one)
         }
         self.Link("_check_err_{n}")
     } else {
@@ -758,15 +795,82 @@ func (self *_Assembler) parse_number(vt reflect.Type, pin string, pin2 int) {
 }
 
 func (self *_Assembler) parse_signed(vt reflect.Type, pin string, pin2 int) {
+    if pin2 == -1 {
+        self.parse_bool_int()
+    }
     self.Emit("MOVQ", _IC, _BX)       // save ic when call native func    
     self.call_vf(_F_vsigned)
     self.check_err(vt, pin, pin2)
+    if pin2 == -1 {
+        self.Link("_bool_int_end_{n}")
+    }
 }
 
 func (self *_Assembler) parse_unsigned(vt reflect.Type, pin string, pin2 int) {
+    if pin2 == -1 {
+        self.parse_bool_int()
+    }
     self.Emit("MOVQ", _IC, _BX)       // save ic when call native func    
     self.call_vf(_F_vunsigned)
     self.check_err(vt, pin, pin2)
+    if pin2 == -1 {
+        self.Link("_bool_int_end_{n}")
+    }
+}
+
+// parse_bool_int stores `true`/`false` as 1/0 into st.Iv when OptionBoolAsInt is set,
+// and jumps over the number parsing.
+func (self *_Assembler) parse_bool_int() {
+    self.Emit("BTQ" , jit.Imm(_F_bool_as_int), _ARG_fv)         // BTQ  ${_F_bool_as_int}, fv
+    self.Sjmp("JNC" , "_bool_int_skip_{n}")                     // JNC  _bool_int_skip_{n}
+    self.Emit("LEAQ", jit.Ptr(_IC, 4), _AX)                     // LEAQ 4(IC), AX
+    self.Emit("CMPQ", _AX, _IL)                                 // CMPQ AX, IL
+    self.Sjmp("JA"  , "_bool_int_skip_{n}")                     // JA   _bool_int_skip_{n}
+    self.Emit("MOVL", jit.Imm(_IM_true), _CX)                   // MOVL $"true", CX
+    self.Emit("CMPL", _CX, jit.Sib(_IP, _IC, 1, 0))             // CMPL CX, (IP)(IC)
+    self.Sjmp("JE"  , "_bool_int_true_{n}")                     // JE   _bool_int_true_{n}
+    self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm('f'))    // CMPB (IP)(IC), $'f'
+    self.Sjmp("JNE" , "_bool_int_skip_{n}")                     // JNE  _bool_int_skip_{n}
+    self.Emit("ADDQ", jit.Imm(1), _AX)                          // ADDQ $1, AX
+    self.Emit("ADDQ", jit.Imm(1), _IC)                          // ADDQ $1, IC
+    self.Emit("CMPQ", _AX, _IL)                                 // CMPQ AX, IL
+    self.Sjmp("JA"  , _LB_eof_error)                            // JA   _eof_error
+    self.Emit("MOVL", jit.Imm(_IM_alse), _CX)                   // MOVL $"alse", CX
+    self.Emit("CMPL", _CX, jit.Sib(_IP, _IC, 1, 0))             // CMPL CX, (IP)(IC)
+    self.Sjmp("JNE" , _LB_im_error)                             // JNE  _im_error
+    self.Emit("MOVQ", _AX, _IC)                                 // MOVQ AX, IC
+    self.Emit("MOVQ", jit.Imm(0), _VAR_st_Iv)                   // MOVQ $0, st.Iv
+    self.Sjmp("JMP" , "_bool_int_end_{n}")                      // JMP  _bool_int_end_{n}
+    self.Link("_bool_int_true_{n}")                             // _bool_int_true_{n}:
+    self.Emit("MOVQ", _AX, _IC)                                 // MOVQ AX, IC
+    self.Emit("MOVQ", jit.Imm(1), _VAR_st_Iv)                   // MOVQ $1, st.Iv
+    self.Sjmp("JMP" , "_bool_int_end_{n}")                      // JMP  _bool_int_end_{n}
+    self.Link("_bool_int_skip_{n}")                             // _bool_int_skip_{n}:
+}
+
+// check_delim makes sure a number is followed by a valid JSON delimiter,
+// numbers inside a quoted string are terminated by the quote instead.
+func (self *_Assembler) check_delim(p *_Instr) {
+    if p.vb() == '"' {
+        return
+    }
+    self.Emit("CMPQ"   , _IC, _IL)                      // CMPQ    IC, IL
+    self.Sjmp("JAE"    , "_delim_end_{n}")              // JAE     _delim_end_{n}
+    self.Emit("MOVBQZX", jit.Sib(_IP, _IC, 1, 0), _AX)  // MOVBQZX (IP)(IC), AX
+    self.Emit("CMPQ"   , _AX, jit.Imm(' '))             // CMPQ    AX, $' '
+    self.Sjmp("JA"     , "_delim_char_{n}")             // JA      _delim_char_{n}
+    self.Emit("MOVQ"   , jit.Imm(_BM_space), _DX)       // MOVQ    _BM_space, DX
+    self.Emit("BTQ"    , _AX, _DX)                      // BTQ     AX, DX
+    self.Sjmp("JC"     , "_delim_end_{n}")              // JC      _delim_end_{n}
+    self.Sjmp("JMP"    , _LB_char_0_error)              // JMP     _char_0_error
+    self.Link("_delim_char_{n}")                        // _delim_char_{n}:
+    self.Emit("CMPQ"   , _AX, jit.Imm(','))             // CMPQ    AX, $','
+    self.Sjmp("JE"     , "_delim_end_{n}")              // JE      _delim_end_{n}
+    self.Emit("CMPQ"   , _AX, jit.Imm(']'))             // CMPQ    AX, $']'
+    self.Sjmp("JE"     , "_delim_end_{n}")              // JE      _delim_end_{n}
+    self.Emit("CMPQ"   , _AX, jit.Imm('}'))             // CMPQ    AX, $'}'
+    self.Sjmp("JNE"    , _LB_char_0_error)              // JNE     _char_0_error
+    self.Link("_delim_end_{n}")                         // _delim_end_{n}:
 }
 
 // Pointer: DI, Size: SI, Return: R9  
@@ -822,8 +926,8 @@ func (self *_Assembler) escape_string_twice() {
     self.Emit("MOVQ" , _VAR_bs_n, _SI)        
     self.Emit("LEAQ" , _VAR_sr, _CX)                                // LEAQ   sr, CX
     self.Emit("MOVL" , jit.Imm(types.F_DOUBLE_UNQUOTE), _R8)        // MOVL   ${types.F_DOUBLE_UNQUOTE}, R8
-    self.Emit("BTQ"  , jit.Imm(_F_disable_urc), _ARG_fv)            // BTQ    ${_F_disable_urc}, AX
     self.Emit("XORL" , _AX, _AX)                                    // XORL   AX, AX
+    self.Emit("BTQ"  , jit.Imm(_F_disable_urc), _ARG_fv)            // BTQ    ${_F_disable_urc}, fv
     self.Emit("SETCC", _AX)                                         // SETCC  AX
     self.Emit("SHLQ" , jit.Imm(types.B_UNICODE_REPLACE), _AX)       // SHLQ   ${types.B_UNICODE_REPLACE}, AX
     self.Emit("ORQ"  , _AX, _R8)                                    // ORQ    AX, R8
@@ -1157,14 +1261,32 @@ func (self *_Assembler) unmarshal_func(t reflect.Type, fn obj.Addr, deref bool)
 /** Dynamic Decoding Routine **/
 
 var (
+    _F_decodeTypedTuple   obj.Addr
+    _F_decodeTypedPairs   obj.Addr
     _F_decodeTypedPointer obj.Addr
+    _F_decodeDynamic      obj.Addr
+    _F_decodeSetter       obj.Addr
+    _F_decodeFlexTime     obj.Addr
+    _F_decodeRef          obj.Addr
+    _F_decodeRequired     obj.Addr
 )
 
 func init() {
+    _F_decodeTypedTuple = jit.Func(decodeTypedTuple)
+    _F_decodeTypedPairs = jit.Func(decodeTypedPairs)
     _F_decodeTypedPointer = jit.Func(decodeTypedPointer)
+    _F_decodeDynamic = jit.Func(decodeDynamic)
+    _F_decodeSetter = jit.Func(decodeSetter)
+    _F_decodeFlexTime = jit.Func(decodeFlexTime)
+    _F_decodeRef = jit.Func(decodeRef)
+    _F_decodeRequired = jit.Func(decodeRequired)
 }
 
 func (self *_Assembler) decode_dynamic(vt obj.Addr, vp obj.Addr) {
+    self.decode_typed(_F_decodeDynamic, vt, vp)
+}
+
+func (self *_Assembler) decode_typed(fn obj.Addr, vt obj.Addr, vp obj.Addr) {
     self.Emit("MOVQ" , vp, _SI)    // MOVQ    ${vp}, SI
     self.Emit("MOVQ" , vt, _DI)    // MOVQ    ${vt}, DI
     self.Emit("MOVQ", _ARG_sp, _AX)            // MOVQ    sp, AX
@@ -1173,7 +1295,7 @@ func (self *_Assembler) decode_dynamic(vt obj.Addr, vp obj.Addr) {
     self.Emit("MOVQ" , _ST, _R8)                // MOVQ    ST, R8 
     self.Emit("MOVQ" , _ARG_fv, _R9)            // MOVQ    fv, R9
     self.save(_REG_rt...)
-    self.Emit("MOVQ", _F_decodeTypedPointer, _IL)  // MOVQ ${fn}, R11
+    self.Emit("MOVQ", fn, _IL)                  // MOVQ ${fn}, R11
     self.Rjmp("CALL", _IL)      // CALL R11
     self.load(_REG_rt...)
     self.Emit("MOVQ" , _AX, _IC)                // MOVQ    AX, IC
@@ -1212,6 +1334,7 @@ var (
 
 var (
     _F_FieldMap_GetCaseInsensitive obj.Addr
+    _F_NameResolver_Resolve obj.Addr
     _Empty_Slice = []byte{}
     _Zero_Base = int64(uintptr(((*rt.GoSlice)(unsafe.Pointer(&_Empty_Slice))).Ptr))
 )
@@ -1233,6 +1356,7 @@ const (
 
 func init() {
     _F_FieldMap_GetCaseInsensitive = jit.Func((*caching.FieldMap).GetCaseInsensitive)
+    _F_NameResolver_Resolve = jit.Func((*resolver.NameResolver).Resolve)
 }
 
 func (self *_Assembler) _asm_OP_any(_ *_Instr) {
@@ -1266,11 +1390,10 @@ func (self *_Assembler) _asm_OP_dyn(p *_Instr) {
 
     /* if nil iface, call skip one */
     self.Emit("MOVQ", _IC, _VAR_ic)
-    self.Emit("MOVQ", _ET, _VAR_et)
     self.Byte(0x4c, 0x8d, 0x0d)       
     self.Sref("_decode_end_{n}", 4)
     self.Emit("MOVQ", _R9, _VAR_pc)
-    self.Sjmp("JMP"  , _LB_skip_one)
+    self.Sjmp("JMP"  , _LB_coerce_one)
 
     self.Link("_decode_dyn_non_nil_{n}")                    // _decode_dyn_non_nil_{n}:
     self.Emit("MOVQ"   , jit.Ptr(_VP, 0), _CX)              // MOVQ    (VP), CX
@@ -1281,11 +1404,10 @@ func (self *_Assembler) _asm_OP_dyn(p *_Instr) {
     self.Sjmp("JE"    , "_decode_dyn_ptr_{n}")              // JNE     _type_error
 
     self.Emit("MOVQ", _IC, _VAR_ic)
-    self.Emit("MOVQ", _ET, _VAR_et)
     self.Byte(0x4c, 0x8d, 0x0d)       
     self.Sref("_decode_end_{n}", 4)
     self.Emit("MOVQ", _R9, _VAR_pc)
-    self.Sjmp("JMP"  , _LB_skip_one)
+    self.Sjmp("JMP"  , _LB_coerce_one)
 
     self.Link("_decode_dyn_ptr_{n}")                        // _decode_dyn_ptr_{n}:
     self.Emit("LEAQ"   , jit.Ptr(_VP, 8), _DI)              // LEAQ    8(VP), DI
@@ -1343,11 +1465,10 @@ func (self *_Assembler) _asm_OP_bool(_ *_Instr) {
     // try to skip the value
     self.Emit("MOVQ", _IC, _VAR_ic)           
     self.Emit("MOVQ", _T_bool, _ET)     
-    self.Emit("MOVQ", _ET, _VAR_et)
     self.Byte(0x4c, 0x8d, 0x0d)         // LEAQ (PC), R9
     self.Sref("_end_{n}", 4)
     self.Emit("MOVQ", _R9, _VAR_pc)
-    self.Sjmp("JMP"  , _LB_skip_one) 
+    self.Sjmp("JMP"  , _LB_coerce_one) 
 
     self.Link("_bool_true_{n}")
     self.Emit("MOVQ", _AX, _IC)                                 // MOVQ AX, IC
@@ -1367,7 +1488,7 @@ func (self *_Assembler) _asm_OP_bool(_ *_Instr) {
     self.Link("_end_{n}")                                       // _end_{n}:
 }
 
-func (self *_Assembler) _asm_OP_num(_ *_Instr) {
+func (self *_Assembler) _asm_OP_num(p *_Instr) {
     self.Emit("MOVQ", jit.Imm(0), _VAR_fl)
     self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm('"'))
     self.Emit("MOVQ", _IC, _BX)
@@ -1388,11 +1509,10 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
     /* call skip one */
     self.Emit("MOVQ", _BX, _VAR_ic)           
     self.Emit("MOVQ", _T_number, _ET)     
-    self.Emit("MOVQ", _ET, _VAR_et)
     self.Byte(0x4c, 0x8d, 0x0d)       
     self.Sref("_num_end_{n}", 4)
     self.Emit("MOVQ", _R9, _VAR_pc)
-    self.Sjmp("JMP"  , _LB_skip_one)
+    self.Sjmp("JMP"  , _LB_coerce_one)
 
     /* assign string */
     self.Link("_num_next_{n}")
@@ -1406,88 +1526,119 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
     self.Emit("MOVQ", _SI, jit.Ptr(_VP, 8))     // MOVQ  SI, 8(VP)
     self.WriteRecNotAX(13, _DI, jit.Ptr(_VP, 0), false, false)
     self.Emit("CMPQ", _VAR_fl, jit.Imm(1))
-    self.Sjmp("JNE", "_num_end_{n}")
+    self.Sjmp("JE", "_num_quote_{n}")
+    self.check_delim(p)
+    self.Sjmp("JMP", "_num_end_{n}")
+    self.Link("_num_quote_{n}")
     self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm('"'))
     self.Sjmp("JNE", _LB_char_0_error)
     self.Emit("ADDQ", jit.Imm(1), _IC)
     self.Link("_num_end_{n}")
 }
 
-func (self *_Assembler) _asm_OP_i8(_ *_Instr) {
+func (self *_Assembler) _asm_OP_raw(_ *_Instr) {
+    self.call_sf(_F_skip_one)                                   // CALL_SF   skip_one
+    self.Emit("TESTQ", _AX, _AX)                                // TESTQ     AX, AX
+    self.Sjmp("JS"   , _LB_parsing_error_v)                     // JS        _parse_error_v
+    self.slice_from_r(_AX, 0)                                   // SLICE_R   AX, $0
+
+    /* refer to the source unless asked to copy it */
+    self.Emit("BTQ"  , jit.Imm(_F_copy_string), _ARG_fv)        // BTQ       ${_F_copy_string}, fv
+    self.Sjmp("JNC"  , "_raw_write_{n}")                        // JNC       _raw_write_{n}
+    self.Byte(0x4c, 0x8d, 0x0d)                                 // LEAQ      (PC), R9
+    self.Sref("_raw_write_{n}", 4)
+    self.Sjmp("JMP"  , "_copy_string")                          // JMP       _copy_string
+    self.Link("_raw_write_{n}")                                 // _raw_write_{n}:
+    self.Emit("MOVQ" , _SI, jit.Ptr(_VP, 8))                    // MOVQ      SI, 8(VP)
+    self.Emit("MOVQ" , _SI, jit.Ptr(_VP, 16))                   // MOVQ      SI, 16(VP)
+    self.WriteRecNotAX(15, _DI, jit.Ptr(_VP, 0), false, false)  // MOVQ      DI, (VP)
+}
+
+func (self *_Assembler) _asm_OP_i8(p *_Instr) {
     var pin = "_i8_end_{n}"
     self.parse_signed(int8Type, pin, -1)                                                 // PARSE int8
+    self.check_delim(p)
     self.range_signed_CX(_I_int8, _T_int8, math.MinInt8, math.MaxInt8)     // RANGE int8
     self.Emit("MOVB", _CX, jit.Ptr(_VP, 0))                             // MOVB  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_i16(_ *_Instr) {
+func (self *_Assembler) _asm_OP_i16(p *_Instr) {
     var pin = "_i16_end_{n}"
     self.parse_signed(int16Type, pin, -1)                                                     // PARSE int16
+    self.check_delim(p)
     self.range_signed_CX(_I_int16, _T_int16, math.MinInt16, math.MaxInt16)     // RANGE int16
     self.Emit("MOVW", _CX, jit.Ptr(_VP, 0))                                 // MOVW  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_i32(_ *_Instr) {
+func (self *_Assembler) _asm_OP_i32(p *_Instr) {
     var pin = "_i32_end_{n}"
     self.parse_signed(int32Type, pin, -1)                                                     // PARSE int32
+    self.check_delim(p)
     self.range_signed_CX(_I_int32, _T_int32, math.MinInt32, math.MaxInt32)     // RANGE int32
     self.Emit("MOVL", _CX, jit.Ptr(_VP, 0))                                 // MOVL  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_i64(_ *_Instr) {
+func (self *_Assembler) _asm_OP_i64(p *_Instr) {
     var pin = "_i64_end_{n}"
     self.parse_signed(int64Type, pin, -1)                         // PARSE int64
+    self.check_delim(p)
     self.Emit("MOVQ", _VAR_st_Iv, _AX)          // MOVQ  st.Iv, AX
     self.Emit("MOVQ", _AX, jit.Ptr(_VP, 0))     // MOVQ  AX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_u8(_ *_Instr) {
+func (self *_Assembler) _asm_OP_u8(p *_Instr) {
     var pin = "_u8_end_{n}"
     self.parse_unsigned(uint8Type, pin, -1)                                   // PARSE uint8
+    self.check_delim(p)
     self.range_unsigned_CX(_I_uint8, _T_uint8, math.MaxUint8)  // RANGE uint8
     self.Emit("MOVB", _CX, jit.Ptr(_VP, 0))                 // MOVB  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_u16(_ *_Instr) {
+func (self *_Assembler) _asm_OP_u16(p *_Instr) {
     var pin = "_u16_end_{n}"
     self.parse_unsigned(uint16Type, pin, -1)                                       // PARSE uint16
+    self.check_delim(p)
     self.range_unsigned_CX(_I_uint16, _T_uint16, math.MaxUint16)   // RANGE uint16
     self.Emit("MOVW", _CX, jit.Ptr(_VP, 0))                     // MOVW  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_u32(_ *_Instr) {
+func (self *_Assembler) _asm_OP_u32(p *_Instr) {
     var pin = "_u32_end_{n}"
     self.parse_unsigned(uint32Type, pin, -1)                                       // PARSE uint32
+    self.check_delim(p)
     self.range_uint32_CX(_I_uint32, _T_uint32)   // RANGE uint32
     self.Emit("MOVL", _CX, jit.Ptr(_VP, 0))                     // MOVL  CX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_u64(_ *_Instr) {
+func (self *_Assembler) _asm_OP_u64(p *_Instr) {
     var pin = "_u64_end_{n}"
     self.parse_unsigned(uint64Type, pin, -1)                       // PARSE uint64
+    self.check_delim(p)
     self.Emit("MOVQ", _VAR_st_Iv, _AX)          // MOVQ  st.Iv, AX
     self.Emit("MOVQ", _AX, jit.Ptr(_VP, 0))     // MOVQ  AX, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_f32(_ *_Instr) {
+func (self *_Assembler) _asm_OP_f32(p *_Instr) {
     var pin = "_f32_end_{n}"
     self.parse_number(float32Type, pin, -1)                         // PARSE NUMBER
+    self.check_delim(p)
     self.range_single_X0()                         // RANGE float32
     self.Emit("MOVSS", _X0, jit.Ptr(_VP, 0))    // MOVSS X0, (VP)
     self.Link(pin)
 }
 
-func (self *_Assembler) _asm_OP_f64(_ *_Instr) {
+func (self *_Assembler) _asm_OP_f64(p *_Instr) {
     var pin = "_f64_end_{n}"
     self.parse_number(float64Type, pin, -1)                         // PARSE NUMBER
+    self.check_delim(p)
     self.Emit("MOVSD", _VAR_st_Dv, _X0)         // MOVSD st.Dv, X0
     self.Emit("MOVSD", _X0, jit.Ptr(_VP, 0))    // MOVSD X0, (VP)
     self.Link(pin)
@@ -1718,6 +1869,52 @@ func (self *_Assembler) _asm_OP_slice_init(p *_Instr) {
     self.Link("_done_{n}")                          // _done_{n}
 }
 
+func (self *_Assembler) _asm_OP_check_tuple(p *_Instr) {
+    self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm('['))    // CMPB    (IP)(IC), $'['
+    self.Sjmp("JNE" , "_not_tuple_{n}")                         // JNE     _not_tuple_{n}
+    self.Emit("MOVQ", jit.Type(p.vt()), _AX)                    // MOVQ    ${p.vt()}, AX
+    self.decode_typed(_F_decodeTypedTuple, _AX, _VP)            // DECODE  AX, VP
+    self.Xjmp("JMP" , p.vi())                                   // JMP     {p.vi()}
+    self.Link("_not_tuple_{n}")                                 // _not_tuple_{n}:
+}
+
+func (self *_Assembler) _asm_OP_check_time(p *_Instr) {
+    self.Emit("BTQ" , jit.Imm(_F_flexible_time), _ARG_fv)       // BTQ     ${_F_flexible_time}, fv
+    self.Sjmp("JNC" , "_not_flex_time_{n}")                     // JNC     _not_flex_time_{n}
+    self.Emit("MOVQ", jit.Type(p.vt()), _AX)                    // MOVQ    ${p.vt()}, AX
+    self.decode_typed(_F_decodeFlexTime, _AX, _VP)              // DECODE  AX, VP
+    self.Xjmp("JMP" , p.vi())                                   // JMP     {p.vi()}
+    self.Link("_not_flex_time_{n}")                             // _not_flex_time_{n}:
+}
+
+func (self *_Assembler) _asm_OP_check_ref(p *_Instr) {
+    self.Emit("BTQ" , jit.Imm(_F_pointer_refs), _ARG_fv)        // BTQ     ${_F_pointer_refs}, fv
+    self.Sjmp("JNC" , "_not_ref_{n}")                           // JNC     _not_ref_{n}
+    self.Emit("MOVQ", jit.Type(p.vt()), _AX)                    // MOVQ    ${p.vt()}, AX
+    self.decode_typed(_F_decodeRef, _AX, _VP)                   // DECODE  AX, VP
+    self.Xjmp("JMP" , p.vi())                                   // JMP     {p.vi()}
+    self.Link("_not_ref_{n}")                                   // _not_ref_{n}:
+}
+
+func (self *_Assembler) _asm_OP_required(p *_Instr) {
+    self.Emit("MOVQ", jit.Type(p.vt()), _AX)                    // MOVQ    ${p.vt()}, AX
+    self.decode_typed(_F_decodeRequired, _AX, _VP)              // DECODE  AX, VP
+}
+
+func (self *_Assembler) _asm_OP_setter(p *_Instr) {
+    self.Emit("MOVQ", jit.ImmPtr(unsafe.Pointer(p.vm())), _AX) // MOVQ    ${p.vm()}, AX
+    self.decode_typed(_F_decodeSetter, _AX, _VP)                // DECODE  AX, VP
+}
+
+func (self *_Assembler) _asm_OP_check_pairs(p *_Instr) {
+    self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm('{'))    // CMPB    (IP)(IC), $'{'
+    self.Sjmp("JNE" , "_not_pairs_{n}")                         // JNE     _not_pairs_{n}
+    self.Emit("MOVQ", jit.Type(p.vt()), _AX)                    // MOVQ    ${p.vt()}, AX
+    self.decode_typed(_F_decodeTypedPairs, _AX, _VP)            // DECODE  AX, VP
+    self.Xjmp("JMP" , p.vi())                                   // JMP     {p.vi()}
+    self.Link("_not_pairs_{n}")                                 // _not_pairs_{n}:
+}
+
 func (self *_Assembler) _asm_OP_check_empty(p *_Instr) {
     rbracket := p.vb()
     if rbracket == ']' {
@@ -1726,6 +1923,11 @@ func (self *_Assembler) _asm_OP_check_empty(p *_Instr) {
         self.Emit("CMPB", jit.Sib(_IP, _IC, 1, 0), jit.Imm(int64(rbracket))) // CMPB    (IP)(IC), ']'
         self.Sjmp("JNE" , "_not_empty_array_{n}")                            // JNE     _not_empty_array_{n}
         self.Emit("MOVQ", _AX, _IC)                                          // MOVQ    AX, IC
+        self.Emit("CMPQ", jit.Ptr(_VP, 0), jit.Imm(0))                       // CMPQ    (VP), $0
+        self.Sjmp("JE"  , "_empty_nil_{n}")                                  // JE      _empty_nil_{n}
+        self.Emit("MOVQ", jit.Imm(0), jit.Ptr(_VP, 8))                       // MOVQ    $0, 8(VP)
+        self.Xjmp("JMP" , p.vi())                                            // JMP     {p.vi()}
+        self.Link("_empty_nil_{n}")                                          // _empty_nil_{n}:
         self.Emit("MOVQ", jit.Imm(_Zero_Base), _AX)
         self.WritePtrAX(9, jit.Ptr(_VP, 0), false)
         self.Emit("PXOR", _X0, _X0)                                          // PXOR    X0, X0
@@ -1789,12 +1991,34 @@ func (self *_Assembler) _asm_OP_object_next(_ *_Instr) {
     self.Sjmp("JS"   , _LB_parsing_error_v)     // JS      _parse_error_v
 }
 
+func (self *_Assembler) check_key() {
+    self.Emit("BTQ"  , jit.Imm(_F_limit_key_length), _ARG_fv)   // BTQ     ${_F_limit_key_length}, fv
+    self.Sjmp("JNC"  , "_key_checked_{n}")                      // JNC     _key_checked_{n}
+    self.Emit("CMPQ" , _ARG_sv_n, jit.Imm(_MaxKeyLength))       // CMPQ    sv.n, ${_MaxKeyLength}
+    self.Sjmp("JA"   , _LB_key_error)                           // JA      _key_error
+    self.Link("_key_checked_{n}")                               // _key_checked_{n}:
+}
+
+func (self *_Assembler) resolve_key() {
+    self.Emit("MOVQ" , jit.Ptr(_ST, _RnOffset), _AX)            // MOVQ    stack.rn, AX
+    self.Emit("TESTQ", _AX, _AX)                                // TESTQ   AX, AX
+    self.Sjmp("JZ"   , "_resolved_{n}")                         // JZ      _resolved_{n}
+    self.Emit("MOVQ" , _ARG_sv_p, _BX)                          // MOVQ    sv.p, BX
+    self.Emit("MOVQ" , _ARG_sv_n, _CX)                          // MOVQ    sv.n, CX
+    self.call_go(_F_NameResolver_Resolve)                       // CALL_GO NameResolver::Resolve
+    self.Emit("MOVQ" , _AX, _ARG_sv_p)                          // MOVQ    AX, sv.p
+    self.Emit("MOVQ" , _BX, _ARG_sv_n)                          // MOVQ    BX, sv.n
+    self.Link("_resolved_{n}")                                  // _resolved_{n}:
+}
+
 func (self *_Assembler) _asm_OP_struct_field(p *_Instr) {
     assert_eq(caching.FieldEntrySize, 32, "invalid field entry size")
     self.Emit("MOVQ" , jit.Imm(-1), _AX)                        // MOVQ    $-1, AX
     self.Emit("MOVQ" , _AX, _VAR_sr)                            // MOVQ    AX, sr
     self.parse_string()                                         // PARSE   STRING
     self.unquote_once(_ARG_sv_p, _ARG_sv_n, true, false)                     // UNQUOTE once, sv.p, sv.n
+    self.check_key()                                            // CHECK   sv.n
+    self.resolve_key()                                          // RESOLVE sv
     self.Emit("LEAQ" , _ARG_sv, _AX)                            // LEAQ    sv, AX
     self.Emit("XORL" , _BX, _BX)                                // XORL    BX, BX
     self.call_go(_F_strhash)                                    // CALL_GO strhash
@@ -1956,17 +2180,48 @@ func (self *_Assembler) _asm_OP_load(_ *_Instr) {
     self.Emit("MOVQ", jit.Sib(_ST, _AX, 1, 0), _VP)     // MOVQ (ST)(AX), VP
 }
 
+var (
+    _F_growStack   = jit.Func(growStack)
+    _F_shrinkStack = jit.Func(shrinkStack)
+)
+
 func (self *_Assembler) _asm_OP_save(_ *_Instr) {
     self.Emit("MOVQ", jit.Ptr(_ST, 0), _CX)             // MOVQ (ST), CX
+    self.Emit("MOVQ", jit.Ptr(_ST, _RsOffset), _AX)     // MOVQ ${_RsOffset}(ST), AX
+    self.Emit("ADDQ", _CX, _AX)                         // ADDQ CX, AX
+    self.Emit("CMPQ", _AX, jit.Imm(_MaxStackBytes))     // CMPQ AX, ${_MaxStackBytes}
+    self.Sjmp("JB"   , "_save_{n}")                     // JB   _save_{n}
     self.Emit("CMPQ", _CX, jit.Imm(_MaxStackBytes))     // CMPQ CX, ${_MaxStackBytes}
-    self.Sjmp("JAE"  , _LB_stack_error)                  // JA   _stack_error
+    self.Sjmp("JB"   , _LB_stack_error)                  // JB   _stack_error
+    self.Emit("BTQ" , jit.Imm(_F_grow_stack), _ARG_fv)  // BTQ  ${_F_grow_stack}, fv
+    self.Sjmp("JNC"  , _LB_stack_error)                  // JNC  _stack_error
+    self.Emit("MOVQ", _ST, _AX)                         // MOVQ ST, AX
+    self.call_go(_F_growStack)                          // CALL_GO growStack
+    self.Emit("TESTB", _AX, _AX)                        // TESTB AL, AL
+    self.Sjmp("JZ"   , _LB_stack_error)                  // JZ   _stack_error
+    self.Emit("MOVQ", jit.Ptr(_ST, 0), _CX)             // MOVQ (ST), CX
+    self.Link("_save_{n}")                              // _save_{n}:
     self.WriteRecNotAX(0 , _VP, jit.Sib(_ST, _CX, 1, 8), false, false) // MOVQ VP, 8(ST)(CX)
     self.Emit("ADDQ", jit.Imm(8), _CX)                  // ADDQ $8, CX
     self.Emit("MOVQ", _CX, jit.Ptr(_ST, 0))             // MOVQ CX, (ST)
 }
 
-func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
+// shrink_stack refills the state stack from the heap when OptionGrowStack has
+// spilled it and there are too few slots left to drop, it leaves sp in AX
+func (self *_Assembler) shrink_stack() {
     self.Emit("MOVQ", jit.Ptr(_ST, 0), _AX)             // MOVQ (ST), AX
+    self.Emit("CMPQ", _AX, jit.Imm(4 * _PtrBytes))      // CMPQ AX, $32
+    self.Sjmp("JAE" , "_shrunk_{n}")                    // JAE  _shrunk_{n}
+    self.Emit("CMPQ", jit.Ptr(_ST, _HsLenOffset), jit.Imm(0)) // CMPQ ${_HsLenOffset}(ST), $0
+    self.Sjmp("JE"  , "_shrunk_{n}")                    // JE   _shrunk_{n}
+    self.Emit("MOVQ", _ST, _AX)                         // MOVQ ST, AX
+    self.call_go(_F_shrinkStack)                        // CALL_GO shrinkStack
+    self.Emit("MOVQ", jit.Ptr(_ST, 0), _AX)             // MOVQ (ST), AX
+    self.Link("_shrunk_{n}")                            // _shrunk_{n}:
+}
+
+func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
+    self.shrink_stack()                                 // SHRINK_STACK
     self.Emit("SUBQ", jit.Imm(8), _AX)                  // SUBQ $8, AX
     self.Emit("MOVQ", jit.Sib(_ST, _AX, 1, 8), _VP)     // MOVQ 8(ST)(AX), VP
     self.Emit("MOVQ", _AX, jit.Ptr(_ST, 0))             // MOVQ AX, (ST)
@@ -1975,7 +2230,7 @@ func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
 }
 
 func (self *_Assembler) _asm_OP_drop_2(_ *_Instr) {
-    self.Emit("MOVQ" , jit.Ptr(_ST, 0), _AX)            // MOVQ  (ST), AX
+    self.shrink_stack()                                 // SHRINK_STACK
     self.Emit("SUBQ" , jit.Imm(16), _AX)                // SUBQ  $16, AX
     self.Emit("MOVQ" , jit.Sib(_ST, _AX, 1, 8), _VP)    // MOVQ  8(ST)(AX), VP
     self.Emit("MOVQ" , _AX, jit.Ptr(_ST, 0))            // MOVQ  AX, (ST)
@@ -27,7 +27,6 @@ import (
 	"strings"
 	"unsafe"
 
-	"github.com/bytedance/sonic/internal/caching"
 	"github.com/bytedance/sonic/internal/jit"
 	"github.com/bytedance/sonic/internal/native"
 	"github.com/bytedance/sonic/internal/native/types"
@@ -93,10 +92,15 @@ const (
 	_MODE_JSON = 1 << 3 // base64 mode
 )
 
+const (
+	_LoopAlign = 16 // alignment of the loop heads, to keep branch targets in one fetch block
+)
+
 const (
 	_LB_error           = "_error"
 	_LB_im_error        = "_im_error"
 	_LB_eof_error       = "_eof_error"
+	_LB_key_error       = "_key_error"
 	_LB_type_error      = "_type_error"
 	_LB_field_error     = "_field_error"
 	_LB_range_error     = "_range_error"
@@ -121,78 +125,94 @@ const (
 const (
 	_LB_skip_one = "_skip_one"
 	_LB_skip_key_value = "_skip_key_value"
+	_LB_coerce_one     = "_coerce_one"
+)
+
+// ARM64 Register definitions, named after the A64 X registers while the Go
+// assembler calls them R0 to R30
+var (
+	_X0  = jit.R0
+	_X1  = jit.R1
+	_X2  = jit.R2
+	_X3  = jit.R3
+	_X4  = jit.R4
+	_X5  = jit.R5
+	_X6  = jit.R6
+	_X7  = jit.R7
+	_X8  = jit.R8
+	_X9  = jit.R9
+	_X10 = jit.R10
+	_X11 = jit.R11
+	_X12 = jit.R12
+	_X13 = jit.R13
+	_X14 = jit.R14
+	_X15 = jit.R15
+	_X16 = jit.R16
+	_X17 = jit.R17
+	_X18 = jit.R18
+	_X19 = jit.R19
+	_X20 = jit.R20
+	_X21 = jit.R21
+	_X22 = jit.R22
+	_X23 = jit.R23
+	_X24 = jit.R24
+	_X25 = jit.R25
+	_X26 = jit.R26
+	_X27 = jit.R27
+	_X28 = jit.R28
+	_X29 = jit.FP // frame pointer
+	_X30 = jit.LR // link register
+	_SP  = jit.RSP
+	_ZR  = jit.ZR
 )
 
-// ARM64 Register definitions
+// ARM64 floating point registers, which the Go assembler calls F0 to F31 for
+// both the single (S) and the double (D) precision views
 var (
-	_X0 = jit.Reg("X0")
-	_X1 = jit.Reg("X1")
-	_X2 = jit.Reg("X2")
-	_X3 = jit.Reg("X3")
-	_X4 = jit.Reg("X4")
-	_X5 = jit.Reg("X5")
-	_X6 = jit.Reg("X6")
-	_X7 = jit.Reg("X7")
-	_X8 = jit.Reg("X8")
-	_X9 = jit.Reg("X9")
-	_X10 = jit.Reg("X10")
-	_X11 = jit.Reg("X11")
-	_X12 = jit.Reg("X12")
-	_X13 = jit.Reg("X13")
-	_X14 = jit.Reg("X14")
-	_X15 = jit.Reg("X15")
-	_X16 = jit.Reg("X16")
-	_X17 = jit.Reg("X17")
-	_X18 = jit.Reg("X18")
-	_X19 = jit.Reg("X19")
-	_X20 = jit.Reg("X20")
-	_X21 = jit.Reg("X21")
-	_X22 = jit.Reg("X22")
-	_X23 = jit.Reg("X23")
-	_X24 = jit.Reg("X24")
-	_X25 = jit.Reg("X25")
-	_X26 = jit.Reg("X26")
-	_X27 = jit.Reg("X27")
-	_X28 = jit.Reg("X28")
-	_X29 = jit.Reg("X29") // FP
-	_X30 = jit.Reg("X30") // LR
-	_SP = jit.Reg("SP")
-	_ZR = jit.Reg("ZR")
+	_D0  = jit.F0
+	_D1  = jit.F1
+	_D2  = jit.F2
+	_D3  = jit.F3
+	_D4  = jit.F4
+	_D5  = jit.F5
+	_D6  = jit.F6
+	_D7  = jit.F7
+	_D8  = jit.F8
+	_D9  = jit.F9
+	_D10 = jit.F10
+	_D11 = jit.F11
+	_D12 = jit.F12
+	_D13 = jit.F13
+	_D14 = jit.F14
+	_D15 = jit.F15
+	_S0  = jit.F0 // the low half of D0
+	_S1  = jit.F1 // the low half of D1
 )
 
-// ARM64 floating point registers
+// ARM64 SIMD registers
 var (
-	_D0 = jit.Reg("D0")
-	_D1 = jit.Reg("D1")
-	_D2 = jit.Reg("D2")
-	_D3 = jit.Reg("D3")
-	_D4 = jit.Reg("D4")
-	_D5 = jit.Reg("D5")
-	_D6 = jit.Reg("D6")
-	_D7 = jit.Reg("D7")
-	_D8 = jit.Reg("D8")
-	_D9 = jit.Reg("D9")
-	_D10 = jit.Reg("D10")
-	_D11 = jit.Reg("D11")
-	_D12 = jit.Reg("D12")
-	_D13 = jit.Reg("D13")
-	_D14 = jit.Reg("D14")
-	_D15 = jit.Reg("D15")
+	_V0 = jit.Reg("V0")
+	_V1 = jit.Reg("V1")
+	_V2 = jit.Reg("V2")
+	_V3 = jit.Reg("V3")
+	_V4 = jit.Reg("V4")
+	_V5 = jit.Reg("V5")
+	_V6 = jit.Reg("V6")
 )
 
 // State registers (callee-saved)
 var (
-	_ST = jit.Reg("X19")  // stack base
-	_IP = jit.Reg("X20")  // input pointer
-	_IL = jit.Reg("X21")  // input length
-	_IC = jit.Reg("X22")  // input cursor
-	_VP = jit.Reg("X23")  // value pointer
+	_ST = _X19  // stack base
+	_IP = _X20  // input pointer
+	_IL = _X21  // input length
+	_IC = _X22  // input cursor
+	_VP = _X23  // value pointer
 )
 
 // Error registers (caller-saved)
 var (
-	_ET = jit.Reg("X0")   // error type
-	_EP = jit.Reg("X1")   // error pointer
+	_ET = _X0   // error type
+	_EP = _X1   // error pointer
 )
 
 // Argument locations
@@ -249,6 +269,8 @@ var (
 	_VAR_ic = jit.Ptr(_SP, _FP_fargs + _FP_saves + 128) // save mismatched position
 )
 
+var _VAR_ss_X4 = jit.Ptr(_SP, _FP_fargs + _FP_saves + 136)
+
 type _Assembler struct {
 	jit.BaseAssembler
 	p _Program
@@ -278,6 +300,7 @@ func (self *_Assembler) compile() {
 	self.copy_string()
 	self.escape_string()
 	self.escape_string_twice()
+	self.coerce_one()
 	self.skip_one()
 	self.skip_key_value()
 	self.type_error()
@@ -298,6 +321,7 @@ var _OpFuncTab = [256]func(*_Assembler, *_Instr) {
 	_OP_bin              : (*_Assembler)._asm_OP_bin,
 	_OP_bool             : (*_Assembler)._asm_OP_bool,
 	_OP_num              : (*_Assembler)._asm_OP_num,
+	_OP_raw              : (*_Assembler)._asm_OP_raw,
 	_OP_i8               : (*_Assembler)._asm_OP_i8,
 	_OP_i16              : (*_Assembler)._asm_OP_i16,
 	_OP_i32              : (*_Assembler)._asm_OP_i32,
@@ -358,6 +382,12 @@ var _OpFuncTab = [256]func(*_Assembler, *_Instr) {
 	_OP_skip_emtpy       : (*_Assembler)._asm_OP_skip_empty,
 	_OP_add              : (*_Assembler)._asm_OP_add,
 	_OP_check_empty      : (*_Assembler)._asm_OP_check_empty,
+	_OP_check_tuple      : (*_Assembler)._asm_OP_check_tuple,
+	_OP_check_pairs      : (*_Assembler)._asm_OP_check_pairs,
+	_OP_setter           : (*_Assembler)._asm_OP_setter,
+	_OP_check_time       : (*_Assembler)._asm_OP_check_time,
+	_OP_check_ref        : (*_Assembler)._asm_OP_check_ref,
+	_OP_required         : (*_Assembler)._asm_OP_required,
 	_OP_unsupported      : (*_Assembler)._asm_OP_unsupported,
 	_OP_debug            : (*_Assembler)._asm_OP_debug,
 }
@@ -375,13 +405,30 @@ func (self *_Assembler) instr(v *_Instr) {
 }
 
 func (self *_Assembler) instrs() {
+	heads := self.loops()
 	for i, v := range self.p {
+		if heads[i] {
+			self.Align(_LoopAlign)
+		}
 		self.Mark(i)
 		self.instr(&v)
 		self.debug_instr(i, &v)
 	}
 }
 
+// loops returns the heads of the loops in the program, which are the
+// targets of backward jumps, such as the lspace before each element of
+// slices and maps
+func (self *_Assembler) loops() map[int]bool {
+	heads := make(map[int]bool)
+	for i, v := range self.p {
+		if v.op() == _OP_goto && v.vi() <= i {
+			heads[v.vi()] = true
+		}
+	}
+	return heads
+}
+
 func (self *_Assembler) epilogue() {
 	self.Mark(len(self.p))
 	self.Emit("MOVD", _VAR_et, _ET)                     // MOVD VAR_et, ET
@@ -419,10 +466,12 @@ func (self *_Assembler) prologue() {
 	self.Emit("MOVD", _ZR, _ARG_sv_n)                  // MOVD ZR, sv.n
 	self.Emit("MOVD", _ZR, _ARG_vk)                    // MOVD ZR, vk
 	self.Emit("MOVD", _ZR, _VAR_et)                    // MOVD ZR, et
-	// initialize digital buffer first
-	self.Emit("MOVD", jit.Imm(_MaxDigitNums), _VAR_st_Dc) // MOVD #_MaxDigitNums, st.Dcap
-	self.Emit("ADD", _X0, _ST, jit.Imm(_DbufOffset))    // ADD X0, ST, #_DbufOffset
-	self.Emit("MOVD", _X0, _VAR_st_Db)                  // MOVD X0, st.Dbuf
+	// initialize digital buffer first, only numbers need it
+	if self.p.hasNumber() {
+		self.Emit("MOVD", jit.Imm(_MaxDigitNums), _VAR_st_Dc) // MOVD #_MaxDigitNums, st.Dcap
+		self.Emit("ADD", _X0, _ST, jit.Imm(_DbufOffset))    // ADD X0, ST, #_DbufOffset
+		self.Emit("MOVD", _X0, _VAR_st_Db)                  // MOVD X0, st.Dbuf
+	}
 }
 
 /** Function Calling Helpers **/
@@ -454,7 +503,7 @@ func (self *_Assembler) load(r ...obj.Addr) {
 
 func (self *_Assembler) call(fn obj.Addr) {
 	self.Emit("MOVD", fn, _X16)                    // MOVD ${fn}, X16
-	self.Rjmp("BLR", _X16)                          // BLR X16
+	self.Rjmp("CALL", _X16)                         // CALL    (X16)
 }
 
 func (self *_Assembler) call_go(fn obj.Addr) {
@@ -516,7 +565,7 @@ var (
 )
 
 var (
-	_V_stackOverflow              = jit.Imm(int64(uintptr(unsafe.Pointer(&stackOverflow))))
+	_V_stackOverflow              = jit.Imm(int64(uintptr(unsafe.Pointer(stackOverflow))))
 	_I_json_UnsupportedValueError = jit.Itab(_T_error, reflect.TypeOf(new(json.UnsupportedValueError)))
 	_I_json_MismatchTypeError     = jit.Itab(_T_error, reflect.TypeOf(new(MismatchTypeError)))
 	_I_json_MismatchQuotedError   = jit.Itab(_T_error, reflect.TypeOf(new(MismatchQuotedError)))
@@ -584,9 +633,13 @@ func (self *_Assembler) parsing_error() {
 	self.Emit("MOVD", _IL, _IC)                     // MOVD    IL, IC
 	self.Emit("MOVW", jit.Imm(int64(types.ERR_EOF)), _EP) // MOVW    ${types.ERR_EOF}, EP
 	self.Sjmp("B", _LB_parsing_error)               // B     _parsing_error
+	self.Link(_LB_key_error)                        // _key_error:
+	self.Emit("MOVW", jit.Imm(int64(types.ERR_KEY_TOO_LONG)), _EP) // MOVW    ${types.ERR_KEY_TOO_LONG}, EP
+	self.Sjmp("B", _LB_parsing_error)               // B     _parsing_error
 	self.Link(_LB_unquote_error)                    // _unquote_error:
-	self.Emit("SUB", _SI, _SI, _VAR_sr)            // SUB    SI, SI, sr
-	self.Emit("SUB", _IC, _IC, _SI)                // SUB    IC, IC, SI
+	self.Emit("MOVD", _VAR_sr, _X2)                // MOVD   sr, X2
+	self.Emit("SUB", _X1, _X1, _X2)                // SUB    X1, X1, X2
+	self.Emit("SUB", _IC, _IC, _X1)                // SUB    IC, IC, X1
 	self.Link(_LB_parsing_error_v)                  // _parsing_error_v:
 	self.Emit("MOVD", _X0, _EP)                    // MOVD    X0, EP
 	self.Emit("NEG", _EP, _EP)                     // NEG    EP, EP
@@ -632,8 +685,8 @@ func (self *_Assembler) _asm_OP_dismatch_err(p *_Instr) {
 }
 
 func (self *_Assembler) _asm_OP_go_skip(p *_Instr) {
-	self.Byte(0x50, 0x00, 0x00, 0x58)              // ADRP X16, pc+...
-	self.Emit("ADD", _X16, _X16, jit.Imm(p.vi())) // ADD X16, X16, #{p.vi()}
+	self.Byte(0x10, 0x00, 0x00, 0x10)              // ADR  X16, pc+...
+	self.Xref(p.vi(), 4)                            // .... &{p.vi()}
 	self.Emit("MOVD", _X16, _VAR_pc)                // MOVD X16, VAR_pc
 	self.Sjmp("B", _LB_skip_one)                   // B     _skip_one
 }
@@ -657,14 +710,49 @@ func (self *_Assembler) _asm_OP_skip_empty(p *_Instr) {
 	self.Sjmp("BPL", _LB_field_error)              // BPL _field_error
 }
 
+var _F_decodeCoerced = jit.Func(decodeCoerced)
+
+// coerce_one gives the coercion hook a chance to decode the mismatched value
+// before it is skipped, ET holds the type which VP points to.
+func (self *_Assembler) coerce_one() {
+	self.Link(_LB_coerce_one)                       // _coerce_one:
+	self.Emit("MOVD", _VP, _X1)                     // MOVD    VP, X1
+	self.Emit("MOVD", _ARG_sp, _X2)                 // MOVD    sp, X2
+	self.Emit("MOVD", _ARG_sl, _X3)                 // MOVD    sl, X3
+	self.Emit("MOVD", _VAR_ic, _X4)                 // MOVD    _VAR_ic, X4
+	self.call_go(_F_decodeCoerced)                  // CALL_GO decodeCoerced
+	self.Emit("CMP", _X0, _ZR)                      // CMP     X0, ZR
+	self.Sjmp("BMI", "_coerce_mismatch")            // BMI     _coerce_mismatch
+	self.Emit("MOVD", _X0, _IC)                     // MOVD    X0, IC
+	self.Emit("MOVD", _VAR_pc, _X16)                // MOVD    pc, X16
+	self.Rjmp("JMP", _X16)                          // JMP     (X16)
+	self.Link("_coerce_mismatch")                   // _coerce_mismatch:
+	self.Emit("MOVD", _X1, _VAR_et)                 // MOVD    X1, VAR_et
+	self.Sjmp("B", _LB_skip_one)                    // B       _skip_one
+}
+
 func (self *_Assembler) skip_one() {
 	self.Link(_LB_skip_one)                         // _skip:
+	self.stat_inc(&statMismatches, "_stat_mismatch") // INC     statMismatches
 	self.Emit("MOVD", _VAR_ic, _IC)                 // MOVD    _VAR_ic, IC
 	self.call_sf(_F_skip_one)                       // CALL_SF skip_one
 	self.Emit("CMP", _X0, _ZR)                      // CMP    X0, ZR
 	self.Sjmp("BMI", _LB_parsing_error_v)          // BMI      _parse_error_v
 	self.Emit("MOVD", _VAR_pc, _X16)               // MOVD    pc, X16
-	self.Rjmp("BR", _X16)                           // BR     (X16)
+	self.Rjmp("JMP", _X16)                          // JMP     (X16)
+}
+
+// stat_inc increments the runtime counter at p, see Decoder.Stats. It uses an
+// exclusive load/store pair rather than the LSE atomics so that it runs on all
+// the ARMv8 cores, and clobbers X15, X16 and X17.
+func (self *_Assembler) stat_inc(p *uint64, loop string) {
+	self.Emit("MOVD", jit.Imm(int64(uintptr(unsafe.Pointer(p)))), _X16) // MOVD    ${p}, X16
+	self.Link(loop)                                                      // ${loop}:
+	self.Emit("LDAXR", jit.Ptr(_X16, 0), _X17)                           // LDAXR   (X16), X17
+	self.Emit("ADD", _X17, _X17, jit.Imm(1))                             // ADD     X17, X17, #1
+	self.Emit("STLXR", _X17, jit.Ptr(_X16, 0)).RegTo2 = _X15.Reg         // STLXR   X17, (X16), X15
+	self.Emit("CMPW", _X15, _ZR)                                         // CMPW    X15, ZR
+	self.Sjmp("BNE", loop)                                               // BNE     ${loop}
 }
 
 func (self *_Assembler) skip_key_value() {
@@ -687,7 +775,7 @@ func (self *_Assembler) skip_key_value() {
 	self.Sjmp("BMI", _LB_parsing_error_v)          // BMI      _parse_error_v
 	// jump back to specified address
 	self.Emit("MOVD", _VAR_pc, _X16)               // MOVD    pc, X16
-	self.Rjmp("BR", _X16)                           // BR     (X16)
+	self.Rjmp("JMP", _X16)                          // JMP     (X16)
 }
 
 /** Memory Management Routines **/
@@ -746,21 +834,20 @@ func (self *_Assembler) check_err(vt reflect.Type, pin string, pin2 int) {
 	if vt != nil {
 		self.Sjmp("BPL", "_check_err_{n}")           // BPL  _parsing_error_v
 		self.Emit("MOVD", jit.Type(vt), _ET)
-		self.Emit("MOVD", _ET, _VAR_et)
 		if pin2 != -1 {
+			self.Emit("MOVD", _ET, _VAR_et)
 			self.Emit("SUB", _X1, _X1, jit.Imm(1)) // SUB X1, X1, #1
 			self.Emit("MOVD", _X1, _VAR_ic)
-			self.Byte(0x50, 0x00, 0x00, 0x58)      // ADRP X16, pc+...
-			self.Emit("ADD", _X16, _X16, jit.Imm(pin2)) // ADD X16, X16, #{pin2}
+			self.Byte(0x10, 0x00, 0x00, 0x10)      // ADR  X16, pc+...
+			self.Xref(pin2, 4)                     // .... &{pin2}
 			self.Emit("MOVD", _X16, _VAR_pc)
 			self.Sjmp("B", _LB_skip_key_value)
 		} else {
 			self.Emit("MOVD", _X1, _VAR_ic)
-			self.Byte(0x50, 0x00, 0x00, 0x58)      // ADRP X16, pc+...
-			self.Sref(pin, 4)
-			self.Emit("ADD", _X16, _X16, _X16)       // ADD X16, X16, X16
+			self.Byte(0x10, 0x00, 0x00, 0x10)      // ADR  X16, pc+...
+			self.Sref(pin, 4)                      // .... &{pin}
 			self.Emit("MOVD", _X16, _VAR_pc)
-			self.Sjmp("B", _LB_skip_one)
+			self.Sjmp("B", _LB_coerce_one)
 		}
 		self.Link("_check_err_{n}")
 	} else {
@@ -816,7 +903,17 @@ func (self *_Assembler) copy_string() {
 	self.Emit("MOVD", _ARG_sv_p, _X0)
 	self.Emit("MOVD", _VAR_bs_n, _X1)
 	self.Emit("MOVD", _VAR_bs_LR, _X16)
-	self.Rjmp("BR", _X16)
+	self.Rjmp("JMP", _X16)
+}
+
+// unicode_replace sets r to F_UNICODE_REPLACE unless the caller passed
+// F_disable_urc, mirroring the BTQ/SETCC/SHLQ sequence on amd64.
+func (self *_Assembler) unicode_replace(r obj.Addr) {
+	self.Emit("MOVD", _ARG_fv, r)                    // MOVD fv, r
+	self.Emit("MVN", r, r)                           // MVN  r, r
+	self.Emit("LSR", r, r, jit.Imm(_F_disable_urc))  // LSR  r, r, ${_F_disable_urc}
+	self.Emit("AND", r, r, jit.Imm(1))               // AND  r, r, #1
+	self.Emit("LSL", r, r, jit.Imm(types.B_UNICODE_REPLACE)) // LSL r, r, ${types.B_UNICODE_REPLACE}
 }
 
 // Pointer: X0, Size: X1, Return: X16
@@ -830,10 +927,7 @@ func (self *_Assembler) escape_string() {
 	self.Emit("MOVD", _VAR_bs_p, _X0)
 	self.Emit("MOVD", _VAR_bs_n, _X1)
 	self.Emit("ADD", _X2, _SP, jit.Imm(_FP_fargs + _FP_saves + 104)) // ADD X2, SP, #sr_offset
-	self.Emit("MOVD", _ZR, _X3)                      // XORL X3, X3
-	self.Emit("TST", jit.Imm(_F_disable_urc), _ARG_fv) // TST ${_F_disable_urc}, fv
-	self.Emit("CSET", _X3, jit.Imm(1))               // CSET X3, NE
-	self.Emit("LSL", _X3, _X3, jit.Imm(types.B_UNICODE_REPLACE)) // LSL X3, X3, ${types.B_UNICODE_REPLACE}
+	self.unicode_replace(_X3)                        // X3 = urc ? ${types.F_UNICODE_REPLACE} : 0
 	self.call_c(_F_unquote)                          // CALL   unquote
 	self.Emit("MOVD", _VAR_bs_n, _X1)                // MOVD   ${n}, X1
 	self.Emit("ADD", _X1, _X1, jit.Imm(1))          // ADD    X1, X1, #1
@@ -842,7 +936,7 @@ func (self *_Assembler) escape_string() {
 	self.Emit("MOVD", _X0, _X1)
 	self.Emit("MOVD", _ARG_sv_p, _X0)
 	self.Emit("MOVD", _VAR_bs_LR, _X16)
-	self.Rjmp("BR", _X16)
+	self.Rjmp("JMP", _X16)
 }
 
 func (self *_Assembler) escape_string_twice() {
@@ -856,9 +950,7 @@ func (self *_Assembler) escape_string_twice() {
 	self.Emit("MOVD", _VAR_bs_n, _X1)
 	self.Emit("ADD", _X2, _SP, jit.Imm(_FP_fargs + _FP_saves + 104)) // ADD X2, SP, #sr_offset
 	self.Emit("MOVW", jit.Imm(types.F_DOUBLE_UNQUOTE), _X3) // MOVW ${types.F_DOUBLE_UNQUOTE}, X3
-	self.Emit("TST", jit.Imm(_F_disable_urc), _ARG_fv) // TST ${_F_disable_urc}, fv
-	self.Emit("CSET", _X4, jit.Imm(1))               // CSET X4, NE
-	self.Emit("LSL", _X4, _X4, jit.Imm(types.B_UNICODE_REPLACE)) // LSL X4, X4, ${types.B_UNICODE_REPLACE}
+	self.unicode_replace(_X4)                        // X4 = urc ? ${types.F_UNICODE_REPLACE} : 0
 	self.Emit("ORR", _X3, _X3, _X4)                 // ORR X3, X3, X4
 	self.call_c(_F_unquote)                          // CALL   unquote
 	self.Emit("MOVD", _VAR_bs_n, _X1)                // MOVD   ${n}, X1
@@ -868,7 +960,7 @@ func (self *_Assembler) escape_string_twice() {
 	self.Emit("MOVD", _X0, _X1)
 	self.Emit("MOVD", _ARG_sv_p, _X0)
 	self.Emit("MOVD", _VAR_bs_LR, _X16)
-	self.Rjmp("BR", _X16)
+	self.Rjmp("JMP", _X16)
 }
 
 /** Range Checking Routines **/
@@ -889,15 +981,18 @@ func init() {
 }
 
 func (self *_Assembler) range_single_D0() {
-	self.Emit("FCVT", _S0, _D0, jit.Imm(0))         // FCVT S0, D0
-	self.Emit("MOVD", _V_max_f32, _X1)              // MOVD _max_f32, X1
-	self.Emit("MOVD", jit.Gitab(_I_float32), _ET)   // MOVD ${itab(float32)}, ET
-	self.Emit("MOVD", jit.Gtype(_T_float32), _EP)   // MOVD ${type(float32)}, EP
-	self.Emit("FCMP", _S0, jit.Ptr(_X1, 0))         // FCMP S0, (X1)
-	self.Sjmp("BGT", _LB_range_error)              // BGT     _range_error
-	self.Emit("MOVD", _V_min_f32, _X1)              // MOVD _min_f32, X1
-	self.Emit("FCMP", _S0, jit.Ptr(_X1, 0))         // FCMP S0, (X1)
-	self.Sjmp("BLT", _LB_range_error)              // BLT     _range_error
+	self.Emit("FMOVD", _VAR_st_Dv, _D0)             // FMOVD  st.Dv, D0
+	self.Emit("FCVTDS", _D0, _S0)                   // FCVTDS D0, S0
+	self.Emit("MOVD", _V_max_f32, _X1)              // MOVD   _max_f32, X1
+	self.Emit("MOVD", jit.Gitab(_I_float32), _ET)   // MOVD   ${itab(float32)}, ET
+	self.Emit("MOVD", jit.Gtype(_T_float32), _EP)   // MOVD   ${type(float32)}, EP
+	self.Emit("FMOVS", jit.Ptr(_X1, 0), _S1)        // FMOVS  (X1), S1
+	self.Emit("FCMPS", _S0, _S1)                    // FCMPS  S0, S1
+	self.Sjmp("BGT", _LB_range_error)              // BGT    _range_error
+	self.Emit("MOVD", _V_min_f32, _X1)              // MOVD   _min_f32, X1
+	self.Emit("FMOVS", jit.Ptr(_X1, 0), _S1)        // FMOVS  (X1), S1
+	self.Emit("FCMPS", _S0, _S1)                    // FCMPS  S0, S1
+	self.Sjmp("BMI", _LB_range_error)              // BMI    _range_error
 }
 
 func (self *_Assembler) range_signed_X1(i *rt.GoItab, t *rt.GoType, a int64, b int64) {
@@ -953,14 +1048,14 @@ func (self *_Assembler) unquote_once(p obj.Addr, n obj.Addr, stack bool, copy bo
 	self.slice_from(_VAR_st_Iv, -1)                 // SLICE  st.Iv, #-1
 	self.Emit("CMP", _VAR_st_Ep, jit.Imm(-1))       // CMP   st.Ep, #-1
 	self.Sjmp("BEQ", "_noescape_{n}")               // BEQ     _escape_{n}
-	self.Byte(0x50, 0x00, 0x00, 0x58)             // ADRP X16, pc+...
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_unquote_once_write_{n}", 4)
 	self.Sjmp("B", "_escape_string")
 	self.Link("_noescape_{n}")
 	if copy {
 		self.Emit("TST", jit.Imm(_F_copy_string), _ARG_fv)
 		self.Sjmp("BCC", "_unquote_once_write_{n}")
-		self.Byte(0x50, 0x00, 0x00, 0x58)         // ADRP X16, pc+...
+		self.Byte(0x10, 0x00, 0x00, 0x10)         // ADR  X16, pc+...
 		self.Sref("_unquote_once_write_{n}", 4)
 		self.Sjmp("B", "_copy_string")
 	}
@@ -989,13 +1084,13 @@ func (self *_Assembler) unquote_twice(p obj.Addr, n obj.Addr, stack bool) {
 	self.Emit("ADD", _X0, _X1, _VAR_st_Iv)         // ADD X0, X1, st.Iv
 	self.Emit("CMP", _VAR_st_Ep, _X0)              // CMP st.Ep, X0
 	self.Sjmp("BEQ", "_noescape_{n}")               // BEQ     _noescape_{n}
-	self.Byte(0x50, 0x00, 0x00, 0x58)             // ADRP X16, pc+...
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_unquote_twice_write_{n}", 4)
 	self.Sjmp("B", "_escape_string_twice")
 	self.Link("_noescape_{n}")                      // _noescape_{n}:
 	self.Emit("TST", jit.Imm(_F_copy_string), _ARG_fv)
 	self.Sjmp("BCC", "_unquote_twice_write_{n}")
-	self.Byte(0x50, 0x00, 0x00, 0x58)             // ADRP X16, pc+...
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_unquote_twice_write_{n}", 4)
 	self.Sjmp("B", "_copy_string")
 	self.Link("_unquote_twice_write_{n}")
@@ -1192,17 +1287,48 @@ func (self *_Assembler) unmarshal_func(t reflect.Type, fn obj.Addr, deref bool)
 	}
 }
 
+/** State Stack Routines **/
+
+var (
+	_F_growStack   obj.Addr
+	_F_shrinkStack obj.Addr
+)
+
+func init() {
+	_F_growStack = jit.Func(growStack)
+	_F_shrinkStack = jit.Func(shrinkStack)
+}
+
 /** Dynamic Decoding Routine **/
 
 var (
+	_F_decodeTypedTuple   obj.Addr
+	_F_decodeTypedPairs   obj.Addr
 	_F_decodeTypedPointer obj.Addr
+	_F_decodeDynamic      obj.Addr
+	_F_decodeSetter       obj.Addr
+	_F_decodeFlexTime     obj.Addr
+	_F_decodeRef          obj.Addr
+	_F_decodeRequired     obj.Addr
 )
 
 func init() {
+	_F_decodeTypedTuple = jit.Func(decodeTypedTuple)
+	_F_decodeTypedPairs = jit.Func(decodeTypedPairs)
 	_F_decodeTypedPointer = jit.Func(decodeTypedPointer)
+	_F_decodeDynamic = jit.Func(decodeDynamic)
+	_F_decodeSetter = jit.Func(decodeSetter)
+	_F_decodeFlexTime = jit.Func(decodeFlexTime)
+	_F_decodeRef = jit.Func(decodeRef)
+	_F_decodeRequired = jit.Func(decodeRequired)
 }
 
 func (self *_Assembler) decode_dynamic(vt obj.Addr, vp obj.Addr) {
+	self.stat_inc(&statDynamics, "_stat_dynamic_{n}") // INC     statDynamics
+	self.decode_typed(_F_decodeDynamic, vt, vp)
+}
+
+func (self *_Assembler) decode_typed(fn obj.Addr, vt obj.Addr, vp obj.Addr) {
 	self.Emit("MOVD", vp, _X1)                       // MOVD    ${vp}, X1
 	self.Emit("MOVD", vt, _X2)                       // MOVD    ${vt}, X2
 	self.Emit("MOVD", _ARG_sp, _X0)                  // MOVD    sp, X0
@@ -1211,8 +1337,8 @@ func (self *_Assembler) decode_dynamic(vt obj.Addr, vp obj.Addr) {
 	self.Emit("MOVD", _ST, _X3)                      // MOVD    ST, X3
 	self.Emit("MOVD", _ARG_fv, _X4)                  // MOVD    fv, X4
 	self.save(_REG_rt...)
-	self.Emit("MOVD", _F_decodeTypedPointer, _X5)    // MOVD ${fn}, X5
-	self.Rjmp("BLR", _X5)                           // BLR X5
+	self.Emit("MOVD", fn, _X5)                       // MOVD ${fn}, X5
+	self.Rjmp("CALL", _X5)                          // CALL    (X5)
 	self.load(_REG_rt...)
 	self.Emit("MOVD", _X0, _IC)                      // MOVD    X0, IC
 	self.Emit("MOVD", _X1, _ET)                      // MOVD    X1, ET
@@ -1227,42 +1353,9 @@ func (self *_Assembler) decode_dynamic(vt obj.Addr, vp obj.Addr) {
 	self.Link("_decode_dynamic_end_{n}")
 }
 
-// Continue implementing the remaining operation code handlers...
-// This file is getting large, so I'll continue with the remaining
-// opcode implementations in the next part.
-
-// Placeholder for the remaining opcode implementations
-func (self *_Assembler) _asm_OP_any(_ *_Instr) {
-	// Implementation for OP_any
+func (self *_Assembler) print_gc(i int, p1 *_Instr, p2 *_Instr) {
+	self.Emit("MOVD", jit.Imm(int64(p2.op())), _X2) // MOVD    $(p2.op()), X2
+	self.Emit("MOVD", jit.Imm(int64(p1.op())), _X1) // MOVD    $(p1.op()), X1
+	self.Emit("MOVD", jit.Imm(int64(i)), _X0)       // MOVD    $(i), X0
+	self.call_go(_F_println)
 }
-
-func (self *_Assembler) _asm_OP_dyn(_ *_Instr) {
-	// Implementation for OP_dyn
-}
-
-// ... More opcode implementations will follow
-
-// Helper functions for ARM64 instruction generation
-var (
-	argPtrs = []obj.Addr{_ARG_sp, _ARG_sl, _ARG_ic, _ARG_vp, _ARG_sb, _ARG_fv}
-	localPtrs = []obj.Addr{_VAR_st, _VAR_st_Vt, _VAR_st_Dv, _VAR_st_Iv}
-)
-
-func ptodec(code jit.Code) _Decoder {
-	// Convert JIT code to decoder interface
-	return _Decoder{
-		encode: func(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64, sv string) (int, error) {
-			// Implementation would call the generated code
-			return 0, nil
-		},
-	}
-}
-
-type _Decoder struct {
-	encode func(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64, sv string) (int, error)
-}
-
-// Placeholder for debug instruction
-func (self *_Assembler) debug_instr(i int, v *_Instr) {
-	// Implementation for debugging instructions
-}
\ No newline at end of file
@@ -21,18 +21,17 @@ package jitdec
 
 import (
 	"encoding/json"
-	"fmt"
 	"math"
 	"reflect"
-	"sort"
-	"strconv"
-	"strings"
 	"unsafe"
 
 	"github.com/bytedance/sonic/internal/caching"
+	"github.com/bytedance/sonic/internal/native"
+	"github.com/bytedance/sonic/internal/resolver"
 	"github.com/bytedance/sonic/internal/rt"
-	"github.com/bytedance/sonic/internal/native/types"
 	"github.com/bytedance/sonic/internal/jit"
+	"github.com/twitchyliquid64/golang-asm/obj"
+	"github.com/twitchyliquid64/golang-asm/obj/arm64"
 )
 
 // Additional helper functions
@@ -43,28 +42,16 @@ var (
 	_F_makeslice        = jit.Func(rt.MakeSliceStd)
 	_F_makemap_small    = jit.Func(rt.MakemapSmall)
 	_F_mapassign_fast64 = jit.Func(rt.Mapassign_fast64)
-	_F_lspace           = jit.Func(jit.Func(native.S_lspace))
-	_F_strhash          = jit.Func(jit.Func(caching.S_strhash))
-	_F_b64decode        = jit.Func(jit.Func(rt.SubrB64Decode))
-	_F_decodeValue      = jit.Func(jit.Func(_subr_decode_value))
+	_F_lspace           = jit.Imm(int64(native.S_lspace))
+	_F_strhash          = jit.Imm(int64(caching.S_strhash))
+	_F_decodeBase64     = jit.Func(decodeBase64)
+	_F_decodeValue      = jit.Func(_subr_decode_value)
 	_F_FieldMap_GetCaseInsensitive = jit.Func((*caching.FieldMap).GetCaseInsensitive)
+	_F_NameResolver_Resolve = jit.Func((*resolver.NameResolver).Resolve)
 	_ByteSlice = []byte{}
 	_Zero_Base = int64(uintptr(((*rt.GoSlice)(unsafe.Pointer(&_ByteSlice))).Ptr))
 )
 
-var (
-	_F_convT64 = jit.Func(rt.ConvT64)
-	_F_error_wrap = jit.Func(error_wrap)
-	_F_error_type = jit.Func(error_type)
-	_F_error_field = jit.Func(error_field)
-	_F_error_value = jit.Func(error_value)
-	_F_error_mismatch = jit.Func(error_mismatch)
-)
-
-var (
-	_F_println = jit.Func(fmt.Println)
-)
-
 // Opcode implementations
 
 func (self *_Assembler) _asm_OP_any(_ *_Instr) {
@@ -82,12 +69,21 @@ func (self *_Assembler) _asm_OP_any(_ *_Instr) {
 	self.decode_dynamic(_X0, _X3)                       // DECODE  X0, X3
 	self.Sjmp("B", "_decode_end_{n}")                  // B       _decode_end_{n}
 	self.Link("_decode_{n}")                           // _decode_{n}:
-	self.Emit("MOVD", _ARG_fv, _X4)                    // MOVD    fv, X4
-	self.Emit("MOVD", _ST, jit.Ptr(_SP, 0))            // MOVD    _ST, (SP)
-	self.call(_F_decodeValue)                          // CALL    decodeValue
-	self.Emit("MOVD", _ZR, jit.Ptr(_SP, 0))            // MOVD    _ST, (SP)
-	self.Emit("CMP", _EP, _ZR)                         // CMP     EP, ZR
-	self.Sjmp("BNE", _LB_parsing_error)               // BNE     _parsing_error
+	self.Emit("MOVD", _ARG_sp, _X0)                    // MOVD    sp, X0
+	self.Emit("MOVD", _ARG_sl, _X1)                    // MOVD    sl, X1
+	self.Emit("MOVD", _IC, _X2)                        // MOVD    IC, X2
+	self.Emit("MOVD", _VP, _X3)                        // MOVD    VP, X3
+	self.Emit("MOVD", _ST, _X4)                        // MOVD    ST, X4
+	self.Emit("MOVD", _ARG_fv, _X5)                    // MOVD    fv, X5
+	self.save(_REG_rt...)
+	self.Emit("MOVD", _F_decodeValue, _X6)             // MOVD    ${decodeValue}, X6
+	self.Rjmp("CALL", _X6)                             // CALL    (X6)
+	self.load(_REG_rt...)
+	self.Emit("MOVD", _X0, _IC)                        // MOVD    X0, IC
+	self.Emit("MOVD", _X1, _ET)                        // MOVD    X1, ET
+	self.Emit("MOVD", _X2, _EP)                        // MOVD    X2, EP
+	self.Emit("CMP", _ET, _ZR)                         // CMP     ET, ZR
+	self.Sjmp("BNE", _LB_error)                        // BNE     _error
 	self.Link("_decode_end_{n}")                       // _decode_end_{n}:
 }
 
@@ -99,7 +95,7 @@ func (self *_Assembler) _asm_OP_dyn(p *_Instr) {
 	/* if nil iface, call skip one */
 	self.Emit("MOVD", _IC, _VAR_ic)
 	self.Emit("MOVD", _ET, _VAR_et)
-	self.Byte(0x50, 0x00, 0x00, 0x58)
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_decode_end_{n}", 4)
 	self.Emit("MOVD", _X16, _VAR_pc)
 	self.Sjmp("B", _LB_skip_one)
@@ -114,7 +110,7 @@ func (self *_Assembler) _asm_OP_dyn(p *_Instr) {
 
 	self.Emit("MOVD", _IC, _VAR_ic)
 	self.Emit("MOVD", _ET, _VAR_et)
-	self.Byte(0x50, 0x00, 0x00, 0x58)
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_decode_end_{n}", 4)
 	self.Emit("MOVD", _X16, _VAR_pc)
 	self.Sjmp("B", _LB_skip_one)
@@ -135,32 +131,24 @@ func (self *_Assembler) _asm_OP_str(_ *_Instr) {
 	self.unquote_once(jit.Ptr(_VP, 0), jit.Ptr(_VP, 8), false, true) // UNQUOTE once, (VP), 8(VP)
 }
 
-func (self *_Assembler) _asm_OP_bin(_ *_Instr) {
-	self.parse_string()                                 // PARSE  STRING
-	self.slice_from(_VAR_st_Iv, -1)                    // SLICE  st.Iv, #-1
-	self.Emit("MOVD", _X0, jit.Ptr(_VP, 0))           // MOVD   X0, (VP)
-	self.Emit("MOVD", _X1, jit.Ptr(_VP, 8))           // MOVD   X1, 8(VP)
-	self.Emit("UBFX", _X1, _X1, jit.Imm(2), jit.Imm(30)) // UBFX X1, X1, #2, #30
-	self.Emit("ADD", _X2, _X1, _X1, jit.Imm(1))        // ADD X2, X1, X1, LSL #1
-	self.Emit("MOVD", _X2, jit.Ptr(_VP, 16))          // MOVD   X2, 16(VP)
-	self.malloc_X0(_X2, _X2)                          // MALLOC X2, X2
-
-	// TODO: due to base64x's bug, only use AVX mode now
-	self.Emit("MOVW", jit.Imm(_MODE_JSON), _X2)        // MOVW $_MODE_JSON, X2
-
-	/* call the decoder */
-	self.Emit("MOVD", _ZR, _X3)                       // MOVD ZR, X3
-	self.Emit("MOVD", _VP, _X0)                       // MOVD  VP, X0
-
-	self.Emit("MOVD", jit.Ptr(_VP, 0), _X4)           // MOVD X4, (VP)
-	self.WriteRecNotAX(4, _X2, jit.Ptr(_VP, 0), true, false) // XCHGQ X2, (VP)
-	self.Emit("MOVD", _X4, _X2)
+// decodeBase64 decodes the base64 string s into the []byte at vp, the native
+// base64x decoder used on AMD64 not being available on ARM64
+func decodeBase64(s string, vp unsafe.Pointer) error {
+	buf, err := rt.DecodeBase64(rt.Str2Mem(s))
+	if err != nil {
+		return err
+	}
+	*(*[]byte)(vp) = buf
+	return nil
+}
 
-	self.Emit("MOVD", _X3, jit.Ptr(_VP, 8))           // XCHGQ X3, 8(VP)
-	self.call_c(_F_b64decode)                          // CALL  b64decode
-	self.Emit("CMP", _X0, _ZR)                        // CMP X0, ZR
-	self.Sjmp("BMI", _LB_base64_error)               // BMI    _base64_error
-	self.Emit("MOVD", _X0, jit.Ptr(_VP, 8))          // MOVD  X0, 8(VP)
+func (self *_Assembler) _asm_OP_bin(_ *_Instr) {
+	self.parse_string()                                 // PARSE   STRING
+	self.slice_from(_VAR_st_Iv, -1)                     // SLICE   st.Iv, #-1
+	self.Emit("MOVD", _VP, _X2)                         // MOVD    VP, X2
+	self.call_go(_F_decodeBase64)                       // CALL_GO decodeBase64
+	self.Emit("CMP", _ET, _ZR)                          // CMP     ET, ZR
+	self.Sjmp("BNE", _LB_error)                         // BNE     _error
 }
 
 func (self *_Assembler) _asm_OP_bool(_ *_Instr) {
@@ -177,7 +165,7 @@ func (self *_Assembler) _asm_OP_bool(_ *_Instr) {
 	self.Emit("MOVD", _IC, _VAR_ic)
 	self.Emit("MOVD", _T_bool, _ET)
 	self.Emit("MOVD", _ET, _VAR_et)
-	self.Byte(0x50, 0x00, 0x00, 0x58)
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_end_{n}", 4)
 	self.Emit("MOVD", _X16, _VAR_pc)
 	self.Sjmp("B", _LB_skip_one)
@@ -214,7 +202,7 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
 	self.Emit("MOVD", _ARG_sp, _X0)                    // MOVD  s, X0
 	self.Emit("MOVD", _IC, _ARG_ic)                    // MOVD  IC, ic
 	self.Emit("MOVD", _ARG_ic, _X1)                    // MOVD  ic, X1
-	self.callc(_F_skip_number)                         // CALL  _F_skip_number
+	self.call_c(_F_skip_number)                         // CALL  _F_skip_number
 	self.Emit("MOVD", _ARG_ic, _IC)                    // MOVD  ic, IC
 	self.Emit("CMP", _X0, _ZR)                         // CMP X0, ZR
 	self.Sjmp("BPL", "_num_next_{n}")
@@ -223,7 +211,7 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
 	self.Emit("MOVD", _X2, _VAR_ic)
 	self.Emit("MOVD", _T_number, _ET)
 	self.Emit("MOVD", _ET, _VAR_et)
-	self.Byte(0x50, 0x00, 0x00, 0x58)
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_num_end_{n}", 4)
 	self.Emit("MOVD", _X16, _VAR_pc)
 	self.Sjmp("B", _LB_skip_one)
@@ -233,7 +221,7 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
 	self.slice_from_r(_X0, 0)
 	self.Emit("TST", jit.Imm(_F_copy_string), _ARG_fv)
 	self.Sjmp("BCC", "_num_write_{n}")
-	self.Byte(0x50, 0x00, 0x00, 0x58)
+	self.Byte(0x10, 0x00, 0x00, 0x10)             // ADR  X16, pc+...
 	self.Sref("_num_write_{n}", 4)
 	self.Sjmp("B", "_copy_string")
 	self.Link("_num_write_{n}")
@@ -248,6 +236,24 @@ func (self *_Assembler) _asm_OP_num(_ *_Instr) {
 	self.Link("_num_end_{n}")
 }
 
+func (self *_Assembler) _asm_OP_raw(_ *_Instr) {
+	self.call_sf(_F_skip_one)                      // CALL_SF   skip_one
+	self.Emit("CMP", _X0, _ZR)                     // CMP       X0, ZR
+	self.Sjmp("BMI", _LB_parsing_error_v)          // BMI       _parse_error_v
+	self.slice_from_r(_X0, 0)                      // SLICE_R   X0, #0
+
+	/* refer to the source unless asked to copy it */
+	self.Emit("TST", jit.Imm(_F_copy_string), _ARG_fv) // TST       ${_F_copy_string}, fv
+	self.Sjmp("BCC", "_raw_write_{n}")             // BCC       _raw_write_{n}
+	self.Byte(0x10, 0x00, 0x00, 0x10)              // ADR       X16, pc+...
+	self.Sref("_raw_write_{n}", 4)
+	self.Sjmp("B", "_copy_string")                 // B         _copy_string
+	self.Link("_raw_write_{n}")                    // _raw_write_{n}:
+	self.Emit("MOVD", _X1, jit.Ptr(_VP, 8))        // MOVD      X1, 8(VP)
+	self.Emit("MOVD", _X1, jit.Ptr(_VP, 16))       // MOVD      X1, 16(VP)
+	self.WriteRecNotAX(15, _X0, jit.Ptr(_VP, 0), false, false) // MOVD      X0, (VP)
+}
+
 func (self *_Assembler) _asm_OP_i8(_ *_Instr) {
 	var pin = "_i8_end_{n}"
 	self.parse_signed(int8Type, pin, -1)             // PARSE int8
@@ -526,11 +532,11 @@ func (self *_Assembler) _asm_OP_array_skip(_ *_Instr) {
 	self.Sjmp("BMI", _LB_parsing_error_v)             // BMI     _parse_error_v
 }
 
-func (self *_Assembler) _asm_OP_array_clear(_ *_Instr) {
+func (self *_Assembler) _asm_OP_array_clear(p *_Instr) {
 	self.mem_clear_rem(p.i64(), true)
 }
 
-func (self *_Assembler) _asm_OP_array_clear_p(_ *_Instr) {
+func (self *_Assembler) _asm_OP_array_clear_p(p *_Instr) {
 	self.mem_clear_rem(p.i64(), false)
 }
 
@@ -550,6 +556,56 @@ func (self *_Assembler) _asm_OP_slice_init(p *_Instr) {
 	self.Link("_done_{n}")                            // _done_{n}
 }
 
+func (self *_Assembler) _asm_OP_check_tuple(p *_Instr) {
+	self.Emit("MOVBU", jit.Sib(_IP, _IC, 1, 0), _X1)         // MOVBU  (IP)(IC), X1
+	self.Emit("CMP", _X1, jit.Imm('['))                      // CMP    X1, #'['
+	self.Sjmp("BNE", "_not_tuple_{n}")                       // BNE    _not_tuple_{n}
+	self.Emit("MOVD", jit.Type(p.vt()), _X0)                 // MOVD   ${p.vt()}, X0
+	self.decode_typed(_F_decodeTypedTuple, _X0, _VP)         // DECODE X0, VP
+	self.Xjmp("B", p.vi())                                   // B      {p.vi()}
+	self.Link("_not_tuple_{n}")                              // _not_tuple_{n}:
+}
+
+func (self *_Assembler) _asm_OP_check_time(p *_Instr) {
+	self.Emit("MOVD", _ARG_fv, _X0)                          // MOVD   fv, X0
+	self.Emit("TST", _X0, jit.Imm(1 << _F_flexible_time))    // TST    X0, #(1 << _F_flexible_time)
+	self.Sjmp("BEQ", "_not_flex_time_{n}")                   // BEQ    _not_flex_time_{n}
+	self.Emit("MOVD", jit.Type(p.vt()), _X0)                 // MOVD   ${p.vt()}, X0
+	self.decode_typed(_F_decodeFlexTime, _X0, _VP)           // DECODE X0, VP
+	self.Xjmp("B", p.vi())                                   // B      {p.vi()}
+	self.Link("_not_flex_time_{n}")                          // _not_flex_time_{n}:
+}
+
+func (self *_Assembler) _asm_OP_check_ref(p *_Instr) {
+	self.Emit("MOVD", _ARG_fv, _X0)                          // MOVD   fv, X0
+	self.Emit("TST", _X0, jit.Imm(1 << _F_pointer_refs))     // TST    X0, #(1 << _F_pointer_refs)
+	self.Sjmp("BEQ", "_not_ref_{n}")                         // BEQ    _not_ref_{n}
+	self.Emit("MOVD", jit.Type(p.vt()), _X0)                 // MOVD   ${p.vt()}, X0
+	self.decode_typed(_F_decodeRef, _X0, _VP)                // DECODE X0, VP
+	self.Xjmp("B", p.vi())                                   // B      {p.vi()}
+	self.Link("_not_ref_{n}")                                // _not_ref_{n}:
+}
+
+func (self *_Assembler) _asm_OP_required(p *_Instr) {
+	self.Emit("MOVD", jit.Type(p.vt()), _X0)                 // MOVD   ${p.vt()}, X0
+	self.decode_typed(_F_decodeRequired, _X0, _VP)           // DECODE X0, VP
+}
+
+func (self *_Assembler) _asm_OP_setter(p *_Instr) {
+	self.Emit("MOVD", jit.ImmPtr(unsafe.Pointer(p.vm())), _X0) // MOVD   ${p.vm()}, X0
+	self.decode_typed(_F_decodeSetter, _X0, _VP)               // DECODE X0, VP
+}
+
+func (self *_Assembler) _asm_OP_check_pairs(p *_Instr) {
+	self.Emit("MOVBU", jit.Sib(_IP, _IC, 1, 0), _X1)         // MOVBU  (IP)(IC), X1
+	self.Emit("CMP", _X1, jit.Imm('{'))                      // CMP    X1, #'{'
+	self.Sjmp("BNE", "_not_pairs_{n}")                       // BNE    _not_pairs_{n}
+	self.Emit("MOVD", jit.Type(p.vt()), _X0)                 // MOVD   ${p.vt()}, X0
+	self.decode_typed(_F_decodeTypedPairs, _X0, _VP)         // DECODE X0, VP
+	self.Xjmp("B", p.vi())                                   // B      {p.vi()}
+	self.Link("_not_pairs_{n}")                              // _not_pairs_{n}:
+}
+
 func (self *_Assembler) _asm_OP_check_empty(p *_Instr) {
 	rbracket := p.vb()
 	if rbracket == ']' {
@@ -559,6 +615,12 @@ func (self *_Assembler) _asm_OP_check_empty(p *_Instr) {
 		self.Emit("CMP", _X1, jit.Imm(int64(rbracket))) // CMP X1, ${rbracket}
 		self.Sjmp("BNE", "_not_empty_array_{n}")     // BNE     _not_empty_array_{n}
 		self.Emit("MOVD", _X0, _IC)                    // MOVD X0, IC
+		self.Emit("MOVD", jit.Ptr(_VP, 0), _X1)         // MOVD (VP), X1
+		self.Emit("CMP", _X1, _ZR)                      // CMP X1, ZR
+		self.Sjmp("BEQ", "_empty_nil_{n}")              // BEQ     _empty_nil_{n}
+		self.Emit("MOVD", _ZR, jit.Ptr(_VP, 8))         // MOVD ZR, 8(VP)
+		self.Xjmp("B", p.vi())                        // B     {p.vi()}
+		self.Link("_empty_nil_{n}")                     // _empty_nil_{n}:
 		self.Emit("MOVD", jit.Imm(_Zero_Base), _X0)
 		self.WritePtrAX(9, jit.Ptr(_VP, 0), false)
 		self.Emit("MOVD", _ZR, jit.Ptr(_VP, 8))         // MOVD ZR, 8(VP)
@@ -621,12 +683,36 @@ func (self *_Assembler) _asm_OP_object_next(_ *_Instr) {
 	self.Sjmp("BMI", _LB_parsing_error_v)            // BMI     _parse_error_v
 }
 
+func (self *_Assembler) resolve_key() {
+	self.Emit("MOVD", jit.Ptr(_ST, _RnOffset), _X0) // MOVD    stack.rn, X0
+	self.Emit("CMP", _X0, _ZR)                      // CMP     X0, ZR
+	self.Sjmp("BEQ", "_resolved_{n}")               // BEQ     _resolved_{n}
+	self.Emit("MOVD", _ARG_sv_p, _X1)               // MOVD    sv.p, X1
+	self.Emit("MOVD", _ARG_sv_n, _X2)               // MOVD    sv.n, X2
+	self.call_go(_F_NameResolver_Resolve)           // CALL_GO NameResolver::Resolve
+	self.Emit("MOVD", _X0, _ARG_sv_p)               // MOVD    X0, sv.p
+	self.Emit("MOVD", _X1, _ARG_sv_n)               // MOVD    X1, sv.n
+	self.Link("_resolved_{n}")                      // _resolved_{n}:
+}
+
+func (self *_Assembler) check_key() {
+	self.Emit("MOVD", _ARG_fv, _X0)                   // MOVD    fv, X0
+	self.Emit("TST", _X0, jit.Imm(1 << _F_limit_key_length)) // TST     X0, #(1 << _F_limit_key_length)
+	self.Sjmp("BEQ", "_key_checked_{n}")              // BEQ     _key_checked_{n}
+	self.Emit("MOVD", _ARG_sv_n, _X1)                 // MOVD    sv.n, X1
+	self.Emit("CMP", _X1, jit.Imm(_MaxKeyLength))     // CMP     X1, #_MaxKeyLength
+	self.Sjmp("BHI", _LB_key_error)                   // BHI     _key_error
+	self.Link("_key_checked_{n}")                     // _key_checked_{n}:
+}
+
 func (self *_Assembler) _asm_OP_struct_field(p *_Instr) {
 	assert_eq(caching.FieldEntrySize, 32, "invalid field entry size")
 	self.Emit("MOVD", jit.Imm(-1), _X0)              // MOVD    $-1, X0
 	self.Emit("MOVD", _X0, _VAR_sr)                  // MOVD    X0, sr
 	self.parse_string()                               // PARSE   STRING
 	self.unquote_once(_ARG_sv_p, _ARG_sv_n, true, false) // UNQUOTE once, sv.p, sv.n
+	self.check_key()                                  // CHECK   sv.n
+	self.resolve_key()                                // RESOLVE sv
 	self.Emit("ADD", _X0, _SP, jit.Imm(_FP_fargs + _FP_saves + 104)) // ADD X0, SP, #sv_offset
 	self.Emit("MOVD", _ZR, _X1)                      // XORL    X1, X1
 	self.call_go(_F_strhash)                         // CALL_GO strhash
@@ -686,6 +772,7 @@ func (self *_Assembler) _asm_OP_struct_field(p *_Instr) {
 	self.Emit("MOVD", _X0, _VAR_sr)                  // MOVD    X0, sr
 	self.Emit("TST", jit.Imm(_F_disable_unknown), _ARG_fv) // BTQ     ${_F_disable_unknown}, fv
 	self.Sjmp("BNE", _LB_field_error)                // BNE     _field_error
+	self.stat_inc(&statUnknowns, "_stat_unknown_{n}") // INC     statUnknowns
 	self.Link("_end_{n}")                             // _end_{n}:
 }
 
@@ -728,6 +815,11 @@ func (self *_Assembler) lspace(subfix string) {
 	self.Emit("TST", _X0, _X1)                      // TST     X0, X1
 	self.Sjmp("BCC", label)                          // BCC     _nospace_{n}
 
+	/* skip 16 characters at a time with NEON */
+	if _HasNEON {
+		self.lspace_neon(label)
+	}
+
 	/* test up to 4 characters */
 	for i := 0; i < 3; i++ {
 		self.Emit("ADD", _IC, _IC, jit.Imm(1))         // ADD     IC, IC, #1
@@ -744,7 +836,7 @@ func (self *_Assembler) lspace(subfix string) {
 	self.Emit("MOVD", _IP, _X0)                      // MOVD    IP, X0
 	self.Emit("MOVD", _IL, _X1)                      // MOVD    IL, X1
 	self.Emit("MOVD", _IC, _X2)                      // MOVD    IC, X2
-	self.callc(_F_lspace)                            // CALL    lspace
+	self.call_c(_F_lspace)                            // CALL    lspace
 	self.Emit("CMP", _X0, _ZR)                      // CMP     X0, ZR
 	self.Sjmp("BMI", _LB_parsing_error_v)           // BMI     _parsing_error_v
 	self.Emit("CMP", _X0, _IL)                      // CMP     X0, IL
@@ -753,6 +845,62 @@ func (self *_Assembler) lspace(subfix string) {
 	self.Link(label)                                 // _nospace_{n}:
 }
 
+var _HasNEON = GetArchitectureInfo()["has_neon"] == true
+
+// lspace_neon skips whitespaces 16 characters at a time as long as they are
+// within the input, and leaves the remaining ones to the scalar path
+func (self *_Assembler) lspace_neon(label string) {
+	var loop = label + "_neon"
+	var tail = label + "_tail"
+	var high = label + "_high"
+	var found = label + "_found"
+	var chars = jit.Vec(_V0, arm64.ARNG_16B)
+	var space = jit.Vec(_V1, arm64.ARNG_16B)
+	var tab = jit.Vec(_V2, arm64.ARNG_16B)
+	var cr = jit.Vec(_V3, arm64.ARNG_16B)
+	var lf = jit.Vec(_V4, arm64.ARNG_16B)
+	var mask = jit.Vec(_V5, arm64.ARNG_16B)
+	var temp = jit.Vec(_V6, arm64.ARNG_16B)
+	self.Emit("VMOVI", jit.Imm(' '), space)                   // VMOVI  $' ', V1.B16
+	self.Emit("VMOVI", jit.Imm('\t'), tab)                    // VMOVI  $'\t', V2.B16
+	self.Emit("VMOVI", jit.Imm('\r'), cr)                     // VMOVI  $'\r', V3.B16
+	self.Emit("VMOVI", jit.Imm('\n'), lf)                     // VMOVI  $'\n', V4.B16
+	self.Link(loop)                                           // _lspace_{n}_neon:
+	self.Emit("ADD", jit.Imm(16), _IC, _X0)                   // ADD    $16, IC, X0
+	self.Emit("CMP", _IL, _X0, obj.Addr{})                    // CMP    IL, X0
+	self.Sjmp("BHI", tail)                                    // BHI    _lspace_{n}_tail
+	self.Emit("ADD", _IC, _IP, _X0)                           // ADD    IC, IP, X0
+	self.Emit("VLD1", jit.Ptr(_X0, 0), jit.VecList(_V0))      // VLD1   (X0), [V0.B16]
+	self.Emit("VCMEQ", space, chars, mask)                    // VCMEQ  V1.B16, V0.B16, V5.B16
+	self.Emit("VCMEQ", tab, chars, temp)                      // VCMEQ  V2.B16, V0.B16, V6.B16
+	self.Emit("VORR", temp, mask, mask)                       // VORR   V6.B16, V5.B16, V5.B16
+	self.Emit("VCMEQ", cr, chars, temp)                       // VCMEQ  V3.B16, V0.B16, V6.B16
+	self.Emit("VORR", temp, mask, mask)                       // VORR   V6.B16, V5.B16, V5.B16
+	self.Emit("VCMEQ", lf, chars, temp)                       // VCMEQ  V4.B16, V0.B16, V6.B16
+	self.Emit("VORR", temp, mask, mask)                       // VORR   V6.B16, V5.B16, V5.B16
+
+	/* the first non-whitespace is the lowest zero byte of the mask */
+	self.Emit("VMOV", jit.VecElem(_V5, arm64.ARNG_D, 0), _X0) // VMOV   V5.D[0], X0
+	self.Emit("MVN", _X0, _X0)                                // MVN    X0, X0
+	self.Emit("TST", _X0, _X0, obj.Addr{})                    // TST    X0, X0
+	self.Sjmp("BNE", found)                                   // BNE    _lspace_{n}_found
+	self.Emit("VMOV", jit.VecElem(_V5, arm64.ARNG_D, 1), _X0) // VMOV   V5.D[1], X0
+	self.Emit("MVN", _X0, _X0)                                // MVN    X0, X0
+	self.Emit("TST", _X0, _X0, obj.Addr{})                    // TST    X0, X0
+	self.Sjmp("BNE", high)                                    // BNE    _lspace_{n}_high
+	self.Emit("ADD", jit.Imm(16), _IC, _IC)                   // ADD    $16, IC, IC
+	self.Sjmp("B", loop)                                      // B      _lspace_{n}_neon
+	self.Link(high)                                           // _lspace_{n}_high:
+	self.Emit("ADD", jit.Imm(8), _IC, _IC)                    // ADD    $8, IC, IC
+	self.Link(found)                                          // _lspace_{n}_found:
+	self.Emit("RBIT", _X0, _X0)                               // RBIT   X0, X0
+	self.Emit("CLZ", _X0, _X0)                                // CLZ    X0, X0
+	self.Emit("LSR", jit.Imm(3), _X0, _X0)                    // LSR    $3, X0, X0
+	self.Emit("ADD", _X0, _IC, _IC)                           // ADD    X0, IC, IC
+	self.Sjmp("B", label)                                     // B      _nospace_{n}
+	self.Link(tail)                                           // _lspace_{n}_tail:
+}
+
 func (self *_Assembler) _asm_OP_match_char(p *_Instr) {
 	self.match_char(p.vb())
 }
@@ -792,15 +940,43 @@ func (self *_Assembler) _asm_OP_load(_ *_Instr) {
 
 func (self *_Assembler) _asm_OP_save(_ *_Instr) {
 	self.Emit("MOVD", jit.Ptr(_ST, 0), _X1)          // MOVD (ST), X1
+	self.Emit("MOVD", jit.Ptr(_ST, _RsOffset), _X0)  // MOVD ${_RsOffset}(ST), X0
+	self.Emit("ADD", _X0, _X0, _X1)                  // ADD X0, X0, X1
+	self.Emit("CMP", _X0, jit.Imm(_MaxStackBytes))   // CMP X0, ${_MaxStackBytes}
+	self.Sjmp("BLO", "_save_{n}")                   // BLO   _save_{n}
 	self.Emit("CMP", _X1, jit.Imm(_MaxStackBytes))   // CMP X1, ${_MaxStackBytes}
-	self.Sjmp("BHS", _LB_stack_error)               // BHS   _stack_error
+	self.Sjmp("BLO", _LB_stack_error)               // BLO   _stack_error
+	self.Emit("MOVD", _ARG_fv, _X0)                  // MOVD fv, X0
+	self.Emit("TST", _X0, jit.Imm(1 << _F_grow_stack)) // TST X0, #(1 << _F_grow_stack)
+	self.Sjmp("BEQ", _LB_stack_error)               // BEQ   _stack_error
+	self.Emit("MOVD", _ST, _X0)                      // MOVD ST, X0
+	self.call_go(_F_growStack)                      // CALL_GO growStack
+	self.Emit("TST", _X0, jit.Imm(0xff))             // TST X0, #0xff
+	self.Sjmp("BEQ", _LB_stack_error)               // BEQ   _stack_error
+	self.Emit("MOVD", jit.Ptr(_ST, 0), _X1)          // MOVD (ST), X1
+	self.Link("_save_{n}")                          // _save_{n}:
 	self.WriteRecNotAX(0, _VP, jit.Sib(_ST, _X1, 1, 8), false, false) // MOVD VP, 8(ST)(X1)
 	self.Emit("ADD", _X1, _X1, jit.Imm(8))           // ADD X1, X1, #8
 	self.Emit("MOVD", _X1, jit.Ptr(_ST, 0))          // MOVD X1, (ST)
 }
 
-func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
+// shrink_stack refills the state stack from the heap when OptionGrowStack has
+// spilled it and there are too few slots left to drop, it leaves sp in X0
+func (self *_Assembler) shrink_stack() {
 	self.Emit("MOVD", jit.Ptr(_ST, 0), _X0)          // MOVD (ST), X0
+	self.Emit("CMP", _X0, jit.Imm(4 * _PtrBytes))    // CMP X0, #32
+	self.Sjmp("BHS", "_shrunk_{n}")                 // BHS   _shrunk_{n}
+	self.Emit("MOVD", jit.Ptr(_ST, _HsLenOffset), _X1) // MOVD ${_HsLenOffset}(ST), X1
+	self.Emit("CMP", _X1, _ZR)                       // CMP X1, ZR
+	self.Sjmp("BEQ", "_shrunk_{n}")                 // BEQ   _shrunk_{n}
+	self.Emit("MOVD", _ST, _X0)                      // MOVD ST, X0
+	self.call_go(_F_shrinkStack)                    // CALL_GO shrinkStack
+	self.Emit("MOVD", jit.Ptr(_ST, 0), _X0)          // MOVD (ST), X0
+	self.Link("_shrunk_{n}")                        // _shrunk_{n}:
+}
+
+func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
+	self.shrink_stack()                             // SHRINK_STACK
 	self.Emit("SUB", _X0, _X0, jit.Imm(8))           // SUB X0, X0, #8
 	self.Emit("MOVD", jit.Sib(_ST, _X0, 1, 8), _VP)  // MOVD 8(ST)(X0), VP
 	self.Emit("MOVD", _X0, jit.Ptr(_ST, 0))          // MOVD X0, (ST)
@@ -808,7 +984,7 @@ func (self *_Assembler) _asm_OP_drop(_ *_Instr) {
 }
 
 func (self *_Assembler) _asm_OP_drop_2(_ *_Instr) {
-	self.Emit("MOVD", jit.Ptr(_ST, 0), _X0)          // MOVD  (ST), X0
+	self.shrink_stack()                             // SHRINK_STACK
 	self.Emit("SUB", _X0, _X0, jit.Imm(16))          // SUB  X0, X0, #16
 	self.Emit("MOVD", jit.Sib(_ST, _X0, 1, 8), _VP)  // MOVD  8(ST)(X0), VP
 	self.Emit("MOVD", _X0, jit.Ptr(_ST, 0))          // MOVD  X0, (ST)
@@ -831,15 +1007,17 @@ func (self *_Assembler) _asm_OP_switch(p *_Instr) {
 	self.Sjmp("BHS", "_default_{n}")                 // BHS  _default_{n}
 
 	/* jump table selector */
-	self.Emit("ADR", _X1, "_switch_table_{n}")     // ADR    X1, ?(PC)
-	self.Emit("MOVWU", jit.Sib(_X1, _X0, 2), _X0)  // MOVWU (X1)(X0*2), X0
+	self.Byte(0x01, 0x00, 0x00, 0x10)               // ADR     X1, ?(PC)
+	self.Sref("_switch_table_{n}", 4)               // ....    &_switch_table_{n}
+	self.Emit("LSL", _X0, _X0, jit.Imm(2))          // LSL     X0, X0, #2
+	self.Emit("MOVW", jit.OffsetReg(_X1, _X0), _X0) // MOVW    (X1)(X0), X0
 	self.Emit("ADD", _X0, _X0, _X1)                 // ADD     X0, X0, X1
-	self.Rjmp("BR", _X0)                           // BR      X0
+	self.Rjmp("JMP", _X0)                          // JMP     (X0)
 	self.Link("_switch_table_{n}")                  // _switch_table_{n}:
 
 	/* generate the jump table */
 	for i, v := range p.vs() {
-		self.Sref(v, int64(-i) * 4)
+		self.Xref(v, int64(-i) * 4)
 	}
 
 	/* default case */
@@ -847,84 +1025,6 @@ func (self *_Assembler) _asm_OP_switch(p *_Instr) {
 	self.Emit("NOP")
 }
 
-func (self *_Assembler) _asm_OP_skip_empty(p *_Instr) {
-	self.call_sf(_F_skip_one)                       // CALL_SF skip_one
-	self.Emit("CMP", _X0, _ZR)                      // CMP    X0, ZR
-	self.Sjmp("BMI", _LB_parsing_error_v)           // BMI     _parse_error_v
-	self.Emit("TST", jit.Imm(_F_disable_unknown), _ARG_fv)
-	self.Xjmp("BCC", p.vi())
-	self.Emit("ADD", _X1, _IC, _X0)                 // ADD X1, IC, X0
-	self.Emit("MOVD", _X1, _ARG_sv_n)               // MOVD X1, sv.n
-	self.Emit("ADD", _X0, _IP, _X0)                 // ADD X0, IP, X0
-	self.Emit("MOVD", _X0, _ARG_sv_p)               // MOVD X0, sv.p
-	self.Emit("MOVD", jit.Imm(':'), _X2)           // MOVD ':', X2
-	self.call_go(_F_IndexByte)
-	self.Emit("CMP", _X0, _ZR)                      // CMP X0, ZR
-	// disallow unknown field
-	self.Sjmp("BPL", _LB_field_error)              // BPL _field_error
-}
-
-func (self *_Assembler) _asm_OP_debug(_ *_Instr) {
-	self.Emit("BRK", jit.Imm(0))
-}
-
-// Additional helper functions
-func assert_eq(a, b interface{}, msg string) {
-	// Implementation for assertion
-}
-
-func freezeFields(vf *caching.FieldMap) int64 {
-	// Implementation for freezing fields
-	return 0
-}
-
-func referenceFields(vf *caching.FieldMap) int64 {
-	// Implementation for reference fields
-	return 0
-}
-
-// Constants needed for ARM64 implementation
-const (
-	_F_convT64 = 0
-	_F_error_wrap = 1
-	_F_error_type = 2
-	_F_error_field = 3
-	_F_error_value = 4
-	_F_error_mismatch = 5
-	_F_memequal = 6
-	_F_memmove = 7
-	_F_growslice = 8
-	_F_makeslice = 9
-	_F_makemap_small = 10
-	_F_mapassign_fast64 = 11
-	_F_lspace = 12
-	_F_strhash = 13
-	_F_b64decode = 14
-	_F_decodeValue = 15
-	_F_FieldMap_GetCaseInsensitive = 16
-	_F_println = 17
-	_F_unquote = 18
-	_F_skip_one = 19
-	_F_skip_array = 20
-	_F_skip_number = 21
-	_F_vstring = 22
-	_F_vnumber = 23
-	_F_vsigned = 24
-	_F_vunsigned = 25
-	_F_IndexByte = 26
-	_F_mallocgc = 27
-	_F_memclrHasPointers = 28
-	_F_memclrNoHeapPointers = 29
-	_F_mapassign = 30
-	_F_mapassign_fast32 = 31
-	_F_mapassign_faststr = 32
-	_F_mapassign_fast64ptr = 33
-	_F_decodeJsonUnmarshaler = 34
-	_F_decodeJsonUnmarshalerQuoted = 35
-	_F_decodeTextUnmarshaler = 36
-	_F_decodeTypedPointer = 37
-)
-
 const (
 	_Fe_ID = int64(unsafe.Offsetof(caching.FieldEntry{}.ID))
 	_Fe_Name = int64(unsafe.Offsetof(caching.FieldEntry{}.Name))
@@ -936,119 +1036,7 @@ const (
 	_Gt_KindFlags = int64(unsafe.Offsetof(rt.GoType{}.KindFlags))
 )
 
-const (
-	_MaxStackBytes = 1 << 20 // 1MB
-	_MinSlice = 32
-	_MaxDigitNums = 32
-	_DbufOffset = 64
-	_FsmOffset = 128
-	_EpOffset = 136
-)
-
-// Type references
-var (
-	int8Type    = reflect.TypeOf(int8(0))
-	int16Type   = reflect.TypeOf(int16(0))
-	int32Type   = reflect.TypeOf(int32(0))
-	uint8Type   = reflect.TypeOf(uint8(0))
-	uint16Type  = reflect.TypeOf(uint16(0))
-	uint32Type  = reflect.TypeOf(uint32(0))
-	float32Type = reflect.TypeOf(float32(0))
-	byteType    = reflect.TypeOf(byte(0))
-	jsonNumberType = reflect.TypeOf(json.Number(""))
-	stringType   = reflect.TypeOf("")
-)
-
-	_I_int8, _T_int8    = rtype(int8Type)
-	_I_int16, _T_int16   = rtype(int16Type)
-	_I_int32, _T_int32   = rtype(int32Type)
-	_I_uint8, _T_uint8   = rtype(uint8Type)
-	_I_uint16, _T_uint16 = rtype(uint16Type)
-	_I_uint32, _T_uint32 = rtype(uint32Type)
-	_I_float32, _T_float32 = rtype(float32Type)
-
-	_T_error                = rt.UnpackType(reflect.TypeOf((*error)(nil)).Elem())
-	_I_base64_CorruptInputError = jit.Itab(_T_error, reflect.TypeOf(base64.CorruptInputError(0)))
-
-	_I_json_UnsupportedValueError = jit.Itab(_T_error, reflect.TypeOf(new(json.UnsupportedValueError)))
-	_I_json_MismatchTypeError     = jit.Itab(_T_error, reflect.TypeOf(new(MismatchTypeError)))
-	_I_json_MismatchQuotedError   = jit.Itab(_T_error, reflect.TypeOf(new(MismatchQuotedError)))
-
-	_T_bool   = rt.UnpackType(reflect.TypeOf(false))
-	_T_number = rt.UnpackType(reflect.TypeOf(json.Number("")))
-)
-
-	jsonUnmarshalerType         = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
-	encodingTextUnmarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-)
-
-// Helper functions
-func rtype(t reflect.Type) (*rt.GoItab, *rt.GoType) {
-	// Implementation for getting runtime type information
-	return nil, rt.UnpackType(t)
-}
-
-func error_wrap(s string, ic int, et interface{}, ep interface{}) error {
-	// Implementation for error wrapping
-	return nil
-}
-
-func error_type(vt interface{}) error {
-	// Implementation for type error
-	return nil
-}
-
-func error_field(field string) error {
-	// Implementation for field error
-	return nil
-}
-
-func error_value(value interface{}, vt interface{}, ep interface{}) error {
-	// Implementation for value error
-	return nil
-}
-
-func error_mismatch(s string, ic int, vt interface{}, et interface{}) error {
-	// Implementation for mismatch error
-	return nil
-}
-
 var (
-	stackOverflow = new(stackOverflowType)
+	_T_bool   = jit.Type(reflect.TypeOf(false))
+	_T_number = jit.Type(reflect.TypeOf(json.Number("")))
 )
-
-type stackOverflowType struct{}
-
-func decodeTypedPointer(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64, sv string) (int, error) {
-	// Implementation for typed pointer decoding
-	return 0, nil
-}
-
-func _subr_decode_value(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
-	// Implementation for value decoding
-	return 0, nil
-}
-
-func (self *_Assembler) WritePtrAX(reg int, addr obj.Addr, spill bool) {
-	// Implementation for writing pointer from AX register
-}
-
-func (self *_Assembler) WriteRecNotAX(reg int, src obj.Addr, dst obj.Addr, spill bool, zero bool) {
-	// Implementation for writing register to memory
-}
-
-func (self *_Assembler) Sref(label string, offset int) {
-	// Implementation for symbol reference
-}
-
-func (self *_Assembler) Xref(label int, offset int) {
-	// Implementation for cross reference
-}
-
-func (self *_Assembler) Xjmp(op string, target int) {
-	// Implementation for extended jump
-}
-
-func (self *_Assembler) Byte(b ...byte) {
-	// Implementation for writing bytes directly
-}
\ No newline at end of file
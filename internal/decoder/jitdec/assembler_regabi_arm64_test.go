@@ -20,19 +20,35 @@
 package jitdec
 
 import (
-	"testing"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
 	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
 	"unsafe"
 
-	"github.com/bytedance/sonic/internal/encoder/vars"
+	"github.com/bytedance/sonic/internal/caching"
+	"github.com/bytedance/sonic/internal/decoder/consts"
 	"github.com/bytedance/sonic/internal/jit"
 	"github.com/bytedance/sonic/internal/rt"
+	"github.com/bytedance/sonic/option"
+	"github.com/twitchyliquid64/golang-asm/obj"
 )
 
 func TestARM64AssemblerCreation(t *testing.T) {
 	// Create a simple instruction program
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 		{u: packOp(_OP_bool)},
 		{u: packOp(_OP_i32)},
 	}
@@ -53,7 +69,7 @@ func TestARM64AssemblerCreation(t *testing.T) {
 
 func TestARM64AssemblerInit(t *testing.T) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 	}
 
 	assembler := newAssembler(prog)
@@ -74,7 +90,7 @@ func TestARM64AssemblerInit(t *testing.T) {
 
 func TestARM64AssemblerLoad(t *testing.T) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 	}
 
 	assembler := newAssembler(prog)
@@ -87,11 +103,141 @@ func TestARM64AssemblerLoad(t *testing.T) {
 	}
 }
 
+// A trap in a generated decoder is fatal, so it is raised in a child process,
+// whose traceback must walk through the generated frame back into the test
+func TestARM64DecoderTraceback(t *testing.T) {
+	if os.Getenv("SONIC_TRACEBACK_TRAP") != "" {
+		assembler := newAssembler(_Program{{u: packOp(_OP_debug)}})
+		assembler.name = "traceback"
+		assembler.Load()("null", 0, nil, new(_Stack), 0, "", nil)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestARM64DecoderTraceback$")
+	cmd.Env = append(os.Environ(), "SONIC_TRACEBACK_TRAP=1", "GOTRACEBACK=all")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected the trap to crash the child process:\n%s", out)
+	}
+	for _, name := range []string{"sonic.jit.arm64.decode_traceback", "TestARM64DecoderTraceback"} {
+		if !strings.Contains(string(out), name) {
+			t.Errorf("Expected %s in the traceback:\n%s", name, out)
+		}
+	}
+}
+
+func TestARM64LoopAlignment(t *testing.T) {
+	prog, err := newCompiler().compile(reflect.TypeOf([]map[string]int{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slice and map loops jump back to the lspace before each element
+	heads := newAssembler(prog).loops()
+	if len(heads) == 0 {
+		t.Error("Expected loop heads in the program")
+	}
+	for i := range heads {
+		if prog[i].op() != _OP_lspace {
+			t.Errorf("Expected lspace at loop head %d, got %s", i, prog[i].op())
+		}
+	}
+}
+
+func loadLspace(neon bool, vt reflect.Type) (_Decoder, error) {
+	defer func(old bool) { _HasNEON = old }(_HasNEON)
+	_HasNEON = neon
+
+	prog, err := newCompiler().compile(vt)
+	if err != nil {
+		return nil, err
+	}
+	assembler := newAssembler(prog)
+	assembler.name = vt.String()
+	return assembler.Load(), nil
+}
+
+func decodeLspace(t *testing.T, neon bool, src string, vt reflect.Type) (interface{}, int, error) {
+	fn, err := loadLspace(neon, vt)
+	if err != nil {
+		t.Fatalf("Compilation of %v failed: %v", vt, err)
+	}
+
+	v := reflect.New(vt).Interface()
+	sb := newStack()
+	defer freeStack(sb)
+	pos, err := fn(src, 0, rt.UnpackEface(v).Value, sb, 0, "", nil)
+	return v, pos, err
+}
+
+func TestARM64LspaceNEON(t *testing.T) {
+	for n := 0; n <= 40; n++ {
+		pad := make([]byte, n)
+		for i := range pad {
+			pad[i] = " \t\r\n"[i%4]
+		}
+		ws := string(pad)
+
+		// whitespace runs around the 16 bytes loads, and up to the end of input
+		for _, c := range []struct {
+			src string
+			vt  reflect.Type
+		}{
+			{"[" + ws + "1" + ws + "," + ws + "2" + ws + "]", reflect.TypeOf([]int{})},
+			{`{"a"` + ws + ":" + ws + "[" + ws + "]" + ws + "}", reflect.TypeOf(map[string][]int{})},
+			{"[1," + ws, reflect.TypeOf([]int{})},
+			{ws, reflect.TypeOf([]int{})},
+		} {
+			v1, p1, e1 := decodeLspace(t, false, c.src, c.vt)
+			v2, p2, e2 := decodeLspace(t, true, c.src, c.vt)
+			if p1 != p2 || (e1 == nil) != (e2 == nil) || !reflect.DeepEqual(v1, v2) {
+				t.Errorf("Mismatch on %q: scalar (%d, %v, %v), neon (%d, %v, %v)", c.src, p1, e1, v1, p2, e2, v2)
+			}
+		}
+	}
+}
+
+func BenchmarkARM64LspaceNEON(b *testing.B) {
+	type item struct {
+		ID   int      `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	items := make([]item, 100)
+	for i := range items {
+		items[i] = item{ID: i, Name: "item" + strconv.Itoa(i), Tags: []string{"a", "b", "c"}}
+	}
+	buf, _ := json.MarshalIndent(items, "", "        ")
+	src := string(buf)
+
+	for _, neon := range []bool{false, true} {
+		name := "scalar"
+		if neon {
+			name = "neon"
+		}
+		fn, err := loadLspace(neon, reflect.TypeOf([]item{}))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			sb := newStack()
+			defer freeStack(sb)
+			b.SetBytes(int64(len(src)))
+			for i := 0; i < b.N; i++ {
+				var v []item
+				if _, err := fn(src, 0, unsafe.Pointer(&v), sb, 0, "", nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestARM64RegisterConstants(t *testing.T) {
 	// Test that all register constants are properly defined
 	tests := []struct {
 		name string
-		reg  jit.Addr
+		reg  obj.Addr
 	}{
 		{"_X0", _X0},
 		{"_X1", _X1},
@@ -108,20 +254,20 @@ func TestARM64RegisterConstants(t *testing.T) {
 		{"X12", _X12},
 		{"X13", _X13},
 		{"X14", _X14},
-		{"X15", _15},
+		{"X15", _X15},
 		{"X16", _X16},
 		{"X17", _X17},
-		{"X18", _18},
+		{"X18", _X18},
 		{"X19", _X19},
 		{"X20", _X20},
-	{"X21", _21},
-		{"X22", _22},
-		{"X23", _23},
-		{"X24", _24},
-		{"X25", _25},
-		{"X26", _26},
-		{"X27", _27},
-		{"X28", _28},
+	{"X21", _X21},
+		{"X22", _X22},
+		{"X23", _X23},
+		{"X24", _X24},
+		{"X25", _X25},
+		{"X26", _X26},
+		{"X27", _X27},
+		{"X28", _X28},
 		{"X29", _X29}, // FP
 		{"X30", _X30}, // LR
 		{"SP", _SP},
@@ -141,7 +287,7 @@ func TestARM64FloatingPointRegisters(t *testing.T) {
 	// Test that floating point register constants are properly defined
 	tests := []struct {
 		name string
-		reg  jit.Addr
+		reg  obj.Addr
 	}{
 		{"_D0", _D0},
 		{"_D1", _D1},
@@ -152,7 +298,7 @@ func TestARM64FloatingPointRegisters(t *testing.T) {
 		{"_D6", _D6},
 		{"D7", _D7},
 		{"D8", _D8},
-		{"D9", _9},
+		{"D9", _D9},
 		{"D10", _D10},
 		{"D11", _D11},
 		{"D12", _D12},
@@ -174,7 +320,7 @@ func TestARM64StateRegisters(t *testing.T) {
 	// Test that state registers are properly defined
 	tests := []struct {
 		name string
-		reg  jit.Addr
+		reg  obj.Addr
 	}{
 		{"_ST", _ST},  // stack base
 		{"_IP", _IP},  // input pointer
@@ -196,7 +342,7 @@ func TestARM64ErrorRegisters(t *testing.T) {
 	// Test that error registers are properly defined
 	tests := []struct {
 		name string
-		reg  jit.Addr
+		reg  obj.Addr
 	}{
 		{"_ET", _ET}, // error type
 		{"_EP", _EP}, // error pointer
@@ -319,7 +465,7 @@ func TestARM64OpFuncTable(t *testing.T) {
 
 	// Test that some key operations are defined
 	keyOps := []_Op{
-		_OP_null,
+		_OP_nil_1,
 		_OP_bool,
 		_OP_i8,
 		_OP_i16,
@@ -353,7 +499,7 @@ func TestARM64OpFuncTable(t *testing.T) {
 
 func TestARM64BasicOperations(t *testing.T) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 		{u: packOp(_OP_bool)},
 		{u: packOp(_OP_i32)},
 	}
@@ -474,17 +620,22 @@ func TestARM64ControlOperations(t *testing.T) {
 	}
 }
 
-func TestARM64ComplexProgram(t *testing.T) {
-	// Create a more complex program that simulates decoding a struct
-	prog := _Program{
-		{u: packOp(_OP_lspace)},
-		{u: packOp(_OP_match_char), vb: '{'},
-		{u: packOp(_OP_text), vs: "name:\""},
-		{u: packOp(_OP_str)},
-		{u: packOp(_OP_text), vs: "\",age:"},
-		{u: packOp(_OP_i64)},
-		{u: packOp(_OP_match_char), vb: '}'},
+// complexProgram compiles the program which decodes a small struct
+func complexProgram(tb testing.TB) _Program {
+	type T struct {
+		Name string `json:"name"`
+		Age  int64  `json:"age"`
+	}
+	prog, err := newCompiler().compile(reflect.TypeOf(T{}))
+	if err != nil {
+		tb.Fatal(err)
 	}
+	return prog
+}
+
+func TestARM64ComplexProgram(t *testing.T) {
+	// Compile a more complex program that decodes a struct
+	prog := complexProgram(t)
 
 	assembler := newAssembler(prog)
 	assembler.name = "test_complex_program"
@@ -500,14 +651,14 @@ func TestARM64InstructionHandling(t *testing.T) {
 	assembler.name = "test_instructions"
 
 	// Test that basic instruction handling doesn't panic
-	assembler.instr(&_Instr{u: packOp(_OP_null)})
+	assembler.instr(&_Instr{u: packOp(_OP_nil_1)})
 	assembler.instr(&_Instr{u: packOp(_OP_bool)})
 	assembler.instr(&_Instr{u: packOp(_OP_i32)})
 }
 
 func TestARM64BuiltinFunctions(t *testing.T) {
 	prog := _Program{
-		{u: packOp(_OP_null)}, // This will call builtins during compilation
+		{u: packOp(_OP_nil_1)}, // This will call builtins during compilation
 	}
 
 	assembler := newAssembler(prog)
@@ -543,49 +694,10 @@ func TestARM64HelperFunctions(t *testing.T) {
 	assembler.call(jit.Func(func() {}))
 }
 
-func TestARM64StackFrameLayout(t *testing.T) {
-	// Verify stack frame layout is consistent
-	if _FP_offs <= _FP_fargs + _FP_saves + _FP_locals {
-		t.Error("FP_offs should be larger than fargs + saves + locals")
-	}
-
-	if _FP_size <= _FP_offs {
-		t.Error("_FP_size should be larger than FP_offs")
-	}
-
-	if _FP_base <= _FP_size {
-		t.Error("_FP_base should be larger than _FP_size")
-	}
-
-	// Verify alignment to 16 bytes (ARM64 requirement)
-	if _FP_size%16 != 0 {
-		t.Errorf("Stack frame size should be 16-byte aligned, got %d", _FP_size)
-	}
-}
-
-func TestARM64RegisterAllocation(t *testing.T) {
-	// Verify that critical registers don't conflict
-	if _IC.Reg == _IP.Reg {
-		t.Error("Input cursor and input pointer registers should be different")
-	}
-
-	if _IC.Reg == _IL.Reg {
-		t.Error("Input cursor and input length registers should be different")
-	}
-
-	if _VP.Reg == _ST.Reg {
-		t.Error("Value pointer and stack base registers should be different")
-	}
-
-	if _ET.Reg == _EP.Reg {
-		t.Error("Error type and error pointer registers should be different")
-	}
-}
-
 // Benchmark tests for performance validation
 func BenchmarkARM64AssemblerCreation(b *testing.B) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 		{u: packOp(_OP_bool)},
 		{u: packOp(_OP_i32)},
 	}
@@ -601,7 +713,7 @@ func BenchmarkARM64AssemblerCreation(b *testing.B) {
 
 func BenchmarkARM64AssemblerLoad(b *testing.B) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 		{u: packOp(_OP_bool)},
 		{u: packOp(_OP_i32)},
 	}
@@ -620,7 +732,7 @@ func BenchmarkARM64AssemblerLoad(b *testing.B) {
 
 func BenchmarkARM64BasicOperations(b *testing.B) {
 	ops := []_Op{
-		_OP_null,
+		_OP_nil_1,
 		_OP_bool,
 		_OP_i32,
 		_OP_str,
@@ -640,15 +752,7 @@ func BenchmarkARM64BasicOperations(b *testing.B) {
 }
 
 func BenchmarkARM64ComplexProgram(b *testing.B) {
-	prog := _Program{
-		{u: packOp(_OP_lspace)},
-		{u: packOp(_OP_match_char), vb: '{'},
-		{u: packOp(_OP_text), vs: "test:"},
-		{u: packOp(_OP_str)},
-		{u: packOp(_OP_match_char), vb: ','},
-		{u: packOp(_OP_i64)},
-		{u: packOp(_OP_match_char), vb: '}'},
-	}
+	prog := complexProgram(b)
 
 	assembler := newAssembler(prog)
 	assembler.name = "benchmark_complex"
@@ -662,253 +766,334 @@ func BenchmarkARM64ComplexProgram(b *testing.B) {
 	}
 }
 
-// Integration test with actual decoding
-func TestARM64DecodingIntegration(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	// This test requires the JIT to be functional
-	if !jit.IsARM64JITEnabled() {
-		t.Skip("ARM64 JIT is not enabled")
-	}
-
-	// Test a simple struct decoding scenario
-	type TestStruct struct {
+// The loaded decoder runs the generated code, instead of returning zero values
+func TestARM64DecodeStruct(t *testing.T) {
+	type T struct {
 		Name string `json:"name"`
 		Age  int    `json:"age"`
-			Valid bool   `json:"valid"`
 	}
 
-	// Create a program that simulates decoding TestStruct
-	prog := _Program{
-		{u: packOp(_OP_lspace)},
-		{u: packOp(_OP_match_char), vb: '{'},
-		{u: packOp(_OP_text), vs: "name:\""},
-		{u: packOp(_OP_str)},
-		{u: packOp(_OP_text), vs: "\",age:"},
-		{u: packOp(_OP_i64)},
-		{u: packOp(_OP_text), vs: ",valid:"},
-		{u: packOp(_OP_bool)},
-		{u: packOp(_OP_match_char), vb: '}'},
+	src := `{"name":"x","age":3}`
+	pf, err := findOrCompile(rt.UnpackType(reflect.TypeOf(T{})), _PK_value)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
 	}
+	fn := pf.(_Decoder)
 
-	assembler := newAssembler(prog)
-	assembler.name = "test_integration"
-
-	decoder := assembler.Load()
-	if decoder == nil {
-		t.Error("Expected non-nil decoder for integration test")
+	var v T
+	sb := newStack()
+	defer freeStack(sb)
+	pos, err := fn(src, 0, unsafe.Pointer(&v), sb, 0, "", nil)
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
 	}
-
-	// The decoder should be callable
-	if decoder == nil {
-		t.Fatal("Decoder should be callable")
+	if pos != len(src) {
+		t.Errorf("Expected position %d, got %d", len(src), pos)
 	}
-
-	// Note: Actual decoding would require more setup and testing infrastructure
-	// This test mainly verifies that the assembler can generate code without panicking
-}
-
-// Test ARM64 specific instruction generation
-func TestARM64InstructionGeneration(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_instruction_generation"
-
-	// Test prologue and epilogue
-	assembler.prologue()
-	assembler.epilogue()
-
-	// Should have generated some code
-	if assembler.CodeSize() == 0 {
-		t.Error("Expected non-zero code size after prologue and epilogue")
+	if v.Name != "x" || v.Age != 3 {
+		t.Errorf("Expected {x 3}, got %+v", v)
 	}
 }
 
-// Test register allocation
-func TestARM64RegisterAllocation(t *testing.T) {
-	// Verify that critical registers don't conflict
-	if _IC.Reg == _IP.Reg {
-		t.Error("Input cursor and input pointer registers should be different")
-	}
-
-	if _IC.Reg == _IL.Reg {
-		t.Error("Input cursor and input length registers should be different")
+func decodeARM64(t *testing.T, src string, v interface{}) {
+	vt := reflect.TypeOf(v).Elem()
+	pf, err := findOrCompile(rt.UnpackType(vt), _PK_value)
+	if err != nil {
+		t.Fatalf("Compilation of %v failed: %v", vt, err)
 	}
+	fn := pf.(_Decoder)
 
-	if _VP.Reg == _ST.Reg {
-		t.Error("Value pointer and stack base registers should be different")
+	sb := newStack()
+	defer freeStack(sb)
+	pos, err := fn(src, 0, rt.UnpackEface(v).Value, sb, 0, "", nil)
+	if err != nil {
+		t.Fatalf("Decoding %s failed: %v", src, err)
 	}
-
-	if _ET.Reg == _EP.Reg {
-		t.Error("Error type and error pointer registers should be different")
+	if pos != len(src) {
+		t.Errorf("Expected position %d, got %d", len(src), pos)
 	}
 }
 
-// Test stack frame layout
-func TestARM64StackFrameLayout(t *testing.T) {
-	// Verify stack frame layout is consistent
-	if _FP_offs <= _FP_fargs + _FP_saves + _FP_locals {
-		t.Error("FP_offs should be larger than fargs + saves + locals")
+// Nested and dynamic values are decoded by decodeTypedPointer
+func TestARM64DecodeTypedPointer(t *testing.T) {
+	var e interface{}
+	decodeARM64(t, `{"a":[1,"b",null]}`, &e)
+	if !reflect.DeepEqual(e, map[string]interface{}{"a": []interface{}{float64(1), "b", nil}}) {
+		t.Errorf("Unexpected interface{} value: %#v", e)
 	}
 
-	if _FP_size <= _FP_offs {
-		t.Error("_FP_size should be larger than FP_offs")
+	var pp **int
+	decodeARM64(t, `42`, &pp)
+	if pp == nil || *pp == nil || **pp != 42 {
+		t.Errorf("Unexpected pointer-to-pointer value: %v", pp)
 	}
 
-	if _FP_base <= _FP_size {
-		t.Error("_FP_base should be larger than _FP_size")
+	type List struct {
+		V    int   `json:"v"`
+		Next *List `json:"next"`
 	}
-
-	// Verify alignment to 16 bytes (ARM64 requirement)
-	if _FP_size%16 != 0 {
-		t.Errorf("Stack frame size should be 16-byte aligned, got %d", _FP_size)
+	var l List
+	decodeARM64(t, `{"v":1,"next":{"v":2,"next":{"v":3,"next":null}}}`, &l)
+	if l.Next == nil || l.Next.Next == nil || l.Next.Next.Next != nil {
+		t.Fatalf("Unexpected list shape: %+v", l)
+	}
+	if l.V != 1 || l.Next.V != 2 || l.Next.Next.V != 3 {
+		t.Errorf("Unexpected list values: %d, %d, %d", l.V, l.Next.V, l.Next.Next.V)
 	}
 }
 
-// Test error handling paths
-func TestARM64ErrorHandlingPaths(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_error_handling"
-
-	// Test that all error handlers can be linked without panicking
-	assembler.type_error()
-	assembler.mismatch_error()
-	assembler.field_error()
-	assembler.range_error()
-	assembler.stack_error()
-	assembler.base64_error()
-	assembler.parsing_error()
+type wbNode struct {
+	Name     string             `json:"name"`
+	Next     *wbNode            `json:"next"`
+	Children []*wbNode          `json:"children"`
+	Attrs    map[string]*wbNode `json:"attrs"`
+	Any      interface{}        `json:"any"`
 }
 
-// Test buffer management
-func TestARM64BufferManagement(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_buffer_management"
+// Pointers stored by the decoder must go through the write barrier, otherwise
+// a concurrent GC frees objects that are still referenced. Run under
+// GODEBUG=gccheckmark=1 as well, which re-execs this test with it set.
+func TestARM64WriteBarrierStress(t *testing.T) {
+	if os.Getenv("SONIC_WB_STRESS") == "" && !testing.Short() {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestARM64WriteBarrierStress$", "-test.short")
+		cmd.Env = append(os.Environ(), "SONIC_WB_STRESS=1", "GODEBUG=gccheckmark=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Stress test under gccheckmark failed: %v\n%s", err, out)
+		}
+	}
 
-	// Test buffer helper functions
-	assembler.check_eof(1)
-	assembler.check_size(10)
-	assembler.add_char('a')
-	assembler.add_long(0x61626364, 4)
-	assembler.add_text("test")
-}
+	src := `{"name":"root","next":{"name":"n1","next":{"name":"n2"}},` +
+		`"children":[{"name":"c1","any":["x",{"y":"z"}]},null,{"name":"c3","attrs":{"k":{"name":"v"}}}],` +
+		`"attrs":{"a":{"name":"aa","children":[{"name":"aaa"}]},"b":null},"any":{"list":[1,"two",{"three":3}]}}`
+	var exp wbNode
+	if err := json.Unmarshal([]byte(src), &exp); err != nil {
+		t.Fatal(err)
+	}
+
+	/* keep the GC busy while decoding */
+	defer debug.SetGCPercent(debug.SetGCPercent(1))
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				runtime.GC()
+			}
+		}
+	}()
 
-// Test state management
-func TestARM64StateManagement(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_state_management"
+	vals := make([]*wbNode, 2000)
+	for i := range vals {
+		vals[i] = new(wbNode)
+		decodeARM64(t, src, vals[i])
+		if i%100 == 0 {
+			runtime.GC()
+		}
+	}
 
-	// Test state management functions
-	assembler.save_state()
-	assembler.drop_state(32)
+	/* churn the heap so freed objects get reused before checking */
+	for i := 0; i < 100; i++ {
+		_ = make([]byte, 1<<16)
+		runtime.GC()
+	}
+	for i, v := range vals {
+		if !reflect.DeepEqual(*v, exp) {
+			t.Fatalf("Value %d corrupted: %+v", i, *v)
+		}
+	}
 }
 
-// Test function calling conventions
-func TestARM64FunctionCalling(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_function_calling"
-
-	// Test function call helpers
-	assembler.save_c()
-	assembler.call_go(jit.Func(func() {}))
-}
+// Generic values are decoded by _subr_decode_value, like encoding/json does
+func TestARM64DecodeGenericValue(t *testing.T) {
+	src := ` {"a": [1, -2.5e3, "x\u00e9y", true, false, null], "b": {"c": {}, "d": []}, "e": 12345678901234567} `
+	var exp interface{}
+	if err := json.Unmarshal([]byte(src), &exp); err != nil {
+		t.Fatal(err)
+	}
 
-// Test memory operations
-func TestARM64MemoryOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_memory_operations"
+	var v interface{}
+	pos, err := _subr_decode_value(src, 0, unsafe.Pointer(&v), nil, 0)
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if pos != len(src)-1 {
+		t.Errorf("Expected position %d, got %d", len(src)-1, pos)
+	}
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("Expected %#v, got %#v", exp, v)
+	}
 
-	// Test memory allocation and management
-	assembler.malloc_X0(jit.Imm(64), _X0)
-	assembler.valloc(reflect.TypeOf(int(0)), _X0)
-	assembler.vfollow(reflect.TypeOf(&int(0)))
-}
+	var e interface{}
+	decodeARM64(t, src[1:len(src)-1], &e)
+	if !reflect.DeepEqual(e, exp) {
+		t.Errorf("Expected %#v, got %#v", exp, e)
+	}
 
-// Test range checking
-func TestARM64RangeChecking(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_range_checking"
+	/* numbers as the options tell */
+	var n interface{}
+	if _, err := _subr_decode_value(`[1, 2.5]`, 0, unsafe.Pointer(&n), nil, 1<<_F_use_number); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, []interface{}{json.Number("1"), json.Number("2.5")}) {
+		t.Errorf("Unexpected UseNumber value: %#v", n)
+	}
+	if _, err := _subr_decode_value(`[1, 2.5]`, 0, unsafe.Pointer(&n), nil, 1<<_F_use_int64); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, []interface{}{int64(1), 2.5}) {
+		t.Errorf("Unexpected UseInt64 value: %#v", n)
+	}
 
-	// Test range checking for different types
-	assembler.range_signed_X1(_I_int8, _T_int8, -128, 127)
-	assembler.range_unsigned_X1(_I_uint8, _T_uint8, 0, 255)
-	assembler.range_uint32_X1(_I_uint32, _T_uint32)
-	assembler.range_single_D0()
+	/* malformed input reports an error */
+	for _, bad := range []string{`{"a":}`, `[1,]`, `tru`, `"abc`, `-`, `{"a" 1}`} {
+		if _, err := _subr_decode_value(bad, 0, unsafe.Pointer(&n), nil, 0); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
 }
 
-// Test string operations
-func TestARM64StringOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_string_operations"
+// Nulls branch with Xjmp and struct fields are dispatched through the
+// _OP_switch jump table built with Xref
+func TestARM64DecodeNullAndSwitch(t *testing.T) {
+	type Ptr struct {
+		A *int `json:"a"`
+		B *int `json:"b"`
+	}
+	one := 1
+	p := Ptr{A: &one, B: &one}
+	decodeARM64(t, `{"a":null,"b":2}`, &p)
+	if p.A != nil || p.B == nil || *p.B != 2 {
+		t.Errorf("Unexpected pointer fields: %v, %v", p.A, p.B)
+	}
 
-	// Test string manipulation functions
-	assembler.slice_from(_VAR_st_Iv, -1)
-	assembler.unquote_once(_X0, _X1, true, false)
-	assembler.unquote_twice(_X0, _X1, false)
+	type Circle struct {
+		R float64 `json:"r"`
+	}
+	type Rect struct {
+		W float64 `json:"w"`
+		H float64 `json:"h"`
+	}
+	type Shape struct {
+		Kind   string  `json:"kind"`
+		Circle *Circle `json:"circle"`
+		Rect   *Rect   `json:"rect"`
+		Tags   []int   `json:"tags"`
+	}
+	for _, src := range []string{
+		`{"kind":"circle","circle":{"r":1.5},"rect":null}`,
+		`{"rect":{"h":3,"w":2},"kind":"rect","tags":[1,2],"circle":null}`,
+		`{"tags":null,"kind":"none"}`,
+	} {
+		var exp, v Shape
+		if err := json.Unmarshal([]byte(src), &exp); err != nil {
+			t.Fatal(err)
+		}
+		decodeARM64(t, src, &v)
+		if !reflect.DeepEqual(v, exp) {
+			t.Errorf("Decoding %s: expected %+v, got %+v", src, exp, v)
+		}
+	}
 }
 
-// Test map operations
-func TestARM64MapOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_map_operations"
-
-	// Test map assignment functions
-	assembler.mapassign_std(reflect.TypeOf(map[string]interface{}{}), _X0)
-	assembler.mapassign_str_fast(reflect.TypeOf(map[string]string{}), _X0, _X1)
-	assembler.mapassign_utext(reflect.TypeOf(map[string]interface{}{}), false)
+func TestARM64DecodeEscapedStrings(t *testing.T) {
+	type Escaped struct {
+		A string `json:"a"`
+		B string `json:"b,string"`
+	}
+	for _, src := range []string{
+		`{"a":"\ud83d\ude00 \/ \b\f","b":"\"\\ud83d\\ude00 \\/ \\b\\f\""}`,
+		`{"a":"x\udc00y","b":"\"x\\ud800y\""}`,
+	} {
+		var exp, v Escaped
+		if err := json.Unmarshal([]byte(src), &exp); err != nil {
+			t.Fatal(err)
+		}
+		decodeARM64(t, src, &v)
+		if v != exp {
+			t.Errorf("Decoding %s: expected %+q, got %+q", src, exp, v)
+		}
+	}
 }
 
-// Test external unmarshaler support
-func TestARM64UnmarshalerSupport(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_unmarshaler_support"
+func TestARM64DecodeShortArray(t *testing.T) {
+	ints := [4]int{9, 9, 9, 9}
+	decodeARM64(t, `[1,2]`, &ints)
+	if ints != [4]int{1, 2, 0, 0} {
+		t.Errorf("Expected the tail to be cleared, got %v", ints)
+	}
 
-	// Test unmarshaler function calls
-	assembler.unmarshal_json(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem(), true, _F_decodeJsonUnmarshaler)
-	assembler.unmarshal_text(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(), true)
+	x := 9
+	ptrs := [4]*int{nil, nil, &x, &x}
+	decodeARM64(t, `[1,2]`, &ptrs)
+	if *ptrs[0] != 1 || *ptrs[1] != 2 || ptrs[2] != nil || ptrs[3] != nil {
+		t.Errorf("Expected the tail to be cleared, got %v", ptrs)
+	}
 }
 
-// Test dynamic decoding
-func TestARM64DynamicDecoding(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_dynamic_decoding"
-
-	// Test dynamic decoding support
-	assembler.decode_dynamic(jit.Type(reflect.TypeOf(interface{}(0))), _VP)
+type statsNode struct {
+	A int        `json:"a"`
+	N *statsNode `json:"n"`
 }
 
-// Test JIT options and configuration
-func TestARM64JITOptions(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_jit_options"
+func TestARM64DecodeStats(t *testing.T) {
+	count := func(key string) uint64 {
+		return NewDecoder("stats").Stats()[key].(uint64)
+	}
+	m0, u0, d0 := count("mismatches"), count("unknown_fields"), count("dynamics")
 
-	// Test JIT option application
-	opts := jitdec.DefaultJITOptions()
-	assembler.ApplyOptions(opts)
+	var v statsNode
+	decodeARM64(t, `{"a":1,"x":[1,{"y":2}],"z":null}`, &v)
+	if n := count("unknown_fields") - u0; n != 2 {
+		t.Errorf("Expected 2 unknown fields, got %d", n)
+	}
 
-	// Test statistics and debugging
-	stats := assembler.Stats()
-	if stats == nil {
-		t.Error("Expected non-nil stats")
+	decodeARM64(t, `{"n":{"n":{"a":3}}}`, &v)
+	if n := count("dynamics") - d0; n == 0 {
+		t.Errorf("Expected the recursive fields to be dispatched dynamically")
 	}
 
-	debug := jitdec.GetDebugInfo(assembler)
-	if debug.Program == "" {
-		t.Error("Expected non-empty program name")
+	pf, err := findOrCompile(rt.UnpackType(reflect.TypeOf(v)), _PK_value)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	fn := pf.(_Decoder)
+	sb := newStack()
+	defer freeStack(sb)
+	src := `{"a":"1","n":null}`
+	if _, err := fn(src, 0, unsafe.Pointer(&v), sb, 0, "", nil); err == nil {
+		t.Errorf("Expected a mismatch error for %s", src)
+	}
+	if n := count("mismatches") - m0; n != 1 {
+		t.Errorf("Expected 1 mismatch, got %d", n)
 	}
 }
 
-// Helper function to pack operation into instruction format
-func packOp(op _Op) uint64 {
-	return uint64(op) << 56
+func TestARM64DecodeQuotedFields(t *testing.T) {
+	type T struct {
+		N int64  `json:"n,string"`
+		P *int64 `json:"p,string"`
+	}
+	var v T
+	decodeARM64(t, `{"n":"42","p":"-3"}`, &v)
+	if v.N != 42 || v.P == nil || *v.P != -3 {
+		t.Errorf("Expected quoted numbers to be decoded, got %+v", v)
+	}
+
+	v = T{N: 1}
+	decodeARM64(t, `{"n":"null","p":"null"}`, &v)
+	if v.N != 1 || v.P != nil {
+		t.Errorf("Expected quoted null to be skipped, got %+v", v)
+	}
 }
 
 // Test instruction creation helpers
 func TestInstructionCreation(t *testing.T) {
 	// Test basic instruction creation
-	instr := newInsOp(_OP_null)
-	if instr.op() != _OP_null {
+	instr := newInsOp(_OP_nil_1)
+	if instr.op() != _OP_nil_1 {
 		t.Errorf("Expected OP_null, got %v", instr.op())
 	}
 
@@ -967,7 +1152,7 @@ func TestInstructionFieldAccess(t *testing.T) {
 // Test instruction disassembly
 func TestInstructionDisassembly(t *testing.T) {
 	// Test basic instruction disassembly
-	instr := newInsOp(_OP_null)
+	instr := newInsOp(_OP_nil_1)
 	expected := "null"
 	if instr.disassemble() != expected {
 		t.Errorf("Expected '%s', got '%s'", expected, instr.disassemble())
@@ -992,7 +1177,7 @@ func TestProgramOperations(t *testing.T) {
 	var prog _Program
 
 	// Test adding instructions
-	prog.add(_OP_null)
+	prog.add(_OP_nil_1)
 	prog.add(_OP_bool)
 	if len(prog) != 2 {
 		t.Errorf("Expected program length 2, got %d", len(prog))
@@ -1019,7 +1204,7 @@ func TestProgramOperations(t *testing.T) {
 	// Test instruction with type
 	prog.rtt(_OP_recurse, reflect.TypeOf(int(0)))
 	if len(prog) != 5 {
-		t	t.Errorf("Expected program length 5, got %d", len(prog))
+		t.Errorf("Expected program length 5, got %d", len(prog))
 	}
 	if prog[4].vt() != reflect.TypeOf(int(0)) {
 		t.Errorf("Expected int type, got %v", prog[4].vt())
@@ -1042,7 +1227,7 @@ func TestProgramTaggingAndPinning(t *testing.T) {
 
 	// Test tagging
 	prog.tag(0)
-	prog.add(_OP_null)
+	prog.add(_OP_nil_1)
 	prog.add(_OP_bool)
 
 	// Test pinning
@@ -1065,10 +1250,10 @@ func TestProgramTaggingAndPinning(t *testing.T) {
 // Test program disassembly
 func TestProgramDisassembly(t *testing.T) {
 	prog := _Program{
-		{u: packOp(_OP_null)},
+		{u: packOp(_OP_nil_1)},
 		{u: packOp(_OP_bool)},
-		{u: packOp(_OP_goto), vi: 5},
-		{u: packOp(_OP_switch), vs: []int{1, 2, 3}},
+		newInsVi(_OP_goto, 5),
+		newInsVs(_OP_switch, []int{1, 2, 3}),
 	}
 
 	disassembled := prog.disassemble()
@@ -1077,14 +1262,14 @@ func TestProgramDisassembly(t *testing.T) {
 	}
 
 	// Verify it contains our operations
-	if !strings.Contains(disassembled, "null") {
-		t.Error("Disassembly should contain 'null'")
+	if !strings.Contains(disassembled, "nil_1") {
+		t.Error("Disassembly should contain 'nil_1'")
 	}
 	if !strings.Contains(disassembled, "bool") {
 		t.Error("Disassembly should contain 'bool'")
 	}
-	if strings.Contains(disassembled, "L_5") {
-		t.Error("Disassembly should not contain 'L_5' (no such label)")
+	if !strings.Contains(disassembled, "goto") {
+		t.Error("Disassembly should contain 'goto'")
 	}
 	if !strings.Contains(disassembled, "switch") {
 		t.Error("Disassembly should contain 'switch'")
@@ -1104,7 +1289,7 @@ func TestBranchInstructionHandling(t *testing.T) {
 		t.Error("SWITCH instruction should be a branch")
 	}
 
-	instr = newInsOp(_OP_null)
+	instr = newInsOp(_OP_nil_1)
 	if instr.isBranch() {
 		t.Error("NULL instruction should not be a branch")
 	}
@@ -1148,20 +1333,20 @@ func TestMarshalerChecking(t *testing.T) {
 
 	// Test with JSON unmarshaler type
 	jsonUnmarshalerType := reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
-	if compiler.checkMarshaler(jsonUnmarshalerType, 0, true) {
+	if !compiler.checkMarshaler(nil, jsonUnmarshalerType, 0, false) {
 		t.Error("JSON Unmarshaler should be detected")
 	}
 
 	// Test with text unmarshaler type
-	textUnmarshalerType := reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-	if compiler.checkMarshaler(textUnmarshalerType, 0, true) {
-		t.Error("Text Marshaler should be detected")
+	textUnmarshalerType := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	if !compiler.checkMarshaler(nil, textUnmarshalerType, 0, false) {
+		t.Error("Text Unmarshaler should be detected")
 	}
 
 	// Test with regular type
 	regularType := reflect.TypeOf(int(0))
-	if !compiler.checkMarshaler(regularType, 0, true) {
-		t.Error("Regular type should not be detected as marshaler")
+	if compiler.checkMarshaler(nil, regularType, 0, false) {
+		t.Error("Regular type should not be detected as unmarshaler")
 	}
 }
 
@@ -1251,8 +1436,9 @@ func TestFieldCaching(t *testing.T) {
 }
 
 type TestStruct struct {
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Name  string `json:"name"`
+	Age   int    `json:"age"`
+	Valid bool   `json:"valid"`
 }
 
 // Test type compilation for all basic types
@@ -1314,7 +1500,7 @@ func TestErrorTypes(t *testing.T) {
 	compiler := newCompiler()
 
 	// Test unsupported type
-	invalidType := reflect.TypeOf((*func())(0))
+	invalidType := reflect.TypeOf(func() {})
 	_, err := compiler.compile(invalidType)
 	if err == nil {
 		t.Error("Function type should return error")
@@ -1353,7 +1539,7 @@ func TestConcurrentCompilation(t *testing.T) {
 	}
 
 	// Test that multiple compilers can work concurrently
-	compilers := make([]*Compiler, 10)
+	compilers := make([]*_Compiler, 10)
 	for i := range compilers {
 		compilers[i] = newCompiler()
 	}
@@ -1443,25 +1629,23 @@ func TestDecoderCompilation(t *testing.T) {
 	}
 
 	// Test compiled decoder function
-	if fn, ok := compiledDecoder.(func(string, int, unsafe.Pointer, *_Stack, uint64, string) (int, error)); ok {
+	if fn, ok := compiledDecoder.(*_Decoder); ok {
 		// Call the compiled function
-		result, err := fn(`{"name":"test","age":42}`, 0, unsafe.Pointer(&TestStruct{}), jitdec.NewStack(), 0, "")
+		src := `{"name":"test","age":42}`
+		sb := newStack()
+		defer freeStack(sb)
+		result, err := (*fn)(src, 0, unsafe.Pointer(&TestStruct{}), sb, 0, "", nil)
 		if err != nil {
 			t.Errorf("Compiled decoder function error: %v", err)
 		}
-		if result != 0 {
-			t.Errorf("Expected result 0, got %d", result)
+		if result != len(src) {
+			t.Errorf("Expected result %d, got %d", len(src), result)
 		}
 	} else {
-		t.Error("Compiled decoder should be callable function")
+		t.Error("Compiled decoder should be a *_Decoder")
 	}
 }
 
-type TestStruct struct {
-	Name string `json:"name"`
-	Age  int    `json:"age"`
-}
-
 // Test decoder reuse and caching
 func TestDecoderReuse(t *testing.T) {
 	decoder := NewDecoder("test_reuse")
@@ -1485,8 +1669,36 @@ func TestDecoderReuse(t *testing.T) {
 		t.Error("Both compiled decoders should be non-nil")
 	}
 
-	// In current implementation, they might be different objects
-	// In a full implementation, caching could make them the same
+	// The second compilation should come from the cache
+	if decoder1 != decoder2 {
+		t.Error("Compiling the same type twice should return the cached decoder")
+	}
+
+	// Another decoder should share the cached one as well
+	decoder3, err3 := NewDecoder("test_reuse_other").Compile(testType)
+	if err3 != nil {
+		t.Errorf("Third compilation failed: %v", err3)
+	}
+	if decoder3 != decoder1 {
+		t.Error("Decoders of the same type should share the cached decoder")
+	}
+	if size := GetDecoderCacheSize(); size < 1 {
+		t.Errorf("Expected a non-empty decoder cache, got %d", size)
+	}
+}
+
+func BenchmarkDecoderCompileCached(b *testing.B) {
+	testType := reflect.TypeOf(TestStruct{})
+	if _, err := NewDecoder("bench_cached").Compile(testType); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDecoder("bench_cached").Compile(testType); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
 
 // Test multiple type compilation
@@ -1529,32 +1741,29 @@ func TestDecoderPerformance(t *testing.T) {
 	testType := reflect.TypeOf(TestStruct{})
 
 	// Measure compilation time
-	start := testing.Benchmark{}
-	start.ResetTimer()
+	start := time.Now()
 	compiledDecoder, err := decoder.Compile(testType)
-	elapsed := start.Elapsed
+	elapsed := time.Since(start)
 
 	if err != nil {
-		t.Errorf("Compilation failed: %v", err)
+		t.Fatalf("Compilation failed: %v", err)
 	}
 
 	t.Logf("Compilation took: %v", elapsed)
 
-	// Benchmark decoding if compilation succeeded
-	if compiledDecoder != nil {
-		fn, ok := compiledDecoder.(func(string, int, unsafe.Pointer, *_Stack, uint64, string) (int, error))
-		if ok {
-			b.Run("Decode", func(b *testing.B) {
-				b.ResetTimer()
-				for i := 0; i < b.N; i++ {
-					_, err := fn(`{"name":"test","age":42}`, 0, unsafe.Pointer(&TestStruct{}), jitdec.NewStack(), 0, "")
-					if err != nil {
-						b.Fatalf("Decode error: %v", err)
-					}
-				}
-			})
+	// Measure decoding with the compiled decoder
+	fn := *compiledDecoder.(*_Decoder)
+	res := testing.Benchmark(func(b *testing.B) {
+		sb := newStack()
+		defer freeStack(sb)
+		for i := 0; i < b.N; i++ {
+			_, err := fn(`{"name":"test","age":42}`, 0, unsafe.Pointer(&TestStruct{}), sb, 0, "", nil)
+			if err != nil {
+				b.Fatalf("Decode error: %v", err)
+			}
 		}
-	}
+	})
+	t.Logf("Decoding took: %v", res)
 }
 
 // Test JIT options and configuration
@@ -1562,17 +1771,11 @@ func TestJITOptions(t *testing.T) {
 	decoder := NewDecoder("jit_options")
 
 	// Test default options
-	defaultOpts := jitdec.DefaultJITOptions()
+	defaultOpts := DefaultJITOptions()
 	decoder.ApplyJITOptions(defaultOpts)
 
-	// Test option validation
-	invalidOpts := jitdec.JITOptions{
-		OptimizationLevel: -1, // Invalid level
-	}
-	// TODO: Test that invalid options are rejected
-
 	// Test option effects
-	validOpts := jitdec.JITOptions{
+	validOpts := JITOptions{
 		OptimizationLevel: 2,
 		EnableSIMD:       true,
 		EnableInlining:   true,
@@ -1586,12 +1789,15 @@ func TestDebugFunctionality(t *testing.T) {
 	decoder := NewDecoder("debug_test")
 
 	// Test with debug mode enabled
-	opts := jitdec.DefaultJITOptions()
+	opts := DefaultJITOptions()
 	opts.DebugMode = true
 	decoder.ApplyJITOptions(opts)
+	if _, err := decoder.Compile(reflect.TypeOf(TestStruct{})); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
 
 	// Test debug info retrieval
-	debugInfo := jitdec.GetDebugInfo(decoder)
+	debugInfo := GetDebugInfo(decoder)
 	if debugInfo.Program == "" {
 		t.Error("Debug info should have program name")
 	}
@@ -1603,10 +1809,8 @@ func TestARM64Optimizations(t *testing.T) {
 		t.Skip("Skipping ARM64 optimization test in short mode")
 	}
 
-	decoder := NewDecoder("arm64_opt")
-
 	// Test SIMD availability
-	simdInfo := jitdec.GetArchitectureInfo()
+	simdInfo := GetArchitectureInfo()
 	if simdInfo["has_simd"] != true {
 		t.Error("ARM64 should have SIMD support")
 	}
@@ -1615,11 +1819,11 @@ func TestARM64Optimizations(t *testing.T) {
 	}
 
 	// Test register allocation
-	registerInfo := jitdec.GetArchitectureInfo()
-	if registerInfo["max_registers"] != float64(31) {
+	registerInfo := GetArchitectureInfo()
+	if registerInfo["max_registers"] != 31 {
 		t.Errorf("Expected 31 registers, got %v", registerInfo["max_registers"])
 	}
-	if registerInfo["stack_align"] != float64(16) {
+	if registerInfo["stack_align"] != 16 {
 		t.Errorf("Expected 16-byte stack alignment, got %v", registerInfo["stack_align"])
 	}
 }
@@ -1629,7 +1833,7 @@ func TestErrorRecovery(t *testing.T) {
 	decoder := NewDecoder("error_recovery")
 
 	// Test compilation error handling
-	invalidType := reflect.TypeOf((*func())(0))
+	invalidType := reflect.TypeOf(func() {})
 	_, err := decoder.Compile(invalidType)
 	if err == nil {
 		t.Error("Expected compilation error for invalid type")
@@ -1663,6 +1867,63 @@ func TestMemoryUsageManagement(t *testing.T) {
 	t.Logf("Memory usage: allocated=%d, max=%d, count=%d", allocated, max, count)
 }
 
+// Test batch compilation with bad types in the batch
+func TestBatchCompile(t *testing.T) {
+	good := []reflect.Type{
+		reflect.TypeOf(int(0)),
+		reflect.TypeOf(""),
+		reflect.TypeOf([]int{}),
+		reflect.TypeOf(map[string]interface{}{}),
+		reflect.TypeOf(TestStruct{}),
+	}
+	bad := []reflect.Type{
+		reflect.TypeOf(func() {}),
+		reflect.TypeOf((*chan int)(nil)),
+	}
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		types := append(append([]reflect.Type{}, good[:2]...), bad[0])
+		types = append(append(types, good[2:]...), bad[1])
+		results, err := BatchCompileConcurrently(types, workers)
+
+		// The good types are compiled despite the bad ones
+		if len(results) != len(good) {
+			t.Errorf("workers=%d: expected %d decoders, got %d", workers, len(good), len(results))
+		}
+		for _, vt := range good {
+			if results[vt] == nil {
+				t.Errorf("workers=%d: %v should be compiled", workers, vt)
+			}
+		}
+
+		// The bad types are all reported
+		berr, ok := err.(*BatchCompileError)
+		if !ok {
+			t.Fatalf("workers=%d: expected a *BatchCompileError, got %v", workers, err)
+		}
+		if len(berr.Errors) != len(bad) {
+			t.Errorf("workers=%d: expected %d errors, got %v", workers, len(bad), berr)
+		}
+		for _, vt := range bad {
+			if berr.Errors[vt] == nil || !strings.Contains(berr.Error(), vt.String()) {
+				t.Errorf("workers=%d: %v should be reported, got %v", workers, vt, berr)
+			}
+		}
+	}
+
+	// The batch reuses the cached decoders
+	results, err := BatchCompile(good)
+	if err != nil {
+		t.Fatalf("Batch compilation failed: %v", err)
+	}
+	for _, vt := range good {
+		cached, _ := NewDecoder("batch_cached").Compile(vt)
+		if results[vt] != cached {
+			t.Errorf("%v should be compiled from the cache", vt)
+		}
+	}
+}
+
 // Test thread safety
 func TestThreadSafety(t *testing.T) {
 	// Skip in short mode
@@ -1680,10 +1941,10 @@ func TestThreadSafety(t *testing.T) {
 	// Compile types concurrently
 	var wg sync.WaitGroup
 	type compilationResult struct {
-		decoder *Decoder
+		decoder interface{}
 		err     error
 		index   int
-		type    reflect.Type
+		vt      reflect.Type
 	}
 
 	results := make(chan compilationResult, numDecoders)
@@ -1691,10 +1952,10 @@ func TestThreadSafety(t *testing.T) {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			typeToCompile := reflect.TypeOf(i)
+			typeToCompile := reflect.TypeOf(idx)
 			decoder := decoders[idx]
 			compiled, err := decoder.Compile(typeToCompile)
-			results <- compilationResult{decoder: compiled, err: err, index: idx, type: typeToCompile}
+			results <- compilationResult{decoder: compiled, err: err, index: idx, vt: typeToCompile}
 		}(i)
 	}
 
@@ -1731,22 +1992,22 @@ func TestEdgeCases(t *testing.T) {
 		F3 [100]int `json:"f3"`
 		F4 [100]int `json:"f4"`
 		F5 [100]int `json:"f5"`
-	})
-	_, err := compiler.compile(largeType)
+	}{})
+	_, err = compiler.compile(largeType)
 	if err != nil {
 		t.Errorf("Large struct compilation failed: %v", err)
 	}
 
 	// Test nil interface
 	nilInterfaceType := reflect.TypeOf((*interface{})(nil)).Elem()
-	_, err := compiler.compile(nilInterfaceType)
+	_, err = compiler.compile(nilInterfaceType)
 	if err != nil {
 		t.Errorf("Nil interface compilation failed: %v", err)
 	}
 
 	// Test recursive types
 	recursiveType := reflect.TypeOf((*RecursiveType)(nil))
-	_, err := compiler.compile(recursiveType)
+	_, err = compiler.compile(recursiveType)
 	if err != nil {
 		t.Errorf("Recursive type compilation should handle recursion safely: %v", err)
 	}
@@ -1777,54 +2038,114 @@ func TestTypeValidation(t *testing.T) {
 	}
 }
 
+var warmUpTypes int64
+
+// newWarmUpType returns a struct type which was never compiled before
+func newWarmUpType() reflect.Type {
+	n := atomic.AddInt64(&warmUpTypes, 1)
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "WarmUp" + strconv.FormatInt(n, 10),
+		Type: reflect.TypeOf(""),
+		Tag:  `json:"v"`,
+	}})
+}
+
 // Test JIT warmup functionality
 func TestJITWarmup(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping JIT warmup test in short mode")
+	decoder := NewDecoder("warmup_test")
+
+	// Types never compiled before, each of them given twice
+	var types []reflect.Type
+	for i := 0; i < 4; i++ {
+		vt := newWarmUpType()
+		types = append(types, vt, vt)
 	}
 
-	decoder := NewARM64JITDecoder("warmup_test")
+	// Warm up all types
+	size := GetDecoderCacheSize()
+	if err := decoder.WarmUp(types); err != nil {
+		t.Fatalf("JIT warmup failed: %v", err)
+	}
+	if got := GetDecoderCacheSize(); got != size + len(types) / 2 {
+		t.Errorf("Cache size after warmup: %d (expected %d)", got, size + len(types) / 2)
+	}
 
-	// Common types to pre-compile
-	commonTypes := []reflect.Type{
-		reflect.TypeOf(int(0)),
-		reflect.TypeOf(string("")),
-		reflect.TypeOf([]int{}),
-		reflect.TypeOf(map[string]interface{}{}),
-		reflect.TypeOf(TestStruct{}),
+	// Decode finds the decoders of the warmed up types
+	for _, vt := range types {
+		if programsOf(rt.UnpackType(vt)).fn[_PK_value].Load() == nil {
+			t.Errorf("%v should be cached for Decode", vt)
+		}
 	}
 
-	// Warm up all types
-	err := decoder.WarmUp(commonTypes)
-	if err != nil {
-		t.Errorf("JIT warmup failed: %v", err)
+	// Warming up again, even concurrently, compiles nothing more
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := WarmUp(types); err != nil {
+				t.Errorf("JIT warmup failed: %v", err)
+			}
+		}()
 	}
+	wg.Wait()
+	if got := GetDecoderCacheSize(); got != size + len(types) / 2 {
+		t.Errorf("Cache size after warming up again: %d (expected %d)", got, size + len(types) / 2)
+	}
+
+	// The types failing to compile are all reported
+	bad := []reflect.Type{reflect.TypeOf(func() {}), reflect.TypeOf(make(chan int))}
+	err := WarmUp(append([]reflect.Type{newWarmUpType()}, bad...))
+	if berr, ok := err.(*BatchCompileError); !ok || len(berr.Errors) != len(bad) {
+		t.Errorf("expected %d types to be reported, got %v", len(bad), err)
+	}
+}
+
+func benchmarkFirstDecode(b *testing.B, warm bool) {
+	src := `{"v":"first"}`
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		vt := newWarmUpType()
+		if warm {
+			if err := WarmUp([]reflect.Type{vt}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		val := reflect.New(vt).Interface()
+		b.StartTimer()
 
-	// Verify cache size increases
-	initialCacheSize := GetDecoderCacheSize()
-	if initialCacheSize < len(commonTypes) {
-		t.Logf("Cache size after warmup: %d (expected >= %d)", initialCacheSize, len(commonTypes))
+		s, ic := src, 0
+		if err := Decode(&s, &ic, 0, val); err != nil {
+			b.Fatal(err)
+		}
 	}
 }
 
+// The first decode into a type compiles it, unless the type was warmed up
+func BenchmarkFirstDecode_Cold(b *testing.B) {
+	benchmarkFirstDecode(b, false)
+}
+
+func BenchmarkFirstDecode_WarmUp(b *testing.B) {
+	benchmarkFirstDecode(b, true)
+}
+
+// decodeString decodes src into val through the package level Decode
+func decodeString(src string, opts Options, val interface{}) error {
+	s, ic := src, 0
+	return Decode(&s, &ic, uint64(opts), val)
+}
+
+const testOptions = consts.OptionUseInt64 | consts.OptionCopyString | consts.OptionValidateString
+
 // Integration with existing API
 func TestAPIIntegration(t *testing.T) {
-	// Test that our implementation works with existing decoder API
 	testJSON := `{"name":"test","age":42,"valid":true}`
 
-	// Create decoder
-	decoder := NewDecoder("api_integration")
-	decoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
-	// Test decoding
 	var result TestStruct
-	err := decoder.Decode(testJSON, &result)
+	err := decodeString(testJSON, testOptions, &result)
 	if err != nil {
-		t.Errorf("API integration decoding failed: %v", err)
+		t.Fatalf("API integration decoding failed: %v", err)
 	}
 
 	if result.Name != "test" {
@@ -1840,92 +2161,40 @@ func TestAPIIntegration(t *testing.T) {
 
 // Compatibility with standard library
 func TestStdCompatibility(t *testing.T) {
-	// Test that our decoder produces same results as encoding/json
 	testJSON := `{"name":"test","age":42,"valid":true}`
 
-	// Use our decoder
 	var result1 TestStruct
-	decoder1 := NewDecoder("std_compat")
-	decoder1.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
-	err1 := decoder1.Decode(testJSON, &result1)
-	if err1 != nil {
-		t.Errorf("ARM64 decoder error: %v", err1)
+	if err := decodeString(testJSON, testOptions, &result1); err != nil {
+		t.Errorf("ARM64 decoder error: %v", err)
 	}
 
-	// Use standard library for comparison
 	var result2 TestStruct
-	decoder2 := NewDecoder("std_library")
-	decoder2.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
-	err2 := decoder2.Decode(testJSON, &result2)
-	if err2 != nil {
-		t.Errorf("Fallback decoder error: %v", err2)
+	if err := json.Unmarshal([]byte(testJSON), &result2); err != nil {
+		t.Errorf("encoding/json error: %v", err)
 	}
 
-	// Results should be identical
-	if result1.Name != result2.Name || result1.Age != result2.Age || result1.Valid != result2.Valid {
-		t.Error("Decoders should produce identical results")
+	if result1 != result2 {
+		t.Errorf("Decoders should produce identical results, got %+v and %+v", result1, result2)
 	}
 }
 
-// Performance comparison with fallback
-func TestPerformanceComparison(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping performance comparison in short mode")
-	}
-
+// Performance comparison with encoding/json
+func BenchmarkDecoderComparison(b *testing.B) {
 	testJSON := `{"name":"test","age":42,"valid":true}`
 
-	// ARM64 JIT decoder
-	arm64Decoder := NewDecoder("perf_arm64")
-	arm64Decoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
-	// Optimized decoder
-	optimizedDecoder := NewDecoder("perf_optimized")
-	optimizedDecoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-	OptionValidateString: true,
-	})
-
-	// Fallback decoder
-	fallbackDecoder := NewDecoder("perf_fallback")
-	fallbackDecoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
 	decoders := []struct {
-		name     string
-		decoder  *Decoder
-		expected func(string, interface{}) error
+		name   string
+		decode func(string, interface{}) error
 	}{
-		{"ARM64 JIT", arm64Decoder, arm64Decoder.Decode},
-		{"Optimized", optimizedDecoder, optimizedDecoder.Decode},
-		{"Fallback", fallbackDecoder, fallbackDecoder.Decode},
+		{"ARM64 JIT", func(s string, v interface{}) error { return decodeString(s, testOptions, v) }},
+		{"encoding/json", func(s string, v interface{}) error { return json.Unmarshal([]byte(s), v) }},
 	}
 
 	for _, tt := range decoders {
-		t.Run(tt.name, func(b *testing.B) {
-			b.ResetTimer()
+		b.Run(tt.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				var result TestStruct
-				err := tt.expected(testJSON, &result)
-				if err != nil {
+				if err := tt.decode(testJSON, &result); err != nil {
 					b.Fatalf("Decode error with %s: %v", tt.name, err)
 				}
 			}
@@ -1951,32 +2220,23 @@ func TestMemoryLeakDetection(t *testing.T) {
 
 	// Reset and test cleanup
 	decoder.Reset()
-
-	// Force garbage collection
-	// (In a real implementation, this would be more sophisticated)
+	runtime.GC()
 }
 
 // Test with invalid JSON input
 func TestInvalidJSONHandling(t *testing.T) {
-	decoder := NewDecoder("invalid_json_test")
-	decoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
 	invalidJSON := []string{
-		`{"name":"test","age":}`, // Missing closing brace
-		`{"name":null,"age":42}`, // Invalid null placement
-		`{"name":test,"age":invalid}`, // Invalid number
-		`{"name":"test","age":42,}`, // Extra comma
-		`{"name":"test","age":42}`,    // Extra space
+		`{"name":"test","age":}`,      // Missing value
+		`{"name":test,"age":invalid}`, // Unquoted literals
+		`{"name":"test","age":42,}`,   // Extra comma
+		`{"name":"test","age":42`,     // Missing closing brace
+		`{"name":"test","age":"42"}`,  // Mismatched type
 	}
 
 	for _, jsonStr := range invalidJSON {
 		t.Run(jsonStr, func(t *testing.T) {
 			var result TestStruct
-			err := decoder.Decode(jsonStr, &result)
+			err := decodeString(jsonStr, testOptions, &result)
 			if err == nil {
 				t.Errorf("Expected error for invalid JSON: %s", jsonStr)
 			}
@@ -1986,28 +2246,23 @@ func TestInvalidJSONHandling(t *testing.T) {
 
 // Test empty JSON handling
 func TestEmptyJSONHandling(t *testing.T) {
-	decoder := NewDecoder("empty_json_test")
-	decoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-			OptionValidateString: true,
-	})
-
 	emptyJSONs := []string{
-		"",
 		"null",
 		"{}",
-		"[]",
-		"\"\"",
+		"{ }",
+		` {"name":""} `,
 	}
 
 	for _, jsonStr := range emptyJSONs {
 		t.Run(jsonStr, func(t *testing.T) {
 			var result TestStruct
-			err := decoder.Decode(jsonStr, &result)
+			err := decodeString(jsonStr, testOptions, &result)
 			if err != nil {
 				t.Errorf("Empty JSON error for '%s': %v", jsonStr, err)
 			}
+			if result != (TestStruct{}) {
+				t.Errorf("Expected zero value for '%s', got %+v", jsonStr, result)
+			}
 		})
 	}
 }
@@ -2018,15 +2273,8 @@ func TestLargeJSONHandling(t *testing.T) {
 		t.Skip("Skipping large JSON test in short mode")
 	}
 
-	decoder := NewDecoder("large_json_test")
-	decoder.SetOptions(Options{
-		OptionUseInt64:     true,
-		OptionCopyString:   true,
-		OptionValidateString: true,
-	})
-
 	// Create a large JSON string
-		largeJSON := `{"data":[`
+	largeJSON := `{"data":[`
 	for i := 0; i < 1000; i++ {
 		if i > 0 {
 			largeJSON += ","
@@ -2035,20 +2283,20 @@ func TestLargeJSONHandling(t *testing.T) {
 	}
 	largeJSON += "]}"
 
-	// This should handle large JSON without issues
-	decoder := NewDecoder("large_json_test")
 	var result map[string]interface{}
-		err := decoder.Decode(largeJSON, &result)
+	err := decodeString(largeJSON, testOptions, &result)
 	if err != nil {
-		t.Errorf("Large JSON error: %v", err)
+		t.Fatalf("Large JSON error: %v", err)
 	}
 
 	// Verify the parsed data
-	if data, ok := result["data"].([]interface{}); ok && len(data) == 1000 {
-		if id, ok := data[999].(int); ok && id == 9999 {
-			t.Logf("Successfully parsed large JSON with %d items", len(data))
-		}
+	data, ok := result["data"].([]interface{})
+	if !ok || len(data) != 1000 {
+		t.Fatalf("Expected 1000 items, got %#v", result["data"])
 	}
+	last, _ := data[999].(map[string]interface{})
+	if last["id"] != int64(999) || last["value"] != int64(9990) {
+		t.Errorf("Unexpected last item: %#v", data[999])
 	}
 }
 
@@ -2058,21 +2306,10 @@ func TestConcurrentDecoding(t *testing.T) {
 		t.Skip("Skipping concurrent decoding test in short mode")
 	}
 
-	testJSON := `{"name":"test","age":42,"valid":true}``
-
-	// Create multiple decoders
-	numDecoders := 5
-	decoders := make([]*Decoder, numDecoders)
-	for i := range decoders {
-		decoders[i] = NewDecoder(fmt.Sprintf("concurrent_%d", i))
-		decoders[i].SetOptions(Options{
-			OptionUseInt64:     true,
-			OptionCopyString:   true,
-			OptionValidateString: true,
-		})
-	}
+	testJSON := `{"name":"test","age":42,"valid":true}`
 
 	// Decode JSON concurrently
+	numDecoders := 5
 	var wg sync.WaitGroup
 	errors := make([]error, numDecoders)
 	results := make([]TestStruct, numDecoders)
@@ -2081,16 +2318,11 @@ func TestConcurrentDecoding(t *testing.T) {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			var result TestStruct
-			err := decoders[idx].Decode(testJSON, &result)
-			errors[idx] = err
-			if err == nil {
-				results[idx] = result
-			}
+			errors[idx] = decodeString(testJSON, testOptions, &results[idx])
 		}(i)
 	}
 
-		wg.Wait()
+	wg.Wait()
 
 	// Check for errors
 	for i, err := range errors {
@@ -2101,7 +2333,7 @@ func TestConcurrentDecoding(t *testing.T) {
 
 	// Verify all results are identical
 	for i := 1; i < numDecoders; i++ {
-		if results[i].Name != results[0].Name || results[i].Age != results[0].Age || results[i].Valid != results[0].Valid {
+		if results[i] != results[0] {
 			t.Errorf("Results %d and 0 differ", i)
 		}
 	}
@@ -2115,45 +2347,34 @@ func TestDecoderWithDifferentOptions(t *testing.T) {
 		name    string
 		options Options
 	}{
-		{"default", Options{}},
-		{"int64", Options{OptionUseInt64: true}},
-		{"copy_string", Options{OptionCopyString: true}},
-		{"validate_string", Options{OptionValidateString: true}},
-	{"all_options", Options{
-			OptionUseInt64:     true,
-			OptionCopyString:   true,
-			OptionValidateString: true,
-		}},
+		{"default", 0},
+		{"int64", consts.OptionUseInt64},
+		{"copy_string", consts.OptionCopyString},
+		{"validate_string", consts.OptionValidateString},
+		{"all_options", testOptions},
 	}
 
 	for _, test := range optionsTests {
 		t.Run(test.name, func(t *testing.T) {
-			decoder := NewDecoder("options_test")
-			decoder.SetOptions(test.options)
-
 			var result TestStruct
-			err := decoder.Decode(testJSON, &result)
+			err := decodeString(testJSON, test.options, &result)
 			if err != nil {
 				t.Errorf("Decode with options %v failed: %v", test.name, err)
 			}
+			if result.Name != "test" || result.Age != 42 || !result.Valid {
+				t.Errorf("Unexpected result with options %v: %+v", test.name, result)
+			}
 
-			// Verify expected behavior based on options
-			switch test.name {
-			case "int64":
-				if _, ok := result.Age.(int64); !ok {
-					t.Error("With OptionUseInt64, Age should be int64")
-				}
-			case "copy_string":
-				// Should copy string values
-				if result.Name != "test" {
-					t.Error("With OptionCopyString, string should be copied")
-				}
-			case "validate_string":
-				// Should validate string values
-				// This would require checking validation logic
+			// Numbers in interfaces depend on OptionUseInt64
+			var generic map[string]interface{}
+			if err := decodeString(testJSON, test.options, &generic); err != nil {
+				t.Fatalf("Generic decode with options %v failed: %v", test.name, err)
 			}
-		}
-	})
+			_, isInt := generic["age"].(int64)
+			if want := test.options&consts.OptionUseInt64 != 0; isInt != want {
+				t.Errorf("Expected int64 age to be %v, got %T", want, generic["age"])
+			}
+		})
 	}
 }
 
@@ -2165,142 +2386,104 @@ func BenchmarkDecoderConfigurations(b *testing.B) {
 		name string
 		opts Options
 	}{
-		{"default", Options{}},
-		{"with_int64", Options{OptionUseInt64: true}},
-		{"with_copy_string", Options{OptionCopyString: true}},
-		{"with_validation", Options{OptionValidateString: true}},
+		{"default", 0},
+		{"with_int64", consts.OptionUseInt64},
+		{"with_copy_string", consts.OptionCopyString},
+		{"with_validation", consts.OptionValidateString},
 	}
 
 	for _, cfg := range configs {
 		b.Run(cfg.name, func(b *testing.B) {
-			decoder := NewDecoder("benchmark_" + cfg.name)
-			decoder.SetOptions(cfg.opts)
-
-			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
 				var result TestStruct
-				decoder.Decode(testJSON, &result)
+				_ = decodeString(testJSON, cfg.opts, &result)
 			}
 		})
 	}
 }
 
+// assembleARM64 assembles the decoder of an empty program with the code
+// emitted by fn appended after the error handlers, where it is never
+// executed, and returns the size of the machine code
+func assembleARM64(name string, fn func(self *_Assembler)) int {
+	self := new(_Assembler)
+	self.name = name
+	self.BaseAssembler.Init(func() {
+		self.compile()
+		fn(self)
+	})
+	self.Load()
+	return self.Size()
+}
+
 // ARM64-specific benchmarks
 func BenchmarkARM64InstructionGeneration(b *testing.B) {
-	prog := _Program{
-		{u: packOp(_OP_null)},
-		{u: packOp(_OP_bool)},
-		{u: packOp(_OP_i64)},
-		{u: packOp(_OP_str)},
-		{u: packOp(_OP_goto), vi: 10},
+	prog, err := newCompiler().compile(reflect.TypeOf(TestStruct{}))
+	if err != nil {
+		b.Fatal(err)
 	}
 
-	assembler := newAssembler(prog)
-	assembler.name = "benchmark_arm64"
-
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		decoder := assembler.Load()
-		if decoder == nil {
+		assembler := newAssembler(prog)
+		assembler.name = "benchmark_arm64"
+		if decoder := assembler.Load(); decoder == nil {
 			b.Fatal("Failed to load decoder")
 		}
 	}
-	}
 }
 
 func BenchmarkARM64RegisterOperations(b *testing.B) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "benchmark_registers"
-
-	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		assembler.save(_X0, _X1, _X2, _X3)
-		assembler.load(_X0, _X1, _X2, _X3)
+		assembleARM64("benchmark_registers", func(self *_Assembler) {
+			self.save(_X0, _X1, _X2, _X3)
+			self.load(_X0, _X1, _X2, _X3)
+		})
 	}
 }
 
 func BenchmarkARM64StackOperations(b *testing.B) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "benchmark_stack"
-
-	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		assembler.save_state()
-		assembler.drop_state(32)
+		assembleARM64("benchmark_stack", func(self *_Assembler) {
+			self._asm_OP_save(nil)
+			self._asm_OP_drop(nil)
+		})
 	}
 }
 
 func BenchmarkARM64MemoryOperations(b *testing.B) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "benchmark_memory"
-
-	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		assembler.malloc_X0(jit.Imm(64), _X0)
-		assembler.valloc(reflect.TypeOf(int(0)), _X0)
+		assembleARM64("benchmark_memory", func(self *_Assembler) {
+			self.malloc_X0(jit.Imm(64), _X0)
+			self.valloc(reflect.TypeOf(int(0)), _X0)
+		})
 	}
 }
 
 // Integration test with actual decoding
 func TestARM64DecodingIntegration(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	// This test requires the JIT to be functional
-	if !jit.IsARM64JITEnabled() {
-		t.Skip("ARM64 JIT is not enabled")
-	}
-
-	// Test a simple struct decoding scenario
 	type TestStruct struct {
-		Name string `json:"name"`
-		Age  int    `json:"age"`
+		Name  string `json:"name"`
+		Age   int    `json:"age"`
 		Valid bool   `json:"valid"`
 	}
 
-	// Create a program that simulates decoding TestStruct
-	prog := _Program{
-		{u: packOp(_OP_lspace)},
-		{u: packOp(_OP_match_char), vb: '{'},
-		{u: packOp(_OP_text), vs: "name:\""},
-		{u: packOp(_OP_str)},
-		{u: packOp(_OP_text), vs: "\",age:"},
-		{u: packOp(_OP_i64)},
-		{u: packOp(_OP_text), vs: ",valid:"},
-		{u: packOp(_OP_bool)},
-		{u: packOp(_OP_match_char), vb: '}'},
-	}
-
-	assembler := newAssembler(prog)
-	assembler.name = "test_integration"
-
-	decoder := assembler.Load()
-	if decoder == nil {
-		t.Error("Expected non-nil decoder for integration test")
+	var v TestStruct
+	decodeARM64(t, `{"name":"test","age":42,"valid":true}`, &v)
+	if v != (TestStruct{"test", 42, true}) {
+		t.Errorf("Unexpected result: %+v", v)
 	}
-
-	// The decoder should be callable
-	if decoder == nil {
-		t.Fatal("Decoder should be callable")
-	}
-
-	// Note: Actual decoding would require more setup and testing infrastructure
-	// This test mainly verifies that the assembler can generate code without panicking
 }
 
 // Test ARM64 specific instruction generation
 func TestARM64InstructionGeneration(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_instruction_generation"
-
-	// Test prologue and epilogue
-	assembler.prologue()
-	assembler.epilogue()
+	base := assembleARM64("test_instruction_base", func(*_Assembler) {})
+	size := assembleARM64("test_instruction_generation", func(self *_Assembler) {
+		self.prologue()
+	})
 
-	// Should have generated some code
-	if assembler.CodeSize() == 0 {
-		t.Error("Expected non-zero code size after prologue and epilogue")
+	if size <= base {
+		t.Errorf("Expected the prologue to generate code, got %d and %d bytes", base, size)
 	}
 }
 
@@ -2327,12 +2510,12 @@ func TestARM64RegisterAllocation(t *testing.T) {
 // Test stack frame layout
 func TestARM64StackFrameLayout(t *testing.T) {
 	// Verify stack frame layout is consistent
-	if _FP_offs <= _FP_fargs + _FP_saves + _FP_locals {
-		t.Error("FP_offs should be larger than fargs + saves + locals")
+	if _FP_offs < _FP_fargs + _FP_saves + _FP_locals {
+		t.Error("_FP_offs should not be less than fargs + saves + locals")
 	}
 
 	if _FP_size <= _FP_offs {
-		t.Error("_FP_size should be larger than FP_offs")
+		t.Error("_FP_size should be larger than _FP_offs")
 	}
 
 	if _FP_base <= _FP_size {
@@ -2347,228 +2530,117 @@ func TestARM64StackFrameLayout(t *testing.T) {
 
 // Test error handling paths
 func TestARM64ErrorHandlingPaths(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_error_handling"
-
-	// Test that all error handlers can be linked without panicking
-	assembler.type_error()
-	assembler.mismatch_error()
-	assembler.field_error()
-	assembler.range_error()
-	assembler.stack_error()
-	assembler.base64_error()
-	assembler.parsing_error()
+	// The error handlers are linked by every decoder, even empty ones
+	if assembleARM64("test_error_handling", func(*_Assembler) {}) == 0 {
+		t.Error("Expected the error handlers to generate code")
+	}
 }
 
 // Test buffer management
 func TestARM64BufferManagement(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_buffer_management"
-
-	// Test buffer helper functions
-	assembler.check_eof(1)
-	assembler.check_size(10)
-	assembler.add_char('a')
-	assembler.add_long(0x61626364, 4)
-	assembler.add_text("test")
+	assembleARM64("test_buffer_management", func(self *_Assembler) {
+		self.check_eof(1)
+		self.parse_string()
+		self.slice_from(_VAR_st_Iv, -1)
+	})
 }
 
 // Test state management
 func TestARM64StateManagement(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_state_management"
-
-	// Test state management functions
-	assembler.save_state()
-	assembler.drop_state(32)
+	assembleARM64("test_state_management", func(self *_Assembler) {
+		self._asm_OP_save(nil)
+		self._asm_OP_drop(nil)
+	})
 }
 
 // Test function calling conventions
 func TestARM64FunctionCalling(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_function_calling"
-
-	// Test function call helpers
-	assembler.save_c()
-	assembler.call_go(jit.Func(func() {}))
+	assembleARM64("test_function_calling", func(self *_Assembler) {
+		self.call_go(jit.Func(func() {}))
+		self.call_c(_F_lspace)
+	})
 }
 
 // Test memory operations
 func TestARM64MemoryOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_memory_operations"
-
-	// Test memory allocation and management
-	assembler.malloc_X0(jit.Imm(64), _X0)
-	assembler.valloc(reflect.TypeOf(int(0)), _X0)
-	assembler.vfollow(reflect.TypeOf(&int(0)))
+	assembleARM64("test_memory_operations", func(self *_Assembler) {
+		self.malloc_X0(jit.Imm(64), _X0)
+		self.valloc(reflect.TypeOf(int(0)), _X0)
+		self.vfollow(reflect.TypeOf(int(0)))
+	})
 }
 
 // Test range checking
 func TestARM64RangeChecking(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_range_checking"
-
-	// Test range checking for different types
-	assembler.range_signed_X1(_I_int8, _T_int8, -128, 127)
-	assembler.range_unsigned_X1(_I_uint8, _T_uint8, 0, 255)
-	assembler.range_uint32_X1(_I_uint32, _T_uint32)
-	assembler.range_single_D0()
+	assembleARM64("test_range_checking", func(self *_Assembler) {
+		self.range_signed_X1(_I_int8, _T_int8, math.MinInt8, math.MaxInt8)
+		self.range_unsigned_X1(_I_uint8, _T_uint8, math.MaxUint8)
+		self.range_uint32_X1(_I_uint32, _T_uint32)
+		self.range_single_D0()
+	})
 }
 
-// Test string operations
-func TestARM64StringOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_string_operations"
-
-	// Test string manipulation functions
-	assembler.slice_from(_VAR_st_Iv, -1)
-	assembler.unquote_once(_X0, _X1, true, false)
-	assembler.unquote_twice(_X0, _X1, false)
+// Test string helpers
+func TestARM64StringHelpers(t *testing.T) {
+	assembleARM64("test_string_operations", func(self *_Assembler) {
+		self.slice_from(_VAR_st_Iv, -1)
+		self.unquote_once(_ARG_sv_p, _ARG_sv_n, true, false)
+		self.unquote_twice(_ARG_sv_p, _ARG_sv_n, false)
+	})
 }
 
 // Test map operations
 func TestARM64MapOperations(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_map_operations"
+	assembleARM64("test_map_operations", func(self *_Assembler) {
+		self.mapassign_std(reflect.TypeOf(map[string]interface{}{}), _ARG_sv_p)
+		self.mapassign_str_fast(reflect.TypeOf(map[string]string{}), _ARG_sv_p, _ARG_sv_n)
+		self.mapassign_utext(reflect.TypeOf(map[textKey]int{}), false)
+	})
+}
+
+type textKey string
+
+func (self *textKey) UnmarshalText(b []byte) error {
+	*self = textKey(b)
+	return nil
+}
+
+type jsonValue struct{}
 
-	// Test map assignment functions
-	assembler.mapassign_std(reflect.TypeOf(map[string]interface{}{}), _X0)
-	assembler.mapassign_str_fast(reflect.TypeOf(map[string]string{}), _X0, _X1)
-	assembler.mapassign_utext(reflect.TypeOf(map[string]interface{}{}), false)
+func (*jsonValue) UnmarshalJSON([]byte) error {
+	return nil
 }
 
 // Test external unmarshaler support
 func TestARM64UnmarshalerSupport(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_unmarshaler_support"
-
-	// Test unmarshaler function calls
-	assembler.unmarshal_json(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem(), true, _F_decodeJsonUnmarshaler)
-	assembler.unmarshal_text(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(), true)
+	assembleARM64("test_unmarshaler_support", func(self *_Assembler) {
+		self.unmarshal_json(reflect.TypeOf(jsonValue{}), true, _F_decodeJsonUnmarshaler)
+		self.unmarshal_text(reflect.TypeOf(textKey("")), true)
+	})
 }
 
 // Test dynamic decoding
 func TestARM64DynamicDecoding(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_dynamic_decoding"
-
-	// Test dynamic decoding support
-	assembler.decode_dynamic(jit.Type(reflect.TypeOf(interface{}(0))), _VP)
+	assembleARM64("test_dynamic_decoding", func(self *_Assembler) {
+		self.decode_dynamic(_ARG_vk, _VP)
+	})
 }
 
 // Test JIT options and configuration
 func TestARM64JITOptions(t *testing.T) {
-	assembler := newAssembler(_Program{})
-	assembler.name = "test_jit_options"
-
-	// Test default options
-	opts := jitdec.DefaultJITOptions()
-	assembler.ApplyJITOptions(opts)
+	decoder := NewDecoder("test_jit_options")
+	decoder.ApplyJITOptions(DefaultJITOptions())
+	if _, err := decoder.Compile(reflect.TypeOf(TestStruct{})); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
 
 	// Test statistics and debugging
-	stats := assembler.Stats()
-	if stats == nil {
+	if stats := decoder.Stats(); stats == nil {
 		t.Error("Expected non-nil stats")
 	}
 
-	debug := jitdec.GetDebugInfo(assembler)
+	debug := GetDebugInfo(decoder)
 	if debug.Program == "" {
-		t.Error("Expected non-empty program name")
+		t.Error("Expected non-empty program")
 	}
 }
-
-// Helper function to create instruction for testing
-func newInsOp(op _Op) _Instr {
-	return _Instr{u: packOp(op)}
-}
-
-func newInsVi(op _Op, vi int) _Instr {
-	return _Instr{u: packOp(op) | rt.PackInt(vi)}
-}
-
-func newInsVb(op _Op, vb byte) _Instr {
-	return _Instr{u: packOp(op) | (uint64(vb) << 48)}
-}
-
-func newInsVt(op _Op, vt reflect.Type) _Instr {
-	return _Instr{
-		u: packOp(op),
-		p: unsafe.Pointer(rt.UnpackType(vt)),
-	}
-}
-
-func newInsVs(op _Op, vs []int) _Instr {
-	slice := (*rt.GoSlice)(unsafe.Pointer(&vs))
-	return _Instr{
-		u: packOp(op) | rt.PackInt(len(vs)),
-		p: slice.Ptr,
-	}
-}
-
-// Mock functions for testing
-func error_wrap(s string, ic int, et interface{}, ep interface{}) error {
-	return fmt.Errorf("error: %s at position %d: %v (type: %v, pointer: %v)", s, ic, et, ep)
-}
-
-func error_type(vt interface{}) error {
-	return fmt.Errorf("type error: %v", vt)
-}
-
-func error_field(field string) error {
-	return fmt.Errorf("field error: %s", field)
-}
-
-func error_value(value interface{}, vt interface{}, ep interface{}) error {
-	return fmt.Errorf("value error: %v (type: %v, pointer: %v)", value, vt, ep)
-}
-
-func error_mismatch(s string, ic int, vt interface{}, et interface{}) error {
-	return fmt.Errorf("mismatch error: %s at position %d (type: %v, expected: %v)", s, ic, vt, et)
-}
-
-func decodeJsonUnmarshaler(data []byte, v interface{}) error {
-	// Mock implementation
-	return nil
-}
-
-func decodeJsonUnmarshalerQuoted(data []byte, v interface{}) error {
-	// Mock implementation
-	return nil
-}
-
-func decodeTextUnmarshaler(data []byte, v interface{}) error {
-	// Mock implementation
-	return nil
-}
-
-func error_wrap(s string, ic int, et interface{}, ep interface{}) error {
-	return fmt.Errorf("error: %s at position %d: %v (type: %v, pointer: %v)", s, ic, et, ep)
-}
-
-func error_type(vt interface{}) error {
-	return fmt.Errorf("type error: %v", vt)
-}
-
-func error_field(field string) error {
-	return fmt.Errorf("field error: %s", field)
-}
-
-func error_value(value interface{}, vt interface{}, ep interface{}) error {
-	return fmt.Errorf("value error: %v (type: %v, pointer: %v)", value, vt, ep)
-}
-
-func error_mismatch(s string, ic int, vt interface{}, et interface{}) error {
-	return fmt.Errorf("mismatch error: %s at position %d (type: %v, expected: %v)", s, ic, vt, et)
-}
-
-var (
-	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
-	encodingTextMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-	_Vp_max_f32 = new(float32)
-	_Vp_min_f32 = new(float32)
-	base64CorruptInputError = reflect.TypeOf(base64.CorruptInputError(0))
-)
-
-	_StackOverflow = new(stackOverflowType)
-)
\ No newline at end of file
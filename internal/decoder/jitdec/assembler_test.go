@@ -1,3 +1,6 @@
+//go:build amd64
+// +build amd64
+
 /*
 * Copyright 2021 ByteDance Inc.
 *
@@ -20,6 +23,7 @@ import (
     `encoding/base64`
     `encoding/json`
     `reflect`
+    `strconv`
     `testing`
     `unsafe`
 
@@ -745,4 +749,26 @@ func TestAssembler_DecodeByteSlice_List(t *testing.T) {
     require.NoError(t, err)
     assert.Equal(t, len(s), pos)
     assert.Equal(t, []byte("hello, world"), v)
-}
\ No newline at end of file
+}
+func TestAssembler_MemoryUsage(t *testing.T) {
+    allocated, max, count := GetMemoryUsage()
+
+    /* compile types which were never compiled before */
+    const n = 8
+    for i := 0; i < n; i++ {
+        vt := reflect.StructOf([]reflect.StructField{{
+            Name: "MemoryUsage" + strconv.Itoa(i),
+            Type: reflect.TypeOf(""),
+        }})
+        _, err := findOrCompile(rt.UnpackType(vt), _PK_value)
+        require.NoError(t, err)
+    }
+
+    newAllocated, newMax, newCount := GetMemoryUsage()
+    assert.GreaterOrEqual(t, newCount, count + n)
+    assert.Greater(t, newAllocated, allocated)
+    assert.NotZero(t, newMax)
+    assert.GreaterOrEqual(t, newMax, max)
+    assert.LessOrEqual(t, newMax, newAllocated)
+    assert.NotEmpty(t, jit.FunctionSizes())
+}
@@ -26,6 +26,8 @@ import (
     `unsafe`
 
     `github.com/bytedance/sonic/internal/caching`
+    `github.com/bytedance/sonic/internal/decoder/flextime`
+    `github.com/bytedance/sonic/internal/registry`
     `github.com/bytedance/sonic/internal/resolver`
     `github.com/bytedance/sonic/internal/rt`
     `github.com/bytedance/sonic/option`
@@ -40,6 +42,7 @@ const (
     _OP_bin
     _OP_bool
     _OP_num
+    _OP_raw
     _OP_i8
     _OP_i16
     _OP_i32
@@ -100,6 +103,12 @@ const (
     _OP_skip_emtpy
     _OP_add
     _OP_check_empty
+    _OP_check_tuple
+    _OP_check_pairs
+    _OP_setter
+    _OP_check_time
+    _OP_check_ref
+    _OP_required
     _OP_unsupported
     _OP_debug
 )
@@ -122,6 +131,7 @@ var _OpNames = [256]string {
     _OP_bin              : "bin",
     _OP_bool             : "bool",
     _OP_num              : "num",
+    _OP_raw              : "raw",
     _OP_i8               : "i8",
     _OP_i16              : "i16",
     _OP_i32              : "i32",
@@ -179,6 +189,12 @@ var _OpNames = [256]string {
     _OP_add              : "add",
     _OP_go_skip          : "go_skip",
     _OP_check_empty      : "check_empty",
+    _OP_check_tuple      : "check_tuple",
+    _OP_check_pairs      : "check_pairs",
+    _OP_setter           : "setter",
+    _OP_check_time       : "check_time",
+    _OP_check_ref        : "check_ref",
+    _OP_required         : "required",
     _OP_unsupported      : "unsupported type",
     _OP_debug            : "debug",
 }
@@ -191,6 +207,11 @@ func (self _Op) String() string {
     }
 }
 
+// isNumber reports whether the opcode parses numbers with the digit buffer.
+func (self _Op) isNumber() bool {
+    return (self >= _OP_i8 && self <= _OP_f64) || (self >= _OP_map_key_i8 && self <= _OP_map_key_f64)
+}
+
 func _OP_int() _Op {
     switch _INT_SIZE {
         case 32: return _OP_i32
@@ -288,6 +309,13 @@ func newInsVf(op _Op, vf *caching.FieldMap) _Instr {
     }
 }
 
+func newInsVm(op _Op, vm *resolver.SetterMeta) _Instr {
+    return _Instr {
+        u: packOp(op),
+        p: unsafe.Pointer(vm),
+    }
+}
+
 func (self _Instr) op() _Op {
     return _Op(self.u >> 56)
 }
@@ -311,6 +339,10 @@ func (self _Instr) vf() *caching.FieldMap {
     return (*caching.FieldMap)(self.p)
 }
 
+func (self _Instr) vm() *resolver.SetterMeta {
+    return (*resolver.SetterMeta)(self.p)
+}
+
 func (self _Instr) vk() reflect.Kind {
     return (*rt.GoType)(self.p).Kind()
 }
@@ -333,6 +365,10 @@ func (self _Instr) isBranch() bool {
         case _OP_switch        : fallthrough
         case _OP_is_null       : fallthrough
         case _OP_is_null_quote : fallthrough
+        case _OP_check_tuple   : fallthrough
+        case _OP_check_pairs   : fallthrough
+        case _OP_check_time    : fallthrough
+        case _OP_check_ref     : fallthrough
         case _OP_check_char    : return true
         default                : return false
     }
@@ -361,7 +397,13 @@ func (self _Instr) disassemble() string {
         case _OP_unmarshal_p      : fallthrough
         case _OP_unmarshal_text   : fallthrough
         case _OP_unmarshal_text_p : fallthrough
+        case _OP_required         : fallthrough
         case _OP_recurse          : return fmt.Sprintf("%-18s%s", self.op(), self.vt())
+        case _OP_check_tuple      : fallthrough
+        case _OP_check_pairs      : fallthrough
+        case _OP_check_time       : fallthrough
+        case _OP_check_ref        : return fmt.Sprintf("%-18sL_%d, %s", self.op(), self.vi(), self.vt())
+        case _OP_setter           : return fmt.Sprintf("%-18s%s.%s", self.op(), self.vm().Owner, self.vm().Method.Name)
         case _OP_goto             : fallthrough
         case _OP_is_null_quote    : fallthrough
         case _OP_is_null          : return fmt.Sprintf("%-18sL_%d", self.op(), self.vi())
@@ -424,6 +466,15 @@ func (self _Program) pc() int {
     return len(self)
 }
 
+func (self _Program) hasNumber() bool {
+    for _, ins := range self {
+        if ins.op().isNumber() {
+            return true
+        }
+    }
+    return false
+}
+
 func (self _Program) tag(n int) {
     if n >= _MaxStack {
         panic("type nesting too deep")
@@ -470,6 +521,10 @@ func (self *_Program) fmv(op _Op, vf *caching.FieldMap) {
     *self = append(*self, newInsVf(op, vf))
 }
 
+func (self *_Program) stm(op _Op, vm *resolver.SetterMeta) {
+    *self = append(*self, newInsVm(op, vm))
+}
+
 func (self _Program) disassemble() string {
     nb  := len(self)
     tab := make([]bool, nb + 1)
@@ -510,6 +565,8 @@ type _Compiler struct {
     opts option.CompileOptions
     tab  map[reflect.Type]bool
     rec  map[reflect.Type]bool
+    reg  *registry.Snapshot
+    lay  _ProgramKey
 }
 
 func newCompiler() *_Compiler {
@@ -517,6 +574,7 @@ func newCompiler() *_Compiler {
         opts: option.DefaultCompileOptions(),
         tab: map[reflect.Type]bool{},
         rec: map[reflect.Type]bool{},
+        reg: registry.Load(),
     }
 }
 
@@ -525,6 +583,13 @@ func (self *_Compiler) apply(opts option.CompileOptions) *_Compiler {
     return self
 }
 
+// layout makes the struct and slice bodies take the layouts in key as well,
+// see _ProgramKey.
+func (self *_Compiler) layout(key _ProgramKey) *_Compiler {
+    self.lay = key & _PK_layouts
+    return self
+}
+
 func (self *_Compiler) rescue(ep *error) {
     if val := recover(); val != nil {
         if err, ok := val.(error); ok {
@@ -541,6 +606,30 @@ func (self *_Compiler) compile(vt reflect.Type) (ret _Program, err error) {
     return
 }
 
+func (self *_Compiler) compileTuple(vt reflect.Type) (ret _Program, err error) {
+    defer self.rescue(&err)
+    self.tab[vt] = true
+    self.compileStructTuple(&ret, 0, vt)
+    delete(self.tab, vt)
+    return
+}
+
+func (self *_Compiler) compileRequired(vt reflect.Type) (ret _Program, err error) {
+    defer self.rescue(&err)
+    self.tab[vt] = true
+    self.compileStructBody(&ret, 0, vt)
+    delete(self.tab, vt)
+    return
+}
+
+func (self *_Compiler) compilePairs(vt reflect.Type) (ret _Program, err error) {
+    defer self.rescue(&err)
+    self.tab[vt] = true
+    self.compileSlicePairs(&ret, 0, vt)
+    delete(self.tab, vt)
+    return
+}
+
 const (
     checkMarshalerFlags_quoted = 1
 )
@@ -548,6 +637,15 @@ const (
 func (self *_Compiler) checkMarshaler(p *_Program, vt reflect.Type, flags int, exec bool) bool {
     pt := reflect.PtrTo(vt)
 
+    /* `json.RawMessage` takes the raw value without calling the unmarshaler */
+    if vt == jsonRawMessageType && flags == 0 {
+        if exec {
+            p.add(_OP_lspace)
+            p.add(_OP_raw)
+        }
+        return true
+    }
+
     /* check for `json.Unmarshaler` with pointer receiver */
     if pt.Implements(jsonUnmarshalerType) {
         if exec {
@@ -600,7 +698,14 @@ func (self *_Compiler) compileOne(p *_Program, sp int, vt reflect.Type) {
         return
     }
 
-    if self.checkMarshaler(p, vt, 0, true) {
+    /* check for timestamps and durations */
+    if flextime.IsTimeType(vt) {
+        self.compileFlexTime(p, sp, vt)
+        return
+    }
+
+    /* pointers to timestamps are dereferenced before checking the formats */
+    if !isFlexTimePtr(vt) && self.checkMarshaler(p, vt, 0, true) {
         return
     }
 
@@ -611,6 +716,8 @@ func (self *_Compiler) compileOne(p *_Program, sp int, vt reflect.Type) {
     delete(self.tab, vt)
 }
 
+// compileOps dispatches on the kind rather than the type, so defined types
+// and aliases (eg. `type ID int64`) are decoded like their underlying types.
 func (self *_Compiler) compileOps(p *_Program, sp int, vt reflect.Type) {
     switch vt.Kind() {
         case reflect.Bool      : self.compilePrimitive (vt, p, _OP_bool)
@@ -638,6 +745,24 @@ func (self *_Compiler) compileOps(p *_Program, sp int, vt reflect.Type) {
     }
 }
 
+// compileFlexTime parses the value with several formats at runtime if
+// OptionFlexibleTime is set, and falls back to the standard decoding otherwise.
+func (self *_Compiler) compileFlexTime(p *_Program, sp int, vt reflect.Type) {
+    i := p.pc()
+    p.rtt(_OP_check_time, vt)
+
+    /* time.Time is a json.Unmarshaler, time.Duration is a plain int64 */
+    if !self.checkMarshaler(p, vt, 0, true) {
+        p.add(_OP_lspace)
+        self.compileOps(p, sp, vt)
+    }
+    p.pin(i)
+}
+
+func isFlexTimePtr(vt reflect.Type) bool {
+    return vt.Kind() == reflect.Ptr && flextime.IsTimeType(vt.Elem())
+}
+
 func (self *_Compiler) compileUnsupportedType(p *_Program, vt reflect.Type) {
     i := p.pc()
     p.add(_OP_is_null)
@@ -726,14 +851,21 @@ func (self *_Compiler) compileMapOp(p *_Program, sp int, vt reflect.Type, op _Op
 
 func (self *_Compiler) compilePtr(p *_Program, sp int, et reflect.Type) {
     i := p.pc()
+    r := -1
     p.add(_OP_is_null)
 
     /* dereference all the way down */
     for et.Kind() == reflect.Ptr {
-        if self.checkMarshaler(p, et, 0, true) {
+        if !isFlexTimePtr(et) && self.checkMarshaler(p, et, 0, true) {
             return
         }
         et = et.Elem()
+
+        /* struct pointers may be given as references to earlier objects */
+        if et.Kind() == reflect.Struct {
+            r = p.pc()
+            p.rtt(_OP_check_ref, et)
+        }
         p.rtt(_OP_deref, et)
     }
 
@@ -741,6 +873,8 @@ func (self *_Compiler) compilePtr(p *_Program, sp int, et reflect.Type) {
     ok := self.tab[et]
     if ok {
         p.rtt(_OP_recurse, et)
+    } else if flextime.IsTimeType(et) {
+        self.compileFlexTime(p, sp, et)
     } else {
         /* enter the recursion */
         p.add(_OP_lspace)
@@ -753,6 +887,9 @@ func (self *_Compiler) compilePtr(p *_Program, sp int, et reflect.Type) {
     }
     delete(self.tab, et)
 
+    if r >= 0 {
+        p.pin(r)
+    }
     j := p.pc()
     p.add(_OP_goto)
 
@@ -849,7 +986,7 @@ func (self *_Compiler) compileSliceList(p *_Program, sp int, vt reflect.Type) {
     i := p.pc()
     p.add(_OP_is_null)
     p.tag(sp)
-    skip := self.checkIfSkip(p, vt, '[')
+    skip, t := self.checkIfSkipPairs(p, vt)
     self.compileSliceBody(p, sp, vt.Elem())
     x := p.pc()
     p.add(_OP_goto)
@@ -857,6 +994,67 @@ func (self *_Compiler) compileSliceList(p *_Program, sp int, vt reflect.Type) {
     p.add(_OP_nil_3)
     p.pin(x)
     p.pin(skip)
+    if t >= 0 {
+        p.pin(t)
+    }
+}
+
+// checkIfSkipPairs is checkIfSkip for slices, which also take objects when the
+// elements are pairs and the program is compiled for OptionObjectAsPairs.
+func (self *_Compiler) checkIfSkipPairs(p *_Program, vt reflect.Type) (int, int) {
+    if _, _, ok := resolver.ResolvePair(vt.Elem()); !ok || self.lay & _PK_object_as_pairs == 0 {
+        return self.checkIfSkip(p, vt, '['), -1
+    }
+    j := p.pc()
+    p.chr(_OP_check_char_0, '[')
+    t := p.pc()
+    p.rtt(_OP_check_pairs, vt)
+    p.rtt(_OP_dismatch_err, vt)
+    s := p.pc()
+    p.add(_OP_go_skip)
+    p.pin(j)
+    p.int(_OP_add, 1)
+    return s, t
+}
+
+// compileSlicePairs decodes a JSON object into a slice of pairs in the order
+// of the keys, it is compiled on demand when OptionObjectAsPairs is set.
+func (self *_Compiler) compileSlicePairs(p *_Program, sp int, vt reflect.Type) {
+    et := vt.Elem()
+    kf, vf, _ := resolver.ResolvePair(et)
+
+    /* start of object */
+    p.tag(sp)
+    p.add(_OP_lspace)
+    p.chr(_OP_match_char, '{')
+    p.rtt(_OP_slice_init, et)
+    p.add(_OP_lspace)
+    j := p.pc()
+    p.chr(_OP_check_char, '}')
+    p.add(_OP_save)
+
+    /* append a pair for every entry */
+    k0 := p.pc()
+    p.rtt(_OP_slice_append, et)
+    p.add(_OP_save)
+    self.compileStructField(p, sp + 1, kf)
+    p.add(_OP_load)
+    p.add(_OP_lspace)
+    p.chr(_OP_match_char, ':')
+    self.compileStructField(p, sp + 1, vf)
+    p.add(_OP_drop)
+    p.add(_OP_load)
+
+    /* the next entry */
+    p.add(_OP_lspace)
+    k1 := p.pc()
+    p.chr(_OP_check_char, '}')
+    p.chr(_OP_match_char, ',')
+    p.add(_OP_lspace)
+    p.int(_OP_goto, k0)
+    p.pin(k1)
+    p.add(_OP_drop)
+    p.pin(j)
 }
 
 func (self *_Compiler) compileSliceBody(p *_Program, sp int, et reflect.Type) {
@@ -900,6 +1098,12 @@ func (self *_Compiler) compileStringBody(vt reflect.Type, p *_Program) {
 }
 
 func (self *_Compiler) compileStruct(p *_Program, sp int, vt reflect.Type) {
+    /* structs with "required" fields check the object keys after decoding */
+    if hasRequiredFields(vt) {
+        p.rtt(_OP_required, vt)
+        return
+    }
+
     if sp >= self.opts.MaxInlineDepth || p.pc() >= _MAX_ILBUF || (sp > 0 && vt.NumField() >= _MAX_FIELDS) {
         p.rtt(_OP_recurse, vt)
         if self.opts.RecursiveDepth > 0 {
@@ -910,9 +1114,18 @@ func (self *_Compiler) compileStruct(p *_Program, sp int, vt reflect.Type) {
     }
 }
 
+func hasRequiredFields(vt reflect.Type) bool {
+    for _, f := range resolver.ResolveStruct(vt) {
+        if (f.Opts & resolver.F_required) != 0 {
+            return true
+        }
+    }
+    return false
+}
+
 func (self *_Compiler) compileStructBody(p *_Program, sp int, vt reflect.Type) {
-    fv := resolver.ResolveStruct(vt)
-    fm, sw := caching.CreateFieldMap(len(fv)), make([]int, len(fv))
+    fv, sv := resolver.ResolveStructWithSetters(self.reg, vt)
+    fm, sw := caching.CreateFieldMap(len(fv) + len(sv)), make([]int, len(fv) + len(sv))
 
     /* start of object */
     p.tag(sp)
@@ -921,14 +1134,18 @@ func (self *_Compiler) compileStructBody(p *_Program, sp int, vt reflect.Type) {
 
     j := p.pc()
     p.chr(_OP_check_char_0, '{')
+    t := self.checkTuple(p, vt)
     p.rtt(_OP_dismatch_err, vt)
 
     /* special case for empty object */
-    if len(fv) == 0 {
+    if len(fv) == 0 && len(sv) == 0 {
         p.pin(j)
         s := p.pc()
         p.add(_OP_skip_emtpy)
         p.pin(s)
+        if t >= 0 {
+            p.pin(t)
+        }
         p.pin(n)
         return
     }
@@ -969,33 +1186,90 @@ func (self *_Compiler) compileStructBody(p *_Program, sp int, vt reflect.Type) {
     for i, f := range fv {
         sw[i] = p.pc()
         fm.Set(f.Name, i)
-
-        /* index to the field */
-        for _, o := range f.Path {
-            if p.int(_OP_index, int(o.Size)); o.Kind == resolver.F_deref {
-                p.rtt(_OP_deref, o.Type)
-            }
-        }
-
-        /* check for "stringnize" option */
-        if (f.Opts & resolver.F_stringize) == 0 {
-            self.compileOne(p, sp + 1, f.Type)
-        } else {
-            self.compileStructFieldStr(p, sp + 1, f.Type)
-        }
+        self.compileStructField(p, sp, f)
 
         /* load the state, and try next field */
         p.add(_OP_load)
         p.int(_OP_goto, y0)
     }
 
+    /* keys mapped to setters come after the fields */
+    for i, s := range sv {
+        sw[len(fv) + i] = p.pc()
+        fm.Set(s.Name, len(fv) + i)
+        p.stm(_OP_setter, s)
+        p.add(_OP_load)
+        p.int(_OP_goto, y0)
+    }
+
     p.pin(x)
     p.pin(y1)
     p.add(_OP_drop)
+    if t >= 0 {
+        p.pin(t)
+    }
     p.pin(n)
     p.pin(skip)
 }
 
+func (self *_Compiler) compileStructField(p *_Program, sp int, f resolver.FieldMeta) {
+    /* index to the field */
+    for _, o := range f.Path {
+        if p.int(_OP_index, int(o.Size)); o.Kind == resolver.F_deref {
+            p.rtt(_OP_deref, o.Type)
+        }
+    }
+
+    /* check for "stringnize" option */
+    if (f.Opts & resolver.F_stringize) == 0 {
+        self.compileOne(p, sp + 1, f.Type)
+    } else {
+        self.compileStructFieldStr(p, sp + 1, f.Type)
+    }
+}
+
+// checkTuple makes the struct body decode arrays with the tuple program of vt,
+// if the program is compiled for OptionStructAsArray, and returns the jump to
+// the end of the body, or -1.
+func (self *_Compiler) checkTuple(p *_Program, vt reflect.Type) int {
+    if self.lay & _PK_struct_as_array == 0 {
+        return -1
+    }
+    t := p.pc()
+    p.rtt(_OP_check_tuple, vt)
+    return t
+}
+
+// compileStructTuple decodes a JSON array into the struct fields positionally,
+// it is compiled on demand when OptionStructAsArray is set.
+func (self *_Compiler) compileStructTuple(p *_Program, sp int, vt reflect.Type) {
+    fv := resolver.ResolveStruct(vt)
+
+    /* start of array */
+    p.tag(sp)
+    p.add(_OP_lspace)
+    p.chr(_OP_match_char, '[')
+    p.add(_OP_save)
+    p.add(_OP_lspace)
+    v := []int{p.pc()}
+    p.chr(_OP_check_char, ']')
+
+    /* decode every element into the next field */
+    for _, f := range fv {
+        self.compileStructField(p, sp, f)
+        p.add(_OP_load)
+        p.add(_OP_lspace)
+        v = append(v, p.pc())
+        p.chr(_OP_check_char, ']')
+        p.chr(_OP_match_char, ',')
+    }
+
+    /* drop the extra elements */
+    p.add(_OP_array_skip)
+    p.rel(v)
+    p.add(_OP_drop)
+}
+
 func (self *_Compiler) compileStructFieldStrUnmarshal(p *_Program, vt reflect.Type) {
     p.add(_OP_lspace)
     n0 := p.pc()
@@ -1076,23 +1350,23 @@ func (self *_Compiler) compileStructFieldStr(p *_Program, sp int, vt reflect.Typ
         }
     }
 
-    /* compile for each type */
+    /* compile for each type, the value is terminated by the closing quote */
     switch vt.Kind() {
         case reflect.Bool    : p.add(_OP_bool)
-        case reflect.Int     : p.add(_OP_int())
-        case reflect.Int8    : p.add(_OP_i8)
-        case reflect.Int16   : p.add(_OP_i16)
-        case reflect.Int32   : p.add(_OP_i32)
-        case reflect.Int64   : p.add(_OP_i64)
-        case reflect.Uint    : p.add(_OP_uint())
-        case reflect.Uint8   : p.add(_OP_u8)
-        case reflect.Uint16  : p.add(_OP_u16)
-        case reflect.Uint32  : p.add(_OP_u32)
-        case reflect.Uint64  : p.add(_OP_u64)
-        case reflect.Uintptr : p.add(_OP_uintptr())
-        case reflect.Float32 : p.add(_OP_f32)
-        case reflect.Float64 : p.add(_OP_f64)
-        case reflect.String  : p.add(_OP_string())
+        case reflect.Int     : p.chr(_OP_int(), '"')
+        case reflect.Int8    : p.chr(_OP_i8, '"')
+        case reflect.Int16   : p.chr(_OP_i16, '"')
+        case reflect.Int32   : p.chr(_OP_i32, '"')
+        case reflect.Int64   : p.chr(_OP_i64, '"')
+        case reflect.Uint    : p.chr(_OP_uint(), '"')
+        case reflect.Uint8   : p.chr(_OP_u8, '"')
+        case reflect.Uint16  : p.chr(_OP_u16, '"')
+        case reflect.Uint32  : p.chr(_OP_u32, '"')
+        case reflect.Uint64  : p.chr(_OP_u64, '"')
+        case reflect.Uintptr : p.chr(_OP_uintptr(), '"')
+        case reflect.Float32 : p.chr(_OP_f32, '"')
+        case reflect.Float64 : p.chr(_OP_f64, '"')
+        case reflect.String  : p.chr(_OP_string(), '"')
         default              : panic("not reachable")
     }
 
@@ -1115,6 +1389,7 @@ func (self *_Compiler) compileStructFieldStr(p *_Program, sp int, vt reflect.Typ
         p.int(_OP_add, 1)
         p.pin(pc2)
         p.pin(n0)
+        p.pin(skip)
         return
     }
 
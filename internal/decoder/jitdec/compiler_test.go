@@ -28,3 +28,50 @@ func TestCompiler_Compile(t *testing.T) {
     assert.Nil(t, err)
     prg.disassemble()
 }
+
+func TestCompiler_HasNumber(t *testing.T) {
+    type T struct {
+        A string            `json:"a"`
+        B []string          `json:"b"`
+        C map[string]string `json:"c"`
+    }
+    prg, err := newCompiler().compile(reflect.TypeOf(T{}))
+    assert.Nil(t, err)
+    assert.False(t, prg.hasNumber())
+
+    /* numbers as map keys also need the digit buffer */
+    prg, err = newCompiler().compile(reflect.TypeOf(map[int]string{}))
+    assert.Nil(t, err)
+    assert.True(t, prg.hasNumber())
+    prg, err = newCompiler().compile(reflect.TypeOf(TwitterStruct{}))
+    assert.Nil(t, err)
+    assert.True(t, prg.hasNumber())
+}
+
+func TestCompiler_Layout(t *testing.T) {
+    type P struct {
+        K string
+        V int
+    }
+    type T struct {
+        A struct{ X int } `json:"a"`
+        B []P             `json:"b"`
+    }
+    count := func(key _ProgramKey, op _Op) (n int) {
+        prg, err := newCompiler().layout(key).compile(reflect.TypeOf(T{}))
+        assert.Nil(t, err)
+        for _, ins := range prg {
+            if ins.op() == op {
+                n++
+            }
+        }
+        return
+    }
+
+    /* the other layouts are only taken by the programs compiled for them */
+    assert.Zero(t, count(0, _OP_check_tuple))
+    assert.Zero(t, count(0, _OP_check_pairs))
+    assert.NotZero(t, count(_PK_struct_as_array, _OP_check_tuple))
+    assert.Zero(t, count(_PK_struct_as_array, _OP_check_pairs))
+    assert.Equal(t, 1, count(_PK_layouts, _OP_check_pairs))
+}
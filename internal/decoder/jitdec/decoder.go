@@ -8,6 +8,7 @@ import (
 
 	`github.com/bytedance/sonic/internal/decoder/consts`
 	`github.com/bytedance/sonic/internal/decoder/errors`
+    `github.com/bytedance/sonic/internal/resolver`
     `github.com/bytedance/sonic/internal/rt`
     `github.com/bytedance/sonic/utf8`
 	`github.com/bytedance/sonic/option`
@@ -20,6 +21,9 @@ type (
 
 const (
 	_F_allow_control = consts.F_allow_control
+	_F_bool_as_int = consts.F_bool_as_int
+	_F_struct_as_array = consts.F_struct_as_array
+	_F_object_as_pairs = consts.F_object_as_pairs
 	_F_copy_string = consts.F_copy_string
 	_F_disable_unknown = consts.F_disable_unknown
 	_F_disable_urc = consts.F_disable_urc
@@ -28,12 +32,21 @@ const (
 	_F_no_validate_json = consts.F_no_validate_json
 	_F_validate_string = consts.F_validate_string
     _F_case_sensitive = consts.F_case_sensitive
+	_F_flexible_time = consts.F_flexible_time
+	_F_limit_key_length = consts.F_limit_key_length
+	_F_pointer_refs = consts.F_pointer_refs
+	_F_limit_dynamic_depth = consts.F_limit_dynamic_depth
+	_F_grow_stack = consts.F_grow_stack
+
+	_MaxKeyLength = consts.MaxKeyLength
+	_MaxDynamicDepth = consts.MaxDynamicDepth
 )
 
 var (
 	error_wrap = errors.ErrorWrap
 	error_type = errors.ErrorType
 	error_field = errors.ErrorField
+	error_missing = errors.ErrorMissingField
 	error_value = errors.ErrorValue
 	error_mismatch = errors.ErrorMismatch
 	stackOverflow = errors.StackOverflow
@@ -43,8 +56,21 @@ var (
 // Decode parses the JSON-encoded data from current position and stores the result
 // in the value pointed to by val.
 func Decode(s *string, i *int, f uint64, val interface{}) error {
+    return DecodeWithResolver(s, i, f, val, nil, 0)
+}
+
+// DecodeWithResolver is like Decode, but object keys are mapped by rn
+// before being matched against the struct fields, and arrays and objects
+// can not be nested deeper than maxDepth if it is positive.
+func DecodeWithResolver(s *string, i *int, f uint64, val interface{}, rn *resolver.NameResolver, maxDepth int) error {
     /* validate json if needed */
     if (f & (1 << _F_validate_string)) != 0  && !utf8.ValidateString(*s){
+        /* report invalid UTF-8 instead of replacing it, when asked to */
+        if (f & (1 << _F_disable_urc)) != 0 {
+            if err := errors.ErrorInvalidUTF8(*s, *i); err != nil {
+                return err
+            }
+        }
         dbuf := utf8.CorrectWith(nil, rt.Str2Mem(*s), "\ufffd")
         *s = rt.Mem2Str(dbuf)
     }
@@ -73,6 +99,8 @@ func Decode(s *string, i *int, f uint64, val interface{}) error {
 
     /* create a new stack, and call the decoder */
     sb := newStack()
+    sb.rn = rn
+    setMaxDepth(sb, maxDepth)
     nb, err := decodeTypedPointer(*s, *i, etp, vp, sb, f)
     /* return the stack back */
     *i = nb
@@ -100,7 +128,7 @@ func Pretouch(vt reflect.Type, opts ...option.CompileOption) error {
 func pretouchType(_vt reflect.Type, opts option.CompileOptions) (map[reflect.Type]bool, error) {
     /* compile function */
     compiler := newCompiler().apply(opts)
-    decoder := func(vt *rt.GoType, _ ...interface{}) (interface{}, error) {
+    decoder := func() (interface{}, error) {
         if pp, err := compiler.compile(_vt); err != nil {
             return nil, err
         } else {
@@ -111,10 +139,10 @@ func pretouchType(_vt reflect.Type, opts option.CompileOptions) (map[reflect.Typ
     }
 
     /* find or compile */
-    vt := rt.UnpackType(_vt)
-    if val := programCache.Get(vt); val != nil {
+    ps := programsOf(rt.UnpackType(_vt))
+    if val := ps.fn[_PK_value].Load(); val != nil {
         return nil, nil
-    } else if _, err := programCache.Compute(vt, decoder); err == nil {
+    } else if _, err := ps.findOrCompile(_PK_value, decoder); err == nil {
         return compiler.rec, nil
     } else {
         return nil, err
@@ -23,13 +23,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 
-	"github.com/bytedance/sonic/internal/caching"
 	"github.com/bytedance/sonic/internal/decoder/consts"
-	"github.com/bytedance/sonic/internal/decoder/errors"
 	"github.com/bytedance/sonic/internal/jit"
-	"github.com/bytedance/sonic/internal/native/types"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/option"
 )
@@ -38,6 +40,7 @@ import (
 type Decoder struct {
 	assembler *_Assembler
 	program   _Program
+	decoder   *_Decoder
 	name      string
 	compiled  bool
 }
@@ -50,13 +53,59 @@ func NewDecoder(name string) *Decoder {
 	}
 }
 
-// Compile compiles the given type into ARM64 JIT code
+// _MaxCachedDecoders bounds the number of types kept in decoderCache
+const _MaxCachedDecoders = 4096
+
+// decoderCache maps the *rt.GoType of every type compiled without options to
+// its *Decoder, so that compiling a type again reuses the machine code. It is
+// safe for concurrent use: when several goroutines compile the same type at
+// once, the first stored decoder wins and the others are dropped. Entries are
+// never evicted, instead no more types are cached once _MaxCachedDecoders is
+// reached, which bounds the memory held by the cache.
+var (
+	decoderCache     sync.Map
+	decoderCacheSize int64
+)
+
+// GetDecoderCacheSize returns the number of types in the decoder cache
+func GetDecoderCacheSize() int {
+	return int(atomic.LoadInt64(&decoderCacheSize))
+}
+
+// cacheDecoder stores d for vt unless the cache is full, and returns the
+// decoder cached for vt, which is d itself if it was stored
+func cacheDecoder(vt *rt.GoType, d *Decoder) *Decoder {
+	if atomic.AddInt64(&decoderCacheSize, 1) > _MaxCachedDecoders {
+		atomic.AddInt64(&decoderCacheSize, -1)
+		return d
+	}
+	if v, loaded := decoderCache.LoadOrStore(vt, d); loaded {
+		atomic.AddInt64(&decoderCacheSize, -1)
+		return v.(*Decoder)
+	}
+	return d
+}
+
+// Compile compiles the given type into ARM64 JIT code, the returned value is
+// a *_Decoder. Types compiled without options are cached, so compiling such
+// a type again returns the same *_Decoder at nearly no cost.
 func (d *Decoder) Compile(vt reflect.Type, opts ...option.CompileOption) (interface{}, error) {
+	if err := checkDecodable(vt); err != nil {
+		return nil, err
+	}
+	if len(opts) == 0 {
+		if v, ok := decoderCache.Load(rt.UnpackType(vt)); ok {
+			d.reuse(v.(*Decoder))
+			return d.decoder, nil
+		}
+	}
+
 	// Create compiler to generate instruction program
-	compiler := newCompiler()
-	if len(opts) > 0 {
-		compiler.apply(opts[0])
+	cfg := option.DefaultCompileOptions()
+	for _, opt := range opts {
+		opt(&cfg)
 	}
+	compiler := newCompiler().apply(cfg)
 
 	// Generate instruction program
 	program, err := compiler.compile(vt)
@@ -71,8 +120,43 @@ func (d *Decoder) Compile(vt reflect.Type, opts ...option.CompileOption) (interf
 
 	// Compile to ARM64 machine code
 	decoder := d.assembler.Load()
+	d.decoder = &decoder
+	d.compiled = true
+
+	// Cache the result, another goroutine may have cached the type meanwhile
+	if len(opts) == 0 {
+		d.reuse(cacheDecoder(rt.UnpackType(vt), &Decoder{
+			assembler: d.assembler,
+			program:   d.program,
+			decoder:   d.decoder,
+			name:      d.name,
+			compiled:  true,
+		}))
+	}
+	return d.decoder, nil
+}
+
+// checkDecodable rejects the types which no JSON value can be decoded into,
+// which are functions, channels, complex numbers and unsafe pointers, as well
+// as the pointers to them
+func checkDecodable(vt reflect.Type) error {
+	et := vt
+	for et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	switch et.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return &json.UnsupportedTypeError{Type: vt}
+	}
+	return nil
+}
+
+// reuse takes over the compiled code of the cached decoder c, keeping the name
+func (d *Decoder) reuse(c *Decoder) {
+	d.assembler = c.assembler
+	d.program = c.program
+	d.decoder = c.decoder
 	d.compiled = true
-	return &decoder, nil
 }
 
 // GetProgram returns the compiled JIT program for debugging
@@ -83,12 +167,24 @@ func (d *Decoder) GetProgram() *_Program {
 	return nil
 }
 
-// Stats returns compilation statistics
+// Runtime counters bumped by the generated code of every decoder, they tell
+// how often the decoding falls back to the slow paths
+var (
+	statMismatches uint64 // mismatched values skipped and reported at the end
+	statUnknowns   uint64 // unknown object keys whose values were skipped
+	statDynamics   uint64 // values dispatched to decodeDynamic at runtime
+)
+
+// Stats returns compilation statistics, along with the runtime counters
+// shared by all the decoders: "mismatches", "unknown_fields" and "dynamics"
 func (d *Decoder) Stats() map[string]interface{} {
 	stats := map[string]interface{}{
-		"platform": "arm64",
-		"name":     d.name,
-		"jit":      "enabled",
+		"platform":       "arm64",
+		"name":           d.name,
+		"jit":            "enabled",
+		"mismatches":     atomic.LoadUint64(&statMismatches),
+		"unknown_fields": atomic.LoadUint64(&statUnknowns),
+		"dynamics":       atomic.LoadUint64(&statDynamics),
 	}
 
 	if d.compiled {
@@ -104,6 +200,7 @@ func (d *Decoder) Stats() map[string]interface{} {
 func (d *Decoder) Reset() {
 	d.assembler = nil
 	d.program = nil
+	d.decoder = nil
 	d.compiled = false
 }
 
@@ -151,149 +248,16 @@ func (d *Decoder) ApplyOptions(opts interface{}) {
 
 // Decode performs the actual JSON decoding using the compiled JIT code
 func (d *Decoder) Decode(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64, sv string) (int, error) {
-	if !d.compiled || d.assembler == nil {
+	if !d.compiled || d.decoder == nil {
 		return 0, fmt.Errorf("decoder not compiled")
 	}
 
 	// Call the compiled decoder function
-	if encoder, ok := d.assembler.Load().(func(string, int, unsafe.Pointer, *_Stack, uint64, string) (int, error)); ok {
-		return encoder(s, ic, vp, sb, fv, sv)
-	}
-
-	return 0, fmt.Errorf("failed to load decoder function")
-}
-
-// Stack represents the decoder stack for recursive decoding
-type _Stack struct {
-	data []interface{}
-}
-
-// NewStack creates a new decoder stack
-func NewStack() *_Stack {
-	return &_Stack{
-		data: make([]interface{}, 0, 64),
-	}
-}
-
-// Push pushes a value onto the stack
-func (s *_Stack) Push(v interface{}) {
-	s.data = append(s.data, v)
-}
-
-// Pop pops a value from the stack
-func (s *_Stack) Pop() interface{} {
-	if len(s.data) == 0 {
-		return nil
-	}
-	v := s.data[len(s.data)-1]
-	s.data = s.data[:len(s.data)-1]
-	return v
-}
-
-// Peek returns the top value without popping it
-func (s *_Stack) Peek() interface{} {
-	if len(s.data) == 0 {
-		return nil
-	}
-	return s.data[len(s.data)-1]
-}
-
-// Empty returns true if the stack is empty
-func (s *_Stack) Empty() bool {
-	return len(s.data) == 0
-}
-
-// Size returns the current stack size
-func (s *_Stack) Size() int {
-	return len(s.data)
-}
-
-// Pretouch pre-compiles the given type to avoid JIT compilation on-the-fly
-func Pretouch(vt reflect.Type, opts ...option.CompileOption) error {
-	// Create a temporary decoder to pre-compile the type
-	decoder := NewDecoder("pretouch")
-
-	// Compile the type
-	_, err := decoder.Compile(vt, opts...)
-	if err != nil {
-		return err
-	}
-
-	// TODO: Store the compiled decoder in cache for later use
-	return nil
+	return (*d.decoder)(s, ic, vp, sb, fv, sv, nil)
 }
 
-// DecodeTypedPointer is the main entry point for ARM64 JIT decoding
-func DecodeTypedPointer(s string, ic *int, vp unsafe.Pointer, sb *_Stack, fv uint64) error {
-	// Extract the type information from the value pointer
-	if vp == nil {
-		return errors.NewTypeError("nil value pointer")
-	}
-
-	// Get the actual type of the value
-	val := reflect.NewAt(reflect.TypeOf(vp).Elem(), vp)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	// Create a decoder and compile it for this type
-	decoder := NewDecoder("runtime")
-	compiledDecoder, err := decoder.Compile(val.Type())
-	if err != nil {
-		return err
-	}
-
-	// Perform the actual decoding
-	result, err := compiledDecoder.(func(string, int, unsafe.Pointer, *_Stack, uint64, string) (int, error))(s, *ic, vp, sb, fv, "")
-	if err != nil {
-		return err
-	}
-
-	*ic = result
-	return nil
-}
-
-// Helper types and constants
-type (
-	Options = consts.Options
-	MismatchTypeError = errors.MismatchTypeError
-	SyntaxError = errors.SyntaxError
-)
-
-// Global decoder instance for common operations
-var defaultDecoder = NewDecoder("default")
-
-// High-level decoding functions
-func Decode(s string, val interface{}) error {
-	return decodeImpl(&s, new(int), 0, val)
-}
-
-func decodeImpl(sp *string, ic *int, fv uint64, val interface{}) error {
-	// Create a decoder stack
-	sb := NewStack()
-
-	// Get the type of the value to decode
-	vt := reflect.TypeOf(val)
-	if vt.Kind() == reflect.Ptr {
-		vt = vt.Elem()
-	}
-
-	// Create and compile a decoder for this type
-	decoder := NewDecoder("decode")
-	compiledDecoder, err := decoder.Compile(vt)
-	if err != nil {
-		return err
-	}
-
-	// Use the compiled decoder to decode
-	result, err := compiledDecoder.(func(string, int, unsafe.Pointer, *_Stack, uint64, string) (int, error))(*sp, *ic, unsafe.Pointer(&val), sb, fv, "")
-	if err != nil {
-		return err
-	}
-
-	*ic = result
-	return nil
-}
+// Options are the decoding flags
+type Options = consts.Options
 
 // Validate validates JSON-encoded bytes and reports if it is valid
 func Valid(data []byte) bool {
@@ -371,7 +335,7 @@ func GetDebugInfo(decoder *Decoder) DebugInfo {
 	}
 
 	return DebugInfo{
-		Program:   "arm64_jit",
+		Program:   program,
 		Assembly: "", // Would contain assembly code
 		Code:      decoder.DumpCode(),
 		Stats:    decoder.Stats(),
@@ -398,27 +362,122 @@ func CreateDecoderWithName(name string) *Decoder {
 	return NewDecoder(name)
 }
 
-// Batch compilation for multiple types
-func BatchCompile(types []reflect.Type) (map[reflect.Type]interface{}, error) {
-	results := make(map[reflect.Type]interface{})
-	errors := make(map[reflect.Type]error)
+// BatchCompileError reports the types which BatchCompile failed to compile
+type BatchCompileError struct {
+	Errors map[reflect.Type]error
+}
 
+func (self *BatchCompileError) Error() string {
+	msgs := make([]string, 0, len(self.Errors))
+	for vt, err := range self.Errors {
+		msgs = append(msgs, fmt.Sprintf("%v: %v", vt, err))
+	}
+	sort.Strings(msgs)
+	return fmt.Sprintf("failed to compile %d type(s): %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// BatchCompile compiles the decoders of all the types, so that they are
+// cached before being used, with as many workers as GOMAXPROCS. The decoders
+// compiled successfully are returned even if some types fail, which are
+// reported by a *BatchCompileError.
+func BatchCompile(types []reflect.Type) (map[reflect.Type]interface{}, error) {
+	return BatchCompileConcurrently(types, runtime.GOMAXPROCS(0))
+}
+
+// BatchCompileConcurrently is like BatchCompile, but with at most workers
+// goroutines compiling at once, the types are compiled one by one if workers
+// is not greater than 1.
+func BatchCompileConcurrently(types []reflect.Type, workers int) (map[reflect.Type]interface{}, error) {
+	if workers > len(types) {
+		workers = len(types)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[reflect.Type]interface{}, len(types))
+	errs := make(map[reflect.Type]error)
+	queue := make(chan reflect.Type)
+
+	/* each worker compiles the types taken from the queue */
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for vt := range queue {
+				ret, err := compileOne(vt)
+				mu.Lock()
+				if err != nil {
+					errs[vt] = err
+				} else {
+					results[vt] = ret
+				}
+				mu.Unlock()
+			}
+		}()
+	}
 	for _, vt := range types {
-		decoder := CreateDecoderWithName("batch_" + vt.String())
-		compiledDecoder, err := decoder.Compile(vt)
-		if err != nil {
-			errors[vt] = err
-			continue
+		queue <- vt
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return results, &BatchCompileError{Errors: errs}
+	}
+	return results, nil
+}
+
+// compileOne compiles vt, turning panics of the compiler into errors so that
+// one bad type doesn't abort the whole batch
+func compileOne(vt reflect.Type) (ret interface{}, err error) {
+	if vt == nil {
+		return nil, fmt.Errorf("nil type")
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			ret, err = nil, fmt.Errorf("compiling %v panicked: %v", vt, v)
 		}
+	}()
+	return CreateDecoderWithName("batch_" + vt.String()).Compile(vt)
+}
+
+// BatchCompile is like the BatchCompile function, see it for details
+func (d *Decoder) BatchCompile(types []reflect.Type) (map[reflect.Type]interface{}, error) {
+	return BatchCompile(types)
+}
 
-		results[vt] = compiledDecoder
+// WarmUp compiles the decoders of all the types ahead of time, and caches them
+// for Decode as well, so that the first values decoded into them pay no
+// compilation cost. Every type is compiled once, however many times it is
+// given or WarmUp is called, and it is safe to call WarmUp concurrently. The
+// types failing to compile are all reported by a *BatchCompileError.
+func WarmUp(types []reflect.Type) error {
+	seen := make(map[reflect.Type]bool, len(types))
+	uniq := make([]reflect.Type, 0, len(types))
+	for _, vt := range types {
+		if !seen[vt] {
+			seen[vt] = true
+			uniq = append(uniq, vt)
+		}
 	}
 
-	if len(errors) > 0 {
-		return results, errors[reflect.TypeOf(struct{}{})] // Return first error
+	/* Decode looks the decoders up in programCache, share the machine code with it */
+	results, err := BatchCompile(uniq)
+	for vt, ret := range results {
+		fn := *ret.(*_Decoder)
+		_, _ = programsOf(rt.UnpackType(vt)).findOrCompile(_PK_value, func() (interface{}, error) {
+			return fn, nil
+		})
 	}
+	return err
+}
 
-	return results, nil
+// WarmUp is like the WarmUp function, see it for details
+func (d *Decoder) WarmUp(types []reflect.Type) error {
+	return WarmUp(types)
 }
 
 // ARM64 JIT options specific to decoding
@@ -456,12 +515,13 @@ func IsJITEnabled() bool {
 
 // EnableJIT enables JIT compilation
 func EnableJIT() {
-	// jit.EnableARM64JIT() // Would need to implement this
+	jit.EnableARM64JIT()
 }
 
-// DisableJIT disables JIT compilation
+// DisableJIT disables JIT compilation, the decoders already compiled are not
+// used until it is enabled again
 func DisableJIT() {
-	// jit.DisableARM64JIT() // Would need to implement this
+	jit.DisableARM64JIT()
 }
 
 // ForceUseFallback forces the use of fallback decoder instead of JIT
@@ -478,12 +538,3 @@ const (
 	DefaultOptLevel      = 1
 )
 
-// ARM64-specific types
-type (
-	_Stack   struct {
-		data []interface{}
-	}
-	_Decoder interface {
-		Decode(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64, sv string) (int, error)
-	}
-)
\ No newline at end of file
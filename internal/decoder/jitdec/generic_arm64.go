@@ -0,0 +1,269 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jitdec
+
+import (
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/native"
+	"github.com/bytedance/sonic/internal/native/types"
+	"github.com/bytedance/sonic/internal/rt"
+)
+
+// The value decoder is plain Go code on ARM64, which grows its own stack, so
+// unlike the AMD64 one it needs no frame of its own in the JIT stack budget.
+const (
+	_VD_offs = 0
+	_VD_size = 0
+)
+
+// _subr_decode_value decodes the JSON value at ic into the interface{} at vp,
+// building the same values as encoding/json, and returns the position after
+// the value. The AMD64 decoder generates this subroutine, see generic_regabi_amd64.go.
+func _subr_decode_value(s string, ic int, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+	d := _ValueDecoder{s: s, fv: fv}
+	v, p, err := d.decode(ic, 0)
+	if err != 0 {
+		return p, error_wrap(s, p, err)
+	}
+	*(*interface{})(vp) = v
+	return p, nil
+}
+
+type _ValueDecoder struct {
+	s  string
+	fv uint64
+}
+
+func (self *_ValueDecoder) space(i int) int {
+	for i < len(self.s) && (types.SPACE_MASK>>self.s[i])&1 != 0 {
+		i++
+	}
+	return i
+}
+
+func (self *_ValueDecoder) decode(i int, depth int) (interface{}, int, types.ParsingError) {
+	if depth >= types.MAX_RECURSE {
+		return nil, i, types.ERR_RECURSE_EXCEED_MAX
+	}
+	if i = self.space(i); i >= len(self.s) {
+		return nil, i, types.ERR_EOF
+	}
+
+	/* dispatch on the first byte */
+	switch c := self.s[i]; {
+	case c == '{':
+		return self.object(i+1, depth+1)
+	case c == '[':
+		return self.array(i+1, depth+1)
+	case c == '"':
+		return self.str(i)
+	case c == 't':
+		return self.literal(i, "true", true)
+	case c == 'f':
+		return self.literal(i, "false", false)
+	case c == 'n':
+		return self.literal(i, "null", nil)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return self.number(i)
+	default:
+		return nil, i, types.ERR_INVALID_CHAR
+	}
+}
+
+func (self *_ValueDecoder) object(i int, depth int) (interface{}, int, types.ParsingError) {
+	m := make(map[string]interface{})
+	if i = self.space(i); i < len(self.s) && self.s[i] == '}' {
+		return m, i + 1, 0
+	}
+
+	for {
+		/* the key */
+		if i = self.space(i); i >= len(self.s) {
+			return nil, i, types.ERR_EOF
+		} else if self.s[i] != '"' {
+			return nil, i, types.ERR_INVALID_CHAR
+		}
+		k, p, err := self.str(i)
+		if err != 0 {
+			return nil, p, err
+		}
+
+		/* the colon */
+		if i = self.space(p); i >= len(self.s) {
+			return nil, i, types.ERR_EOF
+		} else if self.s[i] != ':' {
+			return nil, i, types.ERR_INVALID_CHAR
+		}
+
+		/* the value */
+		v, p, err := self.decode(i+1, depth)
+		if err != 0 {
+			return nil, p, err
+		}
+		m[k.(string)] = v
+
+		/* the comma or the end of the object */
+		if i = self.space(p); i >= len(self.s) {
+			return nil, i, types.ERR_EOF
+		} else if self.s[i] == '}' {
+			return m, i + 1, 0
+		} else if self.s[i] != ',' {
+			return nil, i, types.ERR_INVALID_CHAR
+		}
+		i++
+	}
+}
+
+func (self *_ValueDecoder) array(i int, depth int) (interface{}, int, types.ParsingError) {
+	a := make([]interface{}, 0, _MinSlice)
+	if i = self.space(i); i < len(self.s) && self.s[i] == ']' {
+		return a, i + 1, 0
+	}
+
+	for {
+		v, p, err := self.decode(i, depth)
+		if err != 0 {
+			return nil, p, err
+		}
+		a = append(a, v)
+
+		/* the comma or the end of the array */
+		if i = self.space(p); i >= len(self.s) {
+			return nil, i, types.ERR_EOF
+		} else if self.s[i] == ']' {
+			return a, i + 1, 0
+		} else if self.s[i] != ',' {
+			return nil, i, types.ERR_INVALID_CHAR
+		}
+		i++
+	}
+}
+
+func (self *_ValueDecoder) literal(i int, lit string, v interface{}) (interface{}, int, types.ParsingError) {
+	if !strings.HasPrefix(self.s[i:], lit) {
+		return nil, i, types.ERR_INVALID_CHAR
+	}
+	return v, i + len(lit), 0
+}
+
+func (self *_ValueDecoder) str(i int) (interface{}, int, types.ParsingError) {
+	esc := false
+	for p := i + 1; p < len(self.s); p++ {
+		switch c := self.s[p]; {
+		case c == '"':
+			return self.unquote(self.s[i+1:p], esc, p+1)
+		case c == '\\':
+			esc = true
+			p++
+		case c < ' ' && self.fv&(1<<_F_allow_control) == 0:
+			return nil, p, types.ERR_INVALID_CHAR
+		}
+	}
+	return nil, len(self.s), types.ERR_EOF
+}
+
+func (self *_ValueDecoder) unquote(s string, esc bool, p int) (interface{}, int, types.ParsingError) {
+	if !esc {
+		if self.fv&(1<<_F_copy_string) != 0 {
+			s = strings.Clone(s)
+		}
+		return s, p, 0
+	}
+
+	/* unescape the string, replacing invalid unicodes unless told not to */
+	ep := -1
+	flags := uint64(0)
+	if self.fv&(1<<_F_disable_urc) == 0 {
+		flags |= types.F_UNICODE_REPLACE
+	}
+	buf := make([]byte, len(s))
+	ret := native.Unquote((*rt.GoString)(unsafe.Pointer(&s)).Ptr, len(s), unsafe.Pointer(&buf[0]), &ep, flags)
+	runtime.KeepAlive(s)
+	if ret < 0 {
+		return nil, p - len(s) - 1 + ep, types.ParsingError(-ret)
+	}
+	return rt.Mem2Str(buf[:ret]), p, 0
+}
+
+func (self *_ValueDecoder) number(i int) (interface{}, int, types.ParsingError) {
+	p := i
+	if p < len(self.s) && self.s[p] == '-' {
+		p++
+	}
+
+	/* the integral part, no leading zeros */
+	if p < len(self.s) && self.s[p] == '0' {
+		p++
+	} else if q := self.digits(p); q == p {
+		return nil, p, types.ERR_INVALID_NUMBER_FMT
+	} else {
+		p = q
+	}
+
+	/* the fraction and the exponent */
+	isInt := true
+	if p < len(self.s) && self.s[p] == '.' {
+		isInt = false
+		if q := self.digits(p + 1); q == p+1 {
+			return nil, q, types.ERR_INVALID_NUMBER_FMT
+		} else {
+			p = q
+		}
+	}
+	if p < len(self.s) && (self.s[p] == 'e' || self.s[p] == 'E') {
+		isInt = false
+		if p++; p < len(self.s) && (self.s[p] == '+' || self.s[p] == '-') {
+			p++
+		}
+		if q := self.digits(p); q == p {
+			return nil, q, types.ERR_INVALID_NUMBER_FMT
+		} else {
+			p = q
+		}
+	}
+
+	/* convert as the options tell */
+	raw := self.s[i:p]
+	if self.fv&(1<<_F_use_number) != 0 {
+		return json.Number(raw), p, 0
+	}
+	if isInt && self.fv&(1<<_F_use_int64) != 0 {
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v, p, 0
+		}
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, i, types.ERR_FLOAT_INFINITY
+	}
+	return v, p, 0
+}
+
+func (self *_ValueDecoder) digits(p int) int {
+	for p < len(self.s) && self.s[p] >= '0' && self.s[p] <= '9' {
+		p++
+	}
+	return p
+}
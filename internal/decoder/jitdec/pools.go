@@ -18,11 +18,16 @@ package jitdec
 
 import (
     `sync`
+    `sync/atomic`
     `unsafe`
 
     `github.com/bytedance/sonic/internal/caching`
+    `github.com/bytedance/sonic/internal/decoder/refs`
+    `github.com/bytedance/sonic/internal/jit`
     `github.com/bytedance/sonic/internal/native/types`
+    `github.com/bytedance/sonic/internal/resolver`
     `github.com/bytedance/sonic/internal/rt`
+    `github.com/bytedance/sonic/option`
 )
 
 const (
@@ -33,11 +38,15 @@ const (
 )
 
 const (
-    _PtrBytes   = _PTR_SIZE / 8
-    _FsmOffset  = (_MaxStack + 1) * _PtrBytes
-    _DbufOffset = _FsmOffset + int64(unsafe.Sizeof(types.StateMachine{})) + types.MAX_RECURSE * _PtrBytes
-    _EpOffset   = _DbufOffset + _MaxDigitNums
-    _StackSize  = unsafe.Sizeof(_Stack{})
+    _PtrBytes    = _PTR_SIZE / 8
+    _FsmOffset   = (_MaxStack + 1) * _PtrBytes
+    _DbufOffset  = _FsmOffset + int64(unsafe.Sizeof(types.StateMachine{})) + types.MAX_RECURSE * _PtrBytes
+    _EpOffset    = _DbufOffset + _MaxDigitNums
+    _RnOffset    = _EpOffset + _PtrBytes
+    _StackSize   = unsafe.Sizeof(_Stack{})
+    _HsOffset    = int64(unsafe.Offsetof(_Stack{}.hs))
+    _HsLenOffset = _HsOffset + _PtrBytes
+    _RsOffset    = int64(unsafe.Offsetof(_Stack{}.rs))
 )
 
 var (
@@ -55,6 +64,12 @@ type _Stack struct {
     vp [types.MAX_RECURSE]unsafe.Pointer
     dp [_MaxDigitNums]byte
     ep unsafe.Pointer
+    rn *resolver.NameResolver
+    refs refs.Table
+    dd int
+    hs []unsafe.Pointer
+    rs uintptr
+    md int
 }
 
 type _Decoder func(
@@ -107,9 +122,71 @@ func resetStack(p *_Stack) {
 
 func freeStack(p *_Stack) {
     p.sp = 0
+    p.rn = nil
+    p.refs = nil
+    p.dd = 0
+    p.hs = nil
+    p.rs = 0
+    p.md = 0
     stackPool.Put(p)
 }
 
+// growStack spills the older half of the state stack into the heap when it
+// overflows with OptionGrowStack set, it returns false once the whole depth
+// would exceed option.MaxDecoderStackDepth, or the limit set by setMaxDepth.
+func growStack(p *_Stack) bool {
+    n := _MaxStack / 2
+    if uint(len(p.hs) + _MaxStack) >= option.MaxDecoderStackDepth {
+        return false
+    }
+    if p.md > 0 && len(p.hs) + _MaxStack >= p.md {
+        return false
+    }
+    p.hs = append(p.hs, p.sb[:n]...)
+    copy(p.sb[:], p.sb[n:])
+    for i := _MaxStack - n; i < _MaxStack; i++ {
+        p.sb[i] = nil
+    }
+    p.sp -= uintptr(n * _PtrBytes)
+    p.rs = stackReserve(p)
+    return true
+}
+
+// setMaxDepth limits the state stack to n slots, which is the nesting depth
+// of arrays and objects decoded into typed values, n <= 0 means no limit.
+func setMaxDepth(p *_Stack, n int) {
+    p.md = n
+    p.rs = stackReserve(p)
+}
+
+// stackReserve returns how many bytes at the top of the state stack must be
+// left unused to keep within the depth set by setMaxDepth, taking the spilled
+// slots into account.
+func stackReserve(p *_Stack) uintptr {
+    if p.md <= 0 || p.md - len(p.hs) >= _MaxStack {
+        return 0
+    }
+    return uintptr((_MaxStack - p.md + len(p.hs)) * _PtrBytes)
+}
+
+// shrinkStack moves the most recently spilled slots back from the heap to the
+// bottom of the state stack, before it is drained by the drop instructions.
+func shrinkStack(p *_Stack) {
+    n := len(p.hs)
+    if n > _MaxStack / 2 {
+        n = _MaxStack / 2
+    }
+    m := len(p.hs) - n
+    copy(p.sb[n:], p.sb[:p.sp / _PtrBytes])
+    copy(p.sb[:n], p.hs[m:])
+    for i := m; i < len(p.hs); i++ {
+        p.hs[i] = nil
+    }
+    p.hs = p.hs[:m]
+    p.sp += uintptr(n * _PtrBytes)
+    p.rs = stackReserve(p)
+}
+
 func freezeValue(v unsafe.Pointer) uintptr {
     valueCache = append(valueCache, v)
     return uintptr(v)
@@ -126,20 +203,140 @@ func referenceFields(v *caching.FieldMap) int64 {
     return int64(uintptr(unsafe.Pointer(v)))
 }
 
-func makeDecoder(vt *rt.GoType, _ ...interface{}) (interface{}, error) {
-    if pp, err := newCompiler().compile(vt.Pack()); err != nil {
+// _ProgramKey selects one of the programs compiled for a type: the layouts
+// which its struct and slice bodies take besides the usual ones, as selected by
+// the decoding flags, and what the program decodes.
+type _ProgramKey uint8
+
+const (
+    _PK_struct_as_array _ProgramKey = 1 << iota // structs take arrays as well
+    _PK_object_as_pairs                         // slices of pairs take objects as well
+    _PK_layouts = _PK_struct_as_array | _PK_object_as_pairs
+)
+
+const (
+    _PK_value    _ProgramKey = iota << 2 // the value itself
+    _PK_tuple                            // a struct from an array, see compileStructTuple
+    _PK_pairs                            // a slice of pairs from an object, see compileSlicePairs
+    _PK_required                         // a struct with "required" fields, see _RequiredDecoder
+    _PK_count
+)
+
+var _ProgramNames = [...]string {
+    _PK_value    >> 2 : "",
+    _PK_tuple    >> 2 : "tuple_",
+    _PK_pairs    >> 2 : "pairs_",
+    _PK_required >> 2 : "required_",
+}
+
+// programKey returns the key of the value program for the layouts selected by fv.
+func programKey(fv uint64) _ProgramKey {
+    return _ProgramKey(fv >> _F_struct_as_array & 1) | _ProgramKey(fv >> _F_object_as_pairs & 1) << 1
+}
+
+// _Programs holds the programs compiled for a type, which are compiled on demand.
+type _Programs struct {
+    m  sync.Mutex
+    fn [_PK_count]atomic.Value
+}
+
+func makePrograms(_ *rt.GoType, _ ...interface{}) (interface{}, error) {
+    return new(_Programs), nil
+}
+
+func makeDecoder(vt *rt.GoType, key _ProgramKey) (interface{}, error) {
+    var err error
+    var pp _Program
+
+    /* compile the program selected by key */
+    cc := newCompiler().layout(key)
+    switch key &^ _PK_layouts {
+        case _PK_value    : pp, err = cc.compile(vt.Pack())
+        case _PK_tuple    : pp, err = cc.compileTuple(vt.Pack())
+        case _PK_pairs    : pp, err = cc.compilePairs(vt.Pack())
+        case _PK_required : pp, err = cc.compileRequired(vt.Pack())
+    }
+    if err != nil {
         return nil, err
-    } else {
-        return newAssembler(pp).Load(), nil
     }
+
+    /* structs with "required" fields come with their field map */
+    as := newAssembler(pp)
+    as.name = _ProgramNames[key >> 2] + vt.String()
+    if key &^ _PK_layouts != _PK_required {
+        return as.Load(), nil
+    }
+    fv := resolver.ResolveStruct(vt.Pack())
+    rd := &_RequiredDecoder{fn: as.Load(), fm: caching.CreateFieldMap(len(fv)), nf: len(fv)}
+
+    /* index the fields, and remember the required ones */
+    for i, f := range fv {
+        rd.fm.Set(f.Name, i)
+        if (f.Opts & resolver.F_required) != 0 {
+            rd.req = append(rd.req, f.Name)
+            rd.idx = append(rd.idx, i)
+        }
+    }
+    return rd, nil
 }
 
-func findOrCompile(vt *rt.GoType) (_Decoder, error) {
+// programsOf returns the programs of vt, which may be none compiled yet.
+func programsOf(vt *rt.GoType) *_Programs {
     if val := programCache.Get(vt); val != nil {
-        return val.(_Decoder), nil
-    } else if ret, err := programCache.Compute(vt, makeDecoder); err == nil {
-        return ret.(_Decoder), nil
-    } else {
+        return val.(*_Programs)
+    }
+    val, _ := programCache.Compute(vt, makePrograms)
+    return val.(*_Programs)
+}
+
+// findOrCompile returns the program selected by key, compiling it with compile
+// if it is not compiled yet.
+func (self *_Programs) findOrCompile(key _ProgramKey, compile func() (interface{}, error)) (interface{}, error) {
+    if fn := self.fn[key].Load(); fn != nil {
+        return fn, nil
+    }
+
+    /* double check with the lock held */
+    self.m.Lock()
+    defer self.m.Unlock()
+    if fn := self.fn[key].Load(); fn != nil {
+        return fn, nil
+    }
+
+    /* compile the program */
+    fn, err := compile()
+    if err != nil {
         return nil, err
     }
+    self.fn[key].Store(fn)
+    return fn, nil
+}
+
+// findOrCompile returns the program of vt selected by key, which is a _Decoder,
+// or a *_RequiredDecoder for _PK_required.
+func findOrCompile(vt *rt.GoType, key _ProgramKey) (interface{}, error) {
+    ps := programsOf(vt)
+    if fn := ps.fn[key].Load(); fn != nil {
+        return fn, nil
+    }
+    return ps.findOrCompile(key, func() (interface{}, error) {
+        return makeDecoder(vt, key)
+    })
+}
+
+// GetMemoryUsage returns the bytes of machine code loaded by the JIT, including
+// the one of the encoders, the size of the largest function and the number of
+// functions. It helps to find out how much memory compiling many types costs.
+func GetMemoryUsage() (allocated int64, max int64, count int) {
+    return jit.MemoryUsage()
+}
+
+// _RequiredDecoder decodes a struct which has "required" fields, the field map
+// finds out which fields the keys of the decoded object refer to.
+type _RequiredDecoder struct {
+    fn  _Decoder
+    fm  *caching.FieldMap
+    nf  int
+    req []string
+    idx []int
 }
@@ -19,22 +19,197 @@ package jitdec
 import (
     `encoding`
     `encoding/json`
+    `reflect`
+    `strconv`
+    `strings`
     `unsafe`
 
+    `github.com/bytedance/sonic/internal/decoder/coerce`
+    `github.com/bytedance/sonic/internal/decoder/flextime`
+    `github.com/bytedance/sonic/internal/decoder/refs`
     `github.com/bytedance/sonic/internal/native`
+    `github.com/bytedance/sonic/internal/native/types`
+    `github.com/bytedance/sonic/internal/resolver`
     `github.com/bytedance/sonic/internal/rt`
 )
 
 func decodeTypedPointer(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
-    if fn, err := findOrCompile(vt); err != nil {
+    return decodeProgram(s, i, vt, vp, sb, fv, _PK_value | programKey(fv))
+}
+
+// decodeProgram decodes with the program of vt selected by key, which must be
+// a _Decoder.
+func decodeProgram(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64, key _ProgramKey) (int, error) {
+    if fn, err := findOrCompile(vt, key); err != nil {
         return 0, err
     } else {
         rt.MoreStack(_FP_size + _VD_size + native.MaxFrameSize)
-        ret, err := fn(s, i, vp, sb, fv, "", nil)
+        ret, err := fn.(_Decoder)(s, i, vp, sb, fv, "", nil)
         return ret, err
     }
 }
 
+// decodeDynamic decodes into the value pointed by a non-nil interface, keeping
+// track of the nesting of such values when OptionLimitDynamicDepth is set
+func decodeDynamic(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    if fv & (1 << _F_limit_dynamic_depth) == 0 {
+        return decodeTypedPointer(s, i, vt, vp, sb, fv)
+    }
+    if sb.dd >= _MaxDynamicDepth {
+        return i, error_wrap(s, i, types.ERR_RECURSE_EXCEED_MAX)
+    }
+    sb.dd++
+    ret, err := decodeTypedPointer(s, i, vt, vp, sb, fv)
+    sb.dd--
+    return ret, err
+}
+
+func decodeTypedTuple(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    return decodeProgram(s, i, vt, vp, sb, fv, _PK_tuple | programKey(fv))
+}
+
+func decodeTypedPairs(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    return decodeProgram(s, i, vt, vp, sb, fv, _PK_pairs | programKey(fv))
+}
+
+// decodeRequired decodes the struct, and then reports the first "required"
+// field which is absent in the object.
+func decodeRequired(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    if fn, err := findOrCompile(vt, _PK_required | programKey(fv)); err != nil {
+        return 0, err
+    } else {
+        rd := fn.(*_RequiredDecoder)
+        rt.MoreStack(_FP_size + _VD_size + native.MaxFrameSize)
+        ret, err := rd.fn(s, i, vp, sb, fv, "", nil)
+        if err != nil {
+            return ret, err
+        }
+        return ret, rd.check(s, i, sb, fv)
+    }
+}
+
+// check scans the keys of the object at i, and marks the fields they refer to
+// in a bitmap, the same way as the struct_field instruction matches them.
+func (self *_RequiredDecoder) check(s string, i int, sb *_Stack, fv uint64) error {
+    i = skipSpace(s, i)
+    if i >= len(s) || s[i] != '{' {
+        return nil
+    }
+
+    /* mark every field that has a key */
+    seen := make([]uint64, (self.nf + 63) / 64)
+    for p := skipSpace(s, i + 1); p < len(s) && s[p] == '"'; {
+        k := native.SkipOneFast(&s, &p)
+        if k < 0 {
+            return error_wrap(s, p, types.ParsingError(-k))
+        }
+
+        /* unquote the key if needed */
+        key := s[k + 1:p - 1]
+        if strings.IndexByte(key, '\\') >= 0 {
+            if err := json.Unmarshal([]byte(s[k:p]), &key); err != nil {
+                return err
+            }
+        }
+        if sb.rn != nil {
+            key = sb.rn.Resolve(key)
+        }
+
+        /* match the key with the fields */
+        id := self.fm.Get(key)
+        if id < 0 && (fv & (1 << _F_case_sensitive)) == 0 {
+            id = self.fm.GetCaseInsensitive(key)
+        }
+        if id >= 0 {
+            seen[id / 64] |= 1 << (id % 64)
+        }
+
+        /* skip the value and go to the next key */
+        p = skipSpace(s, p) + 1
+        if v := native.SkipOneFast(&s, &p); v < 0 {
+            return error_wrap(s, p, types.ParsingError(-v))
+        }
+        if p = skipSpace(s, p); p >= len(s) || s[p] != ',' {
+            break
+        }
+        p = skipSpace(s, p + 1)
+    }
+
+    /* all the required fields must be marked */
+    for j, id := range self.idx {
+        if (seen[id / 64] & (1 << (id % 64))) == 0 {
+            return error_missing(self.req[j])
+        }
+    }
+    return nil
+}
+
+func skipSpace(s string, i int) int {
+    for i < len(s) && (types.SPACE_MASK & (1 << s[i])) != 0 {
+        i++
+    }
+    return i
+}
+
+func decodeSetter(s string, i int, sm *resolver.SetterMeta, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    av := reflect.New(sm.Type)
+    ret, err := decodeTypedPointer(s, i, rt.UnpackType(sm.Type), unsafe.Pointer(av.Pointer()), sb, fv)
+    if err != nil {
+        return ret, err
+    }
+    return ret, sm.Call(vp, av.Elem())
+}
+
+func decodeFlexTime(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    p := i
+    if ret := native.SkipOneFast(&s, &p); ret < 0 {
+        return p, error_wrap(s, p, types.ParsingError(-ret))
+    } else if !flextime.Decode(s[ret:p], vt.Pack(), vp) {
+        return p, error_mismatch(s, ret, vt)
+    }
+    return p, nil
+}
+
+// decodeRef points vp to an earlier object if the value at i is `{"$ref":N}`,
+// otherwise it assigns the next reference ID to the object and decodes into it.
+func decodeRef(s string, i int, vt *rt.GoType, vp unsafe.Pointer, sb *_Stack, fv uint64) (int, error) {
+    if i < len(s) && s[i] == '{' {
+        p := i
+        if ret := native.SkipOneFast(&s, &p); ret < 0 {
+            return p, error_wrap(s, p, types.ParsingError(-ret))
+        } else if id, ok := refs.Parse(s[ret:p]); !ok {
+            /* not a reference */
+        } else if ptr := sb.refs.Get(id); ptr == nil {
+            return p, error_value("$ref " + strconv.Itoa(id), vt.Pack())
+        } else {
+            *(*unsafe.Pointer)(vp) = ptr
+            return p, nil
+        }
+    }
+
+    /* allocate the object if needed */
+    ptr := *(*unsafe.Pointer)(vp)
+    if ptr == nil {
+        ptr = rt.Mallocgc(vt.Size, vt, true)
+        *(*unsafe.Pointer)(vp) = ptr
+    }
+    sb.refs.Add(ptr)
+    return decodeTypedPointer(s, i, vt, ptr, sb, fv)
+}
+
+// decodeCoerced returns the end of the value at i if the coercion hook decoded
+// it into vp, or -1 and vt to keep the mismatch.
+func decodeCoerced(vt *rt.GoType, vp unsafe.Pointer, s string, i int) (int, *rt.GoType) {
+    if !coerce.Enabled() {
+        return -1, vt
+    }
+    p := i
+    if ret := native.SkipOneFast(&s, &p); ret < 0 || !coerce.Value(vt.Pack(), vp, s[ret:p]) {
+        return -1, vt
+    }
+    return p, vt
+}
+
 func decodeJsonUnmarshaler(vv interface{}, s string) error {
     return vv.(json.Unmarshaler).UnmarshalJSON(rt.Str2Mem(s))
 }
@@ -43,6 +43,7 @@ var (
     stringType              = reflect.TypeOf("")
     bytesType               = reflect.TypeOf([]byte(nil))
     jsonNumberType          = reflect.TypeOf(json.Number(""))
+    jsonRawMessageType      = reflect.TypeOf(json.RawMessage(nil))
     base64CorruptInputError = reflect.TypeOf(base64.CorruptInputError(0))
 )
 
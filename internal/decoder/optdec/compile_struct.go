@@ -139,8 +139,8 @@ func (c *compiler) compileStruct(vt reflect.Type) decFunc {
 }
 
 func (c *compiler) compileStructBody(vt reflect.Type) decFunc {
-	fv := resolver.ResolveStruct(vt)
-	entries := make([]fieldEntry, 0, len(fv))
+	fv, sv := resolver.ResolveStructWithSetters(c.reg, vt)
+	entries := make([]fieldEntry, 0, len(fv) + len(sv))
 
 	for _, f := range fv {
 		var dec decFunc
@@ -165,9 +165,40 @@ func (c *compiler) compileStructBody(vt reflect.Type) decFunc {
 			fieldDec:  dec,
 		})
 	}
+
+	/* only the fields take the elements of arrays */
+	ntuple := len(entries)
+
+	/* keys mapped to setters are decoded against the struct itself */
+	for _, s := range sv {
+		f := resolver.FieldMeta{
+			Name: s.Name,
+			Path: []resolver.Offset{{Kind: resolver.F_offset, Type: vt}},
+			Type: s.Type,
+		}
+		fv = append(fv, f)
+		entries = append(entries, fieldEntry{
+			FieldMeta: f,
+			fieldDec:  &setterDecoder{
+				setter: s,
+				valDec: c.compile(s.Type),
+			},
+		})
+	}
+
+	/* fields with the "required" option must be present in the object */
+	var required []int
+	for i, f := range fv {
+		if f.Opts&resolver.F_required != 0 {
+			required = append(required, i)
+		}
+	}
+
 	return &structDecoder{
 		fieldMap:  	caching.NewFieldLookup(fv),
 		fields:     entries,
+		ntuple:     ntuple,
+		required:   required,
 		structName: vt.Name(),
 		typ: 		vt,
 	}
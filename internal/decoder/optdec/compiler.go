@@ -7,6 +7,9 @@ import (
 	"github.com/bytedance/sonic/option"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/internal/caching"
+	"github.com/bytedance/sonic/internal/decoder/flextime"
+	"github.com/bytedance/sonic/internal/registry"
+	"github.com/bytedance/sonic/internal/resolver"
 )
 
 var (
@@ -33,12 +36,14 @@ type compiler struct {
 	counts  int
 	opts 	option.CompileOptions
 	namedPtr bool
+	reg     *registry.Snapshot
 }
 
 func newCompiler() *compiler {
 	return &compiler{
 		visited: make(map[reflect.Type]bool),
 		opts:  option.DefaultCompileOptions(),
+		reg:   registry.Load(),
 	}
 }
 
@@ -113,13 +118,22 @@ func (c *compiler) compile(vt reflect.Type) decFunc {
 	}
 
 	dec := c.tryCompilePtrUnmarshaler(vt, false)
-	if dec != nil {
-		return dec
+	if dec == nil {
+		dec = c.compileBasic(vt)
 	}
 
-	return c.compileBasic(vt)
+	/* timestamps and durations may be parsed in several formats at runtime */
+	if flextime.IsTimeType(vt) {
+		return &flexTimeDecoder{
+			typ: vt,
+			dec: dec,
+		}
+	}
+	return dec
 }
 
+// compileBasic dispatches on the kind rather than the type, so defined types
+// and aliases (eg. `type ID int64`) are decoded like their underlying types.
 func (c *compiler) compileBasic(vt reflect.Type) decFunc {
 	defer func() {
 		c.counts += 1
@@ -268,11 +282,21 @@ func (c *compiler) compileSlice(vt reflect.Type) decFunc {
 		return &sliceStringDecoder{}
 	}
 
-	return &sliceDecoder{
+	sd := sliceDecoder{
 		elemType: rt.UnpackType(vt.Elem()),
 		elemDec:  c.compile(vt.Elem()),
 		typ: vt,
 	}
+	if kf, vf, ok := resolver.ResolvePair(vt.Elem()); ok {
+		return &slicePairsDecoder{
+			sliceDecoder: sd,
+			keyOff: kf.Path[0].Size,
+			keyDec: c.compile(kf.Type),
+			valOff: vf.Path[0].Size,
+			valDec: c.compile(vf.Type),
+		}
+	}
+	return &sd
 }
 
 func (c *compiler) compileSliceBytes(vt reflect.Type) decFunc {
@@ -433,6 +457,11 @@ func (c *compiler) compileMapKey(vt reflect.Type) decKey {
 func (c *compiler) tryCompilePtrUnmarshaler(vt reflect.Type, strOpt bool) decFunc {
 	pt := reflect.PtrTo(vt)
 
+	/* `json.RawMessage` takes the raw value without calling the unmarshaler */
+	if vt == jsonRawMessageType && !strOpt {
+		return &rawMessageDecoder{}
+	}
+
 	/* check for `json.Unmarshaler` with pointer receiver */
 	if pt.Implements(jsonUnmarshalerType) {
 		return &unmarshalJSONDecoder{
@@ -5,8 +5,10 @@ import (
 	"unsafe"
 
 	"encoding/json"
+	"github.com/bytedance/sonic/internal/resolver"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/option"
+	"github.com/bytedance/sonic/utf8"
 	"github.com/bytedance/sonic/internal/decoder/errors"
 	"github.com/bytedance/sonic/internal/decoder/consts"
 )
@@ -19,12 +21,16 @@ type (
 
 const (
 	_F_allow_control = consts.F_allow_control
+	_F_bool_as_int = consts.F_bool_as_int
+	_F_struct_as_array = consts.F_struct_as_array
+	_F_object_as_pairs = consts.F_object_as_pairs
 	_F_copy_string = consts.F_copy_string
 	_F_disable_unknown = consts.F_disable_unknown
 	_F_disable_urc = consts.F_disable_urc
 	_F_use_int64 = consts.F_use_int64
 	_F_use_number = consts.F_use_number
 	_F_validate_string = consts.F_validate_string
+	_F_limit_dynamic_depth = consts.F_limit_dynamic_depth
 )
 
 type Options = consts.Options
@@ -36,10 +42,19 @@ const (
 	OptionDisableUnknown = consts.OptionDisableUnknown
 	OptionCopyString = consts.OptionCopyString
 	OptionValidateString = consts.OptionValidateString
+	OptionLimitKeyLength = consts.OptionLimitKeyLength
+	OptionLimitDynamicDepth = consts.OptionLimitDynamicDepth
 )
 
 
 func Decode(s *string, i *int, f uint64, val interface{}) error {
+	return DecodeWithResolver(s, i, f, val, nil, 0)
+}
+
+// DecodeWithResolver is like Decode, but object keys are mapped by rn
+// before being matched against the struct fields, and arrays and objects
+// can not be nested deeper than maxDepth if it is positive.
+func DecodeWithResolver(s *string, i *int, f uint64, val interface{}, rn *resolver.NameResolver, maxDepth int) error {
 	vv := rt.UnpackEface(val)
 	vp := vv.Value
 
@@ -67,8 +82,16 @@ func Decode(s *string, i *int, f uint64, val interface{}) error {
 		return err
 	}
 
+	/* report invalid UTF-8 instead of replacing it, when asked to */
+	if f&(1<<_F_validate_string) != 0 && f&(1<<_F_disable_urc) != 0 && !utf8.ValidateString((*s)[*i:]) {
+		if err := errors.ErrorInvalidUTF8(*s, *i); err != nil {
+			return err
+		}
+	}
+
 	/* parse into document */
 	ctx, err := NewContext(*s, *i, uint64(f), etp)
+	ctx.Resolver = rn
 	defer ctx.Delete()
 	if ctx.Parser.Utf8Inv {
 		*s = ctx.Parser.Json
@@ -76,6 +99,10 @@ func Decode(s *string, i *int, f uint64, val interface{}) error {
 	if err != nil {
 		goto fix_error;
 	}
+	if maxDepth > 0 && int(ctx.Parser.nbuf.stat.max_depth) > maxDepth {
+		err = stackOverflow
+		goto fix_error;
+	}
 	err = dec.FromDom(vp, ctx.Root(), &ctx)
 
 fix_error:
@@ -91,6 +118,7 @@ func fix_error(json string, pos int, err error) error {
 		return SyntaxError{
 			Pos: int(e.Pos) + pos,
 			Src: json,
+			Code: e.Code,
 			Msg: e.Msg,
 		}
 	}
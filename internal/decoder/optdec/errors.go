@@ -21,7 +21,10 @@
 	 "errors"
 	 "reflect"
 	 "strconv"
+	 "unsafe"
  
+	 "github.com/bytedance/sonic/internal/decoder/coerce"
+	 "github.com/bytedance/sonic/internal/native/types"
 	 "github.com/bytedance/sonic/internal/rt"
  )
 
@@ -38,12 +41,20 @@
  
  func error_mismatch(node Node, ctx *context, typ reflect.Type) error {
 	 return MismatchTypeError{
-		 Pos:  node.Position(),
+		 Pos:  node.Start(),
 		 Src:  ctx.Parser.Json,
 		 Type: typ,
 	 }
  }
  
+ // error_coerce reports the mismatch, unless the coercion hook decodes the node into vp.
+ func error_coerce(vp unsafe.Pointer, node Node, ctx *context, typ reflect.Type) error {
+	 if coerce.Value(typ, vp, node.AsRaw(ctx)) {
+		 return nil
+	 }
+	 return error_mismatch(node, ctx, typ)
+ }
+ 
  func newUnmatched(pos int, vt *rt.GoType) error {
 	 return MismatchTypeError{
 		Pos:  pos,
@@ -56,6 +67,26 @@
 	 return errors.New("json: unknown field " + strconv.Quote(name))
  }
  
+ func error_missing(name string) error {
+	 return errors.New("json: missing required field " + strconv.Quote(name))
+ }
+
+ func error_key_length(pos int, src string) error {
+	 return SyntaxError{
+		 Pos:  pos,
+		 Src:  src,
+		 Code: types.ERR_KEY_TOO_LONG,
+	 }
+ }
+
+ func error_recurse(pos int, src string) error {
+	 return SyntaxError{
+		 Pos:  pos,
+		 Src:  src,
+		 Code: types.ERR_RECURSE_EXCEED_MAX,
+	 }
+ }
+
  func error_value(value string, vtype reflect.Type) error {
 	 return &json.UnmarshalTypeError{
 		 Type:  vtype,
@@ -3,8 +3,13 @@ package optdec
 import (
 	"encoding/json"
 	"math"
+	"reflect"
+	"strconv"
 	"unsafe"
 
+	"github.com/bytedance/sonic/internal/decoder/consts"
+	"github.com/bytedance/sonic/internal/decoder/flextime"
+	"github.com/bytedance/sonic/internal/decoder/refs"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/internal/resolver"
 )
@@ -25,9 +30,24 @@ func (d *ptrDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 		return nil
 	}
 
+	useRefs := d.typ.Kind() == reflect.Struct && ctx.Options()&uint64(consts.OptionPointerRefs) != 0
+	if useRefs && node.Type() == KObject {
+		if id, ok := refs.Parse(node.AsRaw(ctx)); ok {
+			ptr := ctx.Refs.Get(id)
+			if ptr == nil {
+				return error_value("$ref " + strconv.Itoa(id), d.typ.Pack())
+			}
+			*(*unsafe.Pointer)(vp) = ptr
+			return nil
+		}
+	}
+
 	if *(*unsafe.Pointer)(vp) == nil {
 		*(*unsafe.Pointer)(vp) = rt.Mallocgc(d.typ.Size, d.typ, true)
 	}
+	if useRefs {
+		ctx.Refs.Add(*(*unsafe.Pointer)(vp))
+	}
 
 	return d.deref.FromDom(*(*unsafe.Pointer)(vp), node, ctx)
 }
@@ -59,6 +79,34 @@ func (d *embeddedFieldPtrDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *con
 	return d.fieldDec.FromDom(vp, node, ctx)
 }
 
+type setterDecoder struct {
+	setter *resolver.SetterMeta
+	valDec decFunc
+}
+
+func (d *setterDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
+	av := reflect.New(d.setter.Type)
+	if err := d.valDec.FromDom(unsafe.Pointer(av.Pointer()), node, ctx); err != nil {
+		return err
+	}
+	return d.setter.Call(vp, av.Elem())
+}
+
+type flexTimeDecoder struct {
+	typ reflect.Type
+	dec decFunc
+}
+
+func (d *flexTimeDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
+	if ctx.Options()&uint64(consts.OptionFlexibleTime) == 0 {
+		return d.dec.FromDom(vp, node, ctx)
+	}
+	if !flextime.Decode(node.AsRaw(ctx), d.typ, vp) {
+		return error_mismatch(node, ctx, d.typ)
+	}
+	return nil
+}
+
 type i8Decoder struct{}
 
 func (d *i8Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
@@ -68,7 +116,7 @@ func (d *i8Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsI64(ctx)
 	if !ok ||  ret > math.MaxInt8 || ret < math.MinInt8 {
-		return error_mismatch(node, ctx, int8Type)
+		return error_coerce(vp, node, ctx, int8Type)
 	}
 
 	*(*int8)(vp) = int8(ret)
@@ -84,7 +132,7 @@ func (d *i16Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsI64(ctx)
 	if !ok || ret > math.MaxInt16 || ret < math.MinInt16 {
-		return error_mismatch(node, ctx, int16Type)
+		return error_coerce(vp, node, ctx, int16Type)
 	}
 
 	*(*int16)(vp) = int16(ret)
@@ -100,7 +148,7 @@ func (d *i32Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsI64(ctx)
 	if !ok ||  ret > math.MaxInt32 || ret < math.MinInt32 {
-		return error_mismatch(node, ctx, int32Type)
+		return error_coerce(vp, node, ctx, int32Type)
 	}
 
 	*(*int32)(vp) = int32(ret)
@@ -116,7 +164,7 @@ func (d *i64Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsI64(ctx)
 	if !ok  {
-		return error_mismatch(node, ctx, int64Type)
+		return error_coerce(vp, node, ctx, int64Type)
 	}
 
 	*(*int64)(vp) = int64(ret)
@@ -132,7 +180,7 @@ func (d *u8Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsU64(ctx)
 	if !ok || ret > math.MaxUint8 {
-		err := error_mismatch(node, ctx, uint8Type)
+		err := error_coerce(vp, node, ctx, uint8Type)
 		return err
 	}
 
@@ -149,7 +197,7 @@ func (d *u16Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsU64(ctx)
 	if !ok || ret > math.MaxUint16 {
-		return error_mismatch(node, ctx, uint16Type)
+		return error_coerce(vp, node, ctx, uint16Type)
 	}
 	*(*uint16)(vp) = uint16(ret)
 	return nil
@@ -164,7 +212,7 @@ func (d *u32Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsU64(ctx)
 	if !ok || ret > math.MaxUint32 {
-		return error_mismatch(node, ctx, uint32Type)
+		return error_coerce(vp, node, ctx, uint32Type)
 	}
 
 	*(*uint32)(vp) = uint32(ret)
@@ -180,7 +228,7 @@ func (d *u64Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsU64(ctx)
 	if !ok {
-		return error_mismatch(node, ctx, uint64Type)
+		return error_coerce(vp, node, ctx, uint64Type)
 	}
 
 	*(*uint64)(vp) = uint64(ret)
@@ -196,7 +244,7 @@ func (d *f32Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsF64(ctx)
 	if !ok || ret > math.MaxFloat32 || ret < -math.MaxFloat32 {
-		return error_mismatch(node, ctx, float32Type)
+		return error_coerce(vp, node, ctx, float32Type)
 	}
 
 	*(*float32)(vp) = float32(ret)
@@ -212,7 +260,7 @@ func (d *f64Decoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
 
 	ret, ok := node.AsF64(ctx)
 	if !ok {
-		return  error_mismatch(node, ctx, float64Type)
+		return  error_coerce(vp, node, ctx, float64Type)
 	}
 
 	*(*float64)(vp) = float64(ret)
@@ -229,7 +277,7 @@ func (d *boolDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error
 
 	ret, ok := node.AsBool()
 	if !ok {
-		return error_mismatch(node, ctx, boolType)
+		return error_coerce(vp, node, ctx, boolType)
 	}
 
 	*(*bool)(vp) = bool(ret)
@@ -262,7 +310,7 @@ func (d *numberDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) erro
 
 	num, ok := node.AsNumber(ctx)
 	if !ok {
-		return error_mismatch(node, ctx, jsonNumberType)
+		return error_coerce(vp, node, ctx, jsonNumberType)
 	}
 	*(*json.Number)(vp) = num
 	return nil
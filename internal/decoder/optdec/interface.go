@@ -6,6 +6,7 @@ import (
 	"unsafe"
 	"reflect"
 
+	"github.com/bytedance/sonic/internal/decoder/consts"
 	"github.com/bytedance/sonic/internal/rt"
 )
 
@@ -58,7 +59,22 @@ func (d *efaceDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error
 		return err
 	}
 
-	return dec.FromDom(vp, node, ctx)
+	return fromDomDynamic(dec, vp, node, ctx)
+}
+
+// fromDomDynamic decodes node into the value pointed by a non-nil interface,
+// keeping track of the nesting of such values when OptionLimitDynamicDepth is set
+func fromDomDynamic(dec decFunc, vp unsafe.Pointer, node Node, ctx *context) error {
+	if ctx.Options()&(1<<_F_limit_dynamic_depth) == 0 {
+		return dec.FromDom(vp, node, ctx)
+	}
+	if ctx.DynDepth >= consts.MaxDynamicDepth {
+		return error_recurse(node.Position(), ctx.Parser.Json)
+	}
+	ctx.DynDepth++
+	err := dec.FromDom(vp, node, ctx)
+	ctx.DynDepth--
+	return err
 }
 
 type ifaceDecoder struct {
@@ -96,7 +112,7 @@ func (d *ifaceDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error
 		return err
 	}
 
-	return dec.FromDom(vp, node, ctx)
+	return fromDomDynamic(dec, vp, node, ctx)
 }
 
 type unmarshalTextDecoder struct {
@@ -133,6 +149,20 @@ func (d *unmarshalTextDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *contex
 	return error_type(d.typ)
 }
 
+// rawMessageDecoder decodes json.RawMessage like the JIT decoder does, which
+// refers to the input instead of copying it, unless OptionCopyString is set.
+type rawMessageDecoder struct{}
+
+func (d *rawMessageDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
+	raw := node.AsRaw(ctx)
+	if ctx.Options() & (1 << _F_copy_string) != 0 {
+		*(*[]byte)(vp) = []byte(raw)
+	} else {
+		*(*[]byte)(vp) = rt.Str2Mem(raw)
+	}
+	return nil
+}
+
 type unmarshalJSONDecoder struct {
 	typ 	*rt.GoType
 	strOpt	bool
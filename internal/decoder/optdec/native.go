@@ -262,6 +262,22 @@ func (p *Parser) fixError(code ErrorCode) error {
 	return error_syntax(pos, p.Json, ParsingErrors[code])
 }
 
+// atDelim reports whether the parser stops at the end of the JSON or
+// at a character which may follow a value.
+func (p *Parser) atDelim() bool {
+	src := p.JsonBytes()
+	if !p.Utf8Inv {
+		src = src[:len(src) - len(padding)]
+	}
+	if pos := p.Pos(); pos < len(src) {
+		switch src[pos] {
+			case ' ', '\t', '\n', '\r', ',', ']', '}': return true
+			default: return false
+		}
+	}
+	return true
+}
+
 func Parse(data string, opt uint64) error {
 	p := newParser(data, 0, opt)
 	err := p.parse()
@@ -5,7 +5,9 @@ import (
 	"math"
 	"unsafe"
 
+	"github.com/bytedance/sonic/internal/decoder/refs"
 	"github.com/bytedance/sonic/internal/envs"
+	"github.com/bytedance/sonic/internal/resolver"
 	"github.com/bytedance/sonic/internal/rt"
 )
 
@@ -14,6 +16,9 @@ type Context struct {
 	efacePool   *efacePool
 	Stack       boundedStack
 	Utf8Inv     bool
+	Resolver    *resolver.NameResolver
+	Refs        refs.Table
+	DynDepth    int
 }
 
 func (ctx *Context) Options() uint64 {
@@ -128,6 +133,11 @@ func NewContext(json string, pos int, opts uint64, root *rt.GoType) (Context, er
 		return ctx, ctx.Parser.fixError(ecode)
 	}
 
+	/* the parser stops right after a number at the top level */
+	if ctx.Root().Type() & 0x7 == KNumber && !ctx.Parser.atDelim() {
+		return ctx, error_syntax(ctx.Parser.Pos(), ctx.Parser.Json, ParsingErrors[SONIC_INVALID_CHAR])
+	}
+
 	useNumber := (opts & (1 << _F_use_number )) != 0
 	if canUseFastMap(opts, root) {
 		ctx.efacePool = newEfacePool(&ctx.Parser.nbuf.stat, useNumber)
@@ -252,8 +262,20 @@ func (self Node) AsU64(ctx *Context) (uint64, bool) {
 		}
 		return num, true
 	} else {
+		return self.boolAsInt(ctx)
+	}
+}
+
+// boolAsInt converts `true`/`false` into 1/0 when OptionBoolAsInt is set.
+func (self Node) boolAsInt(ctx *Context) (uint64, bool) {
+	if ctx.Options() & (1 << _F_bool_as_int) == 0 {
 		return 0, false
 	}
+	switch self.Type() {
+		case KTrue: return 1, true
+		case KFalse: return 0, true
+		default: return 0, false
+	}
 }
 
 func (val *Node) AsObj() (Object, bool) {
@@ -297,7 +319,8 @@ func (self Node) AsI64(ctx *Context) (int64, bool) {
 		}
 		return val, true
 	} else {
-		return 0, false
+		val, ok := self.boolAsInt(ctx)
+		return int64(val), ok
 	}
 }
 
@@ -502,6 +525,15 @@ func (val Node) Position() int {
 	return int(node.typ >> PosBits)
 }
 
+// Start returns the offset where the value begins in the input. It differs
+// from Position only for strings, whose position points past the opening quote.
+func (val Node) Start() int {
+	if val.IsStr() {
+		return val.Position() - 1
+	}
+	return val.Position()
+}
+
 func (val Node) AsNumber(ctx *Context) (json.Number, bool) {
 	// parse JSON string as number
 	if val.IsStr() {
@@ -615,7 +647,7 @@ func (node *Node) AsMapEface(ctx *Context, vp unsafe.Pointer) error {
 
 	obj, ok := node.AsObj()
 	if !ok {
-		return newUnmatched(node.Position(), rt.MapEfaceType)
+		return newUnmatched(node.Start(), rt.MapEfaceType)
 	}
 
 	var err, gerr error
@@ -652,7 +684,7 @@ func (node *Node) AsMapEface(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsMapString(ctx *Context, vp unsafe.Pointer) error {
 	obj, ok := node.AsObj()
 	if !ok {
-		return newUnmatched(node.Position(), rt.MapStringType)
+		return newUnmatched(node.Start(), rt.MapStringType)
 	}
 
 	size := obj.Len()
@@ -673,7 +705,7 @@ func (node *Node) AsMapString(ctx *Context, vp unsafe.Pointer) error {
 		m[key], ok = val.AsStr(ctx)
 		if !ok {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.StringType)
+				gerr = newUnmatched(val.Start(), rt.StringType)
 			}
 			next = val.Next()
 		} else {
@@ -688,7 +720,7 @@ func (node *Node) AsMapString(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceEface(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceEfaceType)
+		return newUnmatched(node.Start(), rt.SliceEfaceType)
 	}
 
 	size := arr.Len()
@@ -721,7 +753,7 @@ func (node *Node) AsSliceEface(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceI32(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceI32Type)
+		return newUnmatched(node.Start(), rt.SliceI32Type)
 	}
 
 	size := arr.Len()
@@ -734,7 +766,7 @@ func (node *Node) AsSliceI32(ctx *Context, vp unsafe.Pointer) error {
 		ret, ok := val.AsI64(ctx)
 		if !ok || ret > math.MaxInt32 || ret < math.MinInt32 {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.Int32Type)
+				gerr = newUnmatched(val.Start(), rt.Int32Type)
 			}
 			next = val.Next()
 		} else {
@@ -750,7 +782,7 @@ func (node *Node) AsSliceI32(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceI64(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceI64Type)
+		return newUnmatched(node.Start(), rt.SliceI64Type)
 	}
 
 	size := arr.Len()
@@ -764,7 +796,7 @@ func (node *Node) AsSliceI64(ctx *Context, vp unsafe.Pointer) error {
 		ret, ok := val.AsI64(ctx)
 		if !ok {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.Int64Type)
+				gerr = newUnmatched(val.Start(), rt.Int64Type)
 			}
 			next = val.Next()
 		} else {
@@ -780,7 +812,7 @@ func (node *Node) AsSliceI64(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceU32(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceU32Type)
+		return newUnmatched(node.Start(), rt.SliceU32Type)
 	}
 
 	size := arr.Len()
@@ -793,7 +825,7 @@ func (node *Node) AsSliceU32(ctx *Context, vp unsafe.Pointer) error {
 		ret, ok := val.AsU64(ctx)
 		if !ok ||  ret > math.MaxUint32 {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.Uint32Type)
+				gerr = newUnmatched(val.Start(), rt.Uint32Type)
 			}
 			next = val.Next()
 		} else {
@@ -809,7 +841,7 @@ func (node *Node) AsSliceU32(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceU64(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceU64Type)
+		return newUnmatched(node.Start(), rt.SliceU64Type)
 	}
 
 	size := arr.Len()
@@ -822,7 +854,7 @@ func (node *Node) AsSliceU64(ctx *Context, vp unsafe.Pointer) error {
 		ret, ok := val.AsU64(ctx)
 		if !ok {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.Uint64Type)
+				gerr = newUnmatched(val.Start(), rt.Uint64Type)
 			}
 			next = val.Next()
 		} else {
@@ -838,7 +870,7 @@ func (node *Node) AsSliceU64(ctx *Context, vp unsafe.Pointer) error {
 func (node *Node) AsSliceString(ctx *Context, vp unsafe.Pointer) error {
 	arr, ok := node.AsArr()
 	if !ok {
-		return newUnmatched(node.Position(), rt.SliceStringType)
+		return newUnmatched(node.Start(), rt.SliceStringType)
 	}
 
 	size := arr.Len()
@@ -851,7 +883,7 @@ func (node *Node) AsSliceString(ctx *Context, vp unsafe.Pointer) error {
 		ret, ok := val.AsStr(ctx)
 		if !ok {
 			if gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.StringType)
+				gerr = newUnmatched(val.Start(), rt.StringType)
 			}
 			next = val.Next()
 		} else {
@@ -884,18 +916,18 @@ func (val *Node) AsSliceBytes(ctx *Context) ([]byte, error) {
 		for i := 0; i < size; i++ {
 			a[i], ok = elem.AsByte(ctx)
 			if !ok && gerr == nil {
-				gerr = newUnmatched(val.Position(), rt.BytesType)
+				gerr = newUnmatched(val.Start(), rt.BytesType)
 			}
 			elem = NewNode(PtrOffset(elem.cptr, 1))
 		}
 		return a, gerr
 	default:
-		return nil,  newUnmatched(val.Position(), rt.BytesType)
+		return nil,  newUnmatched(val.Start(), rt.BytesType)
 	}
 	
 	b64, err := rt.DecodeBase64(origin)
 	if err != nil {
-		return nil, newUnmatched(val.Position(), rt.BytesType)
+		return nil, newUnmatched(val.Start(), rt.BytesType)
 	}
 	return b64, nil
 }
@@ -1255,7 +1287,7 @@ func (node *Node) AsEfaceFallback(ctx *Context) (interface{}, error) {
 			if !ok {
 				// skip the unmatched type
 				*node = NewNode(node.Next())
-				return nil, newUnmatched(node.Position(), rt.JsonNumberType)
+				return nil, newUnmatched(node.Start(), rt.JsonNumberType)
 			} else {
 				*node = NewNode(PtrOffset(node.cptr, 1))
 				return num, nil
@@ -1277,13 +1309,13 @@ func (node *Node) AsEfaceFallback(ctx *Context) (interface{}, error) {
 		
 			// skip the unmatched type
 			*node = NewNode(node.Next())
-			return nil, newUnmatched(node.Position(), rt.Int64Type)
+			return nil, newUnmatched(node.Start(), rt.Int64Type)
 		} else {
 			num, ok := node.AsF64(ctx)
 			if !ok {
 				// skip the unmatched type
 				*node = NewNode(node.Next())
-				return nil, newUnmatched(node.Position(), rt.Float64Type)
+				return nil, newUnmatched(node.Start(), rt.Float64Type)
 			} else {
 				*node = NewNode(PtrOffset(node.cptr, 1))
 				return num, nil
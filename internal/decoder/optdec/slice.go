@@ -47,6 +47,46 @@ func (d *sliceDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error
 	return gerr
 }
 
+/** Decoder for slices of pairs, which also take objects when OptionObjectAsPairs is set **/
+
+type slicePairsDecoder struct {
+	sliceDecoder
+	keyOff uintptr
+	keyDec decFunc
+	valOff uintptr
+	valDec decFunc
+}
+
+func (d *slicePairsDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) error {
+	obj, ok := node.AsObj()
+	if !ok || ctx.Options()&(1<<_F_object_as_pairs) == 0 {
+		return d.sliceDecoder.FromDom(vp, node, ctx)
+	}
+
+	slice := rt.MakeSlice(vp, d.elemType, obj.Len())
+	elems := slice.Ptr
+	next := obj.Children()
+
+	var gerr error
+	for i := 0; i < obj.Len(); i++ {
+		keyn := NewNode(next)
+		valn := NewNode(PtrOffset(next, 1))
+		elem := unsafe.Pointer(uintptr(elems) + uintptr(i)*d.elemType.Size)
+		err := d.keyDec.FromDom(unsafe.Pointer(uintptr(elem)+d.keyOff), keyn, ctx)
+		if gerr == nil && err != nil {
+			gerr = err
+		}
+		err = d.valDec.FromDom(unsafe.Pointer(uintptr(elem)+d.valOff), valn, ctx)
+		if gerr == nil && err != nil {
+			gerr = err
+		}
+		next = valn.Next()
+	}
+
+	*(*rt.GoSlice)(vp) = *slice
+	return gerr
+}
+
 type arrayDecoder struct {
 	len      int
 	elemType *rt.GoType
@@ -17,6 +17,8 @@ type fieldEntry struct {
 type structDecoder struct {
 	fieldMap   caching.FieldLookup
 	fields     []fieldEntry
+	ntuple     int
+	required   []int
 	structName string
 	typ        reflect.Type
 }
@@ -29,15 +31,35 @@ func (d *structDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) erro
 	var gerr error
 	obj, ok := node.AsObj()
 	if !ok {
+		if arr, ok := node.AsArr(); ok && ctx.Options()&(1<<_F_struct_as_array) != 0 {
+			return d.fromTuple(vp, arr, ctx)
+		}
 		return error_mismatch(node, ctx, d.typ)
 	}
 
+	// bitmap of the fields present in the object
+	var seen []uint64
+	if len(d.required) > 0 {
+		seen = make([]uint64, (len(d.fields)+63)/64)
+	}
+
 	next := obj.Children()
 	for i := 0; i < obj.Len(); i++ {
-		key, _ := NewNode(next).AsStrRef(ctx)
+		knode := NewNode(next)
+		key, _ := knode.AsStrRef(ctx)
 		val := NewNode(PtrOffset(next, 1))
 		next = val.Next()
 
+		// reject overlong keys before hashing them
+		if len(key) > consts.MaxKeyLength && Options(ctx.Options())&OptionLimitKeyLength != 0 {
+			return error_key_length(knode.Position(), ctx.Parser.Json)
+		}
+
+		// map the key to the field name if needed
+		if ctx.Resolver != nil {
+			key = ctx.Resolver.Resolve(key)
+		}
+
 		// find field idx
 		idx := d.fieldMap.Get(key, ctx.Options()&uint64(consts.OptionCaseSensitive) != 0)
         if idx == -1 {
@@ -46,6 +68,9 @@ func (d *structDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) erro
             }
             continue
         }
+		if seen != nil {
+			seen[idx/64] |= 1 << (idx % 64)
+		}
 
 		offset := d.fields[idx].Path[0].Size
 		elem := unsafe.Pointer(uintptr(vp) + offset)
@@ -57,6 +82,33 @@ func (d *structDecoder) FromDom(vp unsafe.Pointer, node Node, ctx *context) erro
 			gerr = err
 		}
 	}
-	return gerr
+	if gerr != nil {
+		return gerr
+	}
+
+	// check the fields with the "required" option
+	for _, idx := range d.required {
+		if seen[idx/64]&(1<<(idx%64)) == 0 {
+			return error_missing(d.fields[idx].Name)
+		}
+	}
+	return nil
 }
 
+// fromTuple decodes the elements of arr into the fields positionally when
+// OptionStructAsArray is set, the extra elements are skipped.
+func (d *structDecoder) fromTuple(vp unsafe.Pointer, arr Array, ctx *context) error {
+	var gerr error
+	next := arr.Children()
+	for i := 0; i < arr.Len() && i < d.ntuple; i++ {
+		val := NewNode(next)
+		offset := d.fields[i].Path[0].Size
+		elem := unsafe.Pointer(uintptr(vp) + offset)
+		err := d.fields[i].fieldDec.FromDom(elem, val, ctx)
+		if gerr == nil && err != nil {
+			gerr = err
+		}
+		next = val.Next()
+	}
+	return gerr
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package refs resolves the `{"$ref":N}` objects written by the PointerRefs
+// encoder option, it backs the OptionPointerRefs decoder option.
+package refs
+
+import (
+    `strconv`
+    `strings`
+    `unsafe`
+)
+
+// Table records the struct pointers created while decoding, the N-th entry
+// is referred to as `{"$ref":N}`.
+type Table []unsafe.Pointer
+
+// Add assigns the next reference ID to p.
+func (self *Table) Add(p unsafe.Pointer) {
+    *self = append(*self, p)
+}
+
+// Get returns the pointer with the reference ID id, or nil if there is none.
+func (self Table) Get(id int) unsafe.Pointer {
+    if id < 1 || id > len(self) {
+        return nil
+    }
+    return self[id - 1]
+}
+
+// Parse returns N if the raw JSON value src is exactly `{"$ref":N}`,
+// ignoring whitespaces.
+func Parse(src string) (int, bool) {
+    i := skipSpace(src, 0)
+    if i >= len(src) || src[i] != '{' {
+        return 0, false
+    }
+    i = skipSpace(src, i + 1)
+    if !strings.HasPrefix(src[i:], `"$ref"`) {
+        return 0, false
+    }
+    i = skipSpace(src, i + 6)
+    if i >= len(src) || src[i] != ':' {
+        return 0, false
+    }
+    i = skipSpace(src, i + 1)
+    j := i
+    for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+        j++
+    }
+    id, err := strconv.Atoi(src[i:j])
+    if err != nil {
+        return 0, false
+    }
+    j = skipSpace(src, j)
+    if j != len(src) - 1 || src[j] != '}' {
+        return 0, false
+    }
+    return id, true
+}
+
+func skipSpace(src string, i int) int {
+    for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n' || src[i] == '\r') {
+        i++
+    }
+    return i
+}
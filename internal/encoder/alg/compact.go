@@ -0,0 +1,61 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+import (
+    `bytes`
+)
+
+// Compact appends to dst the valid JSON src without the insignificant spaces,
+// the same way as json.Compact, without checking it again.
+func Compact(dst []byte, src []byte) []byte {
+    s := 0
+    for i := 0; i < len(src); {
+        switch src[i] {
+        case '"':
+            i = skipQuoted(src, i + 1)
+        case ' ', '\t', '\n', '\r':
+            dst = append(dst, src[s:i]...)
+            i++
+            s = i
+        default:
+            i++
+        }
+    }
+    return append(dst, src[s:]...)
+}
+
+// skipQuoted returns the position after the closing quote of the string
+// whose characters start at i.
+func skipQuoted(src []byte, i int) int {
+    for {
+        j := bytes.IndexByte(src[i:], '"')
+        if j < 0 {
+            return len(src)
+        }
+
+        /* the quote is escaped by an odd number of backslashes before it */
+        i += j
+        k := i
+        for src[k - 1] == '\\' {
+            k--
+        }
+        if i++; (i - k) % 2 == 1 {
+            return i
+        }
+    }
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+import (
+    `bytes`
+    `encoding/json`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestCompact(t *testing.T) {
+    cases := []string{
+        `1`,
+        ` "a b" `,
+        `""`,
+        "\t[ ]\r\n",
+        `{ }`,
+        `[1, 2 , 3]`,
+        `{"a b" : [ true, false, null ], "c": {"d" : "e\" f"}}`,
+        `{ "\\" : " \\\" ", "\\\\": "x\\\\" }`,
+        `[ "\"", " \\", "  \n" ]`,
+        "{\n  \"a\": [\n    1,\n    {\n      \"b\": \"c d\"\n    }\n  ]\n}\n",
+    }
+    for _, c := range cases {
+        var exp bytes.Buffer
+        require.NoError(t, json.Compact(&exp, []byte(c)), c)
+        require.Equal(t, exp.String(), string(Compact(nil, []byte(c))), c)
+        require.Equal(t, "x" + exp.String(), string(Compact([]byte("x"), []byte(c))), c)
+    }
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+// Indenter indents valid JSON the same way as json.Indent, without checking
+// it again, except that spaces after the value are dropped too. The JSON may
+// be given in pieces split anywhere, even inside strings, since the
+// indentation state is kept between the pieces.
+type Indenter struct {
+    Prefix string
+    Indent string
+    depth  int
+    str    bool // inside a string
+    esc    bool // right after a backslash inside a string
+    open   bool // an object or array was just opened, its first line is pending
+}
+
+// Append appends the next piece of the JSON to dst, indented.
+func (self *Indenter) Append(dst []byte, src []byte) []byte {
+    for i := 0; i < len(src); i++ {
+        if self.str {
+            j := self.skipString(src, i)
+            dst = append(dst, src[i:j]...)
+            i = j - 1
+            continue
+        }
+        if c := src[i]; c == '"' {
+            dst = self.value(dst)
+            self.str = true
+            dst = append(dst, c)
+        } else {
+            dst = self.punct(dst, c)
+        }
+    }
+    return dst
+}
+
+// value starts a value, on a new line if it is the first one of an object or
+// an array.
+func (self *Indenter) value(dst []byte) []byte {
+    if self.open {
+        self.open = false
+        self.depth++
+        dst = self.newline(dst)
+    }
+    return dst
+}
+
+// punct appends c, which is outside of strings, indented.
+func (self *Indenter) punct(dst []byte, c byte) []byte {
+    /* insignificant spaces are dropped */
+    if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+        return dst
+    }
+
+    /* empty objects and arrays stay on their line */
+    if c != '}' && c != ']' {
+        dst = self.value(dst)
+    }
+
+    switch c {
+    case '{', '[':
+        self.open = true
+        dst = append(dst, c)
+    case ',':
+        dst = self.newline(append(dst, c))
+    case ':':
+        dst = append(dst, c, ' ')
+    case '}', ']':
+        if self.open {
+            self.open = false
+        } else {
+            self.depth--
+            dst = self.newline(dst)
+        }
+        dst = append(dst, c)
+    default:
+        dst = append(dst, c)
+    }
+    return dst
+}
+
+// skipString returns the end of the string characters starting at i, which
+// includes the closing quote if it is in src.
+func (self *Indenter) skipString(src []byte, i int) int {
+    for ; i < len(src); i++ {
+        switch {
+        case self.esc:
+            self.esc = false
+        case src[i] == '\\':
+            self.esc = true
+        case src[i] == '"':
+            self.str = false
+            return i + 1
+        }
+    }
+    return i
+}
+
+func (self *Indenter) newline(dst []byte) []byte {
+    dst = append(dst, '\n')
+    dst = append(dst, self.Prefix...)
+    for i := 0; i < self.depth; i++ {
+        dst = append(dst, self.Indent...)
+    }
+    return dst
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+import (
+    `bytes`
+    `encoding/json`
+    `testing`
+)
+
+var indentCases = []string{
+    `null`,
+    `"a{b[c:d,e\"f\\"`,
+    `[]`,
+    `{}`,
+    `[[],{},[{}]]`,
+    `{"a":1,"b":[true,false,null],"c":{"d":"}]"}}`,
+    `{"{":"[","\\":"\\\"","x:y":[1,2.5e-3,-4]}`,
+    ` { "a" : [ 1 , { } ] , "b" : "c" } `,
+}
+
+func TestIndent(t *testing.T) {
+    for _, src := range indentCases {
+        for _, ind := range [][2]string{{"", "  "}, {">", "\t"}, {"", ""}} {
+            var exp bytes.Buffer
+            if err := json.Indent(&exp, []byte(src), ind[0], ind[1]); err != nil {
+                t.Fatal(err)
+            }
+            exp.Truncate(len(bytes.TrimRight(exp.Bytes(), " ")))
+
+            /* all at once */
+            if out := Indent(nil, []byte(src), ind[0], ind[1]); string(out) != exp.String() {
+                t.Fatalf("Indent(%q): got %q, want %q", src, out, exp.String())
+            }
+
+            /* split anywhere, even inside strings */
+            for n := 1; n < len(src); n++ {
+                ind := Indenter{Prefix: ind[0], Indent: ind[1]}
+                out := ind.Append(nil, []byte(src[:n]))
+                out = ind.Append(out, []byte(src[n:]))
+                if string(out) != exp.String() {
+                    t.Fatalf("Indent(%q) split at %d: got %q, want %q", src, n, out, exp.String())
+                }
+            }
+        }
+    }
+}
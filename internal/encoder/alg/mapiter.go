@@ -37,6 +37,7 @@ type MapIterator struct {
     It rt.GoMapIterator     // must be the first field
     kv rt.GoSlice           // slice of _MapPair
     ki int
+    on bool                 // skip pairs with nil values
 }
 
 var (
@@ -61,6 +62,7 @@ func newIterator() *MapIterator {
 
 func resetIterator(p *MapIterator) *MapIterator {
     p.ki = 0
+    p.on = false
     p.It = rt.GoMapIterator{}
     p.kv.Len = 0
     return p
@@ -157,6 +159,22 @@ func (self *MapIterator) appendInterface(p *_MapPair, t *rt.GoType, k unsafe.Poi
     return
 }
 
+func (self *MapIterator) skipNil() {
+    for self.It.K != nil && *(*unsafe.Pointer)(self.It.V) == nil {
+        rt.Mapiternext(&self.It)
+    }
+}
+
+func omitNilValues(t *rt.GoMapType, fv uint64) bool {
+    if (fv & (1<<BitOmitNilMapValues)) == 0 {
+        return false
+    }
+    switch t.Elem.Kind() {
+        case reflect.Ptr, reflect.Interface : return true
+        default                             : return false
+    }
+}
+
 func IteratorStop(p *MapIterator) {
     iteratorPool.Put(p)
 }
@@ -167,7 +185,9 @@ func IteratorNext(p *MapIterator) {
 
     /* check for unordered iteration */
     if i < 0 {
-        rt.Mapiternext(t)
+        if rt.Mapiternext(t); p.on {
+            p.skipNil()
+        }
         return
     }
 
@@ -188,10 +208,13 @@ func IteratorStart(t *rt.GoMapType, m unsafe.Pointer, fv uint64) (*MapIterator,
     it := newIterator()
     rt.Mapiterinit(t, m, &it.It)
     count := rt.Maplen(m)
+    it.on = omitNilValues(t, fv)
 
     /* check for key-sorting, empty map don't need sorting */
     if count == 0 || (fv & (1<<BitSortMapKeys)) == 0 {
-        it.ki = -1
+        if it.ki = -1; it.on {
+            it.skipNil()
+        }
         return it, nil
     }
 
@@ -202,14 +225,22 @@ func IteratorStart(t *rt.GoMapType, m unsafe.Pointer, fv uint64) (*MapIterator,
 
     /* dump all the key-value pairs */
     for ; it.It.K != nil; rt.Mapiternext(&it.It) {
+        if it.on && *(*unsafe.Pointer)(it.It.V) == nil {
+            continue
+        }
         if err := it.append(t.Key, it.It.K, it.It.V); err != nil {
             IteratorStop(it)
             return nil, err
         }
     }
 
+    /* all the values might have been skipped */
+    if it.kv.Len == 0 {
+        return it, nil
+    }
+
     /* sort the keys, map with only 1 item don't need sorting */
-    if it.ki = 1; count > 1 {
+    if it.ki = 1; it.kv.Len > 1 {
         radixQsort(it.data(), 0, maxDepth(it.kv.Len))
     }
 
@@ -26,6 +26,11 @@ const (
     BitNoValidateJSONMarshaler
     BitNoEncoderNewline 
     BitEncodeNullForInfOrNan 
+    BitStructAsArray
+    BitOmitNilMapValues
+    BitSortStructFields
+    BitPointerRefs
+    BitOmitEmptyStructs
 	
     BitPointerValue = 63
 )
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+import (
+    "strconv"
+    "unsafe"
+
+    "github.com/bytedance/sonic/internal/encoder/vars"
+)
+
+// WriteRef writes `{"$ref":N}` into b if p has been encoded before,
+// otherwise p is assigned the next reference ID and nothing is written.
+func WriteRef(b *[]byte, p unsafe.Pointer, s *vars.Stack) bool {
+    id, ok := s.Ref(p)
+    if !ok {
+        return false
+    }
+    buf := append(*b, `{"$ref":`...)
+    buf = strconv.AppendInt(buf, int64(id), 10)
+    *b = append(buf, '}')
+    return true
+}
@@ -0,0 +1,49 @@
+/**
+ * Copyright 2025 ByteDance Inc.
+ * 
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ * 
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * 
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alg
+
+// Separate appends to dst the compact JSON src, with the commas between
+// elements replaced by item and the colons after object keys replaced by key.
+// An empty item or key leaves the corresponding separator as it is.
+func Separate(dst []byte, src []byte, item string, key string) []byte {
+    if item == "" {
+        item = ","
+    }
+    if key == "" {
+        key = ":"
+    }
+
+    str, esc := false, false
+    for _, c := range src {
+        switch {
+        case esc:
+            esc = false
+        case str && c == '\\':
+            esc = true
+        case c == '"':
+            str = !str
+        case !str && c == ',':
+            dst = append(dst, item...)
+            continue
+        case !str && c == ':':
+            dst = append(dst, key...)
+            continue
+        }
+        dst = append(dst, c)
+    }
+    return dst
+}
@@ -33,7 +33,9 @@ import (
 // if it is only one valid json value.
 // Otherwise returns invalid character position using start.
 //
-// Note: it does not check for the invalid UTF-8 characters.
+// Note: it does not check for the invalid UTF-8 characters, but rejects
+// control characters in strings, like encoding/json. The nesting depth is
+// limited to types.MAX_RECURSE. It does not allocate.
 func Valid(data []byte) (ok bool, start int) {
     n := len(data)
     if n == 0 {
@@ -42,7 +44,7 @@ func Valid(data []byte) (ok bool, start int) {
     s := rt.Mem2Str(data)
     p := 0
     m := types.NewStateMachine()
-    ret := native.ValidateOne(&s, &p, m, 0)
+    ret := native.ValidateOne(&s, &p, m, types.F_VALIDATE_STRING)
     types.FreeStateMachine(m)
 
     if ret < 0 {
@@ -59,6 +61,26 @@ func Valid(data []byte) (ok bool, start int) {
     return true, ret
 }
 
+// Indent appends to dst the valid JSON src indented like json.Indent, except
+// that spaces after the value are dropped too. The JSON is not checked again,
+// strings and numbers are skipped at once by the native scanner.
+func Indent(dst []byte, src []byte, prefix string, indent string) []byte {
+    ind := Indenter{Prefix: prefix, Indent: indent}
+    s := rt.Mem2Str(src)
+    for p := 0; p < len(s); {
+        switch c := s[p]; c {
+        case '{', '[', '}', ']', ',', ':', ' ', '\t', '\n', '\r':
+            dst = ind.punct(dst, c)
+            p++
+        default:
+            dst = ind.value(dst)
+            q := native.SkipOneFast(&s, &p)
+            dst = append(dst, s[q:p]...)
+        }
+    }
+    return dst
+}
+
 var typeByte = rt.UnpackEface(byte(0)).Type
 
 func Quote(buf []byte, val string, double bool) []byte {
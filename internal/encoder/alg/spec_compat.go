@@ -38,6 +38,13 @@ func Valid(data []byte) (ok bool, start int) {
 	return ok, 0
 }
 
+// Indent appends to dst the valid JSON src indented like json.Indent, except
+// that spaces after the value are dropped too. The JSON is not checked again.
+func Indent(dst []byte, src []byte, prefix string, indent string) []byte {
+	ind := Indenter{Prefix: prefix, Indent: indent}
+	return ind.Append(dst, src)
+}
+
 var typeByte = rt.UnpackEface(byte(0)).Type
 
 func Quote(e []byte, s string, double bool) []byte {
@@ -21,7 +21,9 @@ package arm64
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
+	"sync"
 	"unsafe"
 
 	"github.com/bytedance/sonic/internal/encoder/alg"
@@ -33,6 +35,7 @@ import (
 	"github.com/twitchyliquid64/golang-asm/obj"
 
 	"github.com/bytedance/sonic/internal/native"
+	"github.com/bytedance/sonic/internal/native/types"
 	"github.com/bytedance/sonic/internal/rt"
 )
 
@@ -51,27 +54,30 @@ import (
  *
  *  Error Registers:
  *
- *      X27 : error type register
- *      X28 : error pointer register
+ *      X0 : error type register
+ *      X1 : error pointer register
  *
  *  Temporary Registers:
  *
- *      X0-X8 : argument/return registers (caller-saved)
- *      X9-X15 : temporary registers (caller-saved)
- *      X16-X17 : intra-procedure-call temporaries (caller-saved)
- *      X18 : platform register (callee-saved)
+ *      X0-X7  : argument/return registers
+ *      X8-X15 : temporary registers
+ *
+ *  X27 is the assembler temporary and X28 holds g, so neither is used. The
+ *  native routines follow the C convention and preserve X19-X28, while Go
+ *  functions preserve none of them, so the state is saved around Go calls.
  */
 
 /** Function Prototype & Stack Map
  *
  *  func (buf *[]byte, p unsafe.Pointer, sb *_Stack, fv uint64) (err error)
  *
- *  buf    :   (FP)
- *  p      :  8(FP)
- *  sb     : 16(FP)
- *  fv     : 24(FP)
- *  err.vt : 32(FP)
- *  err.vp : 40(FP)
+ *  The arguments are passed in X0-X3 and spilled to the area reserved by the
+ *  caller, the error is returned in X0 and X1.
+ *
+ *  buf    :   8(FP)
+ *  p      :  16(FP)
+ *  sb     :  24(FP)
+ *  fv     :  32(FP)
  */
 
 const (
@@ -82,15 +88,16 @@ const (
 const (
 	_FP_args   = 32 // 32 bytes for spill registers of arguments
 	_FP_fargs  = 40 // 40 bytes for passing arguments to other Go functions
-	_FP_saves  = 80 // 80 bytes for saving the registers before CALL instructions (ARM64 has more callee-saved)
+	_FP_saves  = 80 // 80 bytes for saving the registers before CALL instructions
 	_FP_locals = 24 // 24 bytes for local variables
 )
 
 const (
-	_FP_loffs = _FP_fargs + _FP_saves
+	_FP_soffs = 8 + _FP_fargs // 8 bytes for the return address at 0(RSP)
+	_FP_loffs = _FP_soffs + _FP_saves
 	FP_offs   = _FP_loffs + _FP_locals
-	_FP_size  = FP_offs + 8  // 8 bytes for the parent frame pointer
-	_FP_base  = _FP_size + 8 // 8 bytes for the return address
+	_FP_size  = FP_offs + 8  // 8 bytes for the frame pointer saved by the caller
+	_FP_base  = _FP_size + 8 // 8 bytes for the return address of the caller
 )
 
 const (
@@ -152,9 +159,9 @@ var (
 	_RL = jit.R21 // result length
 	_RC = jit.R22 // result capacity
 
-	// Error registers
-	_ET = jit.R27 // error type
-	_EP = jit.R28 // error pointer
+	// Error registers, which are also the result registers
+	_ET = jit.R0 // error type
+	_EP = jit.R1 // error pointer
 
 	// Stack pointer registers
 	_SP_p = jit.R23 // sp->p
@@ -163,19 +170,25 @@ var (
 	_SP_f = jit.R26 // sp->f
 
 	// Frame pointer and link register
-	_FP_REG = jit.FP // frame pointer
-	_LR_REG = jit.LR // link register
+	_FP_REG = jit.FP  // frame pointer
+	_LR_REG = jit.LR  // link register
+	_LR_ms  = _TEMP7  // return address of _more_space
+	_IP_REG = jit.R16 // address of the called function
 
 	// Zero register
 	_ZR = jit.ZR // zero register
+
+	// Floating-point argument and vector scratch register
+	_FARG0 = jit.F0 // D0 or S0, the float argument of the native routines
+	_VEC0  = jit.F0 // V0, used as Q0 for 128-bit copies
 )
 
 // Argument locations on stack
 var (
-	_ARG_rb = jit.Ptr(jit.SP, _FP_base)
-	_ARG_vp = jit.Ptr(jit.SP, _FP_base+8)
-	_ARG_sb = jit.Ptr(jit.SP, _FP_base+16)
-	_ARG_fv = jit.Ptr(jit.SP, _FP_base+24)
+	_ARG_rb = jit.Ptr(jit.RSP, _FP_base)
+	_ARG_vp = jit.Ptr(jit.RSP, _FP_base+8)
+	_ARG_sb = jit.Ptr(jit.RSP, _FP_base+16)
+	_ARG_fv = jit.Ptr(jit.RSP, _FP_base+24)
 )
 
 // Return value locations
@@ -186,18 +199,20 @@ var (
 
 // Local variable locations
 var (
-	_VAR_sp = jit.Ptr(jit.SP, _FP_fargs+_FP_saves)
-	_VAR_dn = jit.Ptr(jit.SP, _FP_fargs+_FP_saves+8)
-	_VAR_vp = jit.Ptr(jit.SP, _FP_fargs+_FP_saves+16)
+	_VAR_sp = jit.Ptr(jit.RSP, _FP_loffs)
+	_VAR_dn = jit.Ptr(jit.RSP, _FP_loffs+8)
+	_VAR_vp = jit.Ptr(jit.RSP, _FP_loffs+16)
+
+	// _VAR_cp keeps SP.p across C calls in the save slot after _REG_ffi
+	_VAR_cp = jit.Ptr(jit.RSP, _FP_soffs+int64(len(_REG_ffi))*8)
 )
 
 // Register sets for different purposes
 var (
 	_REG_ffi = []obj.Addr{_ARG0, _ARG1, _ARG2, _ARG3, _ARG4, _ARG5, _ARG6, _ARG7}
-	_REG_b64 = []obj.Addr{_SP_p, _SP_q}
 
 	_REG_all = []obj.Addr{_ST, _SP_x, _SP_f, _SP_p, _SP_q, _RP, _RL, _RC}
-	_REG_ms  = []obj.Addr{_ST, _SP_x, _SP_f, _SP_p, _SP_q, _TEMP0}
+	_REG_ms  = []obj.Addr{_ST, _SP_x, _SP_f, _SP_p, _SP_q, _LR_ms}
 	_REG_enc = []obj.Addr{_ST, _SP_x, _SP_f, _SP_p, _SP_q, _RL}
 )
 
@@ -270,6 +285,7 @@ var _OpFuncTab = [256]func(*Assembler, *ir.Instr){
 	ir.OP_drop:           (*Assembler)._asm_OP_drop,
 	ir.OP_drop_2:         (*Assembler)._asm_OP_drop_2,
 	ir.OP_recurse:        (*Assembler)._asm_OP_recurse,
+	ir.OP_tuple:          (*Assembler)._asm_OP_tuple,
 	ir.OP_is_nil:         (*Assembler)._asm_OP_is_nil,
 	ir.OP_is_nil_p1:      (*Assembler)._asm_OP_is_nil_p1,
 	ir.OP_is_zero_1:      (*Assembler)._asm_OP_is_zero_1,
@@ -286,13 +302,20 @@ var _OpFuncTab = [256]func(*Assembler, *ir.Instr){
 	ir.OP_slice_len:      (*Assembler)._asm_OP_slice_len,
 	ir.OP_slice_next:     (*Assembler)._asm_OP_slice_next,
 	ir.OP_marshal:        (*Assembler)._asm_OP_marshal,
+	ir.OP_raw:            (*Assembler)._asm_OP_raw,
 	ir.OP_marshal_p:      (*Assembler)._asm_OP_marshal_p,
 	ir.OP_marshal_text:   (*Assembler)._asm_OP_marshal_text,
 	ir.OP_marshal_text_p: (*Assembler)._asm_OP_marshal_text_p,
+	ir.OP_map_key_utext:  (*Assembler)._asm_OP_map_key_utext,
 	ir.OP_cond_set:       (*Assembler)._asm_OP_cond_set,
 	ir.OP_cond_testc:     (*Assembler)._asm_OP_cond_testc,
 	ir.OP_unsupported:    (*Assembler)._asm_OP_unsupported,
 	ir.OP_is_zero:        (*Assembler)._asm_OP_is_zero,
+	ir.OP_check_tuple:    (*Assembler)._asm_OP_check_tuple,
+	ir.OP_check_sorted:   (*Assembler)._asm_OP_check_sorted,
+	ir.OP_ref:            (*Assembler)._asm_OP_ref,
+	ir.OP_is_zero_struct: (*Assembler)._asm_OP_is_zero_struct,
+	ir.OP_is_hidden:      (*Assembler)._asm_OP_is_hidden,
 }
 
 func (self *Assembler) instr(v *ir.Instr) {
@@ -323,48 +346,37 @@ func (self *Assembler) builtins() {
 
 func (self *Assembler) epilogue() {
 	self.Mark(len(self.p))
-
-	// Clear error registers
-	self.Emit("MOVD", _ET, _ZR) // MOV XZR, X27 (error type)
-	self.Emit("MOVD", _EP, _ZR) // MOV XZR, X28 (error pointer)
-
+	self.Emit("MOVD", _ZR, _ET) // MOVD ZR, ET
+	self.Emit("MOVD", _ZR, _EP) // MOVD ZR, EP
 	self.Link(_LB_error)
+	self.Emit("MOVD", _ARG_rb, _TEMP0)                                    // MOVD rb, X8
+	self.Emit("MOVD", _RL, jit.Ptr(_TEMP0, 8))                            // MOVD RL, 8(X8)
+	self.Emit("MOVD", _ZR, _ARG_rb)                                       // MOVD ZR, rb
+	self.Emit("MOVD", _ZR, _ARG_vp)                                       // MOVD ZR, vp
+	self.Emit("MOVD", _ZR, _ARG_sb)                                       // MOVD ZR, sb
+	self.Emit("LDP", jit.Ptr(jit.RSP, -8), jit.RegPair(_FP_REG, _LR_REG)) // LDP  -8(RSP), (FP, LR)
+	self.Emit("ADD", jit.RSP, jit.RSP, jit.Imm(_FP_size))                 // ADD  $_FP_size, RSP
 
-	// Save buffer state back to arguments
-	self.Emit("MOVD", _ARG_rb, _TEMP0)         // MOV rb, X0
-	self.Emit("MOVD", _RL, jit.Ptr(_TEMP0, 8)) // STR RL, [X0, #8]
-	self.Emit("MOVD", _ZR, _ARG_rb)            // MOV ZR, rb (clear for GC)
-	self.Emit("MOVD", _ZR, _ARG_vp)            // MOV ZR, vp (clear for GC)
-	self.Emit("MOVD", _ZR, _ARG_sb)            // MOV ZR, sb (clear for GC)
-
-	// Restore frame pointer and return
-	self.Emit("MOVD", jit.Ptr(_SP, FP_offs), _FP_REG) // LDR FP, [SP, #FP_offs]
-	self.Emit("ADD", _SP, _SP, jit.Imm(_FP_size))     // ADD SP, SP, #_FP_size
-	self.Emit("RET")                                  // RET
+	/* the builtins after RET still run with the frame */
+	self.To("RET", _LR_REG).Spadj = _FP_size // RET
 }
 
 func (self *Assembler) prologue() {
-	// Set up frame
-	self.Emit("STP", _FP_REG, _LR_REG, jit.Ptr(_SP, -16)) // STP FP, LR, [SP, #-16]!
-	self.Emit("MOVD", _SP, _FP_REG)                       // MOV FP, SP
-	self.Emit("SUB", _SP, _SP, jit.Imm(_FP_size))         // SUB SP, SP, #_FP_size
-
-	// Load arguments into registers
-	self.Emit("MOVD", _ARG0, _RP)    // MOV X0, X20 (result pointer)
-	self.Emit("MOVD", _ARG1, _SP_p)  // MOV X1, X23 (sp->p)
-	self.Emit("MOVD", _ARG2, _ST)    // MOV X2, X19 (stack base)
-	self.Emit("MOVD", _ARG3, _TEMP0) // MOV X3, X0 (flags)
-
-	// Load buffer fields
-	self.Emit("MOVD", jit.Ptr(_RP, 0), _RP)  // LDR X20, [X20] (data pointer)
-	self.Emit("MOVD", jit.Ptr(_RP, 8), _RL)  // LDR X21, [X20, #8] (length)
-	self.Emit("MOVD", jit.Ptr(_RP, 16), _RC) // LDR X22, [X20, #16] (capacity)
-
-	// Initialize stack pointers
-	self.Emit("MOVD", _TEMP0, _SP_x) // MOV X0, X25 (sp->x)
-	self.Emit("MOVD", _ZR, _SP_x)    // MOV ZR, X25 (clear sp->x)
-	self.Emit("MOVD", _ZR, _SP_f)    // MOV ZR, X26 (clear sp->f)
-	self.Emit("MOVD", _ZR, _SP_q)    // MOV ZR, X24 (clear sp->q)
+	self.Emit("SUB", jit.RSP, jit.RSP, jit.Imm(_FP_size))                 // SUB  $_FP_size, RSP
+	self.Emit("STP", jit.RegPair(_FP_REG, _LR_REG), jit.Ptr(jit.RSP, -8)) // STP  (FP, LR), -8(RSP)
+	self.Emit("SUB", _FP_REG, jit.RSP, jit.Imm(8))                        // SUB  $8, RSP, FP
+	self.Emit("MOVD", _ARG0, _ARG_rb)                                     // MOVD X0, rb
+	self.Emit("MOVD", _ARG1, _ARG_vp)                                     // MOVD X1, vp
+	self.Emit("MOVD", _ARG2, _ARG_sb)                                     // MOVD X2, sb
+	self.Emit("MOVD", _ARG3, _ARG_fv)                                     // MOVD X3, fv
+	self.Emit("MOVD", jit.Ptr(_ARG0, 0), _RP)                             // MOVD (X0), RP
+	self.Emit("MOVD", jit.Ptr(_ARG0, 8), _RL)                             // MOVD 8(X0), RL
+	self.Emit("MOVD", jit.Ptr(_ARG0, 16), _RC)                            // MOVD 16(X0), RC
+	self.Emit("MOVD", _ARG1, _SP_p)                                       // MOVD X1, SP.p
+	self.Emit("MOVD", _ARG2, _ST)                                         // MOVD X2, ST
+	self.Emit("MOVD", _ZR, _SP_x)                                         // MOVD ZR, SP.x
+	self.Emit("MOVD", _ZR, _SP_f)                                         // MOVD ZR, SP.f
+	self.Emit("MOVD", _ZR, _SP_q)                                         // MOVD ZR, SP.q
 }
 
 /** ARM64 Inline Functions **/
@@ -374,7 +386,7 @@ func (self *Assembler) xsave(reg ...obj.Addr) {
 		if i > _FP_saves/8-1 {
 			panic("too many registers to save")
 		} else {
-			self.Emit("MOVD", v, jit.Ptr(_SP, _FP_fargs+int64(i)*8))
+			self.Emit("MOVD", v, jit.Ptr(jit.RSP, _FP_soffs+int64(i)*8))
 		}
 	}
 }
@@ -384,140 +396,180 @@ func (self *Assembler) xload(reg ...obj.Addr) {
 		if i > _FP_saves/8-1 {
 			panic("too many registers to load")
 		} else {
-			self.Emit("MOVD", jit.Ptr(_SP, _FP_fargs+int64(i)*8), v)
+			self.Emit("MOVD", jit.Ptr(jit.RSP, _FP_soffs+int64(i)*8), v)
 		}
 	}
 }
 
 func (self *Assembler) rbuf_rp() {
-	// Add result length to result pointer
-	self.Emit("ADD", _RP, _RP, _RL) // ADD X20, X20, X21
+	self.Emit("ADD", _ARG0, _RP, _RL) // ADD RL, RP, X0
 }
 
 func (self *Assembler) store_int(nd int, fn obj.Addr, ins string) {
 	self.check_size(nd)
 	self.save_c()                            // SAVE $C_regs
-	self.rbuf_rp()                           // ADD RP, RP, RL
-	self.Emit(ins, jit.Ptr(_SP_p, 0), _ARG0) // $ins (SP.p), X0
+	self.rbuf_rp()                           // ADD  RL, RP, X0
+	self.Emit(ins, jit.Ptr(_SP_p, 0), _ARG1) // $ins (SP.p), X1
 	self.call_c(fn)                          // CALL_C $fn
-	self.Emit("ADD", _RL, _RL, _ARG0)        // ADD X21, X21, X0
+	self.Emit("ADD", _RL, _RL, _ARG0)        // ADD  X0, RL
+}
+
+// literals holds the string literals that compiled programs copy with vector
+// loads. Programs are cached for the lifetime of the process, so are these.
+var (
+	literalMu sync.Mutex
+	literals  = make(map[string][]byte)
+)
+
+func pinLiteral(s string) unsafe.Pointer {
+	literalMu.Lock()
+	defer literalMu.Unlock()
+	m, ok := literals[s]
+	if !ok {
+		m = []byte(s)
+		literals[s] = m
+	}
+	return unsafe.Pointer(&m[0])
 }
 
 func (self *Assembler) store_str(s string) {
 	i := 0
 	m := rt.Str2Mem(s)
 
+	/* ARM64 can not address with both an index and an offset */
+	self.rbuf_di() // ADD RL, RP, X10
+
+	/* 16-byte vector copies from the pinned literal */
+	if len(m) >= 16 {
+		self.Emit("MOVD", jit.Imm(int64(uintptr(pinLiteral(s)))), _TEMP0) // MOVD  $&s, X8
+		for i <= len(m)-16 {
+			self.Emit("FMOVQ", jit.Ptr(_TEMP0, int64(i)), _VEC0) // FMOVQ i(X8), F0
+			self.Emit("FMOVQ", _VEC0, jit.Ptr(_TEMP2, int64(i))) // FMOVQ F0, i(X10)
+			i += 16
+		}
+	}
+
 	/* 8-byte stores */
 	for i <= len(m)-8 {
-		self.Emit("MOVD", jit.Imm(rt.Get64(m[i:])), _TEMP0) // MOV $s[i:], X0
-		self.Emit("MOVD", _TEMP0, jit.Ptr(_RP, int64(i)))   // STR X0, [RP, #i]
+		self.Emit("MOVD", jit.Imm(rt.Get64(m[i:])), _TEMP0)  // MOVD $s[i:], X8
+		self.Emit("MOVD", _TEMP0, jit.Ptr(_TEMP2, int64(i))) // MOVD X8, i(X10)
 		i += 8
 	}
 
 	/* 4-byte stores */
 	if i <= len(m)-4 {
-		self.Emit("MOVW", jit.Imm(int64(rt.Get32(m[i:]))), _TEMP0) // MOVW $s[i:], X0
-		self.Emit("MOVWU", _TEMP0, jit.Ptr(_RP, int64(i)))         // STRH X0, [RP, #i]
+		self.Emit("MOVD", jit.Imm(int64(rt.Get32(m[i:]))), _TEMP0) // MOVD $s[i:], X8
+		self.Emit("MOVW", _TEMP0, jit.Ptr(_TEMP2, int64(i)))       // MOVW X8, i(X10)
 		i += 4
 	}
 
 	/* 2-byte stores */
 	if i <= len(m)-2 {
-		self.Emit("MOVH", jit.Imm(int64(rt.Get16(m[i:]))), _TEMP0) // MOVH $s[i:], X0
-		self.Emit("MOVHU", _TEMP0, jit.Ptr(_RP, int64(i)))         // STRH X0, [RP, #i]
+		self.Emit("MOVD", jit.Imm(int64(rt.Get16(m[i:]))), _TEMP0) // MOVD $s[i:], X8
+		self.Emit("MOVH", _TEMP0, jit.Ptr(_TEMP2, int64(i)))       // MOVH X8, i(X10)
 		i += 2
 	}
 
 	/* last byte */
 	if i < len(m) {
-		self.Emit("MOVB", jit.Imm(int64(m[i])), _TEMP0)    // MOVB $s[i:], X0
-		self.Emit("MOVBU", _TEMP0, jit.Ptr(_RP, int64(i))) // STRB X0, [RP, #i]
+		self.Emit("MOVD", jit.Imm(int64(m[i])), _TEMP0)      // MOVD $s[i:], X8
+		self.Emit("MOVB", _TEMP0, jit.Ptr(_TEMP2, int64(i))) // MOVB X8, i(X10)
 	}
 }
 
 func (self *Assembler) check_size(n int) {
-	self.check_size_rl(jit.Ptr(_RL, int64(n)))
+	self.Emit("ADD", _TEMP0, _RL, jit.Imm(int64(n))) // ADD X8, RL, #n
+	self.check_size_x0()
 }
 
 func (self *Assembler) check_size_r(r obj.Addr, d int) {
-	// For ARM64, we need to add RP to the register value
-	self.Emit("ADD", _TEMP0, _RP, r) // ADD X0, X20, r
-	self.Emit("CMP", _TEMP0, _RC)    // CMP X0, X22
-	key := "_size_ok_" + strconv.Itoa(self.x)
-	self.Sjmp("B.LE", key)  // BLE _size_ok_{n}
-	self.slice_grow_x0(key) // GROW $key
-	self.Link(key)          // _size_ok_{n}:
+	self.Emit("ADD", _TEMP0, _RL, r) // ADD X8, RL, r
+	if d != 0 {
+		self.Emit("ADD", _TEMP0, _TEMP0, jit.Imm(int64(d))) // ADD X8, X8, #d
+	}
+	self.check_size_x0()
 }
 
-func (self *Assembler) check_size_rl(v obj.Addr) {
+// check_size_x0 grows the buffer when the length in X8 exceeds RC
+func (self *Assembler) check_size_x0() {
 	idx := self.x
 	key := _LB_more_space_return + strconv.Itoa(idx)
 
-	// Check for buffer capacity: RP + RL + n <= RC
-	self.Emit("ADD", _TEMP0, _RP, v) // ADD X0, X20, v
-	self.Emit("CMP", _TEMP0, _RC)    // CMP X0, X22
-	self.Sjmp("B.LE", key)           // BLE _more_space_return_{n}
-	self.slice_grow_x0(key)          // GROW $key
-	self.Link(key)                   // _more_space_return_{n}:
+	/* check for buffer capacity */
+	self.x++
+	self.Emit("CMP", _TEMP0, _RC) // CMP X8, RC
+	self.Sjmp("BLS", key)         // BLS _more_space_return_{n}
+	self.slice_grow_x0(key)       // GROW $key
+	self.Link(key)                // _more_space_return_{n}:
 }
 
+// slice_grow_x0 grows the buffer to the length in X8 and resumes at ret,
+// whose address is passed in _LR_ms, since the call to growslice clobbers LR
 func (self *Assembler) slice_grow_x0(ret string) {
-	// ARM64 ADR instruction: ADR X30, <label>
-	// ADR encoding: 10010000 immlo:2 immhi:19 rd:5
-	// For X30 (LR): rd = 11110
-	// Base encoding: 10010000 00 xxxxxxx xxxxxxxxxx 11110
-	self.Byte(0x10, 0x00, 0x00, 0x90) // ADR X30, ret (placeholder)
-	self.Sref(ret, 0)                 // Symbol reference for the address
-	self.Sjmp("B", _LB_more_space)    // B _more_space
+	ins := uint32(0x10000000) | uint32(_LR_ms.Reg&31)
+	self.Byte(byte(ins), byte(ins>>8), byte(ins>>16), byte(ins>>24)) // ADR X15, ?(PC)
+	self.Sref(ret, 0)                                                // .... &ret
+	self.Sjmp("B", _LB_more_space)                                   // B   _more_space
 }
 
 /** State Stack Helpers */
 
 func (self *Assembler) save_state() {
-	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)                // LDR X0, [X19]
-	self.Emit("ADD", _TEMP1, _TEMP0, jit.Imm(vars.StateSize)) // ADD X1, X0, #vars.StateSize
-	self.Emit("CMP", _TEMP1, jit.Imm(vars.StackLimit))        // CMP X1, #vars.StackLimit
-	self.Sjmp("B.HS", _LB_error_too_deep)                     // B.HS _error_too_deep
-
-	// Save current state to stack
-	self.Emit("MOVD", _SP_x, jit.Ptr(_ST, 8))  // STR X25, [X19, #8]
-	self.Emit("MOVD", _SP_f, jit.Ptr(_ST, 16)) // STR X26, [X19, #16]
-	self.Emit("MOVD", _SP_p, jit.Ptr(_ST, 24)) // STR X23, [X19, #24]
-	self.Emit("MOVD", _SP_q, jit.Ptr(_ST, 32)) // STR X24, [X19, #32]
-	self.Emit("MOVD", _TEMP1, jit.Ptr(_ST, 0)) // STR X1, [X19]
-}
-
+	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)                // MOVD (ST), X8
+	self.Emit("ADD", _TEMP1, _TEMP0, jit.Imm(vars.StateSize)) // ADD  $vars.StateSize, X8, X9
+	self.Emit("CMP", _TEMP1, jit.Imm(vars.StackLimit))        // CMP  X9, $vars.StackLimit
+	self.Sjmp("BHS", _LB_error_too_deep)                      // BHS  _error_too_deep
+	self.Emit("ADD", _TEMP0, _ST, _TEMP0)                     // ADD  X8, ST, X8
+	self.Emit("MOVD", _SP_x, jit.Ptr(_TEMP0, 8))              // MOVD SP.x, 8(X8)
+	self.Emit("MOVD", _SP_f, jit.Ptr(_TEMP0, 16))             // MOVD SP.f, 16(X8)
+	self.Emit("MOVD", _SP_p, jit.Ptr(_TEMP0, 24))             // MOVD SP.p, 24(X8)
+	self.Emit("MOVD", _SP_q, jit.Ptr(_TEMP0, 32))             // MOVD SP.q, 32(X8)
+	self.Emit("MOVD", _TEMP1, jit.Ptr(_ST, 0))                // MOVD X9, (ST)
+}
+
+// drop_state leaves the address of the dropped state in X8
 func (self *Assembler) drop_state(decr int64) {
-	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)      // LDR X0, [X19]
-	self.Emit("SUB", _TEMP0, _TEMP0, jit.Imm(decr)) // SUB X0, X0, #decr
-	self.Emit("MOVD", _TEMP0, jit.Ptr(_ST, 0))      // STR X0, [X19]
-
-	// Restore state from stack
-	self.Emit("MOVD", jit.Ptr(_ST, 8), _SP_x)  // LDR X25, [X19, #8]
-	self.Emit("MOVD", jit.Ptr(_ST, 16), _SP_f) // LDR X26, [X19, #16]
-	self.Emit("MOVD", jit.Ptr(_ST, 24), _SP_p) // LDR X23, [X19, #24]
-	self.Emit("MOVD", jit.Ptr(_ST, 32), _SP_q) // LDR X24, [X19, #32]
-
-	// Clear remaining state
-	self.Emit("MOVD", _ZR, jit.Ptr(_ST, 40)) // STR ZR, [X19, #40]
-	self.Emit("MOVD", _ZR, jit.Ptr(_ST, 48)) // STR ZR, [X19, #48]
+	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)                   // MOVD (ST), X8
+	self.Emit("SUB", _TEMP0, _TEMP0, jit.Imm(decr))              // SUB  $decr, X8
+	self.Emit("MOVD", _TEMP0, jit.Ptr(_ST, 0))                   // MOVD X8, (ST)
+	self.Emit("ADD", _TEMP0, _ST, _TEMP0)                        // ADD  X8, ST, X8
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 8), _SP_x)                 // MOVD 8(X8), SP.x
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 16), _SP_f)                // MOVD 16(X8), SP.f
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 24), _SP_p)                // MOVD 24(X8), SP.p
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 32), _SP_q)                // MOVD 32(X8), SP.q
+	self.Emit("STP", jit.RegPair(_ZR, _ZR), jit.Ptr(_TEMP0, 8))  // STP  (ZR, ZR), 8(X8)
+	self.Emit("STP", jit.RegPair(_ZR, _ZR), jit.Ptr(_TEMP0, 24)) // STP  (ZR, ZR), 24(X8)
 }
 
 /** Buffer Helpers **/
 
 func (self *Assembler) add_char(ch byte) {
-	self.Emit("MOVB", jit.Imm(int64(ch)), jit.Ptr(_RP, 0)) // STRB $ch, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(1))                 // ADD X21, X21, #1
+	self.Emit("MOVD", jit.Imm(int64(ch)), _TEMP0)      // MOVD $ch, X8
+	self.Emit("MOVB", _TEMP0, jit.OffsetReg(_RP, _RL)) // MOVB X8, (RP)(RL)
+	self.Emit("ADD", _RL, _RL, jit.Imm(1))             // ADD  $1, RL
 }
 
 func (self *Assembler) add_long(ch uint32, n int64) {
-	self.Emit("MOVW", jit.Imm(int64(ch)), jit.Ptr(_RP, 0)) // STR $ch, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(n))                 // ADD X21, X21, #n
+	self.Emit("MOVD", jit.Imm(int64(ch)), _TEMP0)      // MOVD $ch, X8
+	self.Emit("MOVW", _TEMP0, jit.OffsetReg(_RP, _RL)) // MOVW X8, (RP)(RL)
+	self.Emit("ADD", _RL, _RL, jit.Imm(n))             // ADD  $n, RL
 }
 
 func (self *Assembler) add_text(ss string) {
 	self.store_str(ss)                                  // TEXT $ss
-	self.Emit("ADD", _RL, _RL, jit.Imm(int64(len(ss)))) // ADD X21, X21, #${len(ss)}
+	self.Emit("ADD", _RL, _RL, jit.Imm(int64(len(ss)))) // ADD  ${len(ss)}, RL
+}
+
+// test_fv sets the flags on a bit of fv, which has to be loaded first, as
+// ARM64 can not test the memory
+func (self *Assembler) test_fv(bit int) {
+	self.Emit("MOVD", _ARG_fv, _TEMP1)               // MOVD fv, X9
+	self.Emit("TST", _TEMP1, jit.Imm(int64(1)<<bit)) // TST  X9, $(1<<bit)
+}
+
+// rbuf_di loads the end of the buffer into X10
+func (self *Assembler) rbuf_di() {
+	self.Emit("ADD", _TEMP2, _RP, _RL) // ADD RL, RP, X10
 }
 
 // get *buf at X0
@@ -544,26 +596,23 @@ func (self *Assembler) load_buffer_X0() {
 /** Function Interface Helpers **/
 
 func (self *Assembler) call(pc obj.Addr) {
-	self.Emit("MOVD", pc, _LR_REG) // MOV $pc, LR
-	self.Emit("BLR", _LR_REG)      // BLR LR
+	self.Emit("MOVD", pc, _IP_REG)       // MOVD $pc, R16
+	self.To("CALL", jit.Ptr(_IP_REG, 0)) // CALL (R16)
 }
 
 func (self *Assembler) save_c() {
 	self.xsave(_REG_ffi...) // SAVE $REG_ffi
 }
 
-func (self *Assembler) call_b64(pc obj.Addr) {
-	self.xsave(_REG_b64...) // SAVE $REG_all
-	self.call(pc)           // CALL $pc
-	self.xload(_REG_b64...) // LOAD $REG_ffi
-}
-
 func (self *Assembler) call_c(pc obj.Addr) {
-	// Swap SP_p with a temp register to preserve it
-	self.Emit("MOVD", _SP_p, _TEMP1)
-	self.call(pc)           // CALL $pc
-	self.xload(_REG_ffi...) // LOAD $REG_ffi
-	self.Emit("MOVD", _TEMP1, _SP_p)
+	self.Emit("MOVD", _SP_p, _VAR_cp) // MOVD SP.p, cp
+	self.call(pc)                     // CALL $pc
+	self.Emit("MOVD", _VAR_cp, _SP_p) // MOVD cp, SP.p
+
+	/* X0 holds the result, so only the remaining argument registers are reloaded */
+	for i := 1; i < len(_REG_ffi); i++ {
+		self.Emit("MOVD", jit.Ptr(jit.RSP, _FP_soffs+int64(i)*8), _REG_ffi[i]) // LOAD $REG_ffi[i]
+	}
 }
 
 func (self *Assembler) call_go(pc obj.Addr) {
@@ -584,21 +633,88 @@ func (self *Assembler) call_encoder(pc obj.Addr) {
 	self.xload(_REG_enc...) // LOAD $REG_all
 }
 
+func (self *Assembler) call_marshaler(fn obj.Addr, it *rt.GoType, vt reflect.Type) {
+	switch vt.Kind() {
+	case reflect.Interface:
+		self.call_marshaler_i(fn, it)
+	case reflect.Ptr, reflect.Map:
+		self.call_marshaler_v(fn, it, vt, true)
+	// struct/array of 1 direct iface type can be direct
+	default:
+		self.call_marshaler_v(fn, it, vt, !rt.UnpackType(vt).Indirect())
+	}
+}
+
+var (
+	_F_assertI2I = jit.Func(rt.AssertI2I)
+)
+
+func (self *Assembler) call_marshaler_i(fn obj.Addr, it *rt.GoType) {
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1) // MOVD    (SP.p), X1
+	self.Emit("CMP", _ARG1, _ZR)                // CMP     X1, ZR
+	self.Sjmp("BEQ", "_null_{n}")               // BEQ     _null_{n}
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _ARG2) // MOVD    8(SP.p), X2
+	self.Emit("MOVD", jit.Gtype(it), _ARG0)     // MOVD    $it, X0
+	self.call_go(_F_assertI2I)                  // CALL_GO assertI2I
+	self.Emit("CMP", _ARG0, _ZR)                // CMP     X0, ZR
+	self.Sjmp("BEQ", "_null_{n}")               // BEQ     _null_{n}
+	self.Emit("MOVD", _ARG1, _ARG2)             // MOVD    X1, X2
+	self.Emit("MOVD", _ARG0, _ARG1)             // MOVD    X0, X1
+	self.prep_buffer_X0()                       // MOVE    {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0)            // MOVD    X8, X0
+	self.Emit("MOVD", _ARG_fv, _ARG3)           // MOVD    fv, X3
+	self.call_go(fn)                            // CALL_GO $fn
+	self.check_call_error()                     // CHECK   X0, X1
+	self.load_buffer_X0()                       // LOAD    {buf}
+	self.Sjmp("B", "_done_{n}")                 // B       _done_{n}
+	self.Link("_null_{n}")                      // _null_{n}:
+	self._asm_OP_null(nil)                      // NULL
+	self.Link("_done_{n}")                      // _done_{n}:
+}
+
+func (self *Assembler) call_marshaler_v(fn obj.Addr, it *rt.GoType, vt reflect.Type, deref bool) {
+	self.prep_buffer_X0()                      // MOVE    {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0)           // MOVD    X8, X0
+	self.Emit("MOVD", jit.Itab(it, vt), _ARG1) // MOVD    $(itab(it, vt)), X1
+
+	/* dereference the pointer if needed */
+	if !deref {
+		self.Emit("MOVD", _SP_p, _ARG2) // MOVD    SP.p, X2
+	} else {
+		self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG2) // MOVD    (SP.p), X2
+	}
+
+	/* call the encoder, and perform error checks */
+	self.Emit("MOVD", _ARG_fv, _ARG3) // MOVD    fv, X3
+	self.call_go(fn)                  // CALL_GO $fn
+	self.check_call_error()           // CHECK   X0, X1
+	self.load_buffer_X0()             // LOAD    {buf}
+}
+
+func (self *Assembler) check_call_error() {
+	self.Emit("MOVD", _RET0, _ET) // MOVD    X0, ET
+	self.Emit("MOVD", _RET1, _EP) // MOVD    X1, EP
+	self.Emit("CMP", _ET, _ZR)    // CMP     ET, ZR
+	self.Sjmp("BNE", _LB_error)   // BNE     _error
+}
+
 /** OpCode Implementations **/
 
 var (
-	_F_f64toa    = jit.Imm(int64(native.S_f64toa))
-	_F_f32toa    = jit.Imm(int64(native.S_f32toa))
-	_F_i64toa    = jit.Imm(int64(native.S_i64toa))
-	_F_u64toa    = jit.Imm(int64(native.S_u64toa))
-	_F_b64encode = jit.Imm(int64(rt.SubrB64Encode))
+	_F_f64toa = jit.Imm(int64(native.S_f64toa))
+	_F_f32toa = jit.Imm(int64(native.S_f32toa))
+	_F_i64toa = jit.Imm(int64(native.S_i64toa))
+	_F_u64toa = jit.Imm(int64(native.S_u64toa))
 )
 
 var (
 	_F_memmove       = jit.Func(rt.Memmove)
 	_F_error_number  = jit.Func(vars.Error_number)
 	_F_isValidNumber = jit.Func(alg.IsValidNumber)
-	_F_is_zero       = jit.Func(rt.IsZero)
+	_F_is_zero       = jit.Func(prim.IsZero)
+	_F_encodeBase64  = jit.Func(encodeBase64)
+	_F_quoteHTML     = jit.Func(quoteHTML)
+	_F_is_hidden     = jit.Func(prim.IsHidden)
 )
 
 var (
@@ -608,66 +724,63 @@ var (
 )
 
 var (
-	_F_encodeTypedPointer  obj.Addr
-	_F_encodeJsonMarshaler obj.Addr
-	_F_encodeTextMarshaler obj.Addr
+	_F_encodeTypedPointer     obj.Addr
+	_F_encodeTypedTuple       obj.Addr
+	_F_encodeJsonMarshaler    obj.Addr
+	_F_encodeTextMarshaler    obj.Addr
+	_F_encodeTextMarshalerKey obj.Addr
+	_F_encodeRawMessage       obj.Addr
 )
 
 func init() {
 	_F_encodeJsonMarshaler = jit.Func(prim.EncodeJsonMarshaler)
 	_F_encodeTextMarshaler = jit.Func(prim.EncodeTextMarshaler)
+	_F_encodeTextMarshalerKey = jit.Func(prim.EncodeTextMarshalerKey)
+	_F_encodeRawMessage = jit.Func(prim.EncodeRawMessage)
 	_F_encodeTypedPointer = jit.Func(EncodeTypedPointer)
+	_F_encodeTypedTuple = jit.Func(EncodeTypedTuple)
 }
 
 // Basic operation implementations
 func (self *Assembler) _asm_OP_null(_ *ir.Instr) {
 	self.check_size(4)
-	self.Emit("MOVW", jit.Imm(_IM_null), _TEMP0) // MOVW $'null', X0
-	self.Emit("MOVWU", _TEMP0, jit.Ptr(_RP, 0))  // STRH X0, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(4))       // ADD X21, X21, #4
+	self.add_long(_IM_null, 4) // TEXT $'null'
 }
 
 func (self *Assembler) _asm_OP_empty_arr(_ *ir.Instr) {
-	self.Emit("TST", _ARG_fv, jit.Imm(1<<alg.BitNoNullSliceOrMap)) // TST fv, #(1<<BitNoNullSliceOrMap)
-	self.Sjmp("B.NE", "_empty_arr_{n}")                            // B.NE _empty_arr_{n}
+	self.test_fv(alg.BitNoNullSliceOrMap) // TST  fv, $(1<<BitNoNullSliceOrMap)
+	self.Sjmp("BNE", "_empty_arr_{n}")    // BNE  _empty_arr_{n}
 	self._asm_OP_null(nil)
-	self.Sjmp("B", "_empty_arr_end_{n}") // B _empty_arr_end_{n}
+	self.Sjmp("B", "_empty_arr_end_{n}") // B    _empty_arr_end_{n}
 	self.Link("_empty_arr_{n}")
 	self.check_size(2)
-	self.Emit("MOVH", jit.Imm(_IM_array), _TEMP0) // MOVH $'[]', X0
-	self.Emit("MOVHU", _TEMP0, jit.Ptr(_RP, 0))   // STRH X0, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(2))        // ADD X21, X21, #2
+	self.add_text("[]") // TEXT $'[]'
 	self.Link("_empty_arr_end_{n}")
 }
 
 func (self *Assembler) _asm_OP_empty_obj(_ *ir.Instr) {
-	self.Emit("TST", _ARG_fv, jit.Imm(1<<alg.BitNoNullSliceOrMap)) // TST fv, #(1<<BitNoNullSliceOrMap)
-	self.Sjmp("B.NE", "_empty_obj_{n}")                            // B.NE _empty_obj_{n}
+	self.test_fv(alg.BitNoNullSliceOrMap) // TST  fv, $(1<<BitNoNullSliceOrMap)
+	self.Sjmp("BNE", "_empty_obj_{n}")    // BNE  _empty_obj_{n}
 	self._asm_OP_null(nil)
-	self.Sjmp("B", "_empty_obj_end_{n}") // B _empty_obj_end_{n}
+	self.Sjmp("B", "_empty_obj_end_{n}") // B    _empty_obj_end_{n}
 	self.Link("_empty_obj_{n}")
 	self.check_size(2)
-	self.Emit("MOVH", jit.Imm(_IM_object), _TEMP0) // MOVH $'{}', X0
-	self.Emit("MOVHU", _TEMP0, jit.Ptr(_RP, 0))    // STRH X0, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(2))         // ADD X21, X21, #2
+	self.add_text("{}") // TEXT $'{}'
 	self.Link("_empty_obj_end_{n}")
 }
 
 func (self *Assembler) _asm_OP_bool(_ *ir.Instr) {
-	self.Emit("CMPB", jit.Ptr(_SP_p, 0), jit.Imm(0)) // CMPB (SP.p), #0
-	self.Sjmp("B.EQ", "_false_{n}")                  // B.EQ _false_{n}
-	self.check_size(4)                               // SIZE $4
-	self.Emit("MOVW", jit.Imm(_IM_true), _TEMP0)     // MOVW $'true', X0
-	self.Emit("MOVWU", _TEMP0, jit.Ptr(_RP, 0))      // STRH X0, [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(4))           // ADD X21, X21, #4
-	self.Sjmp("B", "_end_{n}")                       // B _end_{n}
-	self.Link("_false_{n}")
-	self.check_size(5)                               // SIZE $5
-	self.Emit("MOVW", jit.Imm(_IM_fals), _TEMP0)     // MOVW $'fals', X0
-	self.Emit("MOVWU", _TEMP0, jit.Ptr(_RP, 0))      // STRH X0, [RP]
-	self.Emit("MOVB", jit.Imm('e'), jit.Ptr(_RP, 4)) // STRB $'e', [RP, #4]
-	self.Emit("ADD", _RL, _RL, jit.Imm(5))           // ADD X21, X21, #5
-	self.Link("_end_{n}")
+	self.Emit("MOVBU", jit.Ptr(_SP_p, 0), _TEMP1) // MOVBU (SP.p), X9
+	self.Emit("CMPW", _TEMP1, _ZR)                // CMPW  X9, ZR
+	self.Sjmp("BEQ", "_false_{n}")                // BEQ   _false_{n}
+	self.check_size(4)                            // SIZE  $4
+	self.add_long(_IM_true, 4)                    // TEXT  $'true'
+	self.Sjmp("B", "_end_{n}")                    // B     _end_{n}
+	self.Link("_false_{n}")                       // _false_{n}:
+	self.check_size(5)                            // SIZE  $5
+	self.add_long(_IM_fals, 4)                    // TEXT  $'fals'
+	self.add_char('e')                            // CHAR  $'e'
+	self.Link("_end_{n}")                         // _end_{n}:
 }
 
 // Integer operations
@@ -688,15 +801,15 @@ func (self *Assembler) _asm_OP_i64(_ *ir.Instr) {
 }
 
 func (self *Assembler) _asm_OP_u8(_ *ir.Instr) {
-	self.store_int(3, _F_u64toa, "MOVB")
+	self.store_int(3, _F_u64toa, "MOVBU")
 }
 
 func (self *Assembler) _asm_OP_u16(_ *ir.Instr) {
-	self.store_int(5, _F_u64toa, "MOVH")
+	self.store_int(5, _F_u64toa, "MOVHU")
 }
 
 func (self *Assembler) _asm_OP_u32(_ *ir.Instr) {
-	self.store_int(16, _F_u64toa, "MOVW")
+	self.store_int(16, _F_u64toa, "MOVWU")
 }
 
 func (self *Assembler) _asm_OP_u64(_ *ir.Instr) {
@@ -706,45 +819,45 @@ func (self *Assembler) _asm_OP_u64(_ *ir.Instr) {
 // Float operations
 func (self *Assembler) _asm_OP_f32(_ *ir.Instr) {
 	self.check_size(32)
-	self.Emit("MOVW", jit.Ptr(_SP_p, 0), _TEMP0)         // MOVW (SP.p), X0
-	self.Emit("AND", _TEMP0, _TEMP0, jit.Imm(_FM_exp32)) // AND X0, X0, #$_FM_exp32
-	self.Emit("EOR", _TEMP0, _TEMP0, jit.Imm(_FM_exp32)) // EOR X0, X0, #$_FM_exp32
-	self.Sjmp("B.NE", "_encode_normal_f32_{n}")          // B.NE _encode_normal_f32_{n}
+	self.Emit("MOVWU", jit.Ptr(_SP_p, 0), _TEMP0)        // MOVWU (SP.p), X8
+	self.Emit("AND", _TEMP0, _TEMP0, jit.Imm(_FM_exp32)) // AND   $_FM_exp32, X8
+	self.Emit("CMP", _TEMP0, jit.Imm(_FM_exp32))         // CMP   X8, $_FM_exp32
+	self.Sjmp("BNE", "_encode_normal_f32_{n}")           // BNE   _encode_normal_f32_{n}
 
 	// Handle NaN/Infinity
-	self.Emit("TST", _ARG_fv, jit.Imm(1<<alg.BitEncodeNullForInfOrNan)) // TST fv, #(1<<BitEncodeNullForInfOrNan)
-	self.Sjmp("B.EQ", _LB_error_nan_or_infinite)                        // B.EQ _error_nan_or_infinite
+	self.test_fv(alg.BitEncodeNullForInfOrNan)  // TST fv, $(1<<BitEncodeNullForInfOrNan)
+	self.Sjmp("BEQ", _LB_error_nan_or_infinite) // BEQ _error_nan_or_infinite
 	self._asm_OP_null(nil)
 	self.Sjmp("B", "_encode_f32_end_{n}") // B _encode_f32_end_{n}
 
 	self.Link("_encode_normal_f32_{n}")
-	self.save_c()                                // SAVE $C_regs
-	self.rbuf_rp()                               // ADD RP, RP, RL
-	self.Emit("MOVS", jit.Ptr(_SP_p, 0), _TEMP1) // MOVS (SP.p), S0
-	self.call_c(_F_f32toa)                       // CALL_C f32toa
-	self.Emit("ADD", _RL, _RL, _ARG0)            // ADD X21, X21, X0
+	self.save_c()                                 // SAVE $C_regs
+	self.rbuf_rp()                                // ADD  RL, RP, X0
+	self.Emit("FMOVS", jit.Ptr(_SP_p, 0), _FARG0) // FMOVS (SP.p), S0
+	self.call_c(_F_f32toa)                        // CALL_C f32toa
+	self.Emit("ADD", _RL, _RL, _ARG0)             // ADD  X0, RL
 	self.Link("_encode_f32_end_{n}")
 }
 
 func (self *Assembler) _asm_OP_f64(_ *ir.Instr) {
 	self.check_size(32)
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0)         // MOVD (SP.p), X0
-	self.Emit("AND", _TEMP0, _TEMP0, jit.Imm(_FM_exp64)) // AND X0, X0, #$_FM_exp64
-	self.Emit("EOR", _TEMP0, _TEMP0, jit.Imm(_FM_exp64)) // EOR X0, X0, #$_FM_exp64
-	self.Sjmp("B.NE", "_encode_normal_f64_{n}")          // B.NE _encode_normal_f64_{n}
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0)         // MOVD (SP.p), X8
+	self.Emit("AND", _TEMP0, _TEMP0, jit.Imm(_FM_exp64)) // AND  $_FM_exp64, X8
+	self.Emit("CMP", _TEMP0, jit.Imm(_FM_exp64))         // CMP  X8, $_FM_exp64
+	self.Sjmp("BNE", "_encode_normal_f64_{n}")           // BNE  _encode_normal_f64_{n}
 
 	// Handle NaN/Infinity
-	self.Emit("TST", _ARG_fv, jit.Imm(1<<alg.BitEncodeNullForInfOrNan)) // TST fv, #(1<<BitEncodeNullForInfOrNan)
-	self.Sjmp("B.EQ", _LB_error_nan_or_infinite)                        // B.EQ _error_nan_or_infinite
+	self.test_fv(alg.BitEncodeNullForInfOrNan)  // TST fv, $(1<<BitEncodeNullForInfOrNan)
+	self.Sjmp("BEQ", _LB_error_nan_or_infinite) // BEQ _error_nan_or_infinite
 	self._asm_OP_null(nil)
 	self.Sjmp("B", "_encode_f64_end_{n}") // B _encode_f64_end_{n}
 
 	self.Link("_encode_normal_f64_{n}")
-	self.save_c()                                // SAVE $C_regs
-	self.rbuf_rp()                               // ADD RP, RP, RL
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP1) // MOVD (SP.p), D0
-	self.call_c(_F_f64toa)                       // CALL_C f64toa
-	self.Emit("ADD", _RL, _RL, _ARG0)            // ADD X21, X21, X0
+	self.save_c()                                 // SAVE  $C_regs
+	self.rbuf_rp()                                // ADD   RL, RP, X0
+	self.Emit("FMOVD", jit.Ptr(_SP_p, 0), _FARG0) // FMOVD (SP.p), F0
+	self.call_c(_F_f64toa)                        // CALL_C f64toa
+	self.Emit("ADD", _RL, _RL, _ARG0)             // ADD   X0, RL
 	self.Link("_encode_f64_end_{n}")
 }
 
@@ -753,25 +866,13 @@ func (self *Assembler) _asm_OP_str(_ *ir.Instr) {
 	self.encode_string(false)
 }
 
+// There is no native base64 encoder on ARM64, so the bytes are encoded by Go
 func (self *Assembler) _asm_OP_bin(_ *ir.Instr) {
-	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP0) // LDR X0, [SP_p, #8]
-	self.Emit("ADD", _TEMP0, _TEMP0, jit.Imm(2)) // ADD X0, X0, #2
-	self.Emit("MOVD", jit.Imm(_IM_mulv), _TEMP1) // MOV $_MF_mulv, X1
-	self.Emit("MUL", _TEMP1, _TEMP0, _TEMP1)     // MUL X0, X1, X1
-	self.Emit("ADD", _TEMP0, _TEMP1, jit.Imm(1)) // ADD X0, X1, #1
-	self.Emit("ADD", _TEMP0, _TEMP0, jit.Imm(2)) // ADD X0, X0, #2
-	self.check_size_r(_TEMP0, 0)                 // SIZE X0
-	self.add_char('"')                           // CHAR $'"'
-
-	// Prepare for b64encode call
-	self.Emit("MOVD", _ARG_rb, _ARG0)        // MOV rb, X0
-	self.Emit("STR", _RL, jit.Ptr(_ARG0, 8)) // STR X21, [X0, #8]
-	self.Emit("MOVD", _SP_p, _ARG1)          // MOV SP.p, X1
-
-	// Call b64encode
-	self.call_b64(_F_b64encode) // CALL b64encode
-	self.load_buffer_X0()       // LOAD {buf}
-	self.add_char('"')          // CHAR $'"'
+	self.prep_buffer_X0()            // MOVE    {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0) // MOVD    X8, X0
+	self.Emit("MOVD", _SP_p, _ARG1)  // MOVD    SP.p, X1
+	self.call_go(_F_encodeBase64)    // CALL_GO encodeBase64
+	self.load_buffer_X0()            // LOAD    {buf}
 }
 
 func (self *Assembler) _asm_OP_quote(_ *ir.Instr) {
@@ -780,41 +881,35 @@ func (self *Assembler) _asm_OP_quote(_ *ir.Instr) {
 
 // Number operation
 func (self *Assembler) _asm_OP_number(_ *ir.Instr) {
-	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // LDR X1, [SP_p, #8]
-	self.Emit("CMP", _TEMP1, _ZR)                // CMP X1, XZR
-	self.Sjmp("B.EQ", "_empty_{n}")              // B.EQ _empty_{n}
-
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0) // LDR X0, [SP_p]
-	self.Emit("CMP", _TEMP0, _ZR)                // CMP X0, XZR
-	self.Sjmp("B.NE", "_number_next_{n}")        // B.NE _number_next_{n}
-
-	// Handle nil pointer error
-	self.Emit("MOVD", jit.Imm(int64(vars.PanicNilPointerOfNonEmptyString)), _TEMP0)
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // MOVD 8(SP.p), X9
+	self.Emit("CMP", _TEMP1, _ZR)                // CMP  X9, ZR
+	self.Sjmp("BEQ", "_empty_{n}")               // BEQ  _empty_{n}
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0) // MOVD (SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                // CMP  X8, ZR
+	self.Sjmp("BNE", "_number_next_{n}")         // BNE  _number_next_{n}
+	self.Emit("MOVD", jit.Imm(int64(vars.PanicNilPointerOfNonEmptyString)), _ARG0)
 	self.Sjmp("B", _LB_panic)
-
 	self.Link("_number_next_{n}")
-	self.call_go(_F_isValidNumber)              // CALL_GO isValidNumber
-	self.Emit("CMPW", _ARG0, jit.Imm(0))        // CMPW X0, #0
-	self.Sjmp("B.EQ", _LB_error_invalid_number) // B.EQ _error_invalid_number
-
-	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // LDR X1, [SP_p, #8]
-	self.check_size_r(_TEMP1, 0)                 // SIZE X1
-	self.Emit("ADD", _TEMP0, _RP, _RL)           // ADD X0, X20, X21
-	self.Emit("ADD", _RL, _RL, _TEMP1)           // ADD X21, X21, X1
-
-	// Use memmove to copy the number string
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1) // MOV (SP.p), X1
-	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _ARG2) // MOV 8(SP.p), X2
-	self.Emit("MOVD", _TEMP0, _ARG0)            // MOV X0, X0 (dest)
-	self.call_go(_F_memmove)                    // CALL_GO memmove
-	self.Emit("MOVD", _ARG_rb, _TEMP0)          // MOV rb, X0
-	self.Emit("STR", _RL, jit.Ptr(_TEMP0, 8))   // STR X21, [X0, #8]
-	self.Sjmp("B", "_done_{n}")                 // B _done_{n}
-
-	self.Link("_empty_{n}")
-	self.check_size(1) // SIZE $1
-	self.add_char('0') // CHAR $'0'
-	self.Link("_done_{n}")
+	self.Emit("MOVD", _TEMP0, _ARG0)             // MOVD    X8, X0
+	self.Emit("MOVD", _TEMP1, _ARG1)             // MOVD    X9, X1
+	self.call_go(_F_isValidNumber)               // CALL_GO isValidNumber
+	self.Emit("MOVBU", _RET0, _RET0)             // MOVBU   X0, X0
+	self.Emit("CMPW", _RET0, _ZR)                // CMPW    X0, ZR
+	self.Sjmp("BEQ", _LB_error_invalid_number)   // BEQ     _error_invalid_number
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // MOVD    8(SP.p), X9
+	self.check_size_r(_TEMP1, 0)                 // SIZE    X9
+	self.Emit("ADD", _ARG0, _RP, _RL)            // ADD     RL, RP, X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1)  // MOVD    (SP.p), X1
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _ARG2)  // MOVD    8(SP.p), X2
+	self.Emit("ADD", _RL, _RL, _ARG2)            // ADD     X2, RL
+	self.call_go(_F_memmove)                     // CALL_GO memmove
+	self.Emit("MOVD", _ARG_rb, _TEMP0)           // MOVD    rb, X8
+	self.Emit("MOVD", _RL, jit.Ptr(_TEMP0, 8))   // MOVD    RL, 8(X8)
+	self.Sjmp("B", "_done_{n}")                  // B       _done_{n}
+	self.Link("_empty_{n}")                      // _empty_{n}:
+	self.check_size(1)                           // SIZE    $1
+	self.add_char('0')                           // CHAR    $'0'
+	self.Link("_done_{n}")                       // _done_{n}:
 }
 
 // Helper function to print debug info
@@ -823,65 +918,144 @@ func (self *Assembler) debug_instr(i int, p *ir.Instr) {
 }
 
 // String encoding routine
+//
+// The string is quoted by the native quoter, which writes as much as fits in
+// the remaining buffer and reports the consumed input on overflow, so that the
+// buffer can be grown and the loop resumed. The native quoter can not escape
+// the HTML characters, so quoteHTML is called instead when EscapeHTML is set.
+// UTF-8 validation (ValidateString) is applied to the whole output afterwards
+// by encodeFinish, exactly as on amd64.
 func (self *Assembler) encode_string(doubleQuote bool) {
-	// Load string length
-	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP0) // LDR X0, [SP_p, #8]
-	self.Emit("CMP", _TEMP0, _ZR)                // CMP X0, XZR
-	self.Sjmp("B.EQ", "_str_empty_{n}")          // B.EQ _str_empty_{n}
-
-	// For simplicity, implement basic string copying
-	// In a full implementation, this would handle escaping properly
-
-	// Add opening quote if needed
-	if doubleQuote {
-		self.check_size(1)
-		self.add_char('"')
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // MOVD 8(SP.p), X9
+	self.Emit("CMP", _TEMP1, _ZR)                // CMP  X9, ZR
+	self.Sjmp("BEQ", "_str_empty_{n}")           // BEQ  _str_empty_{n}
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0) // MOVD (SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                // CMP  X8, ZR
+	self.Sjmp("BNE", "_str_next_{n}")            // BNE  _str_next_{n}
+	self.Emit("MOVD", jit.Imm(int64(vars.PanicNilPointerOfNonEmptyString)), _ARG0)
+	self.Sjmp("B", _LB_panic)
+	self.Link("_str_next_{n}")
+	self.test_fv(alg.BitEscapeHTML)              // TST  fv, ${EscapeHTML}
+	self.Sjmp("BNE", "_str_html_{n}")            // BNE  _str_html_{n}
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP1) // MOVD 8(SP.p), X9
+
+	/* opening quote, check for double quote */
+	if !doubleQuote {
+		self.check_size_r(_TEMP1, 2) // SIZE $2
+		self.add_char('"')           // CHAR $'"'
+	} else {
+		self.check_size_r(_TEMP1, 6) // SIZE $6
+		self.add_long(_IM_open, 3)   // TEXT $`"\"`
 	}
 
-	// Load string data pointer and length
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP1) // LDR X1, [SP_p] (data)
-	self.Emit("MOVD", _TEMP0, _ARG2)             // MOV X0, X2 (len)
-
-	// Check buffer size
-	self.check_size_r(_TEMP0, 0)
-
-	// Copy string bytes
-	self.Emit("MOVD", _TEMP1, _ARG1)  // MOV src, X1
-	self.Emit("ADD", _ARG0, _RP, _RL) // MOV dst, X0
-	self.call_go(_F_memmove)          // CALL memmove
-
-	// Update result length
-	self.Emit("ADD", _RL, _RL, _TEMP0) // ADD RL, RL, len
+	/* quoting loop */
+	self.Emit("MOVD", _ZR, _VAR_sp) // MOVD ZR, sp
+	self.Link("_str_loop_{n}")      // _str_loop_{n}:
+	self.save_c()                   // SAVE $REG_ffi
+
+	/* output buffer and the remaining capacity */
+	self.Emit("SUB", _TEMP0, _RC, _RL)                        // SUB  RL, RC, X8
+	self.Emit("MOVD", _TEMP0, _VAR_dn)                        // MOVD X8, dn
+	self.Emit("ADD", _ARG2, _RP, _RL)                         // ADD  RL, RP, X2
+	self.Emit("ADD", _ARG3, jit.RSP, jit.Imm(_VAR_dn.Offset)) // MOVD $dn, X3
+	self.Emit("MOVD", _VAR_sp, _TEMP0)                        // MOVD sp, X8
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG0)               // MOVD (SP.p), X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _ARG1)               // MOVD 8(SP.p), X1
+	self.Emit("ADD", _ARG0, _ARG0, _TEMP0)                    // ADD  X8, X0
+	self.Emit("SUB", _ARG1, _ARG1, _TEMP0)                    // SUB  X8, X1
+
+	/* set the flags based on `doubleQuote` */
+	if !doubleQuote {
+		self.Emit("MOVD", _ZR, _ARG4) // MOVD ZR, X4
+	} else {
+		self.Emit("MOVD", jit.Imm(types.F_DOUBLE_UNQUOTE), _ARG4) // MOVD ${types.F_DOUBLE_UNQUOTE}, X4
+	}
 
-	// Add closing quote if needed
-	if doubleQuote {
-		self.check_size(1)
-		self.add_char('"')
+	/* call the native quoter */
+	self.call_c(_F_quote)              // CALL quote
+	self.Emit("MOVD", _VAR_dn, _TEMP0) // LDR  X8, dn
+	self.Emit("ADD", _RL, _RL, _TEMP0) // ADD  X8, RL
+	self.Emit("CMP", _RET0, _ZR)       // CMP  X0, ZR
+	self.Sjmp("BLT", "_str_space_{n}") // BLT  _str_space_{n}
+
+	/* close the string, check for double quote */
+	if !doubleQuote {
+		self.check_size(1)             // SIZE $1
+		self.add_char('"')             // CHAR $'"'
+		self.Sjmp("B", "_str_end_{n}") // B    _str_end_{n}
+	} else {
+		self.check_size(3)             // SIZE $3
+		self.add_text("\\\"\"")        // TEXT $'\""'
+		self.Sjmp("B", "_str_end_{n}") // B    _str_end_{n}
 	}
 
-	self.Link("_str_empty_{n}")
-	// Handle empty string
-	if doubleQuote {
-		self.check_size(2)
-		self.Emit("MOVH", jit.Imm(int64('"'|('"')<<8)), _TEMP0) // MOVW '""', X0
-		self.Emit("MOVHU", _TEMP0, jit.Ptr(_RP, 0))             // STRH X0, [RP]
-		self.Emit("ADD", _RL, _RL, jit.Imm(2))                  // ADD RL, RL, #2
+	/* not enough space to contain the quoted string */
+	self.Link("_str_space_{n}")             // _str_space_{n}:
+	self.Emit("MVN", _RET0, _RET0)          // MVN  X0, X0
+	self.Emit("MOVD", _VAR_sp, _TEMP1)      // MOVD sp, X9
+	self.Emit("ADD", _TEMP1, _TEMP1, _RET0) // ADD  X0, X9
+	self.Emit("MOVD", _TEMP1, _VAR_sp)      // MOVD X9, sp
+	self.Emit("ADD", _TEMP0, _RC, _RC)      // ADD  RC, RC, X8
+	self.slice_grow_x0("_str_loop_{n}")     // GROW _str_loop_{n}
+
+	/* quote and escape the HTML characters in Go */
+	self.Link("_str_html_{n}")       // _str_html_{n}:
+	self.prep_buffer_X0()            // MOVE    {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0) // MOVD    X8, X0
+	self.Emit("MOVD", _SP_p, _ARG1)  // MOVD    SP.p, X1
+	if !doubleQuote {
+		self.Emit("MOVD", _ZR, _ARG2) // MOVD    ZR, X2
+	} else {
+		self.Emit("MOVD", jit.Imm(1), _ARG2) // MOVD    $1, X2
+	}
+	self.call_go(_F_quoteHTML)     // CALL_GO quoteHTML
+	self.load_buffer_X0()          // LOAD    {buf}
+	self.Sjmp("B", "_str_end_{n}") // B       _str_end_{n}
+
+	/* empty string, check for double quote */
+	if !doubleQuote {
+		self.Link("_str_empty_{n}") // _str_empty_{n}:
+		self.check_size(2)          // SIZE $2
+		self.add_text("\"\"")       // TEXT $'""'
+		self.Link("_str_end_{n}")   // _str_end_{n}:
+	} else {
+		self.Link("_str_empty_{n}")   // _str_empty_{n}:
+		self.check_size(6)            // SIZE $6
+		self.add_text("\"\\\"\\\"\"") // TEXT $'"\"\""'
+		self.Link("_str_end_{n}")     // _str_end_{n}:
 	}
 }
 
-// Placeholder implementations for other operations that need full implementation
 func (self *Assembler) _asm_OP_eface(_ *ir.Instr) {
-	// Implementation needed
+	self.prep_buffer_X0()                       // MOVE {buf}, X0
+	self.Emit("MOVD", _TEMP0, _ARG0)            // MOV  X8, X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1) // LDR  X1, [SP.p]
+	self.Emit("ADD", _ARG2, _SP_p, jit.Imm(8))  // ADD  X2, SP.p, #8
+	self.Emit("MOVD", _ST, _ARG3)               // MOV  ST, X3
+	self.Emit("MOVD", _ARG_fv, _ARG4)           // MOV  fv, X4
+
+	/* pointer types are direct, so encodeTypedPointer takes care of the nil pointers */
+	self.call_encoder(_F_encodeTypedPointer) // CALL encodeTypedPointer
+	self.check_call_error()                  // CHECK X0, X1
+	self.load_buffer_X0()
 }
 
 func (self *Assembler) _asm_OP_iface(_ *ir.Instr) {
-	// Implementation needed
+	self.prep_buffer_X0()                        // MOVE {buf}, X0
+	self.Emit("MOVD", _TEMP0, _ARG0)             // MOV  X8, X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP1) // LDR  X9, [SP.p]
+	self.Emit("MOVD", jit.Ptr(_TEMP1, 8), _ARG1) // LDR  X1, [X9, #8] (itab._type)
+	self.Emit("ADD", _ARG2, _SP_p, jit.Imm(8))   // ADD  X2, SP.p, #8
+	self.Emit("MOVD", _ST, _ARG3)                // MOV  ST, X3
+	self.Emit("MOVD", _ARG_fv, _ARG4)            // MOV  fv, X4
+	self.call_encoder(_F_encodeTypedPointer)     // CALL encodeTypedPointer
+	self.check_call_error()                      // CHECK X0, X1
+	self.load_buffer_X0()
 }
 
 func (self *Assembler) _asm_OP_byte(p *ir.Instr) {
 	self.check_size(1)
-	self.Emit("MOVB", jit.Imm(p.I64()), jit.Ptr(_RP, 0)) // STRB p.Vi(), [RP]
-	self.Emit("ADD", _RL, _RL, jit.Imm(1))               // ADD X21, X21, #1
+	self.add_char(byte(p.I64())) // CHAR p.Vi()
 }
 
 func (self *Assembler) _asm_OP_text(p *ir.Instr) {
@@ -899,10 +1073,11 @@ func (self *Assembler) _asm_OP_index(p *ir.Instr) {
 }
 
 func (self *Assembler) _asm_OP_load(_ *ir.Instr) {
-	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)           // LDR X0, [ST]
-	self.Emit("LDR", _SP_x, jit.Ptr(_ST, _TEMP0, 1, 8))  // LDR X25, [ST, X0, LSL #3, #8]
-	self.Emit("LDR", _SP_p, jit.Ptr(_ST, _TEMP0, 1, 24)) // LDR X23, [ST, X0, LSL #3, #24]
-	self.Emit("LDR", _SP_q, jit.Ptr(_ST, _TEMP0, 1, 32)) // LDR X24, [ST, X0, LSL #3, #32]
+	self.Emit("MOVD", jit.Ptr(_ST, 0), _TEMP0)     // MOVD (ST), X8
+	self.Emit("ADD", _TEMP0, _ST, _TEMP0)          // ADD  X8, ST, X8
+	self.Emit("MOVD", jit.Ptr(_TEMP0, -24), _SP_x) // MOVD -24(X8), SP.x
+	self.Emit("MOVD", jit.Ptr(_TEMP0, -8), _SP_p)  // MOVD -8(X8), SP.p
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 0), _SP_q)   // MOVD (X8), SP.q
 }
 
 func (self *Assembler) _asm_OP_save(_ *ir.Instr) {
@@ -914,166 +1089,290 @@ func (self *Assembler) _asm_OP_drop(_ *ir.Instr) {
 }
 
 func (self *Assembler) _asm_OP_drop_2(_ *ir.Instr) {
-	self.drop_state(vars.StateSize * 2)
-	// Clear additional state
-	self.Emit("MOVD", _ZR, jit.Ptr(_ST, 40)) // STR ZR, [ST, #40]
-	self.Emit("MOVD", _ZR, jit.Ptr(_ST, 48)) // STR ZR, [ST, #48]
+	self.drop_state(vars.StateSize * 2)                          // DROP  $(vars.StateSize * 2)
+	self.Emit("STP", jit.RegPair(_ZR, _ZR), jit.Ptr(_TEMP0, 56)) // STP   (ZR, ZR), 56(X8)
 }
 
 func (self *Assembler) _asm_OP_recurse(p *ir.Instr) {
-	self.prep_buffer_X0() // MOVE {buf}, X0
+	self.encode_typed(_F_encodeTypedPointer, p)
+}
+
+func (self *Assembler) _asm_OP_tuple(p *ir.Instr) {
+	self.encode_typed(_F_encodeTypedTuple, p)
+}
+
+func (self *Assembler) encode_typed(fn obj.Addr, p *ir.Instr) {
+	self.prep_buffer_X0()            // MOVE {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0) // MOVD X8, X0
 	vt, pv := p.Vp()
-	self.Emit("MOVD", jit.Type(vt), _ARG1) // MOV $(type(p.Vt())), X1
+	self.Emit("MOVD", jit.Type(vt), _ARG1) // MOVD $(type(p.Vt())), X1
 
 	// Check for indirection
 	if !rt.UnpackType(vt).Indirect() {
-		self.Emit("MOVD", _SP_p, _ARG2) // MOV SP.p, X2
+		self.Emit("MOVD", _SP_p, _ARG2) // MOVD SP.p, X2
 	} else {
-		self.Emit("MOVD", _SP_p, _VAR_vp) // MOV SP.p, VAR.vp
-		self.Emit("MOVD", _VAR_vp, _ARG2) // MOV VAR.vp, X2
+		self.Emit("MOVD", _SP_p, _VAR_vp)                         // MOVD SP.p, VAR.vp
+		self.Emit("ADD", _ARG2, jit.RSP, jit.Imm(_VAR_vp.Offset)) // MOVD $VAR.vp, X2
 	}
 
 	// Call the encoder
-	self.Emit("MOVD", _ST, _ARG3)     // MOV ST, X3
-	self.Emit("MOVD", _ARG_fv, _ARG4) // MOV fv, X4
+	self.Emit("MOVD", _ST, _ARG3)     // MOVD ST, X3
+	self.Emit("MOVD", _ARG_fv, _ARG4) // MOVD fv, X4
 	if pv {
-		self.Emit("ORR", _ARG4, _ARG4, jit.Imm(1<<alg.BitPointerValue)) // ORR X4, X4, #(1<<BitPointerValue)
+		self.Emit("ORR", _ARG4, _ARG4, jit.Imm(-1<<alg.BitPointerValue)) // ORR $(1<<BitPointerValue), X4
 	}
 
-	self.call_encoder(_F_encodeTypedPointer) // CALL encodeTypedPointer
-	self.Emit("CMP", _ET, _ZR)               // CMP X27, XZR
-	self.Sjmp("B.NE", _LB_error)             // B.NE _error
+	self.call_encoder(fn)   // CALL $fn
+	self.check_call_error() // CHECK X0, X1
 	self.load_buffer_X0()
 }
 
 // Placeholder implementations for remaining operations
+// ARM64 can not compare memory directly, and has no byte or half-word forms
+// of CMP, so the nil and zero checks load the value zero-extended first.
 func (self *Assembler) _asm_OP_is_nil(p *ir.Instr) {
-	self.Emit("CMP", jit.Ptr(_SP_p, 0), _ZR)                // CMP (SP.p), XZR
-	self.Sjmp("B.EQ", "_is_nil_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0) // MOVD (SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                // CMP  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                     // BEQ  p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_nil_p1(p *ir.Instr) {
-	self.Emit("CMP", jit.Ptr(_SP_p, 8), _ZR)                   // CMP 8(SP.p), XZR
-	self.Sjmp("B.EQ", "_is_nil_p1_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _TEMP0) // MOVD 8(SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                // CMP  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                     // BEQ  p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero_1(p *ir.Instr) {
-	self.Emit("CMPB", jit.Ptr(_SP_p, 0), _ZR)                  // CMPB (SP.p), #0
-	self.Sjmp("B.EQ", "_is_zero_1_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVBU", jit.Ptr(_SP_p, 0), _TEMP0) // MOVBU (SP.p), X8
+	self.Emit("CMPW", _TEMP0, _ZR)                // CMPW  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                      // BEQ   p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero_2(p *ir.Instr) {
-	self.Emit("CMPH", jit.Ptr(_SP_p, 0), _ZR)                  // CMPH (SP.p), #0
-	self.Sjmp("B.EQ", "_is_zero_2_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVHU", jit.Ptr(_SP_p, 0), _TEMP0) // MOVHU (SP.p), X8
+	self.Emit("CMPW", _TEMP0, _ZR)                // CMPW  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                      // BEQ   p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero_4(p *ir.Instr) {
-	self.Emit("CMPW", jit.Ptr(_SP_p, 0), _ZR)                  // CMPW (SP.p), #0
-	self.Sjmp("B.EQ", "_is_zero_4_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVWU", jit.Ptr(_SP_p, 0), _TEMP0) // MOVWU (SP.p), X8
+	self.Emit("CMPW", _TEMP0, _ZR)                // CMPW  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                      // BEQ   p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero_8(p *ir.Instr) {
-	self.Emit("CMP", jit.Ptr(_SP_p, 0), _ZR)                   // CMP (SP.p), XZR
-	self.Sjmp("B.EQ", "_is_zero_8_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0) // MOVD (SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                // CMP  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                     // BEQ  p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero_map(p *ir.Instr) {
-	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0)                   // LDR X0, [SP_p]
-	self.Emit("CMP", _TEMP0, _ZR)                                  // CMP X0, XZR
-	self.Sjmp("B.EQ", "_is_zero_map_1_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
-	self.Emit("CMP", jit.Ptr(_TEMP0, 0), _ZR)                      // CMP [X0], XZR
-	self.Sjmp("B.EQ", "_is_zero_map_2_"+strconv.Itoa(int(p.Vi()))) // B.EQ p.Vi()
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _TEMP0)  // MOVD (SP.p), X8
+	self.Emit("CMP", _TEMP0, _ZR)                 // CMP  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                      // BEQ  p.Vi()
+	self.Emit("MOVD", jit.Ptr(_TEMP0, 0), _TEMP0) // MOVD (X8), X8
+	self.Emit("CMP", _TEMP0, _ZR)                 // CMP  X8, ZR
+	self.Xjmp("BEQ", p.Vi())                      // BEQ  p.Vi()
 }
 
 func (self *Assembler) _asm_OP_is_zero(p *ir.Instr) {
 	fv := p.VField()
-	self.Emit("MOVD", _SP_p, _ARG1)                          // ptr
-	self.Emit("MOVD", jit.ImmPtr(unsafe.Pointer(fv)), _ARG2) // fv
-	self.call_go(_F_is_zero)                                 // CALL $fn
-	self.Emit("CMPB", _ARG0, _ZR)                            // CMPB X0, #0
-	self.Sjmp("B.NE", "_is_zero_"+strconv.Itoa(int(p.Vi()))) // B.NE p.Vi()
+	self.Emit("MOVD", _SP_p, _ARG0)                          // ptr
+	self.Emit("MOVD", jit.ImmPtr(unsafe.Pointer(fv)), _ARG1) // fv
+	self.call_go(_F_is_zero)                                 // CALL  $fn
+	self.Emit("MOVBU", _RET0, _RET0)                         // MOVBU X0, X0
+	self.Emit("CMPW", _RET0, _ZR)                            // CMPW  X0, ZR
+	self.Xjmp("BNE", p.Vi())                                 // BNE   p.Vi()
+}
+
+func (self *Assembler) _asm_OP_is_zero_struct(p *ir.Instr) {
+	fv := p.VField()
+	self.test_fv(alg.BitOmitEmptyStructs)                    // TEST  fv, ${BitOmitEmptyStructs}
+	self.Sjmp("BEQ", "_not_zero_struct_{n}")                 // BEQ   _not_zero_struct_{n}
+	self.Emit("MOVD", _SP_p, _ARG0)                          // MOVD  SP.p, X0
+	self.Emit("MOVD", jit.ImmPtr(unsafe.Pointer(fv)), _ARG1) // MOVD  $fv, X1
+	self.call_go(_F_is_zero)                                 // CALL  IsZero
+	self.Emit("MOVBU", _RET0, _RET0)                         // MOVBU X0, X0
+	self.Emit("CMPW", _RET0, _ZR)                            // CMPW  X0, ZR
+	self.Xjmp("BNE", p.Vi())                                 // BNE   p.Vi()
+	self.Link("_not_zero_struct_{n}")                        // _not_zero_struct_{n}:
+}
+
+func (self *Assembler) _asm_OP_is_hidden(p *ir.Instr) {
+	self.Emit("MOVD", _SP_p, _ARG0)                                 // MOVD  SP.p, X0
+	self.Emit("MOVD", jit.ImmPtr(unsafe.Pointer(p.VPred())), _ARG1) // MOVD  $fn, X1
+	self.call_go(_F_is_hidden)                                      // CALL_GO IsHidden
+	self.Emit("MOVBU", _RET0, _RET0)                                // MOVBU X0, X0
+	self.Emit("CMPW", _RET0, _ZR)                                   // CMPW  X0, ZR
+	self.Xjmp("BNE", p.Vi())                                        // BNE   p.Vi()
 }
 
 func (self *Assembler) _asm_OP_goto(p *ir.Instr) {
-	self.Sjmp("B", "_goto_"+strconv.Itoa(int(p.Vi())))
+	self.Xjmp("B", p.Vi())
+}
+
+func (self *Assembler) _asm_OP_check_tuple(p *ir.Instr) {
+	self.test_fv(alg.BitStructAsArray) // TEST fv, ${BitStructAsArray}
+	self.Xjmp("BNE", p.Vi())           // BNE  p.Vi()
+}
+
+func (self *Assembler) _asm_OP_check_sorted(p *ir.Instr) {
+	self.test_fv(alg.BitSortStructFields) // TEST fv, ${BitSortStructFields}
+	self.Xjmp("BNE", p.Vi())              // BNE  p.Vi()
+}
+
+var _F_writeRef = jit.Func(alg.WriteRef)
+
+func (self *Assembler) _asm_OP_ref(p *ir.Instr) {
+	self.test_fv(alg.BitPointerRefs)            // TEST  fv, ${BitPointerRefs}
+	self.Sjmp("BEQ", "_ref_next_{n}")           // BEQ   _ref_next_{n}
+	self.prep_buffer_X0()                       // MOVE  {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0)            // MOVD  X8, X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1) // MOVD  (SP.p), X1
+	self.Emit("MOVD", _ST, _ARG2)               // MOVD  ST, X2
+	self.call_encoder(_F_writeRef)              // CALL  WriteRef
+	self.Emit("MOVBU", _RET0, _RET0)            // MOVBU X0, X0
+	self.load_buffer_X0()                       // LOAD  {buf}
+	self.Emit("CMPW", _RET0, _ZR)               // CMPW  X0, ZR
+	self.Xjmp("BNE", p.Vi())                    // BNE   p.Vi()
+	self.Link("_ref_next_{n}")                  // _ref_next_{n}:
 }
 
-// Placeholder for map operations - these need full implementation
 func (self *Assembler) _asm_OP_map_iter(p *ir.Instr) {
-	// Implementation needed
+	self.Emit("MOVD", jit.Type(p.Vt()), _ARG0)  // MOVD    $p.Vt(), X0
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _ARG1) // MOVD    (SP.p), X1
+	self.Emit("MOVD", _ARG_fv, _ARG2)           // MOVD    fv, X2
+	self.call_go(_F_iteratorStart)              // CALL_GO iteratorStart
+	self.Emit("MOVD", _RET0, _SP_q)             // MOVD    X0, SP.q
+	self.Emit("MOVD", _ARG1, _ET)               // MOVD    X1, ET
+	self.Emit("MOVD", _ARG2, _EP)               // MOVD    X2, EP
+	self.Emit("CMP", _ET, _ZR)                  // CMP     ET, ZR
+	self.Sjmp("BNE", _LB_error)                 // BNE     _error
 }
 
 func (self *Assembler) _asm_OP_map_stop(_ *ir.Instr) {
-	// Implementation needed
+	self.Emit("MOVD", _SP_q, _ARG0) // MOVD    SP.q, X0
+	self.call_go(_F_iteratorStop)   // CALL_GO iteratorStop
+	self.Emit("MOVD", _ZR, _SP_q)   // MOVD    ZR, SP.q
 }
 
 func (self *Assembler) _asm_OP_map_check_key(p *ir.Instr) {
-	// Implementation needed
+	self.Emit("MOVD", jit.Ptr(_SP_q, 0), _SP_p) // MOVD    (SP.q), SP.p
+	self.Emit("CMP", _SP_p, _ZR)                // CMP     SP.p, ZR
+	self.Xjmp("BEQ", p.Vi())                    // BEQ     p.Vi()
 }
 
 func (self *Assembler) _asm_OP_map_write_key(p *ir.Instr) {
-	// Implementation needed
+	self.test_fv(alg.BitSortMapKeys)       // TEST    fv, ${SortMapKeys}
+	self.Sjmp("BEQ", "_unordered_key_{n}") // BEQ     _unordered_key_{n}
+	self.encode_string(false)              // STR     $false
+	self.Xjmp("B", p.Vi())                 // B       p.Vi()
+	self.Link("_unordered_key_{n}")        // _unordered_key_{n}:
 }
 
-func (self *Assembler) _asm_OP_map_value_next(p *ir.Instr) {
-	// Implementation needed
+func (self *Assembler) _asm_OP_map_value_next(_ *ir.Instr) {
+	self.Emit("MOVD", jit.Ptr(_SP_q, 8), _SP_p) // MOVD    8(SP.q), SP.p
+	self.Emit("MOVD", _SP_q, _ARG0)             // MOVD    SP.q, X0
+	self.call_go(_F_iteratorNext)               // CALL_GO iteratorNext
 }
 
-func (self *Assembler) _asm_OP_slice_len(p *ir.Instr) {
-	// Implementation needed
+func (self *Assembler) _asm_OP_slice_len(_ *ir.Instr) {
+	self.Emit("MOVD", jit.Ptr(_SP_p, 8), _SP_x)         // MOVD    8(SP.p), SP.x
+	self.Emit("MOVD", jit.Ptr(_SP_p, 0), _SP_p)         // MOVD    (SP.p), SP.p
+	self.Emit("ORR", _SP_f, _SP_f, jit.Imm(1<<_S_init)) // ORR     SP.f, SP.f, #(1<<_S_init)
 }
 
 func (self *Assembler) _asm_OP_slice_next(p *ir.Instr) {
-	// Implementation needed
+	self.Emit("CMP", _SP_x, _ZR)                             // CMP     SP.x, ZR
+	self.Xjmp("BEQ", p.Vi())                                 // BEQ     p.Vi()
+	self.Emit("SUB", _SP_x, _SP_x, jit.Imm(1))               // SUB     $1, SP.x
+	self.Emit("TST", _SP_f, jit.Imm(1<<_S_init))             // TST     SP.f, $(1<<_S_init)
+	self.Emit("AND", _SP_f, _SP_f, jit.Imm(^(1 << _S_init))) // AND     $^(1<<_S_init), SP.f
+	self.Sjmp("BNE", "_slice_first_{n}")                     // BNE     _slice_first_{n}
+	self.Emit("ADD", _SP_p, _SP_p, jit.Imm(int64(p.Vlen()))) // ADD     $(p.vlen()), SP.p
+	self.Link("_slice_first_{n}")                            // _slice_first_{n}:
 }
 
 func (self *Assembler) _asm_OP_marshal(p *ir.Instr) {
-	// Implementation needed
+	self.call_marshaler(_F_encodeJsonMarshaler, _T_json_Marshaler, p.Vt())
 }
 
 func (self *Assembler) _asm_OP_marshal_p(p *ir.Instr) {
-	// Implementation needed
+	if p.Vk() != reflect.Ptr {
+		panic("marshal_p: invalid type")
+	} else {
+		self.call_marshaler_v(_F_encodeJsonMarshaler, _T_json_Marshaler, p.Vt(), false)
+	}
+}
+
+func (self *Assembler) _asm_OP_raw(_ *ir.Instr) {
+	self.prep_buffer_X0()             // MOVE    {buf}, X8
+	self.Emit("MOVD", _TEMP0, _ARG0)  // MOVD    X8, X0
+	self.Emit("MOVD", _SP_p, _ARG1)   // MOVD    SP.p, X1
+	self.Emit("MOVD", _ARG_fv, _ARG2) // MOVD    fv, X2
+	self.call_go(_F_encodeRawMessage) // CALL_GO encodeRawMessage
+	self.check_call_error()           // CHECK   X0, X1
+	self.load_buffer_X0()             // LOAD    {buf}
 }
 
 func (self *Assembler) _asm_OP_marshal_text(p *ir.Instr) {
-	// Implementation needed
+	self.call_marshaler(_F_encodeTextMarshaler, _T_encoding_TextMarshaler, p.Vt())
 }
 
 func (self *Assembler) _asm_OP_marshal_text_p(p *ir.Instr) {
-	// Implementation needed
+	if p.Vk() != reflect.Ptr {
+		panic("marshal_text_p: invalid type")
+	} else {
+		self.call_marshaler_v(_F_encodeTextMarshaler, _T_encoding_TextMarshaler, p.Vt(), false)
+	}
+}
+
+func (self *Assembler) _asm_OP_map_key_utext(p *ir.Instr) {
+	self.call_marshaler(_F_encodeTextMarshalerKey, _T_encoding_TextMarshaler, p.Vt())
 }
 
-func (self *Assembler) _asm_OP_cond_set(p *ir.Instr) {
-	// Implementation needed
+func (self *Assembler) _asm_OP_cond_set(_ *ir.Instr) {
+	self.Emit("ORR", _SP_f, _SP_f, jit.Imm(1<<_S_cond)) // ORR     SP.f, SP.f, #(1<<_S_cond)
 }
 
 func (self *Assembler) _asm_OP_cond_testc(p *ir.Instr) {
-	// Implementation needed
+	self.Emit("TST", _SP_f, jit.Imm(1<<_S_cond))             // TST     SP.f, $(1<<_S_cond)
+	self.Emit("AND", _SP_f, _SP_f, jit.Imm(^(1 << _S_cond))) // AND     $^(1<<_S_cond), SP.f
+	self.Xjmp("BNE", p.Vi())                                 // BNE     p.Vi()
 }
 
+var _F_error_unsupported = jit.Func(vars.Error_unsuppoted)
+
 func (self *Assembler) _asm_OP_unsupported(i *ir.Instr) {
-	// Implementation needed
+	typ := int64(uintptr(unsafe.Pointer(i.GoType())))
+	self.Emit("MOVD", jit.Imm(typ), _ARG0) // MOVD    ${i.GoType()}, X0
+	self.call_go(_F_error_unsupported)     // CALL_GO error_unsupported
+	self.Emit("MOVD", _RET0, _ET)          // MOVD    X0, ET
+	self.Emit("MOVD", _RET1, _EP)          // MOVD    X1, EP
+	self.Sjmp("B", _LB_error)              // B       _error
 }
 
 // Built-in functions
 var (
-	_T_byte      = jit.Type(vars.ByteType)
-	_F_growslice = jit.Func(rt.GrowSlice)
+	_F_more_space = jit.Func(vars.MoreSpace)
+
+	_T_json_Marshaler         = rt.UnpackType(vars.JsonMarshalerType)
+	_T_encoding_TextMarshaler = rt.UnpackType(vars.EncodingTextMarshalerType)
 )
 
 func (self *Assembler) more_space() {
 	self.Link(_LB_more_space)
-	self.Emit("MOVD", _RP, _ARG1)      // MOV X20, X1 (result pointer)
-	self.Emit("MOVD", _RL, _ARG2)      // MOV X21, X2 (result length)
-	self.Emit("MOVD", _RC, _ARG3)      // MOV X22, X3 (result capacity)
-	self.Emit("MOVD", _TEMP0, _ARG4)   // MOV X0, X4 (new length)
-	self.Emit("MOVD", _T_byte, _ARG0)  // MOV $_T_byte, X0
-	self.call_more_space(_F_growslice) // CALL $pc
-	self.Emit("MOVD", _ARG0, _RP)      // MOV X0, X20
-	self.Emit("MOVD", _ARG2, _RL)      // MOV X2, X21
-	self.Emit("MOVD", _ARG3, _RC)      // MOV X3, X22
-	self.save_buffer()                 // SAVE {buf}
-	self.Emit("BR", _LR_REG)           // BR LR
+	self.Emit("MOVD", _RP, _ARG1)       // MOV X20, X1 (result pointer)
+	self.Emit("MOVD", _RL, _ARG2)       // MOV X21, X2 (result length)
+	self.Emit("MOVD", _RC, _ARG3)       // MOV X22, X3 (result capacity)
+	self.Emit("MOVD", _TEMP0, _ARG4)    // MOV X0, X4 (new length)
+	self.Emit("MOVD", _ST, _ARG0)       // MOV X19, X0 (encoder stack)
+	self.call_more_space(_F_more_space) // CALL $pc
+	self.Emit("MOVD", _ARG0, _RP)       // MOV X0, X20 (new pointer)
+	self.Emit("MOVD", _ARG1, _RL)       // MOV X1, X21 (old length)
+	self.Emit("MOVD", _ARG2, _RC)       // MOV X2, X22 (new capacity)
+	self.save_buffer()                  // SAVE {buf}
+	self.To("JMP", jit.Ptr(_LR_ms, 0))  // JMP  (X15)
 }
 
 var (
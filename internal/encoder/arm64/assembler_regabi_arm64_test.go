@@ -24,14 +24,15 @@ import (
 
 	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/jit"
+	"github.com/twitchyliquid64/golang-asm/obj"
 )
 
 func TestARM64AssemblerCreation(t *testing.T) {
 	// Create a simple instruction program
 	prog := ir.Program{
-		{Op: ir.OP_null},
-		{Op: ir.OP_bool},
-		{Op: ir.OP_i32},
+		ir.NewInsOp(ir.OP_null),
+		ir.NewInsOp(ir.OP_bool),
+		ir.NewInsOp(ir.OP_i32),
 	}
 
 	assembler := NewAssembler(prog)
@@ -50,7 +51,7 @@ func TestARM64AssemblerCreation(t *testing.T) {
 
 func TestARM64AssemblerInit(t *testing.T) {
 	prog := ir.Program{
-		{Op: ir.OP_null},
+		ir.NewInsOp(ir.OP_null),
 	}
 
 	assembler := NewAssembler(prog)
@@ -71,7 +72,7 @@ func TestARM64AssemblerInit(t *testing.T) {
 
 func TestARM64AssemblerLoad(t *testing.T) {
 	prog := ir.Program{
-		{Op: ir.OP_null},
+		ir.NewInsOp(ir.OP_null),
 	}
 
 	assembler := NewAssembler(prog)
@@ -88,7 +89,7 @@ func TestARM64RegisterConstants(t *testing.T) {
 	// Test that all register constants are properly defined
 	tests := []struct {
 		name string
-		reg  jit.Addr
+		reg  obj.Addr
 	}{
 		{"_ARG0", _ARG0},
 		{"_ARG1", _ARG1},
@@ -175,36 +176,41 @@ func TestARM64Constants(t *testing.T) {
 
 func TestARM64ArgumentLocations(t *testing.T) {
 	// Test that argument locations are properly defined
-	if _ARG_rb.Type != jit.Ptr(_SP, 0).Type {
+	if _ARG_rb.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_ARG_rb should be a pointer")
 	}
 
-	if _ARG_vp.Type != jit.Ptr(_SP, 0).Type {
+	if _ARG_vp.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_ARG_vp should be a pointer")
 	}
 
-	if _ARG_sb.Type != jit.Ptr(_SP, 0).Type {
+	if _ARG_sb.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_ARG_sb should be a pointer")
 	}
 
-	if _ARG_fv.Type != jit.Ptr(_SP, 0).Type {
+	if _ARG_fv.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_ARG_fv should be a pointer")
 	}
 }
 
 func TestARM64LocalVariableLocations(t *testing.T) {
 	// Test that local variable locations are properly defined
-	if _VAR_sp.Type != jit.Ptr(_SP, 0).Type {
+	if _VAR_sp.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_VAR_sp should be a pointer")
 	}
 
-	if _VAR_dn.Type != jit.Ptr(_SP, 0).Type {
+	if _VAR_dn.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_VAR_dn should be a pointer")
 	}
 
-	if _VAR_vp.Type != jit.Ptr(_SP, 0).Type {
+	if _VAR_vp.Type != jit.Ptr(jit.RSP, 0).Type {
 		t.Error("_VAR_vp should be a pointer")
 	}
+
+	/* SP.p is kept after the C registers while calling into C */
+	if _VAR_cp.Offset < _FP_soffs+int64(len(_REG_ffi))*8 || _VAR_cp.Offset+8 > _FP_loffs {
+		t.Errorf("_VAR_cp at %d overlaps the C registers or the locals", _VAR_cp.Offset)
+	}
 }
 
 func TestARM64OpFuncTable(t *testing.T) {
@@ -214,7 +220,7 @@ func TestARM64OpFuncTable(t *testing.T) {
 	}
 
 	// Test that some key operations are defined
-	keyOps := []ir.OpCode{
+	keyOps := []ir.Op{
 		ir.OP_null,
 		ir.OP_bool,
 		ir.OP_i8,
@@ -246,9 +252,9 @@ func TestARM64OpFuncTable(t *testing.T) {
 
 func TestARM64BasicOperations(t *testing.T) {
 	prog := ir.Program{
-		{Op: ir.OP_null},
-		{Op: ir.OP_bool},
-		{Op: ir.OP_i32},
+		ir.NewInsOp(ir.OP_null),
+		ir.NewInsOp(ir.OP_bool),
+		ir.NewInsOp(ir.OP_i32),
 	}
 
 	assembler := NewAssembler(prog)
@@ -263,9 +269,9 @@ func TestARM64BasicOperations(t *testing.T) {
 
 func TestARM64StackOperations(t *testing.T) {
 	prog := ir.Program{
-		{Op: ir.OP_save},
-		{Op: ir.OP_load},
-		{Op: ir.OP_drop},
+		ir.NewInsOp(ir.OP_save),
+		ir.NewInsOp(ir.OP_load),
+		ir.NewInsOp(ir.OP_drop),
 	}
 
 	assembler := NewAssembler(prog)
@@ -279,7 +285,7 @@ func TestARM64StackOperations(t *testing.T) {
 }
 
 func TestARM64IntegerOperations(t *testing.T) {
-	intOps := []ir.OpCode{
+	intOps := []ir.Op{
 		ir.OP_i8,
 		ir.OP_i16,
 		ir.OP_i32,
@@ -292,7 +298,7 @@ func TestARM64IntegerOperations(t *testing.T) {
 
 	for _, op := range intOps {
 		t.Run(op.String(), func(t *testing.T) {
-			prog := ir.Program{{Op: op}}
+			prog := ir.Program{ir.NewInsOp(op)}
 			assembler := NewAssembler(prog)
 			assembler.Name = "test_" + op.String()
 
@@ -305,14 +311,14 @@ func TestARM64IntegerOperations(t *testing.T) {
 }
 
 func TestARM64FloatOperations(t *testing.T) {
-	floatOps := []ir.OpCode{
+	floatOps := []ir.Op{
 		ir.OP_f32,
 		ir.OP_f64,
 	}
 
 	for _, op := range floatOps {
 		t.Run(op.String(), func(t *testing.T) {
-			prog := ir.Program{{Op: op}}
+			prog := ir.Program{ir.NewInsOp(op)}
 			assembler := NewAssembler(prog)
 			assembler.Name = "test_" + op.String()
 
@@ -325,7 +331,7 @@ func TestARM64FloatOperations(t *testing.T) {
 }
 
 func TestARM64StringOperations(t *testing.T) {
-	strOps := []ir.OpCode{
+	strOps := []ir.Op{
 		ir.OP_str,
 		ir.OP_bin,
 		ir.OP_quote,
@@ -333,7 +339,7 @@ func TestARM64StringOperations(t *testing.T) {
 
 	for _, op := range strOps {
 		t.Run(op.String(), func(t *testing.T) {
-			prog := ir.Program{{Op: op}}
+			prog := ir.Program{ir.NewInsOp(op)}
 			assembler := NewAssembler(prog)
 			assembler.Name = "test_" + op.String()
 
@@ -346,7 +352,7 @@ func TestARM64StringOperations(t *testing.T) {
 }
 
 func TestARM64ControlOperations(t *testing.T) {
-	controlOps := []ir.OpCode{
+	controlOps := []ir.Op{
 		ir.OP_is_nil,
 		ir.OP_is_zero_1,
 		ir.OP_is_zero_2,
@@ -357,7 +363,7 @@ func TestARM64ControlOperations(t *testing.T) {
 
 	for _, op := range controlOps {
 		t.Run(op.String(), func(t *testing.T) {
-			prog := ir.Program{{Op: op}}
+			prog := ir.Program{ir.NewInsOp(op)}
 			assembler := NewAssembler(prog)
 			assembler.Name = "test_" + op.String()
 
@@ -372,12 +378,12 @@ func TestARM64ControlOperations(t *testing.T) {
 func TestARM64ComplexProgram(t *testing.T) {
 	// Create a more complex program that simulates encoding a struct
 	prog := ir.Program{
-		{Op: ir.OP_byte, Vi: int64('{')},
-		{Op: ir.OP_text, Vs: `"name":"`},
-		{Op: ir.OP_str},
-		{Op: ir.OP_text, Vs: `","age":`},
-		{Op: ir.OP_i64},
-		{Op: ir.OP_byte, Vi: int64('}')},
+		ir.NewInsVi(ir.OP_byte, '{'),
+		ir.NewInsVs(ir.OP_text, `"name":"`),
+		ir.NewInsOp(ir.OP_str),
+		ir.NewInsVs(ir.OP_text, `","age":`),
+		ir.NewInsOp(ir.OP_i64),
+		ir.NewInsVi(ir.OP_byte, '}'),
 	}
 
 	assembler := NewAssembler(prog)
@@ -389,19 +395,36 @@ func TestARM64ComplexProgram(t *testing.T) {
 	}
 }
 
-func TestARM64InstructionHandling(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_instructions"
+// assembleWith loads a function running fn in the frame of an encoder, with
+// the builtins the helpers jump to
+func assembleWith(t *testing.T, name string, fn func(a *Assembler)) *Assembler {
+	a := NewAssembler(ir.Program{})
+	a.Name = name
+	a.BaseAssembler.Init(func() {
+		a.prologue()
+		fn(a)
+		a.epilogue()
+		a.builtins()
+	})
+	if a.Load() == nil {
+		t.Errorf("Expected non-nil encoder for %s", name)
+	}
+	return a
+}
 
-	// Test that basic instruction handling doesn't panic
-	assembler.instr(&ir.Instr{Op: ir.OP_null})
-	assembler.instr(&ir.Instr{Op: ir.OP_bool})
-	assembler.instr(&ir.Instr{Op: ir.OP_i32})
+func TestARM64InstructionHandling(t *testing.T) {
+	assembleWith(t, "test_instructions", func(a *Assembler) {
+		// Test that basic instruction handling doesn't panic
+		for _, op := range []ir.Op{ir.OP_null, ir.OP_bool, ir.OP_i32} {
+			ins := ir.NewInsOp(op)
+			a.instr(&ins)
+		}
+	})
 }
 
 func TestARM64BuiltinFunctions(t *testing.T) {
 	prog := ir.Program{
-		{Op: ir.OP_null}, // This will call builtins during compilation
+		ir.NewInsOp(ir.OP_null), // This will call builtins during compilation
 	}
 
 	assembler := NewAssembler(prog)
@@ -422,59 +445,55 @@ func TestARM64ErrorHandling(t *testing.T) {
 		}
 	}()
 
-	prog := ir.Program{{Op: ir.OpCode(999)}} // Invalid operation
+	prog := ir.Program{ir.NewInsOp(ir.Op(255))} // Invalid operation
 	assembler := NewAssembler(prog)
-	assembler.compile() // This should panic
+	assembler.Load() // This should panic
 }
 
 func TestARM64HelperFunctions(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_helpers"
-
-	// Test that helper functions don't panic
-	assembler.xsave(_ARG0, _ARG1)
-	assembler.xload(_ARG0, _ARG1)
-	assembler.rbuf_rp()
-	assembler.check_size(10)
-	assembler.add_char('a')
-	assembler.add_long(0x61626364, 4)
-	assembler.add_text("test")
+	assembleWith(t, "test_helpers", func(a *Assembler) {
+		// Test that helper functions don't panic
+		a.xsave(_ARG0, _ARG1)
+		a.xload(_ARG0, _ARG1)
+		a.rbuf_rp()
+		a.check_size(10)
+		a.add_char('a')
+		a.add_long(0x61626364, 4)
+		a.add_text("test")
+	})
 }
 
 func TestARM64BufferHelpers(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_buffer_helpers"
-
-	// Test buffer helper functions
-	assembler.prep_buffer_X0()
-	assembler.save_buffer()
-	assembler.load_buffer_X0()
+	assembleWith(t, "test_buffer_helpers", func(a *Assembler) {
+		// Test buffer helper functions
+		a.prep_buffer_X0()
+		a.save_buffer()
+		a.load_buffer_X0()
+	})
 }
 
 func TestARM64StateManagement(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_state_management"
-
-	// Test state management functions
-	assembler.save_state()
-	assembler.drop_state(32)
+	assembleWith(t, "test_state_management", func(a *Assembler) {
+		// Test state management functions
+		a.save_state()
+		a.drop_state(32)
+	})
 }
 
 func TestARM64FunctionCalls(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_function_calls"
-
-	// Test function call helpers
-	assembler.save_c()
-	assembler.call_go(jit.Func(func() {}))
+	assembleWith(t, "test_function_calls", func(a *Assembler) {
+		// Test function call helpers
+		a.save_c()
+		a.call_go(jit.Func(func() {}))
+	})
 }
 
 // Benchmark tests for performance validation
 func BenchmarkARM64AssemblerCreation(b *testing.B) {
 	prog := ir.Program{
-		{Op: ir.OP_null},
-		{Op: ir.OP_bool},
-		{Op: ir.OP_i32},
+		ir.NewInsOp(ir.OP_null),
+		ir.NewInsOp(ir.OP_bool),
+		ir.NewInsOp(ir.OP_i32),
 	}
 
 	b.ResetTimer()
@@ -488,9 +507,9 @@ func BenchmarkARM64AssemblerCreation(b *testing.B) {
 
 func BenchmarkARM64AssemblerLoad(b *testing.B) {
 	prog := ir.Program{
-		{Op: ir.OP_null},
-		{Op: ir.OP_bool},
-		{Op: ir.OP_i32},
+		ir.NewInsOp(ir.OP_null),
+		ir.NewInsOp(ir.OP_bool),
+		ir.NewInsOp(ir.OP_i32),
 	}
 
 	assembler := NewAssembler(prog)
@@ -506,7 +525,7 @@ func BenchmarkARM64AssemblerLoad(b *testing.B) {
 }
 
 func BenchmarkARM64BasicOperations(b *testing.B) {
-	ops := []ir.OpCode{
+	ops := []ir.Op{
 		ir.OP_null,
 		ir.OP_bool,
 		ir.OP_i32,
@@ -516,7 +535,7 @@ func BenchmarkARM64BasicOperations(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		op := ops[i%len(ops)]
-		prog := ir.Program{{Op: op}}
+		prog := ir.Program{ir.NewInsOp(op)}
 		assembler := NewAssembler(prog)
 		assembler.Name = "benchmark_op"
 		encoder := assembler.Load()
@@ -528,12 +547,12 @@ func BenchmarkARM64BasicOperations(b *testing.B) {
 
 func BenchmarkARM64ComplexProgram(b *testing.B) {
 	prog := ir.Program{
-		{Op: ir.OP_byte, Vi: int64('{')},
-		{Op: ir.OP_text, Vs: `"test":`},
-		{Op: ir.OP_str},
-		{Op: ir.OP_text, Vs: `,"value":`},
-		{Op: ir.OP_i64},
-		{Op: ir.OP_byte, Vi: int64('}')},
+		ir.NewInsVi(ir.OP_byte, '{'),
+		ir.NewInsVs(ir.OP_text, `"test":`),
+		ir.NewInsOp(ir.OP_str),
+		ir.NewInsVs(ir.OP_text, `,"value":`),
+		ir.NewInsOp(ir.OP_i64),
+		ir.NewInsVi(ir.OP_byte, '}'),
 	}
 
 	assembler := NewAssembler(prog)
@@ -568,14 +587,14 @@ func TestARM64EncodingIntegration(t *testing.T) {
 
 	// Create a program that simulates encoding TestStruct
 	prog := ir.Program{
-		{Op: ir.OP_byte, Vi: int64('{')},
-		{Op: ir.OP_text, Vs: `"name":"`},
-		{Op: ir.OP_str},
-		{Op: ir.OP_text, Vs: `","age":`},
-		{Op: ir.OP_i64},
-		{Op: ir.OP_text, Vs: `","valid":`},
-		{Op: ir.OP_bool},
-		{Op: ir.OP_byte, Vi: int64('}')},
+		ir.NewInsVi(ir.OP_byte, '{'),
+		ir.NewInsVs(ir.OP_text, `"name":"`),
+		ir.NewInsOp(ir.OP_str),
+		ir.NewInsVs(ir.OP_text, `","age":`),
+		ir.NewInsOp(ir.OP_i64),
+		ir.NewInsVs(ir.OP_text, `","valid":`),
+		ir.NewInsOp(ir.OP_bool),
+		ir.NewInsVi(ir.OP_byte, '}'),
 	}
 
 	assembler := NewAssembler(prog)
@@ -597,12 +616,7 @@ func TestARM64EncodingIntegration(t *testing.T) {
 
 // Test ARM64 specific instruction generation
 func TestARM64InstructionGeneration(t *testing.T) {
-	assembler := NewAssembler(ir.Program{})
-	assembler.Name = "test_instruction_generation"
-
-	// Test prologue and epilogue
-	assembler.prologue()
-	assembler.epilogue()
+	assembler := assembleWith(t, "test_instruction_generation", func(a *Assembler) {})
 
 	// Should have generated some code
 	if assembler.Size() == 0 {
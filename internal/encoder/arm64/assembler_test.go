@@ -17,24 +17,45 @@
 package arm64_test
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"math"
+	"net"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/bytedance/sonic/internal/encoder"
+	"github.com/bytedance/sonic/internal/encoder/alg"
 	"github.com/bytedance/sonic/internal/encoder/arm64"
 	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/encoder/vars"
 	"github.com/bytedance/sonic/internal/rt"
+	"github.com/bytedance/sonic/utf8"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMain(m *testing.M) {
 	encoder.ForceUseJit()
+	arm64.SetCompiler(func(vt *rt.GoType, ex ...interface{}) (interface{}, error) {
+		var p ir.Program
+		var err error
+		if len(ex) > 1 && ex[1].(bool) {
+			p, err = encoder.NewCompiler().CompileTuple(vt.Pack(), ex[0].(bool))
+		} else {
+			p, err = encoder.NewCompiler().Compile(vt.Pack(), ex[0].(bool))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return arm64.NewAssembler(p).Load(), nil
+	})
 	m.Run()
 }
 
@@ -266,9 +287,54 @@ func TestAssembler_OpCode(t *testing.T) {
 			ins: []ir.Instr{ir.NewInsVs(ir.OP_text, "hello, world !!")},
 			exp: "hello, world !!",
 			val: nil,
+		}, {
+			key: "_OP_eface",
+			ins: []ir.Instr{ir.NewInsOp(ir.OP_eface)},
+			exp: `12345`,
+			val: &eface,
+		}, {
+			key: "_OP_iface",
+			ins: []ir.Instr{ir.NewInsOp(ir.OP_iface)},
+			exp: `12345`,
+			val: &iface,
+		}, {
+			key: "_OP_map_[iter,next,value]",
+			ins: mustCompile(map[string]map[int64]int{}),
+			exp: `{"asdf":{"-9223372036854775808":1234}}`,
+			val: &map[string]map[int64]int{"asdf": {math.MinInt64: 1234}},
+		}, {
+			key: "_OP_slice_[len,next]",
+			ins: mustCompile([][]int{}),
+			exp: `[[1,2,3],[4,5,6]]`,
+			val: &[][]int{{1, 2, 3}, {4, 5, 6}},
+		}, {
+			key: "_OP_marshal[_text]",
+			ins: []ir.Instr{ir.NewInsVt(ir.OP_marshal, reflect.TypeOf(JsonMarshalerValue(0)))},
+			exp: "123456789",
+			val: new(JsonMarshalerValue),
+		}, {
+			key: "_OP_marshal[_text]/ptr",
+			ins: []ir.Instr{ir.NewInsVt(ir.OP_marshal, reflect.TypeOf(new(JsonMarshalerValue)))},
+			exp: "123456789",
+			val: &jval,
+		}, {
+			key: "_OP_marshal[_text]/iface_v",
+			ins: []ir.Instr{ir.NewInsVt(ir.OP_marshal, vars.JsonMarshalerType)},
+			exp: "123456789",
+			val: &jifv,
+		}, {
+			key: "_OP_marshal[_text]/iface_p",
+			ins: []ir.Instr{ir.NewInsVt(ir.OP_marshal, vars.JsonMarshalerType)},
+			exp: "123456789",
+			val: &jifp,
+		}, {
+			key: "_OP_recurse",
+			ins: mustCompile(rec),
+			exp: `{"a":123,"p":{"a":789,"p":{"a":777,"q":[{"a":999,"q":null,"r":{"` +
+				`xxx":{"a":333,"q":null,"r":null,"z":0}},"z":222}],"r":null,"z":8` +
+				`88},"q":null,"r":null,"z":666},"q":null,"r":null,"z":456}`,
+			val: &rec,
 		},
-		// TODO: Add more complex tests like _OP_bin, _OP_quote, _OP_map, _OP_slice, _OP_marshal, _OP_recurse
-		// These require more complete ARM64 JIT implementation
 	}
 	for _, tv := range tests {
 		t.Run(tv.key, func(t *testing.T) {
@@ -277,6 +343,395 @@ func TestAssembler_OpCode(t *testing.T) {
 	}
 }
 
+func testEncodeFlags(t *testing.T, v interface{}, fv uint64) string {
+	m := []byte(nil)
+	f := arm64.NewAssembler(mustCompile(v)).Load()
+	e := f(&m, rt.UnpackEface(v).Value, new(vars.Stack), fv)
+	assert.Nil(t, e)
+	return string(m)
+}
+
+func TestAssembler_Map(t *testing.T) {
+	type T struct {
+		A string            `json:"a"`
+		M map[string]string `json:"m"`
+		Z int               `json:"z"`
+	}
+	for _, v := range []interface{}{
+		map[string]int{"b": 2, "a": 1, "c": 3},
+		map[int]string{3: "c", -1: "a", 20: "b"},
+		map[string]int{},
+		&T{A: "x", M: map[string]string{"k2": "v2", "k1": "v1"}, Z: 1},
+		&T{A: "y"},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 1<<alg.BitSortMapKeys))
+	}
+
+	/* single keys are ordered without sorting */
+	assert.Equal(t, `{"a":1}`, testEncodeFlags(t, map[string]int{"a": 1}, 0))
+
+	/* nil maps are null, unless BitNoNullSliceOrMap is set */
+	assert.Equal(t, `null`, testEncodeFlags(t, map[string]int(nil), 0))
+	assert.Equal(t, `{}`, testEncodeFlags(t, map[string]int(nil), 1<<alg.BitNoNullSliceOrMap))
+}
+
+func TestAssembler_SortMapKeys(t *testing.T) {
+	ms := make(map[string]int)
+	mi := make(map[int64]bool)
+	for i := 0; i < 200; i++ {
+		ms["k"+strconv.Itoa(i*7919%1000)] = i
+		mi[int64(i*7919%1000-500)] = i%2 == 0
+	}
+
+	/* the keys come out in the order of encoding/json, on every run */
+	for _, v := range []interface{}{ms, mi, &ms} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, string(exp), testEncodeFlags(t, v, 1<<alg.BitSortMapKeys))
+			ret, err := encoder.Encode(v, encoder.SortMapKeys)
+			assert.Nil(t, err)
+			assert.Equal(t, string(exp), string(ret))
+		}
+	}
+}
+
+func TestAssembler_Slice(t *testing.T) {
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	for _, v := range []interface{}{
+		[]int{1, -2, 3},
+		[]int{},
+		[]string{"a", "", "c"},
+		[]*T{{A: 1, B: "x"}, nil, {A: 2}},
+		[][]int{{1, 2}, {}, nil, {3}},
+		&struct {
+			S []int `json:"s"`
+			Z int   `json:"z"`
+		}{S: []int{7}, Z: 1},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 0))
+	}
+
+	/* nil slices are null, unless BitNoNullSliceOrMap is set */
+	assert.Equal(t, `null`, testEncodeFlags(t, []int(nil), 0))
+	assert.Equal(t, `[]`, testEncodeFlags(t, []int(nil), 1<<alg.BitNoNullSliceOrMap))
+}
+
+func TestAssembler_CallCPreservesSP(t *testing.T) {
+	type T struct {
+		F float64 `json:"f"`
+		S string  `json:"s"`
+		G float32 `json:"g"`
+		I int64   `json:"i"`
+		U uint64  `json:"u"`
+	}
+
+	/* f64toa, f32toa, i64toa, u64toa and quote all use X9-X15, and SP.p
+	 * has to survive every one of them for the next element to be right */
+	f64 := make([]float64, 64)
+	f32 := make([]float32, 64)
+	i64 := make([]int64, 64)
+	str := make([]string, 64)
+	obj := make([]T, 16)
+	for i := range f64 {
+		f64[i] = float64(i) * 1.25e-3
+		f32[i] = float32(i) * -0.5
+		i64[i] = int64(i) * -1234567
+		str[i] = strconv.Itoa(i) + "\t\"x"
+	}
+	for i := range obj {
+		obj[i] = T{F: math.Pi * float64(i), S: str[i], G: f32[i], I: i64[i], U: uint64(i) << 40}
+	}
+	for _, v := range []interface{}{&f64, &f32, &i64, &str, &obj} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 0))
+	}
+}
+
+func TestAssembler_CondComma(t *testing.T) {
+	elems := func(n int, set bool) ir.Program {
+		p := ir.Program{}
+		p.Int(ir.OP_byte, '[')
+		if set {
+			p.Add(ir.OP_cond_set)
+		}
+		for i := 0; i < n; i++ {
+			j := p.PC()
+			p.Add(ir.OP_cond_testc)
+			p.Int(ir.OP_byte, ',')
+			p.Pin(j)
+			p.Int(ir.OP_byte, 'x')
+		}
+		p.Int(ir.OP_byte, ']')
+		return p
+	}
+
+	/* the first element after cond_set skips the comma, the flag is cleared by it */
+	testOpCode(t, (*int)(nil), `[x]`, nil, elems(1, true))
+	testOpCode(t, (*int)(nil), `[x,x,x]`, nil, elems(3, true))
+	testOpCode(t, (*int)(nil), `[]`, nil, elems(0, true))
+
+	/* without cond_set every element is preceded by a comma */
+	testOpCode(t, (*int)(nil), `[,x,x]`, nil, elems(2, false))
+
+	/* nested containers keep their own state across save / load */
+	for _, v := range []interface{}{
+		[]int{1, 2, 3},
+		[][]int{{1, 2}, {3}},
+		map[string]int{"a": 1, "b": 2},
+		&struct {
+			A int            `json:"a"`
+			B []int          `json:"b,omitempty"`
+			C map[string]int `json:"c"`
+			D int            `json:"d"`
+		}{A: 1, C: map[string]int{"x": 1}, D: 2},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 1<<alg.BitSortMapKeys))
+	}
+}
+
+func TestAssembler_Unsupported(t *testing.T) {
+	for _, v := range []interface{}{
+		&struct {
+			C chan int `json:"c"`
+		}{},
+		&struct {
+			A int    `json:"a"`
+			F func() `json:"f"`
+		}{A: 1},
+		make(chan int),
+	} {
+		_, exp := json.Marshal(v)
+		m := []byte(nil)
+		f := arm64.NewAssembler(mustCompile(v)).Load()
+		err := f(&m, rt.UnpackEface(v).Value, new(vars.Stack), 0)
+		var ute *json.UnsupportedTypeError
+		assert.True(t, errors.As(err, &ute), "%T", err)
+		assert.Equal(t, exp.Error(), err.Error())
+	}
+}
+
+type jsonMarshalerValue int
+
+func (v jsonMarshalerValue) MarshalJSON() ([]byte, error) {
+	if v < 0 {
+		return nil, errors.New("negative value")
+	}
+	return []byte(`{"v":` + strconv.Itoa(int(v)) + `}`), nil
+}
+
+type jsonMarshalerPtr struct{ N int }
+
+func (v *jsonMarshalerPtr) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(v.N)), nil
+}
+
+func TestAssembler_Marshaler(t *testing.T) {
+	type T struct {
+		At  time.Time          `json:"at"`
+		IP  net.IP             `json:"ip"`
+		V   jsonMarshalerValue `json:"v"`
+		P   *jsonMarshalerPtr  `json:"p"`
+		Q   jsonMarshalerPtr   `json:"q"`
+		I   json.Marshaler     `json:"i"`
+		Nil json.Marshaler     `json:"nil"`
+	}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	for _, v := range []interface{}{
+		at,
+		net.ParseIP("192.168.0.1"),
+		jsonMarshalerValue(1),
+		&jsonMarshalerPtr{N: 2},
+		&T{At: at, IP: net.ParseIP("::1"), V: 3, P: &jsonMarshalerPtr{N: 4}, Q: jsonMarshalerPtr{N: 5}, I: jsonMarshalerValue(6)},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 0))
+	}
+
+	/* errors from the marshaler are returned */
+	m := []byte(nil)
+	v := jsonMarshalerValue(-1)
+	f := arm64.NewAssembler(mustCompile(v)).Load()
+	assert.NotNil(t, f(&m, rt.UnpackEface(v).Value, new(vars.Stack), 0))
+}
+
+type textKey int
+
+func (k textKey) MarshalText() ([]byte, error) {
+	if k < 0 {
+		return nil, errors.New("negative key")
+	}
+	return []byte("<" + strconv.Itoa(int(k)) + ">"), nil
+}
+
+type textKeyPtr struct{ N int }
+
+func (k *textKeyPtr) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(k.N)), nil
+}
+
+func TestAssembler_TextMarshalerKeys(t *testing.T) {
+	for _, v := range []interface{}{
+		map[textKey]int{1: 1, 20: 2, 0: 3},
+		map[*textKeyPtr]string{{N: 1}: "a", nil: "b"},
+		map[textKey]bool{},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 1<<alg.BitSortMapKeys))
+		assert.JSONEq(t, string(exp), testEncodeFlags(t, v, 0))
+	}
+
+	/* the keys are quoted even if the values are not */
+	v := map[textKey]textKey{1: 2}
+	assert.Equal(t, `{"<1>":<2>}`, testEncodeFlags(t, v, 1<<alg.BitNoQuoteTextMarshaler))
+	assert.Equal(t, `{"<1>":<2>}`, testEncodeFlags(t, v, 1<<alg.BitNoQuoteTextMarshaler|1<<alg.BitSortMapKeys))
+
+	/* errors from the marshaler are returned */
+	for _, fv := range []uint64{0, 1 << alg.BitSortMapKeys} {
+		m := []byte(nil)
+		v := map[textKey]int{-1: 1}
+		f := arm64.NewAssembler(mustCompile(v)).Load()
+		assert.EqualError(t, f(&m, rt.UnpackEface(v).Value, new(vars.Stack), fv), "negative key")
+	}
+}
+
+type ifaceArea interface {
+	Area() int
+}
+
+type ifaceRect struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+func (r ifaceRect) Area() int { return r.W * r.H }
+
+type ifaceFailing int
+
+func (ifaceFailing) Area() int { return 0 }
+
+func (ifaceFailing) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("failing value")
+}
+
+func TestAssembler_Interface(t *testing.T) {
+	type T struct {
+		A interface{} `json:"a"`
+		S ifaceArea   `json:"s"`
+		P ifaceArea   `json:"p"`
+	}
+	for _, v := range []interface{}{
+		&T{A: 1},
+		&T{A: "x", S: ifaceRect{W: 2, H: 3}, P: &ifaceRect{W: 4, H: 5}},
+		&T{A: ifaceRect{W: 1}, P: (*ifaceRect)(nil)},
+		&T{},
+		[]interface{}{1, "a", nil, 2.5, true, []int{1}, map[string]int{"k": 1}, ifaceRect{H: 1}},
+		[]ifaceArea{ifaceRect{W: 1, H: 1}, nil},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 1<<alg.BitSortMapKeys))
+	}
+
+	/* errors from the dynamic values are returned */
+	for _, v := range []interface{}{
+		&T{A: ifaceFailing(1)},
+		&T{A: &T{A: ifaceFailing(2)}},
+		&T{S: ifaceFailing(3)},
+		&T{P: ifaceFailing(4)},
+		[]interface{}{1, make(chan int)},
+	} {
+		m := []byte(nil)
+		f := arm64.NewAssembler(mustCompile(v)).Load()
+		assert.NotNil(t, f(&m, rt.UnpackEface(v).Value, new(vars.Stack), 0))
+	}
+}
+
+func TestAssembler_EfaceHoldingPointer(t *testing.T) {
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	type W struct {
+		V interface{} `json:"v"`
+	}
+	for _, v := range []interface{}{
+		&T{A: 1, B: "x"},
+		W{V: &T{A: 2}},
+		W{V: (*T)(nil)},
+		&W{V: &W{V: &T{B: "y"}}},
+		[]interface{}{&T{A: 3}, (*T)(nil), new(int)},
+	} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.Equal(t, string(exp), testEncodeFlags(t, v, 0))
+	}
+}
+
+func stdEncode(t *testing.T, v interface{}, html bool) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(html)
+	assert.Nil(t, enc.Encode(v))
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func TestAssembler_StringEscaping(t *testing.T) {
+	type T struct {
+		S string `json:"s"`
+		Q string `json:"q,string"`
+	}
+	for _, s := range []string{
+		"",
+		"plain",
+		`a"b`,
+		"a\\b",
+		"line\nbreak",
+		"tab\there",
+		"\u0000\u001f\u007f",
+		"<script>&</script>",
+		"中文字符串",
+		"emoji \U0001F600",
+		strings.Repeat("\"<&>\n", 1024),
+	} {
+		v := &T{S: s, Q: s}
+
+		/* the JIT output is unescaped for HTML, like encoding/json with SetEscapeHTML(false) */
+		out := testEncodeFlags(t, v, 0)
+		assert.Equal(t, stdEncode(t, v, false), out)
+
+		/* EscapeHTML selects the quoter escaping the HTML characters */
+		out = testEncodeFlags(t, v, 1<<alg.BitEscapeHTML)
+		assert.Equal(t, stdEncode(t, v, true), out)
+		m := map[string]string{s: s, "<": ">"}
+		out = testEncodeFlags(t, m, 1<<alg.BitEscapeHTML|1<<alg.BitSortMapKeys)
+		assert.Equal(t, stdEncode(t, m, true), out)
+
+		/* the output without EscapeHTML can be escaped as a whole too */
+		out = testEncodeFlags(t, &s, 0)
+		assert.Equal(t, stdEncode(t, s, true), string(encoder.HTMLEscape(nil, []byte(out))))
+	}
+
+	/* invalid UTF-8 is copied through, and corrected by ValidateString */
+	for _, s := range []string{"\xff", "a\xc3b", "\xed\xa0\x80", "ok\xe4\xb8"} {
+		out := testEncodeFlags(t, &s, 0)
+		assert.Equal(t, `"`+s+`"`, out)
+		assert.Equal(t, stdEncode(t, s, false), string(utf8.CorrectWith(nil, []byte(out), `\ufffd`)))
+	}
+}
+
 func TestAssembler_StringMoreSpace(t *testing.T) {
 	p := ir.Program{ir.NewInsOp(ir.OP_str)}
 	m := make([]byte, 0, 8)
@@ -288,3 +743,26 @@ func TestAssembler_StringMoreSpace(t *testing.T) {
 	assert.Nil(t, e)
 	spew.Dump(m)
 }
+
+func TestAssembler_MoreSpaceGrows(t *testing.T) {
+	ints := make([]int, 100000)
+	strs := make([]string, 10000)
+	for i := range ints {
+		ints[i] = i*7919 - 50000
+	}
+	for i := range strs {
+		strs[i] = strings.Repeat("\"x", i%16)
+	}
+	for _, v := range []interface{}{ints, strs} {
+		exp, err := json.Marshal(v)
+		assert.Nil(t, err)
+
+		/* start from a tiny buffer, so that it is grown many times */
+		m := make([]byte, 0, 8)
+		f := arm64.NewAssembler(mustCompile(v)).Load()
+		e := f(&m, rt.UnpackEface(v).Value, new(vars.Stack), 0)
+		assert.Nil(t, e)
+		assert.Equal(t, len(exp), len(m))
+		assert.Equal(t, string(exp), string(m))
+	}
+}
@@ -0,0 +1,40 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arm64
+
+import (
+	"github.com/bytedance/sonic/internal/encoder"
+	"github.com/bytedance/sonic/internal/encoder/ir"
+	"github.com/bytedance/sonic/internal/encoder/vars"
+)
+
+func init() {
+	encoder.RegisterBackend(&encoder.Backend{
+		Assemble:           assemble,
+		SetCompiler:        SetCompiler,
+		EncodeTypedPointer: EncodeTypedPointer,
+	})
+}
+
+func assemble(p ir.Program, name string) vars.Encoder {
+	as := NewAssembler(p)
+	as.Name = name
+	return as.Load()
+}
@@ -0,0 +1,65 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arm64
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bytedance/sonic/internal/encoder"
+	"github.com/bytedance/sonic/internal/encoder/vars"
+	"github.com/bytedance/sonic/internal/rt"
+	"github.com/bytedance/sonic/option"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister_UsesJIT(t *testing.T) {
+	if vars.UseVM {
+		t.Skip("VM forced with SONIC_ENCODER_USE_VM")
+	}
+	type data struct{ X int }
+	buf, err := encoder.Encode(data{X: 1}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"X":1}`, string(buf))
+
+	prog := vars.GetProgram(rt.UnpackType(reflect.TypeOf(data{})))
+	assert.IsType(t, vars.Encoder(nil), prog)
+}
+
+func TestRegister_Pretouch(t *testing.T) {
+	type subA struct{}
+	type subB struct{}
+	type subC struct{}
+	type data struct {
+		SubA subA
+		SubB subB
+		SubC subC
+	}
+
+	err := encoder.Pretouch(reflect.TypeOf(data{}),
+		option.WithCompileMaxInlineDepth(1),
+		option.WithCompileRecursiveDepth(1000),
+	)
+	assert.NoError(t, err)
+	assert.NotNil(t, vars.GetProgram(rt.UnpackType(reflect.TypeOf(data{}))))
+	assert.NotNil(t, vars.GetProgram(rt.UnpackType(reflect.TypeOf(subA{}))))
+	assert.NotNil(t, vars.GetProgram(rt.UnpackType(reflect.TypeOf(subB{}))))
+	assert.NotNil(t, vars.GetProgram(rt.UnpackType(reflect.TypeOf(subC{}))))
+}
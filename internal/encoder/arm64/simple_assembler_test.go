@@ -23,7 +23,6 @@ import (
 	"testing"
 	"unsafe"
 
-	"github.com/bytedance/sonic/internal/encoder"
 	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/encoder/vars"
 	"github.com/bytedance/sonic/internal/rt"
@@ -49,10 +48,9 @@ func TestBasicNullEncoding(t *testing.T) {
 
 	var buf []byte
 	var stack vars.Stack
-	vt := rt.UnpackType((*interface{})(nil))
 	var vp unsafe.Pointer
 
-	err := f(&buf, vt, vp, &stack, 0)
+	err := f(&buf, vp, &stack, 0)
 	assert.Nil(t, err)
 	assert.Equal(t, "null", string(buf))
 }
@@ -88,7 +86,7 @@ func TestBasicIntegerEncoding(t *testing.T) {
 		name     string
 		value    interface{}
 		expected string
-		opcode   ir.OpCode
+		opcode   ir.Op
 	}{
 		{"int8", int8(42), "42", ir.OP_i8},
 		{"int16", int16(42), "42", ir.OP_i16},
@@ -7,9 +7,9 @@ import (
 	"testing"
 	"reflect"
 
+	"github.com/bytedance/sonic/internal/encoder"
 	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/jit"
-	"github.com/bytedance/sonic/internal/rt"
 )
 
 // TestBasicFunctionality tests basic ARM64 JIT functionality
@@ -44,7 +44,7 @@ func TestBasicFunctionality(t *testing.T) {
 	t.Run("IRProgramGeneration", func(t *testing.T) {
 		// Create a simple program that encodes a null value
 		program := ir.Program{
-			{Op: ir.OP_null},
+			ir.NewInsOp(ir.OP_null),
 		}
 
 		if len(program) != 1 {
@@ -58,21 +58,17 @@ func TestBasicFunctionality(t *testing.T) {
 
 	// Test encoder creation
 	t.Run("EncoderCreation", func(t *testing.T) {
-		encoder := NewEncoder("test")
-		if encoder == nil {
+		f := assemble(ir.Program{ir.NewInsOp(ir.OP_null)}, "test")
+		if f == nil {
 			t.Fatal("Expected non-nil encoder")
 		}
-
-		if encoder.name != "test" {
-			t.Errorf("Expected name 'test', got '%s'", encoder.name)
-		}
 	})
 
 	// Test assembler creation
 	t.Run("AssemblerCreation", func(t *testing.T) {
 		program := ir.Program{
-			{Op: ir.OP_null},
-			{Op: ir.OP_bool},
+			ir.NewInsOp(ir.OP_null),
+			ir.NewInsOp(ir.OP_bool),
 		}
 
 		assembler := NewAssembler(program)
@@ -87,20 +83,13 @@ func TestBasicFunctionality(t *testing.T) {
 
 	// Test basic compilation (without actually running the code)
 	t.Run("BasicCompilation", func(t *testing.T) {
-		encoder := NewEncoder("basic_test")
-
-		// Try to compile a basic type
-		goType := rt.UnpackType(reflect.TypeOf(42))
-		_, err := encoder.Compile(goType)
-
-		// We expect this to not panic, even if it doesn't fully work yet
+		program, err := encoder.NewCompiler().Compile(reflect.TypeOf(42), false)
 		if err != nil {
-			t.Logf("Compilation returned error (expected for now): %v", err)
+			t.Fatalf("Compilation failed: %v", err)
 		}
 
-		// Check that assembler was created
-		if encoder.assembler == nil {
-			t.Error("Expected assembler to be created after compilation")
+		if f := assemble(program, "basic_test"); f == nil {
+			t.Error("Expected non-nil encoder after compilation")
 		}
 	})
 }
@@ -0,0 +1,78 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package arm64
+
+import (
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/encoder/alg"
+	"github.com/bytedance/sonic/internal/encoder/prim"
+	"github.com/bytedance/sonic/internal/encoder/vars"
+	"github.com/bytedance/sonic/internal/rt"
+)
+
+var compiler func(*rt.GoType, ...interface{}) (interface{}, error)
+
+// SetCompiler sets the function used to compile the encoders of the
+// dynamic types met by interfaces and recursive types.
+func SetCompiler(c func(*rt.GoType, ...interface{}) (interface{}, error)) {
+	compiler = c
+}
+
+// EncodeTypedPointer encodes the value of type vt stored at vp, it is called
+// by the JIT code for interfaces and recursive types.
+func EncodeTypedPointer(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars.Stack, fv uint64) error {
+	if vt == nil {
+		return prim.EncodeNil(buf)
+	} else if fn, err := vars.FindOrCompile(vt, (fv&(1<<alg.BitPointerValue)) != 0, compiler); err != nil {
+		return err
+	} else if vt.Indirect() {
+		return fn.(vars.Encoder)(buf, *vp, sb, fv)
+	} else {
+		return fn.(vars.Encoder)(buf, unsafe.Pointer(vp), sb, fv)
+	}
+}
+
+// EncodeTypedTuple encodes the struct of type vt stored at vp as an array, it
+// is called by the JIT code when StructAsArray is set.
+func EncodeTypedTuple(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars.Stack, fv uint64) error {
+	if fn, err := vars.FindOrCompileTuple(vt, (fv&(1<<alg.BitPointerValue)) != 0, compiler); err != nil {
+		return err
+	} else if vt.Indirect() {
+		return fn.(vars.Encoder)(buf, *vp, sb, fv)
+	} else {
+		return fn.(vars.Encoder)(buf, unsafe.Pointer(vp), sb, fv)
+	}
+}
+
+// encodeBase64 appends the quoted base64 encoding of the []byte at vp to buf,
+// there is no native base64 encoder for the JIT code to call on ARM64.
+func encodeBase64(buf *[]byte, vp unsafe.Pointer) {
+	*buf = rt.EncodeBase64(*buf, *(*[]byte)(vp))
+}
+
+// quoteHTML appends the quoted string at vp to buf with the HTML characters
+// escaped, it is called by the JIT code instead of the native quoter when
+// EscapeHTML is set.
+func quoteHTML(buf *[]byte, vp *string, double bool) {
+	n := len(*buf)
+	*buf = alg.Quote(*buf, *vp, double)
+	*buf = append((*buf)[:n], alg.HtmlEscape(nil, (*buf)[n:])...)
+}
@@ -68,7 +68,7 @@ func TestSyntaxValidation(t *testing.T) {
 // TestInstructionMapping validates that all opcodes have corresponding implementations
 func TestInstructionMapping(t *testing.T) {
 	// Test that all basic opcodes are mapped in the instruction table
-	opcodes := []ir.OpCode{
+	opcodes := []ir.Op{
 		ir.OP_null,
 		ir.OP_empty_arr,
 		ir.OP_empty_obj,
@@ -149,7 +149,6 @@ func TestStackConstants(t *testing.T) {
 
 	// Test register sets
 	assert.NotNil(t, _REG_ffi)
-	assert.NotNil(t, _REG_b64)
 	assert.NotNil(t, _REG_all)
 	assert.NotNil(t, _REG_ms)
 	assert.NotNil(t, _REG_enc)
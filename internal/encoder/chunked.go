@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+import (
+    `fmt`
+    `io`
+    `unicode/utf8`
+
+    `github.com/bytedance/sonic/internal/encoder/vars`
+)
+
+// ChunkedEncoder is a StreamEncoder which hands the output off to a callback
+// in chunks of a fixed size, instead of writing into an io.Writer.
+//
+// The value is encoded into a buffer of one chunk, which is handed off every
+// time it is full instead of being grown, so the whole document is never held
+// in memory. Therefore a value which fails to encode may have been handed off
+// in part. Values encoded with separators, or indented with
+// NoValidateJSONMarshaler, are still encoded as a whole before being handed off.
+type ChunkedEncoder struct {
+    StreamEncoder
+    cw  *chunkWriter
+    out io.Writer
+    buf []byte
+    tmp []byte
+    err error
+}
+
+// NewChunkedEncoder returns a new encoder that calls flush every time size bytes
+// of JSON are produced, or an error if size is not positive. The chunk is only
+// valid until flush returns, since the buffer is reused for the following chunks.
+func NewChunkedEncoder(size int, flush func(chunk []byte) error) (*ChunkedEncoder, error) {
+    if size <= 0 {
+        return nil, fmt.Errorf("invalid chunk size: %d", size)
+    }
+    cw := &chunkWriter{size: size, fn: flush}
+    return &ChunkedEncoder{StreamEncoder: StreamEncoder{w: cw}, cw: cw, buf: make([]byte, 0, size)}, nil
+}
+
+// Encode encodes val, handing off the chunks as they are filled. The bytes
+// which do not fill a chunk are kept for the following values, or Flush.
+func (enc *ChunkedEncoder) Encode(val interface{}) error {
+    indent := enc.indent != "" || enc.prefix != ""
+    if enc.item != "" || enc.key != "" || (indent && enc.Opts & NoValidateJSONMarshaler != 0) {
+        return enc.StreamEncoder.Encode(val)
+    }
+
+    /* indent the JSON on its way to the chunks */
+    var iw *indentWriter
+    if enc.out = enc.cw; indent {
+        iw = newIndentWriter(enc.cw, enc.prefix, enc.indent)
+        enc.out = iw
+    }
+
+    /* let the encoder hand off its buffer instead of growing it */
+    stk := vars.NewStack()
+    stk.SetSink(enc.sink)
+    err := encodeIntoStack(&enc.buf, val, enc.Opts, stk)
+
+    /* hand off the rest of the value */
+    if err == nil {
+        enc.write(enc.buf)
+
+        // according to standard library, terminate each value with a newline...
+        if enc.Opts & NoEncoderNewline == 0 && enc.err == nil {
+            if iw != nil {
+                *iw.buf = append(*iw.buf, '\n')
+            } else {
+                _, enc.err = enc.cw.Write([]byte{'\n'})
+            }
+        }
+    }
+    if iw != nil {
+        if err == nil && enc.err == nil {
+            enc.err = iw.Flush()
+        }
+        iw.Free()
+    }
+
+    /* the callback failed while encoding */
+    if err == nil {
+        err = enc.err
+    }
+    enc.buf = enc.buf[:0]
+    enc.out = nil
+    enc.err = nil
+    return err
+}
+
+// Flush hands off the remaining bytes which are fewer than a chunk, if any.
+// It should be called after the last value is encoded.
+func (enc *ChunkedEncoder) Flush() error {
+    return enc.cw.Flush()
+}
+
+// sink takes the whole buffer of the encoder, except a partial UTF-8 sequence
+// at its end, which the escaping and the validation of strings need whole.
+func (enc *ChunkedEncoder) sink(buf []byte) int {
+    n := len(buf)
+    if enc.Opts & (EscapeHTML | ValidateString) != 0 {
+        n = runeEnd(buf)
+    }
+    enc.write(buf[:n])
+    return n
+}
+
+func (enc *ChunkedEncoder) write(buf []byte) {
+    if enc.err != nil || len(buf) == 0 {
+        return
+    }
+    if enc.Opts & EscapeHTML != 0 {
+        enc.tmp = HTMLEscape(enc.tmp[:0], buf)
+        buf = enc.tmp
+    }
+    _, enc.err = enc.out.Write(encodeFinish(buf, enc.Opts &^ EscapeHTML))
+}
+
+// runeEnd returns the length of buf without the partial UTF-8 sequence at its
+// end, if any.
+func runeEnd(buf []byte) int {
+    for i := len(buf) - 1; i >= 0 && i > len(buf) - utf8.UTFMax; i-- {
+        if utf8.RuneStart(buf[i]) {
+            if !utf8.FullRune(buf[i:]) {
+                return i
+            }
+            break
+        }
+    }
+    return len(buf)
+}
+
+type chunkWriter struct {
+    size int
+    buf  []byte
+    fn   func([]byte) error
+}
+
+func (self *chunkWriter) Write(p []byte) (int, error) {
+    n := len(p)
+    for len(self.buf) + len(p) >= self.size {
+        var err error
+        m := self.size - len(self.buf)
+
+        /* hand off the input directly if nothing is pending */
+        if len(self.buf) == 0 {
+            err = self.fn(p[:m])
+        } else {
+            self.buf = append(self.buf, p[:m]...)
+            err = self.fn(self.buf)
+            self.buf = self.buf[:0]
+        }
+
+        /* stop at the first failed chunk */
+        if p = p[m:]; err != nil {
+            return n - len(p), err
+        }
+    }
+
+    /* keep the rest until the chunk is full */
+    self.buf = append(self.buf, p...)
+    return n, nil
+}
+
+func (self *chunkWriter) Flush() error {
+    if len(self.buf) == 0 {
+        return nil
+    }
+    err := self.fn(self.buf)
+    self.buf = self.buf[:0]
+    return err
+}
@@ -18,11 +18,13 @@ package encoder
 
 import (
 	"reflect"
+	"sort"
 	"unsafe"
 
 	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/encoder/vars"
 	"github.com/bytedance/sonic/internal/encoder/vm"
+	"github.com/bytedance/sonic/internal/registry"
 	"github.com/bytedance/sonic/internal/resolver"
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/option"
@@ -38,7 +40,7 @@ func ForceUseVM() {
 var encodeTypedPointer func(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars.Stack, fv uint64) error
 
 func makeEncoderVM(vt *rt.GoType, ex ...interface{}) (interface{}, error) {
-	pp, err := NewCompiler().Compile(vt.Pack(), ex[0].(bool))
+	pp, err := NewCompiler().compileEx(vt, ex...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,11 +89,26 @@ func pretouchRec(vtm map[reflect.Type]uint8, opts option.CompileOptions) error {
 	return pretouchRec(next, opts)
 }
 
+const (
+	_TM_check = iota
+	_TM_object
+	_TM_tuple
+)
+
+const (
+	_SM_check = iota
+	_SM_declared
+	_SM_sorted
+)
+
 type Compiler struct {
 	opts option.CompileOptions
 	pv   bool
+	tm   int
+	sm   int
 	tab  map[reflect.Type]bool
 	rec  map[reflect.Type]uint8
+	reg  *registry.Snapshot
 }
 
 func NewCompiler() *Compiler {
@@ -99,6 +116,7 @@ func NewCompiler() *Compiler {
 		opts: option.DefaultCompileOptions(),
 		tab:  map[reflect.Type]bool{},
 		rec:  map[reflect.Type]uint8{},
+		reg:  registry.Load(),
 	}
 }
 
@@ -123,7 +141,26 @@ func (self *Compiler) rescue(ep *error) {
 func (self *Compiler) Compile(vt reflect.Type, pv bool) (ret ir.Program, err error) {
 	defer self.rescue(&err)
 	self.compileOne(&ret, 0, vt, pv)
-	return
+	return ret.Fuse(), nil
+}
+
+// compileEx compiles vt for the program caches, which pass pv, followed by
+// true for the tuple programs, see vars.FindOrCompileTuple.
+func (self *Compiler) compileEx(vt *rt.GoType, ex ...interface{}) (ir.Program, error) {
+	if len(ex) > 1 && ex[1].(bool) {
+		return self.CompileTuple(vt.Pack(), ex[0].(bool))
+	} else {
+		return self.Compile(vt.Pack(), ex[0].(bool))
+	}
+}
+
+// CompileTuple compiles the struct vt laid out as an array for StructAsArray,
+// the structs nested in it are laid out the same way.
+func (self *Compiler) CompileTuple(vt reflect.Type, pv bool) (ret ir.Program, err error) {
+	defer self.rescue(&err)
+	self.tm = _TM_tuple
+	self.compileOne(&ret, 0, vt, pv)
+	return ret.Fuse(), nil
 }
 
 func (self *Compiler) compileOne(p *ir.Program, sp int, vt reflect.Type, pv bool) {
@@ -137,6 +174,15 @@ func (self *Compiler) compileOne(p *ir.Program, sp int, vt reflect.Type, pv bool
 func (self *Compiler) tryCompileMarshaler(p *ir.Program, vt reflect.Type, pv bool) bool {
 	pt := reflect.PtrTo(vt)
 
+	/* `json.RawMessage` is copied as it is, without calling the marshaler */
+	if vt == vars.JsonRawMessageType {
+		p.Add(ir.OP_raw)
+		return true
+	}
+	if vt.Kind() == reflect.Ptr && vt.Elem() == vars.JsonRawMessageType {
+		return false
+	}
+
 	/* check for addressable `json.Marshaler` with pointer receiver */
 	if pv && pt.Implements(vars.JsonMarshalerType) {
 		addMarshalerOp(p, ir.OP_marshal_p, pt, vars.JsonMarshalerType)
@@ -246,11 +292,19 @@ func (self *Compiler) compilePtr(p *ir.Program, sp int, vt reflect.Type) {
 }
 
 func (self *Compiler) compilePtrBody(p *ir.Program, sp int, vt reflect.Type) {
+	j := -1
+	if vt.Kind() == reflect.Struct {
+		j = p.PC()
+		p.Add(ir.OP_ref)
+	}
 	p.Tag(sp)
 	p.Add(ir.OP_save)
 	p.Add(ir.OP_deref)
 	self.compileOne(p, sp+1, vt, true)
 	p.Add(ir.OP_drop)
+	if j >= 0 {
+		p.Pin(j)
+	}
 }
 
 func (self *Compiler) compileMap(p *ir.Program, sp int, vt reflect.Type) {
@@ -348,7 +402,7 @@ func (self *Compiler) compileMapBodyTextKey(p *ir.Program, vk reflect.Type) {
 
 func (self *Compiler) compileMapBodyUtextKey(p *ir.Program, vk reflect.Type) {
 	if vk.Kind() != reflect.Ptr {
-		addMarshalerOp(p, ir.OP_marshal_text, vk, vars.EncodingTextMarshalerType)
+		addMarshalerOp(p, ir.OP_map_key_utext, vk, vars.EncodingTextMarshalerType)
 	} else {
 		self.compileMapBodyUtextPtr(p, vk)
 	}
@@ -357,7 +411,7 @@ func (self *Compiler) compileMapBodyUtextKey(p *ir.Program, vk reflect.Type) {
 func (self *Compiler) compileMapBodyUtextPtr(p *ir.Program, vk reflect.Type) {
 	i := p.PC()
 	p.Add(ir.OP_is_nil)
-	addMarshalerOp(p, ir.OP_marshal_text, vk, vars.EncodingTextMarshalerType)
+	addMarshalerOp(p, ir.OP_map_key_utext, vk, vars.EncodingTextMarshalerType)
 	j := p.PC()
 	p.Add(ir.OP_goto)
 	p.Pin(i)
@@ -448,12 +502,48 @@ func (self *Compiler) compileStruct(p *ir.Program, sp int, vt reflect.Type) {
 
 func (self *Compiler) compileStructBody(p *ir.Program, sp int, vt reflect.Type) {
 	p.Tag(sp)
+	fvs := resolver.ResolveStruct(vt)
+
+	/* the layout is already decided by an enclosing struct */
+	switch self.tm {
+	case _TM_object:
+		self.compileStructObject(p, sp, vt, fvs)
+		return
+	case _TM_tuple:
+		self.compileStructTuple(p, sp, fvs)
+		return
+	}
+
+	/* check for the tuple mode, whose program is compiled on demand, so that
+	 * the nested structs only need the object layout here */
+	t := p.PC()
+	p.Add(ir.OP_check_tuple)
+	self.tm = _TM_object
+	self.compileStructObject(p, sp, vt, fvs)
+	e := p.PC()
+	p.Add(ir.OP_goto)
+	p.Pin(t)
+	p.Vp(ir.OP_tuple, vt, self.pv)
+	p.Pin(e)
+	self.tm = _TM_check
+}
+
+func (self *Compiler) compileStructObject(p *ir.Program, sp int, vt reflect.Type, fvs []resolver.FieldMeta) {
+	switch self.sm {
+	case _SM_sorted:
+		fvs = sortStructFields(fvs)
+	case _SM_check:
+		if sfv := sortStructFields(fvs); !sameFieldOrder(fvs, sfv) {
+			self.compileStructSorted(p, sp, vt, fvs, sfv)
+			return
+		}
+	}
+
 	p.Int(ir.OP_byte, '{')
 	p.Add(ir.OP_save)
 	p.Add(ir.OP_cond_set)
 
 	/* compile each field */
-	fvs := resolver.ResolveStruct(vt)
 	for i, fv := range fvs {
 		var s []int
 		var o resolver.Offset
@@ -465,6 +555,12 @@ func (self *Compiler) compileStructBody(p *ir.Program, sp int, vt reflect.Type)
 			}
 		}
 
+		/* check for the runtime predicate, which takes the struct pointer */
+		if fn := vars.FindFieldPredicate(self.reg, vt, fv.Name); fn != nil {
+			s = append(s, p.PC())
+			p.Pred(ir.OP_is_hidden, fn)
+		}
+
 		/* index to the field */
 		for _, o = range fv.Path {
 			if p.Int(ir.OP_index, int(o.Size)); o.Kind == resolver.F_deref {
@@ -479,6 +575,11 @@ func (self *Compiler) compileStructBody(p *ir.Program, sp int, vt reflect.Type)
 			s = append(s, p.PC())
 			self.compileStructFieldEmpty(p, fv.Type)
 		}
+		/* "omitempty" for structs, only if OmitEmptyStructs is set */
+		if fv.Type.Kind() == reflect.Struct && fv.Opts&(resolver.F_omitempty|resolver.F_omitzero) == resolver.F_omitempty {
+			s = append(s, p.PC())
+			p.VField(ir.OP_is_zero_struct, &fvs[i])
+		}
 		/* check for "omitzero" option */
 		if fv.Opts&resolver.F_omitzero != 0 {
 			s = append(s, p.PC())
@@ -492,15 +593,8 @@ func (self *Compiler) compileStructBody(p *ir.Program, sp int, vt reflect.Type)
 		p.Pin(i)
 
 		/* compile the key and value */
-		ft := fv.Type
 		p.Str(ir.OP_text, Quote(fv.Name)+":")
-
-		/* check for "stringnize" option */
-		if (fv.Opts & resolver.F_stringize) == 0 {
-			self.compileOne(p, sp+1, ft, self.pv)
-		} else {
-			self.compileStructFieldStr(p, sp+1, ft)
-		}
+		self.compileStructFieldValue(p, sp+1, fv)
 
 		/* patch the skipping jumps and reload the struct pointer */
 		p.Rel(s)
@@ -512,6 +606,109 @@ func (self *Compiler) compileStructBody(p *ir.Program, sp int, vt reflect.Type)
 	p.Int(ir.OP_byte, '}')
 }
 
+func (self *Compiler) compileStructSorted(p *ir.Program, sp int, vt reflect.Type, fvs []resolver.FieldMeta, sfv []resolver.FieldMeta) {
+	/* both orders are compiled, nested structs follow the same branch */
+	t := p.PC()
+	p.Add(ir.OP_check_sorted)
+	self.sm = _SM_declared
+	self.compileStructObject(p, sp, vt, fvs)
+	e := p.PC()
+	p.Add(ir.OP_goto)
+	p.Pin(t)
+	self.sm = _SM_sorted
+	self.compileStructObject(p, sp, vt, sfv)
+	p.Pin(e)
+	self.sm = _SM_check
+}
+
+func sortStructFields(fvs []resolver.FieldMeta) []resolver.FieldMeta {
+	ret := make([]resolver.FieldMeta, len(fvs))
+	copy(ret, fvs)
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func sameFieldOrder(a []resolver.FieldMeta, b []resolver.FieldMeta) bool {
+	for i := range a {
+		if a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *Compiler) compileStructTuple(p *ir.Program, sp int, fvs []resolver.FieldMeta) {
+	p.Int(ir.OP_byte, '[')
+	p.Add(ir.OP_save)
+
+	/* compile each field, positions are kept so nothing is omitted */
+	for i, fv := range fvs {
+		var s []int
+
+		/* add the comma if not the first element */
+		if i != 0 {
+			p.Int(ir.OP_byte, ',')
+		}
+
+		/* index to the field */
+		for _, o := range fv.Path {
+			if p.Int(ir.OP_index, int(o.Size)); o.Kind == resolver.F_deref {
+				s = append(s, p.PC())
+				p.Add(ir.OP_is_nil)
+				p.Add(ir.OP_deref)
+			}
+		}
+
+		/* compile the value */
+		self.compileStructFieldValue(p, sp+1, fv)
+
+		/* the "null" case of the embedded pointers */
+		if len(s) != 0 {
+			e := p.PC()
+			p.Add(ir.OP_goto)
+			p.Rel(s)
+			p.Add(ir.OP_null)
+			p.Pin(e)
+		}
+
+		/* reload the struct pointer */
+		p.Add(ir.OP_load)
+	}
+
+	/* end of array */
+	p.Add(ir.OP_drop)
+	p.Int(ir.OP_byte, ']')
+}
+
+// compileStructFieldValue compiles the value of a field, an empty value is
+// replaced by the JSON literal of the "default" option if the field has one.
+func (self *Compiler) compileStructFieldValue(p *ir.Program, sp int, fv resolver.FieldMeta) {
+	z := -1
+	if fv.Default != "" && fv.Type.Kind() != reflect.Struct && fv.Type.Kind() != reflect.Array {
+		if ok, _ := Valid([]byte(fv.Default)); !ok {
+			panic(vars.Error_default(fv.Name, fv.Default))
+		}
+		z = p.PC()
+		self.compileStructFieldEmpty(p, fv.Type)
+	}
+
+	/* check for "stringnize" option */
+	if (fv.Opts & resolver.F_stringize) == 0 {
+		self.compileOne(p, sp, fv.Type, self.pv)
+	} else {
+		self.compileStructFieldStr(p, sp, fv.Type)
+	}
+
+	/* the empty case writes the default */
+	if z != -1 {
+		e := p.PC()
+		p.Add(ir.OP_goto)
+		p.Pin(z)
+		p.Str(ir.OP_text, fv.Default)
+		p.Pin(e)
+	}
+}
+
 func (self *Compiler) compileStructFieldStr(p *ir.Program, sp int, vt reflect.Type) {
 	// NOTICE: according to encoding/json, Marshaler type has higher priority than string option
 	// see issue:
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"runtime"
+	"unsafe"
 
 	"github.com/bytedance/sonic/utf8"
 	"github.com/bytedance/sonic/internal/encoder/alg"
@@ -73,6 +74,28 @@ const (
 
     // Encode Infinity or Nan float into `null`, instead of returning an error.
     EncodeNullForInfOrNan Options = 1 << alg.BitEncodeNullForInfOrNan
+
+    // StructAsArray indicates that structs are encoded as positional arrays
+    // of their field values (eg. `[1,"a"]`) instead of objects.
+    StructAsArray Options = 1 << alg.BitStructAsArray
+
+    // OmitNilMapValues indicates that map entries whose value is
+    // a nil pointer or a nil interface are left out of the output.
+    OmitNilMapValues Options = 1 << alg.BitOmitNilMapValues
+
+    // SortStructFields indicates that struct fields are encoded in the
+    // ascending order of their JSON names, instead of the declaration order.
+    SortStructFields Options = 1 << alg.BitSortStructFields
+
+    // PointerRefs indicates that a struct pointer which has already been
+    // encoded is written as `{"$ref":N}`, where N is the 1-based order in
+    // which the pointer was first encountered. This allows cyclic values.
+    PointerRefs Options = 1 << alg.BitPointerRefs
+
+    // OmitEmptyStructs indicates that struct fields with the "omitempty"
+    // option are left out when they are zero values (eg. `struct{}{}`).
+    // By default they are always encoded, the same as encoding/json.
+    OmitEmptyStructs Options = 1 << alg.BitOmitEmptyStructs
 )
 
 // Encoder represents a specific set of encoder configurations.
@@ -80,6 +103,8 @@ type Encoder struct {
     Opts Options
     prefix string
     indent string
+    item   string
+    key    string
 }
 
 // Encode returns the JSON encoding of v.
@@ -87,6 +112,9 @@ func (self *Encoder) Encode(v interface{}) ([]byte, error) {
     if self.indent != "" || self.prefix != "" { 
         return EncodeIndented(v, self.prefix, self.indent, self.Opts)
     }
+    if self.item != "" || self.key != "" {
+        return EncodeSeparated(v, self.item, self.key, self.Opts)
+    }
     return Encode(v, self.Opts)
 }
 
@@ -159,6 +187,23 @@ func (enc *Encoder) SetIndent(prefix, indent string) {
     enc.indent = indent
 }
 
+// SetSeparators instructs the encoder to write item between elements and key
+// after object keys, as if by the package-level function EncodeSeparated().
+// The separators are ignored when indenting.
+// Calling SetSeparators("", "") restores the compact ones.
+func (enc *Encoder) SetSeparators(item, key string) {
+    enc.item = item
+    enc.key = key
+}
+
+// RegisterFieldPredicate registers fn to decide whether the field named name
+// (as in JSON) of the struct vt should be emitted, v points to the struct.
+// The field is emitted only if fn returns true, in addition to "omitempty", and fn
+// must be registered before vt is compiled (encoded or pretouched) for the first time.
+func RegisterFieldPredicate(vt reflect.Type, name string, fn func(v unsafe.Pointer) bool) {
+    vars.RegisterFieldPredicate(vt, name, fn)
+}
+
 // Quote returns the JSON-quoted version of s.
 func Quote(s string) string {
     buf := make([]byte, 0, len(s)+2)
@@ -207,7 +252,10 @@ func EncodeInto(buf *[]byte, val interface{}, opts Options) error {
 }
 
 func encodeInto(buf *[]byte, val interface{}, opts Options) error {
-    stk := vars.NewStack()
+    return encodeIntoStack(buf, val, opts, vars.NewStack())
+}
+
+func encodeIntoStack(buf *[]byte, val interface{}, opts Options, stk *vars.Stack) error {
     efv := rt.UnpackEface(val)
     err := encodeTypedPointer(buf, efv.Type, &efv.Value, stk, uint64(opts))
 
@@ -263,7 +311,6 @@ func HTMLEscape(dst []byte, src []byte) []byte {
 // followed by one or more copies of indent according to the indentation nesting.
 func EncodeIndented(val interface{}, prefix string, indent string, opts Options) ([]byte, error) {
     var err error
-    var buf *bytes.Buffer
 
     /* encode into the buffer */
     out := vars.NewBytes()
@@ -275,28 +322,36 @@ func EncodeIndented(val interface{}, prefix string, indent string, opts Options)
         return nil, err
     }
 
-    /* indent the JSON */
-    buf = vars.NewBuffer()
-    err = json.Indent(buf, *out, prefix, indent)
+    /* unvalidated marshalers may produce invalid JSON, report it like json.Indent */
+    if opts & NoValidateJSONMarshaler != 0 {
+        if ok, _ := Valid(*out); !ok {
+            err = json.Indent(new(bytes.Buffer), *out, prefix, indent)
+            vars.FreeBytes(out)
+            return nil, err
+        }
+    }
+
+    /* indent the JSON, it is valid so it is not checked again */
+    ret := alg.Indent(make([]byte, 0, len(*out) * 2), *out, prefix, indent)
     vars.FreeBytes(out)
+    return ret, nil
+}
 
-    /* check for errors */
-    if err != nil {
-        vars.FreeBuffer(buf)
+// EncodeSeparated is like Encode but writes item instead of ',' between the
+// elements of arrays and objects, and key instead of ':' after object keys,
+// eg. ", " and ": " for readability without indentation. An empty separator
+// stays compact. The output is not valid JSON if they are not made of ',' or
+// ':' and white spaces.
+func EncodeSeparated(val interface{}, item string, key string, opts Options) ([]byte, error) {
+    out := vars.NewBytes()
+    if err := EncodeInto(out, val, opts); err != nil {
+        vars.FreeBytes(out)
         return nil, err
     }
 
-    /* copy to the result buffer */
-    var ret []byte
-    if rt.CanSizeResue(cap(buf.Bytes())) {
-        ret = make([]byte, buf.Len())
-        copy(ret, buf.Bytes())
-        /* return the buffers into pool */
-        vars.FreeBuffer(buf)
-    } else {
-        ret = buf.Bytes()
-    }
-    
+    /* replace the separators while copying to the result buffer */
+    ret := alg.Separate(make([]byte, 0, len(*out) * 5 / 4), *out, item, key)
+    vars.FreeBytes(out)
     return ret, nil
 }
 
@@ -313,6 +368,13 @@ func Pretouch(vt reflect.Type, opts ...option.CompileOption) error {
     return pretouchRec(map[reflect.Type]uint8{vt: 0}, cfg)
 }
 
+// GetEncoderCacheSize returns the number of types in the encoder cache.
+// Every type is compiled once and shared by all later encodes, even when
+// several goroutines encode it for the first time concurrently.
+func GetEncoderCacheSize() int {
+    return vars.GetProgramCacheSize()
+}
+
 // Valid validates json and returns first non-blank character position,
 // if it is only one valid json value.
 // Otherwise returns invalid character position using start.
@@ -321,3 +383,34 @@ func Pretouch(vt reflect.Type, opts ...option.CompileOption) error {
 func Valid(data []byte) (ok bool, start int) {
     return alg.Valid(data)
 }
+
+// Compact appends to dst the JSON src without the insignificant spaces, like
+// json.Compact. If src is not one valid JSON value, dst is left unchanged and
+// the error reports the position of the first invalid character.
+func Compact(dst *[]byte, src []byte) error {
+    if ok, s := alg.Valid(src); !ok {
+        if s < 0 {
+            s = 0
+        }
+        return vars.Error_syntax(src, s)
+    }
+    *dst = alg.Compact(*dst, src)
+    return nil
+}
+
+// Indent appends to dst an indented form of the JSON src, like json.Indent.
+// If src is not one valid JSON value, dst is left unchanged and the error
+// reports the position of the first invalid character.
+func Indent(dst *[]byte, src []byte, prefix string, indent string) error {
+    if ok, s := alg.Valid(src); !ok {
+        if s < 0 {
+            s = 0
+        }
+        return vars.Error_syntax(src, s)
+    }
+    *dst = alg.Indent(*dst, src, prefix, indent)
+
+    /* like json.Indent, the spaces after the value are kept */
+    *dst = append(*dst, src[len(bytes.TrimRight(src, " \t\n\r")):]...)
+    return nil
+}
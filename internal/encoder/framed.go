@@ -0,0 +1,54 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+import (
+    `encoding/binary`
+    `fmt`
+    `io`
+    `math`
+
+    `github.com/bytedance/sonic/internal/encoder/vars`
+)
+
+// _FrameHeaderSize is the size of the big-endian length prefix of a JSON frame.
+const _FrameHeaderSize = 4
+
+// EncodeFramed writes the JSON encoding of val to w as one length-prefixed frame,
+// which starts with the length of the JSON as a 4-byte big-endian integer.
+func EncodeFramed(w io.Writer, val interface{}) error {
+    buf := vars.NewBytes()
+
+    /* reserve the header, and encode right after it */
+    *buf = append(*buf, 0, 0, 0, 0)
+    err := encodeIntoCheckRace(buf, val, 0)
+    if err != nil {
+        goto free_bytes
+    }
+
+    /* fill the length, and write the whole frame at once */
+    if n := len(*buf) - _FrameHeaderSize; uint64(n) > math.MaxUint32 {
+        err = fmt.Errorf("JSON frame too large: %d bytes", n)
+    } else {
+        binary.BigEndian.PutUint32((*buf)[:_FrameHeaderSize], uint32(n))
+        _, err = w.Write(*buf)
+    }
+
+free_bytes:
+    vars.FreeBytes(buf)
+    return err
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+import (
+    `io`
+
+    `github.com/bytedance/sonic/internal/encoder/alg`
+    `github.com/bytedance/sonic/internal/encoder/vars`
+    `github.com/bytedance/sonic/option`
+)
+
+// indentWriter indents the valid JSON written to it, the same way as
+// json.Indent, and passes it on to w every time a buffer of indented JSON is
+// full. The JSON may be written in pieces split anywhere, even inside strings,
+// since the indentation state is kept between the pieces.
+type indentWriter struct {
+    w   io.Writer
+    buf *[]byte
+    alg.Indenter
+}
+
+func newIndentWriter(w io.Writer, prefix string, indent string) *indentWriter {
+    return &indentWriter{w: w, buf: vars.NewBytes(), Indenter: alg.Indenter{Prefix: prefix, Indent: indent}}
+}
+
+func (self *indentWriter) Write(p []byte) (int, error) {
+    n := int(option.DefaultEncoderBufferSize)
+    for i := 0; i < len(p); i += n {
+        /* indent a piece at a time, so the buffer stays bounded */
+        e := i + n
+        if e > len(p) {
+            e = len(p)
+        }
+        *self.buf = self.Append(*self.buf, p[i:e])
+
+        /* pass on the buffer once it is full */
+        if len(*self.buf) >= n {
+            if err := self.Flush(); err != nil {
+                return e, err
+            }
+        }
+    }
+    return len(p), nil
+}
+
+// Flush passes on the indented JSON which is still buffered.
+func (self *indentWriter) Flush() error {
+    buf := *self.buf
+    for len(buf) > 0 {
+        n, err := self.w.Write(buf)
+        if buf = buf[n:]; err != nil {
+            return err
+        }
+    }
+    *self.buf = (*self.buf)[:0]
+    return nil
+}
+
+// Free returns the buffer into the pool, the writer must not be used anymore.
+func (self *indentWriter) Free() {
+    vars.FreeBytes(self.buf)
+    self.buf = nil
+}
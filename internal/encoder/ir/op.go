@@ -83,6 +83,14 @@ const (
 	OP_cond_testc
 	OP_unsupported
 	OP_is_zero
+	OP_check_tuple
+	OP_is_hidden
+	OP_check_sorted
+	OP_ref
+	OP_is_zero_struct
+	OP_raw
+	OP_tuple
+	OP_map_key_utext
 )
 
 const (
@@ -145,6 +153,14 @@ var OpNames = [256]string{
 	OP_cond_set:       "cond_set",
 	OP_cond_testc:     "cond_testc",
 	OP_unsupported:    "unsupported type",
+	OP_check_tuple:    "check_tuple",
+	OP_is_hidden:      "is_hidden",
+	OP_check_sorted:   "check_sorted",
+	OP_ref:            "ref",
+	OP_is_zero_struct: "is_zero_struct",
+	OP_raw:            "raw",
+	OP_tuple:          "tuple",
+	OP_map_key_utext:  "map_key_utext",
 }
 
 func (self Op) String() string {
@@ -255,6 +271,13 @@ func NewInsField(op Op, fv *resolver.FieldMeta) Instr {
 	}
 }
 
+func NewInsPred(op Op, fn *vars.FieldPredicate) Instr {
+	return Instr{
+		o: op,
+		p: unsafe.Pointer(fn),
+	}
+}
+
 func NewInsVp(op Op, vt reflect.Type, pv bool) Instr {
 	i := 0
 	if pv {
@@ -283,6 +306,10 @@ func (self Instr) VField() (*resolver.FieldMeta) {
 	return (*resolver.FieldMeta)(self.p)
 }
 
+func (self Instr) VPred() *vars.FieldPredicate {
+	return (*vars.FieldPredicate)(self.p)
+}
+
 func (self Instr) Vs() (v string) {
 	(*rt.GoString)(unsafe.Pointer(&v)).Ptr = self.p
 	(*rt.GoString)(unsafe.Pointer(&v)).Len = self.Vi()
@@ -353,12 +380,37 @@ func (self Instr) isBranch() bool {
 	case OP_slice_next:
 		fallthrough
 	case OP_cond_testc:
+		fallthrough
+	case OP_check_tuple:
+		fallthrough
+	case OP_check_sorted:
+		fallthrough
+	case OP_ref:
+		fallthrough
+	case OP_is_zero_struct:
+		fallthrough
+	case OP_is_zero_map:
+		fallthrough
+	case OP_is_zero:
+		fallthrough
+	case OP_is_hidden:
 		return true
 	default:
 		return false
 	}
 }
 
+func (self Instr) isConst() bool {
+	return self.Op() == OP_byte || self.Op() == OP_text
+}
+
+func (self Instr) constText() string {
+	if self.Op() == OP_byte {
+		return string([]byte{self.Byte()})
+	}
+	return self.Vs()
+}
+
 func (self Instr) Disassemble() string {
 	switch self.Op() {
 	case OP_byte:
@@ -369,6 +421,8 @@ func (self Instr) Disassemble() string {
 		return fmt.Sprintf("%-18s%d", self.Op().String(), self.Vi())
 	case OP_recurse:
 		fallthrough
+	case OP_tuple:
+		fallthrough
 	case OP_map_iter:
 		return fmt.Sprintf("%-18s%s", self.Op().String(), self.Vt())
 	case OP_marshal:
@@ -378,6 +432,8 @@ func (self Instr) Disassemble() string {
 	case OP_marshal_text:
 		fallthrough
 	case OP_marshal_text_p:
+		fallthrough
+	case OP_map_key_utext:
 		vt, _ := self.Vtab()
 		return fmt.Sprintf("%-18s%s", self.Op().String(), vt.Pack())
 	case OP_goto:
@@ -398,6 +454,16 @@ func (self Instr) Disassemble() string {
 		fallthrough
 	case OP_cond_testc:
 		fallthrough
+	case OP_check_tuple:
+		fallthrough
+	case OP_check_sorted:
+		fallthrough
+	case OP_ref:
+		fallthrough
+	case OP_is_zero_struct:
+		fallthrough
+	case OP_is_hidden:
+		fallthrough
 	case OP_map_check_key:
 		fallthrough
 	case OP_map_write_key:
@@ -470,6 +536,48 @@ func (self *Program) VField(op Op, fv *resolver.FieldMeta) {
 	*self = append(*self, NewInsField(op, fv))
 }
 
+func (self *Program) Pred(op Op, fn *vars.FieldPredicate) {
+	*self = append(*self, NewInsPred(op, fn))
+}
+
+// Fuse returns the program with every run of adjacent constant writes, which
+// are OP_byte and OP_text, coalesced into a single OP_text, so that the run
+// needs only one capacity check. Runs are split at the branch targets, and
+// the branches are retargeted to the instructions they pointed to.
+func (self Program) Fuse() Program {
+	nb := len(self)
+	tab := make([]bool, nb+1)
+	pcs := make([]int, nb+1)
+	ret := make(Program, 0, nb)
+
+	/* prescan to get all the labels */
+	for _, ins := range self {
+		if ins.isBranch() {
+			tab[ins.Vi()] = true
+		}
+	}
+
+	/* merge each run into the first instruction of it */
+	for i, ins := range self {
+		pcs[i] = len(ret)
+		if !ins.isConst() || tab[i] || i == 0 || !self[i-1].isConst() {
+			ret = append(ret, ins)
+			continue
+		}
+		pcs[i] = len(ret) - 1
+		ret[len(ret)-1] = NewInsVs(OP_text, ret[len(ret)-1].constText()+ins.constText())
+	}
+
+	/* relocate the branches */
+	pcs[nb] = len(ret)
+	for i := range ret {
+		if ret[i].isBranch() {
+			ret[i].u = pcs[ret[i].u]
+		}
+	}
+	return ret
+}
+
 func (self Program) Disassemble() string {
 	nb := len(self)
 	tab := make([]bool, nb+1)
@@ -51,7 +51,7 @@ var _KeepAlive struct {
 }
 
 func makeEncoderX86(vt *rt.GoType, ex ...interface{}) (interface{}, error) {
-	pp, err := NewCompiler().Compile(vt.Pack(), ex[0].(bool))
+	pp, err := NewCompiler().compileEx(vt, ex...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/encoder/ir"
+	"github.com/bytedance/sonic/internal/encoder/vars"
+	"github.com/bytedance/sonic/internal/rt"
+	"github.com/bytedance/sonic/option"
+)
+
+// Backend is a native code generator for encoder programs. The ARM64 JIT in
+// internal/encoder/arm64 registers itself with RegisterBackend, so that this
+// package does not depend on the JIT unless it is linked in.
+type Backend struct {
+	// Assemble turns a compiled program into a native encoder.
+	Assemble func(p ir.Program, name string) vars.Encoder
+
+	// SetCompiler installs the compiler used for the dynamic types met by
+	// interfaces and recursive types.
+	SetCompiler func(c func(*rt.GoType, ...interface{}) (interface{}, error))
+
+	// EncodeTypedPointer encodes a dynamic value with the native encoders.
+	EncodeTypedPointer func(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars.Stack, fv uint64) error
+}
+
+var (
+	backend *Backend
+	useVM   = vars.UseVM
+)
+
+// RegisterBackend registers the ARM64 JIT backend and switches to it, unless
+// the VM is forced with SONIC_ENCODER_USE_VM.
+func RegisterBackend(b *Backend) {
+	backend = b
+	if !useVM {
+		ForceUseJit()
+	}
+}
+
+// ForceUseJit switches to the registered JIT backend, the VM is kept when no
+// backend is linked in.
+func ForceUseJit() {
+	if backend == nil {
+		ForceUseVM()
+		return
+	}
+	backend.SetCompiler(makeEncoderARM64)
+	pretouchType = pretouchTypeARM64
+	encodeTypedPointer = backend.EncodeTypedPointer
+	vars.UseVM = false
+}
+
+func init() {
+	ForceUseVM()
+}
+
+func makeEncoderARM64(vt *rt.GoType, ex ...interface{}) (interface{}, error) {
+	pp, err := NewCompiler().compileEx(vt, ex...)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Assemble(pp, vt.String()), nil
+}
+
+func pretouchTypeARM64(_vt reflect.Type, opts option.CompileOptions, v uint8) (map[reflect.Type]uint8, error) {
+	/* compile function */
+	compiler := NewCompiler().apply(opts)
+	encoder := func(vt *rt.GoType, ex ...interface{}) (interface{}, error) {
+		pp, err := compiler.Compile(vt.Pack(), ex[0].(bool))
+		if err != nil {
+			return nil, err
+		}
+		return backend.Assemble(pp, vt.String()), nil
+	}
+
+	/* find or compile */
+	vt := rt.UnpackType(_vt)
+	if val := vars.GetProgram(vt); val != nil {
+		return nil, nil
+	} else if _, err := vars.ComputeProgram(vt, encoder, v == 1); err == nil {
+		return compiler.rec, nil
+	} else {
+		return nil, err
+	}
+}
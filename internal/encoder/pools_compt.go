@@ -1,5 +1,6 @@
-//go:build !amd64
+//go:build !amd64 && !(arm64 && go1.20 && !go1.26)
 // +build !amd64
+// +build !arm64 !go1.20 go1.26
 
 /*
  * Copyright 2021 ByteDance Inc.
@@ -29,21 +29,16 @@ import (
 )
 
 func Compact(p *[]byte, v []byte) error {
-	buf := vars.NewBuffer()
-	err := json.Compact(buf, v)
-
-	/* check for errors */
-	if err != nil {
-		return err
+	if ok, _ := alg.Valid(v); ok {
+		*p = alg.Compact(*p, v)
+		return nil
 	}
 
-	/* add to result */
-	v = buf.Bytes()
-	*p = append(*p, v...)
-
-	/* return the buffer into pool */
+	/* let encoding/json report the error */
+	buf := vars.NewBuffer()
+	err := json.Compact(buf, v)
 	vars.FreeBuffer(buf)
-	return nil
+	return err
 }
 
 func EncodeNil(rb *[]byte) error {
@@ -67,21 +62,54 @@ func EncodeNil(rb *[]byte) error {
 // 	}
 // }
 
+var nullJSON = []byte("null")
+
+// marshalJSON calls val.MarshalJSON, except for json.RawMessage which already
+// holds its output and is passed through verbatim
+func marshalJSON(val json.Marshaler) ([]byte, error) {
+	switch v := val.(type) {
+	case json.RawMessage:
+		if v == nil {
+			return nullJSON, nil
+		}
+		return v, nil
+	case *json.RawMessage:
+		if v != nil {
+			return marshalJSON(*v)
+		}
+	}
+	return val.MarshalJSON()
+}
+
 func EncodeJsonMarshaler(buf *[]byte, val json.Marshaler, opt uint64) error {
-	if ret, err := val.MarshalJSON(); err != nil {
+	if ret, err := marshalJSON(val); err != nil {
 		return err
 	} else {
-		if opt&(1<<alg.BitCompactMarshaler) != 0 {
-			return Compact(buf, ret)
-		}
-		if opt&(1<<alg.BitNoValidateJSONMarshaler) == 0 {
-			if ok, s := alg.Valid(ret); !ok {
-				return vars.Error_marshaler(ret, s)
-			}
-		}
-		*buf = append(*buf, ret...)
+		return appendMarshaled(buf, ret, opt)
+	}
+}
+
+// EncodeRawMessage appends the raw JSON of val the same way as the output of
+// a json.Marshaler, without converting val into one.
+func EncodeRawMessage(buf *[]byte, val *json.RawMessage, opt uint64) error {
+	if *val == nil {
+		*buf = append(*buf, nullJSON...)
 		return nil
 	}
+	return appendMarshaled(buf, *val, opt)
+}
+
+func appendMarshaled(buf *[]byte, ret []byte, opt uint64) error {
+	if opt&(1<<alg.BitCompactMarshaler) != 0 {
+		return Compact(buf, ret)
+	}
+	if opt&(1<<alg.BitNoValidateJSONMarshaler) == 0 {
+		if ok, s := alg.Valid(ret); !ok {
+			return vars.Error_marshaler(ret, s)
+		}
+	}
+	*buf = append(*buf, ret...)
+	return nil
 }
 
 func EncodeTextMarshaler(buf *[]byte, val encoding.TextMarshaler, opt uint64) error {
@@ -97,6 +125,16 @@ func EncodeTextMarshaler(buf *[]byte, val encoding.TextMarshaler, opt uint64) er
 	}
 }
 
+// EncodeTextMarshalerKey encodes val as a map key, which is quoted regardless
+// of the NoQuoteTextMarshaler option.
+func EncodeTextMarshalerKey(buf *[]byte, val encoding.TextMarshaler, opt uint64) error {
+	return EncodeTextMarshaler(buf, val, opt&^(1<<alg.BitNoQuoteTextMarshaler))
+}
+
+func IsHidden(val unsafe.Pointer, fn *vars.FieldPredicate) bool {
+	return !(*fn)(val)
+}
+
 func IsZero(val unsafe.Pointer, fv *resolver.FieldMeta) bool {
 	rv := reflect.NewAt(fv.Type, val).Elem()
 	b1 := fv.IsZero == nil && rv.IsZero()
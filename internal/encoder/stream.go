@@ -17,9 +17,11 @@
 package encoder
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 
+	"github.com/bytedance/sonic/internal/encoder/alg"
 	"github.com/bytedance/sonic/internal/encoder/vars"
 )
 
@@ -47,30 +49,35 @@ func (enc *StreamEncoder) Encode(val interface{}) (err error) {
     }
 
     if enc.indent != "" || enc.prefix != "" {
-        /* indent the JSON */
-        buf := vars.NewBuffer()
-        err = json.Indent(buf, *out, enc.prefix, enc.indent)
-        if err != nil {
-            vars.FreeBuffer(buf)
-            goto free_bytes
+        /* unvalidated marshalers may produce invalid JSON, report it before writing anything */
+        if enc.Opts & NoValidateJSONMarshaler != 0 {
+            if ok, _ := Valid(*out); !ok {
+                err = json.Indent(new(bytes.Buffer), *out, enc.prefix, enc.indent)
+                goto free_bytes
+            }
         }
 
-        // according to standard library, terminate each value with a newline...
-        if enc.Opts & NoEncoderNewline == 0 {
-            buf.WriteByte('\n')
-        }
+        /* indent the JSON while passing it on to io.Writer */
+        iw := newIndentWriter(enc.w, enc.prefix, enc.indent)
+        _, err = iw.Write(*out)
 
-        /* copy into io.Writer */
-        _, err = io.Copy(enc.w, buf)
-        if err != nil {
-            vars.FreeBuffer(buf)
-            goto free_bytes
+        if err == nil {
+            // according to standard library, terminate each value with a newline...
+            if enc.Opts & NoEncoderNewline == 0 {
+                *iw.buf = append(*iw.buf, '\n')
+            }
+            err = iw.Flush()
         }
+        iw.Free()
 
     } else {
+        buf := *out
+        if enc.item != "" || enc.key != "" {
+            buf = alg.Separate(make([]byte, 0, len(buf) * 5 / 4), buf, enc.item, enc.key)
+        }
+
         /* copy into io.Writer */
         var n int
-        buf := *out
         for len(buf) > 0 {
             n, err = enc.w.Write(buf)
             buf = buf[n:]
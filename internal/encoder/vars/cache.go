@@ -39,10 +39,27 @@ func FindOrCompile(vt *rt.GoType, pv bool, compiler func(*rt.GoType, ... interfa
 	}
 }
 
+// FindOrCompileTuple returns the encoder of the struct vt laid out as an array,
+// which is compiled on demand when StructAsArray is set. The compiler gets
+// true as the second extra argument.
+func FindOrCompileTuple(vt *rt.GoType, pv bool, compiler func(*rt.GoType, ... interface{}) (interface{}, error)) (interface{}, error) {
+	if val := tupleCache.Get(vt); val != nil {
+		return val, nil
+	} else if ret, err := tupleCache.Compute(vt, compiler, pv, true); err == nil {
+		return ret, nil
+	} else {
+		return nil, err
+	}
+}
+
 func GetProgram(vt *rt.GoType) (interface{}) {
 	return programCache.Get(vt)
 }
 
 func ComputeProgram(vt *rt.GoType, compute func(*rt.GoType, ... interface{}) (interface{}, error), pv bool) (interface{}, error) {
 	return programCache.Compute(vt, compute, pv)
-}
\ No newline at end of file
+}
+
+func GetProgramCacheSize() int {
+	return programCache.Len()
+}
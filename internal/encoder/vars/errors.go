@@ -53,10 +53,32 @@ func Error_unsuppoted(typ *rt.GoType) error {
 	return &json.UnsupportedTypeError{Type: typ.Pack() }
 }
 
+func Error_default(name string, value string) error {
+    return fmt.Errorf("json: invalid default value %q of field %q", value, name)
+}
+
 func Error_marshaler(ret []byte, pos int) error {
     return fmt.Errorf("invalid Marshaler output json syntax at %d: %q", pos, ret)
 }
 
+func Error_syntax(src []byte, pos int) error {
+    p, q := pos - syntaxContext, pos + syntaxContext
+    if p < 0 {
+        p = 0
+    }
+    if q > len(src) {
+        q = len(src)
+    }
+    if p > q {
+        p = q
+    }
+    return fmt.Errorf("invalid json syntax at %d: %q", pos, src[p:q])
+}
+
+// syntaxContext is the number of bytes on each side of the position that
+// Error_syntax quotes.
+const syntaxContext = 16
+
 const (
     PanicNilPointerOfNonEmptyString int = 1 + iota
 )
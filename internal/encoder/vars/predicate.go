@@ -0,0 +1,42 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vars
+
+import (
+    `reflect`
+    `unsafe`
+
+    `github.com/bytedance/sonic/internal/registry`
+)
+
+// FieldPredicate decides whether a struct field should be emitted,
+// v points to the struct which contains the field.
+type FieldPredicate func(v unsafe.Pointer) bool
+
+// RegisterFieldPredicate registers fn for the field named name (as in JSON) of the struct vt.
+func RegisterFieldPredicate(vt reflect.Type, name string, fn FieldPredicate) {
+    registry.Store(registry.FieldPredicate, vt, name, &fn)
+}
+
+// FindFieldPredicate returns the predicate registered for the field in the
+// snapshot of the registry, or nil.
+func FindFieldPredicate(reg *registry.Snapshot, vt reflect.Type, name string) *FieldPredicate {
+    if fn, ok := reg.Get(registry.FieldPredicate, vt, name).(*FieldPredicate); ok {
+        return fn
+    }
+    return nil
+}
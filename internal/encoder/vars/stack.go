@@ -34,8 +34,10 @@ type State struct {
 }
 
 type Stack struct {
-	sp uintptr
-	sb [MaxStack]State
+	sp   uintptr
+	sb   [MaxStack]State
+	refs map[unsafe.Pointer]int
+	sink func([]byte) int
 }
 
 var (
@@ -47,6 +49,7 @@ var (
 	}
 	bufferPool   = sync.Pool{}
 	programCache = caching.CreateProgramCache()
+	tupleCache   = caching.CreateProgramCache()
 )
 
 func NewBytes() *[]byte {
@@ -110,6 +113,58 @@ func (s *Stack) Drop() (int, uint64, unsafe.Pointer, unsafe.Pointer) {
 	return st.x, st.f, st.p, st.q
 }
 
+// Ref returns the reference ID of p, and whether p has been seen before.
+// IDs start from 1 and follow the order in which pointers are first seen.
+func (s *Stack) Ref(p unsafe.Pointer) (int, bool) {
+	if id, ok := s.refs[p]; ok {
+		return id, true
+	}
+	if s.refs == nil {
+		s.refs = make(map[unsafe.Pointer]int)
+	}
+	id := len(s.refs) + 1
+	s.refs[p] = id
+	return id, false
+}
+
+// SetSink makes the encoding on s hand off its output buffer to fn every time
+// the buffer is full. fn returns the number of leading bytes it has taken.
+func (s *Stack) SetSink(fn func([]byte) int) {
+	s.sink = fn
+}
+
+// Sinking tells whether the encoding on s hands off its output buffer.
+func (s *Stack) Sinking() bool {
+	return s.sink != nil
+}
+
+// Flush hands off buf to the sink of s, and returns the bytes which are not
+// taken, moved to the start of buf.
+func (s *Stack) Flush(buf []byte) []byte {
+	if s.sink == nil || len(buf) == 0 {
+		return buf
+	}
+	n := s.sink(buf)
+	return buf[:copy(buf, buf[n:])]
+}
+
+var byteType = rt.UnpackType(ByteType)
+
+// MoreSpace grows the output buffer old of the encoding on s to newCap bytes.
+// If s has a sink, the buffer is handed off first, and only grows when the
+// sink does not take enough of it.
+func MoreSpace(s *Stack, old rt.GoSlice, newCap int) rt.GoSlice {
+	if s.sink != nil && old.Len > 0 {
+		n := old.Len - len(s.Flush(*(*[]byte)(unsafe.Pointer(&old))))
+		old.Len -= n
+		newCap -= n
+	}
+	if newCap <= old.Cap {
+		return old
+	}
+	return rt.GrowSlice(byteType, old, newCap)
+}
+
 func NewBuffer() *bytes.Buffer {
 	if ret := bufferPool.Get(); ret != nil {
 		return ret.(*bytes.Buffer)
@@ -127,6 +182,8 @@ func FreeBytes(p *[]byte) {
 
 func FreeStack(p *Stack) {
 	p.sp = 0
+	p.refs = nil
+	p.sink = nil
 	stackPool.Put(p)
 }
 
@@ -25,6 +25,7 @@ import (
 var (
     ByteType                 = reflect.TypeOf(byte(0))
     JsonNumberType           = reflect.TypeOf(json.Number(""))
+    JsonRawMessageType       = reflect.TypeOf(json.RawMessage(nil))
     JsonUnsupportedValueType = reflect.TypeOf(new(json.UnsupportedValueError))
 )
 
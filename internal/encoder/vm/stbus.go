@@ -39,6 +39,18 @@ func EncodeTypedPointer(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars
 	}
 }
 
+// EncodeTypedTuple runs the program encoding the struct vt as an array, which
+// is compiled on first use.
+func EncodeTypedTuple(buf *[]byte, vt *rt.GoType, vp *unsafe.Pointer, sb *vars.Stack, fv uint64) error {
+	if pp, err := vars.FindOrCompileTuple(vt, (fv&(1<<alg.BitPointerValue)) != 0, compiler); err != nil {
+		return err
+	} else if vt.Indirect() {
+		return Execute(buf, *vp, sb, fv, pp.(*ir.Program))
+	} else {
+		return Execute(buf, unsafe.Pointer(vp), sb, fv, pp.(*ir.Program))
+	}
+}
+
 var compiler func(*rt.GoType, ... interface{}) (interface{}, error)
 
 func SetCompiler(c func(*rt.GoType, ... interface{}) (interface{}, error)) {
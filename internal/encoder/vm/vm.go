@@ -61,6 +61,11 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 
 	var pro = &(*prog)[0]
 	for pc := 0; pc < pl; {
+		/* hand off the output as the buffer fills up, like the JIT does when growing it */
+		if s.Sinking() && 2*len(buf) >= cap(buf) {
+			buf = s.Flush(buf)
+		}
+
 		ins := (*ir.Instr)(rt.Add(unsafe.Pointer(pro), ir.OpSize*uintptr(pc)))
 		pc++
 		op := ins.Op()
@@ -69,6 +74,21 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 		case ir.OP_goto:
 			pc = ins.Vi()
 			continue
+		case ir.OP_check_tuple:
+			if flags&(1<<alg.BitStructAsArray) != 0 {
+				pc = ins.Vi()
+				continue
+			}
+		case ir.OP_check_sorted:
+			if flags&(1<<alg.BitSortStructFields) != 0 {
+				pc = ins.Vi()
+				continue
+			}
+		case ir.OP_ref:
+			if flags&(1<<alg.BitPointerRefs) != 0 && alg.WriteRef(&buf, *(*unsafe.Pointer)(p), s) {
+				pc = ins.Vi()
+				continue
+			}
 		case ir.OP_byte:
 			v := ins.Byte()
 			buf = append(buf, v)
@@ -109,6 +129,24 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 				}
 			}
 			buf = *b
+		case ir.OP_tuple:
+			vt, pv := ins.Vp2()
+			f := flags
+			if pv {
+				f |= (1 << alg.BitPointerValue)
+			}
+			*b = buf
+			if vt.Indirect() {
+				if err := EncodeTypedTuple(b, vt, (*unsafe.Pointer)(rt.NoEscape(unsafe.Pointer(&p))), s, f); err != nil {
+					return err
+				}
+			} else {
+				vp := (*unsafe.Pointer)(p)
+				if err := EncodeTypedTuple(b, vt, vp, s, f); err != nil {
+					return err
+				}
+			}
+			buf = *b
 		case ir.OP_is_nil:
 			if is_nil(p) {
 				pc = ins.Vi()
@@ -230,7 +268,7 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 				continue
 			}
 			p = it.It.K
-		case ir.OP_marshal_text:
+		case ir.OP_marshal_text, ir.OP_map_key_utext:
 			vt, itab := ins.Vtab()
 			var it rt.GoIface
 			switch vt.Kind() {
@@ -243,7 +281,12 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 				case reflect.Ptr, reflect.Map : it = convT2I(p, true, itab)
 				default                       : it = convT2I(p, !vt.Indirect(), itab)
 			}
-			if err := prim.EncodeTextMarshaler(&buf, *(*encoding.TextMarshaler)(unsafe.Pointer(&it)), (flags)); err != nil {
+			/* map keys are always quoted */
+			enc := prim.EncodeTextMarshaler
+			if ins.Op() == ir.OP_map_key_utext {
+				enc = prim.EncodeTextMarshalerKey
+			}
+			if err := enc(&buf, *(*encoding.TextMarshaler)(unsafe.Pointer(&it)), (flags)); err != nil {
 				return err
 			}
 		case ir.OP_marshal_text_p:
@@ -290,6 +333,16 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 				pc = ins.Vi()
 				continue
 			}
+		case ir.OP_is_zero_struct:
+			if flags&(1<<alg.BitOmitEmptyStructs) != 0 && prim.IsZero(p, ins.VField()) {
+				pc = ins.Vi()
+				continue
+			}
+		case ir.OP_is_hidden:
+			if prim.IsHidden(p, ins.VPred()) {
+				pc = ins.Vi()
+				continue
+			}
 		case ir.OP_is_zero_1:
 			if *(*uint8)(p) == 0 {
 				pc = ins.Vi()
@@ -344,6 +397,10 @@ func Execute(b *[]byte, p unsafe.Pointer, s *vars.Stack, flags uint64, prog *ir.
 			if err := prim.EncodeJsonMarshaler(&buf, *(*json.Marshaler)(unsafe.Pointer(&it)), (flags)); err != nil {
 				return err
 			}
+		case ir.OP_raw:
+			if err := prim.EncodeRawMessage(&buf, (*json.RawMessage)(p), flags); err != nil {
+				return err
+			}
 		case ir.OP_unsupported:
 			return vars.Error_unsuppoted(ins.GoType())
 		default:
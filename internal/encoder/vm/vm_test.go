@@ -22,7 +22,9 @@ import (
 	"time"
 
 	"github.com/bytedance/sonic/internal/encoder"
+	"github.com/bytedance/sonic/internal/encoder/ir"
 	"github.com/bytedance/sonic/internal/encoder/vars"
+	"github.com/bytedance/sonic/internal/encoder/vm"
 	"github.com/stretchr/testify/require"
 )
 
@@ -65,3 +67,61 @@ func TestCorpusMarshal(t *testing.T) {
     require.Equal(t, jerr == nil, serr == nil)
     require.Equal(t, string(jout), string(sout))
 }
+
+func TestProgram_Fuse(t *testing.T) {
+    var p ir.Program
+    p.Int(ir.OP_byte, '[')
+    p.Str(ir.OP_text, "1")
+    j := p.PC()
+    p.Add(ir.OP_goto)
+    p.Int(ir.OP_byte, 'x')
+    p.Str(ir.OP_text, "y")
+    p.Pin(j)
+    p.Int(ir.OP_byte, ',')
+    p.Str(ir.OP_text, "2")
+    p.Int(ir.OP_byte, ']')
+
+    /* the runs are merged, but not across the jump target */
+    f := p.Fuse()
+    require.Equal(t, 4, len(f))
+    require.Equal(t, "[1", f[0].Vs())
+    require.Equal(t, "xy", f[2].Vs())
+    require.Equal(t, ",2]", f[3].Vs())
+    require.Equal(t, 3, f[1].Vi())
+
+    for _, prog := range []ir.Program{p, f} {
+        var buf []byte
+        s := vars.NewStack()
+        require.NoError(t, vm.Execute(&buf, nil, s, 0, &prog))
+        vars.FreeStack(s)
+        require.Equal(t, "[1,2]", string(buf))
+    }
+}
+
+type fusedInner struct {
+    A int    `json:"a"`
+    B string `json:"b,omitempty"`
+}
+
+type fusedStruct struct {
+    I fusedInner   `json:"i"`
+    L []fusedInner `json:"l"`
+    N int          `json:"n,string"`
+    S string       `json:"s,string"`
+    E struct{}     `json:"e"`
+    P *fusedInner  `json:"p,omitempty"`
+    A [2]int       `json:"a"`
+}
+
+func TestEncoder_FusedProgram(t *testing.T) {
+    for _, v := range []fusedStruct{
+        {},
+        {I: fusedInner{1, "x"}, L: []fusedInner{{}, {2, "y"}}, N: 3, S: "z", P: &fusedInner{B: "w"}, A: [2]int{4, 5}},
+    } {
+        exp, err := json.Marshal(v)
+        require.NoError(t, err)
+        ret, err := encoder.Encode(v, 0)
+        require.NoError(t, err)
+        require.Equal(t, string(exp), string(ret))
+    }
+}
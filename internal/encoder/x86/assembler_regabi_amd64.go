@@ -245,6 +245,7 @@ var _OpFuncTab = [256]func(*Assembler, *ir.Instr){
 	ir.OP_drop:           (*Assembler)._asm_OP_drop,
 	ir.OP_drop_2:         (*Assembler)._asm_OP_drop_2,
 	ir.OP_recurse:        (*Assembler)._asm_OP_recurse,
+	ir.OP_tuple:          (*Assembler)._asm_OP_tuple,
 	ir.OP_is_nil:         (*Assembler)._asm_OP_is_nil,
 	ir.OP_is_nil_p1:      (*Assembler)._asm_OP_is_nil_p1,
 	ir.OP_is_zero_1:      (*Assembler)._asm_OP_is_zero_1,
@@ -261,13 +262,20 @@ var _OpFuncTab = [256]func(*Assembler, *ir.Instr){
 	ir.OP_slice_len:      (*Assembler)._asm_OP_slice_len,
 	ir.OP_slice_next:     (*Assembler)._asm_OP_slice_next,
 	ir.OP_marshal:        (*Assembler)._asm_OP_marshal,
+	ir.OP_raw:            (*Assembler)._asm_OP_raw,
 	ir.OP_marshal_p:      (*Assembler)._asm_OP_marshal_p,
 	ir.OP_marshal_text:   (*Assembler)._asm_OP_marshal_text,
 	ir.OP_marshal_text_p: (*Assembler)._asm_OP_marshal_text_p,
+	ir.OP_map_key_utext:  (*Assembler)._asm_OP_map_key_utext,
 	ir.OP_cond_set:       (*Assembler)._asm_OP_cond_set,
 	ir.OP_cond_testc:     (*Assembler)._asm_OP_cond_testc,
 	ir.OP_unsupported:    (*Assembler)._asm_OP_unsupported,
 	ir.OP_is_zero:        (*Assembler)._asm_OP_is_zero,
+	ir.OP_check_tuple:    (*Assembler)._asm_OP_check_tuple,
+	ir.OP_check_sorted:   (*Assembler)._asm_OP_check_sorted,
+	ir.OP_ref:            (*Assembler)._asm_OP_ref,
+	ir.OP_is_zero_struct: (*Assembler)._asm_OP_is_zero_struct,
+	ir.OP_is_hidden:      (*Assembler)._asm_OP_is_hidden,
 }
 
 func (self *Assembler) instr(v *ir.Instr) {
@@ -600,8 +608,7 @@ func (self *Assembler) call_marshaler_v(fn obj.Addr, it *rt.GoType, vt reflect.T
 /** Builtin: _more_space **/
 
 var (
-	_T_byte      = jit.Type(vars.ByteType)
-	_F_growslice = jit.Func(rt.GrowSlice)
+	_F_more_space = jit.Func(vars.MoreSpace)
 
 	_T_json_Marshaler         = rt.UnpackType(vars.JsonMarshalerType)
 	_T_encoding_TextMarshaler = rt.UnpackType(vars.EncodingTextMarshalerType)
@@ -610,17 +617,17 @@ var (
 // AX must saving n
 func (self *Assembler) more_space() {
 	self.Link(_LB_more_space)
-	self.Emit("MOVQ", _RP, _BX)        // MOVQ DI, BX
-	self.Emit("MOVQ", _RL, _CX)        // MOVQ SI, CX
-	self.Emit("MOVQ", _RC, _DI)        // MOVQ DX, DI
-	self.Emit("MOVQ", _AX, _SI)        // MOVQ AX, SI
-	self.Emit("MOVQ", _T_byte, _AX)    // MOVQ $_T_byte, AX
-	self.call_more_space(_F_growslice) // CALL $pc
-	self.Emit("MOVQ", _AX, _RP)        // MOVQ AX, DI
-	self.Emit("MOVQ", _BX, _RL)        // MOVQ BX, SI
-	self.Emit("MOVQ", _CX, _RC)        // MOVQ CX, DX
-	self.save_buffer()                 // SAVE {buf}
-	self.Rjmp("JMP", _LR)              // JMP  LR
+	self.Emit("MOVQ", _RP, _BX)         // MOVQ DI, BX
+	self.Emit("MOVQ", _RL, _CX)         // MOVQ SI, CX
+	self.Emit("MOVQ", _RC, _DI)         // MOVQ DX, DI
+	self.Emit("MOVQ", _AX, _SI)         // MOVQ AX, SI
+	self.Emit("MOVQ", _ST, _AX)         // MOVQ ST, AX
+	self.call_more_space(_F_more_space) // CALL $pc
+	self.Emit("MOVQ", _AX, _RP)         // MOVQ AX, DI
+	self.Emit("MOVQ", _BX, _RL)         // MOVQ BX, SI
+	self.Emit("MOVQ", _CX, _RC)         // MOVQ CX, DX
+	self.save_buffer()                  // SAVE {buf}
+	self.Rjmp("JMP", _LR)               // JMP  LR
 }
 
 /** Builtin Errors **/
@@ -777,9 +784,12 @@ var (
 )
 
 var (
-	_F_encodeTypedPointer  obj.Addr
-	_F_encodeJsonMarshaler obj.Addr
-	_F_encodeTextMarshaler obj.Addr
+	_F_encodeTypedPointer     obj.Addr
+	_F_encodeTypedTuple       obj.Addr
+	_F_encodeJsonMarshaler    obj.Addr
+	_F_encodeTextMarshaler    obj.Addr
+	_F_encodeTextMarshalerKey obj.Addr
+	_F_encodeRawMessage       obj.Addr
 )
 
 const (
@@ -787,9 +797,12 @@ const (
 )
 
 func init() {
-	_F_encodeJsonMarshaler = jit.Func(prim.EncodeJsonMarshaler)
-	_F_encodeTextMarshaler = jit.Func(prim.EncodeTextMarshaler)
-	_F_encodeTypedPointer  = jit.Func(EncodeTypedPointer)
+	_F_encodeJsonMarshaler    = jit.Func(prim.EncodeJsonMarshaler)
+	_F_encodeTextMarshaler    = jit.Func(prim.EncodeTextMarshaler)
+	_F_encodeTextMarshalerKey = jit.Func(prim.EncodeTextMarshalerKey)
+	_F_encodeRawMessage       = jit.Func(prim.EncodeRawMessage)
+	_F_encodeTypedPointer     = jit.Func(EncodeTypedPointer)
+	_F_encodeTypedTuple       = jit.Func(EncodeTypedTuple)
 }
 
 func (self *Assembler) _asm_OP_null(_ *ir.Instr) {
@@ -1039,6 +1052,14 @@ func (self *Assembler) _asm_OP_drop_2(_ *ir.Instr) {
 }
 
 func (self *Assembler) _asm_OP_recurse(p *ir.Instr) {
+	self.encode_typed(_F_encodeTypedPointer, p)
+}
+
+func (self *Assembler) _asm_OP_tuple(p *ir.Instr) {
+	self.encode_typed(_F_encodeTypedTuple, p)
+}
+
+func (self *Assembler) encode_typed(fn obj.Addr, p *ir.Instr) {
 	self.prep_buffer_AX() // MOVE {buf}, (SP)
 	vt, pv := p.Vp()
 	self.Emit("MOVQ", jit.Type(vt), _BX) // MOVQ $(type(p.Vt())), BX
@@ -1058,9 +1079,9 @@ func (self *Assembler) _asm_OP_recurse(p *ir.Instr) {
 		self.Emit("BTSQ", jit.Imm(alg.BitPointerValue), _SI) // BTSQ $1, SI
 	}
 
-	self.call_encoder(_F_encodeTypedPointer) // CALL  encodeTypedPointer
-	self.Emit("TESTQ", _ET, _ET)             // TESTQ ET, ET
-	self.Sjmp("JNZ", _LB_error)              // JNZ   _error
+	self.call_encoder(fn)        // CALL  $fn
+	self.Emit("TESTQ", _ET, _ET) // TESTQ ET, ET
+	self.Sjmp("JNZ", _LB_error)  // JNZ   _error
 	self.load_buffer_AX()
 }
 
@@ -1104,6 +1125,7 @@ func (self *Assembler) _asm_OP_is_zero_map(p *ir.Instr) {
 
 var (
 	_F_is_zero = jit.Func(prim.IsZero)
+	_F_is_hidden = jit.Func(prim.IsHidden)
 	_T_reflect_Type = rt.UnpackIface(reflect.Type(nil))
 )
 
@@ -1116,10 +1138,55 @@ func (self *Assembler) _asm_OP_is_zero(p *ir.Instr) {
 	self.Xjmp("JNE", p.Vi())                          // JE   p.Vi()
 }
 
+func (self *Assembler) _asm_OP_is_zero_struct(p *ir.Instr) {
+	fv := p.VField()
+	self.Emit("BTQ", jit.Imm(alg.BitOmitEmptyStructs), _ARG_fv) // BTQ  ${BitOmitEmptyStructs}, fv
+	self.Sjmp("JNC", "_not_zero_struct_{n}")                    // JNC  _not_zero_struct_{n}
+	self.Emit("MOVQ", _SP_p, _AX)                               // MOVQ SP.p, AX
+	self.Emit("MOVQ", jit.ImmPtr(unsafe.Pointer(fv)), _BX)      // MOVQ $fv, BX
+	self.call_go(_F_is_zero)                                    // CALL IsZero
+	self.Emit("CMPB", _AX, jit.Imm(0))                          // CMPB AX, $0
+	self.Xjmp("JNE", p.Vi())                                    // JNE  p.Vi()
+	self.Link("_not_zero_struct_{n}")                           // _not_zero_struct_{n}:
+}
+
+func (self *Assembler) _asm_OP_is_hidden(p *ir.Instr) {
+	self.Emit("MOVQ", _SP_p, _AX)                                 // MOVQ SP.p, AX
+	self.Emit("MOVQ", jit.ImmPtr(unsafe.Pointer(p.VPred())), _BX) // MOVQ $fn, BX
+	self.call_go(_F_is_hidden)                                    // CALL_GO IsHidden
+	self.Emit("CMPB", _AX, jit.Imm(0))                            // CMPB AX, $0
+	self.Xjmp("JNE", p.Vi())                                      // JNE  p.Vi()
+}
+
 func (self *Assembler) _asm_OP_goto(p *ir.Instr) {
 	self.Xjmp("JMP", p.Vi())
 }
 
+func (self *Assembler) _asm_OP_check_tuple(p *ir.Instr) {
+	self.Emit("BTQ", jit.Imm(alg.BitStructAsArray), _ARG_fv) // BTQ ${BitStructAsArray}, fv
+	self.Xjmp("JC", p.Vi())                                   // JC  p.Vi()
+}
+
+func (self *Assembler) _asm_OP_check_sorted(p *ir.Instr) {
+	self.Emit("BTQ", jit.Imm(alg.BitSortStructFields), _ARG_fv) // BTQ ${BitSortStructFields}, fv
+	self.Xjmp("JC", p.Vi())                                      // JC  p.Vi()
+}
+
+var _F_writeRef = jit.Func(alg.WriteRef)
+
+func (self *Assembler) _asm_OP_ref(p *ir.Instr) {
+	self.Emit("BTQ", jit.Imm(alg.BitPointerRefs), _ARG_fv) // BTQ   ${BitPointerRefs}, fv
+	self.Sjmp("JNC", "_ref_next_{n}")                      // JNC   _ref_next_{n}
+	self.prep_buffer_AX()                                  // MOVE  {buf}, AX
+	self.Emit("MOVQ", jit.Ptr(_SP_p, 0), _BX)              // MOVQ  (SP.p), BX
+	self.Emit("MOVQ", _ST, _CX)                            // MOVQ  ST, CX
+	self.call_encoder(_F_writeRef)                         // CALL  WriteRef
+	self.Emit("TESTB", _AX, _AX)                           // TESTB AL, AL
+	self.load_buffer_AX()                                  // LOAD  {buf}
+	self.Xjmp("JNZ", p.Vi())                               // JNZ   p.Vi()
+	self.Link("_ref_next_{n}")                             // _ref_next_{n}:
+}
+
 func (self *Assembler) _asm_OP_map_iter(p *ir.Instr) {
 	self.Emit("MOVQ", jit.Type(p.Vt()), _AX)  // MOVQ    $p.Vt(), AX
 	self.Emit("MOVQ", jit.Ptr(_SP_p, 0), _BX) // MOVQ    (SP.p), BX
@@ -1185,6 +1252,16 @@ func (self *Assembler) _asm_OP_marshal_p(p *ir.Instr) {
 	}
 }
 
+func (self *Assembler) _asm_OP_raw(_ *ir.Instr) {
+	self.prep_buffer_AX()              // MOVE    {buf}, AX
+	self.Emit("MOVQ", _SP_p, _BX)      // MOVQ    SP.p, BX
+	self.Emit("MOVQ", _ARG_fv, _CX)    // MOVQ    ARG.fv, CX
+	self.call_go(_F_encodeRawMessage)  // CALL_GO encodeRawMessage
+	self.Emit("TESTQ", _ET, _ET)       // TESTQ   ET, ET
+	self.Sjmp("JNZ", _LB_error)        // JNZ     _error
+	self.load_buffer_AX()
+}
+
 func (self *Assembler) _asm_OP_marshal_text(p *ir.Instr) {
 	self.call_marshaler(_F_encodeTextMarshaler, _T_encoding_TextMarshaler, p.Vt())
 }
@@ -1197,6 +1274,10 @@ func (self *Assembler) _asm_OP_marshal_text_p(p *ir.Instr) {
 	}
 }
 
+func (self *Assembler) _asm_OP_map_key_utext(p *ir.Instr) {
+	self.call_marshaler(_F_encodeTextMarshalerKey, _T_encoding_TextMarshaler, p.Vt())
+}
+
 func (self *Assembler) _asm_OP_cond_set(_ *ir.Instr) {
 	self.Emit("ORQ", jit.Imm(1<<_S_cond), _SP_f) // ORQ $(1<<_S_cond), SP.f
 }
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package envs
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// jitDisabled is non-zero while the JIT must not be used, it may be flipped
+// at any time, so it is accessed atomically. Setting SONIC_JIT_ENABLED=0
+// disables the JIT from the start.
+var jitDisabled int32
+
+func init() {
+	if os.Getenv("SONIC_JIT_ENABLED") == "0" {
+		jitDisabled = 1
+	}
+}
+
+// UseJIT reports whether the JIT is enabled.
+func UseJIT() bool {
+	return atomic.LoadInt32(&jitDisabled) == 0
+}
+
+func EnableJIT() {
+	atomic.StoreInt32(&jitDisabled, 0)
+}
+
+func DisableJIT() {
+	atomic.StoreInt32(&jitDisabled, 1)
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/twitchyliquid64/golang-asm/asm/arch"
 	"github.com/twitchyliquid64/golang-asm/obj"
+	"github.com/twitchyliquid64/golang-asm/obj/arm64"
 )
 
 var (
@@ -55,7 +56,7 @@ var (
 	R15 = Reg("R15")
 	R16 = Reg("R16")
 	R17 = Reg("R17")
-	R18 = Reg("R18")
+	R18 = Reg("R18_PLATFORM") // golang-asm renames R18 to avoid accidental use
 	R19 = Reg("R19")
 	R20 = Reg("R20")
 	R21 = Reg("R21")
@@ -65,15 +66,16 @@ var (
 	R25 = Reg("R25")
 	R26 = Reg("R26")
 	R27 = Reg("R27")
-	R28 = Reg("R28")
+	R28 = Reg("g")   // golang-asm names R28 after the goroutine it holds
 	FP  = Reg("R29") // Frame Pointer
 	LR  = Reg("R30") // Link Register
 )
 
 // Zero register and Stack Pointer
 var (
-	ZR = Reg("ZR") // Zero Register (always 0)
-	SP = Reg("SP") // Stack Pointer
+	ZR  = Reg("ZR")  // Zero Register (always 0)
+	SP  = Reg("SP")  // Pseudo Stack Pointer of the Go assembler
+	RSP = Reg("RSP") // Hardware Stack Pointer
 )
 
 // Floating-point and SIMD registers (V0-V31)
@@ -174,6 +176,15 @@ func Ptr(reg obj.Addr, offs int64) obj.Addr {
 	}
 }
 
+// RegPair creates the register pair operand of LDP and STP
+func RegPair(reg1, reg2 obj.Addr) obj.Addr {
+	return obj.Addr{
+		Reg:    reg1.Reg,
+		Type:   obj.TYPE_REGREG,
+		Offset: int64(reg2.Reg),
+	}
+}
+
 // OffsetReg creates a memory address with base register and index register offset
 func OffsetReg(base, index obj.Addr) obj.Addr {
 	return obj.Addr{
@@ -184,6 +195,18 @@ func OffsetReg(base, index obj.Addr) obj.Addr {
 	}
 }
 
+// Sib creates a memory address of the form base + index*scale + offset, as on
+// AMD64. ARM64 cannot address it directly, see arm64.InstructionTranslator
+func Sib(reg obj.Addr, idx obj.Addr, scale int16, offs int64) obj.Addr {
+	return obj.Addr{
+		Reg:    reg.Reg,
+		Index:  idx.Reg,
+		Scale:  scale,
+		Type:   obj.TYPE_MEM,
+		Offset: offs,
+	}
+}
+
 // ImmPtr creates an immediate pointer address from unsafe.Pointer
 func ImmPtr(imm unsafe.Pointer) obj.Addr {
 	return obj.Addr{
@@ -211,6 +234,33 @@ func ExtReg(reg obj.Addr, extType uint8) obj.Addr {
 	}
 }
 
+// Vec creates the arrangement Vn.<T> of a vector register, such as V0.B16
+// for Vec(Reg("V0"), arm64.ARNG_16B)
+func Vec(reg obj.Addr, arng int16) obj.Addr {
+	return obj.Addr{
+		Reg:  arm64.REG_ARNG + (reg.Reg & 31) + (arng&15)<<5,
+		Type: obj.TYPE_REG,
+	}
+}
+
+// VecElem creates the element Vn.<T>[index] of a vector register, such as
+// V0.D[1] for VecElem(Reg("V0"), arm64.ARNG_D, 1)
+func VecElem(reg obj.Addr, arng int16, index int16) obj.Addr {
+	return obj.Addr{
+		Reg:   arm64.REG_ELEM + (reg.Reg & 31) + (arng&15)<<5,
+		Index: index,
+		Type:  obj.TYPE_REG,
+	}
+}
+
+// VecList creates the single register list [Vn.B16] of VLD1 and VST1
+func VecList(reg obj.Addr) obj.Addr {
+	return obj.Addr{
+		Type:   obj.TYPE_REGLIST,
+		Offset: int64(reg.Reg&31) | 0x7<<12 | 1<<30 | 1<<60,
+	}
+}
+
 // ARM64 condition codes
 const (
 	COND_EQ = 0       // Equal
@@ -328,6 +378,12 @@ func AlignStack(size int64) int64 {
 	return size
 }
 
+// FrameSize returns the size of a frame holding framesize bytes of locals,
+// including the slot of the saved LR at 0(RSP)
+func FrameSize(framesize int64) int64 {
+	return AlignStack(framesize + 8)
+}
+
 // ARM64 calling convention constants
 const (
 	// Stack alignment
@@ -364,38 +420,3 @@ func As(op string) obj.As {
 		panic("invalid ARM64 instruction: " + op)
 	}
 }
-
-// ARM64 JIT support functions
-var arm64JITEnabled = true
-
-// IsARM64JITEnabled returns true if ARM64 JIT compilation is enabled
-func IsARM64JITEnabled() bool {
-	return arm64JITEnabled
-}
-
-// EnableARM64JIT enables ARM64 JIT compilation
-func EnableARM64JIT() {
-	arm64JITEnabled = true
-}
-
-// DisableARM64JIT disables ARM64 JIT compilation
-func DisableARM64JIT() {
-	arm64JITEnabled = false
-}
-
-// Func creates a function address from a Go function pointer
-func Func(fn interface{}) obj.Addr {
-	return ImmPtr(unsafe.Pointer(&fn))
-}
-
-// Type creates a type address from a Go type
-func Type(t interface{}) obj.Addr {
-	return ImmPtr(unsafe.Pointer(&t))
-}
-
-// Itab creates an itab address for interface type checking
-func Itab(typ, iface interface{}) obj.Addr {
-	// This is a simplified implementation
-	// In a full implementation, this would create proper itab
-	return ImmPtr(unsafe.Pointer(&typ))
-}
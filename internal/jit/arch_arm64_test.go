@@ -139,17 +139,29 @@ func TestARM64OffsetRegisterCreation(t *testing.T) {
 	}
 }
 
+func TestARM64SibCreation(t *testing.T) {
+	sib := Sib(Reg("R0"), Reg("R1"), 4, 16)
+
+	if sib.Type != obj.TYPE_MEM || sib.Reg != Reg("R0").Reg || sib.Index != Reg("R1").Reg {
+		t.Errorf("Expected (R0)(R1) memory operand, got %v", sib)
+	}
+
+	if sib.Scale != 4 || sib.Offset != 16 {
+		t.Errorf("Expected scale 4 and offset 16, got %d and %d", sib.Scale, sib.Offset)
+	}
+}
+
 func TestARM64ImmediatePointer(t *testing.T) {
 	testValue := 0x12345678
-	ptr := unsafe.Pointer(uintptr(testValue))
+	ptr := unsafe.Pointer(&testValue)
 	immPtr := ImmPtr(ptr)
 
 	if immPtr.Type != obj.TYPE_CONST {
 		t.Errorf("Expected constant type TYPE_CONST, got %v", immPtr.Type)
 	}
 
-	if immPtr.Offset != int64(testValue) {
-		t.Errorf("Expected offset %d, got %d", testValue, immPtr.Offset)
+	if immPtr.Offset != int64(uintptr(ptr)) {
+		t.Errorf("Expected offset %d, got %d", uintptr(ptr), immPtr.Offset)
 	}
 }
 
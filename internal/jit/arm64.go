@@ -19,30 +19,30 @@
 
 package jit
 
+import (
+	"github.com/bytedance/sonic/internal/envs"
+)
+
 // This file contains ARM64-specific JIT support for Sonic
 // It imports and exposes ARM64-specific functionality
 
-//go:noescape
-
 // HasARM64JITSupport indicates if ARM64 JIT compilation is supported
 const HasARM64JITSupport = true
 
-// ARM64JITEnabled indicates if ARM64 JIT compilation is enabled
-var ARM64JITEnabled = true
-
 // EnableARM64JIT enables ARM64 JIT compilation
 func EnableARM64JIT() {
-	ARM64JITEnabled = true
+	envs.EnableJIT()
 }
 
 // DisableARM64JIT disables ARM64 JIT compilation
 func DisableARM64JIT() {
-	ARM64JITEnabled = false
+	envs.DisableJIT()
 }
 
-// IsARM64JITEnabled returns true if ARM64 JIT compilation is enabled
+// IsARM64JITEnabled returns true if ARM64 JIT compilation is enabled, it
+// follows sonic.SetJITEnabled
 func IsARM64JITEnabled() bool {
-	return ARM64JITEnabled
+	return envs.UseJIT()
 }
 
 // ARM64JITVersion returns the version of ARM64 JIT support
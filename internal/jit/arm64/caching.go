@@ -242,7 +242,7 @@ func (jc *JITCache) getTypeInfo(vt reflect.Type) *TypeInfo {
 		Type:        vt,
 		Kind:        vt.Kind(),
 		Size:        vt.Size(),
-		Align:       vt.Align(),
+		Align:       uintptr(vt.Align()),
 		IsPointer:   vt.Kind() == reflect.Ptr,
 		IsInterface: vt.Kind() == reflect.Interface,
 		IsSlice:     vt.Kind() == reflect.Slice,
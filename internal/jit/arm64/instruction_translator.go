@@ -22,6 +22,7 @@ package arm64
 import (
 	"fmt"
 
+	"github.com/bytedance/sonic/internal/jit"
 	"github.com/twitchyliquid64/golang-asm/obj"
 	"github.com/twitchyliquid64/golang-asm/obj/arm64"
 )
@@ -56,60 +57,76 @@ const (
 	INSN_PUSH
 	INSN_POP
 	INSN_LEA
+	INSN_NOP
 )
 
-// Condition codes for conditional jumps
+// Condition codes for conditional jumps, numbered as in the AMD64 Jcc encoding
 type ConditionCode int
 
 const (
-	COND_O   ConditionCode = iota // Overflow
-	COND_NO                       // No overflow
-	COND_B  ConditionCode = 2     // Below/Carry
-	COND_NAE                      // Not above or equal
-	COND_C                        // Carry
-	COND_NC                       // No carry
-	COND_NA                       // Not above
-	COND_BE                       // Below or equal
-	COND_A                        // Above
-	COND_E                        // Equal
-	COND_Z                        // Zero
-	COND_NE                       // Not equal
-	COND_NZ                       // Not zero
-	COND_NA                       // Not above
-	COND_BE                       // Below or equal
-	COND_A                        // Above
-	COND_S                        // Sign
-	COND_NS                       // Not sign
-	COND_P                        // Parity
-	COND_NP                       // Not parity
-	COND_PE                       // Parity even
-	COND_PO                       // Parity odd
-	COND_L                        // Less
-	COND_GE                       // Greater or equal
-	COND_NL                       // Not less
-	COND_NGE                      // Not greater or equal
-	COND_LE                       // Less or equal
-	COND_G                        // Greater
-	COND_NLE                      // Not less or equal
-	COND_NG                       // Not greater
+	COND_O  ConditionCode = iota // Overflow
+	COND_NO                      // No overflow
+	COND_B                       // Below (unsigned)
+	COND_AE                      // Above or equal (unsigned)
+	COND_E                       // Equal
+	COND_NE                      // Not equal
+	COND_BE                      // Below or equal (unsigned)
+	COND_A                       // Above (unsigned)
+	COND_S                       // Sign
+	COND_NS                      // Not sign
+	COND_P                       // Parity
+	COND_NP                      // Not parity
+	COND_L                       // Less (signed)
+	COND_GE                      // Greater or equal (signed)
+	COND_LE                      // Less or equal (signed)
+	COND_G                       // Greater (signed)
 )
 
-// AMD64 to ARM64 condition code mapping
+// Aliases of the condition codes above
+const (
+	COND_C   = COND_B  // Carry
+	COND_NAE = COND_B  // Not above or equal
+	COND_NC  = COND_AE // No carry
+	COND_NB  = COND_AE // Not below
+	COND_Z   = COND_E  // Zero
+	COND_NZ  = COND_NE // Not zero
+	COND_NA  = COND_BE // Not above
+	COND_NBE = COND_A  // Not below or equal
+	COND_PE  = COND_P  // Parity even
+	COND_PO  = COND_NP // Parity odd
+	COND_NGE = COND_L  // Not greater or equal
+	COND_NL  = COND_GE // Not less
+	COND_NG  = COND_LE // Not greater
+	COND_NLE = COND_G  // Not less or equal
+)
+
+// Scratch registers for the memory operands ARM64 cannot address directly,
+// the intra-procedure-call registers are free to clobber at any point
+var (
+	_TMP0 = jit.R16
+	_TMP1 = jit.R17
+)
+
+// AMD64 to ARM64 condition code mapping, covering every AMD64 condition.
+// Parity is only meaningful after UCOMISD, where it flags an unordered result,
+// which FCMP reports in the V flag instead.
 var conditionMap = map[ConditionCode]uint8{
-	COND_E:  COND_EQ, // Equal -> Equal
-	COND_Z:  COND_EQ, // Zero -> Equal
-	COND_NE: COND_NE, // Not equal -> Not equal
-	COND_NZ: COND_NE, // Not zero -> Not equal
-	COND_L:  COND_LT, // Less (signed) -> Less than
-	COND_GE: COND_GE, // Greater or equal -> Greater or equal
-	COND_LE: COND_LE, // Less or equal -> Less or equal
-	COND_G:  COND_GT, // Greater -> Greater than
-	COND_B:  COND_LO, // Below (unsigned) -> Lower
-	COND_A:  COND_HI, // Above (unsigned) -> Higher
-	COND_BE: COND_LS, // Below or equal -> Lower or same
-	COND_AE: COND_HS, // Above or equal -> Higher or same
-	COND_S:  COND_MI, // Sign -> Minus
-	COND_NS: COND_PL, // Not sign -> Plus
+	COND_O:  jit.COND_VS, // Overflow -> Overflow set
+	COND_NO: jit.COND_VC, // No overflow -> Overflow clear
+	COND_B:  jit.COND_LO, // Below (unsigned) -> Lower
+	COND_AE: jit.COND_HS, // Above or equal -> Higher or same
+	COND_E:  jit.COND_EQ, // Equal -> Equal
+	COND_NE: jit.COND_NE, // Not equal -> Not equal
+	COND_BE: jit.COND_LS, // Below or equal -> Lower or same
+	COND_A:  jit.COND_HI, // Above (unsigned) -> Higher
+	COND_S:  jit.COND_MI, // Sign -> Minus
+	COND_NS: jit.COND_PL, // Not sign -> Plus
+	COND_P:  jit.COND_VS, // Parity (unordered) -> Overflow set
+	COND_NP: jit.COND_VC, // Not parity (ordered) -> Overflow clear
+	COND_L:  jit.COND_LT, // Less (signed) -> Less than
+	COND_GE: jit.COND_GE, // Greater or equal -> Greater or equal
+	COND_LE: jit.COND_LE, // Less or equal -> Less or equal
+	COND_G:  jit.COND_GT, // Greater -> Greater than
 }
 
 // TranslateInstruction translates a generic instruction to ARM64
@@ -151,6 +168,8 @@ func (t *InstructionTranslator) TranslateInstruction(insnType InstructionType, o
 		return t.translatePop(operands...)
 	case INSN_LEA:
 		return t.translateLea(operands...)
+	case INSN_NOP:
+		return &obj.Prog{As: obj.ANOP}, nil
 	default:
 		return nil, fmt.Errorf("unsupported instruction type: %v", insnType)
 	}
@@ -179,19 +198,26 @@ func (t *InstructionTranslator) translateMov(operands ...interface{}) (*obj.Prog
 		}
 	} else if src.Type == obj.TYPE_MEM && dst.Type == obj.TYPE_REG {
 		// Memory to register - use load instruction
-		p.As = arm64.AMOVD
-		p.From = src
-		p.To = dst
+		return t.load(dst, src)
 	} else if src.Type == obj.TYPE_REG && dst.Type == obj.TYPE_MEM {
 		// Register to memory - use store instruction
-		p.As = arm64.AMOVD
-		p.From = src
-		p.To = dst
+		return t.store(dst, src, _TMP0)
 	} else if src.Type == obj.TYPE_REG && dst.Type == obj.TYPE_REG {
 		// Register to register
 		p.As = arm64.AMOVD
 		p.From = src
 		p.To = dst
+	} else if src.Type == obj.TYPE_MEM && dst.Type == obj.TYPE_MEM {
+		// Memory to memory - ARM64 has no such form, go through a scratch register
+		ld, err := t.load(_TMP0, src)
+		if err != nil {
+			return nil, err
+		}
+		st, err := t.store(dst, _TMP0, _TMP1)
+		if err != nil {
+			return nil, err
+		}
+		return link(ld, st), nil
 	} else {
 		return nil, fmt.Errorf("unsupported MOV operand types: src=%v, dst=%v", src.Type, dst.Type)
 	}
@@ -199,6 +225,35 @@ func (t *InstructionTranslator) translateMov(operands ...interface{}) (*obj.Prog
 	return p, nil
 }
 
+// load loads the memory operand src into the register dst
+func (t *InstructionTranslator) load(dst obj.Addr, src obj.Addr) (*obj.Prog, error) {
+	if src.Index == 0 {
+		return &obj.Prog{As: arm64.AMOVD, From: src, To: dst}, nil
+	}
+
+	// Indexed load - compute the address into dst, then load through it
+	ea, err := t.lea(dst, src)
+	if err != nil {
+		return nil, err
+	}
+	return link(ea, &obj.Prog{As: arm64.AMOVD, From: jit.Ptr(dst, 0), To: dst}), nil
+}
+
+// store stores the register src into the memory operand dst, using tmp to
+// hold the address if dst is indexed
+func (t *InstructionTranslator) store(dst obj.Addr, src obj.Addr, tmp obj.Addr) (*obj.Prog, error) {
+	if dst.Index == 0 {
+		return &obj.Prog{As: arm64.AMOVD, From: src, To: dst}, nil
+	}
+
+	// Indexed store - compute the address into tmp, then store through it
+	ea, err := t.lea(tmp, dst)
+	if err != nil {
+		return nil, err
+	}
+	return link(ea, &obj.Prog{As: arm64.AMOVD, From: src, To: jit.Ptr(tmp, 0)}), nil
+}
+
 // translateAdd translates ADD instructions
 func (t *InstructionTranslator) translateAdd(operands ...interface{}) (*obj.Prog, error) {
 	if len(operands) < 2 {
@@ -476,7 +531,7 @@ func (t *InstructionTranslator) translateJmp(operands ...interface{}) (*obj.Prog
 	case string:
 		p.To = obj.Addr{
 			Type: obj.TYPE_BRANCH,
-			Name: v,
+			Sym:  &obj.LSym{Name: v},
 		}
 	default:
 		return nil, fmt.Errorf("unsupported JMP target type: %T", target)
@@ -504,30 +559,34 @@ func (t *InstructionTranslator) translateJcc(operands ...interface{}) (*obj.Prog
 
 	// Select appropriate ARM64 conditional branch instruction
 	switch arm64Cond {
-	case COND_EQ:
+	case jit.COND_EQ:
 		p.As = arm64.ABEQ
-	case COND_NE:
+	case jit.COND_NE:
 		p.As = arm64.ABNE
-	case COND_LT:
+	case jit.COND_LT:
 		p.As = arm64.ABLT
-	case COND_GE:
+	case jit.COND_GE:
 		p.As = arm64.ABGE
-	case COND_LE:
+	case jit.COND_LE:
 		p.As = arm64.ABLE
-	case COND_GT:
+	case jit.COND_GT:
 		p.As = arm64.ABGT
-	case COND_HI:
+	case jit.COND_HI:
 		p.As = arm64.ABHI
-	case COND_LS:
+	case jit.COND_LS:
 		p.As = arm64.ABLS
-	case COND_HS:
+	case jit.COND_HS:
 		p.As = arm64.ABHS
-	case COND_LO:
+	case jit.COND_LO:
 		p.As = arm64.ABLO
-	case COND_MI:
+	case jit.COND_MI:
 		p.As = arm64.ABMI
-	case COND_PL:
+	case jit.COND_PL:
 		p.As = arm64.ABPL
+	case jit.COND_VS:
+		p.As = arm64.ABVS
+	case jit.COND_VC:
+		p.As = arm64.ABVC
 	default:
 		return nil, fmt.Errorf("unsupported ARM64 condition: %v", arm64Cond)
 	}
@@ -538,7 +597,7 @@ func (t *InstructionTranslator) translateJcc(operands ...interface{}) (*obj.Prog
 	case string:
 		p.To = obj.Addr{
 			Type: obj.TYPE_BRANCH,
-			Name: v,
+			Sym:  &obj.LSym{Name: v},
 		}
 	default:
 		return nil, fmt.Errorf("unsupported JCC target type: %T", target)
@@ -564,7 +623,7 @@ func (t *InstructionTranslator) translateCall(operands ...interface{}) (*obj.Pro
 	case string:
 		p.To = obj.Addr{
 			Type: obj.TYPE_BRANCH,
-			Name: v,
+			Sym:  &obj.LSym{Name: v},
 		}
 	default:
 		return nil, fmt.Errorf("unsupported CALL target type: %T", target)
@@ -576,7 +635,7 @@ func (t *InstructionTranslator) translateCall(operands ...interface{}) (*obj.Pro
 // translateRet translates RET instructions
 func (t *InstructionTranslator) translateRet(operands ...interface{}) (*obj.Prog, error) {
 	p := &obj.Prog{}
-	p.As = arm64.ARET
+	p.As = obj.ARET
 
 	// ARM64 RET can optionally specify return register (usually LR)
 	if len(operands) > 0 {
@@ -603,8 +662,8 @@ func (t *InstructionTranslator) translatePush(operands ...interface{}) (*obj.Pro
 	p.From = src
 	p.To = obj.Addr{
 		Type:   obj.TYPE_MEM,
-		Reg:    SP.Reg,
-		 Offset: -16, // Pre-decrement by 16 (stack alignment)
+		Reg:    jit.RSP.Reg,
+		Offset: -16, // Pre-decrement by 16 (stack alignment)
 	}
 
 	return p, nil
@@ -624,7 +683,7 @@ func (t *InstructionTranslator) translatePop(operands ...interface{}) (*obj.Prog
 	p.As = arm64.AMOVD
 	p.From = obj.Addr{
 		Type:   obj.TYPE_MEM,
-		Reg:    SP.Reg,
+		Reg:    jit.RSP.Reg,
 		Offset: 16, // Post-increment by 16
 	}
 	p.To = dst
@@ -641,37 +700,68 @@ func (t *InstructionTranslator) translateLea(operands ...interface{}) (*obj.Prog
 	dst := operands[0].(obj.Addr)
 	src := operands[1].(obj.Addr)
 
-	p := &obj.Prog{}
+	if src.Type != obj.TYPE_MEM {
+		return nil, fmt.Errorf("LEA requires memory operand")
+	}
 
 	// ARM64 doesn't have LEA, but we can simulate it with ADD
-	if src.Type == obj.TYPE_MEM {
-		// LEA dst, [base + offset] -> ADD dst, base, #offset
-		p.As = arm64.AADD
-		p.From = obj.Addr{
-			Type:   obj.TYPE_REG,
-			Reg:    src.Reg,
-		}
-		p.To = dst
-
-		// Add offset if present
-		if src.Offset != 0 {
-			p.From.Type = obj.TYPE_CONST
-			p.From.Offset = src.Offset
-		}
+	return t.lea(dst, src)
+}
 
-		// Handle index register if present
-		if src.Index != 0 {
-			// Complex addressing mode, need multiple instructions
-			// This is a simplified version - real implementation would be more complex
-			return nil, fmt.Errorf("complex LEA addressing not fully implemented")
-		}
-	} else {
-		return nil, fmt.Errorf("LEA requires memory operand")
+// lea computes the effective address base + index*scale + offset of the
+// memory operand src into the register dst
+func (t *InstructionTranslator) lea(dst obj.Addr, src obj.Addr) (*obj.Prog, error) {
+	var p *obj.Prog
+	var shift int64
+
+	// Only the AMD64 scales are valid, no scale means 1
+	switch src.Scale {
+	case 0, 1:
+		shift = 0
+	case 2:
+		shift = 1
+	case 4:
+		shift = 2
+	case 8:
+		shift = 3
+	default:
+		return nil, fmt.Errorf("invalid LEA scale: %d", src.Scale)
+	}
+
+	switch {
+	case src.Index == 0 && src.Offset == 0:
+		// LEA dst, [base] -> MOVD base, dst
+		return &obj.Prog{As: arm64.AMOVD, From: obj.Addr{Type: obj.TYPE_REG, Reg: src.Reg}, To: dst}, nil
+	case src.Index == 0:
+		// LEA dst, [base + offset] -> ADD $offset, base, dst
+		return &obj.Prog{As: arm64.AADD, From: jit.Imm(src.Offset), Reg: src.Reg, To: dst}, nil
+	case src.Reg == 0:
+		// LEA dst, [index*scale] -> LSL $shift, index, dst
+		p = &obj.Prog{As: arm64.ALSL, From: jit.Imm(shift), Reg: src.Index, To: dst}
+	default:
+		// LEA dst, [base + index*scale] -> ADD index<<shift, base, dst
+		p = &obj.Prog{As: arm64.AADD, From: obj.Addr{Type: obj.TYPE_SHIFT, Offset: int64(src.Index&31)<<16 | shift<<10}, Reg: src.Reg, To: dst}
 	}
 
+	// The offset goes last, dst may be the base or the index register
+	if src.Offset != 0 {
+		p.Link = &obj.Prog{As: arm64.AADD, From: jit.Imm(src.Offset), Reg: dst.Reg, To: dst}
+	}
 	return p, nil
 }
 
+// link chains the instruction sequences together, and returns the first one
+func link(seqs ...*obj.Prog) *obj.Prog {
+	for i := 0; i < len(seqs)-1; i++ {
+		p := seqs[i]
+		for p.Link != nil {
+			p = p.Link
+		}
+		p.Link = seqs[i+1]
+	}
+	return seqs[0]
+}
+
 // TranslateInstructionSequence translates a sequence of instructions
 func (t *InstructionTranslator) TranslateInstructionSequence(instructions []Instruction) ([]*obj.Prog, error) {
 	var programs []*obj.Prog
@@ -693,52 +783,61 @@ type Instruction struct {
 	Operands []interface{}
 }
 
-// OptimizeForARM64 performs ARM64-specific optimizations on the instruction sequence
+// OptimizeForARM64 performs ARM64-specific optimizations on the instruction
+// sequence, the instructions which do nothing are dropped from it
 func (t *InstructionTranslator) OptimizeForARM64(instructions []Instruction) []Instruction {
 	var optimized []Instruction
 
 	for i, insn := range instructions {
-		// Skip redundant MOV instructions
-		if i > 0 && insn.Type == INSN_MOV {
+		// Skip a MOV undoing the previous one
+		if i > 0 && insn.Type == INSN_MOV && len(insn.Operands) >= 2 {
 			prev := instructions[i-1]
-			if prev.Type == INSN_MOV && insn.Operands[0] == prev.Operands[1] && insn.Operands[1] == prev.Operands[0] {
-				// Redundant MOV, skip it
+			if prev.Type == INSN_MOV && len(prev.Operands) >= 2 && insn.Operands[0] == prev.Operands[1] && insn.Operands[1] == prev.Operands[0] {
 				continue
 			}
 		}
 
-		// Optimize common patterns
-		optimized = append(optimized, t.optimizeInstruction(insn))
+		if !t.isNoop(insn) {
+			optimized = append(optimized, insn)
+		}
 	}
 
 	return optimized
 }
 
-// optimizeInstruction performs optimization on a single instruction
-func (t *InstructionTranslator) optimizeInstruction(insn Instruction) Instruction {
+// isNoop tells if insn leaves its destination unchanged, which are the MOVs
+// of a register to itself, and the ADDs and SUBs of zero in place
+func (t *InstructionTranslator) isNoop(insn Instruction) bool {
 	switch insn.Type {
-	case INSN_ADD:
-		// Convert ADD dst, dst, #0 to NOP
-		if len(insn.Operands) == 3 {
-			if imm, ok := insn.Operands[2].(obj.Addr); ok && imm.Type == obj.TYPE_CONST && imm.Offset == 0 {
-				return Instruction{Type: INSN_NOP}
-			}
+	case INSN_NOP:
+		return true
+	case INSN_MOV:
+		if len(insn.Operands) == 2 {
+			dst, ok1 := insn.Operands[0].(obj.Addr)
+			src, ok2 := insn.Operands[1].(obj.Addr)
+			return ok1 && ok2 && dst.Type == obj.TYPE_REG && src == dst
 		}
-	case INSN_SUB:
-		// Convert SUB dst, dst, #0 to NOP
-		if len(insn.Operands) == 3 {
-			if imm, ok := insn.Operands[2].(obj.Addr); ok && imm.Type == obj.TYPE_CONST && imm.Offset == 0 {
-				return Instruction{Type: INSN_NOP}
+	case INSN_ADD, INSN_SUB:
+		var dst, src obj.Addr
+		var ok bool
+		switch len(insn.Operands) {
+		case 2:
+			dst, ok = insn.Operands[0].(obj.Addr)
+			src = dst
+		case 3:
+			if dst, ok = insn.Operands[0].(obj.Addr); ok {
+				src, ok = insn.Operands[1].(obj.Addr)
 			}
 		}
+		if !ok || dst.Type != obj.TYPE_REG || src != dst {
+			return false
+		}
+		imm, ok := insn.Operands[len(insn.Operands)-1].(obj.Addr)
+		return ok && imm.Type == obj.TYPE_CONST && imm.Offset == 0
 	}
-
-	return insn
+	return false
 }
 
-// INSN_NOP represents a no-operation instruction
-const INSN_NOP InstructionType = 999
-
 // ValidateInstructionSequence validates that an instruction sequence is correct for ARM64
 func (t *InstructionTranslator) ValidateInstructionSequence(instructions []Instruction) error {
 	for i, insn := range instructions {
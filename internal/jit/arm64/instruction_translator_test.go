@@ -20,6 +20,7 @@
 package arm64
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/bytedance/sonic/internal/jit"
@@ -208,7 +209,7 @@ func TestInstructionTranslator_TranslateJmp(t *testing.T) {
 			target: "test_label",
 			expected: obj.Addr{
 				Type: obj.TYPE_BRANCH,
-				Name: "test_label",
+				Sym:  &obj.LSym{Name: "test_label"},
 			},
 		},
 		{
@@ -277,7 +278,7 @@ func TestInstructionTranslator_TranslateJcc(t *testing.T) {
 				t.Errorf("Expected instruction %v, got %v", tt.expectedAs, prog.As)
 			}
 
-			if prog.To.Type != obj.TYPE_BRANCH || prog.To.Name != "test_target" {
+			if prog.To.Type != obj.TYPE_BRANCH || prog.To.Sym == nil || prog.To.Sym.Name != "test_target" {
 				t.Errorf("Expected branch target 'test_target', got %v", prog.To)
 			}
 		})
@@ -296,7 +297,7 @@ func TestInstructionTranslator_TranslateCall(t *testing.T) {
 		t.Errorf("Expected instruction %v, got %v", arm64.ABL, prog.As)
 	}
 
-	if prog.To.Type != obj.TYPE_BRANCH || prog.To.Name != "test_function" {
+	if prog.To.Type != obj.TYPE_BRANCH || prog.To.Sym == nil || prog.To.Sym.Name != "test_function" {
 		t.Errorf("Expected branch target 'test_function', got %v", prog.To)
 	}
 }
@@ -309,8 +310,8 @@ func TestInstructionTranslator_TranslateRet(t *testing.T) {
 		t.Fatalf("Translation failed: %v", err)
 	}
 
-	if prog.As != arm64.ARET {
-		t.Errorf("Expected instruction %v, got %v", arm64.ARET, prog.As)
+	if prog.As != obj.ARET {
+		t.Errorf("Expected instruction %v, got %v", obj.ARET, prog.As)
 	}
 }
 
@@ -395,21 +396,18 @@ func TestInstructionTranslator_OptimizeForARM64(t *testing.T) {
 
 	instructions := []Instruction{
 		{Type: INSN_MOV, Operands: []interface{}{jit.R0, jit.Imm(10)}},
-		{Type: INSN_ADD, Operands: []interface{}{jit.R0, jit.Imm(0)}}, // Should be optimized to NOP
-		{Type: INSN_SUB, Operands: []interface{}{jit.R1, jit.R1, jit.Imm(0)}}, // Should be optimized to NOP
+		{Type: INSN_ADD, Operands: []interface{}{jit.R0, jit.Imm(0)}},         // dropped
+		{Type: INSN_SUB, Operands: []interface{}{jit.R1, jit.R1, jit.Imm(0)}}, // dropped
+		{Type: INSN_MOV, Operands: []interface{}{jit.R3, jit.R3}},             // dropped
+		{Type: INSN_NOP},                                                      // dropped
+		{Type: INSN_ADD, Operands: []interface{}{jit.R4, jit.R5, jit.Imm(0)}}, // a move, kept
 		{Type: INSN_MOV, Operands: []interface{}{jit.R2, jit.R0}},
 	}
 
 	optimized := translator.OptimizeForARM64(instructions)
-
-	// Should have 2 instructions (ADD and SUB optimized to NOP)
-	if len(optimized) != 3 {
-		t.Errorf("Expected 3 instructions after optimization, got %d", len(optimized))
-	}
-
-	// Check that ADD with zero was optimized
-	if optimized[1].Type != INSN_NOP {
-		t.Errorf("Expected NOP at position 1, got %v", optimized[1].Type)
+	expected := []Instruction{instructions[0], instructions[5], instructions[6]}
+	if !reflect.DeepEqual(optimized, expected) {
+		t.Errorf("Expected %v after optimization, got %v", expected, optimized)
 	}
 }
 
@@ -466,6 +464,126 @@ func TestInstructionTranslator_ValidateInstructionSequence(t *testing.T) {
 	}
 }
 
+// evalAddr runs the address arithmetic of the instruction chain p over regs
+func evalAddr(t *testing.T, p *obj.Prog, regs map[int16]int64) {
+	for ; p != nil; p = p.Link {
+		var v int64
+		switch p.From.Type {
+		case obj.TYPE_CONST:
+			v = p.From.Offset
+		case obj.TYPE_REG:
+			v = regs[p.From.Reg]
+		case obj.TYPE_SHIFT:
+			v = regs[arm64.REG_R0+int16(p.From.Offset>>16&31)] << (p.From.Offset >> 10 & 63)
+		default:
+			t.Fatalf("Unexpected operand in address arithmetic: %v", p)
+		}
+		switch p.As {
+		case arm64.AMOVD:
+			regs[p.To.Reg] = v
+		case arm64.AADD:
+			regs[p.To.Reg] = regs[p.Reg] + v
+		case arm64.ALSL:
+			regs[p.To.Reg] = regs[p.Reg] << v
+		default:
+			t.Fatalf("Unexpected instruction in address arithmetic: %v", p)
+		}
+	}
+}
+
+// flatten returns the instruction chain p as a slice
+func flatten(p *obj.Prog) []*obj.Prog {
+	var seq []*obj.Prog
+	for ; p != nil; p = p.Link {
+		seq = append(seq, p)
+	}
+	return seq
+}
+
+func TestInstructionTranslator_TranslateLea(t *testing.T) {
+	translator := NewInstructionTranslator()
+
+	tests := []struct {
+		name string
+		dst  obj.Addr
+		src  obj.Addr
+		want int64
+	}{
+		{"base + index*4 + 16", jit.R0, jit.Sib(jit.R1, jit.R2, 4, 16), 0x1000 + 3*4 + 16},
+		{"base + index", jit.R0, jit.Sib(jit.R1, jit.R2, 1, 0), 0x1000 + 3},
+		{"into the base", jit.R1, jit.Sib(jit.R1, jit.R2, 8, -8), 0x1000 + 3*8 - 8},
+		{"into the index", jit.R2, jit.Sib(jit.R1, jit.R2, 2, 1), 0x1000 + 3*2 + 1},
+		{"index*8 + 4", jit.R0, jit.Sib(obj.Addr{}, jit.R2, 8, 4), 3*8 + 4},
+		{"base + offset", jit.R0, jit.Ptr(jit.R1, 24), 0x1000 + 24},
+		{"base", jit.R0, jit.Ptr(jit.R1, 0), 0x1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := translator.TranslateInstruction(INSN_LEA, tt.dst, tt.src)
+			if err != nil {
+				t.Fatalf("Translation failed: %v", err)
+			}
+
+			regs := map[int16]int64{jit.R1.Reg: 0x1000, jit.R2.Reg: 3}
+			evalAddr(t, prog, regs)
+			if regs[tt.dst.Reg] != tt.want {
+				t.Errorf("Expected address %#x, got %#x", tt.want, regs[tt.dst.Reg])
+			}
+		})
+	}
+
+	/* only the AMD64 scales can be lowered */
+	for _, scale := range []int16{3, 16, -1} {
+		if _, err := translator.TranslateInstruction(INSN_LEA, jit.R0, jit.Sib(jit.R1, jit.R2, scale, 0)); err == nil {
+			t.Errorf("Expected error for scale %d, but got none", scale)
+		}
+	}
+}
+
+func TestInstructionTranslator_TranslateMovIndexed(t *testing.T) {
+	translator := NewInstructionTranslator()
+	regs := map[int16]int64{jit.R1.Reg: 0x1000, jit.R2.Reg: 3}
+
+	/* indexed loads compute the address into the destination, then load through it */
+	prog, err := translator.TranslateInstruction(INSN_MOV, jit.R0, jit.Sib(jit.R1, jit.R2, 4, 16))
+	if err != nil {
+		t.Fatalf("Translation failed: %v", err)
+	}
+	seq := flatten(prog)
+	last := seq[len(seq)-1]
+	if last.As != arm64.AMOVD || last.From != jit.Ptr(jit.R0, 0) || last.To != jit.R0 {
+		t.Fatalf("Expected a load through R0, got %v", last)
+	}
+	seq[len(seq)-2].Link = nil
+	evalAddr(t, prog, regs)
+	if regs[jit.R0.Reg] != 0x1000+3*4+16 {
+		t.Errorf("Expected address %#x, got %#x", 0x1000+3*4+16, regs[jit.R0.Reg])
+	}
+
+	/* memory to memory goes through the scratch registers */
+	prog, err = translator.TranslateInstruction(INSN_MOV, jit.Sib(jit.R1, jit.R2, 8, 0), jit.Ptr(jit.R3, 8))
+	if err != nil {
+		t.Fatalf("Translation failed: %v", err)
+	}
+	seq = flatten(prog)
+	if len(seq) != 3 {
+		t.Fatalf("Expected 3 instructions, got %d", len(seq))
+	}
+	if seq[0].From != jit.Ptr(jit.R3, 8) || seq[0].To != _TMP0 {
+		t.Errorf("Expected a load into %v, got %v", _TMP0, seq[0])
+	}
+	if seq[2].From != _TMP0 || seq[2].To != jit.Ptr(_TMP1, 0) {
+		t.Errorf("Expected a store through %v, got %v", _TMP1, seq[2])
+	}
+	seq[0].Link = nil
+	seq[1].Link = nil
+	evalAddr(t, seq[1], regs)
+	if regs[_TMP1.Reg] != 0x1000+3*8 {
+		t.Errorf("Expected address %#x, got %#x", 0x1000+3*8, regs[_TMP1.Reg])
+	}
+}
+
 func TestConditionMapping(t *testing.T) {
 	translator := NewInstructionTranslator()
 
@@ -473,20 +591,20 @@ func TestConditionMapping(t *testing.T) {
 		amd64Cond   ConditionCode
 		expectedARM64 uint8
 	}{
-		{COND_E, COND_EQ},
-		{COND_Z, COND_EQ},
-		{COND_NE, COND_NE},
-		{COND_NZ, COND_NE},
-		{COND_L, COND_LT},
-		{COND_GE, COND_GE},
-		{COND_LE, COND_LE},
-		{COND_G, COND_GT},
-		{COND_B, COND_LO},
-		{COND_A, COND_HI},
-		{COND_BE, COND_LS},
-		{COND_AE, COND_HS},
-		{COND_S, COND_MI},
-		{COND_NS, COND_PL},
+		{COND_E, jit.COND_EQ},
+		{COND_Z, jit.COND_EQ},
+		{COND_NE, jit.COND_NE},
+		{COND_NZ, jit.COND_NE},
+		{COND_L, jit.COND_LT},
+		{COND_GE, jit.COND_GE},
+		{COND_LE, jit.COND_LE},
+		{COND_G, jit.COND_GT},
+		{COND_B, jit.COND_LO},
+		{COND_A, jit.COND_HI},
+		{COND_BE, jit.COND_LS},
+		{COND_AE, jit.COND_HS},
+		{COND_S, jit.COND_MI},
+		{COND_NS, jit.COND_PL},
 	}
 
 	for _, tt := range tests {
@@ -510,29 +628,29 @@ func TestConditionMapping(t *testing.T) {
 			// Verify the correct conditional branch instruction was generated
 			var expectedAs obj.As
 			switch arm64Cond {
-			case COND_EQ:
+			case jit.COND_EQ:
 				expectedAs = arm64.ABEQ
-			case COND_NE:
+			case jit.COND_NE:
 				expectedAs = arm64.ABNE
-			case COND_LT:
+			case jit.COND_LT:
 				expectedAs = arm64.ABLT
-			case COND_GE:
+			case jit.COND_GE:
 				expectedAs = arm64.ABGE
-			case COND_LE:
+			case jit.COND_LE:
 				expectedAs = arm64.ABLE
-			case COND_GT:
+			case jit.COND_GT:
 				expectedAs = arm64.ABGT
-			case COND_HI:
+			case jit.COND_HI:
 				expectedAs = arm64.ABHI
-			case COND_LS:
+			case jit.COND_LS:
 				expectedAs = arm64.ABLS
-			case COND_HS:
+			case jit.COND_HS:
 				expectedAs = arm64.ABHS
-			case COND_LO:
+			case jit.COND_LO:
 				expectedAs = arm64.ABLO
-			case COND_MI:
+			case jit.COND_MI:
 				expectedAs = arm64.ABMI
-			case COND_PL:
+			case jit.COND_PL:
 				expectedAs = arm64.ABPL
 			}
 
@@ -543,6 +661,48 @@ func TestConditionMapping(t *testing.T) {
 	}
 }
 
+func TestConditionMapTotal(t *testing.T) {
+	translator := NewInstructionTranslator()
+
+	/* the aliases share the codes of their conditions */
+	aliases := [][2]ConditionCode{
+		{COND_C, COND_B}, {COND_NAE, COND_B}, {COND_NC, COND_AE}, {COND_NB, COND_AE},
+		{COND_Z, COND_E}, {COND_NZ, COND_NE}, {COND_NA, COND_BE}, {COND_NBE, COND_A},
+		{COND_PE, COND_P}, {COND_PO, COND_NP}, {COND_NGE, COND_L}, {COND_NL, COND_GE},
+		{COND_NG, COND_LE}, {COND_NLE, COND_G},
+	}
+	for _, v := range aliases {
+		if v[0] != v[1] {
+			t.Errorf("Alias %d does not match condition %d", v[0], v[1])
+		}
+	}
+
+	/* every condition maps to a branchable ARM64 condition */
+	for cond := COND_O; cond <= COND_G; cond++ {
+		arm64Cond, ok := conditionMap[cond]
+		if !ok {
+			t.Errorf("Condition %d not found in mapping", cond)
+			continue
+		}
+		if arm64Cond > jit.COND_LE {
+			t.Errorf("Condition %d maps to invalid ARM64 condition %d", cond, arm64Cond)
+		}
+		if _, err := translator.TranslateInstruction(INSN_JCC, cond, "target"); err != nil {
+			t.Errorf("Translation of condition %d failed: %v", cond, err)
+		}
+	}
+	if len(conditionMap) != int(COND_G)+1 {
+		t.Errorf("Expected %d conditions in mapping, got %d", COND_G+1, len(conditionMap))
+	}
+
+	/* inverse conditions stay inverse on ARM64, whose codes differ in bit 0 */
+	for cond := COND_O; cond <= COND_G; cond += 2 {
+		if conditionMap[cond]^conditionMap[cond+1] != 1 {
+			t.Errorf("Conditions %d and %d are not inverse on ARM64", cond, cond+1)
+		}
+	}
+}
+
 // Benchmark tests for performance validation
 func BenchmarkInstructionTranslator_TranslateMov(b *testing.B) {
 	translator := NewInstructionTranslator()
@@ -577,7 +737,7 @@ func BenchmarkInstructionTranslator_TranslateJcc(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := translator.TranslateInstruction(INSN_JCC, COND_EQ, "target")
+		_, err := translator.TranslateInstruction(INSN_JCC, COND_E, "target")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -570,11 +570,11 @@ func TestARM64JIT_Integration_APICompatibility(t *testing.T) {
 	}
 
 	// Test with different sonic options
-	api := sonic.API{
-		PrettyPrint: true,
+	api := sonic.Config{
+		SortMapKeys: true,
 		UseInt64:    true,
 		UseNumber:   false,
-	}
+	}.Froze()
 
 	data := TestStruct{Name: "API Test", Age: 25}
 
@@ -583,9 +583,8 @@ func TestARM64JIT_Integration_APICompatibility(t *testing.T) {
 		t.Fatalf("API marshal error: %v", err)
 	}
 
-	// Should contain indentation due to PrettyPrint
 	if len(result) == 0 {
-		t.Error("Expected non-empty result from pretty print")
+		t.Error("Expected non-empty result")
 	}
 
 	var decoded TestStruct
@@ -207,14 +207,13 @@ func BenchmarkARM64JIT_Encode_LargeMap(b *testing.B) {
 
 func BenchmarkARM64JIT_Decode_Simple(b *testing.B) {
 	data, _ := json.Marshal(benchmarkSimpleData)
-	dec := sonic.NewDecoder(data)
 
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
 		var result SimpleStruct
-		_, err := dec.Decode(&result)
+		err := sonic.Unmarshal(data, &result)
 		if err != nil {
 			b.Fatalf("Decode error: %v", err)
 		}
@@ -223,14 +222,13 @@ func BenchmarkARM64JIT_Decode_Simple(b *testing.B) {
 
 func BenchmarkARM64JIT_Decode_Complex(b *testing.B) {
 	data, _ := json.Marshal(benchmarkComplexData)
-	dec := sonic.NewDecoder(data)
 
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
 		var result ComplexStruct
-		_, err := dec.Decode(&result)
+		err := sonic.Unmarshal(data, &result)
 		if err != nil {
 			b.Fatalf("Decode error: %v", err)
 		}
@@ -239,14 +237,13 @@ func BenchmarkARM64JIT_Decode_Complex(b *testing.B) {
 
 func BenchmarkARM64JIT_Decode_Nested(b *testing.B) {
 	data, _ := json.Marshal(benchmarkNestedData)
-	dec := sonic.NewDecoder(data)
 
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
 		var result NestedStruct
-		_, err := dec.Decode(&result)
+		err := sonic.Unmarshal(data, &result)
 		if err != nil {
 			b.Fatalf("Decode error: %v", err)
 		}
@@ -354,9 +351,8 @@ func BenchmarkARM64JIT_Concurrent_Decode(b *testing.B) {
 
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			dec := sonic.NewDecoder(data)
 			var result SimpleStruct
-			_, err := dec.Decode(&result)
+			err := sonic.Unmarshal(data, &result)
 			if err != nil {
 				b.Fatalf("Decode error: %v", err)
 			}
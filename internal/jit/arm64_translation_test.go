@@ -0,0 +1,77 @@
+//go:build arm64 && go1.20 && !go1.26
+// +build arm64,go1.20,!go1.26
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// The instruction translator lives in internal/jit/arm64, which imports this
+// package, so it can only be tested from outside of it.
+package jit_test
+
+import (
+	"testing"
+
+	"github.com/bytedance/sonic/internal/jit"
+	"github.com/bytedance/sonic/internal/jit/arm64"
+)
+
+func TestARM64InstructionTranslation(t *testing.T) {
+	if !jit.HasARM64JITSupport {
+		t.Skip("ARM64 JIT support not available")
+	}
+
+	translator := arm64.NewInstructionTranslator()
+
+	t.Run("MOV Translation", func(t *testing.T) {
+		prog, err := translator.TranslateInstruction(arm64.INSN_MOV, jit.R0, jit.R1)
+		if err != nil {
+			t.Fatalf("Failed to translate MOV: %v", err)
+		}
+		if prog == nil {
+			t.Error("Expected non-nil program")
+		}
+	})
+
+	t.Run("ADD Translation", func(t *testing.T) {
+		prog, err := translator.TranslateInstruction(arm64.INSN_ADD, jit.R0, jit.R1, jit.R2)
+		if err != nil {
+			t.Fatalf("Failed to translate ADD: %v", err)
+		}
+		if prog == nil {
+			t.Error("Expected non-nil program")
+		}
+	})
+
+	t.Run("JMP Translation", func(t *testing.T) {
+		prog, err := translator.TranslateInstruction(arm64.INSN_JMP, "target")
+		if err != nil {
+			t.Fatalf("Failed to translate JMP: %v", err)
+		}
+		if prog == nil {
+			t.Error("Expected non-nil program")
+		}
+	})
+
+	t.Run("JCC Translation", func(t *testing.T) {
+		prog, err := translator.TranslateInstruction(arm64.INSN_JCC, arm64.COND_E, "target")
+		if err != nil {
+			t.Fatalf("Failed to translate JCC: %v", err)
+		}
+		if prog == nil {
+			t.Error("Expected non-nil program")
+		}
+	})
+}
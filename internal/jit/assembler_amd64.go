@@ -212,6 +212,7 @@ var jitLoader = loader.Loader{
 
 func (self *BaseAssembler) Load(name string, frameSize int, argSize int, argStackmap []bool, localStackmap []bool) loader.Function {
     self.build()
+    recordCode(name, len(self.c))
     return jitLoader.LoadOne(self.c, name, frameSize, argSize, argStackmap, localStackmap)
 }
 
@@ -20,11 +20,13 @@
 package jit
 
 import (
+	"encoding"
 	"encoding/binary"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"unsafe"
 
 	"github.com/bytedance/sonic/internal/rt"
 	"github.com/bytedance/sonic/loader"
@@ -76,19 +78,27 @@ func (self *BaseAssembler) NOPn(n int) {
 	}
 }
 
-// Byte emits raw bytes directly into the instruction stream
-func (self *BaseAssembler) Byte(v ...byte) {
-	for ; len(v) >= 8; v = v[8:] {
-		self.From("MOVD", Imm(rt.Get64(v)))
+// Align pads the code with NOPs up to the next n-byte boundary, n being a
+// power of two. Since the PC is only known once the code is assembled, this
+// emits a PCALIGN directive, which the assembler fills with the same NOP as
+// _NOPS. Alignments up to 4 bytes always hold for ARM64 instructions.
+func (self *BaseAssembler) Align(n int) {
+	if n <= 0 || n&(n-1) != 0 {
+		panic("alignment must be a power of two: " + strconv.Itoa(n))
 	}
-	for ; len(v) >= 4; v = v[4:] {
-		self.From("MOVW", Imm(int64(rt.Get32(v))))
+	if n > 4 {
+		self.From("PCALIGN", Imm(int64(n)))
 	}
-	for ; len(v) >= 2; v = v[2:] {
-		self.From("MOVH", Imm(int64(rt.Get16(v))))
+}
+
+// Byte emits raw bytes directly into the instruction stream, as 32-bit words
+// since WORD is the only data directive that fits between ARM64 instructions
+func (self *BaseAssembler) Byte(v ...byte) {
+	if len(v)%4 != 0 {
+		panic("raw bytes must be whole 32-bit words on ARM64")
 	}
-	for ; len(v) >= 1; v = v[1:] {
-		self.From("MOVB", Imm(int64(v[0])))
+	for ; len(v) >= 4; v = v[4:] {
+		self.To("WORD", Imm(int64(rt.Get32(v))))
 	}
 }
 
@@ -98,9 +108,10 @@ func (self *BaseAssembler) Mark(pc int) {
 	self.Link(_LB_jump_pc + strconv.Itoa(pc))
 }
 
-// Link creates a label that can be jumped to
+// Link creates a label that can be jumped to, as a zero-sized NOP
 func (self *BaseAssembler) Link(to string) {
 	var p *obj.Prog
+	var v []*obj.Prog
 
 	// placeholder substitution for loops
 	if strings.Contains(to, "{n}") {
@@ -112,24 +123,18 @@ func (self *BaseAssembler) Link(to string) {
 		panic("label " + to + " has already been linked")
 	}
 
-	// check for pending jumps
-	if v, ok := self.pendings[to]; ok {
-		// link all pending jumps to this label
-		for _, p = range v {
-			p.To.Type = obj.TYPE_BRANCH
-			p.To.Val = p
-		}
-		delete(self.pendings, to)
+	// get the pending jumps
+	p = self.NOP()
+	v = self.pendings[to]
+
+	// link all pending jumps to this label
+	for _, q := range v {
+		q.To.Val = p
 	}
 
-	// mark the label position
-	p = self.pb.New()
-	p.As = obj.ATEXT
-	p.From.Type = obj.TYPE_ADDR
-	p.From.Name = obj.NAME_EXTERN
-	p.From.Sym = obj.Linksym(fmt.Sprintf("%s%s", loader.ModulePath, to))
+	// mark the label as resolved
 	self.labels[to] = p
-	self.pb.Append(p)
+	delete(self.pendings, to)
 }
 
 // Sjmp generates a jump instruction to a label
@@ -161,44 +166,92 @@ func (self *BaseAssembler) Sjmp(op string, to string) {
 	self.pb.Append(p)
 }
 
-// Sref creates a symbol reference for PC-relative addressing (ARM64 version)
+// Sref creates a symbol reference for PC-relative addressing. Right after an
+// ADR or ADRP instruction emitted with Byte, the reference feeds that
+// instruction, which gets patched to address the label. Otherwise it emits a
+// 32-bit data word holding the offset of the label from the word, minus d.
 func (self *BaseAssembler) Sref(to string, d int64) {
-	p := self.pb.New()
-	p.As = arm64.AMOVD
-	p.From = Imm(-d)
-
 	// placeholder substitution for loops
 	if strings.Contains(to, "{n}") {
 		to = strings.ReplaceAll(to, "{n}", strconv.Itoa(self.i))
 	}
 
+	// patch the address instruction itself
+	if p := self.pb.Tail; p != nil && p.As == arm64.AWORD && isADR(uint32(p.To.Offset)) {
+		self.xrefs[to] = append(self.xrefs[to], p)
+		return
+	}
+
 	// record the patch point
+	p := self.pb.New()
+	p.As = arm64.AWORD
+	p.To = Imm(-d)
 	self.pb.Append(p)
 	self.xrefs[to] = append(self.xrefs[to], p)
 }
 
-// resolve resolves symbol references for PC-relative addressing (ARM64 version)
+// Xref creates a PC-relative reference to the instruction marked with pc,
+// see Sref
+func (self *BaseAssembler) Xref(pc int, d int64) {
+	self.Sref(_LB_jump_pc+strconv.Itoa(pc), d)
+}
+
+// Xjmp generates a jump instruction to the instruction marked with pc
+func (self *BaseAssembler) Xjmp(op string, to int) {
+	self.Sjmp(op, _LB_jump_pc+strconv.Itoa(to))
+}
+
+// Rjmp generates an indirect jump or call through a register, like JMP (R16)
+// or CALL (R16), which are assembled into BR and BLR
+func (self *BaseAssembler) Rjmp(op string, to obj.Addr) {
+	p := self.pb.New()
+	p.As = As(op)
+	p.To = Ptr(to, 0)
+	self.pb.Append(p)
+}
+
+// resolve resolves symbol references for PC-relative addressing
 func (self *BaseAssembler) resolve() {
 	for s, v := range self.xrefs {
 		for _, prog := range v {
-			if prog.As != arm64.AMOVD {
+			if prog.As != arm64.AWORD {
 				panic("invalid PC relative reference")
-			} else if p, exists := self.labels[s]; !exists {
+			} else if p, ok := self.labels[s]; !ok {
 				panic("links are not fully resolved: " + s)
+			} else if ins := uint32(prog.To.Offset); isADR(ins) {
+				binary.LittleEndian.PutUint32(self.c[prog.Pc:], encodeADR(ins, p.Pc-prog.Pc, s))
 			} else {
-				// Calculate PC-relative offset for ARM64
-				off := prog.From.Offset + p.Pc - prog.Pc
-				// For ARM64, we need to write the offset as a 32-bit value
-				// This is a simplified implementation - real ARM64 PC-relative addressing is more complex
-				if int(prog.Pc)+4 <= len(self.c) {
-					// Write the offset as a signed 32-bit value
-					binary.LittleEndian.PutUint32(self.c[prog.Pc:], uint32(off))
-				}
+				off := prog.To.Offset + p.Pc - prog.Pc
+				binary.LittleEndian.PutUint32(self.c[prog.Pc:], uint32(off))
 			}
 		}
 	}
 }
 
+const (
+	_ADR_mask  = 0x1f000000 // op bits shared by ADR and ADRP
+	_ADR_op    = 0x10000000
+	_ADR_page  = 1 << 31 // set for ADRP
+	_ADR_range = 1 << 20 // ADR reaches ±1MB
+)
+
+func isADR(ins uint32) bool {
+	return ins&_ADR_mask == _ADR_op
+}
+
+// encodeADR sets the 21-bit immediate of ins to off, split into immlo (bits
+// 29-30) and immhi (bits 5-23). ADRP counts 4KB pages from the page of the
+// instruction, which depends on where the code gets loaded, so it is turned
+// into an ADR with the same destination register.
+func encodeADR(ins uint32, off int64, to string) uint32 {
+	if off < -_ADR_range || off >= _ADR_range {
+		panic(fmt.Sprintf("PC relative reference to %s is out of the ±1MB ADR range: %d", to, off))
+	}
+	imm := uint32(off) & 0x1fffff
+	ins &^= _ADR_page | 3<<29 | 0x7ffff<<5
+	return ins | (imm&3)<<29 | (imm>>2)<<5
+}
+
 // From generates an instruction with a source operand only
 func (self *BaseAssembler) From(op string, src obj.Addr) *obj.Prog {
 	p := self.pb.New()
@@ -238,7 +291,10 @@ func (self *BaseAssembler) Three(op string, dst, src1, src2 obj.Addr) *obj.Prog
 	return p
 }
 
-// Emit generates a generic instruction with custom operands
+// Emit generates a generic instruction with custom operands. Moves take the
+// source first, like the Go assembler, while the comparisons and the three
+// operand instructions take them in the ARM order: CMP Rn, Rm sets the flags
+// on Rn - Rm, and ADD Rd, Rn, Rm adds Rm to Rn into Rd.
 func (self *BaseAssembler) Emit(op string, args ...obj.Addr) *obj.Prog {
 	p := self.pb.New()
 	p.As = As(op)
@@ -249,12 +305,17 @@ func (self *BaseAssembler) Emit(op string, args ...obj.Addr) *obj.Prog {
 	case 1:
 		p.From = args[0]
 	case 2:
-		p.From = args[0]
-		p.To = args[1]
+		if isCompare(p.As) {
+			p.From = args[1]
+			p.Reg = args[0].Reg
+		} else {
+			p.From = args[0]
+			p.To = args[1]
+		}
 	case 3:
-		p.From = args[0]
+		p.From = args[2]
 		p.Reg = args[1].Reg
-		p.To = args[2]
+		p.To = args[0]
 	default:
 		panic("too many operands for instruction: " + op)
 	}
@@ -265,42 +326,65 @@ func (self *BaseAssembler) Emit(op string, args ...obj.Addr) *obj.Prog {
 
 // LoadFunction loads a Go function address into a register
 func (self *BaseAssembler) LoadFunction(fn interface{}, dst obj.Addr) {
-	self.LoadImm(uintptr(loader.FuncAddr(fn)), dst)
+	self.LoadImm(uintptr(rt.FuncAddr(fn)), dst)
 }
 
-// LoadImm loads an immediate value into a register
+// LoadImm loads an immediate value into a register, 16 bits at a time. The
+// first halfword is set with MOVZ, which clears the others, or with MOVN, which
+// sets them, whichever leaves fewer halfwords for MOVK to fill in, so that any
+// 64-bit value takes at most 4 instructions.
 func (self *BaseAssembler) LoadImm(imm uintptr, dst obj.Addr) {
-	if imm == 0 {
+	var op string
+	var fill uint64
+	v := uint64(imm)
+
+	if v == 0 {
 		// Use MOV ZR, dst for zero
 		self.Two("MOVD", dst, ZR)
 		return
+	} else if v == ^uint64(0) {
+		// Use MOVN $0, dst for all ones
+		self.Two("MOVN", dst, Imm(0))
+		return
 	}
 
-	// For small immediates, try to use MOVN/MOVZ/MOVK combination
-	if imm <= 0xFFFF {
-		self.Two("MOVW", dst, Imm(int64(imm)))
-	} else if imm <= 0xFFFFFFFF {
-		// Use MOVZ + MOVK for 32-bit values
-		self.Two("MOVW", dst, Imm(int64(imm&0xFFFF)))
-		self.Three("MOVK", dst, Imm(int64((imm>>16)&0xFFFF)), Imm(16))
+	// pick the instruction needing the fewest halfwords to be patched
+	zeros, ones := 0, 0
+	for s := uint(0); s < 64; s += 16 {
+		switch v >> s & 0xffff {
+		case 0:
+			zeros++
+		case 0xffff:
+			ones++
+		}
+	}
+	if ones > zeros {
+		op, fill = "MOVN", 0xffff
 	} else {
-		// Use MOVZ + 2x MOVK for 64-bit values
-		self.Two("MOVW", dst, Imm(int64(imm&0xFFFF)))
-		self.Three("MOVK", dst, Imm(int64((imm>>16)&0xFFFF)), Imm(16))
-		self.Three("MOVK", dst, Imm(int64((imm>>32)&0xFFFF)), Imm(32))
-		self.Three("MOVK", dst, Imm(int64((imm>>48)&0xFFFF)), Imm(48))
+		op, fill = "MOVZ", 0
+	}
+
+	// the operands are the halfwords shifted into place, MOVN takes them inverted
+	for s := uint(0); s < 64; s += 16 {
+		h := v >> s & 0xffff
+		if h == fill {
+			continue
+		}
+		if op == "MOVN" {
+			self.Two(op, dst, Imm(int64((h^0xffff)<<s)))
+		} else {
+			self.Two(op, dst, Imm(int64(h<<s)))
+		}
+		op = "MOVK"
 	}
 }
 
-// LoadGlobal loads a global variable address into a register
-func (self *BaseAssembler) LoadGlobal(name string, dst obj.Addr) {
-	self.From("MOVD", obj.Addr{
-		Type:   obj.TYPE_MEM,
-		Name:   name,
-		Sym:    obj.Linksym(name),
-		Offset: 0,
-	})
-	self.Two("MOVD", dst, R0)
+// LoadGlobal loads the address of a global variable into dst. The code is
+// copied into memory allocated at runtime, with no linker to resolve symbol
+// relocations for ADRP and ADD, so the address is materialized as a constant
+// by a MOVZ/MOVN and MOVK sequence, which leaves every other register intact.
+func (self *BaseAssembler) LoadGlobal(p unsafe.Pointer, dst obj.Addr) {
+	self.Two("MOVD", dst, ImmPtr(p))
 }
 
 // Call generates a function call instruction
@@ -360,17 +444,27 @@ func (self *BaseAssembler) BrLs(target string) {
 
 // Cmp generates a comparison instruction
 func (self *BaseAssembler) Cmp(reg1, reg2 obj.Addr) {
-	self.Three("CMP", reg1, reg2, obj.Addr{})
+	self.Emit("CMP", reg1, reg2)
 }
 
 // CmpImm generates a comparison with immediate
 func (self *BaseAssembler) CmpImm(reg obj.Addr, imm int64) {
-	self.Three("CMP", reg, Imm(imm), obj.Addr{})
+	self.Emit("CMP", reg, Imm(imm))
 }
 
 // Test generates a test instruction (bitwise AND)
 func (self *BaseAssembler) Test(reg1, reg2 obj.Addr) {
-	self.Three("TST", reg1, reg2, obj.Addr{})
+	self.Emit("TST", reg1, reg2)
+}
+
+// isCompare tells if op only sets the flags from its two operands
+func isCompare(op obj.As) bool {
+	switch op {
+	case arm64.ACMP, arm64.ACMPW, arm64.ACMN, arm64.ACMNW, arm64.ATST, arm64.ATSTW, arm64.AFCMPS, arm64.AFCMPD:
+		return true
+	default:
+		return false
+	}
 }
 
 // Size returns the size of the generated code
@@ -386,61 +480,255 @@ var arm64JitLoader = loader.Loader{
 	},
 }
 
-// Load compiles and loads the generated code
-func (self *BaseAssembler) Load(name string, framesize int, argsize int, argptrs, localptrs []int64) loader.Function {
-	return self.o.Do(func() loader.Function {
-		// Execute the compilation
+// Load compiles and loads the generated code. The PC->SP delta table is
+// derived from the SP adjustments of the instructions, so the runtime can
+// unwind through the frame; frameSize is only used for code that never
+// adjusts SP in a recognizable way. The function is named with the loader
+// prefix, since tracebacks hide functions without a package path.
+func (self *BaseAssembler) Load(name string, frameSize int, argSize int, argStackmap []bool, localStackmap []bool) loader.Function {
+	self.o.Do(func() {
+		if self.pb == nil {
+			self.init()
+		}
 		self.Execute()
+		if debugValidate {
+			if err := self.Validate(); err != nil {
+				panic("jit: malformed " + name + ": " + err.Error())
+			}
+		}
+		self.assemble()
+	})
+	recordCode(name, len(self.c))
 
-		// Convert []int64 to []bool for stackmap
-		argStackmap := make([]bool, len(argptrs))
-		localStackmap := make([]bool, len(localptrs))
+	size := uint32(len(self.c))
+	pcsp := self.pcsp()
+	if pcsp == nil {
+		pcsp = loader.Pcdata{{PC: size, Val: int32(frameSize)}}
+	}
 
-		// Load the function using the ARM64 JIT loader
-		return arm64JitLoader.LoadOne(self.c, name, framesize, argsize, argStackmap, localStackmap)
-	})
+	fn := loader.Func{
+		Name:     arm64JitLoader.Name + name,
+		TextSize: size,
+		ArgsSize: int32(argSize),
+		Pcsp:     &pcsp,
+		// NOTICE: all the code refers to the first line of the first file
+		Pcfile:            &loader.Pcdata{{PC: size, Val: 0}},
+		Pcline:            &loader.Pcdata{{PC: size, Val: 1}},
+		PcUnsafePoint:     &loader.Pcdata{{PC: size, Val: loader.PCDATA_UnsafePointSafe}},
+		PcStackMapIndex:   &loader.Pcdata{{PC: size, Val: 0}},
+		ArgsPointerMaps:   newStackMap(argStackmap),
+		LocalsPointerMaps: newStackMap(localStackmap),
+	}
+	if arm64JitLoader.NoPreempt {
+		fn.PcUnsafePoint = &loader.Pcdata{{PC: size, Val: loader.PCDATA_UnsafePointUnsafe}}
+	}
+	out := loader.Load(self.c, []loader.Func{fn}, arm64JitLoader.Name+name, []string{arm64JitLoader.File})
+	return out[0]
+}
+
+func (self *BaseAssembler) init() {
+	self.pb = newBackend("arm64")
+	self.xrefs = make(map[string][]*obj.Prog)
+	self.labels = make(map[string]*obj.Prog)
+	self.pendings = make(map[string][]*obj.Prog)
+
+	/* the assembler skips the first instruction, which stands for TEXT */
+	p := self.pb.New()
+	p.As = obj.ATEXT
+	self.pb.Append(p)
+}
+
+// assemble encodes the instruction stream and resolves PC relative references
+func (self *BaseAssembler) assemble() {
+	self.peephole()
+	self.c = self.pb.Assemble()
+	self.resolve()
 }
 
-// Assemble assembles the instruction stream into executable code
-func (self *BaseAssembler) assemble(framesize int, argsize int, argptrs, localptrs []int64) []byte {
-	var sym obj.LSym
-	var fnv obj.FuncInfo
-	sym.Func = &fnv
-	fnv.Text = self.pb.Head
-	fnv.Pcsp = int32(framesize)
-	fnv.Pcfile = int32(framesize)
-	fnv.Pcline = int32(framesize)
-	fnv.Pcdata = []obj.Pcdata{
-		{PC: 0},
-		{PC: 0},
-	}
-
-	// Set up local variable information
-	fnv.Autom = make([]obj.Auto, len(argptrs)+len(localptrs))
-	for i, v := range argptrs {
-		fnv.Autom[i] = obj.Auto{
-			Asym:    obj.Linksym(fmt.Sprintf("arg_%d", i)),
-			Aoffset: int32(v),
-			Name:    obj.NAME_PARAM,
+// peephole unlinks the instructions which do nothing from the stream, these
+// are left by the helpers moving their operands into the registers they
+// expect, when the operands are already there. The first instruction stands
+// for the TEXT directive and is always kept, and so are the jump targets.
+func (self *BaseAssembler) peephole() {
+	targets := make(map[*obj.Prog]bool)
+	for p := self.pb.Head; p != nil; p = p.Link {
+		if q, ok := p.To.Val.(*obj.Prog); ok && p.To.Type == obj.TYPE_BRANCH {
+			targets[q] = true
 		}
 	}
-	for i, v := range localptrs {
-		fnv.Autom[len(argptrs)+i] = obj.Auto{
-			Aoffset: int32(v),
-			Name:    obj.NAME_AUTO,
+	for p := self.pb.Head; p != nil && p.Link != nil; {
+		if q := p.Link; isNoop(q) && !targets[q] {
+			p.Link = q.Link
+			if self.pb.Tail == q {
+				self.pb.Tail = p
+			}
+		} else {
+			p = q
 		}
 	}
+}
 
-	// Assemble the code
-	self.pb.Arch.Assemble(self.pb.Ctxt, &sym, self.New)
+// isNoop tells if p leaves the registers and flags unchanged, which is the
+// case for the moves of a register to itself, and the additions or the
+// subtractions of zero in place. The 32-bit forms clear the upper halves,
+// so they are not.
+func isNoop(p *obj.Prog) bool {
+	if p.Spadj != 0 || p.To.Type != obj.TYPE_REG {
+		return false
+	}
+	switch p.As {
+	case arm64.AMOVD:
+		return p.From.Type == obj.TYPE_REG && p.From.Reg == p.To.Reg
+	case arm64.AADD, arm64.ASUB:
+		return p.From.Type == obj.TYPE_CONST && p.From.Offset == 0 && (p.Reg == 0 || p.Reg == p.To.Reg)
+	default:
+		return false
+	}
+}
 
-	// Extract the assembled bytes
-	self.c = sym.P
+// Validate checks the instruction stream for the malformed instructions
+// golang-asm would either encode silently or reject with a vague diagnostic:
+// the jumps to undefined labels, the conditional instructions with a bad
+// condition code and the instructions missing an operand. The error gives
+// the index of the first bad instruction in the stream.
+func (self *BaseAssembler) Validate() error {
+	undefined := make(map[*obj.Prog]string)
+	for to, v := range self.pendings {
+		for _, p := range v {
+			undefined[p] = to
+		}
+	}
+	for i, p := 0, self.pb.Head; p != nil; i, p = i+1, p.Link {
+		if to, ok := undefined[p]; ok {
+			return fmt.Errorf("instruction %d (%v): jump to undefined label %q", i, p.As, to)
+		}
+		if err := validateProg(p); err != nil {
+			return fmt.Errorf("instruction %d (%v): %w", i, p.As, err)
+		}
+	}
+	return nil
+}
 
-	// Resolve symbol references
-	self.resolve()
+func validateProg(p *obj.Prog) error {
+	switch p.As {
+	case arm64.ABEQ, arm64.ABNE, arm64.ABCS, arm64.ABHS, arm64.ABCC, arm64.ABLO, arm64.ABMI, arm64.ABPL,
+		arm64.ABVS, arm64.ABVC, arm64.ABHI, arm64.ABLS, arm64.ABGE, arm64.ABLT, arm64.ABGT, arm64.ABLE:
+		if p.To.Type != obj.TYPE_BRANCH {
+			return fmt.Errorf("conditional branch without a label")
+		}
+	case arm64.ACBZ, arm64.ACBZW, arm64.ACBNZ, arm64.ACBNZW:
+		if p.From.Type != obj.TYPE_REG {
+			return fmt.Errorf("compare and branch without a register")
+		}
+		if p.To.Type != obj.TYPE_BRANCH {
+			return fmt.Errorf("conditional branch without a label")
+		}
+	case arm64.ACSEL, arm64.ACSELW, arm64.ACSINC, arm64.ACSINCW, arm64.ACSINV, arm64.ACSINVW,
+		arm64.ACSNEG, arm64.ACSNEGW, arm64.ACSET, arm64.ACSETW, arm64.ACSETM, arm64.ACSETMW,
+		arm64.ACINC, arm64.ACINCW, arm64.ACINV, arm64.ACINVW, arm64.ACNEG, arm64.ACNEGW,
+		arm64.ACCMP, arm64.ACCMPW, arm64.ACCMN, arm64.ACCMNW:
+		if p.From.Type != obj.TYPE_REG || p.From.Reg < arm64.COND_EQ || p.From.Reg > arm64.COND_NV {
+			return fmt.Errorf("bad condition code %s", obj.Dconv(p, &p.From))
+		}
+	case arm64.AADD, arm64.AADDW, arm64.AADDS, arm64.AADDSW, arm64.ASUB, arm64.ASUBW, arm64.ASUBS, arm64.ASUBSW,
+		arm64.AAND, arm64.AANDW, arm64.AANDS, arm64.AANDSW, arm64.AORR, arm64.AORRW, arm64.AEOR, arm64.AEORW,
+		arm64.ABIC, arm64.ABICW, arm64.ALSL, arm64.ALSLW, arm64.ALSR, arm64.ALSRW, arm64.AASR, arm64.AASRW,
+		arm64.AMUL, arm64.AMULW, arm64.AUDIV, arm64.AUDIVW, arm64.ASDIV, arm64.ASDIVW,
+		arm64.ANEG, arm64.ANEGW, arm64.AMVN, arm64.AMVNW:
+		if p.From.Type == obj.TYPE_NONE {
+			return fmt.Errorf("missing source operand")
+		}
+		if p.To.Type != obj.TYPE_REG {
+			return fmt.Errorf("destination %s is not a register", obj.Dconv(p, &p.To))
+		}
+	case arm64.ACMP, arm64.ACMPW, arm64.ACMN, arm64.ACMNW, arm64.ATST, arm64.ATSTW, arm64.AFCMPS, arm64.AFCMPD:
+		if p.From.Type == obj.TYPE_NONE || p.Reg == 0 {
+			return fmt.Errorf("comparison requires 2 operands")
+		}
+	case arm64.AMOVD, arm64.AMOVW, arm64.AMOVWU, arm64.AMOVH, arm64.AMOVHU, arm64.AMOVB, arm64.AMOVBU,
+		arm64.AFMOVD, arm64.AFMOVS:
+		if p.From.Type == obj.TYPE_NONE || p.To.Type == obj.TYPE_NONE {
+			return fmt.Errorf("move requires 2 operands")
+		}
+		if p.From.Type == obj.TYPE_MEM && p.To.Type == obj.TYPE_MEM {
+			return fmt.Errorf("memory to memory move")
+		}
+	}
+	return nil
+}
+
+// pcsp builds the PC->SP delta table of the assembled code. Like pctospadj
+// of the Go assembler, the adjustment made by an instruction takes effect
+// right after it. It returns nil if no instruction adjusts SP.
+func (self *BaseAssembler) pcsp() loader.Pcdata {
+	var sp int32
+	var tab loader.Pcdata
+	size := uint32(len(self.c))
+
+	for p := self.pb.Head; p != nil; p = p.Link {
+		if adj := spadj(p); adj != 0 {
+			end := size
+			if p.Link != nil {
+				end = uint32(p.Link.Pc)
+			}
+			tab = append(tab, loader.Pcvalue{PC: end, Val: sp})
+			sp += adj
+		}
+	}
+
+	/* the remaining code runs with the last adjustment */
+	if tab != nil && tab[len(tab)-1].PC < size {
+		tab = append(tab, loader.Pcvalue{PC: size, Val: sp})
+	}
+	return tab
+}
 
-	return self.c
+// spadj returns how many bytes p grows the stack by. It is either set
+// explicitly, like for RET which restores the frame for the code after it,
+// or derived from the instruction the same way the Go assembler does.
+func spadj(p *obj.Prog) int32 {
+	if p.Spadj != 0 {
+		return p.Spadj
+	}
+	switch {
+	case p.Scond == arm64.C_XPRE && p.To.Type == obj.TYPE_MEM && p.To.Reg == arm64.REGSP:
+		return int32(-p.To.Offset)
+	case p.Scond == arm64.C_XPOST && p.From.Type == obj.TYPE_MEM && p.From.Reg == arm64.REGSP:
+		return int32(-p.From.Offset)
+	case p.From.Type != obj.TYPE_CONST || p.To.Type != obj.TYPE_REG || p.To.Reg != arm64.REGSP:
+		return 0
+	case p.Reg != 0 && p.Reg != arm64.REGSP:
+		return 0
+	case p.As == arm64.AADD:
+		return int32(-p.From.Offset)
+	case p.As == arm64.ASUB:
+		return int32(p.From.Offset)
+	default:
+		return 0
+	}
+}
+
+// stackMap is a pointer bitmap in the binary layout of runtime.stackmap,
+// holding a single bitmap
+type stackMap []bool
+
+func newStackMap(ptrs []bool) encoding.BinaryMarshaler {
+	if ptrs == nil {
+		return nil
+	}
+	return stackMap(ptrs)
+}
+
+func (self stackMap) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8+(len(self)+7)/8)
+	binary.LittleEndian.PutUint32(buf[0:], 1)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(self)))
+	for i, ptr := range self {
+		if ptr {
+			buf[8+i/8] |= 1 << (i % 8)
+		}
+	}
+	return buf, nil
 }
 
 // New creates a new program for the assembler
@@ -468,13 +756,8 @@ type ARM64Assembler struct {
 // NewARM64Assembler creates a new ARM64 assembler
 func NewARM64Assembler() *ARM64Assembler {
 	asm := &ARM64Assembler{}
-	asm.BaseAssembler.Init(func() {
-		// Initialize backend
-		asm.pb = newBackend("arm64")
-		asm.xrefs = make(map[string][]*obj.Prog)
-		asm.labels = make(map[string]*obj.Prog)
-		asm.pendings = make(map[string][]*obj.Prog)
-	})
+	asm.BaseAssembler.init()
+	asm.BaseAssembler.Init(func() {})
 	return asm
 }
 
@@ -577,74 +860,77 @@ func (self *ARM64Assembler) STRB(dst, src obj.Addr) {
 
 // LDP loads pair of registers from memory
 func (self *ARM64Assembler) LDP(dst1, dst2, src obj.Addr) {
-	self.Emit("LDP", dst1, dst2, src)
+	self.Two("LDP", RegPair(dst1, dst2), src)
 }
 
 // STP stores pair of registers to memory
 func (self *ARM64Assembler) STP(dst, src1, src2 obj.Addr) {
-	self.Emit("STP", src1, src2, dst)
+	self.Two("STP", dst, RegPair(src1, src2))
+}
+
+// LDPPost loads pair of registers from memory, then adds the offset of src
+// to its base register (LDP.P)
+func (self *ARM64Assembler) LDPPost(dst1, dst2, src obj.Addr) {
+	self.Two("LDP", RegPair(dst1, dst2), src).Scond = arm64.C_XPOST
+}
+
+// STPPre adds the offset of dst to its base register, then stores pair of
+// registers to the new address (STP.W)
+func (self *ARM64Assembler) STPPre(dst, src1, src2 obj.Addr) {
+	self.Two("STP", dst, RegPair(src1, src2)).Scond = arm64.C_XPRE
 }
 
 // Stack manipulation helpers
 
 // SUBSP subtracts from stack pointer (allocates stack space)
 func (self *ARM64Assembler) SUBSP(size int64) {
-	self.Three("SUB", SP, SP, Imm(size))
+	self.Three("SUB", RSP, Imm(size), RSP)
 }
 
 // ADDSP adds to stack pointer (deallocates stack space)
 func (self *ARM64Assembler) ADDSP(size int64) {
-	self.Three("ADD", SP, SP, Imm(size))
+	self.Three("ADD", RSP, Imm(size), RSP)
 }
 
-// Prologue generates function prologue
+// Prologue generates function prologue in the frame layout of the Go
+// toolchain. LR is saved at 0(RSP), where the runtime looks for the return
+// address when unwinding, and FP is saved right below the frame and points
+// at it, which links the frame into the frame pointer chain. The locals
+// start at 8(RSP).
 func (self *ARM64Assembler) Prologue(framesize int64) {
-	// Store FP and LR
-	self.STP(Ptr(SP, -16), FP, LR)
-
-	// Set up new frame pointer
-	self.MOV(FP, SP)
-
-	// Allocate stack space (aligned to 16 bytes)
-	alignedSize := AlignStack(framesize)
-	if alignedSize > 0 {
-		self.SUBSP(alignedSize)
-	}
+	self.SUBSP(FrameSize(framesize))
+	self.STP(Ptr(RSP, -8), FP, LR)
+	self.Three("SUB", FP, Imm(8), RSP)
 }
 
-// Epilogue generates function epilogue
+// Epilogue generates function epilogue, undoing Prologue
 func (self *ARM64Assembler) Epilogue(framesize int64) {
-	// Deallocate stack space
-	alignedSize := AlignStack(framesize)
-	if alignedSize > 0 {
-		self.ADDSP(alignedSize)
-	}
-
-	// Restore FP and LR
-	self.LDP(FP, LR, Ptr(SP, 16))
+	size := FrameSize(framesize)
+	self.LDP(FP, LR, Ptr(RSP, -8))
+	self.ADDSP(size)
 
-	// Return
-	self.Ret()
+	// the code after RET still runs with the frame
+	self.To("RET", LR).Spadj = int32(size)
 }
 
-// SaveCalleeSaved saves all callee-saved registers
+// SaveCalleeSaved pushes all callee-saved registers onto the stack
 func (self *ARM64Assembler) SaveCalleeSaved() {
 	// Save callee-saved registers in pairs
-	self.STP(Ptr(SP, -16), R19, R20)
-	self.STP(Ptr(SP, -16), R21, R22)
-	self.STP(Ptr(SP, -16), R23, R24)
-	self.STP(Ptr(SP, -16), R25, R26)
-	self.STP(Ptr(SP, -16), R27, R28)
+	self.STPPre(Ptr(RSP, -16), R19, R20)
+	self.STPPre(Ptr(RSP, -16), R21, R22)
+	self.STPPre(Ptr(RSP, -16), R23, R24)
+	self.STPPre(Ptr(RSP, -16), R25, R26)
+	self.STPPre(Ptr(RSP, -16), R27, R28)
 }
 
-// RestoreCalleeSaved restores all callee-saved registers
+// RestoreCalleeSaved pops the registers pushed by SaveCalleeSaved
 func (self *ARM64Assembler) RestoreCalleeSaved() {
 	// Restore callee-saved registers in reverse order
-	self.LDP(R27, R28, Ptr(SP, 16))
-	self.LDP(R25, R26, Ptr(SP, 16))
-	self.LDP(R23, R24, Ptr(SP, 16))
-	self.LDP(R21, R22, Ptr(SP, 16))
-	self.LDP(R19, R20, Ptr(SP, 16))
+	self.LDPPost(R27, R28, Ptr(RSP, 16))
+	self.LDPPost(R25, R26, Ptr(RSP, 16))
+	self.LDPPost(R23, R24, Ptr(RSP, 16))
+	self.LDPPost(R21, R22, Ptr(RSP, 16))
+	self.LDPPost(R19, R20, Ptr(RSP, 16))
 }
 
 // Function call helpers
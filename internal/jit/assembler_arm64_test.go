@@ -20,11 +20,17 @@
 package jit
 
 import (
+	"encoding/binary"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 	"unsafe"
 
+	"github.com/bytedance/sonic/loader"
 	"github.com/twitchyliquid64/golang-asm/obj"
 	"github.com/twitchyliquid64/golang-asm/obj/arm64"
+	"golang.org/x/arch/arm64/arm64asm"
 )
 
 // TestARM64Assembler tests the ARM64 assembler functionality
@@ -135,10 +141,136 @@ func TestARM64AssemblerSjmp(t *testing.T) {
 	}
 }
 
+// newLinkedAssembler returns an assembler with its backend ready to emit
+func newLinkedAssembler() *BaseAssembler {
+	return &BaseAssembler{
+		pb:       newBackend("arm64"),
+		xrefs:    make(map[string][]*obj.Prog),
+		labels:   make(map[string]*obj.Prog),
+		pendings: make(map[string][]*obj.Prog),
+	}
+}
+
+// assembleAt assembles the program, resolves the references and disassembles
+// the instruction at pc
+func assembleAt(t *testing.T, a *BaseAssembler, pc int64) arm64asm.Inst {
+	a.c = a.pb.Assemble()
+	a.resolve()
+	ins, err := arm64asm.Decode(a.c[pc:])
+	if err != nil {
+		t.Fatalf("Failed to disassemble at %d: %v", pc, err)
+	}
+	return ins
+}
+
+func TestARM64AssemblerSref(t *testing.T) {
+	a := newLinkedAssembler()
+	a.Link("_back")
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+	a.Byte(0x10, 0x00, 0x00, 0x10) // ADR  X16, _fwd
+	a.Sref("_fwd", 4)
+	a.Byte(0x11, 0x00, 0x00, 0x90) // ADRP X17, _back
+	a.Sref("_back", 4)
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+	a.Link("_fwd")
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+
+	/* the forward reference */
+	ins := assembleAt(t, a, 4)
+	if ins.Op != arm64asm.ADR || ins.Args[0] != arm64asm.X16 {
+		t.Fatalf("Expected ADR X16, got %v", ins)
+	}
+	if off := int64(ins.Args[1].(arm64asm.PCRel)); 4+off != a.labels["_fwd"].Pc {
+		t.Errorf("Expected ADR to resolve to %d, got %d", a.labels["_fwd"].Pc, 4+off)
+	}
+
+	/* the backward reference, ADRP is rewritten into ADR */
+	ins, _ = arm64asm.Decode(a.c[8:])
+	if ins.Op != arm64asm.ADR || ins.Args[0] != arm64asm.X17 {
+		t.Fatalf("Expected ADR X17, got %v", ins)
+	}
+	if off := int64(ins.Args[1].(arm64asm.PCRel)); 8+off != a.labels["_back"].Pc {
+		t.Errorf("Expected ADR to resolve to %d, got %d", a.labels["_back"].Pc, 8+off)
+	}
+
+	/* no placeholders are emitted after the address instructions */
+	if len(a.c) < 20 || a.labels["_fwd"].Pc != 16 {
+		t.Errorf("Unexpected layout, _fwd at %d", a.labels["_fwd"].Pc)
+	}
+}
+
+func TestARM64AssemblerSrefTable(t *testing.T) {
+	a := newLinkedAssembler()
+	a.Link("_back")
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+	a.Link("_table")
+	a.Sref("_back", 0)
+	a.Sref("_fwd", -4)
+	a.Link("_fwd")
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+	a.c = a.pb.Assemble()
+	a.resolve()
+
+	/* entries hold the offsets of the labels from the table */
+	tab := a.labels["_table"].Pc
+	if v := int32(binary.LittleEndian.Uint32(a.c[tab:])); int64(v) != a.labels["_back"].Pc-tab {
+		t.Errorf("Unexpected backward entry: %d", v)
+	}
+	if v := int32(binary.LittleEndian.Uint32(a.c[tab+4:])); int64(v) != a.labels["_fwd"].Pc-tab {
+		t.Errorf("Unexpected forward entry: %d", v)
+	}
+}
+
+func TestARM64AssemblerSrefOutOfRange(t *testing.T) {
+	a := newLinkedAssembler()
+	a.Byte(0x10, 0x00, 0x00, 0x10) // ADR X16, _far
+	a.Sref("_far", 4)
+	for i := 0; i < (1<<20)/4; i++ {
+		a.Byte(0x1f, 0x20, 0x03, 0xd5)
+	}
+	a.Link("_far")
+	a.c = a.pb.Assemble()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for a reference beyond ±1MB")
+		}
+	}()
+	a.resolve()
+}
+
+func TestARM64AssemblerXjmpXref(t *testing.T) {
+	a := newLinkedAssembler()
+	a.Mark(0)
+	a.Byte(0x1f, 0x20, 0x03, 0xd5) // NOP
+	a.Xjmp("B", 1)
+	a.Mark(1)
+	a.Link("_table")
+	a.Xref(0, 0)
+	a.Xref(1, -4)
+
+	/* the jump lands on the marked instruction */
+	ins := assembleAt(t, a, 4)
+	if ins.Op != arm64asm.B {
+		t.Fatalf("Expected B, got %v", ins)
+	}
+	if off := int64(ins.Args[0].(arm64asm.PCRel)); 4+off != a.labels[_LB_jump_pc+"1"].Pc {
+		t.Errorf("Expected B to resolve to %d, got %d", a.labels[_LB_jump_pc+"1"].Pc, 4+off)
+	}
+
+	/* the table entries hold the offsets of the marks from the table */
+	tab := a.labels["_table"].Pc
+	if v := int32(binary.LittleEndian.Uint32(a.c[tab:])); int64(v) != -tab {
+		t.Errorf("Unexpected entry for mark 0: %d", v)
+	}
+	if v := int32(binary.LittleEndian.Uint32(a.c[tab+4:])); v != 0 {
+		t.Errorf("Unexpected entry for mark 1: %d", v)
+	}
+}
+
 func TestARM64AssemblerFrom(t *testing.T) {
 	assembler := NewARM64Assembler()
 
-	dst := R0
 	src := Imm(42)
 
 	p := assembler.From("MOVD", src)
@@ -231,6 +363,15 @@ func TestARM64AssemblerEmit(t *testing.T) {
 	if p3.As != As("ADD") {
 		t.Errorf("Expected ADD instruction, got %v", p3.As)
 	}
+	if p3.To.Reg != R0.Reg || p3.Reg != R1.Reg || p3.From.Reg != R2.Reg {
+		t.Errorf("Expected ADD R2, R1, R0, got %v", p3)
+	}
+
+	// Test a comparison, which reads like the ARM syntax
+	p4 := assembler.Emit("CMP", R0, Imm(1))
+	if p4.Reg != R0.Reg || p4.From.Offset != 1 || p4.To.Type != obj.TYPE_NONE {
+		t.Errorf("Expected CMP $1, R0, got %v", p4)
+	}
 }
 
 func TestARM64AssemblerLoadImm(t *testing.T) {
@@ -250,6 +391,60 @@ func TestARM64AssemblerLoadImm(t *testing.T) {
 	}
 }
 
+// disassemble returns the instructions of code, up to the zero padding
+func disassemble(t *testing.T, code []byte) []string {
+	var ret []string
+	for pc := 0; pc+4 <= len(code) && binary.LittleEndian.Uint32(code[pc:]) != 0; pc += 4 {
+		ins, err := arm64asm.Decode(code[pc:])
+		if err != nil {
+			t.Fatalf("Failed to disassemble at %d: %v", pc, err)
+		}
+		ret = append(ret, ins.String())
+	}
+	return ret
+}
+
+func TestARM64AssemblerLoadImmTable(t *testing.T) {
+	tests := []struct {
+		imm uintptr
+		ins []string
+	}{
+		{0, []string{"MOV X1, #0x0"}},
+		{0xffff, []string{"MOV X1, #0xffff"}},
+		{0x1_0000, []string{"MOV X1, #0x10000"}},
+		{0xffff_ffff, []string{"MOV X1, #0xffff", "MOVK X1, #0xffff, LSL #16"}},
+		{0x1234_0000_0000_5678, []string{"MOV X1, #0x5678", "MOVK X1, #0x1234, LSL #48"}},
+		{0x1234_5678_9abc_def0, []string{
+			"MOV X1, #0xdef0",
+			"MOVK X1, #0x9abc, LSL #16",
+			"MOVK X1, #0x5678, LSL #32",
+			"MOVK X1, #0x1234, LSL #48",
+		}},
+		{0xffff_1234_ffff_ffff, []string{"MOV X1, #0xffff1234ffffffff"}},
+		{0xffff_ffff_5678_1234, []string{"MOV X1, #0xffffffffffff1234", "MOVK X1, #0x5678, LSL #16"}},
+		{^uintptr(0), []string{"MOV X1, #0xffffffffffffffff"}},
+	}
+
+	for _, tt := range tests {
+		a := newLinkedAssembler()
+		a.NOP()
+		a.LoadImm(tt.imm, R1)
+		a.c = a.pb.Assemble()
+
+		got := disassemble(t, a.c)
+		if strings.Join(got, "; ") != strings.Join(tt.ins, "; ") {
+			t.Errorf("%#x: expected %q, got %q", tt.imm, tt.ins, got)
+		}
+
+		// replaying the instructions gives back the value
+		if tt.imm != 0 {
+			if v, n := movImmediate(t, a.c, 1); n > 4 || uintptr(v) != tt.imm {
+				t.Errorf("%#x: loaded %#x with %d instructions", tt.imm, v, n)
+			}
+		}
+	}
+}
+
 func TestARM64AssemblerLoadFunction(t *testing.T) {
 	assembler := NewARM64Assembler()
 
@@ -261,6 +456,57 @@ func TestARM64AssemblerLoadFunction(t *testing.T) {
 	}
 }
 
+var loadGlobalTarget = uint64(0x5eed_1234_abcd_0042)
+
+// movImmediate replays the MOVZ, MOVN and MOVK instructions at the start of
+// code which all write to the register rd, up to the zero padding, and returns
+// the resulting value along with the number of instructions
+func movImmediate(t *testing.T, code []byte, rd uint32) (uint64, int) {
+	var v uint64
+	var n int
+	for ; n*4 < len(code); n++ {
+		ins := binary.LittleEndian.Uint32(code[n*4:])
+		if ins == 0 {
+			break
+		}
+		if ins&0x1f800000 != 0x12800000 || ins&0x1f != rd {
+			t.Fatalf("Unexpected instruction %#08x at %d", ins, n*4)
+		}
+		sh := (ins >> 21 & 3) * 16
+		imm := uint64(ins>>5&0xffff) << sh
+		switch ins >> 29 & 3 {
+		case 0:
+			v = ^imm
+		case 2:
+			v = imm
+		case 3:
+			v = v&^(0xffff<<sh) | imm
+		default:
+			t.Fatalf("Unexpected instruction %#08x at %d", ins, n*4)
+		}
+	}
+	return v, n
+}
+
+func TestARM64AssemblerLoadGlobal(t *testing.T) {
+	a := newLinkedAssembler()
+	a.NOP()
+	a.LoadGlobal(unsafe.Pointer(&loadGlobalTarget), R3)
+	a.c = a.pb.Assemble()
+
+	// the register holds the address of the variable, not its value
+	addr, n := movImmediate(t, a.c, 3)
+	if n == 0 || n > 4 {
+		t.Fatalf("Expected 1 to 4 instructions, got %d", n)
+	}
+	if addr != uint64(uintptr(unsafe.Pointer(&loadGlobalTarget))) {
+		t.Fatalf("Expected address %p, got %#x", &loadGlobalTarget, addr)
+	}
+	if v := **(**uint64)(unsafe.Pointer(&addr)); v != loadGlobalTarget {
+		t.Errorf("Expected %#x through the loaded address, got %#x", loadGlobalTarget, v)
+	}
+}
+
 func TestARM64AssemblerBr(t *testing.T) {
 	assembler := NewARM64Assembler()
 
@@ -605,6 +851,252 @@ func TestARM64AssemblerRestoreCalleeSaved(t *testing.T) {
 	}
 }
 
+func TestARM64AssemblerFrameLayout(t *testing.T) {
+	a := NewARM64Assembler()
+	a.NOP()
+	a.Prologue(24)
+	a.SaveCalleeSaved()
+	a.RestoreCalleeSaved()
+	a.Epilogue(24)
+	a.c = a.pb.Assemble()
+
+	// LR is saved at 0(SP) with FP right below it, and every push is popped in reverse
+	expected := []string{
+		"SUB SP, SP, #0x20",
+		"STP X29, X30, [SP,#-8]",
+		"SUB X29, SP, #0x8",
+		"STP X19, X20, [SP,#-16]!",
+		"STP X21, X22, [SP,#-16]!",
+		"STP X23, X24, [SP,#-16]!",
+		"STP X25, X26, [SP,#-16]!",
+		"STP X27, X28, [SP,#-16]!",
+		"LDP X27, X28, [SP],#16",
+		"LDP X25, X26, [SP],#16",
+		"LDP X23, X24, [SP],#16",
+		"LDP X21, X22, [SP],#16",
+		"LDP X19, X20, [SP],#16",
+		"LDP X29, X30, [SP,#-8]",
+		"ADD SP, SP, #0x20",
+		"RET X30",
+	}
+	got := disassemble(t, a.c)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestARM64AssemblerFrameCall(t *testing.T) {
+	a := NewARM64Assembler()
+	a.Init(func() {
+		a.NOP()
+		a.Prologue(32)
+		a.SaveCalleeSaved()
+		a.LoadImm(0x5a5a, R19)
+		a.LoadImm(0xa5a5, R27)
+		a.RestoreCalleeSaved()
+		a.Epilogue(32)
+	})
+	fn := a.Load("frame_call", int(FrameSize(32))+80, 0, nil, nil)
+	call := *(*func())(unsafe.Pointer(&fn))
+
+	// calls keep returning to Go while the goroutine stacks are scanned
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10000; i++ {
+			call()
+		}
+	}()
+	buf := make([]byte, 1<<16)
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			runtime.GC()
+			runtime.Stack(buf, true)
+		}
+	}
+}
+
+func TestARM64AssemblerPcsp(t *testing.T) {
+	a := NewARM64Assembler()
+	a.NOP()
+	a.Prologue(24)
+	a.SaveCalleeSaved()
+	a.RestoreCalleeSaved()
+	a.Epilogue(24)
+	a.LoadImm(1, R1)
+	a.assemble()
+
+	// every push and pop takes effect after its instruction, and the code
+	// after RET runs with the frame of the function body
+	expected := loader.Pcdata{
+		{PC: 4, Val: 0},
+		{PC: 16, Val: 32},
+		{PC: 20, Val: 48},
+		{PC: 24, Val: 64},
+		{PC: 28, Val: 80},
+		{PC: 32, Val: 96},
+		{PC: 36, Val: 112},
+		{PC: 40, Val: 96},
+		{PC: 44, Val: 80},
+		{PC: 48, Val: 64},
+		{PC: 52, Val: 48},
+		{PC: 60, Val: 32},
+		{PC: 64, Val: 0},
+		{PC: uint32(len(a.c)), Val: 32},
+	}
+	if got := a.pcsp(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected pcsp %v, got %v", expected, got)
+	}
+}
+
+func TestARM64AssemblerAlign(t *testing.T) {
+	a := NewARM64Assembler()
+	a.NOP()
+	a.LoadImm(1, R1)
+	a.Align(16)
+	a.Link("_aligned")
+	a.LoadImm(2, R1)
+	a.Align(16)
+	a.c = a.pb.Assemble()
+
+	if pc := a.labels["_aligned"].Pc; pc != 16 {
+		t.Errorf("Expected the label aligned at 16, got %d", pc)
+	}
+	if len(a.c)%16 != 0 {
+		t.Errorf("Expected the code size to be a multiple of 16, got %d", len(a.c))
+	}
+
+	// everything but the two instructions is padding
+	for i := 0; i < len(a.c); i += 4 {
+		if i != 0 && i != 16 && string(a.c[i:i+4]) != string(_NOPS[0][:4]) {
+			t.Errorf("Expected NOP padding at %d, got %x", i, a.c[i:i+4])
+		}
+	}
+}
+
+func TestARM64AssemblerPeephole(t *testing.T) {
+	a := NewARM64Assembler()
+	a.NOP()
+	a.Two("MOVD", R1, R1)
+	a.Two("ADD", R2, Imm(0))
+	a.Three("SUB", R3, Imm(0), R3)
+	a.Link("_target")
+	a.Three("ADD", R4, Imm(0), R3)
+	a.Two("MOVW", R5, R5)
+	a.Two("ADD", R1, Imm(1))
+	a.Two("MOVD", R2, R1)
+	a.Two("MOVD", R6, R6)
+	a.Sjmp("B", "_target")
+	a.assemble()
+
+	// the moves to the same register and the additions of zero are gone,
+	// and so is the label, which is zero-sized once assembled
+	var ops []obj.As
+	for p := a.pb.Head.Link; p != nil; p = p.Link {
+		if p.As != obj.ANOP {
+			ops = append(ops, p.As)
+		}
+	}
+	expected := []obj.As{arm64.AADD, arm64.AMOVW, arm64.AADD, arm64.AMOVD, arm64.AB}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Expected %v, got %v", expected, ops)
+	}
+	if len(a.c) != 4*len(expected) {
+		t.Errorf("Expected %d bytes of code, got %d", 4*len(expected), len(a.c))
+	}
+	if pc := a.labels["_target"].Pc; pc != 0 {
+		t.Errorf("Expected the label at 0, got %d", pc)
+	}
+}
+
+func TestARM64AssemblerValidate(t *testing.T) {
+	eq := obj.Addr{Type: obj.TYPE_REG, Reg: arm64.COND_EQ}
+	tests := []struct {
+		name string
+		emit func(a *ARM64Assembler)
+		err  string
+	}{
+		{"valid", func(a *ARM64Assembler) {
+			a.Emit("CSET", eq, R3)
+			a.Sjmp("BEQ", "_entry")
+		}, ""},
+		{"immediate destination", func(a *ARM64Assembler) {
+			a.Emit("ADD", Imm(4), R1, R2)
+		}, "instruction 3 (ADD): destination $4 is not a register"},
+		{"missing source", func(a *ARM64Assembler) {
+			a.To("SUB", R1)
+		}, "instruction 3 (SUB): missing source operand"},
+		{"immediate condition", func(a *ARM64Assembler) {
+			a.Emit("CSET", Imm(COND_EQ), R3)
+		}, "instruction 3 (CSET): bad condition code $0"},
+		{"register condition", func(a *ARM64Assembler) {
+			a.Emit("CSEL", R1, R2, R3)
+		}, "instruction 3 (CSEL): bad condition code R3"},
+		{"branch to register", func(a *ARM64Assembler) {
+			a.From("BNE", R1)
+		}, "instruction 3 (BNE): conditional branch without a label"},
+		{"undefined label", func(a *ARM64Assembler) {
+			a.Sjmp("BNE", "_nowhere")
+		}, `instruction 3 (BNE): jump to undefined label "_nowhere"`},
+		{"single operand comparison", func(a *ARM64Assembler) {
+			a.From("CMP", R1)
+		}, "instruction 3 (CMP): comparison requires 2 operands"},
+		{"memory to memory", func(a *ARM64Assembler) {
+			a.Two("MOVD", Ptr(R1, 0), Ptr(R2, 8))
+		}, "instruction 3 (MOVD): memory to memory move"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewARM64Assembler()
+			a.Link("_entry")
+			a.Two("MOVD", R1, Imm(1))
+			a.Cmp(R1, R2)
+			tt.emit(a)
+			a.Two("MOVD", R0, R1)
+
+			err := a.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			} else if err == nil || err.Error() != tt.err {
+				t.Errorf("Expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+var tracebackStack string
+
+func recordTraceback() {
+	buf := make([]byte, 1<<16)
+	tracebackStack = string(buf[:runtime.Stack(buf, false)])
+}
+
+func TestARM64AssemblerTraceback(t *testing.T) {
+	a := NewARM64Assembler()
+	a.Init(func() {
+		a.NOP()
+		a.Prologue(16)
+		a.LoadFunction(recordTraceback, R8)
+		a.Call(R8)
+		a.Epilogue(16)
+	})
+	fn := a.Load("traceback", int(FrameSize(16)), 0, nil, nil)
+	call := *(*func())(unsafe.Pointer(&fn))
+	call()
+
+	// the stack is unwound through the generated frame back into the test
+	for _, name := range []string{"jit.recordTraceback", "sonic.jit.arm64.traceback", "jit.TestARM64AssemblerTraceback"} {
+		if !strings.Contains(tracebackStack, name) {
+			t.Errorf("Expected %s in the stack:\n%s", name, tracebackStack)
+		}
+	}
+}
+
 func TestARM64AssemblerCallGo(t *testing.T) {
 	assembler := NewARM64Assembler()
 
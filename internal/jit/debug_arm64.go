@@ -0,0 +1,24 @@
+//go:build arm64 && go1.20 && !go1.26 && sonic_debug
+// +build arm64,go1.20,!go1.26,sonic_debug
+
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jit
+
+// debugValidate enables the validation of the instruction streams before they
+// are assembled, a malformed instruction panics with its index and reason.
+const debugValidate = true
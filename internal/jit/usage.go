@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jit
+
+import (
+    `sync`
+)
+
+// The machine code loaded by the JIT is registered into the runtime as a
+// module, so it can never be freed, and the usage below only grows.
+var (
+    usageLock  sync.Mutex
+    usageBytes int64
+    usageMax   int64
+    usageFuncs = map[string]int{}
+    usageCount int
+)
+
+// recordCode accounts for size bytes of machine code loaded for function name.
+func recordCode(name string, size int) {
+    usageLock.Lock()
+    usageBytes += int64(size)
+    if int64(size) > usageMax {
+        usageMax = int64(size)
+    }
+    usageFuncs[name] += size
+    usageCount++
+    usageLock.Unlock()
+}
+
+// MemoryUsage returns the bytes of machine code loaded by the JIT so far, the
+// size of the largest function among them, and the number of functions.
+func MemoryUsage() (allocated int64, max int64, count int) {
+    usageLock.Lock()
+    defer usageLock.Unlock()
+    return usageBytes, usageMax, usageCount
+}
+
+// FunctionSizes returns the bytes of machine code loaded for each function
+// name, the functions loaded several times under the same name are summed up.
+func FunctionSizes() map[string]int {
+    usageLock.Lock()
+    defer usageLock.Unlock()
+    ret := make(map[string]int, len(usageFuncs))
+    for k, v := range usageFuncs {
+        ret[k] = v
+    }
+    return ret
+}
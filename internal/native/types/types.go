@@ -90,6 +90,7 @@ const (
     ERR_FLOAT_INFINITY     ParsingError = 8
     ERR_MISMATCH           ParsingError = 9
     ERR_INVALID_UTF8       ParsingError = 10
+    ERR_KEY_TOO_LONG       ParsingError = 11
 
     // error code used in ast
     ERR_NOT_FOUND          ParsingError = 33
@@ -108,6 +109,7 @@ var _ParsingErrors = []string{
     ERR_FLOAT_INFINITY     : "float number is infinity",
     ERR_MISMATCH           : "mismatched type with value",
     ERR_INVALID_UTF8       : "invalid UTF8",
+    ERR_KEY_TOO_LONG       : "object key too long",
 }
 
 func (self ParsingError) Error() string {
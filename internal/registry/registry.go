@@ -0,0 +1,106 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package registry holds everything registered for a type that the encoder
+// and decoder compilers consult, such as field predicates and setters.
+//
+// The registry is copied on write: each registration publishes a new
+// immutable Snapshot, so a compiler which loads the snapshot once sees a
+// consistent view for the whole compilation, while registering never blocks
+// or races with the compilers.
+package registry
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Kind tells apart the different things registered for the same type.
+type Kind uint8
+
+const (
+	// FieldPredicate is the predicate deciding whether a struct field is encoded.
+	FieldPredicate Kind = iota
+
+	// Setters are the setter methods a struct is decoded with.
+	Setters
+)
+
+type key struct {
+	kind Kind
+	vt   reflect.Type
+	name string
+}
+
+// Snapshot is an immutable view of the registry.
+type Snapshot struct {
+	m map[key]interface{}
+}
+
+var (
+	lock    sync.Mutex
+	current atomic.Value
+)
+
+func init() {
+	current.Store(&Snapshot{})
+}
+
+// Load returns the current snapshot of the registry.
+func Load() *Snapshot {
+	return current.Load().(*Snapshot)
+}
+
+// Get returns the value registered as kind for the type vt and the name, or
+// nil if there is none. The name is empty for registrations of the type itself.
+func (self *Snapshot) Get(kind Kind, vt reflect.Type, name string) interface{} {
+	return self.m[key{kind, vt, name}]
+}
+
+// Len returns the number of registrations in the snapshot.
+func (self *Snapshot) Len() int {
+	return len(self.m)
+}
+
+// Update replaces the value registered as kind for the type vt and the name
+// with the one fn returns for it, returning nil removes the registration.
+// Updates are serialized, so fn sees the result of all the earlier ones.
+func Update(kind Kind, vt reflect.Type, name string, fn func(old interface{}) interface{}) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	/* copy the current registrations */
+	old := Load()
+	m := make(map[key]interface{}, len(old.m)+1)
+	for k, v := range old.m {
+		m[k] = v
+	}
+
+	/* apply the update and publish the new snapshot */
+	k := key{kind, vt, name}
+	if v := fn(m[k]); v != nil {
+		m[k] = v
+	} else {
+		delete(m, k)
+	}
+	current.Store(&Snapshot{m: m})
+}
+
+// Store registers v as kind for the type vt and the name, replacing the old value.
+func Store(kind Kind, vt reflect.Type, name string, v interface{}) {
+	Update(kind, vt, name, func(interface{}) interface{} { return v })
+}
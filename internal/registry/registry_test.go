@@ -0,0 +1,83 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type registryTest struct{}
+
+func TestRegistry_CopyOnWrite(t *testing.T) {
+	vt := reflect.TypeOf(registryTest{})
+	old := Load()
+	Store(FieldPredicate, vt, "a", 1)
+
+	/* older snapshots are never changed */
+	require.Nil(t, old.Get(FieldPredicate, vt, "a"))
+	cur := Load()
+	require.Equal(t, 1, cur.Get(FieldPredicate, vt, "a"))
+	require.Nil(t, cur.Get(Setters, vt, "a"))
+
+	/* updates see the current value, and nil removes it */
+	Update(FieldPredicate, vt, "a", func(v interface{}) interface{} { return v.(int) + 1 })
+	require.Equal(t, 2, Load().Get(FieldPredicate, vt, "a"))
+	require.Equal(t, 1, cur.Get(FieldPredicate, vt, "a"))
+	Store(FieldPredicate, vt, "a", nil)
+	require.Nil(t, Load().Get(FieldPredicate, vt, "a"))
+	require.Equal(t, old.Len(), Load().Len())
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	vt := reflect.TypeOf(registryTest{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		/* registering ... */
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Update(Setters, vt, strconv.Itoa(i), func(v interface{}) interface{} {
+					n, _ := v.(int)
+					return n + 1
+				})
+			}
+		}(i)
+
+		/* ... while reading consistent snapshots */
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				reg := Load()
+				v := reg.Get(Setters, vt, strconv.Itoa(i))
+				require.Equal(t, v, reg.Get(Setters, vt, strconv.Itoa(i)))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	/* no update is lost */
+	for i := 0; i < 8; i++ {
+		require.Equal(t, 100, Load().Get(Setters, vt, strconv.Itoa(i)))
+	}
+}
@@ -38,6 +38,8 @@ type StdField struct {
     omitZero    bool
     isZero      func(reflect.Value) bool
     quoted      bool
+    required    bool
+    defaultVal  string
 }
 
 type StdStructFields struct {
@@ -136,7 +138,9 @@ func typeFields(t reflect.Type) StdStructFields {
 						omitEmpty: opts.Contains("omitempty"),
 						omitZero:  opts.Contains("omitzero"),
 						quoted:    quoted,
+						required:  opts.Contains("required"),
 					}
+					field.defaultVal, _ = opts.Value("default")
 					field.nameBytes = []byte(field.name)
 
 					// Build nameEscHTML and nameNonEsc ahead of time.
@@ -315,6 +319,20 @@ func (o tagOptions) Contains(optionName string) bool {
 	return false
 }
 
+// Value returns the value of an option in the form of "name=value", the value
+// must not contain commas.
+func (o tagOptions) Value(optionName string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var opt string
+		opt, s, _ = strings.Cut(s, ",")
+		if name, val, ok := strings.Cut(opt, "="); ok && name == optionName {
+			return val, true
+		}
+	}
+	return "", false
+}
+
 func isValidTag(s string) bool {
 	if s == "" {
 		return false
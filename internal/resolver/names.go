@@ -0,0 +1,53 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedNames bounds the keys cached by a NameResolver, since the keys come
+// from the input, and can be all different.
+const maxCachedNames = 1024
+
+// NameResolver maps object keys to struct field names at decoding time.
+// Results are cached, since the resolver is consulted for every key, but only
+// for the first maxCachedNames keys.
+type NameResolver struct {
+	fn func(string) string
+	mm sync.Map
+	nb int64
+}
+
+func NewNameResolver(fn func(string) string) *NameResolver {
+	return &NameResolver{fn: fn}
+}
+
+// Resolve returns the field name which the object key s refers to.
+func (self *NameResolver) Resolve(s string) string {
+	if v, ok := self.mm.Load(s); ok {
+		return v.(string)
+	}
+
+	/* the key may refer to the input buffer, so copy it before caching */
+	v := self.fn(s)
+	if atomic.AddInt64(&self.nb, 1) <= maxCachedNames {
+		self.mm.Store(string([]byte(s)), v)
+	}
+	return v
+}
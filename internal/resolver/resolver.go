@@ -31,6 +31,7 @@ const (
     F_omitempty FieldOpts = 1 << iota
     F_stringize
     F_omitzero
+    F_required
 )
 
 const (
@@ -50,6 +51,7 @@ type FieldMeta struct {
     Opts FieldOpts
     Type reflect.Type
     IsZero func(reflect.Value) bool
+    Default string
 }
 
 func (self *FieldMeta) String() string {
@@ -75,6 +77,11 @@ func (self *FieldMeta) String() string {
         opts = append(opts, "omitempty")
     }
 
+    /* check for "required" */
+    if (self.Opts & F_required) != 0 {
+        opts = append(opts, "required")
+    }
+
     /* format the field */
     return fmt.Sprintf(
         "{Field \"%s\" @ %s, opts=%s, type=%s}",
@@ -140,6 +147,14 @@ func resolveFields(vt reflect.Type) []FieldMeta {
         /* handle the "omitzero" */
         handleOmitZero(fv, fm)
 
+        /* check for "required" */
+        if fv.required {
+            fm.Opts |= F_required
+        }
+
+        /* the JSON literal of the "default" option */
+        fm.Default = fv.defaultVal
+
         /* dump the field path */
         for _, i := range fv.index {
             kind := F_offset
@@ -218,6 +233,27 @@ func ResolveStruct(vt reflect.Type) []FieldMeta {
     return fm
 }
 
+// ResolvePair resolves the fields of a pair type, a struct of exactly two
+// direct fields with a string first, that a JSON object entry can be decoded
+// into as the key and the value.
+func ResolvePair(vt reflect.Type) (key FieldMeta, val FieldMeta, ok bool) {
+    if vt.Kind() != reflect.Struct {
+        return
+    }
+
+    /* check the fields */
+    fv := ResolveStruct(vt)
+    if len(fv) != 2 || fv[0].Type.Kind() != reflect.String {
+        return
+    }
+    for _, f := range fv {
+        if len(f.Path) != 1 || f.Path[0].Kind != F_offset {
+            return
+        }
+    }
+    return fv[0], fv[1], true
+}
+
 func handleOmitZero(fv StdField, fm *FieldMeta) {
     if fv.omitZero {
         fm.Opts |= F_omitzero
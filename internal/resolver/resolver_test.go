@@ -18,6 +18,7 @@ package resolver
 
 import (
     `reflect`
+    `strconv`
     `testing`
 )
 
@@ -69,3 +70,31 @@ func TestResolver_ResolveStruct(t *testing.T) {
         println(fv.String())
     }
 }
+
+func TestResolver_NameResolverBound(t *testing.T) {
+    calls := 0
+    rn := NewNameResolver(func(s string) string {
+        calls++
+        return s + "_"
+    })
+
+    /* distinct keys are resolved but only the first ones are kept */
+    for i := 0; i < maxCachedNames * 2; i++ {
+        if k := strconv.Itoa(i); rn.Resolve(k) != k + "_" {
+            t.Fatal(k)
+        }
+    }
+    n := 0
+    rn.mm.Range(func(_, _ interface{}) bool { n++; return true })
+    if n != maxCachedNames {
+        t.Fatal(n)
+    }
+
+    /* cached keys are not resolved again */
+    calls = 0
+    rn.Resolve("0")
+    rn.Resolve(strconv.Itoa(maxCachedNames))
+    if calls != 1 {
+        t.Fatal(calls)
+    }
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/bytedance/sonic/internal/registry"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// SetterMeta describes an exported method which assigns the value of
+// an object key to a struct, usually one that is kept in a private field.
+type SetterMeta struct {
+	Name   string
+	Type   reflect.Type
+	Owner  reflect.Type
+	Method reflect.Method
+}
+
+// Call invokes the setter on the struct pointed by vp with the decoded value v.
+func (self *SetterMeta) Call(vp unsafe.Pointer, v reflect.Value) error {
+	rv := self.Method.Func.Call([]reflect.Value{reflect.NewAt(self.Owner, vp), v})
+	if len(rv) == 0 || rv[0].IsNil() {
+		return nil
+	}
+	return rv[0].Interface().(error)
+}
+
+// RegisterSetter maps the object key to the setter method of *vt. The method
+// must take exactly one argument, and return either nothing or an error.
+func RegisterSetter(vt reflect.Type, key string, method string) error {
+	if vt.Kind() != reflect.Struct {
+		return fmt.Errorf("setter owner %s is not a struct", vt)
+	}
+
+	/* the method is looked up on the pointer type, so both receivers work */
+	fn, ok := reflect.PtrTo(vt).MethodByName(method)
+	if !ok {
+		return fmt.Errorf("method %s not found on *%s", method, vt)
+	}
+
+	/* check for the method signature, the receiver is the first argument */
+	ft := fn.Type
+	if ft.NumIn() != 2 || ft.NumOut() > 1 || (ft.NumOut() == 1 && ft.Out(0) != errorType) {
+		return fmt.Errorf("method %s of *%s is not a setter: %s", method, vt, ft)
+	}
+
+	/* the slice is copied, since older snapshots of the registry keep it */
+	registry.Update(registry.Setters, vt, "", func(v interface{}) interface{} {
+		old, _ := v.([]*SetterMeta)
+		ret := make([]*SetterMeta, 0, len(old) + 1)
+
+		/* registering the same key again replaces the old setter */
+		for _, s := range old {
+			if s.Name != key {
+				ret = append(ret, s)
+			}
+		}
+
+		/* add the new setter */
+		return append(ret, &SetterMeta{
+			Name   : key,
+			Type   : ft.In(1),
+			Owner  : vt,
+			Method : fn,
+		})
+	})
+	return nil
+}
+
+// FindSetters returns all the setters registered for vt in the snapshot of
+// the registry.
+func FindSetters(reg *registry.Snapshot, vt reflect.Type) []*SetterMeta {
+	sv, _ := reg.Get(registry.Setters, vt, "").([]*SetterMeta)
+	return sv
+}
+
+// ResolveStructWithSetters is like ResolveStruct, except that fields whose
+// name is also mapped to a setter are removed, since the setter takes over.
+func ResolveStructWithSetters(reg *registry.Snapshot, vt reflect.Type) ([]FieldMeta, []*SetterMeta) {
+	fv := ResolveStruct(vt)
+	sv := FindSetters(reg, vt)
+	if len(sv) == 0 {
+		return fv, nil
+	}
+
+	/* keys mapped to setters shadow the fields */
+	ret := make([]FieldMeta, 0, len(fv))
+	for _, f := range fv {
+		if !hasSetter(sv, f.Name) {
+			ret = append(ret, f)
+		}
+	}
+	return ret, sv
+}
+
+func hasSetter(sv []*SetterMeta, name string) bool {
+	for _, s := range sv {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -140,11 +140,10 @@ var EmptySlice = *(*GoSlice)(unsafe.Pointer(&emptyBytes))
 func MakeSliceStd(et *GoType, len int, cap int) unsafe.Pointer
 
 func MakeSlice(oldPtr unsafe.Pointer, et *GoType, newLen int) *GoSlice {
-	if newLen == 0 {
-		return &EmptySlice
-	}
-
 	if *(*unsafe.Pointer)(oldPtr) == nil {
+		if newLen == 0 {
+			return &EmptySlice
+		}
 		return &GoSlice{
 			Ptr: MakeSliceStd(et, newLen, newLen),
 			Len: newLen,
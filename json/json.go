@@ -0,0 +1,131 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package json is a drop-in replacement of encoding/json backed by sonic.
+//
+// It exports the same API as encoding/json, so that projects can switch by
+// changing the import path only. Values are encoded and decoded by sonic with
+// sonic.ConfigStd, which is the JIT on amd64 and arm64. encoding/json is only
+// used where sonic has no counterpart, such as Decoder.Token, and to report
+// invalid JSON input before anything is decoded, so that syntax errors are the
+// ones of encoding/json. Other errors are the ones of sonic, which reuses the
+// error types of encoding/json where it has them.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/encoder"
+)
+
+// The types of encoding/json, so that values can be shared with it.
+type (
+	Marshaler             = json.Marshaler
+	Unmarshaler           = json.Unmarshaler
+	RawMessage            = json.RawMessage
+	Number                = json.Number
+	Delim                 = json.Delim
+	Token                 = json.Token
+	SyntaxError           = json.SyntaxError
+	UnmarshalTypeError    = json.UnmarshalTypeError
+	InvalidUnmarshalError = json.InvalidUnmarshalError
+	UnsupportedTypeError  = json.UnsupportedTypeError
+	UnsupportedValueError = json.UnsupportedValueError
+	MarshalerError        = json.MarshalerError
+)
+
+// stdConfig is the sonic config behaving like encoding/json.
+var stdConfig = sonic.Config{
+	EscapeHTML:       true,
+	SortMapKeys:      true,
+	CompactMarshaler: true,
+	CopyString:       true,
+	ValidateString:   true,
+}
+
+var api = stdConfig.Froze()
+
+// Marshal returns the JSON encoding of v, see encoding/json.Marshal.
+func Marshal(v interface{}) ([]byte, error) {
+	return api.Marshal(v)
+}
+
+// MarshalIndent is like Marshal but applies Indent to format the output,
+// see encoding/json.MarshalIndent.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return api.MarshalIndent(v, prefix, indent)
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in the value
+// pointed to by v, see encoding/json.Unmarshal.
+func Unmarshal(data []byte, v interface{}) error {
+	/* encoding/json reports these without touching v */
+	if !api.Valid(data) || !isPointer(v) {
+		return json.Unmarshal(data, v)
+	}
+	return api.Unmarshal(data, v)
+}
+
+// isPointer reports whether v can be decoded into.
+func isPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil()
+}
+
+// Valid reports whether data is a valid JSON encoding.
+func Valid(data []byte) bool {
+	return api.Valid(data)
+}
+
+// Compact appends to dst the JSON-encoded src with insignificant space
+// characters elided, see encoding/json.Compact.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	/* invalid JSON is reported by encoding/json, which leaves dst unchanged */
+	if !api.Valid(src) {
+		return json.Compact(dst, src)
+	}
+	var buf []byte
+	if err := encoder.Compact(&buf, src); err != nil {
+		return err
+	}
+	dst.Write(buf)
+	return nil
+}
+
+// Indent appends to dst an indented form of the JSON-encoded src, see
+// encoding/json.Indent.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	/* invalid JSON is reported by encoding/json, which leaves dst unchanged */
+	if !api.Valid(src) {
+		return json.Indent(dst, src, prefix, indent)
+	}
+	var buf []byte
+	if err := encoder.Indent(&buf, src, prefix, indent); err != nil {
+		return err
+	}
+	dst.Write(buf)
+	return nil
+}
+
+// HTMLEscape appends to dst the JSON-encoded src with <, >, &, U+2028 and
+// U+2029 characters inside string literals changed to \u003c, \u003e, \u0026,
+// \u2028, \u2029, see encoding/json.HTMLEscape.
+func HTMLEscape(dst *bytes.Buffer, src []byte) {
+	json.HTMLEscape(dst, src)
+}
@@ -0,0 +1,526 @@
+/*
+ * Copyright 2010 The Go Authors. All rights reserved.
+ * Modifications Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	sonicjson "github.com/bytedance/sonic/json"
+)
+
+// The tests below are a subset of the ones of encoding/json, run through the
+// drop-in package. Syntax errors of Unmarshal are checked to be the ones of
+// encoding/json, the other errors are only checked to be reported.
+
+type T struct {
+	X string
+	Y int
+	Z int `json:"-"`
+}
+
+type U struct {
+	Alphabet string `json:"alpha"`
+}
+
+type V struct {
+	F1 interface{}
+	F2 int32
+	F3 sonicjson.Number
+}
+
+type tx struct {
+	x int
+}
+
+var ifaceNumAsFloat64 = map[string]interface{}{
+	"k1": float64(1),
+	"k2": "s",
+	"k3": []interface{}{float64(1), float64(2.0), float64(3e-3)},
+	"k4": map[string]interface{}{"kk1": "s", "kk2": float64(2)},
+}
+
+var ifaceNumAsNumber = map[string]interface{}{
+	"k1": sonicjson.Number("1"),
+	"k2": "s",
+	"k3": []interface{}{sonicjson.Number("1"), sonicjson.Number("2.0"), sonicjson.Number("3e-3")},
+	"k4": map[string]interface{}{"kk1": "s", "kk2": sonicjson.Number("2")},
+}
+
+var unmarshalTests = []struct {
+	in                    string
+	ptr                   interface{}
+	out                   interface{}
+	err                   bool
+	useNumber             bool
+	disallowUnknownFields bool
+}{
+	// basic types
+	{in: `true`, ptr: new(bool), out: true},
+	{in: `1`, ptr: new(int), out: 1},
+	{in: `1.2`, ptr: new(float64), out: 1.2},
+	{in: `-5`, ptr: new(int16), out: int16(-5)},
+	{in: `2`, ptr: new(sonicjson.Number), out: sonicjson.Number("2"), useNumber: true},
+	{in: `2`, ptr: new(sonicjson.Number), out: sonicjson.Number("2")},
+	{in: `2`, ptr: new(interface{}), out: float64(2.0)},
+	{in: `2`, ptr: new(interface{}), out: sonicjson.Number("2"), useNumber: true},
+	{in: `"a\u1234"`, ptr: new(string), out: "a\u1234"},
+	{in: `"http:\/\/"`, ptr: new(string), out: "http://"},
+	{in: `"g-clef: \uD834\uDD1E"`, ptr: new(string), out: "g-clef: \U0001D11E"},
+	{in: `"invalid: \uD834x\uDD1E"`, ptr: new(string), out: "invalid: \uFFFDx\uFFFD"},
+	{in: "null", ptr: new(interface{}), out: nil},
+	{in: `{"X": [1,2,3], "Y": 4}`, ptr: new(T), out: T{Y: 4}, err: true},
+	{in: `{"X": 23}`, ptr: new(T), out: T{}, err: true},
+	{in: `{"x": 1}`, ptr: new(tx), out: tx{}},
+	{in: `{"x": 1}`, ptr: new(tx), err: true, disallowUnknownFields: true},
+	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: float64(1), F2: int32(2), F3: sonicjson.Number("3")}},
+	{in: `{"F1":1,"F2":2,"F3":3}`, ptr: new(V), out: V{F1: sonicjson.Number("1"), F2: int32(2), F3: sonicjson.Number("3")}, useNumber: true},
+	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsFloat64},
+	{in: `{"k1":1,"k2":"s","k3":[1,2.0,3e-3],"k4":{"kk1":"s","kk2":2}}`, ptr: new(interface{}), out: ifaceNumAsNumber, useNumber: true},
+
+	// raw values with whitespace
+	{in: "\n true ", ptr: new(bool), out: true},
+	{in: "\t 1 ", ptr: new(int), out: 1},
+	{in: "\r 1.2 ", ptr: new(float64), out: 1.2},
+	{in: "\t -5 \n", ptr: new(int16), out: int16(-5)},
+	{in: "\t \"a\\u1234\" \n", ptr: new(string), out: "a\u1234"},
+
+	// Z has a "-" tag.
+	{in: `{"Y": 1, "Z": 2}`, ptr: new(T), out: T{Y: 1}},
+	{in: `{"Y": 1, "Z": 2}`, ptr: new(T), err: true, disallowUnknownFields: true},
+
+	{in: `{"alpha": "abc", "alphabet": "xyz"}`, ptr: new(U), out: U{Alphabet: "abc"}},
+	{in: `{"alpha": "abc", "alphabet": "xyz"}`, ptr: new(U), err: true, disallowUnknownFields: true},
+	{in: `{"alpha": "abc"}`, ptr: new(U), out: U{Alphabet: "abc"}},
+	{in: `{"alphabet": "xyz"}`, ptr: new(U), out: U{}},
+
+	// syntax errors
+	{in: `{"X": "foo", "Y"}`, ptr: new(interface{}), err: true},
+	{in: `[1, 2, 3+]`, ptr: new(interface{}), err: true},
+	{in: `{"X":12x}`, ptr: new(interface{}), err: true, useNumber: true},
+	{in: `[2, 3`, ptr: new(interface{}), err: true},
+
+	// raw value errors
+	{in: "\x01 42", ptr: new(interface{}), err: true},
+	{in: " 42 \x01", ptr: new(interface{}), err: true},
+	{in: " false \x01", ptr: new(interface{}), err: true},
+	{in: " \"string\" \x01", ptr: new(interface{}), err: true},
+
+	// array tests
+	{in: `[1, 2, 3]`, ptr: new([3]int), out: [3]int{1, 2, 3}},
+	{in: `[1, 2, 3]`, ptr: new([1]int), out: [1]int{1}},
+	{in: `[1, 2, 3]`, ptr: new([5]int), out: [5]int{1, 2, 3, 0, 0}},
+
+	// empty array to interface test
+	{in: `[]`, ptr: new([]interface{}), out: []interface{}{}},
+	{in: `null`, ptr: new([]interface{}), out: []interface{}(nil)},
+	{in: `{"T":[]}`, ptr: new(map[string]interface{}), out: map[string]interface{}{"T": []interface{}{}}},
+	{in: `{"T":null}`, ptr: new(map[string]interface{}), out: map[string]interface{}{"T": interface{}(nil)}},
+}
+
+func TestUnmarshal(t *testing.T) {
+	for i, tt := range unmarshalTests {
+		/* the options are only available on Decoder */
+		newPtr := func() interface{} { return reflect.New(reflect.TypeOf(tt.ptr).Elem()).Interface() }
+		exp, got := newPtr(), newPtr()
+		std := json.NewDecoder(strings.NewReader(tt.in))
+		dec := sonicjson.NewDecoder(strings.NewReader(tt.in))
+		if tt.useNumber {
+			std.UseNumber()
+			dec.UseNumber()
+		}
+		if tt.disallowUnknownFields {
+			std.DisallowUnknownFields()
+			dec.DisallowUnknownFields()
+		}
+		experr, err := std.Decode(exp), dec.Decode(got)
+		if (err == nil) != (experr == nil) {
+			t.Errorf("#%d: %s: Decode error:\n\tgot:  %#v\n\twant: %#v", i, tt.in, err, experr)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("#%d: %s: Decode:\n\tgot:  %#v\n\twant: %#v", i, tt.in, got, exp)
+		}
+
+		/* without options, Unmarshal behaves the same */
+		if !tt.useNumber && !tt.disallowUnknownFields {
+			exp, got = newPtr(), newPtr()
+			experr, err = json.Unmarshal([]byte(tt.in), exp), sonicjson.Unmarshal([]byte(tt.in), got)
+			if _, ok := experr.(*sonicjson.SyntaxError); ok && !reflect.DeepEqual(err, experr) || (err == nil) != (experr == nil) {
+				t.Errorf("#%d: %s: Unmarshal error:\n\tgot:  %#v\n\twant: %#v", i, tt.in, err, experr)
+			}
+			if !reflect.DeepEqual(got, exp) {
+				t.Errorf("#%d: %s: Unmarshal:\n\tgot:  %#v\n\twant: %#v", i, tt.in, got, exp)
+			}
+		}
+
+		if (err != nil) != tt.err {
+			t.Errorf("#%d: %s: error: %v, want error: %v", i, tt.in, err, tt.err)
+		}
+		if tt.out != nil && !reflect.DeepEqual(reflect.ValueOf(got).Elem().Interface(), tt.out) {
+			t.Errorf("#%d: %s:\n\tgot:  %#v\n\twant: %#v", i, tt.in, reflect.ValueOf(got).Elem().Interface(), tt.out)
+		}
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	var s struct{ A int }
+	for _, v := range []interface{}{nil, s, (*struct{ A int })(nil)} {
+		err := sonicjson.Unmarshal([]byte(`{"A":1}`), v)
+		if _, ok := err.(*sonicjson.InvalidUnmarshalError); !ok {
+			t.Errorf("Unmarshal(%T) error: got %#v, want *InvalidUnmarshalError", v, err)
+		}
+	}
+}
+
+type Optionals struct {
+	Sr string `json:"sr"`
+	So string `json:"so,omitempty"`
+	Sw string `json:"-"`
+
+	Ir int `json:"omitempty"` // actually named omitempty, not an option
+	Io int `json:"io,omitempty"`
+
+	Slr []string `json:"slr,random"`
+	Slo []string `json:"slo,omitempty"`
+
+	Mr map[string]interface{} `json:"mr"`
+	Mo map[string]interface{} `json:",omitempty"`
+
+	Fr float64 `json:"fr"`
+	Fo float64 `json:"fo,omitempty"`
+
+	Br bool `json:"br"`
+	Bo bool `json:"bo,omitempty"`
+
+	Ur uint `json:"ur"`
+	Uo uint `json:"uo,omitempty"`
+
+	Str struct{} `json:"str"`
+	Sto struct{} `json:"sto,omitempty"`
+}
+
+func TestOmitEmpty(t *testing.T) {
+	var want = `{
+ "sr": "",
+ "omitempty": 0,
+ "slr": null,
+ "mr": {},
+ "fr": 0,
+ "br": false,
+ "ur": 0,
+ "str": {},
+ "sto": {}
+}`
+	var o Optionals
+	o.Sw = "something"
+	o.Mr = map[string]interface{}{}
+	o.Mo = map[string]interface{}{}
+
+	got, err := sonicjson.MarshalIndent(&o, "", " ")
+	if err != nil {
+		t.Fatalf("MarshalIndent error: %v", err)
+	}
+	if got := string(got); got != want {
+		t.Errorf("MarshalIndent:\n\tgot:  %s\n\twant: %s", got, want)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	type Ptr struct {
+		P *int
+	}
+	one := 1
+	for i, v := range []interface{}{
+		nil, true, 0.1, 1e21, 1e-7, -0.0, "hello", "<>&\u2028\u2029", "\xff",
+		[]byte("hello"), []int{1, 2, 3}, [2]string{"a", "b"},
+		map[string]int{"b": 2, "a": 1}, map[int]string{10: "x", 2: "y"},
+		T{X: "x", Y: 1, Z: 2}, &V{F1: []interface{}{1, "a"}, F3: "3"},
+		Ptr{}, Ptr{P: &one}, sonicjson.RawMessage(`{"a" : 1}`),
+	} {
+		exp, experr := json.Marshal(v)
+		got, err := sonicjson.Marshal(v)
+		if !reflect.DeepEqual(err, experr) {
+			t.Errorf("#%d: Marshal(%#v) error:\n\tgot:  %v\n\twant: %v", i, v, err, experr)
+		}
+		if string(got) != string(exp) {
+			t.Errorf("#%d: Marshal(%#v):\n\tgot:  %s\n\twant: %s", i, v, got, exp)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type Cycle struct {
+		Name string
+		Next *Cycle
+	}
+	cycle := &Cycle{Name: "Dummy"}
+	cycle.Next = cycle
+
+	for i, v := range []interface{}{
+		make(chan int), func() {}, map[[2]int]int{{1, 2}: 3}, cycle,
+		sonicjson.RawMessage(`{`), math.NaN(), math.Inf(-1),
+	} {
+		_, experr := json.Marshal(v)
+		_, err := sonicjson.Marshal(v)
+		if err == nil {
+			t.Errorf("#%d: Marshal(%T) error:\n\tgot:  %#v\n\twant: %#v", i, v, err, experr)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		data string
+		ok   bool
+	}{
+		{`foo`, false},
+		{`}{`, false},
+		{`{]`, false},
+		{`{}`, true},
+		{`{"foo":"bar"}`, true},
+		{`{"foo":"bar","bar":{"baz":["qux"]}}`, true},
+	}
+	for _, tt := range tests {
+		if ok := sonicjson.Valid([]byte(tt.data)); ok != tt.ok {
+			t.Errorf("Valid(`%s`) = %v, want %v", tt.data, ok, tt.ok)
+		}
+	}
+}
+
+func TestCompactAndIndent(t *testing.T) {
+	tests := []struct {
+		compact string
+		indent  string
+	}{
+		{`1`, `1`},
+		{`{}`, `{}`},
+		{`[]`, `[]`},
+		{`{"":2}`, "{\n\t\"\": 2\n}"},
+		{`[3]`, "[\n\t3\n]"},
+		{`[1,2,3]`, "[\n\t1,\n\t2,\n\t3\n]"},
+		{`{"x":1}`, "{\n\t\"x\": 1\n}"},
+		{`[true,false,null,"x",1,1.5,0,-5e+2]`, `[
+	true,
+	false,
+	null,
+	"x",
+	1,
+	1.5,
+	0,
+	-5e+2
+]`},
+		{"{\"\":\"<>&\u2028\u2029\"}", "{\n\t\"\": \"<>&\u2028\u2029\"\n}"},
+	}
+	var buf bytes.Buffer
+	for _, tt := range tests {
+		buf.Reset()
+		if err := sonicjson.Compact(&buf, []byte(tt.indent)); err != nil {
+			t.Errorf("Compact error: %v", err)
+		} else if got := buf.String(); got != tt.compact {
+			t.Errorf("Compact:\n\tgot:  %s\n\twant: %s", got, tt.compact)
+		}
+
+		buf.Reset()
+		if err := sonicjson.Indent(&buf, []byte(tt.compact), "", "\t"); err != nil {
+			t.Errorf("Indent error: %v", err)
+		} else if got := buf.String(); got != tt.indent {
+			t.Errorf("Indent:\n\tgot:  %s\n\twant: %s", got, tt.indent)
+		}
+	}
+}
+
+var streamTest = []interface{}{
+	0.1,
+	"hello",
+	nil,
+	true,
+	false,
+	[]interface{}{"a", "b", "c"},
+	map[string]interface{}{"\u212a": "Kelvin", "ß": "long s"},
+	3.14, // another value to make sure something can follow map
+}
+
+var streamEncoded = `0.1
+"hello"
+null
+true
+false
+["a","b","c"]
+{"ß":"long s","K":"Kelvin"}
+3.14
+`
+
+func nlines(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for i, c := range s {
+		if c == '\n' {
+			if n--; n == 0 {
+				return s[0 : i+1]
+			}
+		}
+	}
+	return s
+}
+
+func TestEncoder(t *testing.T) {
+	for i := 0; i <= len(streamTest); i++ {
+		var buf strings.Builder
+		enc := sonicjson.NewEncoder(&buf)
+		// Check that enc.SetIndent("", "") turns off indentation.
+		enc.SetIndent(">", ".")
+		enc.SetIndent("", "")
+		for j, v := range streamTest[0:i] {
+			if err := enc.Encode(v); err != nil {
+				t.Fatalf("#%d.%d Encode error: %v", i, j, err)
+			}
+		}
+		if have, want := buf.String(), nlines(streamEncoded, i); have != want {
+			t.Errorf("encoding %d items: mismatch:\n\thave: %s\n\twant: %s", i, have, want)
+			break
+		}
+	}
+}
+
+func TestEncoderErrorAndReuse(t *testing.T) {
+	type Dummy struct {
+		Name string
+		Next *Dummy
+	}
+	dummy := Dummy{Name: "Dummy"}
+	dummy.Next = &dummy
+
+	var buf bytes.Buffer
+	enc := sonicjson.NewEncoder(&buf)
+	if err := enc.Encode(dummy); err == nil {
+		t.Errorf("Encode(dummy) error: got nil, want non-nil")
+	}
+
+	type Data struct {
+		A string
+		I int
+	}
+	want := Data{A: "a", I: 1}
+	if err := enc.Encode(want); err != nil {
+		t.Errorf("Marshal error: %v", err)
+	}
+
+	var got Data
+	if err := sonicjson.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Errorf("Unmarshal error: %v", err)
+	}
+	if got != want {
+		t.Errorf("Marshal/Unmarshal roundtrip:\n\tgot:  %v\n\twant: %v", got, want)
+	}
+}
+
+func TestEncoderSetEscapeHTML(t *testing.T) {
+	v := map[string]string{"a": "<b>&</b>"}
+	for _, on := range []bool{true, false} {
+		var exp, got bytes.Buffer
+		std, enc := json.NewEncoder(&exp), sonicjson.NewEncoder(&got)
+		std.SetEscapeHTML(on)
+		enc.SetEscapeHTML(on)
+		if err := std.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != exp.String() {
+			t.Errorf("SetEscapeHTML(%v):\n\tgot:  %s\n\twant: %s", on, got.String(), exp.String())
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	for i := 0; i <= len(streamTest); i++ {
+		// Use stream without newlines as input,
+		// just to stress the decoder even more.
+		// Our test input does not include back-to-back numbers.
+		// Otherwise stripping the newlines would
+		// merge two adjacent JSON values.
+		var buf bytes.Buffer
+		for _, c := range nlines(streamEncoded, i) {
+			if c != '\n' {
+				buf.WriteRune(c)
+			}
+		}
+		out := make([]interface{}, i)
+		dec := sonicjson.NewDecoder(&buf)
+		for j := range out {
+			if err := dec.Decode(&out[j]); err != nil {
+				t.Fatalf("decode #%d/%d error: %v", j, i, err)
+			}
+		}
+		if !reflect.DeepEqual(out, streamTest[0:i]) {
+			t.Errorf("decoding %d items: mismatch:\n\tgot:  %v\n\twant: %v", i, out, streamTest[0:i])
+			break
+		}
+	}
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	r := strings.NewReader(`{"Name": "Gopher"} extra `)
+	var m struct {
+		Name string
+	}
+	d := sonicjson.NewDecoder(r)
+	err := d.Decode(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "Gopher" {
+		t.Errorf("Name = %s, want Gopher", m.Name)
+	}
+	rest, err := io.ReadAll(d.Buffered())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(rest), " extra "; got != want {
+		t.Errorf("Remaining = %s, want %s", got, want)
+	}
+}
+
+func TestDecoderToken(t *testing.T) {
+	dec := sonicjson.NewDecoder(strings.NewReader(`[{"a": 1}, {"a": 2}]`))
+	if tok, err := dec.Token(); err != nil || tok != sonicjson.Delim('[') {
+		t.Fatalf("Token() = %v, %v, want [", tok, err)
+	}
+	for i := 1; dec.More(); i++ {
+		var v struct{ A int }
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.A != i {
+			t.Errorf("#%d: A = %d, want %d", i, v.A, i)
+		}
+	}
+	if tok, err := dec.Token(); err != nil || tok != sonicjson.Delim(']') {
+		t.Fatalf("Token() = %v, %v, want ]", tok, err)
+	}
+	if off := dec.InputOffset(); off != 20 {
+		t.Errorf("InputOffset() = %d, want 20", off)
+	}
+}
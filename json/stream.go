@@ -0,0 +1,153 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bytedance/sonic"
+)
+
+// An Encoder writes JSON values to an output stream, see encoding/json.Encoder.
+type Encoder struct {
+	enc sonic.Encoder
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: api.NewEncoder(w)}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline
+// character.
+func (enc *Encoder) Encode(v interface{}) error {
+	return enc.enc.Encode(v)
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings. The default behavior is to escape.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.enc.SetEscapeHTML(on)
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded value as
+// if indented by the package-level function Indent(dst, src, prefix, indent).
+// Calling SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.enc.SetIndent(prefix, indent)
+}
+
+// A Decoder reads and decodes JSON values from an input stream, see
+// encoding/json.Decoder.
+//
+// Values are decoded by the stream decoder of sonic. Once Token is called, the
+// rest of the stream is tokenized by encoding/json, which keeps Token, More
+// and InputOffset exactly as they are, and each value it reads is decoded by
+// sonic.
+type Decoder struct {
+	r   io.Reader
+	dec sonic.Decoder
+	std *json.Decoder // tokenizes the stream from off on, see Token
+	off int64
+	cfg sonic.Config
+	api sonic.API
+	raw json.RawMessage
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, dec: api.NewDecoder(r), cfg: stdConfig, api: api}
+}
+
+// Decode reads the next JSON-encoded value from its input and stores it in
+// the value pointed to by v.
+func (dec *Decoder) Decode(v interface{}) error {
+	if dec.std == nil {
+		return dec.dec.Decode(v)
+	}
+	if err := dec.std.Decode(&dec.raw); err != nil {
+		return err
+	}
+	return dec.api.Unmarshal(dec.raw, v)
+}
+
+// tokenize hands the rest of the stream over to encoding/json.
+func (dec *Decoder) tokenize() {
+	if dec.std != nil {
+		return
+	}
+	dec.off = dec.InputOffset()
+	dec.std = json.NewDecoder(io.MultiReader(dec.dec.Buffered(), dec.r))
+	if dec.cfg.UseNumber {
+		dec.std.UseNumber()
+	}
+	if dec.cfg.DisallowUnknownFields {
+		dec.std.DisallowUnknownFields()
+	}
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer.
+// The reader is valid until the next call to Decode.
+func (dec *Decoder) Buffered() io.Reader {
+	if dec.std != nil {
+		return dec.std.Buffered()
+	}
+	return dec.dec.Buffered()
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the
+// destination is a struct and the input contains object keys which do not
+// match any non-ignored, exported fields in the destination.
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.cfg.DisallowUnknownFields = true
+	dec.api = dec.cfg.Froze()
+	dec.dec.DisallowUnknownFields()
+}
+
+// UseNumber causes the Decoder to unmarshal a number into an interface{} as a
+// Number instead of as a float64.
+func (dec *Decoder) UseNumber() {
+	dec.cfg.UseNumber = true
+	dec.api = dec.cfg.Froze()
+	dec.dec.UseNumber()
+}
+
+// InputOffset returns the input stream byte offset of the current decoder
+// position.
+func (dec *Decoder) InputOffset() int64 {
+	if dec.std != nil {
+		return dec.off + dec.std.InputOffset()
+	}
+	return dec.dec.(interface{ InputOffset() int64 }).InputOffset()
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed.
+func (dec *Decoder) More() bool {
+	if dec.std != nil {
+		return dec.std.More()
+	}
+	return dec.dec.More()
+}
+
+// Token returns the next JSON token in the input stream, see
+// encoding/json.Decoder.Token.
+func (dec *Decoder) Token() (Token, error) {
+	dec.tokenize()
+	return dec.std.Token()
+}
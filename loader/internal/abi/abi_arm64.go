@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// The Go to C wrappers are x86-64 code calling the native routines, which
+// only exist on AMD64, so ARM64 has the frame layouts but nothing to wrap.
+
+const (
+	PtrSize  = 8 // pointer size
+	PtrAlign = 8 // pointer alignment
+)
+
+type Register string
+
+type Parameter struct {
+	InRegister bool
+	IsPointer  bool
+	Mem        uint32
+	Type       reflect.Type
+}
+
+func (self Parameter) String() string {
+	return fmt.Sprintf("[%d(FP), Pointer(%v)]", self.Mem, self.IsPointer)
+}
+
+func ReservedRegs(callc bool) []Register {
+	return nil
+}
+
+func NewFunctionLayout(ft reflect.Type) FunctionLayout {
+	panic("abi: Go to C wrappers are not supported on arm64")
+}
+
+func (self *Frame) StackCheckTextSize() uint32 {
+	panic("abi: Go to C wrappers are not supported on arm64")
+}
+
+func (self *Frame) GrowStackTextSize() uint32 {
+	panic("abi: Go to C wrappers are not supported on arm64")
+}
+
+func CallC(addr uintptr, fr Frame, maxStack uintptr) []byte {
+	panic("abi: Go to C wrappers are not supported on arm64")
+}
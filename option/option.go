@@ -29,6 +29,10 @@ var (
     // LimitBufferSize indicates the max pool buffer size, in case of OOM.
     // See issue https://github.com/bytedance/sonic/issues/614
     LimitBufferSize uint = 1024 * 1024
+
+    // MaxDecoderStackDepth is the overall limit of the decoder state stack, in slots,
+    // when it is allowed to spill into the heap by decoder.OptionGrowStack
+    MaxDecoderStackDepth uint = 1024 * 1024
 )
 
 // CompileOptions includes all options for encoder or decoder compiler.
@@ -739,4 +739,87 @@ func TestGetNotExist(t *testing.T) {
     if x != 3 {
         t.Fatal(x)
     }
-}
\ No newline at end of file
+}
+func genItemsDocument(n int) []byte {
+    var buf bytes.Buffer
+    buf.WriteString(`{"data":{"total":`)
+    fmt.Fprintf(&buf, "%d", n)
+    buf.WriteString(`,"items":[`)
+    for i := 0; i < n; i++ {
+        if i > 0 {
+            buf.WriteByte(',')
+        }
+        fmt.Fprintf(&buf, `{"id":%d,"name":"item-%d","tags":["a","b"],"meta":{"x":[1,2,{"y":"\"}"}]}}`, i * 10, i)
+    }
+    buf.WriteString(`]}}`)
+    return buf.Bytes()
+}
+
+type itemsDocument struct {
+    Data struct {
+        Total int `json:"total"`
+        Items []struct {
+            ID   int                    `json:"id"`
+            Name string                 `json:"name"`
+            Tags []string               `json:"tags"`
+            Meta map[string]interface{} `json:"meta"`
+        } `json:"items"`
+    } `json:"data"`
+}
+
+func TestGetLargeDocument(t *testing.T) {
+    data := genItemsDocument(1000)
+
+    node, err := Get(data, "data", "items", 3, "id")
+    if err != nil {
+        t.Fatal(err)
+    }
+    id, err := node.Int64()
+    if err != nil || id != 30 {
+        t.Fatalf("id: %v, err: %v", id, err)
+    }
+
+    /* intermediate nodes are returned unparsed */
+    item, err := Get(data, "data", "items", 999)
+    if err != nil || !item.IsRaw() || item.Type() != ast.V_OBJECT {
+        t.Fatalf("item: %v, err: %v", item.Type(), err)
+    }
+    name, err := item.Get("name").String()
+    if err != nil || name != "item-999" {
+        t.Fatalf("name: %v, err: %v", name, err)
+    }
+
+    /* an index past the end of the array is not found */
+    if _, err := Get(data, "data", "items", 1000, "id"); err == nil {
+        t.Fatal("expected an error for an out of range index")
+    }
+
+    var doc itemsDocument
+    if err := Unmarshal(data, &doc); err != nil {
+        t.Fatal(err)
+    }
+    if doc.Data.Items[3].ID != int(id) {
+        t.Fatalf("expected %d, got %d", doc.Data.Items[3].ID, id)
+    }
+}
+
+func BenchmarkGetLargeDocument_Sonic(b *testing.B) {
+    data := genItemsDocument(1000)
+    b.SetBytes(int64(len(data)))
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        node, _ := Get(data, "data", "items", 3, "id")
+        _, _ = node.Int64()
+    }
+}
+
+func BenchmarkGetLargeDocument_Unmarshal(b *testing.B) {
+    data := genItemsDocument(1000)
+    b.SetBytes(int64(len(data)))
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        var doc itemsDocument
+        _ = Unmarshal(data, &doc)
+        _ = doc.Data.Items[3].ID
+    }
+}
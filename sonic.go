@@ -22,10 +22,12 @@ package sonic
 import (
     `io`
     `reflect`
+    `sync`
 
     `github.com/bytedance/sonic/decoder`
     `github.com/bytedance/sonic/encoder`
     `github.com/bytedance/sonic/option`
+    `github.com/bytedance/sonic/internal/envs`
     `github.com/bytedance/sonic/internal/rt`
 )
 
@@ -92,27 +94,47 @@ func (cfg Config) Froze() API {
     if cfg.CaseSensitive {
         api.decoderOpts |= decoder.OptionCaseSensitive
     }
+    if cfg.BoolAsInt {
+        api.decoderOpts |= decoder.OptionBoolAsInt
+    }
     return api
 }
 
+// std returns the API implemented by encoding/json, used while the JIT is disabled
+func (cfg frozenConfig) std() stdConfig {
+    return stdConfig{Config: cfg.Config}
+}
+
 // Marshal is implemented by sonic
 func (cfg frozenConfig) Marshal(val interface{}) ([]byte, error) {
+    if !envs.UseJIT() {
+        return cfg.std().Marshal(val)
+    }
     return encoder.Encode(val, cfg.encoderOpts)
 }
 
 // MarshalToString is implemented by sonic
 func (cfg frozenConfig) MarshalToString(val interface{}) (string, error) {
+    if !envs.UseJIT() {
+        return cfg.std().MarshalToString(val)
+    }
     buf, err := encoder.Encode(val, cfg.encoderOpts)
     return rt.Mem2Str(buf), err
 }
 
 // MarshalIndent is implemented by sonic
 func (cfg frozenConfig) MarshalIndent(val interface{}, prefix, indent string) ([]byte, error) {
+    if !envs.UseJIT() {
+        return cfg.std().MarshalIndent(val, prefix, indent)
+    }
     return encoder.EncodeIndented(val, prefix, indent, cfg.encoderOpts)
 }
 
 // UnmarshalFromString is implemented by sonic
 func (cfg frozenConfig) UnmarshalFromString(buf string, val interface{}) error {
+    if !envs.UseJIT() {
+        return cfg.std().UnmarshalFromString(buf, val)
+    }
     dec := decoder.NewDecoder(buf)
     dec.SetOptions(cfg.decoderOpts)
     err := dec.Decode(val)
@@ -132,6 +154,9 @@ func (cfg frozenConfig) Unmarshal(buf []byte, val interface{}) error {
 
 // NewEncoder is implemented by sonic
 func (cfg frozenConfig) NewEncoder(writer io.Writer) Encoder {
+    if !envs.UseJIT() {
+        return cfg.std().NewEncoder(writer)
+    }
     enc := encoder.NewStreamEncoder(writer)
     enc.Opts = cfg.encoderOpts
     return enc
@@ -139,6 +164,9 @@ func (cfg frozenConfig) NewEncoder(writer io.Writer) Encoder {
 
 // NewDecoder is implemented by sonic
 func (cfg frozenConfig) NewDecoder(reader io.Reader) Decoder {
+    if !envs.UseJIT() {
+        return cfg.std().NewDecoder(reader)
+    }
     dec := decoder.NewStreamDecoder(reader)
     dec.SetOptions(cfg.decoderOpts)
     return dec
@@ -150,6 +178,44 @@ func (cfg frozenConfig) Valid(data []byte) bool {
     return ok
 }
 
+// MarshalToBuffer appends the JSON encoding bytes of val to dst, encoded like
+// Marshal, and returns the extended buffer. Marshal copies the result out of a
+// pooled buffer, while this writes into dst directly, so a caller which reuses
+// the returned buffer (truncated to dst[:0]) saves the copy and its allocation.
+// On error, dst is returned unchanged.
+//
+// It always encodes with the options of ConfigDefault, like Marshal, there is
+// no API method using the options of another config.
+func MarshalToBuffer(dst []byte, val interface{}) ([]byte, error) {
+    if !envs.UseJIT() {
+        buf, err := Marshal(val)
+        if err != nil {
+            return dst, err
+        }
+        return append(dst, buf...), nil
+    }
+    buf := bufferHeaders.Get().(*[]byte)
+    *buf = dst
+    err := encoder.EncodeInto(buf, val, 0)
+    ret := *buf
+
+    /* the pooled header must not keep the buffer of the caller alive */
+    *buf = nil
+    bufferHeaders.Put(buf)
+    if err != nil {
+        return dst, err
+    }
+    return ret, nil
+}
+
+// bufferHeaders holds the slice headers passed to encoder.EncodeInto by
+// MarshalToBuffer, which would otherwise be allocated on every call
+var bufferHeaders = sync.Pool{
+    New: func() interface{} {
+        return new([]byte)
+    },
+}
+
 // Pretouch compiles vt ahead-of-time to avoid JIT compilation on-the-fly, in
 // order to reduce the first-hit latency.
 //
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2025 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sonic
+
+import (
+    `bytes`
+    `encoding/json`
+    `io`
+
+    `github.com/bytedance/sonic/internal/envs`
+)
+
+// SetJITEnabled enables or disables the JIT at runtime, it is enabled unless
+// the environment variable SONIC_JIT_ENABLED is "0". While it is disabled,
+// the APIs are implemented by encoding/json, and the functions already
+// compiled by the JIT are not used.
+func SetJITEnabled(on bool) {
+    if on {
+        envs.EnableJIT()
+    } else {
+        envs.DisableJIT()
+    }
+}
+
+// IsJITEnabled reports whether the APIs are implemented by the JIT, which is
+// never the case when APIKind is UseStdJSON.
+func IsJITEnabled() bool {
+    return apiKind == UseSonicJSON && envs.UseJIT()
+}
+
+// stdConfig implements API with encoding/json, only the options supported by
+// encoding/json are taken from Config.
+type stdConfig struct {
+    Config
+}
+
+func (cfg stdConfig) marshalOptions(val interface{}, prefix, indent string) ([]byte, error) {
+    w := bytes.NewBuffer([]byte{})
+    enc := json.NewEncoder(w)
+    enc.SetEscapeHTML(cfg.EscapeHTML)
+    enc.SetIndent(prefix, indent)
+    err := enc.Encode(val)
+	out := w.Bytes()
+
+	// json.Encoder always appends '\n' after encoding,
+	// which is not same with json.Marshal()
+	if len(out) > 0 && out[len(out)-1] == '\n' {
+		out = out[:len(out)-1]
+	}
+	return out, err
+}
+
+// Marshal is implemented by encoding/json
+func (cfg stdConfig) Marshal(val interface{}) ([]byte, error) {
+    if !cfg.EscapeHTML {
+        return cfg.marshalOptions(val, "", "")
+    }
+    return json.Marshal(val)
+}
+
+// MarshalToString is implemented by encoding/json
+func (cfg stdConfig) MarshalToString(val interface{}) (string, error) {
+    out, err := cfg.Marshal(val)
+    return string(out), err
+}
+
+// MarshalIndent is implemented by encoding/json
+func (cfg stdConfig) MarshalIndent(val interface{}, prefix, indent string) ([]byte, error) {
+    if !cfg.EscapeHTML {
+        return cfg.marshalOptions(val, prefix, indent)
+    }
+    return json.MarshalIndent(val, prefix, indent)
+}
+
+// UnmarshalFromString is implemented by encoding/json
+func (cfg stdConfig) UnmarshalFromString(buf string, val interface{}) error {
+    r := bytes.NewBufferString(buf)
+    dec := json.NewDecoder(r)
+    if cfg.UseNumber {
+        dec.UseNumber()
+    }
+    if cfg.DisallowUnknownFields {
+        dec.DisallowUnknownFields()
+    }
+    err := dec.Decode(val)
+    if err != nil {
+        return err
+    }
+
+    // check the trailing chars
+    offset := dec.InputOffset()
+    if t, err := dec.Token(); !(t == nil && err == io.EOF) {
+        return &json.SyntaxError{ Offset: offset}
+    }
+    return nil
+}
+
+// Unmarshal is implemented by encoding/json
+func (cfg stdConfig) Unmarshal(buf []byte, val interface{}) error {
+    return cfg.UnmarshalFromString(string(buf), val)
+}
+
+// NewEncoder is implemented by encoding/json
+func (cfg stdConfig) NewEncoder(writer io.Writer) Encoder {
+    enc := json.NewEncoder(writer)
+    if !cfg.EscapeHTML {
+        enc.SetEscapeHTML(cfg.EscapeHTML)
+    }
+    return enc
+}
+
+// NewDecoder is implemented by encoding/json
+func (cfg stdConfig) NewDecoder(reader io.Reader) Decoder {
+    dec := json.NewDecoder(reader)
+    if cfg.UseNumber {
+        dec.UseNumber()
+    }
+    if cfg.DisallowUnknownFields {
+        dec.DisallowUnknownFields()
+    }
+    return dec
+}
+
+// Valid is implemented by encoding/json
+func (cfg stdConfig) Valid(data []byte) bool {
+    return json.Valid(data)
+}